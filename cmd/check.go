@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/config"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [package-dir]",
+	Short: "Fail when generated code of a package is missing or stale",
+	Long: `List the targets of a package that generate would process: targets never generated,
+and targets whose declaration, instruction or dependencies changed since.
+
+Exits with status 1 when any target is pending. Nothing is generated and no model is called.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 0 {
+			pkgDir = args[0]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		buildenv.Set(cfg.BuildEnv())
+
+		pending, err := app.Check(pkgDir, cfg)
+		if err != nil {
+			slog.Error("check failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		for _, status := range pending {
+			line := fmt.Sprintf("%s (%s): %s", status.Target.GetDisplayName(), filepath.Base(status.Target.FilePath), status.Status)
+			if status.Reason != "" {
+				line += ", " + status.Reason
+			}
+			fmt.Println(line)
+		}
+		if len(pending) > 0 {
+			fmt.Printf("%d targets need generation; run mantra generate %s\n", len(pending), pkgDir)
+			os.Exit(1)
+		}
+		fmt.Println("Generated code is up to date")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [package-dir]",
+	Short: "Print the resolved configuration for a package and validate it",
+	Long: `Print every setting of the configuration used for a package after merging
+mantra.toml files and applying flags, together with where each value comes from:
+flag, env (environment variable references), file (the mantra.toml that set it),
+or default. Exits with an error if the configuration is invalid.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 0 {
+			pkgDir = args[0]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		cfg.OverrideLogLevel(logLevel)
+
+		fmt.Println("Config files:")
+		for _, path := range cfg.Files() {
+			fmt.Printf("  %s\n", path)
+		}
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, setting := range cfg.Settings() {
+			source := setting.Source
+			if setting.Origin != "" {
+				source += " (" + setting.Origin + ")"
+			}
+			fmt.Fprintf(w, "%s = %s\t# %s\n", setting.Key, setting.Value, source)
+		}
+		w.Flush()
+
+		if err := cfg.CheckPackage(pkgDir); err != nil {
+			slog.Error("invalid configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configShowCmd.Flags().StringVar(&logLevel, "log-level", "", "Override log level (error, warn, info, debug, trace)")
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/coder"
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/estimate"
+)
+
+var assumptions = estimate.DefaultAssumptions
+
+var estimateCmd = &cobra.Command{
+	Use:   "estimate [package-dir]",
+	Short: "Estimate tokens, cost and time of generating a package",
+	Long: `Build the prompts of every new or outdated target without calling the provider,
+and estimate the tokens, cost and wall-clock time generation would take.
+
+Prompt sizes are measured; the number of requests per phase, the size of completions
+and the generation speed are assumptions that can be tuned with flags. Cost requires
+[pricing] in mantra.toml.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 0 {
+			pkgDir = args[0]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		setupLogging(cfg)
+		buildenv.Set(cfg.BuildEnv())
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		est, err := app.Estimate(ctx, pkgDir, cfg, assumptions)
+		if err != nil {
+			slog.Error("estimation failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if len(est.Targets) == 0 {
+			fmt.Println("All targets up-to-date, nothing to generate")
+			return
+		}
+
+		hasPricing := cfg.Pricing != nil
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Target\tPhases\tRequests\tInput tokens\tOutput tokens\tCost\tTime")
+		for _, t := range est.Targets {
+			phases := strings.Join(t.Phases, ", ")
+			if phases == "" {
+				phases = "none (rule)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", t.Name, phases, t.Requests, t.InputTokens, t.OutputTokens, formatCost(t.Cost, hasPricing), t.Duration.Round(time.Second))
+		}
+		fmt.Fprintf(w, "Total (%d targets)\t\t%d\t%d\t%d\t%s\t%s\n", len(est.Targets), est.Requests, est.InputTokens, est.OutputTokens, formatCost(est.Cost, hasPricing), est.WallClock.Round(time.Second))
+		w.Flush()
+
+		fmt.Println()
+		fmt.Printf("Assumes %d requests per phase, %d output tokens per request, %.0f tokens/s, %s per request, %d targets in parallel.\n",
+			assumptions.Rounds, assumptions.OutputTokens, assumptions.OutputTPS, assumptions.RequestLatency, assumptions.Concurrency)
+		if est.RateLimited {
+			fmt.Println("Total time is bound by [rate_limit].")
+		}
+		if !hasPricing {
+			fmt.Println("Set [pricing] in mantra.toml to estimate cost.")
+		}
+	},
+}
+
+// formatCost renders a cost in USD, or "-" without pricing
+func formatCost(cost float64, hasPricing bool) string {
+	if !hasPricing {
+		return "-"
+	}
+	return fmt.Sprintf("$%.4f", cost)
+}
+
+func init() {
+	estimateCmd.Flags().IntVar(&assumptions.Rounds, "rounds", assumptions.Rounds, "Assumed requests per phase, including tool rounds")
+	estimateCmd.Flags().IntVar(&assumptions.OutputTokens, "output-tokens", assumptions.OutputTokens, "Assumed completion tokens per request")
+	estimateCmd.Flags().Float64Var(&assumptions.OutputTPS, "tokens-per-second", assumptions.OutputTPS, "Assumed completion tokens generated per second")
+	estimateCmd.Flags().DurationVar(&assumptions.RequestLatency, "request-latency", assumptions.RequestLatency, "Assumed fixed latency per request")
+	estimateCmd.Flags().IntVar(&assumptions.Concurrency, "concurrency", coder.MaxParallelTargets, "Targets generated in parallel")
+	estimateCmd.Flags().StringVar(&logLevel, "log-level", "", "Override log level (error, warn, info, debug, trace)")
+	rootCmd.AddCommand(estimateCmd)
+}
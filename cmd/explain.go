@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/config"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <target> [package-dir]",
+	Short: "Show the context and prompts that would be sent for a target",
+	Long: `Print, for one target, the parsed instruction, the static context extracted from
+the package (imports, types and methods), the conventions file, and the first prompt
+of each phase exactly as generation would send it, without calling the provider.
+Name methods as Type.Method.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 1 {
+			pkgDir = args[1]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		setupLogging(cfg)
+		buildenv.Set(cfg.BuildEnv())
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		explanation, err := app.Explain(ctx, pkgDir, cfg, args[0])
+		if err != nil {
+			slog.Error("explain failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		printExplanation(cfg, explanation)
+	},
+}
+
+// printExplanation writes an explanation as plain text sections
+func printExplanation(cfg *config.Config, e *app.Explanation) {
+	target := e.Status.Target
+	section := func(title string) { fmt.Printf("\n=== %s ===\n", title) }
+
+	fmt.Printf("Target: %s\n", target.GetDisplayName())
+	fmt.Printf("File: %s\n", target.FilePath)
+	if e.Status.Reason != "" {
+		fmt.Printf("Status: %s (%s)\n", e.Status.Status, e.Status.Reason)
+	} else {
+		fmt.Printf("Status: %s\n", e.Status.Status)
+	}
+	if e.Rule != "" {
+		fmt.Printf("Mode: rule (%s; no model call)\n", e.Rule)
+	} else if e.Fast {
+		fmt.Println("Mode: fast (context gathering skipped)")
+	}
+	if e.Status.PreviousFailure != nil {
+		fmt.Printf("Previous failure: %s: %s\n", e.Status.PreviousFailure.Phase, e.Status.PreviousFailure.Message)
+	}
+
+	section("Instruction")
+	fmt.Println(target.Instruction)
+
+	if e.Rule != "" {
+		section("Synthesized body")
+		fmt.Println(e.Body)
+		return
+	}
+
+	section("Static context")
+	if e.Context == nil {
+		fmt.Println("(package failed to load; phases extract context themselves)")
+	} else {
+		fmt.Printf("Package: %s\n", e.Context.PackageName)
+		fmt.Println("Imports:")
+		for _, imp := range e.Context.Imports {
+			if imp.Alias != "" {
+				fmt.Printf("  %s %q\n", imp.Alias, imp.Path)
+			} else {
+				fmt.Printf("  %q\n", imp.Path)
+			}
+		}
+		fmt.Println("Types:")
+		for _, name := range slices.Sorted(maps.Keys(e.Context.Types)) {
+			fmt.Printf("  %s\n", indent(e.Context.Types[name], "  "))
+			for _, method := range e.Context.Methods[name] {
+				fmt.Printf("    %s\n", method.Signature)
+			}
+		}
+		if c := e.Context.Constructor; c != nil {
+			fmt.Printf("Constructor of %s (%d fields, options: %s)\n", c.TypeName, len(c.Fields), strings.Join(c.Options, ", "))
+		}
+	}
+
+	section("Conventions")
+	if data, err := os.ReadFile(cfg.ConventionsFile); err == nil {
+		fmt.Printf("%s\n%s", cfg.ConventionsFile, data)
+	} else {
+		fmt.Printf("%s (not found, built-in defaults apply)\n", cfg.ConventionsFile)
+	}
+
+	for _, prompt := range e.Prompts {
+		section(fmt.Sprintf("%s phase (~%d tokens, tools: %s)", prompt.Phase, prompt.Tokens, strings.Join(prompt.Tools, ", ")))
+		fmt.Println("--- system ---")
+		fmt.Println(prompt.System)
+		fmt.Println("--- user ---")
+		fmt.Println(prompt.User)
+	}
+}
+
+// indent prefixes every line after the first with prefix
+func indent(s, prefix string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+func init() {
+	explainCmd.Flags().StringVar(&logLevel, "log-level", "", "Override log level (error, warn, info, debug, trace)")
+	rootCmd.AddCommand(explainCmd)
+}
@@ -3,20 +3,29 @@ package cmd
 import (
 	"context"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"log/slog"
 
 	"github.com/spf13/cobra"
 
 	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/buildenv"
 	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/diff"
 	"github.com/rail44/mantra/internal/log"
 )
 
 var (
-	plain    bool
-	logLevel string
+	plain        bool
+	logLevel     string
+	statusAddr   string
+	progress     string
+	progressFile string
+	strict       bool
+	fromDiff     bool
 )
 
 var generateCmd = &cobra.Command{
@@ -42,9 +51,14 @@ their implementations based on the natural language instructions provided.`,
 			slog.Error("failed to load configuration", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
+		if err := cfg.CheckPackage(pkgDir); err != nil {
+			slog.Error("invalid configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
 
 		// Set up logging
 		setupLogging(cfg)
+		buildenv.Set(cfg.BuildEnv())
 
 		// Ensure absolute path
 		absPkgDir, err := filepath.Abs(pkgDir)
@@ -55,10 +69,45 @@ their implementations based on the natural language instructions provided.`,
 
 		// Set plain output flag in config
 		cfg.Plain = plain
+		cfg.StatusAddr = statusAddr
+		cfg.ProgressFile = progressFile
+		if strict {
+			if cfg.Lint == nil {
+				cfg.Lint = &config.LintConfig{}
+			}
+			cfg.Lint.Strict = true
+		}
+
+		// Restrict generation to the targets a change touches, e.g. git diff | mantra generate --diff.
+		// Stdin carries the diff, so the TUI is disabled
+		if fromDiff {
+			cfg.Plain = true
+			d, err := diff.Parse(os.Stdin)
+			if err != nil {
+				slog.Error("failed to read diff", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			cfg.Diff = d
+		}
+
+		// The progress stream owns stdout, so the TUI is disabled
+		switch progress {
+		case "":
+		case config.ProgressJSONL:
+			cfg.Progress = progress
+			cfg.Plain = true
+		default:
+			slog.Error("invalid progress format", slog.String("progress", progress), slog.String("expected", config.ProgressJSONL))
+			os.Exit(1)
+		}
 
 		// Run generation
+		// Cancel running targets and tool operations on Ctrl+C or SIGTERM
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
 		generateApp := app.NewGenerateApp()
-		if err := generateApp.Run(context.Background(), absPkgDir, cfg); err != nil {
+		if err := generateApp.Run(ctx, absPkgDir, cfg); err != nil {
 			slog.Error("generation failed", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
@@ -68,6 +117,11 @@ their implementations based on the natural language instructions provided.`,
 func init() {
 	generateCmd.Flags().BoolVar(&plain, "plain", false, "Use plain text output instead of interactive TUI")
 	generateCmd.Flags().StringVar(&logLevel, "log-level", "", "Override log level (error, warn, info, debug, trace)")
+	generateCmd.Flags().StringVar(&progress, "progress", "", "Write machine-readable progress events to stdout (jsonl)")
+	generateCmd.Flags().StringVar(&progressFile, "progress-file", "", "Keep the run state as JSON in this file, replaced atomically on every update")
+	generateCmd.Flags().StringVar(&statusAddr, "status-addr", "", "Serve live progress as HTML and JSON on this address (e.g. :9120)")
+	generateCmd.Flags().BoolVar(&strict, "strict", false, "Lint instructions first and refuse to generate flagged targets")
+	generateCmd.Flags().BoolVar(&fromDiff, "diff", false, "Read a unified diff from stdin and generate only targets it touches")
 	rootCmd.AddCommand(generateCmd)
 }
 
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/history"
+)
+
+var (
+	historyShow    int
+	historyRestore int
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <target> [package-dir]",
+	Short: "Show or restore earlier implementations of a target",
+	Long: `List the last implementations accepted for a target, newest first, each with a
+diff against the version before it. Name methods as Type.Method.
+
+--show prints one version in full, and --restore writes it into the generated file
+as the implementation of the current declaration.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 1 {
+			pkgDir = args[1]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		th, err := app.LoadHistory(pkgDir, cfg, args[0])
+		if err != nil {
+			slog.Error("failed to load history", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		name := history.TargetKey(th.Target)
+
+		switch {
+		case historyRestore > 0:
+			if err := app.RestoreHistory(pkgDir, cfg, th, historyRestore); err != nil {
+				slog.Error("restore failed", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			fmt.Printf("Restored %s to version %d\n", name, historyRestore)
+		case historyShow > 0:
+			entry, err := th.Entry(historyShow)
+			if err != nil {
+				slog.Error("failed to show version", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			fmt.Println(entry.Body)
+		case len(th.Entries) == 0:
+			fmt.Printf("No history recorded for %s\n", name)
+		default:
+			for i := len(th.Entries) - 1; i >= 0; i-- {
+				entry := th.Entries[i]
+				previous := ""
+				if i > 0 {
+					previous = th.Entries[i-1].Body
+				}
+				fmt.Printf("version %d  %s  %s\n", entry.Version, entry.Time.Local().Format(time.DateTime), entry.Model)
+				fmt.Println(strings.TrimSuffix(history.Diff(previous, entry.Body), "\n"))
+				fmt.Println()
+			}
+		}
+	},
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyShow, "show", 0, "Print the given version in full")
+	historyCmd.Flags().IntVar(&historyRestore, "restore", 0, "Write the given version into the generated file")
+	historyCmd.MarkFlagsMutuallyExclusive("show", "restore")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/hooks"
+)
+
+var (
+	hookName     string
+	hookGenerate bool
+	hookForce    bool
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage git hooks",
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install [package-dir...]",
+	Short: "Install a git hook failing when generated code is stale",
+	Long: `Install a pre-commit (or pre-push) hook running mantra check on the given packages,
+so stale generated code cannot be committed by accident. With --generate, the hook first
+runs mantra generate --diff on the staged changes and stages the generated files. A
+pre-commit hook also fails while a package or its generated files have unstaged changes,
+as mantra check reads the working tree rather than what is committed.
+
+The hook exits without checking when a variable of [hooks] skip_env (default: CI) is set.
+A hook installed earlier by mantra is replaced; other hooks only with --force.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+
+		cfg, err := config.Load(args[0])
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		root, err := hooks.RepoRoot(ctx, args[0])
+		if err != nil {
+			slog.Error("not in a git repository", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		// Hooks run from the root of the working tree
+		opts := hooks.Options{Hook: hookName, Generate: hookGenerate, SkipEnv: cfg.HookSkipEnv()}
+		for _, pkgDir := range args {
+			absPkgDir, err := filepath.Abs(pkgDir)
+			if err == nil {
+				absPkgDir, err = filepath.EvalSymlinks(absPkgDir)
+			}
+			if err != nil {
+				slog.Error("failed to resolve package directory", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			rel, err := filepath.Rel(root, absPkgDir)
+			if err != nil {
+				slog.Error("failed to resolve package directory", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			pkgCfg, err := config.Load(pkgDir)
+			if err != nil {
+				slog.Error("failed to load configuration", slog.String("package", pkgDir), slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			pkg := hooks.Package{Dir: rel}
+			if relDest, err := filepath.Rel(root, resolveDest(pkgCfg.Dest)); err == nil {
+				pkg.Dest = relDest
+			}
+			opts.Packages = append(opts.Packages, pkg)
+		}
+
+		path, err := hooks.Install(ctx, root, opts, hookForce)
+		if err != nil {
+			slog.Error("failed to install hook", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fmt.Printf("Installed: %s\n", path)
+	},
+}
+
+// resolveDest resolves symlinks in a destination directory, which may not exist yet, so it
+// can be made relative to the repository root
+func resolveDest(dest string) string {
+	if resolved, err := filepath.EvalSymlinks(dest); err == nil {
+		return resolved
+	}
+	if parent, err := filepath.EvalSymlinks(filepath.Dir(dest)); err == nil {
+		return filepath.Join(parent, filepath.Base(dest))
+	}
+	return dest
+}
+
+func init() {
+	hooksInstallCmd.Flags().StringVar(&hookName, "hook", hooks.PreCommit, "Hook to install (pre-commit, pre-push)")
+	hooksInstallCmd.Flags().BoolVar(&hookGenerate, "generate", false, "Generate the targets staged changes touch before checking (pre-commit only)")
+	hooksInstallCmd.Flags().BoolVar(&hookForce, "force", false, "Replace a hook not installed by mantra")
+	hooksCmd.AddCommand(hooksInstallCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
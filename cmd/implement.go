@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/config"
+)
+
+var (
+	implementFile   string
+	implementFunc   string
+	implementStdout bool
+)
+
+var implementCmd = &cobra.Command{
+	Use:   "implement --file <file.go> --func <target> --stdout",
+	Short: "Generate one target and print its body",
+	Long: `Run both phases for one target and print only the generated body on stdout, formatted
+and without the surrounding braces, so editor plugins can insert it at the cursor.
+Logs go to stderr. Nothing is written and the target is generated even when up to date.
+
+Name methods as Type.Method. Helper functions are disabled, as the body is inserted alone.
+For a // mantra:region only the code of the region is printed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Writing the generated file is left to generate, which handles the whole package
+		if !implementStdout {
+			slog.Error("implement only prints the body; pass --stdout, or use mantra generate to write files")
+			os.Exit(1)
+		}
+
+		cfg, err := config.Load(filepath.Dir(implementFile))
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		setupLogging(cfg)
+		buildenv.Set(cfg.BuildEnv())
+		cfg.Plain = true // stdout carries the body
+		cfg.Helpers = false
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		body, err := app.Implement(ctx, implementFile, cfg, implementFunc)
+		if err != nil {
+			slog.Error("implement failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fmt.Println(body)
+	},
+}
+
+func init() {
+	implementCmd.Flags().StringVar(&implementFile, "file", "", "Source file containing the target")
+	implementCmd.Flags().StringVar(&implementFunc, "func", "", "Target to generate (Func or Type.Method)")
+	implementCmd.Flags().BoolVar(&implementStdout, "stdout", false, "Print the generated body instead of writing files")
+	implementCmd.Flags().StringVar(&logLevel, "log-level", "", "Override log level (error, warn, info, debug, trace)")
+	_ = implementCmd.MarkFlagRequired("file")
+	_ = implementCmd.MarkFlagRequired("func")
+	rootCmd.AddCommand(implementCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/config"
+)
+
+var lintModel bool
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [package-dir]",
+	Short: "Flag ambiguous instructions before generating",
+	Long: `Check the instruction of every target in a package for vague wording, error results
+the instruction says nothing about, and identifiers that do not resolve in the package.
+With --model, the model also reviews each instruction (one request per target).
+
+Exits with status 1 when any instruction is flagged.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 0 {
+			pkgDir = args[0]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		setupLogging(cfg)
+		buildenv.Set(cfg.BuildEnv())
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		useModel := lintModel || (cfg.Lint != nil && cfg.Lint.Model)
+		results, err := app.Lint(ctx, pkgDir, cfg, useModel)
+		if err != nil {
+			slog.Error("lint failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		flagged := 0
+		for _, result := range results {
+			if len(result.Issues) == 0 {
+				continue
+			}
+			flagged++
+			fmt.Printf("%s (%s)\n", result.Target.GetDisplayName(), result.Target.FilePath)
+			for _, issue := range result.Issues {
+				fmt.Printf("  %s\n", issue)
+			}
+		}
+		if flagged > 0 {
+			fmt.Printf("%d of %d instructions flagged\n", flagged, len(results))
+			os.Exit(1)
+		}
+		fmt.Printf("%d instructions, no issues\n", len(results))
+	},
+}
+
+func init() {
+	lintCmd.Flags().BoolVar(&lintModel, "model", false, "Also have the model review each instruction")
+	lintCmd.Flags().StringVar(&logLevel, "log-level", "", "Override log level (error, warn, info, debug, trace)")
+	rootCmd.AddCommand(lintCmd)
+}
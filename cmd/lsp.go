@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/lsp"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server offering generation as a code action",
+	Long: `Serve the language server protocol over stdin and stdout. On a mantra comment, or inside
+a target whose body is still panic("not implemented"), the server offers a
+"Generate implementation with mantra" code action. It runs both phases for the target
+and replaces the body in the editor with a workspace edit.
+
+The package is read from disk, so the file must be saved first. Logs go to stderr.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Configuration is loaded per target, as the workspace may hold several projects
+		implement := func(ctx context.Context, filePath, name string) (string, error) {
+			cfg, err := config.Load(filepath.Dir(filePath))
+			if err != nil {
+				return "", err
+			}
+			buildenv.Set(cfg.BuildEnv())
+			cfg.Plain = true // stdout carries the protocol
+			cfg.Helpers = false
+			return app.Implement(ctx, filePath, cfg, name)
+		}
+
+		if err := lsp.NewServer(os.Stdin, os.Stdout, implement).Run(ctx); err != nil {
+			slog.Error("language server failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/config"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [package-dir]",
+	Short: "Restore generated files to their state before the last run",
+	Long: `Restore every generated file in the configured output directory from the
+.bak copy written before the last generate run touched it, remove the files that run
+created, then remove the backups and markers. Files the last run did not touch are
+left as they are.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 0 {
+			pkgDir = args[0]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		restored, removed, err := codegen.Rollback(cfg.Dest)
+		for _, path := range restored {
+			fmt.Printf("Restored: %s\n", filepath.Base(path))
+		}
+		for _, path := range removed {
+			fmt.Printf("Removed: %s\n", filepath.Base(path))
+		}
+		if err != nil {
+			slog.Error("rollback failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if len(restored) == 0 && len(removed) == 0 {
+			fmt.Println("No backups found")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/app"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/config"
+)
+
+var stabilityOptions = app.StabilityOptions{Sample: 5, Runs: 3}
+
+var stabilityCmd = &cobra.Command{
+	Use:   "stability [package-dir]",
+	Short: "Report how often regenerating up-to-date targets changes them",
+	Long: `Regenerate a sample of up-to-date targets several times and compare each result with
+the accepted implementation, ignoring indentation and blank lines. Use it to judge
+how stable a model and provider are before regenerating on CI.
+
+Nothing is written; every run costs the same as generating the sampled targets.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 0 {
+			pkgDir = args[0]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		setupLogging(cfg)
+		buildenv.Set(cfg.BuildEnv())
+		cfg.Plain = true // The report follows the runs; a TUI per run would clear it
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		report, err := app.Stability(ctx, pkgDir, cfg, stabilityOptions)
+		if err != nil {
+			slog.Error("stability check failed", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if len(report.Targets) == 0 {
+			fmt.Println("No up-to-date targets to regenerate")
+			return
+		}
+
+		var runs, changed, failed int
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Target\tRuns\tChanged\tFailed\tDistinct\tLines")
+		for _, t := range report.Targets {
+			var added, removed int
+			for _, run := range t.Runs {
+				added += run.Added
+				removed += run.Removed
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t+%d -%d\n", t.Target.GetDisplayName(), len(t.Runs), t.Changed(), t.Failed(), t.Distinct(), added, removed)
+			runs += len(t.Runs)
+			changed += t.Changed()
+			failed += t.Failed()
+		}
+		w.Flush()
+
+		fmt.Println()
+		fmt.Printf("%d of %d targets sampled, %d runs each (seed %d)\n", len(report.Targets), report.Current, stabilityOptions.Runs, stabilityOptions.Seed)
+		if succeeded := runs - failed; succeeded > 0 {
+			fmt.Printf("%d of %d successful runs (%.0f%%) differed from the accepted implementation, %d failed\n",
+				changed, succeeded, 100*float64(changed)/float64(succeeded), failed)
+		} else {
+			fmt.Printf("All %d runs failed\n", runs)
+		}
+	},
+}
+
+func init() {
+	stabilityCmd.Flags().IntVar(&stabilityOptions.Sample, "sample", stabilityOptions.Sample, "Up-to-date targets to regenerate (0 for all)")
+	stabilityCmd.Flags().IntVar(&stabilityOptions.Runs, "runs", stabilityOptions.Runs, "Generations per target")
+	stabilityCmd.Flags().Uint64Var(&stabilityOptions.Seed, "seed", 0, "Seed picking the sample")
+	stabilityCmd.Flags().StringVar(&logLevel, "log-level", "", "Override log level (error, warn, info, debug, trace)")
+	rootCmd.AddCommand(stabilityCmd)
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/stats"
+)
+
+var (
+	statsSince   time.Duration
+	statsModel   string
+	statsPackage bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [package-dir]",
+	Short: "Summarize tool use and rounds across recorded runs",
+	Long: `Summarize the statistics recorded by generate when [stats] enabled = true: how many
+rounds targets take, which tools the model calls, how often each call returns an
+error, and the phases targets fail in. Statistics stay on this machine.
+
+Use it to find tools whose descriptions confuse the model or prompts that need many
+rounds.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pkgDir := "."
+		if len(args) > 0 {
+			pkgDir = args[0]
+		}
+
+		cfg, err := config.Load(pkgDir)
+		if err != nil {
+			slog.Error("failed to load configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		setupLogging(cfg)
+
+		path := cfg.StatsFile(pkgDir)
+		records, err := stats.Read(path)
+		if err != nil {
+			slog.Error("failed to read statistics", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		filter := stats.Filter{Model: statsModel}
+		if statsSince > 0 {
+			filter.Since = time.Now().Add(-statsSince)
+		}
+		if statsPackage {
+			if abs, err := filepath.Abs(pkgDir); err == nil {
+				filter.Package = filepath.Base(abs)
+			}
+		}
+		summary := stats.Summarize(records, filter)
+		if summary.Targets == 0 {
+			fmt.Printf("No statistics recorded in %s\n", path)
+			if cfg.Stats == nil || !cfg.Stats.Enabled {
+				fmt.Println("Set [stats] enabled = true in mantra.toml to record them.")
+			}
+			return
+		}
+
+		fmt.Printf("%d runs, %d targets, %d succeeded\n", summary.Runs, summary.Targets, summary.Succeeded)
+		fmt.Printf("Rounds per target: %.1f average, %d at most\n", summary.AverageRounds(), summary.MaxRounds)
+
+		if len(summary.Tools) > 0 {
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Tool\tCalls\tPer target\tUsed by\tErrors\tError rate")
+			for _, t := range summary.Tools {
+				fmt.Fprintf(w, "%s\t%d\t%.1f\t%d%%\t%d\t%.0f%%\n", t.Name, t.Calls,
+					float64(t.Calls)/float64(summary.Targets),
+					100*t.Targets/summary.Targets,
+					t.Errors, 100*t.ErrorRate())
+			}
+			w.Flush()
+		}
+
+		if len(summary.Failures) > 0 {
+			phases := make([]string, 0, len(summary.Failures))
+			for phase, n := range summary.Failures {
+				phases = append(phases, fmt.Sprintf("%s %d", phase, n))
+			}
+			sort.Strings(phases)
+			fmt.Println()
+			fmt.Printf("Failures by phase: %s\n", strings.Join(phases, ", "))
+		}
+	},
+}
+
+func init() {
+	statsCmd.Flags().DurationVar(&statsSince, "since", 0, "Only runs started within this duration (e.g. 168h)")
+	statsCmd.Flags().StringVar(&statsModel, "model", "", "Only runs with this model")
+	statsCmd.Flags().BoolVar(&statsPackage, "package", false, "Only runs of this package")
+	statsCmd.Flags().StringVar(&logLevel, "log-level", "", "Override log level (error, warn, info, debug, trace)")
+	rootCmd.AddCommand(statsCmd)
+}
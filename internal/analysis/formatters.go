@@ -33,13 +33,26 @@ func ExtractReferencedTypesFromDefinition(typeDef string) map[string]bool {
 	lines := strings.Split(typeDef, "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "type ") {
+		if line == "" || strings.HasPrefix(line, "//") {
 			continue
 		}
 
-		// Look for field definitions: "fieldName TypeName"
+		// Single-line definitions reference the type they are declared as:
+		// "type Name = Other" or "type Name []Other"
+		if strings.HasPrefix(line, "type ") {
+			parts := strings.Fields(line)
+			if len(parts) >= 3 && !strings.HasSuffix(line, "{") {
+				typeName := CleanTypeName(parts[len(parts)-1])
+				if typeName != "" && !IsBuiltinType(typeName) {
+					types[typeName] = true
+				}
+			}
+			continue
+		}
+
+		// Look for field definitions: "fieldName TypeName", or "TypeName" for embedded fields
 		parts := strings.Fields(line)
-		if len(parts) >= 2 {
+		if len(parts) >= 1 {
 			// Last part is likely the type
 			typeName := CleanTypeName(parts[len(parts)-1])
 			if typeName != "" && !IsBuiltinType(typeName) && !strings.Contains(typeName, "{") && !strings.Contains(typeName, "}") {
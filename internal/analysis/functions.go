@@ -6,78 +6,75 @@ import (
 	"strings"
 )
 
-// FormatFuncType formats a function type in a readable way
+// FormatFuncType formats a function type in a readable way. Parameter and result names
+// are dropped, but each name still contributes its type
 func FormatFuncType(fn *ast.FuncType) string {
-	var result strings.Builder
-	result.WriteString("(")
+	result := "(" + strings.Join(fieldTypes(fn.Params), ", ") + ")"
 
-	// Parameters
-	if fn.Params != nil {
-		for i, field := range fn.Params.List {
-			if i > 0 {
-				result.WriteString(", ")
-			}
-			result.WriteString(ExtractTypeString(field.Type))
-		}
+	results := fieldTypes(fn.Results)
+	switch len(results) {
+	case 0:
+	case 1:
+		result += " " + results[0]
+	default:
+		result += " (" + strings.Join(results, ", ") + ")"
 	}
-
-	result.WriteString(")")
-
-	// Results
-	if fn.Results != nil && len(fn.Results.List) > 0 {
-		result.WriteString(" ")
-		if len(fn.Results.List) > 1 {
-			result.WriteString("(")
-		}
-		for i, field := range fn.Results.List {
-			if i > 0 {
-				result.WriteString(", ")
-			}
-			result.WriteString(ExtractTypeString(field.Type))
-		}
-		if len(fn.Results.List) > 1 {
-			result.WriteString(")")
-		}
-	}
-
-	return result.String()
+	return result
 }
 
-// BuildFunctionSignature builds a function signature string
+// BuildFunctionSignature builds a function signature string with parameter and result names
 func BuildFunctionSignature(name string, funcType *ast.FuncType) string {
 	var parts []string
 
 	// Parameters
-	if funcType.Params != nil {
-		var params []string
-		for _, param := range funcType.Params.List {
-			paramType := ExtractTypeString(param.Type)
-			if len(param.Names) == 0 {
-				params = append(params, paramType)
-			} else {
-				for _, paramName := range param.Names {
-					params = append(params, fmt.Sprintf("%s %s", paramName.Name, paramType))
-				}
-			}
-		}
-		parts = append(parts, fmt.Sprintf("(%s)", strings.Join(params, ", ")))
+	parts = append(parts, fmt.Sprintf("(%s)", strings.Join(namedFields(funcType.Params), ", ")))
+
+	// Results; named results always need parentheses
+	results := namedFields(funcType.Results)
+	named := funcType.Results != nil && len(funcType.Results.List) > 0 && len(funcType.Results.List[0].Names) > 0
+	if len(results) == 1 && !named {
+		parts = append(parts, results[0])
+	} else if len(results) > 0 {
+		parts = append(parts, fmt.Sprintf("(%s)", strings.Join(results, ", ")))
 	}
 
-	// Results
-	if funcType.Results != nil {
-		var results []string
-		for _, result := range funcType.Results.List {
-			results = append(results, ExtractTypeString(result.Type))
-		}
+	return fmt.Sprintf("%s%s", name, strings.Join(parts, " "))
+}
 
-		if len(results) == 1 {
-			parts = append(parts, results[0])
-		} else if len(results) > 1 {
-			parts = append(parts, fmt.Sprintf("(%s)", strings.Join(results, ", ")))
+// fieldTypes returns the type of every parameter or result in a field list, repeating the
+// type of fields that declare several names (a, b int)
+func fieldTypes(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var types []string
+	for _, field := range fields.List {
+		fieldType := ExtractTypeString(field.Type)
+		for range max(len(field.Names), 1) {
+			types = append(types, fieldType)
 		}
 	}
+	return types
+}
 
-	return fmt.Sprintf("%s%s", name, strings.Join(parts, " "))
+// namedFields returns every parameter or result in a field list as "name type", or just
+// the type when unnamed
+func namedFields(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var named []string
+	for _, field := range fields.List {
+		fieldType := ExtractTypeString(field.Type)
+		if len(field.Names) == 0 {
+			named = append(named, fieldType)
+			continue
+		}
+		for _, name := range field.Names {
+			named = append(named, name.Name+" "+fieldType)
+		}
+	}
+	return named
 }
 
 // BuildFunctionSignatureFromDecl builds a function signature from FuncDecl
@@ -0,0 +1,86 @@
+package analysis
+
+import "go/ast"
+
+// FuncVarDecl returns a function declaration standing for a function variable
+// (var Name = func(...) ... { ... }), so it can be handled like a function: it is named
+// after the variable, has the literal's parameters, results and body, and spans the whole
+// var declaration. It returns nil unless decl is an ungrouped var declaration of a single
+// variable initialized with a function literal.
+//
+// The declaration shares the literal's body; SetFuncBody replaces both
+func FuncVarDecl(decl *ast.GenDecl) *ast.FuncDecl {
+	spec, lit := funcVar(decl)
+	if lit == nil {
+		return nil
+	}
+
+	doc := decl.Doc
+	if doc == nil {
+		doc = spec.Doc
+	}
+	return &ast.FuncDecl{
+		Doc:  doc,
+		Name: spec.Names[0],
+		Type: &ast.FuncType{
+			Func:       decl.TokPos, // Start at "var" so the declaration covers the whole statement
+			TypeParams: lit.Type.TypeParams,
+			Params:     lit.Type.Params,
+			Results:    lit.Type.Results,
+		},
+		Body: lit.Body,
+	}
+}
+
+// funcVar returns the spec and function literal of a function variable declaration
+func funcVar(decl *ast.GenDecl) (*ast.ValueSpec, *ast.FuncLit) {
+	if decl.Lparen.IsValid() || len(decl.Specs) != 1 {
+		return nil, nil
+	}
+	spec, ok := decl.Specs[0].(*ast.ValueSpec)
+	if !ok || len(spec.Names) != 1 || len(spec.Values) != 1 {
+		return nil, nil
+	}
+	lit, ok := spec.Values[0].(*ast.FuncLit)
+	if !ok || lit.Body == nil {
+		return nil, nil
+	}
+	return spec, lit
+}
+
+// FuncDecls returns the top-level functions and methods of a file in source order, with
+// function variables as declarations from FuncVarDecl
+func FuncDecls(file *ast.File) []*ast.FuncDecl {
+	var funcs []*ast.FuncDecl
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			funcs = append(funcs, d)
+		case *ast.GenDecl:
+			if fn := FuncVarDecl(d); fn != nil {
+				funcs = append(funcs, fn)
+			}
+		}
+	}
+	return funcs
+}
+
+// SetFuncBody replaces the body of fn, a declaration of file. For function variables the
+// body of the function literal is replaced as well
+func SetFuncBody(file *ast.File, fn *ast.FuncDecl, body *ast.BlockStmt) {
+	old := fn.Body
+	fn.Body = body
+	if old == nil {
+		return
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		if _, lit := funcVar(gen); lit != nil && lit.Body == old {
+			lit.Body = body
+			return
+		}
+	}
+}
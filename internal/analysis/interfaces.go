@@ -10,7 +10,8 @@ type MethodInfo struct {
 	Name      string `json:"name"`
 	Signature string `json:"signature"`
 	Receiver  string `json:"receiver,omitempty"`
-	Doc       string `json:"doc,omitempty"` // Documentation comment
+	Promoted  string `json:"promoted_from,omitempty"` // Type of the embedded field the method is promoted from
+	Doc       string `json:"doc,omitempty"`           // Documentation comment
 }
 
 // FormatInterfaceType formats an interface type in a readable way
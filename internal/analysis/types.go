@@ -12,11 +12,20 @@ func ExtractTypeString(expr ast.Expr) string {
 		return t.Name
 	case *ast.StarExpr:
 		return "*" + ExtractTypeString(t.X)
+	case *ast.ParenExpr:
+		return ExtractTypeString(t.X)
 	case *ast.ArrayType:
-		if t.Len == nil {
+		// Checksums before version 3 rendered arrays as slices; checksum.legacyType keeps that
+		switch length := t.Len.(type) {
+		case nil:
 			return "[]" + ExtractTypeString(t.Elt)
+		case *ast.BasicLit:
+			return "[" + length.Value + "]" + ExtractTypeString(t.Elt)
+		case *ast.Ellipsis:
+			return "[...]" + ExtractTypeString(t.Elt)
+		default:
+			return "[" + ExtractTypeString(length) + "]" + ExtractTypeString(t.Elt)
 		}
-		return "[]" + ExtractTypeString(t.Elt) // Simplified for now
 	case *ast.MapType:
 		return "map[" + ExtractTypeString(t.Key) + "]" + ExtractTypeString(t.Value)
 	case *ast.SelectorExpr:
@@ -26,6 +35,9 @@ func ExtractTypeString(expr ast.Expr) string {
 		return "qualified.Type"
 	case *ast.ChanType:
 		return "chan " + ExtractTypeString(t.Value)
+	case *ast.Ellipsis:
+		// Checksums before version 3 rendered variadics as any; checksum.legacyContent keeps that
+		return "..." + ExtractTypeString(t.Elt)
 	case *ast.InterfaceType:
 		return "any"
 	case *ast.FuncType:
@@ -35,9 +47,66 @@ func ExtractTypeString(expr ast.Expr) string {
 	}
 }
 
+// ReceiverTypeName returns the name of the type a method receiver belongs to, with
+// pointers, parentheses and type parameters removed: (*List[T]) becomes List
+func ReceiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return ReceiverTypeName(t.X)
+	case *ast.ParenExpr:
+		return ReceiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return ReceiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return ReceiverTypeName(t.X)
+	}
+	return ""
+}
+
+// TypeAliases maps each local alias declared in files (type A = B) to the non-alias
+// type it finally names, so receivers written with either name can be compared.
+// Aliases of types from other packages are left out
+func TypeAliases(files ...*ast.File) map[string]string {
+	direct := make(map[string]string)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok || !ts.Assign.IsValid() {
+					continue
+				}
+				if name := ReceiverTypeName(ts.Type); name != "" {
+					direct[ts.Name.Name] = name
+				}
+			}
+		}
+	}
+
+	aliases := make(map[string]string, len(direct))
+	for alias, name := range direct {
+		// Follow chains of aliases; the step limit guards against invalid cycles
+		for range len(direct) {
+			next, ok := direct[name]
+			if !ok {
+				break
+			}
+			name = next
+		}
+		aliases[alias] = name
+	}
+	return aliases
+}
+
 // CleanTypeName removes pointers, slices, and other modifiers from type name
 func CleanTypeName(typeStr string) string {
 	// Remove common prefixes
+	typeStr = strings.TrimPrefix(typeStr, "...")
 	typeStr = strings.TrimPrefix(typeStr, "*")
 	typeStr = strings.TrimPrefix(typeStr, "[]")
 	typeStr = strings.TrimPrefix(typeStr, "chan ")
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/detector"
+)
+
+// Check returns the targets of the package whose generated code is missing or stale, as
+// generate would detect them
+func Check(pkgDir string, cfg *config.Config) ([]*detector.TargetStatus, error) {
+	results, err := detector.DetectPackageTargetsWithOptions(pkgDir, cfg.Dest, detectOptions(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect targets: %w", err)
+	}
+
+	var pending []*detector.TargetStatus
+	for _, result := range results {
+		for _, status := range result.Statuses {
+			if status.Status != detector.StatusCurrent {
+				pending = append(pending, status)
+			}
+		}
+	}
+	return pending, nil
+}
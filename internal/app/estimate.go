@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/rail44/mantra/internal/coder"
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/detector"
+	"github.com/rail44/mantra/internal/estimate"
+	"github.com/rail44/mantra/internal/llm"
+)
+
+// Estimate predicts the usage of generating the stale targets of a package, building their
+// prompts without calling the provider or writing any files
+func Estimate(ctx context.Context, pkgDir string, cfg *config.Config, assumptions estimate.Assumptions) (*estimate.Estimate, error) {
+	var opts detector.Options
+	if cfg.Detect != nil {
+		opts.SignatureImpact = cfg.Detect.SignatureImpact
+	}
+	results, err := detector.DetectPackageTargetsWithOptions(pkgDir, cfg.Dest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect targets: %w", err)
+	}
+
+	var targets []coder.TargetContext
+	for _, result := range results {
+		var content []byte
+		for _, status := range result.Statuses {
+			if status.Status == detector.StatusCurrent {
+				continue
+			}
+			if content == nil {
+				if content, err = os.ReadFile(result.FileInfo.FilePath); err != nil {
+					return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(result.FileInfo.FilePath), err)
+				}
+			}
+			targets = append(targets, coder.TargetContext{
+				Target:          status.Target,
+				FileContent:     string(content),
+				FileInfo:        result.FileInfo,
+				Index:           len(targets) + 1,
+				PreviousFailure: status.PreviousFailure,
+			})
+		}
+	}
+
+	parallelCoder, err := coder.NewParallelCoder(&llm.ClientConfig{}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	estimated, err := parallelCoder.EstimateTargets(ctx, targets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build prompts: %w", err)
+	}
+
+	var pricing *estimate.Pricing
+	if cfg.Pricing != nil {
+		price := cfg.Pricing.PriceFor(cfg.Model)
+		pricing = &estimate.Pricing{Input: price.Input, Output: price.Output}
+	}
+	var limits *estimate.RateLimits
+	if cfg.RateLimit != nil {
+		host := cfg.URL
+		if u, err := url.Parse(cfg.URL); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		l := cfg.RateLimit.LimitsFor(host)
+		limits = &estimate.RateLimits{RequestsPerMinute: l.RequestsPerMinute, TokensPerMinute: l.TokensPerMinute}
+	}
+
+	return estimate.Compute(estimated, assumptions, pricing, limits), nil
+}
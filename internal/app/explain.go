@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rail44/mantra/internal/coder"
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/detector"
+	"github.com/rail44/mantra/internal/history"
+	"github.com/rail44/mantra/internal/llm"
+)
+
+// Explanation is everything generating one target would send to the model
+type Explanation struct {
+	*coder.Explanation
+	Status *detector.TargetStatus
+}
+
+// Explain builds the static context and phase prompts of the target named name without
+// calling the provider or writing any files
+func Explain(ctx context.Context, pkgDir string, cfg *config.Config, name string) (*Explanation, error) {
+	var opts detector.Options
+	if cfg.Detect != nil {
+		opts.SignatureImpact = cfg.Detect.SignatureImpact
+	}
+	results, err := detector.DetectPackageTargetsWithOptions(pkgDir, cfg.Dest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect targets: %w", err)
+	}
+	result, status, err := findTarget(results, name)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(result.FileInfo.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %w", err)
+	}
+	parallelCoder, err := coder.NewParallelCoder(&llm.ClientConfig{}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	explanation, err := parallelCoder.ExplainTarget(ctx, coder.TargetContext{
+		Target:          status.Target,
+		FileContent:     string(content),
+		FileInfo:        result.FileInfo,
+		Index:           1,
+		PreviousFailure: status.PreviousFailure,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build prompts: %w", err)
+	}
+	return &Explanation{Explanation: explanation, Status: status}, nil
+}
+
+// findTarget finds the target named name: "Func", "Type.Method" or "(*Type).Method"
+func findTarget(results []*detector.FileDetectionResult, name string) (*detector.FileDetectionResult, *detector.TargetStatus, error) {
+	key := strings.NewReplacer("(", "", ")", "", "*", "").Replace(name)
+
+	var matchedResult *detector.FileDetectionResult
+	var matches []*detector.TargetStatus
+	for _, result := range results {
+		for _, status := range result.Statuses {
+			if history.TargetKey(status.Target) == key || (!strings.Contains(key, ".") && status.Target.Name == key) {
+				matchedResult = result
+				matches = append(matches, status)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil, fmt.Errorf("no mantra target named %s", name)
+	case 1:
+		return matchedResult, matches[0], nil
+	default:
+		keys := make([]string, len(matches))
+		for i, m := range matches {
+			keys[i] = history.TargetKey(m.Target)
+		}
+		return nil, nil, fmt.Errorf("%s is ambiguous, use one of: %s", name, strings.Join(keys, ", "))
+	}
+}
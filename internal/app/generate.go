@@ -3,24 +3,43 @@ package app
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"log/slog"
 
+	"github.com/rail44/mantra/internal/checksum"
 	"github.com/rail44/mantra/internal/codegen"
 	"github.com/rail44/mantra/internal/coder"
 	"github.com/rail44/mantra/internal/config"
 	"github.com/rail44/mantra/internal/detector"
+	"github.com/rail44/mantra/internal/diff"
+	"github.com/rail44/mantra/internal/git"
+	"github.com/rail44/mantra/internal/history"
 	"github.com/rail44/mantra/internal/llm"
+	"github.com/rail44/mantra/internal/notify"
 	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/progress"
+	"github.com/rail44/mantra/internal/ratelimit"
+	"github.com/rail44/mantra/internal/report"
+	"github.com/rail44/mantra/internal/stats"
+	"github.com/rail44/mantra/internal/status"
 )
 
 // GenerateApp handles the generate command logic
 type GenerateApp struct {
-	logger *slog.Logger
+	logger   *slog.Logger
+	report   *report.Report   // Summary of the current run
+	notifier *notify.Notifier // Optional completion/failure notifications
+	status   *status.Tracker  // Progress served by --status-addr or written to --progress-file (nil if disabled)
+	progress *progress.Stream // Progress events written by --progress jsonl (nil if disabled)
+	history  *history.Store   // Accepted implementations kept for mantra history (nil if disabled)
+	stats    string           // File statistics of the run are appended to (empty if disabled)
+	model    string           // Model recorded with accepted implementations
 }
 
 // NewGenerateApp creates a new generate app
@@ -31,12 +50,74 @@ func NewGenerateApp() *GenerateApp {
 }
 
 // Run executes the generate command
-func (a *GenerateApp) Run(ctx context.Context, pkgDir string, cfg *config.Config) error {
+func (a *GenerateApp) Run(ctx context.Context, pkgDir string, cfg *config.Config) (err error) {
+	a.report = report.New(filepath.Base(pkgDir), time.Now())
+	a.notifier = notify.New(cfg.Notify, a.logger)
+	a.history = cfg.HistoryStore(pkgDir)
+	if cfg.Stats != nil && cfg.Stats.Enabled {
+		a.stats = cfg.StatsFile(pkgDir)
+	}
+	a.model = cfg.Model
+
+	// The summary is printed and notified however the run ends; generated files go to git
+	// only when it succeeds
+	defer func() {
+		a.finishReport(cfg)
+		if err != nil {
+			a.notifier.NotifyFailed(ctx, a.report, err)
+			return
+		}
+		a.applyGit(ctx, cfg)
+		a.notifier.Notify(ctx, notify.EventCompleted, a.report)
+	}()
+
+	// Stream machine-readable progress to stdout; human-oriented logs stay on stderr
+	if cfg.Progress == config.ProgressJSONL {
+		a.progress = progress.NewStream(os.Stdout)
+		a.progress.RunStarted(a.report.Package)
+		defer func() {
+			a.progress.RunFinished(a.report.Package, a.report.Succeeded(), a.report.Failed(), err)
+		}()
+	}
+
+	// Track progress for runs without a terminal: served over HTTP until the run ends, or
+	// kept in a file editors poll, which ends with the outcome of the run
+	if cfg.StatusAddr != "" || cfg.ProgressFile != "" {
+		a.status = status.NewTracker(filepath.Base(pkgDir))
+		defer func() { a.status.Finish(err) }()
+	}
+	if cfg.ProgressFile != "" {
+		if err := a.status.WriteFile(cfg.ProgressFile); err != nil {
+			return err
+		}
+	}
+	if cfg.StatusAddr != "" {
+		serveCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		addr, err := a.status.Serve(serveCtx, cfg.StatusAddr)
+		if err != nil {
+			return err
+		}
+		a.logger.Info(fmt.Sprintf("Serving status at http://%s", addr))
+	}
+
 	// Detect targets
-	results, err := a.detectTargets(pkgDir, cfg.Dest)
+	results, err := a.detectTargets(pkgDir, cfg)
 	if err != nil {
 		return err
 	}
+	if cfg.PerTargetFiles {
+		fileInfos := make([]*parser.FileInfo, len(results))
+		for i, result := range results {
+			fileInfos[i] = result.FileInfo
+		}
+		if err := codegen.CheckTargetFileNames(fileInfos); err != nil {
+			return err
+		}
+	}
+	if cfg.Diff != nil {
+		results = a.restrictToDiff(results, cfg.Diff)
+	}
 
 	// Check if processing is needed
 	if !a.needsProcessing(results) {
@@ -45,20 +126,59 @@ func (a *GenerateApp) Run(ctx context.Context, pkgDir string, cfg *config.Config
 	}
 
 	// Setup AI client configuration and generator
-	clientConfig, gen, err := a.setupAIClient(cfg, pkgDir)
+	clientConfig, gen, err := a.setupAIClient(ctx, cfg, pkgDir)
 	if err != nil {
 		return err
 	}
 
 	// Process all targets
+	a.status.SetState(status.StateGenerating)
 	if err := a.processAllTargets(ctx, results, clientConfig, gen, cfg); err != nil {
 		return err
 	}
-
 	a.logger.Info("package generation complete")
 	return nil
 }
 
+// applyGit stages and optionally commits the files written in this run
+func (a *GenerateApp) applyGit(ctx context.Context, cfg *config.Config) {
+	integrator := git.New(cfg.Git, cfg.Dest)
+	if integrator == nil {
+		return
+	}
+
+	message, err := integrator.Apply(ctx, a.report)
+	if err != nil {
+		a.logger.Error("git integration failed", slog.String("error", err.Error()))
+		return
+	}
+	switch {
+	case message != "":
+		a.logger.Info(fmt.Sprintf("Committed generated files: %s", strings.SplitN(message, "\n", 2)[0]))
+	case cfg.Git.Commit:
+		a.logger.Info("Generated files unchanged, nothing to commit")
+	default:
+		a.logger.Info("Staged generated files")
+	}
+}
+
+// finishReport prints the run summary and writes it to the summary file if configured
+func (a *GenerateApp) finishReport(cfg *config.Config) {
+	a.report.Finish()
+	a.report.WriteText(os.Stderr)
+
+	if cfg.SummaryFile == "" {
+		return
+	}
+	if err := a.report.WriteMarkdownFile(cfg.SummaryFile); err != nil {
+		a.logger.Error("failed to write summary file",
+			slog.String("file", cfg.SummaryFile),
+			slog.String("error", err.Error()))
+		return
+	}
+	a.logger.Info(fmt.Sprintf("Summary written: %s", cfg.SummaryFile))
+}
+
 // needsProcessing checks if any targets need generation or files need copying
 func (a *GenerateApp) needsProcessing(results []*detector.FileDetectionResult) bool {
 	for _, result := range results {
@@ -77,9 +197,10 @@ func (a *GenerateApp) needsProcessing(results []*detector.FileDetectionResult) b
 }
 
 // detectTargets detects targets and provides logging summary
-func (a *GenerateApp) detectTargets(pkgDir, destDir string) ([]*detector.FileDetectionResult, error) {
+func (a *GenerateApp) detectTargets(pkgDir string, cfg *config.Config) ([]*detector.FileDetectionResult, error) {
 	a.logger.Info("detecting targets in package", slog.String("package", filepath.Base(pkgDir)))
-	results, err := detector.DetectPackageTargets(pkgDir, destDir)
+
+	results, err := detector.DetectPackageTargetsWithOptions(pkgDir, cfg.Dest, detectOptions(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect targets: %w", err)
 	}
@@ -101,11 +222,18 @@ func (a *GenerateApp) detectTargets(pkgDir, destDir string) ([]*detector.FileDet
 					slog.String("file", filepath.Base(status.Target.FilePath)))
 			case detector.StatusOutdated:
 				outdated++
-				a.logger.Info("outdated target found",
-					slog.String("function", status.Target.GetDisplayName()),
-					slog.String("file", filepath.Base(status.Target.FilePath)),
-					slog.String("old_checksum", status.ExistingChecksum),
-					slog.String("new_checksum", status.CurrentChecksum))
+				if status.Reason != "" {
+					a.logger.Info("outdated target found",
+						slog.String("function", status.Target.GetDisplayName()),
+						slog.String("file", filepath.Base(status.Target.FilePath)),
+						slog.String("reason", status.Reason))
+				} else {
+					a.logger.Info("outdated target found",
+						slog.String("function", status.Target.GetDisplayName()),
+						slog.String("file", filepath.Base(status.Target.FilePath)),
+						slog.String("old_checksum", status.ExistingChecksum),
+						slog.String("new_checksum", status.CurrentChecksum))
+				}
 			case detector.StatusCurrent:
 				current++
 			}
@@ -127,6 +255,8 @@ func (a *GenerateApp) detectTargets(pkgDir, destDir string) ([]*detector.FileDet
 		summaryParts = append(summaryParts, fmt.Sprintf("%d files without targets", filesWithoutTargets))
 	}
 
+	a.report.UpToDate = current
+
 	summary := fmt.Sprintf("Found: %s", strings.Join(summaryParts, ", "))
 	if ungenerated == 0 && outdated == 0 && filesWithoutTargets == 0 {
 		summary = "All targets up-to-date"
@@ -138,19 +268,62 @@ func (a *GenerateApp) detectTargets(pkgDir, destDir string) ([]*detector.FileDet
 	return results, nil
 }
 
-// setupAIClient initializes AI client configuration and code generator
-func (a *GenerateApp) setupAIClient(cfg *config.Config, pkgDir string) (*llm.ClientConfig, *codegen.Generator, error) {
-	// Initialize AI client configuration
-	clientConfig := &llm.ClientConfig{
-		URL:     cfg.URL,
-		APIKey:  cfg.GetAPIKey(),
-		Model:   cfg.Model,
-		Timeout: 5 * time.Minute,
+// detectOptions returns the optional detection checks enabled in the configuration
+func detectOptions(cfg *config.Config) detector.Options {
+	var opts detector.Options
+	if cfg.Detect != nil {
+		opts.SignatureImpact = cfg.Detect.SignatureImpact
+	}
+	return opts
+}
+
+// restrictToDiff leaves out the files the diff does not touch: files with targets are kept
+// when the diff touches the lines of a target needing generation, and the file's other
+// pending targets are generated with it, as the file is written as a whole
+func (a *GenerateApp) restrictToDiff(results []*detector.FileDetectionResult, d *diff.Diff) []*detector.FileDetectionResult {
+	var kept []*detector.FileDetectionResult
+	skipped := 0
+	for _, result := range results {
+		filePath := result.FileInfo.FilePath
+		if len(result.Statuses) == 0 {
+			if d.TouchesFile(filePath) {
+				kept = append(kept, result)
+			}
+			continue
+		}
+
+		touched := false
+		for _, status := range result.Statuses {
+			if status.Status == detector.StatusCurrent {
+				continue
+			}
+			if start, end := status.Target.Lines(); d.Touches(filePath, start, end) {
+				touched = true
+				break
+			}
+		}
+		if touched {
+			kept = append(kept, result)
+			continue
+		}
+		for _, status := range result.Statuses {
+			if status.Status != detector.StatusCurrent {
+				skipped++
+			}
+		}
+	}
+
+	if skipped > 0 {
+		a.logger.Info(fmt.Sprintf("Skipped %d pending targets outside the diff", skipped))
 	}
+	return kept
+}
 
-	// Set OpenRouter providers if configured
-	if cfg.OpenRouter != nil && len(cfg.OpenRouter.Providers) > 0 {
-		clientConfig.Provider = cfg.OpenRouter.Providers
+// setupAIClient initializes AI client configuration and code generator
+func (a *GenerateApp) setupAIClient(ctx context.Context, cfg *config.Config, pkgDir string) (*llm.ClientConfig, *codegen.Generator, error) {
+	clientConfig, err := newClientConfig(ctx, cfg, a.logger)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Log which provider we're using
@@ -158,44 +331,173 @@ func (a *GenerateApp) setupAIClient(cfg *config.Config, pkgDir string) (*llm.Cli
 		slog.String("url", cfg.URL),
 		slog.String("model", cfg.Model))
 
-	gen := codegen.New(&codegen.Config{
+	return clientConfig, newGenerator(cfg, pkgDir), nil
+}
+
+// newGenerator creates the generator writing the package's generated files
+func newGenerator(cfg *config.Config, pkgDir string) *codegen.Generator {
+	fallback, _ := cfg.Fallback.Templates() // Validated when the config is loaded
+	return codegen.New(&codegen.Config{
 		Dest:          cfg.Dest,
 		PackageName:   cfg.GetPackageName(),
 		SourcePackage: filepath.Base(pkgDir),
+
+		ConvertBlankImports: cfg.ConvertBlankImports,
+		PerTargetFiles:      cfg.PerTargetFiles,
+		Fallback:            fallback,
+		Summary:             cfg.GenerationSummary,
+		Model:               cfg.Model,
 	})
+}
+
+// newClientConfig builds the provider client configuration, resolving the API key
+func newClientConfig(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*llm.ClientConfig, error) {
+	// Resolve the API key only once generation is needed, as commands may prompt for unlock
+	apiKey, err := cfg.ResolveAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize AI client configuration
+	clientConfig := &llm.ClientConfig{
+		URL:     cfg.URL,
+		APIKey:  apiKey,
+		Model:   cfg.Model,
+		Timeout: 5 * time.Minute,
+	}
+
+	// Set extra headers and custom authentication if configured
+	clientConfig.Headers = cfg.RequestHeaders()
+	if cfg.Auth != nil {
+		token := llm.StaticToken(apiKey)
+		if cfg.Auth.TokenCmd != "" {
+			// Validated when the config is loaded
+			refresh, _ := time.ParseDuration(cfg.Auth.RefreshInterval)
+			token = llm.CachedToken(cfg.Auth.FetchToken, refresh)
+		}
+		clientConfig.Auth = &llm.HeaderAuth{Header: cfg.Auth.Header, Scheme: cfg.Auth.AuthScheme(), Token: token}
+	}
+
+	// Set proxy and TLS settings if configured
+	if cfg.HTTP != nil {
+		clientConfig.Transport = &llm.TransportConfig{
+			ProxyURL:           cfg.HTTP.Proxy,
+			CAFile:             cfg.HTTP.CAFile,
+			CertFile:           cfg.HTTP.CertFile,
+			KeyFile:            cfg.HTTP.KeyFile,
+			InsecureSkipVerify: cfg.HTTP.InsecureSkipVerify,
+
+			CompressRequests:    cfg.HTTP.CompressRequests,
+			MaxIdleConnsPerHost: cfg.HTTP.MaxIdleConnsPerHost,
+			MaxConnsPerHost:     cfg.HTTP.MaxConnsPerHost,
+		}
+		// Validated when the config is loaded
+		clientConfig.Transport.IdleConnTimeout, _ = time.ParseDuration(cfg.HTTP.IdleConnTimeout)
+		if cfg.HTTP.InsecureSkipVerify {
+			logger.Warn("TLS certificate verification is disabled for AI provider requests")
+		}
+	}
+
+	// Pace requests to the provider if rate limits are configured
+	if cfg.RateLimit != nil {
+		host := cfg.URL
+		if u, err := url.Parse(cfg.URL); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		limits := cfg.RateLimit.LimitsFor(host)
+		clientConfig.RateLimiter = ratelimit.New(limits.RequestsPerMinute, limits.TokensPerMinute)
+	}
+
+	// Mark system prompts and shared context as cacheable if configured
+	clientConfig.PromptCache = cfg.PromptCache.StyleFor(cfg.Model)
+
+	// Summarize old tool results if configured
+	if cfg.Transcript != nil {
+		clientConfig.KeepRounds = cfg.Transcript.KeepRounds
+	}
+	if cfg.Tools != nil {
+		clientConfig.MaxParallelTools = cfg.Tools.MaxParallel
+	}
 
-	return clientConfig, gen, nil
+	// Set OpenRouter routing preferences if configured
+	if cfg.OpenRouter != nil {
+		prefs := cfg.OpenRouter.PreferencesFor(cfg.Model)
+		clientConfig.Provider = &llm.ProviderSpec{
+			Only:           prefs.Providers,
+			Order:          prefs.Order,
+			Ignore:         prefs.Ignore,
+			AllowFallbacks: prefs.AllowFallbacks,
+			Quantizations:  prefs.Quantizations,
+		}
+	}
+
+	return clientConfig, nil
 }
 
 // processAllTargets processes all files, generating implementations for targets and copying files without targets
 func (a *GenerateApp) processAllTargets(ctx context.Context, results []*detector.FileDetectionResult, clientConfig *llm.ClientConfig, gen *codegen.Generator, cfg *config.Config) error {
-	// Prepare stub files for all targets before generation
-	if err := a.prepareStubFiles(results, gen); err != nil {
+	// Prepare stubs for all targets before generation
+	overlay, err := a.stubOverlay(results, gen)
+	if err != nil {
 		return fmt.Errorf("failed to prepare stub files: %w", err)
 	}
 
 	// Collect targets and copy files without targets
 	targets := a.collectTargets(results, gen)
 
+	// Strict lint holds back flagged targets, which are written as failures
+	targets, refused := a.lintTargets(ctx, targets, clientConfig, cfg)
+
 	// Skip if no targets need generation
-	if len(targets) == 0 {
+	if len(targets) == 0 && len(refused) == 0 {
 		return nil
 	}
 
 	// Create and execute target executor
-	// Now PackageLoader will see the prepared files with correct structure
-	parallelCoder := coder.NewParallelCoder(clientConfig, cfg)
+	// PackageLoader sees the stubs through the overlay with correct structure
+	parallelCoder, err := coder.NewParallelCoder(clientConfig, cfg)
+	if err != nil {
+		return err
+	}
+	parallelCoder.SetOverlay(overlay)
+	parallelCoder.SetStatusTracker(a.status)
+	parallelCoder.SetProgressStream(a.progress)
+	if a.notifier.OnFailure() {
+		parallelCoder.OnResult(a.firstFailureNotifier(ctx))
+	}
 	allResults, err := parallelCoder.ExecuteTargets(ctx, targets)
 	if err != nil {
 		return fmt.Errorf("failed to generate implementations: %w", err)
 	}
+	a.recordStats(allResults)
+	allResults = append(allResults, refused...)
+	a.report.AddResults(allResults)
 
 	// Write generated files
 	return a.writeGeneratedFiles(results, allResults, gen)
 }
 
-// prepareStubFiles prepares stub files for all targets before generation
-func (a *GenerateApp) prepareStubFiles(results []*detector.FileDetectionResult, gen *codegen.Generator) error {
+// firstFailureNotifier returns a result callback that notifies once on the first failed target
+func (a *GenerateApp) firstFailureNotifier(ctx context.Context) func(*parser.GenerationResult) {
+	var once sync.Once
+	return func(result *parser.GenerationResult) {
+		if result.Success {
+			return
+		}
+		once.Do(func() {
+			partial := report.New(a.report.Package, a.report.StartedAt)
+			partial.AddResults([]*parser.GenerationResult{result})
+			partial.Finish()
+			a.notifier.Notify(ctx, notify.EventFirstFailure, partial)
+		})
+	}
+}
+
+// stubOverlay builds stub contents for every file with targets to generate, keyed by the
+// absolute path of the generated file. Package loading sees them through go/packages
+// overlays, so nothing is written to dest until generation finishes
+func (a *GenerateApp) stubOverlay(results []*detector.FileDetectionResult, gen *codegen.Generator) (map[string][]byte, error) {
+	overlay := make(map[string][]byte)
 	for _, result := range results {
 		fileInfo := result.FileInfo
 
@@ -212,18 +514,26 @@ func (a *GenerateApp) prepareStubFiles(results []*detector.FileDetectionResult,
 			}
 		}
 
-		// If there are targets to generate, prepare the stub file
+		// If there are targets to generate, prepare the stub content
 		if len(targetsToGenerate) > 0 {
-			if err := gen.PrepareTargetStubs(fileInfo, targetsToGenerate); err != nil {
+			stubs, err := gen.Stubs(fileInfo, targetsToGenerate)
+			if err != nil {
 				a.logger.Error("failed to prepare stub file",
 					slog.String("file", fileInfo.FilePath),
 					slog.String("error", err.Error()))
-				return err
+				return nil, err
+			}
+			for path, content := range stubs {
+				path, err := filepath.Abs(path)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get absolute path: %w", err)
+				}
+				overlay[path] = content
 			}
 		}
 	}
 
-	return nil
+	return overlay, nil
 }
 
 // collectTargets collects targets that need generation and copies files without targets
@@ -255,10 +565,11 @@ func (a *GenerateApp) collectTargets(results []*detector.FileDetectionResult, ge
 			if status.Status != detector.StatusCurrent {
 				index += 1
 				targets = append(targets, coder.TargetContext{
-					Target:      status.Target,
-					FileContent: string(content),
-					FileInfo:    result.FileInfo,
-					Index:       index,
+					Target:          status.Target,
+					FileContent:     string(content),
+					FileInfo:        result.FileInfo,
+					Index:           index,
+					PreviousFailure: status.PreviousFailure,
 				})
 			}
 		}
@@ -274,6 +585,9 @@ func (a *GenerateApp) copyFileWithoutTargets(fileInfo *parser.FileInfo, gen *cod
 			slog.String("file", fileInfo.FilePath),
 			slog.String("error", err.Error()))
 	} else {
+		for _, path := range gen.WrittenFiles(fileInfo.FilePath) {
+			a.report.AddFile(path)
+		}
 		a.logger.Info(fmt.Sprintf("Copied: %s", filepath.Base(fileInfo.FilePath)))
 	}
 }
@@ -301,7 +615,14 @@ func (a *GenerateApp) writeGeneratedFiles(results []*detector.FileDetectionResul
 					slog.String("file", filePath),
 					slog.String("error", err.Error()))
 			} else {
+				for _, path := range gen.WrittenFiles(filePath) {
+					a.report.AddFile(path)
+				}
+				for _, importPath := range gen.ConvertedBlankImports(filePath) {
+					a.report.AddBlankImportConversion(gen.OutputPath(filePath), importPath)
+				}
 				a.logger.Info(fmt.Sprintf("Generated: %s", filepath.Base(filePath)))
+				a.recordHistory(fileResults[filePath])
 			}
 		}
 	}
@@ -309,6 +630,44 @@ func (a *GenerateApp) writeGeneratedFiles(results []*detector.FileDetectionResul
 	return nil
 }
 
+// recordStats appends the tool use and rounds of the generated targets to the statistics
+// file when enabled
+func (a *GenerateApp) recordStats(results []*parser.GenerationResult) {
+	if a.stats == "" {
+		return
+	}
+	records := stats.NewRecords(a.report.StartedAt, a.report.Package, a.model, results)
+	if err := stats.Append(a.stats, records); err != nil {
+		a.logger.Warn("failed to record statistics", slog.String("error", err.Error()))
+	}
+}
+
+// recordHistory keeps the implementations accepted in this run in the history, as written
+// to the generated file: the body and helpers after helpers were renamed
+func (a *GenerateApp) recordHistory(results []*parser.GenerationResult) {
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		model := a.model
+		if result.Rule != "" {
+			model = "rule: " + result.Rule
+		}
+		entry := history.Entry{
+			Time:     time.Now(),
+			Model:    model,
+			Checksum: checksum.Calculate(result.Target),
+			Body:     result.Target.Implementation,
+			Helpers:  result.Target.Helpers,
+		}
+		if err := a.history.Record(result.Target, entry); err != nil {
+			a.logger.Warn("failed to record history",
+				slog.String("target", history.TargetKey(result.Target)),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
 // groupResultsByFile groups generation results by their source file
 func (a *GenerateApp) groupResultsByFile(allResults []*parser.GenerationResult) map[string][]*parser.GenerationResult {
 	fileResults := make(map[string][]*parser.GenerationResult)
@@ -335,7 +694,9 @@ func (a *GenerateApp) collectFileGenerationResults(detectionResult *detector.Fil
 				Target:         status.Target,
 				Success:        true,
 				Implementation: status.ExistingImpl,
+				Helpers:        status.ExistingHelpers,
 				Duration:       0, // No generation time for existing implementations
+				Reused:         true,
 			})
 		}
 	}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/detector"
+	"github.com/rail44/mantra/internal/history"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// TargetHistory is the recorded history of one target
+type TargetHistory struct {
+	Target   *parser.Target
+	FileInfo *parser.FileInfo
+	Entries  []history.Entry // Oldest first
+}
+
+// Entry returns the recorded implementation with the given version
+func (h *TargetHistory) Entry(version int) (*history.Entry, error) {
+	for i := range h.Entries {
+		if h.Entries[i].Version == version {
+			return &h.Entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no version %d in its history", history.TargetKey(h.Target), version)
+}
+
+// LoadHistory reads the history of the target named name ("Func", "Type.Method" or
+// "(*Type).Method") in the package
+func LoadHistory(pkgDir string, cfg *config.Config, name string) (*TargetHistory, error) {
+	store := cfg.HistoryStore(pkgDir)
+	if store == nil {
+		return nil, fmt.Errorf("history is disabled (history.keep = 0)")
+	}
+
+	results, err := detector.DetectPackageTargets(pkgDir, cfg.Dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect targets: %w", err)
+	}
+
+	result, status, err := findTarget(results, name)
+	if err != nil {
+		return nil, err
+	}
+
+	th := &TargetHistory{Target: status.Target, FileInfo: result.FileInfo}
+	if th.Entries, err = store.Entries(th.Target); err != nil {
+		return nil, err
+	}
+	return th, nil
+}
+
+// RestoreHistory writes a recorded implementation of the target into the generated file.
+// It becomes the accepted implementation of the current declaration
+func RestoreHistory(pkgDir string, cfg *config.Config, th *TargetHistory, version int) error {
+	entry, err := th.Entry(version)
+	if err != nil {
+		return err
+	}
+
+	return newGenerator(cfg, pkgDir).RestoreImplementation(th.FileInfo, th.Target, entry.Body, entry.Helpers)
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/coder"
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/detector"
+)
+
+// Implement generates the target named name in the source file at filePath and returns its
+// body, formatted for insertion at the cursor. For a region (// mantra:region) only the
+// region's code is returned. The target is generated whether or not it is up to date, and
+// nothing is written
+func Implement(ctx context.Context, filePath string, cfg *config.Config, name string) (string, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	pkgDir := filepath.Dir(absPath)
+
+	results, err := detector.DetectPackageTargetsWithOptions(pkgDir, cfg.Dest, detectOptions(cfg))
+	if err != nil {
+		return "", fmt.Errorf("failed to detect targets: %w", err)
+	}
+	var inFile []*detector.FileDetectionResult
+	for _, result := range results {
+		if path, err := filepath.Abs(result.FileInfo.FilePath); err == nil && path == absPath {
+			inFile = append(inFile, result)
+		}
+	}
+	if len(inFile) == 0 {
+		return "", fmt.Errorf("%s is not a source file of package %s", filePath, pkgDir)
+	}
+	result, status, err := findTarget(inFile, name)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read source file: %w", err)
+	}
+
+	clientConfig, err := newClientConfig(ctx, cfg, slog.Default())
+	if err != nil {
+		return "", err
+	}
+
+	// The target is stubbed as in generate, so an earlier implementation does not leak
+	// into the context
+	gen := newGenerator(cfg, pkgDir)
+	stubs, err := gen.Stubs(result.FileInfo, map[string]bool{status.Target.GetDisplayName(): true})
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare stub file: %w", err)
+	}
+	overlay := make(map[string][]byte, len(stubs))
+	for path, content := range stubs {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		overlay[path] = content
+	}
+
+	parallelCoder, err := coder.NewParallelCoder(clientConfig, cfg)
+	if err != nil {
+		return "", err
+	}
+	parallelCoder.SetOverlay(overlay)
+	generated, err := parallelCoder.ExecuteTargets(ctx, []coder.TargetContext{{
+		Target:          status.Target,
+		FileContent:     string(content),
+		FileInfo:        result.FileInfo,
+		Index:           1,
+		PreviousFailure: status.PreviousFailure,
+	}})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate implementation: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if len(generated) == 0 {
+		return "", fmt.Errorf("no implementation generated for %s", status.Target.GetDisplayName())
+	}
+	if r := generated[0]; !r.Success {
+		if r.FailureReason != nil {
+			return "", fmt.Errorf("failed to generate %s in %s phase: %s", status.Target.GetDisplayName(), r.FailureReason.Phase, r.FailureReason.Message)
+		}
+		return "", fmt.Errorf("failed to generate %s", status.Target.GetDisplayName())
+	}
+	implementation := generated[0].Implementation
+	if region := status.Target.Region; region != nil {
+		implementation = region.Code(implementation)
+	}
+	return codegen.FormatBody(implementation)
+}
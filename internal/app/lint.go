@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rail44/mantra/internal/coder"
+	"github.com/rail44/mantra/internal/config"
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/detector"
+	"github.com/rail44/mantra/internal/lint"
+	"github.com/rail44/mantra/internal/llm"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// TargetIssues are the lint issues found in the instruction of one target
+type TargetIssues struct {
+	Target *parser.Target
+	Issues []lint.Issue
+}
+
+// Lint checks the instructions of every target in the package. With useModel, the model
+// also reviews each instruction
+func Lint(ctx context.Context, pkgDir string, cfg *config.Config, useModel bool) ([]TargetIssues, error) {
+	results, err := detector.DetectPackageTargets(pkgDir, cfg.Dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect targets: %w", err)
+	}
+
+	var clientConfig *llm.ClientConfig
+	if useModel {
+		if clientConfig, err = newClientConfig(ctx, cfg, slog.Default()); err != nil {
+			return nil, err
+		}
+	}
+	l, err := newLinter(clientConfig, slog.Default())
+	if err != nil {
+		return nil, err
+	}
+
+	var all []TargetIssues
+	for _, result := range results {
+		for _, status := range result.Statuses {
+			all = append(all, TargetIssues{Target: status.Target, Issues: l.check(ctx, status.Target)})
+		}
+	}
+	return all, nil
+}
+
+// linter lints targets, sharing the package context of targets in the same file
+type linter struct {
+	completer lint.Completer // Reviews instructions when set
+	logger    *slog.Logger
+	resolvers map[string]lint.Resolver
+}
+
+// newLinter creates a linter. A non-nil clientConfig enables the model review
+func newLinter(clientConfig *llm.ClientConfig, logger *slog.Logger) (*linter, error) {
+	l := &linter{logger: logger, resolvers: make(map[string]lint.Resolver)}
+	if clientConfig != nil {
+		httpClient, err := llm.NewHTTPClient(time.Minute, clientConfig.Transport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+		}
+		client, err := llm.NewClient(clientConfig, httpClient, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AI client: %w", err)
+		}
+		l.completer = client
+	}
+	return l, nil
+}
+
+// check returns the issues of one target. Failures of the model review are logged and
+// leave the heuristic issues
+func (l *linter) check(ctx context.Context, target *parser.Target) []lint.Issue {
+	resolver, ok := l.resolvers[target.FilePath]
+	if !ok {
+		if fc, err := pkgcontext.NewFileContext(ctx, target.FilePath); err == nil {
+			resolver = fc
+		} else {
+			l.logger.Warn("failed to load package, skipping identifier checks", slog.String("error", err.Error()))
+		}
+		l.resolvers[target.FilePath] = resolver
+	}
+
+	issues := lint.Check(target, resolver)
+	if l.completer != nil {
+		reviewed, err := lint.CheckWithModel(ctx, l.completer, target)
+		if err != nil {
+			l.logger.Warn("instruction review failed", slog.String("target", target.GetDisplayName()), slog.String("error", err.Error()))
+		}
+		issues = append(issues, reviewed...)
+	}
+	return issues
+}
+
+// lintTargets lints the targets about to be generated when [lint] is enabled. In strict
+// mode flagged targets are held back and returned as failed results
+func (a *GenerateApp) lintTargets(ctx context.Context, targets []coder.TargetContext, clientConfig *llm.ClientConfig, cfg *config.Config) ([]coder.TargetContext, []*parser.GenerationResult) {
+	if cfg.Lint == nil || (!cfg.Lint.Enabled && !cfg.Lint.Strict) {
+		return targets, nil
+	}
+	if !cfg.Lint.Model {
+		clientConfig = nil
+	}
+	l, err := newLinter(clientConfig, a.logger)
+	if err != nil {
+		a.logger.Warn("failed to set up instruction lint", slog.String("error", err.Error()))
+		return targets, nil
+	}
+
+	var kept []coder.TargetContext
+	var refused []*parser.GenerationResult
+	for _, tc := range targets {
+		issues := l.check(ctx, tc.Target)
+		messages := make([]string, len(issues))
+		for i, issue := range issues {
+			messages[i] = issue.String()
+			a.logger.Warn("instruction lint",
+				slog.String("target", tc.Target.GetDisplayName()),
+				slog.String("issue", messages[i]))
+		}
+
+		if len(issues) == 0 || !cfg.Lint.Strict {
+			kept = append(kept, tc)
+			continue
+		}
+		refused = append(refused, &parser.GenerationResult{
+			Target:  tc.Target,
+			Success: false,
+			FailureReason: &parser.FailureReason{
+				Phase:   "lint",
+				Message: "instruction flagged: " + strings.Join(messages, "; "),
+				Context: "Clarify the instruction, or generate without strict lint",
+			},
+		})
+	}
+	return kept, refused
+}
@@ -0,0 +1,240 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rail44/mantra/internal/coder"
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/detector"
+	"github.com/rail44/mantra/internal/history"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// StabilityOptions selects the targets mantra stability regenerates
+type StabilityOptions struct {
+	Sample int    // Up-to-date targets regenerated (0 for all)
+	Runs   int    // Generations per target
+	Seed   uint64 // Seed picking the sample, so a report can be repeated
+}
+
+// StabilityReport compares regenerations of up-to-date targets with their accepted
+// implementations. Nothing is written
+type StabilityReport struct {
+	Current int // Up-to-date targets in the package
+	Targets []*TargetStability
+}
+
+// TargetStability is how the regenerations of one target compare to its accepted
+// implementation
+type TargetStability struct {
+	Target  *parser.Target
+	Current string // Accepted implementation
+	Runs    []StabilityRun
+}
+
+// StabilityRun is one regeneration of a target
+type StabilityRun struct {
+	Body    string
+	Failure *parser.FailureReason // Set when the run failed
+	Added   int                   // Lines added to the accepted implementation
+	Removed int                   // Lines removed from the accepted implementation
+}
+
+// Changed reports whether the run produced a body other than the accepted one.
+// Indentation and blank lines are ignored
+func (r StabilityRun) Changed() bool {
+	return r.Failure == nil && (r.Added > 0 || r.Removed > 0)
+}
+
+// Changed returns the number of runs whose body differs from the accepted implementation
+func (t *TargetStability) Changed() int {
+	n := 0
+	for _, run := range t.Runs {
+		if run.Changed() {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the number of runs that did not produce an implementation
+func (t *TargetStability) Failed() int {
+	n := 0
+	for _, run := range t.Runs {
+		if run.Failure != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Distinct returns the number of different bodies among the successful runs
+func (t *TargetStability) Distinct() int {
+	bodies := make(map[string]bool)
+	for _, run := range t.Runs {
+		if run.Failure == nil {
+			bodies[normalizeBody(run.Body)] = true
+		}
+	}
+	return len(bodies)
+}
+
+// Stability regenerates a sample of the package's up-to-date targets opts.Runs times and
+// compares each result with the accepted implementation
+func Stability(ctx context.Context, pkgDir string, cfg *config.Config, opts StabilityOptions) (*StabilityReport, error) {
+	if opts.Runs < 1 {
+		return nil, fmt.Errorf("runs must be at least 1, got %d", opts.Runs)
+	}
+
+	results, err := detector.DetectPackageTargets(pkgDir, cfg.Dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect targets: %w", err)
+	}
+
+	type candidate struct {
+		result *detector.FileDetectionResult
+		status *detector.TargetStatus
+	}
+	var candidates []candidate
+	for _, result := range results {
+		for _, status := range result.Statuses {
+			if status.Status == detector.StatusCurrent {
+				candidates = append(candidates, candidate{result, status})
+			}
+		}
+	}
+	report := &StabilityReport{Current: len(candidates)}
+
+	// Shuffle, then keep the sample in package order
+	if opts.Sample > 0 && opts.Sample < len(candidates) {
+		picked := rand.New(rand.NewPCG(opts.Seed, opts.Seed)).Perm(len(candidates))[:opts.Sample]
+		keep := make(map[int]bool, len(picked))
+		for _, i := range picked {
+			keep[i] = true
+		}
+		var sample []candidate
+		for i, c := range candidates {
+			if keep[i] {
+				sample = append(sample, c)
+			}
+		}
+		candidates = sample
+	}
+	if len(candidates) == 0 {
+		return report, nil
+	}
+
+	clientConfig, err := newClientConfig(ctx, cfg, slog.Default())
+	if err != nil {
+		return nil, err
+	}
+	gen := newGenerator(cfg, pkgDir)
+
+	// The sampled targets are stubbed as in generate, so their accepted implementations
+	// do not leak into the context
+	stubbed := make(map[*parser.FileInfo]map[string]bool)
+	contents := make(map[*parser.FileInfo]string)
+	for _, c := range candidates {
+		fileInfo := c.result.FileInfo
+		if stubbed[fileInfo] == nil {
+			stubbed[fileInfo] = make(map[string]bool)
+			content, err := os.ReadFile(fileInfo.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", fileInfo.FilePath, err)
+			}
+			contents[fileInfo] = string(content)
+		}
+		stubbed[fileInfo][c.status.Target.GetDisplayName()] = true
+	}
+	overlay := make(map[string][]byte)
+	for fileInfo, names := range stubbed {
+		stubs, err := gen.Stubs(fileInfo, names)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare stub file: %w", err)
+		}
+		for path, content := range stubs {
+			path, err := filepath.Abs(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get absolute path: %w", err)
+			}
+			overlay[path] = content
+		}
+	}
+
+	targets := make([]coder.TargetContext, len(candidates))
+	byTarget := make(map[*parser.Target]*TargetStability, len(candidates))
+	for i, c := range candidates {
+		targets[i] = coder.TargetContext{
+			Target:      c.status.Target,
+			FileContent: contents[c.result.FileInfo],
+			FileInfo:    c.result.FileInfo,
+			Index:       i + 1,
+		}
+		ts := &TargetStability{Target: c.status.Target, Current: c.status.ExistingImpl}
+		report.Targets = append(report.Targets, ts)
+		byTarget[ts.Target] = ts
+	}
+
+	for run := 1; run <= opts.Runs; run++ {
+		slog.Info("regenerating sample", slog.Int("run", run), slog.Int("runs", opts.Runs), slog.Int("targets", len(targets)))
+
+		parallelCoder, err := coder.NewParallelCoder(clientConfig, cfg)
+		if err != nil {
+			return nil, err
+		}
+		parallelCoder.SetOverlay(overlay)
+		generated, err := parallelCoder.ExecuteTargets(ctx, targets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate implementations: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, result := range generated {
+			ts, ok := byTarget[result.Target]
+			if !ok {
+				continue
+			}
+			if !result.Success {
+				ts.Runs = append(ts.Runs, StabilityRun{Failure: result.FailureReason})
+				continue
+			}
+			added, removed := diffStat(ts.Current, result.Implementation)
+			ts.Runs = append(ts.Runs, StabilityRun{Body: result.Implementation, Added: added, Removed: removed})
+		}
+	}
+	return report, nil
+}
+
+// normalizeBody strips indentation and blank lines, which differ between the generated
+// file and the model's answer without changing the code
+func normalizeBody(body string) string {
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffStat counts the lines added and removed turning old into new, ignoring indentation
+// and blank lines
+func diffStat(old, new string) (added, removed int) {
+	for _, line := range strings.Split(history.Diff(normalizeBody(old), normalizeBody(new)), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
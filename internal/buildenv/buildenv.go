@@ -0,0 +1,98 @@
+// Package buildenv holds the build configuration Go packages are loaded and run with, so
+// type-checking sees the same dependencies as the project's own builds (vendoring,
+// build tags, GOFLAGS)
+package buildenv
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Config is the build configuration of the project
+type Config struct {
+	Flags []string          // Build flags such as -mod=vendor or -tags=integration
+	Env   map[string]string // Variables such as GOFLAGS or GONOSUMCHECK set on top of the process environment
+}
+
+var (
+	mu      sync.RWMutex
+	current *Config
+)
+
+// Set makes c the build configuration of every later package load and go command
+func Set(c *Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+}
+
+// Current returns the build configuration in effect (nil if none was set)
+func Current() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Apply sets the build flags and environment of the current configuration on cfg
+func Apply(cfg *packages.Config) *packages.Config {
+	c := Current()
+	if c == nil {
+		return cfg
+	}
+	cfg.BuildFlags = append(cfg.BuildFlags, c.Flags...)
+	if len(c.Env) > 0 {
+		cfg.Env = Environ(os.Environ())
+	}
+	return cfg
+}
+
+// Flags returns the build flags of the current configuration
+func Flags() []string {
+	if c := Current(); c != nil {
+		return slices.Clone(c.Flags)
+	}
+	return nil
+}
+
+// Environ returns base with the variables of the current configuration set, replacing
+// any earlier value of the same variable
+func Environ(base []string) []string {
+	c := Current()
+	if c == nil || len(c.Env) == 0 {
+		return base
+	}
+
+	env := make([]string, 0, len(base)+len(c.Env))
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, overridden := c.Env[key]; !overridden {
+			env = append(env, kv)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.Env)) {
+		env = append(env, key+"="+c.Env[key])
+	}
+	return env
+}
+
+// Vendored reports whether the module containing dir vendors its dependencies, in which
+// case go commands default to -mod=vendor
+func Vendored(dir string) bool {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			_, err := os.Stat(filepath.Join(dir, "vendor", "modules.txt"))
+			return err == nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,59 @@
+package buildenv
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestApply(t *testing.T) {
+	t.Cleanup(func() { Set(nil) })
+
+	cfg := Apply(&packages.Config{BuildFlags: []string{"-tags=dev"}})
+	if len(cfg.BuildFlags) != 1 || cfg.Env != nil {
+		t.Errorf("Expected config to be unchanged without a build configuration, got %+v", cfg)
+	}
+
+	Set(&Config{
+		Flags: []string{"-mod=vendor"},
+		Env:   map[string]string{"GOFLAGS": "-tags=integration", "GONOSUMCHECK": "1"},
+	})
+	cfg = Apply(&packages.Config{BuildFlags: []string{"-tags=dev"}})
+	if !reflect.DeepEqual(cfg.BuildFlags, []string{"-tags=dev", "-mod=vendor"}) {
+		t.Errorf("Expected configured flags to be appended, got %v", cfg.BuildFlags)
+	}
+
+	env := Environ([]string{"HOME=/home/user", "GOFLAGS=-mod=mod"})
+	expected := []string{"HOME=/home/user", "GOFLAGS=-tags=integration", "GONOSUMCHECK=1"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %v, got %v", expected, env)
+	}
+}
+
+func TestVendored(t *testing.T) {
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "internal", "store")
+	if err := os.MkdirAll(pkgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if Vendored(pkgDir) {
+		t.Error("Expected module without vendor/modules.txt not to be vendored")
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "vendor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "vendor", "modules.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !Vendored(pkgDir) {
+		t.Error("Expected module with vendor/modules.txt to be vendored")
+	}
+}
@@ -1,29 +1,77 @@
 package checksum
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"hash/fnv"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/rail44/mantra/internal/parser"
 )
 
+// Version is the checksum format written to generated files
+const Version = 3
+
+// calculators compute the checksum of each known format version, so checksums written by
+// earlier releases are still compared with the content and hash they were computed with
+var calculators = map[int]func(target *parser.Target) string{
+	1: func(target *parser.Target) string { return hashV1(legacyContent(target)) },
+	2: func(target *parser.Target) string { return hashV2(legacyContent(target)) },
+	3: func(target *parser.Target) string { return hashV2(checksumContent(target)) },
+}
+
+// Checksum is a checksum read from a generated file
+type Checksum struct {
+	Version int
+	Hash    string
+}
+
+// String returns the checksum as written after "// mantra:"
+func (c Checksum) String() string {
+	return fmt.Sprintf("v%d:%s", c.Version, c.Hash)
+}
+
 // Calculate computes a checksum for a target function based on its signature and instruction
 func Calculate(target *parser.Target) string {
-	// Normalize the signature (remove extra spaces, newlines)
-	signature := normalizeSignature(target.GetFunctionSignature())
+	return calculators[Version](target)
+}
 
-	// Combine signature and instruction
-	content := signature + "\n" + target.Instruction
+// Matches reports whether the checksum was computed from the target's current signature and
+// instruction. Checksums of unknown versions, written by newer releases, never match
+func Matches(target *parser.Target, cs Checksum) bool {
+	calculate, ok := calculators[cs.Version]
+	if !ok {
+		return false
+	}
+	return calculate(target) == cs.Hash
+}
 
-	// Calculate FNV-1a hash
+// checksumContent combines the normalized signature and the instruction. For a region the
+// hand-written body around it is added, as the generated file holds a copy of it
+func checksumContent(target *parser.Target) string {
+	content := normalizeSignature(target.GetFunctionSignature()) + "\n" + target.Instruction
+	if target.Region != nil {
+		content += "\n" + target.Region.Before + target.Region.After
+	}
+	return content
+}
+
+// hashV1 is the original format: FNV-1a as an 8-character hex string
+func hashV1(content string) string {
 	h := fnv.New32a()
 	h.Write([]byte(content))
-
-	// Return as 8-character hex string
 	return fmt.Sprintf("%08x", h.Sum32())
 }
 
+// hashV2 is the first 16 hex characters of SHA-256
+func hashV2(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:8])
+}
+
 // normalizeSignature removes extra whitespace and normalizes the function signature
 func normalizeSignature(sig string) string {
 	// Replace multiple spaces with single space
@@ -31,16 +79,119 @@ func normalizeSignature(sig string) string {
 	return strings.TrimSpace(sig)
 }
 
-// ExtractFromComment extracts checksum from a mantra:checksum comment
-func ExtractFromComment(comment string) string {
-	const prefix = "// mantra:checksum:"
+var (
+	versionedPattern = regexp.MustCompile(`^// mantra:v(\d+):(\S+)$`)
+	legacyPattern    = regexp.MustCompile(`^// mantra:checksum:(\S+)$`)
+)
+
+// ParseComment reads a checksum comment in the "// mantra:v<version>:<hash>" format. It also
+// understands the unversioned "// mantra:checksum:<hash>" comments of version 1
+func ParseComment(comment string) (Checksum, bool) {
+	comment = strings.TrimSpace(comment)
+	if m := versionedPattern.FindStringSubmatch(comment); m != nil {
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return Checksum{}, false
+		}
+		return Checksum{Version: version, Hash: m[2]}, true
+	}
+	if m := legacyPattern.FindStringSubmatch(comment); m != nil {
+		return Checksum{Version: 1, Hash: m[1]}, true
+	}
+	return Checksum{}, false
+}
+
+// FormatComment creates a mantra checksum comment in the current format
+func FormatComment(checksum string) string {
+	return fmt.Sprintf("// mantra:v%d:%s", Version, checksum)
+}
+
+// ExtractDepsFromComment extracts the dependency fingerprint from a mantra:deps comment
+func ExtractDepsFromComment(comment string) string {
+	const prefix = "// mantra:deps:"
 	if strings.HasPrefix(comment, prefix) {
 		return strings.TrimSpace(strings.TrimPrefix(comment, prefix))
 	}
 	return ""
 }
 
-// FormatComment creates a mantra checksum comment
-func FormatComment(checksum string) string {
-	return fmt.Sprintf("// mantra:checksum:%s", checksum)
+// FormatDepsComment creates a mantra dependency fingerprint comment
+func FormatDepsComment(fingerprint string) string {
+	return fmt.Sprintf("// mantra:deps:%s", fingerprint)
+}
+
+// helperPrefix marks a helper function generated with a target, followed by the target's
+// display name
+const helperPrefix = "// mantra:helper:"
+
+// FormatHelperComment creates the comment marking a helper function of the target with the
+// given display name
+func FormatHelperComment(owner string) string {
+	return helperPrefix + owner
+}
+
+// ParseHelperComment returns the display name of the target a helper comment marks its
+// function as helper of
+func ParseHelperComment(comment string) (string, bool) {
+	owner, ok := strings.CutPrefix(strings.TrimSpace(comment), helperPrefix)
+	return owner, ok && owner != ""
+}
+
+const (
+	failedPrefix  = "// mantra:failed:"
+	attemptMarker = "// mantra:attempt:"
+)
+
+// FormatFailureComments creates the comments recording a failed generation: the failure
+// reason on one line and, when known, the rejected attempt as an indented block
+func FormatFailureComments(reason *parser.FailureReason) []string {
+	if reason == nil {
+		return []string{failedPrefix + " unknown reason"}
+	}
+
+	// Messages often quote compiler output; keep them on the comment's line
+	message := strings.Join(strings.Fields(reason.Message), " ")
+	comments := []string{fmt.Sprintf("%s%s: %s", failedPrefix, reason.Phase, message)}
+
+	attempt := strings.TrimSpace(reason.Attempt)
+	if attempt == "" {
+		return comments
+	}
+	comments = append(comments, attemptMarker)
+	for _, line := range strings.Split(attempt, "\n") {
+		comments = append(comments, strings.TrimRight("//\t"+line, " \t"))
+	}
+	return comments
+}
+
+// ParseFailureComments reads a failure recorded by FormatFailureComments from the comments
+// of a function. Returns nil if the comments record no failure
+func ParseFailureComments(comments []string) *parser.FailureReason {
+	var reason *parser.FailureReason
+	var attempt []string
+	inAttempt := false
+	for _, comment := range comments {
+		comment = strings.TrimRight(comment, " \t")
+		switch {
+		case strings.HasPrefix(comment, failedPrefix):
+			reason = &parser.FailureReason{}
+			if phase, message, ok := strings.Cut(strings.TrimPrefix(comment, failedPrefix), ": "); ok && !strings.HasPrefix(phase, " ") {
+				reason.Phase, reason.Message = phase, message
+			} else {
+				reason.Message = strings.TrimSpace(strings.TrimPrefix(comment, failedPrefix))
+			}
+			inAttempt = false
+		case comment == attemptMarker:
+			inAttempt = true
+		case inAttempt && (comment == "//" || strings.HasPrefix(comment, "//\t")):
+			attempt = append(attempt, strings.TrimPrefix(strings.TrimPrefix(comment, "//"), "\t"))
+		default:
+			inAttempt = false
+		}
+	}
+
+	if reason != nil {
+		reason.Attempt = strings.Trim(strings.Join(attempt, "\n"), "\n")
+	}
+	return reason
 }
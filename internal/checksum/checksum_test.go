@@ -0,0 +1,202 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func parseTarget(t *testing.T) *parser.Target {
+	t.Helper()
+	return parseSource(t, `package calc
+
+// mantra: Double the input
+func Double(n int) int {
+	panic("not implemented")
+}
+`)
+}
+
+// parseSource parses the single target of a source file
+func parseSource(t *testing.T, source string) *parser.Target {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "calc.go")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	fileInfo, err := parser.ParseFileInfo(path)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	if len(fileInfo.Targets) != 1 {
+		t.Fatalf("Expected 1 target, got %d", len(fileInfo.Targets))
+	}
+	return fileInfo.Targets[0]
+}
+
+func TestParseComment(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    Checksum
+		ok      bool
+	}{
+		{"// mantra:v2:0123456789abcdef", Checksum{Version: 2, Hash: "0123456789abcdef"}, true},
+		{"// mantra:checksum:1a2b3c4d", Checksum{Version: 1, Hash: "1a2b3c4d"}, true},
+		{"// mantra:v3:future", Checksum{Version: 3, Hash: "future"}, true},
+		{"// mantra:deps:1a2b3c4d", Checksum{}, false},
+		{"// mantra: Double the input", Checksum{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseComment(tt.comment)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseComment(%q): expected %+v, %v, got %+v, %v", tt.comment, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestMatchesAcrossVersions(t *testing.T) {
+	target := parseTarget(t)
+
+	current, ok := ParseComment(FormatComment(Calculate(target)))
+	if !ok || current.Version != Version {
+		t.Fatalf("Expected a version %d comment, got %+v", Version, current)
+	}
+	if !Matches(target, current) {
+		t.Errorf("Expected the current checksum to match")
+	}
+
+	// Generated files from before versioning keep matching their target
+	legacy, _ := ParseComment("// mantra:checksum:" + hashV1(legacyContent(target)))
+	if !Matches(target, legacy) {
+		t.Errorf("Expected the legacy checksum to match")
+	}
+
+	if Matches(target, Checksum{Version: 1, Hash: "00000000"}) {
+		t.Errorf("Expected a stale legacy checksum not to match")
+	}
+	if Matches(target, Checksum{Version: 99, Hash: Calculate(target)}) {
+		t.Errorf("Expected a checksum of an unknown version not to match")
+	}
+}
+
+func TestBaselineChecksums(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calc.go")
+	if err := os.WriteFile(path, []byte(`package calc
+
+import "strings"
+
+type Grid struct{}
+
+// mantra: Join the parts with a separator
+func Join(sep string, parts ...string) string {
+	panic("not implemented")
+}
+
+// mantra: Sum every cell of the grid
+func (g *Grid) Sum(cells [3][3]int) (total int, err error) {
+	panic("not implemented")
+}
+
+// mantra: Apply f to each value
+func Apply(values []string, f func(string) string, opts map[string][2]byte) []string {
+	_ = strings.ToUpper
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	fileInfo, err := parser.ParseFileInfo(path)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	// Checksums written by the first release, which rendered variadics as any and
+	// arrays as slices
+	want := map[string]string{
+		"Join":  "// mantra:checksum:719d1767",
+		"Sum":   "// mantra:checksum:7f9564f9",
+		"Apply": "// mantra:checksum:fd32e791",
+	}
+	if len(fileInfo.Targets) != len(want) {
+		t.Fatalf("Expected %d targets, got %d", len(want), len(fileInfo.Targets))
+	}
+	for _, target := range fileInfo.Targets {
+		cs, _ := ParseComment(want[target.Name])
+		if !Matches(target, cs) {
+			t.Errorf("Expected %s to match its checksum from the first release, got %s", target.Name, hashV1(legacyContent(target)))
+		}
+	}
+}
+
+func TestVariadicChecksum(t *testing.T) {
+	target := parseSource(t, `package calc
+
+// mantra: Join the parts
+func Join(parts ...string) string {
+	panic("not implemented")
+}
+`)
+
+	// Version 2 signatures rendered variadic parameters as any
+	legacy := hashV2("func Join(parts any) string\nJoin the parts")
+	if !Matches(target, Checksum{Version: 2, Hash: legacy}) {
+		t.Errorf("Expected a version 2 checksum to render the variadic parameter as any")
+	}
+	if Calculate(target) != hashV2("func Join(parts ...string) string\nJoin the parts") {
+		t.Errorf("Expected the current checksum to include the variadic parameter type")
+	}
+}
+
+func TestArrayChecksum(t *testing.T) {
+	target := parseSource(t, `package calc
+
+// mantra: Sum every cell
+func Sum(cells [3][3]int, bufs [size]byte) int {
+	panic("not implemented")
+}
+`)
+
+	// Version 1 and 2 signatures rendered arrays as slices
+	legacy := "func Sum(cells [][]int, bufs []byte) int\nSum every cell"
+	if !Matches(target, Checksum{Version: 1, Hash: hashV1(legacy)}) || !Matches(target, Checksum{Version: 2, Hash: hashV2(legacy)}) {
+		t.Errorf("Expected version 1 and 2 checksums to render arrays as slices")
+	}
+	if Calculate(target) != hashV2("func Sum(cells [3][3]int, bufs [size]byte) int\nSum every cell") {
+		t.Errorf("Expected the current checksum to include array lengths")
+	}
+}
+
+func TestNamedResultsChecksum(t *testing.T) {
+	target := parseTarget(t)
+	target.Returns[0].Name = "doubled"
+
+	// Version 2 files were written before signatures included result names
+	if !Matches(target, Checksum{Version: 2, Hash: hashV2(legacyContent(target))}) {
+		t.Errorf("Expected a version 2 checksum to ignore result names")
+	}
+
+	named := Calculate(target)
+	target.Returns[0].Name = "twice"
+	if Calculate(target) == named {
+		t.Errorf("Expected renaming a result to change the checksum")
+	}
+}
+
+func TestRegionChecksum(t *testing.T) {
+	target := parseTarget(t)
+	whole := Calculate(target)
+
+	target.Region = &parser.Region{Name: "double", Before: "\t// mantra:region double Double the input\n", After: "\t// mantra:end"}
+	region := Calculate(target)
+	if region == whole {
+		t.Errorf("Expected the hand-written body around a region to change the checksum")
+	}
+
+	// Editing the hand-written part regenerates the region, as the generated file copies it
+	target.Region.After = "\t// mantra:end\n\t// unreachable"
+	if Calculate(target) == region {
+		t.Errorf("Expected editing the body around the region to change the checksum")
+	}
+}
@@ -0,0 +1,93 @@
+package checksum
+
+import (
+	"go/ast"
+	"strings"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// legacyContent is checksumContent as versions 1 and 2 computed it. Signatures then left out
+// the names of results and rendered types as legacyType does
+func legacyContent(target *parser.Target) string {
+	legacy := *target
+	legacy.Returns = make([]parser.Return, len(target.Returns))
+	for i, ret := range target.Returns {
+		legacy.Returns[i] = parser.Return{Type: ret.Type}
+	}
+
+	if fn := target.FuncDecl; fn != nil {
+		if target.Receiver != nil && fn.Recv != nil && len(fn.Recv.List) > 0 {
+			receiver := *target.Receiver
+			receiver.Type = legacyType(fn.Recv.List[0].Type)
+			legacy.Receiver = &receiver
+		}
+		legacy.Params = nil
+		for _, field := range fieldList(fn.Type.Params) {
+			typ := legacyType(field.Type)
+			if len(field.Names) == 0 {
+				legacy.Params = append(legacy.Params, parser.Param{Type: typ})
+			}
+			for _, name := range field.Names {
+				legacy.Params = append(legacy.Params, parser.Param{Name: name.Name, Type: typ})
+			}
+		}
+		legacy.Returns = nil
+		for _, field := range fieldList(fn.Type.Results) {
+			typ := legacyType(field.Type)
+			for range max(len(field.Names), 1) {
+				legacy.Returns = append(legacy.Returns, parser.Return{Type: typ})
+			}
+		}
+	}
+	return checksumContent(&legacy)
+}
+
+// fieldList returns the fields of a possibly nil list
+func fieldList(fields *ast.FieldList) []*ast.Field {
+	if fields == nil {
+		return nil
+	}
+	return fields.List
+}
+
+// legacyType renders a type as signatures of versions 1 and 2 did: arrays as slices,
+// variadic parameters and other unsupported expressions as any, and function types
+// with one entry per field
+func legacyType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + legacyType(t.X)
+	case *ast.ArrayType:
+		return "[]" + legacyType(t.Elt)
+	case *ast.MapType:
+		return "map[" + legacyType(t.Key) + "]" + legacyType(t.Value)
+	case *ast.SelectorExpr:
+		if ident, ok := t.X.(*ast.Ident); ok {
+			return ident.Name + "." + t.Sel.Name
+		}
+		return "qualified.Type"
+	case *ast.ChanType:
+		return "chan " + legacyType(t.Value)
+	case *ast.FuncType:
+		var params, results []string
+		for _, field := range fieldList(t.Params) {
+			params = append(params, legacyType(field.Type))
+		}
+		for _, field := range fieldList(t.Results) {
+			results = append(results, legacyType(field.Type))
+		}
+		rendered := "(" + strings.Join(params, ", ") + ")"
+		switch len(results) {
+		case 0:
+		case 1:
+			rendered += " " + results[0]
+		default:
+			rendered += " (" + strings.Join(results, ", ") + ")"
+		}
+		return rendered
+	}
+	return "any"
+}
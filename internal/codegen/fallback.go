@@ -0,0 +1,107 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// Fallback renders the bodies of targets that failed to generate, in place of the body
+// of the source declaration (usually panic("not implemented"))
+type Fallback struct {
+	Body      *template.Template // Targets without an error result (nil keeps the source body)
+	ErrorBody *template.Template // Targets whose last result is an error (nil uses Body)
+}
+
+// FallbackData is what fallback templates are executed with
+type FallbackData struct {
+	Target  string // Display name, e.g. "(*Service).CreateUser"
+	Phase   string // Phase the generation failed in
+	Message string // Why it failed, on one line
+	Zero    string // Zero values of the results before a trailing error, each followed by ", "
+}
+
+// ParseFallback parses a fallback body template. Besides the fields of FallbackData,
+// templates can use quote to write a value as a Go string literal
+func ParseFallback(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(text)
+}
+
+// fallbackBody renders the fallback body of a failed target. It returns an empty body
+// when no template applies, and an error when the rendered body is not valid Go
+func (g *Generator) fallbackBody(target *parser.Target) (string, error) {
+	fallback := g.config.Fallback
+	if fallback == nil || !target.GenerationFailed || target.FailureReason == nil {
+		return "", nil // Stubs awaiting generation keep panicking
+	}
+
+	returnsError := len(target.Returns) > 0 && target.Returns[len(target.Returns)-1].Type == "error"
+	tmpl := fallback.Body
+	if returnsError && fallback.ErrorBody != nil {
+		tmpl = fallback.ErrorBody
+	}
+	if tmpl == nil {
+		return "", nil
+	}
+
+	results := target.Returns
+	if returnsError {
+		results = results[:len(results)-1]
+	}
+	var zero strings.Builder
+	for _, result := range results {
+		zero.WriteString(zeroValue(result.Type) + ", ")
+	}
+
+	var buf strings.Builder
+	err := tmpl.Execute(&buf, FallbackData{
+		Target:  target.GetDisplayName(),
+		Phase:   target.FailureReason.Phase,
+		Message: strings.Join(strings.Fields(target.FailureReason.Message), " "),
+		Zero:    zero.String(),
+	})
+	if err == nil {
+		_, err = goparser.ParseFile(token.NewFileSet(), "", "package p\nfunc _() {\n"+buf.String()+"\n}", 0)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid fallback body for %s: %w", target.GetDisplayName(), err)
+	}
+	return buf.String(), nil
+}
+
+// zeroValue returns an expression for the zero value of a type. Types whose kind cannot
+// be told from their name use *new(T), which is valid for any type
+func zeroValue(typ string) string {
+	expr, err := goparser.ParseExpr(typ)
+	if err != nil {
+		return "*new(" + typ + ")"
+	}
+	switch t := expr.(type) {
+	case *ast.StarExpr, *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+		if array, ok := t.(*ast.ArrayType); ok && array.Len != nil {
+			return typ + "{}"
+		}
+		return "nil"
+	case *ast.StructType:
+		return typ + "{}"
+	case *ast.Ident:
+		switch t.Name {
+		case "bool":
+			return "false"
+		case "string":
+			return `""`
+		case "error", "any":
+			return "nil"
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+			"uintptr", "byte", "rune", "float32", "float64", "complex64", "complex128":
+			return "0"
+		}
+	}
+	return "*new(" + typ + ")"
+}
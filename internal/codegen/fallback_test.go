@@ -0,0 +1,108 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestGenerateFileFallbackBodies(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "users.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package users
+
+import "errors"
+
+var ErrNotGenerated = errors.New("not generated")
+
+type User struct{}
+
+// mantra: Load the user, returning an error if it is missing
+func Load(id string) (*User, int, error) {
+	panic("not implemented")
+}
+
+// mantra: Count the users
+func Count() int {
+	panic("not implemented")
+}
+
+// mantra: Check the user
+func Check(u User) bool {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	errorBody, err := ParseFallback("error_body", `return {{.Zero}}fmt.Errorf("%w: {{.Target}}: %s", ErrNotGenerated, {{quote .Message}})`)
+	if err != nil {
+		t.Fatalf("ParseFallback failed: %v", err)
+	}
+	body, err := ParseFallback("body", `panic({{quote (print .Target " was not generated in " .Phase)}})`)
+	if err != nil {
+		t.Fatalf("ParseFallback failed: %v", err)
+	}
+
+	failure := &parser.FailureReason{Phase: "implementation", Message: `check_code: "x" undefined`}
+	results := []*parser.GenerationResult{
+		{Target: fileInfo.Targets[0], FailureReason: failure},
+		{Target: fileInfo.Targets[1], FailureReason: failure},
+		{Target: fileInfo.Targets[2], Success: true, Implementation: "return true"},
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "users", Fallback: &Fallback{Body: body, ErrorBody: errorBody}})
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "users.go"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		`return nil, 0, fmt.Errorf("%w: Load: %s", ErrNotGenerated, "check_code: \"x\" undefined")`,
+		`panic("Count was not generated in implementation")`,
+		`"fmt"`,
+		"// mantra:failed:implementation:",
+		"return true",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "not implemented") {
+		t.Errorf("Expected fallback bodies to replace the stubs, got:\n%s", content)
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	tests := map[string]string{
+		"int":            "0",
+		"string":         `""`,
+		"bool":           "false",
+		"error":          "nil",
+		"*User":          "nil",
+		"[]byte":         "nil",
+		"map[string]int": "nil",
+		"[2]int":         "[2]int{}",
+		"User":           "*new(User)",
+		"time.Duration":  "*new(time.Duration)",
+	}
+	for typ, expected := range tests {
+		if got := zeroValue(typ); got != expected {
+			t.Errorf("zeroValue(%q): expected %s, got %s", typ, expected, got)
+		}
+	}
+}
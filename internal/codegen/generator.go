@@ -13,6 +13,7 @@ import (
 
 	"github.com/rail44/mantra/internal/analysis"
 	"github.com/rail44/mantra/internal/checksum"
+	"github.com/rail44/mantra/internal/deps"
 	"github.com/rail44/mantra/internal/imports"
 	"github.com/rail44/mantra/internal/parser"
 )
@@ -22,30 +23,67 @@ type Config struct {
 	Dest          string // Directory where generated files will be saved
 	PackageName   string // Package name for generated files
 	SourcePackage string // Original package name for import reference
+
+	// ConvertBlankImports rewrites blank imports used by generated code into regular
+	// imports; otherwise a regular import is added next to the blank one
+	ConvertBlankImports bool
+
+	// PerTargetFiles writes each target to a file of its own (see TargetFileName) instead
+	// of into the file mirroring its source file
+	PerTargetFiles bool
+
+	// Fallback replaces the bodies of targets that failed to generate (nil keeps them)
+	Fallback *Fallback
+
+	// Summary appends a comment block to each generated file listing its targets with the
+	// model and time they were generated with; Model is recorded for targets generated now
+	Summary bool
+	Model   string
 }
 
 type Generator struct {
-	config *Config
+	config    *Config
+	touched   map[string]bool              // Output files already backed up during this run
+	cleared   map[string]bool              // Directories whose backups of earlier runs were removed
+	converted map[string][]string          // Blank imports converted per source file
+	written   map[string][]string          // Files written per source file
+	aliases   map[string]map[string]string // Local type aliases per source directory
+
+	importNamesByDir map[string]map[string]string // Package names of imports per source directory
 }
 
 func New(config *Config) *Generator {
-	return &Generator{config: config}
+	return &Generator{
+		config:    config,
+		touched:   make(map[string]bool),
+		cleared:   make(map[string]bool),
+		converted: make(map[string][]string),
+		written:   make(map[string][]string),
+		aliases:   make(map[string]map[string]string),
+
+		importNamesByDir: make(map[string]map[string]string),
+	}
 }
 
-// PrepareTargetStubs prepares the generated file with stub implementations for targets
-// that are about to be generated. This creates a valid Go file that can be analyzed
-// by go/packages while generation is in progress.
+// ConvertedBlankImports returns the blank imports of the source file that were turned into
+// regular imports when its generated file was last written
+func (g *Generator) ConvertedBlankImports(sourcePath string) []string {
+	return g.converted[sourcePath]
+}
+
+// OutputPath returns the destination path for the given source file
+func (g *Generator) OutputPath(sourcePath string) string {
+	return filepath.Join(g.config.Dest, filepath.Base(sourcePath))
+}
+
+// TargetStubs returns the content the generated file has while generation is in progress,
+// so go/packages can analyze the destination package through an overlay without anything
+// being written to dest.
 //
 // For targets to be generated: uses panic("not implemented")
 // For other targets: preserves existing implementation if file exists, otherwise uses panic
-func (g *Generator) PrepareTargetStubs(fileInfo *parser.FileInfo, targetsToGenerate map[string]bool) error {
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(g.config.Dest, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	sourceFileName := filepath.Base(fileInfo.FilePath)
-	outputFile := filepath.Join(g.config.Dest, sourceFileName)
+func (g *Generator) TargetStubs(fileInfo *parser.FileInfo, targetsToGenerate map[string]bool) ([]byte, error) {
+	outputFile := g.OutputPath(fileInfo.FilePath)
 
 	// Check if file already exists and preserve it if targets are already generated
 	var existingContent string
@@ -99,23 +137,17 @@ func (g *Generator) PrepareTargetStubs(fileInfo *parser.FileInfo, targetsToGener
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to generate file content: %w", err)
+		return nil, fmt.Errorf("failed to generate file content: %w", err)
 	}
 
-	// Write to file
-	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
+	return []byte(content), nil
 }
 
-// mergeWithExisting intelligently merges new stubs with existing implementations
+// mergeWithExisting swaps in panic stubs for the bodies of targets being regenerated and
+// leaves every other byte of the existing file untouched, so hand-written helpers, vars
+// and types added to the generated file survive stub preparation.
+// Targets missing from the existing file are appended as stubs.
 func (g *Generator) mergeWithExisting(fileInfo *parser.FileInfo, results []*parser.GenerationResult, existingContent string) (string, error) {
-	// For targets marked as successful (to be preserved), we'll keep the existing file
-	// For targets marked as failed (to be regenerated), we'll use panic stubs
-
-	// Parse the existing file to extract implementations
 	fset := token.NewFileSet()
 	existingAST, err := goparser.ParseFile(fset, "", existingContent, goparser.ParseComments)
 	if err != nil {
@@ -123,54 +155,63 @@ func (g *Generator) mergeWithExisting(fileInfo *parser.FileInfo, results []*pars
 		return g.generateFileContent(fileInfo, results, "")
 	}
 
-	// Build a map of which targets to regenerate (use stubs)
-	useStub := make(map[string]bool)
-	for _, result := range results {
-		if !result.Success {
-			useStub[result.Target.GetDisplayName()] = true
-		}
-	}
-
-	// Walk through the existing AST and replace only targets to be regenerated
-	ast.Inspect(existingAST, func(n ast.Node) bool {
-		if fn, ok := n.(*ast.FuncDecl); ok {
-			funcName := fn.Name.Name
-			if fn.Recv != nil && len(fn.Recv.List) > 0 {
-				// Method - check if it needs stub
-				for _, result := range results {
-					if result.Target.Name == funcName && useStub[result.Target.GetDisplayName()] {
-						// Replace with panic stub
-						fn.Body = &ast.BlockStmt{
-							List: []ast.Stmt{
-								&ast.ExprStmt{
-									X: &ast.CallExpr{
-										Fun: &ast.Ident{Name: "panic"},
-										Args: []ast.Expr{
-											&ast.BasicLit{
-												Kind:  token.STRING,
-												Value: `"not implemented"`,
-											},
-										},
-									},
-								},
-							},
-						}
-						break
-					}
-				}
+	// Collect body ranges to replace; declarations appear in source order
+	type span struct{ start, end int }
+	var stubs []span
+	found := make(map[*parser.Target]bool)
+	for _, funcDecl := range analysis.FuncDecls(existingAST) {
+		if funcDecl.Body == nil {
+			continue
+		}
+		for _, result := range results {
+			if !g.isTargetFunction(funcDecl, result.Target) {
+				continue
+			}
+			found[result.Target] = true
+			if !result.Success {
+				stubs = append(stubs, span{
+					start: fset.Position(funcDecl.Body.Lbrace).Offset,
+					end:   fset.Position(funcDecl.Body.Rbrace).Offset + 1,
+				})
 			}
+			break
 		}
-		return true
-	})
+	}
 
-	// Format the modified AST back to source code
 	var buf strings.Builder
-	if err := format.Node(&buf, fset, existingAST); err != nil {
+	last := 0
+	for _, s := range stubs {
+		buf.WriteString(existingContent[last:s.start])
+		buf.WriteString(notImplementedBody)
+		last = s.end
+	}
+	buf.WriteString(existingContent[last:])
+
+	// Targets added to the source since the last run get a stub so the file stays analyzable
+	for _, result := range results {
+		if found[result.Target] {
+			continue
+		}
+		buf.WriteString("\n" + g.formatStub(fileInfo, result.Target) + "\n")
+	}
+
+	formatted, err := format.Source([]byte(buf.String()))
+	if err != nil {
 		// Fall back to fresh generation if formatting fails
 		return g.generateFileContent(fileInfo, results, "")
 	}
 
-	return buf.String(), nil
+	return string(formatted), nil
+}
+
+// notImplementedBody is the function body used for targets awaiting generation
+const notImplementedBody = "{\n\tpanic(\"not implemented\")\n}"
+
+// formatStub renders the target's signature from the source file with a panic body
+func (g *Generator) formatStub(fileInfo *parser.FileInfo, target *parser.Target) string {
+	start := target.TokenSet.Position(target.FuncDecl.Pos()).Offset
+	end := target.TokenSet.Position(target.FuncDecl.Type.End()).Offset
+	return fileInfo.SourceContent[start:end] + " " + notImplementedBody
 }
 
 // GenerateFile generates a complete file with implementations for all targets
@@ -181,8 +222,7 @@ func (g *Generator) GenerateFile(fileInfo *parser.FileInfo, results []*parser.Ge
 	}
 
 	// Check if generated file already exists
-	sourceFileName := filepath.Base(fileInfo.FilePath)
-	outputFile := filepath.Join(g.config.Dest, sourceFileName)
+	outputFile := g.OutputPath(fileInfo.FilePath)
 
 	var existingContent string
 	if existingData, err := os.ReadFile(outputFile); err == nil {
@@ -195,22 +235,100 @@ func (g *Generator) GenerateFile(fileInfo *parser.FileInfo, results []*parser.Ge
 		return fmt.Errorf("failed to generate file content: %w", err)
 	}
 
-	// Format the Go code
-	formatted, err := format.Source([]byte(content))
+	files := map[string]string{outputFile: content}
+	if g.config.PerTargetFiles {
+		if files, err = g.splitTargets(fileInfo, content); err != nil {
+			return fmt.Errorf("failed to split targets into files: %w", err)
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	written := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		content := files[path]
+		if g.config.Summary {
+			content = g.appendSummary(content, readExisting(path), g.fileResults(fileInfo, results, path))
+		}
+
+		// Format the Go code
+		formatted, err := format.Source([]byte(content))
+		if err != nil {
+			// If formatting fails, use the original code but log the error
+			fmt.Fprintf(os.Stderr, "Warning: failed to format generated code: %v\n", err)
+			formatted = []byte(content)
+		}
+
+		// Write the generated file
+		if err := g.writeOutput(path, formatted); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		written[path] = true
+	}
+
+	for _, kind := range TestKinds {
+		testPath, err := g.generateTestFuncs(fileInfo, results, kind)
+		if err != nil {
+			return fmt.Errorf("failed to write %ss: %w", kind.Name, err)
+		}
+		if testPath != "" {
+			paths = append(paths, testPath)
+		}
+	}
+	g.written[fileInfo.FilePath] = paths
+
+	return g.removeStaleTargetFiles(fileInfo.FilePath, written)
+}
+
+// RestoreImplementation replaces the body and the helpers of one target in the existing
+// generated file, leaving the rest of the file untouched. The body is accepted for the
+// target's current declaration, so it stays until the signature or instruction changes
+func (g *Generator) RestoreImplementation(fileInfo *parser.FileInfo, target *parser.Target, implementation string, helpers []parser.Helper) error {
+	outputFile := g.OutputPath(fileInfo.FilePath)
+	if g.config.PerTargetFiles {
+		outputFile = g.TargetPath(fileInfo.FilePath, target)
+	}
+	existing, err := os.ReadFile(outputFile)
 	if err != nil {
-		// If formatting fails, use the original code but log the error
-		fmt.Fprintf(os.Stderr, "Warning: failed to format generated code: %v\n", err)
-		formatted = []byte(content)
+		return fmt.Errorf("failed to read generated file: %w", err)
 	}
 
-	// File paths already determined above
+	target.Implementation = implementation
+	target.Helpers = helpers
+	target.GenerationFailed = false
+	target.FailureReason = nil
+	g.renameHelpers(fileInfo, []*parser.Target{target})
 
-	// Write the generated file
-	if err := os.WriteFile(outputFile, formatted, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	content, err := g.removeHelpers(string(existing), target, outputFile)
+	if err != nil {
+		return err
+	}
+	depIndex, err := deps.BuildIndex(filepath.Dir(fileInfo.FilePath))
+	if err != nil {
+		depIndex = nil // Dependency tracking is best-effort
+	}
+	content, err = g.replaceAllFunctionsWithChecksum(content, []*parser.Target{target}, outputFile, depIndex)
+	if err != nil {
+		return fmt.Errorf("failed to replace %s: %w", target.Name, err)
+	}
+	content, err = g.insertHelpers(content, []*parser.Target{target}, outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to insert helpers: %w", err)
+	}
+	code := parser.WithHelperCode(target.Implementation, target.Helpers)
+	if required := imports.AnalyzeRequiredImports(code); len(required) > 0 {
+		content = g.addImports(content, required)
 	}
 
-	return nil
+	formatted, err := format.Source([]byte(content))
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return g.writeOutput(outputFile, formatted)
 }
 
 // generateFileContent creates the content for the generated file by replacing mantra functions
@@ -218,39 +336,27 @@ func (g *Generator) generateFileContent(fileInfo *parser.FileInfo, results []*pa
 	// Start with the original source content
 	content := fileInfo.SourceContent
 
-	// Add generated code header if not already present
-	if !strings.Contains(content, "Code generated by mantra") {
-		// Insert header after package declaration
-		lines := strings.Split(content, "\n")
-		for i, line := range lines {
-			if strings.HasPrefix(strings.TrimSpace(line), "package ") {
-				header := "// Code generated by mantra; DO NOT EDIT.\n"
-				lines[i] = line + "\n\n" + header
-				content = strings.Join(lines, "\n")
-				break
-			}
-		}
+	// Rename the package and add the generated code header, leaving everything else verbatim
+	content, err := g.rewritePackageClause(content)
+	if err != nil {
+		return "", err
 	}
 
-	// Change package name
-	content = strings.Replace(content, fmt.Sprintf("package %s", fileInfo.PackageName), fmt.Sprintf("package %s", g.config.PackageName), 1)
-
-	// Convert blank imports to regular imports
-	content = g.convertBlankImports(content)
-
-	// Create a map for quick lookup of results by target name
+	// Create a map for quick lookup of results by target; methods of different types may
+	// share a name
 	resultMap := make(map[string]*parser.GenerationResult)
 	for _, result := range results {
-		resultMap[result.Target.Name] = result
+		resultMap[g.getTargetKey(result.Target)] = result
 	}
 
 	// Sort targets by line number in reverse order to avoid line number shifts
 	var targetsToProcess []*parser.Target
 	for _, target := range fileInfo.Targets {
 		// Process all targets with mantra comments
-		if result, exists := resultMap[target.Name]; exists {
+		if result, exists := resultMap[g.getTargetKey(target)]; exists {
 			if result.Success {
 				target.Implementation = result.Implementation
+				target.Helpers = result.Helpers
 				target.GenerationFailed = false
 			} else {
 				// Mark as failed, keep original implementation (panic), store failure reason
@@ -268,6 +374,7 @@ func (g *Generator) generateFileContent(fileInfo *parser.FileInfo, results []*pa
 		}
 		targetsToProcess = append(targetsToProcess, target)
 	}
+	g.renameHelpers(fileInfo, targetsToProcess)
 
 	// Sort by start line in descending order (process from bottom to top)
 	sort.Slice(targetsToProcess, func(i, j int) bool {
@@ -277,27 +384,47 @@ func (g *Generator) generateFileContent(fileInfo *parser.FileInfo, results []*pa
 	})
 
 	// Replace all mantra functions with their implementations in a single AST pass
-	newContent, err := g.replaceAllFunctionsWithChecksum(content, targetsToProcess, fileInfo.FilePath)
+	// Index source declarations so generated bodies can record what they depend on
+	depIndex, err := deps.BuildIndex(filepath.Dir(fileInfo.FilePath))
+	if err != nil {
+		depIndex = nil // Dependency tracking is best-effort
+	}
+
+	newContent, err := g.replaceAllFunctionsWithChecksum(content, targetsToProcess, fileInfo.FilePath, depIndex)
 	if err != nil {
 		return "", fmt.Errorf("failed to replace functions: %w", err)
 	}
-	content = newContent
+	content, err = g.insertHelpers(newContent, targetsToProcess, fileInfo.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert helpers: %w", err)
+	}
 
 	// Analyze required imports from successful implementations
-	var requiredImports []string
+	// Blank imports in the source mark packages generated code may use; only those
+	// actually referenced are needed
+	blankImports := imports.ExtractBlankImports(fileInfo.SourceContent)
+	var requiredImports, usedBlankImports []string
 	for _, result := range results {
 		if result.Success {
-			implImports := imports.AnalyzeRequiredImports(result.Implementation)
+			code := parser.WithHelperCode(result.Implementation, result.Helpers)
+			implImports := imports.AnalyzeRequiredImports(code)
 			requiredImports = imports.MergeImports(requiredImports, implImports)
+			usedBlankImports = imports.MergeImports(usedBlankImports, imports.UsedBlankImports(code, blankImports))
+		}
+	}
+	for _, target := range targetsToProcess {
+		if fallback, err := g.fallbackBody(target); err == nil && fallback != "" {
+			requiredImports = imports.MergeImports(requiredImports, imports.AnalyzeRequiredImports(fallback))
 		}
 	}
 
-	// Extract blank imports from the original file (imports marked with _)
-	// These indicate packages that should be used in generated code
-	blankImports := imports.ExtractBlankImports(fileInfo.SourceContent)
-	if len(blankImports) > 0 {
-		requiredImports = imports.MergeImports(requiredImports, blankImports)
+	if g.config.ConvertBlankImports {
+		content = g.convertBlankImports(content, usedBlankImports)
+		g.converted[fileInfo.FilePath] = usedBlankImports
+	} else {
+		delete(g.converted, fileInfo.FilePath)
 	}
+	requiredImports = imports.MergeImports(requiredImports, usedBlankImports)
 
 	// Add required imports to the generated file
 	if len(requiredImports) > 0 {
@@ -307,12 +434,53 @@ func (g *Generator) generateFileContent(fileInfo *parser.FileInfo, results []*pa
 	return content, nil
 }
 
+// generatedHeader marks output files as generated
+const generatedHeader = "// Code generated by mantra; DO NOT EDIT."
+
+// rewritePackageClause renames the package and inserts the generated header on the line
+// after the package clause. Build constraints, package docs and directives above or around
+// the clause are located through the AST and kept byte for byte.
+func (g *Generator) rewritePackageClause(content string) (string, error) {
+	fset := token.NewFileSet()
+	node, err := goparser.ParseFile(fset, "", content, goparser.PackageClauseOnly|goparser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse package clause: %w", err)
+	}
+
+	nameStart := fset.Position(node.Name.Pos()).Offset
+	nameEnd := fset.Position(node.Name.End()).Offset
+	rest := content[nameEnd:]
+
+	var buf strings.Builder
+	buf.WriteString(content[:nameStart])
+	buf.WriteString(g.config.PackageName)
+	if strings.Contains(content, "Code generated by mantra") {
+		buf.WriteString(rest)
+		return buf.String(), nil
+	}
+
+	// Keep any trailing comment on the package line above the header
+	lineEnd := strings.IndexByte(rest, '\n')
+	if lineEnd < 0 {
+		lineEnd = len(rest)
+	}
+	buf.WriteString(rest[:lineEnd])
+	buf.WriteString("\n\n" + generatedHeader + "\n")
+	buf.WriteString(rest[lineEnd:])
+	return buf.String(), nil
+}
+
 // replaceAllFunctionsWithChecksum replaces all target functions and adds checksums
-func (g *Generator) replaceAllFunctionsWithChecksum(content string, targets []*parser.Target, filePath string) (string, error) {
+func (g *Generator) replaceAllFunctionsWithChecksum(content string, targets []*parser.Target, filePath string, depIndex *deps.Index) (string, error) {
 	if len(targets) == 0 {
 		return content, nil
 	}
 
+	content, err := g.spliceRegionBodies(content, targets, filePath)
+	if err != nil {
+		return "", err
+	}
+
 	// Parse the original content as AST once
 	fset := token.NewFileSet()
 	node, err := goparser.ParseFile(fset, filePath, content, goparser.ParseComments)
@@ -323,8 +491,8 @@ func (g *Generator) replaceAllFunctionsWithChecksum(content string, targets []*p
 	// targetData holds all data needed for replacing a target function
 	type targetData struct {
 		sourceTarget *parser.Target // Original source file's target
-		implBody     *ast.BlockStmt
-		checksum     string
+		implBody     *ast.BlockStmt // nil keeps the body of the file
+		markers      []string       // Checksum or failure comments, and the dependency fingerprint
 	}
 
 	// Prepare implementation bodies and checksums for all targets
@@ -332,29 +500,41 @@ func (g *Generator) replaceAllFunctionsWithChecksum(content string, targets []*p
 
 	for _, target := range targets {
 		var implBody *ast.BlockStmt
-		var checksumComment string
+		var markers []string
 
 		if target.GenerationFailed {
-			// For failed targets, keep original body and set detailed failure comment
-			implBody = target.FuncDecl.Body // Keep original implementation (panic)
-			if target.FailureReason != nil {
-				checksumComment = fmt.Sprintf("// mantra:failed:%s: %s",
-					target.FailureReason.Phase, target.FailureReason.Message)
-			} else {
-				checksumComment = "// mantra:failed: unknown reason"
+			// For failed targets, keep original body and record the failure and the broken
+			// attempt, which the next run shows to the model
+			// The original implementation (panic) is already in the file; its nodes there
+			// carry positions of this file set, so function literals print as written
+			if fallback, err := g.fallbackBody(target); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			} else if fallback != "" {
+				if body, err := g.parseImplementationAsBlockWithFileSet(fallback, fset); err == nil {
+					implBody = body
+				}
 			}
+			markers = checksum.FormatFailureComments(target.FailureReason)
 		} else {
-			// Parse the implementation as a function body
-			cleanedImpl := cleanCode(target.Implementation)
-			var err error
-			implBody, err = g.parseImplementationAsBlockWithFileSet(cleanedImpl, fset)
-			if err != nil {
-				return "", fmt.Errorf("failed to parse implementation for %s: %w", target.Name, err)
+			// Parse the implementation as a function body; bodies with a region are already
+			// in the file
+			cleanedImpl := CleanCode(target.Implementation)
+			if target.Region == nil {
+				var err error
+				implBody, err = g.parseImplementationAsBlockWithFileSet(cleanedImpl, fset)
+				if err != nil {
+					return "", fmt.Errorf("failed to parse implementation for %s: %w", target.Name, err)
+				}
 			}
 
 			// Calculate checksum for the comment
 			cs := checksum.Calculate(target)
-			checksumComment = checksum.FormatComment(cs)
+			markers = append(markers, checksum.FormatComment(cs))
+
+			// Record a fingerprint of referenced declarations for dependency tracking
+			if fp := depIndex.Fingerprint(parser.WithHelperCode(cleanedImpl, target.Helpers), target.Name); fp != "" {
+				markers = append(markers, checksum.FormatDepsComment(fp))
+			}
 		}
 
 		// Create a unique key for the target
@@ -362,65 +542,63 @@ func (g *Generator) replaceAllFunctionsWithChecksum(content string, targets []*p
 		sourceTargetData[key] = &targetData{
 			sourceTarget: target,
 			implBody:     implBody,
-			checksum:     checksumComment,
+			markers:      markers,
 		}
 	}
 
 	// Find and update all target functions in the AST in a single pass
 	processedCount := 0
-	ast.Inspect(node, func(n ast.Node) bool {
-		if funcDecl, ok := n.(*ast.FuncDecl); ok {
-			// Try to match this function with any of our targets
-			for key, data := range sourceTargetData {
-				if g.isTargetFunction(funcDecl, data.sourceTarget) {
-					processedCount++
-
-					// Replace function body with the new implementation
-					funcDecl.Body = data.implBody
-
-					// Remove old doc from file's Comments list if exists
-					if funcDecl.Doc != nil {
-						for i, cg := range node.Comments {
-							if cg == funcDecl.Doc {
-								node.Comments = append(node.Comments[:i], node.Comments[i+1:]...)
-								break
-							}
+	for _, funcDecl := range analysis.FuncDecls(node) {
+		// Try to match this function with any of our targets
+		for key, data := range sourceTargetData {
+			if g.isTargetFunction(funcDecl, data.sourceTarget) {
+				processedCount++
+
+				// Replace function body with the new implementation
+				if data.implBody != nil {
+					analysis.SetFuncBody(node, funcDecl, data.implBody)
+				}
+
+				// Remove old doc from file's Comments list if exists
+				if funcDecl.Doc != nil {
+					for i, cg := range node.Comments {
+						if cg == funcDecl.Doc {
+							node.Comments = append(node.Comments[:i], node.Comments[i+1:]...)
+							break
 						}
 					}
+				}
 
-					// Build new comments: original + checksum
-					var comments []*ast.Comment
-					pos := funcDecl.Pos() - 1
-
-					// Copy original comments from source
-					if data.sourceTarget.FuncDecl.Doc != nil {
-						for i, c := range data.sourceTarget.FuncDecl.Doc.List {
-							comments = append(comments, &ast.Comment{
-								Slash: pos - token.Pos(len(data.sourceTarget.FuncDecl.Doc.List)-i),
-								Text:  c.Text,
-							})
-						}
+				// Build new comments: original + checksum (+ deps fingerprint) or failure
+				var texts []string
+				if data.sourceTarget.FuncDecl.Doc != nil {
+					for _, c := range data.sourceTarget.FuncDecl.Doc.List {
+						texts = append(texts, c.Text)
 					}
+				}
+				texts = append(texts, data.markers...)
 
-					// Add checksum
+				// Position comments immediately before the function declaration
+				var comments []*ast.Comment
+				pos := funcDecl.Pos() - 1
+				for i, text := range texts {
 					comments = append(comments, &ast.Comment{
-						Slash: pos,
-						Text:  data.checksum,
+						Slash: pos - token.Pos(len(texts)-1-i),
+						Text:  text,
 					})
+				}
 
-					// Create and set new doc
-					newDoc := &ast.CommentGroup{List: comments}
-					funcDecl.Doc = newDoc
-					node.Comments = append(node.Comments, newDoc)
+				// Create and set new doc
+				newDoc := &ast.CommentGroup{List: comments}
+				funcDecl.Doc = newDoc
+				node.Comments = append(node.Comments, newDoc)
 
-					// Remove from map to avoid processing again
-					delete(sourceTargetData, key)
-					break
-				}
+				// Remove from map to avoid processing again
+				delete(sourceTargetData, key)
+				break
 			}
 		}
-		return true
-	})
+	}
 
 	if processedCount != len(targets) {
 		// List unprocessed functions for better debugging
@@ -432,7 +610,9 @@ func (g *Generator) replaceAllFunctionsWithChecksum(content string, targets []*p
 			len(targets), processedCount, unprocessed)
 	}
 
-	// Comments have been added to node.Comments during processing
+	// Comments have been added to node.Comments during processing; the printer places them
+	// in the order of the list, so the new doc comments go back to their position
+	sort.Slice(node.Comments, func(i, j int) bool { return node.Comments[i].Pos() < node.Comments[j].Pos() })
 
 	// Format the modified AST back to source code once
 	var buf strings.Builder
@@ -443,6 +623,140 @@ func (g *Generator) replaceAllFunctionsWithChecksum(content string, targets []*p
 	return buf.String(), nil
 }
 
+// spliceRegionBodies writes the generated bodies of functions with a region (// mantra:region)
+// into the text of the file. Their hand-written parts keep their comments, which the printer
+// could not place in a body parsed on its own
+func (g *Generator) spliceRegionBodies(content string, targets []*parser.Target, filePath string) (string, error) {
+	type splice struct {
+		start, end int
+		body       string
+	}
+	var splices []splice
+
+	fset := token.NewFileSet()
+	node, err := goparser.ParseFile(fset, filePath, content, goparser.SkipObjectResolution)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse file content: %w", err)
+	}
+	for _, target := range targets {
+		if target.Region == nil || target.GenerationFailed {
+			continue
+		}
+		for _, funcDecl := range analysis.FuncDecls(node) {
+			if funcDecl.Body != nil && g.isTargetFunction(funcDecl, target) {
+				splices = append(splices, splice{
+					start: fset.Position(funcDecl.Body.Lbrace).Offset,
+					end:   fset.Position(funcDecl.Body.Rbrace).Offset + 1,
+					body:  "{\n" + CleanCode(target.Implementation) + "\n}",
+				})
+				break
+			}
+		}
+	}
+
+	// From the bottom up, so earlier offsets stay valid
+	sort.Slice(splices, func(i, j int) bool { return splices[i].start > splices[j].start })
+	for _, s := range splices {
+		content = content[:s.start] + s.body + content[s.end:]
+	}
+	return content, nil
+}
+
+// renameHelpers renames helpers whose names are declared in the source or destination
+// package, or by helpers of an earlier target of the file. Helpers written for targets of
+// the file before are written again and do not count
+func (g *Generator) renameHelpers(fileInfo *parser.FileInfo, targets []*parser.Target) {
+	owners := make(map[string]bool, len(fileInfo.Targets))
+	for _, target := range fileInfo.Targets {
+		owners[target.GetDisplayName()] = true
+	}
+
+	var taken map[string]bool
+	for _, target := range targets {
+		if target.GenerationFailed || len(target.Helpers) == 0 {
+			continue
+		}
+		if taken == nil {
+			taken = DeclaredNames(func(owner string) bool { return owners[owner] }, filepath.Dir(fileInfo.FilePath), g.config.Dest)
+		}
+		body, helpers, renamed := parser.RenameHelpers(CleanCode(target.Implementation), target.Helpers, taken)
+		if renamed != nil {
+			target.Implementation, target.Helpers = body, helpers
+		}
+		for _, helper := range target.Helpers {
+			taken[helper.Name] = true
+		}
+	}
+}
+
+// removeHelpers removes the helper functions written below the target's declaration in
+// content, the generated file at path
+func (g *Generator) removeHelpers(content string, target *parser.Target, path string) (string, error) {
+	fset := token.NewFileSet()
+	node, err := goparser.ParseFile(fset, path, content, goparser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated file: %w", err)
+	}
+	for _, funcDecl := range analysis.FuncDecls(node) {
+		if g.isTargetFunction(funcDecl, target) {
+			start := fset.Position(funcDecl.End()).Offset
+			end := fset.Position(helpersEnd(node, funcDecl, target.GetDisplayName())).Offset
+			return content[:start] + content[end:], nil
+		}
+	}
+	return content, nil
+}
+
+// insertHelpers writes the helper functions of each generated target below it, marked with
+// the target they belong to
+func (g *Generator) insertHelpers(content string, targets []*parser.Target, filePath string) (string, error) {
+	type insertion struct {
+		offset int
+		text   string
+	}
+	var insertions []insertion
+	var node *ast.File
+	var fset *token.FileSet
+	for _, target := range targets {
+		if target.GenerationFailed || len(target.Helpers) == 0 {
+			continue
+		}
+		if node == nil {
+			fset = token.NewFileSet()
+			var err error
+			node, err = goparser.ParseFile(fset, filePath, content, goparser.ParseComments)
+			if err != nil {
+				return "", fmt.Errorf("failed to parse file content: %w", err)
+			}
+		}
+		for _, funcDecl := range analysis.FuncDecls(node) {
+			if !g.isTargetFunction(funcDecl, target) {
+				continue
+			}
+			var text strings.Builder
+			for _, helper := range target.Helpers {
+				text.WriteString("\n\n" + checksum.FormatHelperComment(target.GetDisplayName()) + "\n" + helper.Source())
+			}
+			insertions = append(insertions, insertion{fset.Position(funcDecl.End()).Offset, text.String()})
+			break
+		}
+	}
+	if len(insertions) == 0 {
+		return content, nil
+	}
+
+	// Insert from the bottom so earlier offsets stay valid
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].offset > insertions[j].offset })
+	for _, ins := range insertions {
+		content = content[:ins.offset] + ins.text + content[ins.offset:]
+	}
+	formatted, err := format.Source([]byte(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to format helpers: %w", err)
+	}
+	return string(formatted), nil
+}
+
 // getTargetKey creates a unique key for a target function
 func (g *Generator) getTargetKey(target *parser.Target) string {
 	if target.Receiver != nil {
@@ -464,14 +778,57 @@ func (g *Generator) isTargetFunction(funcDecl *ast.FuncDecl, target *parser.Targ
 			return false
 		}
 
-		receiverType := analysis.ExtractTypeString(funcDecl.Recv.List[0].Type)
-		return receiverType == target.Receiver.Type
+		// Compare the receiver base types rather than their spelling, so (*T), T[K] and
+		// aliases declared elsewhere in the source package all match
+		want := strings.TrimPrefix(target.Receiver.Type, "*")
+		if target.FuncDecl != nil && target.FuncDecl.Recv != nil && len(target.FuncDecl.Recv.List) > 0 {
+			want = analysis.ReceiverTypeName(target.FuncDecl.Recv.List[0].Type)
+		}
+		got := analysis.ReceiverTypeName(funcDecl.Recv.List[0].Type)
+		if got == want {
+			return true
+		}
+		aliases := g.receiverAliases(target)
+		if name, ok := aliases[got]; ok {
+			got = name
+		}
+		if name, ok := aliases[want]; ok {
+			want = name
+		}
+		return got != "" && got == want
 	}
 
 	// For functions (no receiver), ensure funcDecl also has no receiver
 	return funcDecl.Recv == nil
 }
 
+// receiverAliases returns the local type aliases of the package the target is declared
+// in, read from every non-test file of its directory
+func (g *Generator) receiverAliases(target *parser.Target) map[string]string {
+	if target.FilePath == "" {
+		return nil
+	}
+	dir := filepath.Dir(target.FilePath)
+	if aliases, ok := g.aliases[dir]; ok {
+		return aliases
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		if file, err := goparser.ParseFile(fset, path, nil, goparser.SkipObjectResolution); err == nil {
+			files = append(files, file)
+		}
+	}
+	aliases := analysis.TypeAliases(files...)
+	g.aliases[dir] = aliases
+	return aliases
+}
+
 // parseImplementationAsBlockWithFileSet parses implementation code as a block statement.
 // It uses the provided FileSet to maintain position consistency with the original file.
 func (g *Generator) parseImplementationAsBlockWithFileSet(implementation string, fset *token.FileSet) (*ast.BlockStmt, error) {
@@ -496,9 +853,31 @@ func (g *Generator) parseImplementationAsBlockWithFileSet(implementation string,
 	return funcDecl.Body, nil
 }
 
-// cleanCode removes markdown formatting and extracts function body from AI responses.
+// FormatBody returns the statements of a generated implementation formatted as they would
+// appear in the generated file, one level of indentation less, for insertion into an editor
+func FormatBody(implementation string) (string, error) {
+	src := fmt.Sprintf("package main\nfunc test() {\n%s\n}\n", CleanCode(implementation))
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return "", fmt.Errorf("implementation is not valid Go code: %w", err)
+	}
+
+	code := string(formatted)
+	start := strings.Index(code, "{\n") + len("{\n")
+	end := strings.LastIndex(code, "}")
+	if start < len("{\n") || end < start {
+		return "", nil
+	}
+	lines := strings.Split(strings.TrimRight(code[start:end], "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, "\t")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// CleanCode removes markdown formatting and extracts function body from AI responses.
 // It handles cases where the AI includes function signatures or markdown code blocks.
-func cleanCode(response string) string {
+func CleanCode(response string) string {
 	response = strings.TrimSpace(response)
 
 	// First, check if the response contains markdown code blocks
@@ -0,0 +1,919 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestTargetStubsPreservesHandWrittenDeclarations(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "shop.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package shop
+
+type Cart struct {
+	items []int
+}
+
+// mantra: Sum all item prices
+func (c *Cart) Total() int {
+	panic("not implemented")
+}
+
+// mantra: Apply a percentage discount
+func Discount(price, percent int) int {
+	panic("not implemented")
+}
+
+// mantra: Count items in the cart
+func (c *Cart) Count() int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+
+	// The generated file predates Count and has hand-written additions
+	existing := `package generated
+
+// Code generated by mantra; DO NOT EDIT.
+
+type Cart struct {
+	items []int
+}
+
+// maxDiscount caps discounts; added by hand
+var maxDiscount = 50
+
+// mantra: Sum all item prices
+func (c *Cart) Total() int {
+	// sum prices
+	total := 0
+	for _, item := range c.items {
+		total += item
+	}
+	return total
+}
+
+// mantra: Apply a percentage discount
+func Discount(price, percent int) int {
+	return price - price*clamp(percent)/100
+}
+
+func clamp(percent int) int {
+	if percent > maxDiscount {
+		return maxDiscount
+	}
+	return percent
+}
+`
+	outputFile := filepath.Join(destDir, "shop.go")
+	if err := os.WriteFile(outputFile, []byte(existing), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "shop"})
+	data, err := gen.TargetStubs(fileInfo, map[string]bool{"Discount": true, "Cart.Count": true})
+	if err != nil {
+		t.Fatalf("TargetStubs failed: %v", err)
+	}
+	content := string(data)
+
+	onDisk, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if string(onDisk) != existing {
+		t.Errorf("Expected the existing file to be left untouched, got:\n%s", onDisk)
+	}
+
+	for _, want := range []string{
+		"var maxDiscount = 50",
+		"// maxDiscount caps discounts; added by hand",
+		"func clamp(percent int) int {\n\tif percent > maxDiscount {",
+		"\tfor _, item := range c.items {\n\t\ttotal += item\n\t}",
+		"// sum prices",
+		"func Discount(price, percent int) int {\n\tpanic(\"not implemented\")\n}",
+		"func (c *Cart) Count() int {\n\tpanic(\"not implemented\")\n}",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "price*clamp(percent)") {
+		t.Errorf("Expected Discount body to be replaced by a stub, got:\n%s", content)
+	}
+	if strings.Count(content, "func (c *Cart) Total()") != 1 {
+		t.Errorf("Expected Total to appear exactly once, got:\n%s", content)
+	}
+}
+
+func TestTargetStubsDropsCommentsOfReplacedBody(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "calc.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package calc
+
+// mantra: Double the input
+func Double(n int) int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+
+	existing := `package generated
+
+// mantra: Double the input
+func Double(n int) int {
+	// multiply by two
+	return n * 2
+}
+
+// helper stays as written
+func helper() {}
+`
+	outputFile := filepath.Join(destDir, "calc.go")
+	if err := os.WriteFile(outputFile, []byte(existing), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "calc"})
+	data, err := gen.TargetStubs(fileInfo, map[string]bool{"Double": true})
+	if err != nil {
+		t.Fatalf("TargetStubs failed: %v", err)
+	}
+	content := string(data)
+
+	if strings.Contains(content, "multiply by two") {
+		t.Errorf("Expected comments of the replaced body to be dropped, got:\n%s", content)
+	}
+	if !strings.Contains(content, "// helper stays as written\nfunc helper() {}") {
+		t.Errorf("Expected helper to be preserved verbatim, got:\n%s", content)
+	}
+}
+
+func TestGenerateFilePreservesFileHeader(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "shop.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	header := `//go:build linux && !race
+
+// Package shop implements the checkout flow.
+// Everything in package shop is safe for concurrent use.
+package shop // import "example.com/shop"
+`
+	if err := os.WriteFile(source, []byte(header+`
+//go:generate stringer -type=Status
+
+type Status int
+
+// mantra: Double the input
+func Double(n int) int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "shop"})
+	results := []*parser.GenerationResult{{Target: fileInfo.Targets[0], Success: true, Implementation: "return n * 2"}}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "shop.go"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	content := string(data)
+
+	wantPrefix := strings.Replace(header, "package shop //", "package generated //", 1) +
+		"\n// Code generated by mantra; DO NOT EDIT.\n\n//go:generate stringer -type=Status\n"
+	if !strings.HasPrefix(content, wantPrefix) {
+		t.Errorf("Expected output to start with:\n%s\ngot:\n%s", wantPrefix, content)
+	}
+	if !strings.Contains(content, "return n * 2") {
+		t.Errorf("Expected implementation in output, got:\n%s", content)
+	}
+}
+
+func TestGenerateFileBlankImports(t *testing.T) {
+	source := `package shop
+
+import (
+	_ "strconv"
+	_ "strings"
+)
+
+// mantra: Format the quantity
+func Label(n int) string {
+	panic("not implemented")
+}
+`
+	generate := func(t *testing.T, convert bool) (string, []string) {
+		tempDir := t.TempDir()
+		sourcePath := filepath.Join(tempDir, "shop.go")
+		if err := os.WriteFile(sourcePath, []byte(source), 0644); err != nil {
+			t.Fatalf("Failed to write source: %v", err)
+		}
+		fileInfo, err := parser.ParseFileInfo(sourcePath)
+		if err != nil {
+			t.Fatalf("Failed to parse source: %v", err)
+		}
+
+		destDir := filepath.Join(tempDir, "generated")
+		gen := New(&Config{Dest: destDir, PackageName: "generated", ConvertBlankImports: convert})
+		results := []*parser.GenerationResult{{Target: fileInfo.Targets[0], Success: true, Implementation: "return strconv.Itoa(n)"}}
+		if err := gen.GenerateFile(fileInfo, results); err != nil {
+			t.Fatalf("GenerateFile failed: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(destDir, "shop.go"))
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		return string(data), gen.ConvertedBlankImports(sourcePath)
+	}
+
+	t.Run("kept by default", func(t *testing.T) {
+		content, converted := generate(t, false)
+		for _, want := range []string{`_ "strconv"`, `_ "strings"`, "\t\"strconv\"\n"} {
+			if !strings.Contains(content, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, content)
+			}
+		}
+		if len(converted) != 0 {
+			t.Errorf("Expected no conversions, got %v", converted)
+		}
+	})
+
+	t.Run("converted when used", func(t *testing.T) {
+		content, converted := generate(t, true)
+		if strings.Contains(content, `_ "strconv"`) || !strings.Contains(content, "\t\"strconv\"\n") {
+			t.Errorf("Expected strconv to be converted, got:\n%s", content)
+		}
+		if !strings.Contains(content, `_ "strings"`) {
+			t.Errorf("Expected unused strings import to stay blank, got:\n%s", content)
+		}
+		if len(converted) != 1 || converted[0] != "strconv" {
+			t.Errorf("Expected strconv conversion to be recorded, got %v", converted)
+		}
+	})
+}
+
+func TestRestoreImplementation(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "calc.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package calc
+
+// mantra: Double the input
+func Double(n int) int {
+	panic("not implemented")
+}
+
+// mantra: Format the input
+func Label(n int) string {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "calc"})
+	results := []*parser.GenerationResult{
+		{Target: fileInfo.Targets[0], Success: true, Implementation: "return n + n"},
+		{
+			Target:         fileInfo.Targets[1],
+			Success:        true,
+			Implementation: "return quote(n)",
+			Helpers:        []parser.Helper{{Name: "quote", Signature: "(n int) string", Body: `return "n"`}},
+		},
+	}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	helpers := []parser.Helper{{Name: "itoa", Signature: "(n int) string", Body: "return strconv.Itoa(n)"}}
+	if err := gen.RestoreImplementation(fileInfo, fileInfo.Targets[1], "return itoa(n)", helpers); err != nil {
+		t.Fatalf("RestoreImplementation failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "calc.go"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{"return n + n", "return itoa(n)", "func itoa(n int) string", "return strconv.Itoa(n)", `import "strconv"`} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "quote") {
+		t.Errorf("Expected the restored body and helpers to replace the old ones, got:\n%s", content)
+	}
+	if got := strings.Count(content, "// mantra:v3:"); got != 2 {
+		t.Errorf("Expected 2 checksum comments, got %d:\n%s", got, content)
+	}
+}
+
+func TestTargetStubsMatchesNormalizedReceivers(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "shop.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	// The alias is declared in another file of the source package
+	if err := os.WriteFile(filepath.Join(tempDir, "types.go"), []byte(`package shop
+
+type Cart struct {
+	items []int
+}
+
+type Basket = Cart
+
+type Shelf struct {
+	items []int
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write types: %v", err)
+	}
+	if err := os.WriteFile(source, []byte(`package shop
+
+// mantra: Count items in the basket
+func (c (*Basket)) Count() int {
+	panic("not implemented")
+}
+
+// mantra: Count items on the shelf
+func (s (Shelf)) Count() int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+
+	// The generated file spells the receivers differently from the source
+	outputFile := filepath.Join(destDir, "shop.go")
+	if err := os.WriteFile(outputFile, []byte(`package generated
+
+// mantra: Count items in the basket
+func (c *Cart) Count() int {
+	return len(c.items)
+}
+
+// mantra: Count items on the shelf
+func (s Shelf) Count() int {
+	return len(s.items)
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	if got := fileInfo.Targets[0].Receiver.Type; got != "*Basket" {
+		t.Errorf("Expected receiver type *Basket, got %s", got)
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "shop"})
+	data, err := gen.TargetStubs(fileInfo, map[string]bool{"(Shelf).Count": true})
+	if err != nil {
+		t.Fatalf("TargetStubs failed: %v", err)
+	}
+	content := string(data)
+
+	if !strings.Contains(content, "return len(c.items)") {
+		t.Errorf("Expected the basket implementation to be preserved, got:\n%s", content)
+	}
+	if strings.Contains(content, "return len(s.items)") {
+		t.Errorf("Expected the shelf body to be replaced by a stub, got:\n%s", content)
+	}
+	if got := strings.Count(content, ") Count() int"); got != 2 {
+		t.Errorf("Expected 2 Count methods, got %d:\n%s", got, content)
+	}
+}
+
+func TestGenerateFileMethodsSharingName(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "shop.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	// The alias is declared in another file of the source package
+	if err := os.WriteFile(filepath.Join(tempDir, "types.go"), []byte(`package shop
+
+type Cart struct {
+	items []int
+}
+
+type Basket = Cart
+
+type Shelf struct {
+	items []int
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write types: %v", err)
+	}
+	if err := os.WriteFile(source, []byte(`package shop
+
+// mantra: Count items in the basket
+func (c (*Basket)) Count() int {
+	panic("not implemented")
+}
+
+// mantra: Count items on the shelf
+func (s (Shelf)) Count() int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+
+	// The generated file spells the receivers differently from the source
+	outputFile := filepath.Join(destDir, "shop.go")
+	if err := os.WriteFile(outputFile, []byte(`package generated
+
+// mantra: Count items in the basket
+func (c *Cart) Count() int {
+	panic("not implemented")
+}
+
+// mantra: Count items on the shelf
+func (s Shelf) Count() int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	if got := fileInfo.Targets[0].Receiver.Type; got != "*Basket" {
+		t.Errorf("Expected receiver type *Basket, got %s", got)
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "shop"})
+	results := []*parser.GenerationResult{
+		{Target: fileInfo.Targets[0], Success: true, Implementation: "return len(c.items)"},
+		{Target: fileInfo.Targets[1], Success: true, Implementation: "return len(s.items) * 2"},
+	}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{"return len(c.items)\n", "return len(s.items) * 2\n"} {
+		if strings.Count(content, want) != 1 {
+			t.Errorf("Expected %q exactly once in output, got:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "not implemented") {
+		t.Errorf("Expected every target to be implemented, got:\n%s", content)
+	}
+}
+
+func TestGenerateFileFuncVar(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "parse.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package parse
+
+import "strings"
+
+// mantra: Split s on commas
+var Split = func(s string) []string {
+	panic("not implemented")
+}
+
+// mantra: Join items with commas
+var Join = func(items []string) string {
+	panic("not implemented")
+}
+
+var _ = strings.TrimSpace
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	if len(fileInfo.Targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(fileInfo.Targets))
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "parse"})
+
+	// Targets missing from an existing file are appended as variable stubs
+	existing := filepath.Join(destDir, "parse.go")
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+	if err := os.WriteFile(existing, []byte("package generated\n"), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+	stubs, err := gen.TargetStubs(fileInfo, map[string]bool{"Split": true, "Join": true})
+	if err != nil {
+		t.Fatalf("TargetStubs failed: %v", err)
+	}
+	if !strings.Contains(string(stubs), "var Split = func(s string) []string {\n\tpanic(\"not implemented\")\n}") {
+		t.Errorf("Expected a stub of the variable, got:\n%s", stubs)
+	}
+	if err := os.Remove(existing); err != nil {
+		t.Fatalf("Failed to remove existing file: %v", err)
+	}
+
+	results := []*parser.GenerationResult{
+		{Target: fileInfo.Targets[0], Success: true, Implementation: "return strings.Split(s, \",\")"},
+		{Target: fileInfo.Targets[1], Success: false, FailureReason: &parser.FailureReason{Phase: "implementation", Message: "gave up"}},
+	}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "var Split = func(s string) []string {\n\treturn strings.Split(s, \",\")\n}") {
+		t.Errorf("Expected the literal's body to be replaced, got:\n%s", content)
+	}
+	// The checksum marks the variable, not the literal inside it
+	before, _, _ := strings.Cut(content, "var Split")
+	if lines := strings.Split(strings.TrimSuffix(before, "\n"), "\n"); !strings.HasPrefix(lines[len(lines)-1], "// mantra:") {
+		t.Errorf("Expected mantra comments right above var Split, got:\n%s", content)
+	}
+	if !strings.Contains(content, "var Join = func(items []string) string {\n\tpanic(\"not implemented\")\n}") {
+		t.Errorf("Expected the failed target to keep its stub, got:\n%s", content)
+	}
+}
+
+func TestGenerateFileRegion(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "calc.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package calc
+
+// Sum adds the items up
+func Sum(items []int) int {
+	// nothing to add
+	if len(items) == 0 {
+		return 0
+	}
+
+	// mantra:region total add up the items
+	panic("not implemented")
+	// mantra:end
+}
+
+// Count is hand-written
+func Count(items []int) int {
+	// no generation here
+	return len(items)
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	if len(fileInfo.Targets) != 1 || fileInfo.Targets[0].Region == nil {
+		t.Fatalf("Expected one region target, got %+v", fileInfo.Targets)
+	}
+
+	target := fileInfo.Targets[0]
+	implementation := target.Region.Splice("total := 0\nfor _, item := range items {\n\ttotal += item\n}\nreturn total")
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "calc"})
+	if err := gen.GenerateFile(fileInfo, []*parser.GenerationResult{{Target: target, Success: true, Implementation: implementation}}); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "calc.go"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	content := string(data)
+
+	// The hand-written parts keep their comments, and the doc comments stay with their functions
+	want := `func Sum(items []int) int {
+	// nothing to add
+	if len(items) == 0 {
+		return 0
+	}
+
+	// mantra:region total add up the items
+	total := 0
+	for _, item := range items {
+		total += item
+	}
+	return total
+	// mantra:end
+}
+
+// Count is hand-written
+func Count(items []int) int {
+	// no generation here
+	return len(items)
+}
+`
+	if !strings.Contains(content, want) {
+		t.Errorf("Expected the region spliced into the hand-written body, got:\n%s", content)
+	}
+	if !strings.Contains(content, "// Sum adds the items up\n// mantra:v") {
+		t.Errorf("Expected the checksum below the doc comment of Sum, got:\n%s", content)
+	}
+}
+
+func TestGenerateFileHelpers(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "text.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package text
+
+type Counter struct{}
+
+// mantra: Count the words of s
+func (c *Counter) Words(s string) int {
+	panic("not implemented")
+}
+
+// mantra: Count the vowels of s
+func Vowels(s string) int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	results := []*parser.GenerationResult{
+		{
+			Target:         fileInfo.Targets[0],
+			Success:        true,
+			Implementation: "return len(fields(s))",
+			Helpers:        []parser.Helper{{Name: "fields", Signature: "(s string) []string", Body: "return strings.Fields(s)"}},
+		},
+		{
+			Target:         fileInfo.Targets[1],
+			Success:        false,
+			FailureReason:  &parser.FailureReason{Phase: "implementation", Message: "gave up"},
+			Implementation: "return countVowels(s)",
+			Helpers:        []parser.Helper{{Name: "countVowels", Signature: "(s string) int", Body: "return 0"}},
+		},
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "text"})
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "text.go"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	content := string(data)
+	want := "\treturn len(fields(s))\n}\n\n// mantra:helper:(*Counter).Words\nfunc fields(s string) []string {\n\treturn strings.Fields(s)\n}\n"
+	if !strings.Contains(content, want) {
+		t.Errorf("Expected the helper below its target, got:\n%s", content)
+	}
+	if !strings.Contains(content, `import "strings"`) {
+		t.Errorf("Expected the import used by the helper, got:\n%s", content)
+	}
+	if strings.Contains(content, "countVowels") {
+		t.Errorf("Expected no helpers for the failed target, got:\n%s", content)
+	}
+}
+
+func TestGenerateFileRenamesCollidingHelpers(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "text.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package text
+
+// mantra: Count the words of s
+func Words(s string) int {
+	panic("not implemented")
+}
+
+// mantra: Count the lines of s
+func Lines(s string) int {
+	panic("not implemented")
+}
+
+func normalize(s string) string {
+	return s
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	// A helper written for a target of another file
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(destDir, "other.go"), []byte("package generated\n\n// mantra:helper:Other\nfunc count(s string) int {\n\treturn len(s)\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write other file: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	results := []*parser.GenerationResult{
+		{
+			Target:         fileInfo.Targets[0],
+			Success:        true,
+			Implementation: "return count(normalize(s))",
+			Helpers: []parser.Helper{
+				{Name: "normalize", Signature: "(s string) string", Body: "return s"},
+				{Name: "count", Signature: "(s string) int", Body: "return len(s)"},
+			},
+		},
+		{
+			Target:         fileInfo.Targets[1],
+			Success:        true,
+			Implementation: "return count(normalize(s))",
+			Helpers: []parser.Helper{
+				{Name: "normalize", Signature: "(s string) string", Body: "return s"},
+				{Name: "count", Signature: "(s string) int", Body: "return len(s) + 1"},
+			},
+		},
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "text"})
+	for range 2 {
+		// Helpers written before by targets of the file do not count as taken
+		if err := gen.GenerateFile(fileInfo, results); err != nil {
+			t.Fatalf("GenerateFile failed: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(destDir, "text.go"))
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		content := string(data)
+		for _, want := range []string{
+			"func Words(s string) int {\n\treturn count2(normalize2(s))\n}",
+			"func normalize2(s string) string",
+			"func count2(s string) int",
+			"func Lines(s string) int {\n\treturn count3(normalize3(s))\n}",
+			"func normalize3(s string) string",
+			"func count3(s string) int {\n\treturn len(s) + 1\n}",
+		} {
+			if !strings.Contains(content, want) {
+				t.Errorf("Expected %q in the output, got:\n%s", want, content)
+			}
+		}
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	impl := "```go\nif x==nil {\nreturn errors.New(\"nil\")\n}\nreturn nil\n```"
+	body, err := FormatBody(impl)
+	if err != nil {
+		t.Fatalf("Failed to format body: %v", err)
+	}
+	want := "if x == nil {\n\treturn errors.New(\"nil\")\n}\nreturn nil"
+	if body != want {
+		t.Errorf("Expected body:\n%s\ngot:\n%s", want, body)
+	}
+
+	if _, err := FormatBody("return (("); err == nil {
+		t.Error("Expected an error for invalid code")
+	}
+}
+
+func TestGenerateFileSummary(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "shop.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package shop
+
+type Cart struct {
+	items []int
+}
+
+// mantra: Sum the items
+func (c *Cart) Total() int {
+	panic("not implemented")
+}
+
+// mantra: Apply a percentage discount
+func Discount(price, percent int) int {
+	panic("not implemented")
+}
+
+// mantra: Count the items
+func Count(items []int) int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	total, discount, count := fileInfo.Targets[0], fileInfo.Targets[1], fileInfo.Targets[2]
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	previous := "package generated\n\n" + summaryHeader + "\n//   Count  old-model  2025-01-01T00:00:00Z\n"
+	if err := os.WriteFile(filepath.Join(destDir, "shop.go"), []byte(previous), 0644); err != nil {
+		t.Fatalf("Failed to write previous output: %v", err)
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "shop", Summary: true, Model: "gpt-test"})
+	results := []*parser.GenerationResult{
+		{Target: discount, Success: false, FailureReason: &parser.FailureReason{Phase: "implementation", Message: "gave up"}},
+		{Target: total, Success: true, Rule: "getter", Implementation: "return len(c.items)"},
+		{Target: count, Success: true, Reused: true, Implementation: "return len(items)"},
+	}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "shop.go"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	_, block, ok := strings.Cut(string(data), summaryHeader+"\n")
+	if !ok {
+		t.Fatalf("Expected a summary block, got:\n%s", data)
+	}
+
+	// Entries follow the source order; the reused target keeps its entry
+	lines := strings.Split(strings.TrimSpace(block), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 entries, got:\n%s", block)
+	}
+	if !strings.HasPrefix(lines[0], "//   (*Cart).Total  rule: getter  ") || !strings.HasPrefix(lines[1], "//   Discount       failed        ") {
+		t.Errorf("Expected entries for Total and Discount, got:\n%s", block)
+	}
+	if lines[2] != "//   Count          old-model     2025-01-01T00:00:00Z" {
+		t.Errorf("Expected the previous entry of Count, got %q", lines[2])
+	}
+	if entry := readSummary(string(data))["(*Cart).Total"]; entry.model != "rule: getter" || entry.at == "-" {
+		t.Errorf("Expected the entry of Total to be read back, got %+v", entry)
+	}
+}
@@ -2,9 +2,12 @@ package codegen
 
 import (
 	"fmt"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
 )
 
 // addImports adds required imports to the file content
@@ -20,6 +23,10 @@ func (g *Generator) addImports(content string, requiredImports []string) string
 	// Check existing imports
 	existingImports := make(map[string]bool)
 	for _, imp := range node.Imports {
+		// A blank import does not make the package name available
+		if imp.Name != nil && imp.Name.Name == "_" {
+			continue
+		}
 		path := strings.Trim(imp.Path.Value, `"`)
 		existingImports[path] = true
 	}
@@ -36,70 +43,33 @@ func (g *Generator) addImports(content string, requiredImports []string) string
 		return content // No new imports needed
 	}
 
-	// Find the position to insert imports
-	lines := strings.Split(content, "\n")
-	packageLineIdx := -1
-	existingImportStart := -1
-	existingImportEnd := -1
-
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "package ") {
-			packageLineIdx = i
-		} else if strings.HasPrefix(trimmed, "import (") {
-			existingImportStart = i
-			// Find the closing parenthesis
-			for j := i + 1; j < len(lines); j++ {
-				if strings.TrimSpace(lines[j]) == ")" {
-					existingImportEnd = j
-					break
-				}
-			}
-			break
-		} else if strings.HasPrefix(trimmed, "import ") {
-			existingImportStart = i
-			existingImportEnd = i
-			break
-		}
+	// Insert through the AST so single-line and grouped import declarations are both handled
+	for _, imp := range newImports {
+		astutil.AddImport(fset, node, imp)
 	}
 
-	// Build the new content
-	var result []string
-
-	if existingImportStart >= 0 {
-		// Add to existing imports
-		result = append(result, lines[:existingImportEnd]...)
-		for _, imp := range newImports {
-			result = append(result, fmt.Sprintf("\t\"%s\"", imp))
-		}
-		result = append(result, lines[existingImportEnd:]...)
-	} else if packageLineIdx >= 0 {
-		// No existing imports, add new import block after package declaration
-		result = append(result, lines[:packageLineIdx+1]...)
-		result = append(result, "")
-		result = append(result, "import (")
-		for _, imp := range newImports {
-			result = append(result, fmt.Sprintf("\t\"%s\"", imp))
-		}
-		result = append(result, ")")
-		result = append(result, lines[packageLineIdx+1:]...)
-	} else {
-		// Fallback: just return original content
-		return content
+	var buf strings.Builder
+	if err := format.Node(&buf, fset, node); err != nil {
+		return g.addImportsSimple(content, newImports)
 	}
-
-	return strings.Join(result, "\n")
+	return buf.String()
 }
 
-// convertBlankImports converts blank imports (_ "package") to regular imports
-func (g *Generator) convertBlankImports(content string) string {
+// convertBlankImports converts the given blank imports (_ "package") to regular imports
+func (g *Generator) convertBlankImports(content string, paths []string) string {
+	if len(paths) == 0 {
+		return content
+	}
 	lines := strings.Split(content, "\n")
 	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		// Check for blank import pattern: _ "package/path"
-		if strings.HasPrefix(trimmed, `_ "`) {
-			// Remove the underscore and space
-			lines[i] = strings.Replace(line, `_ "`, `"`, 1)
+		// Match both `_ "path"` in an import block and `import _ "path"`
+		spec := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "import"))
+		for _, path := range paths {
+			blank := fmt.Sprintf(`_ "%s"`, path)
+			if strings.HasPrefix(spec, blank) {
+				lines[i] = strings.Replace(line, blank, fmt.Sprintf(`"%s"`, path), 1)
+				break
+			}
 		}
 	}
 	return strings.Join(lines, "\n")
@@ -0,0 +1,106 @@
+package codegen
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rail44/mantra/internal/checksum"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// DeclaredNames returns the identifiers declared at package level, or imported, by the Go
+// files of dirs: the names a helper function must not take. Helpers of the targets skip
+// reports by display name are left out, as they are written again with their target.
+// Files of external test packages and files that do not parse are ignored
+func DeclaredNames(skip func(owner string) bool, dirs ...string) map[string]bool {
+	names := make(map[string]bool)
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			node, err := goparser.ParseFile(token.NewFileSet(), file, nil, goparser.ParseComments|goparser.SkipObjectResolution)
+			if err != nil || strings.HasSuffix(node.Name.Name, "_test") {
+				continue
+			}
+			addDeclaredNames(names, node, skip)
+		}
+	}
+	delete(names, "_")
+	return names
+}
+
+// addDeclaredNames adds the package-level and imported identifiers of file to names
+func addDeclaredNames(names map[string]bool, file *ast.File, skip func(owner string) bool) {
+	for _, spec := range file.Imports {
+		if spec.Name != nil {
+			if spec.Name.Name != "." {
+				names[spec.Name.Name] = true
+			}
+			continue
+		}
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+			names[path.Base(importPath)] = true
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil || d.Name.Name == "init" || isSkippedHelper(d, skip) {
+				continue
+			}
+			names[d.Name.Name] = true
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names[s.Name.Name] = true
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						names[name.Name] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// isSkippedHelper reports whether funcDecl is a helper of a target skip reports
+func isSkippedHelper(funcDecl *ast.FuncDecl, skip func(owner string) bool) bool {
+	owner, ok := helperOwner(funcDecl)
+	return ok && skip != nil && skip(owner)
+}
+
+// helperOwner returns the display name of the target funcDecl is a helper function of
+func helperOwner(funcDecl *ast.FuncDecl) (string, bool) {
+	if funcDecl.Doc == nil {
+		return "", false
+	}
+	for _, comment := range funcDecl.Doc.List {
+		if owner, ok := checksum.ParseHelperComment(comment.Text); ok {
+			return owner, true
+		}
+	}
+	return "", false
+}
+
+// RenameHelpers renames the helpers of target whose names are declared in the source package
+// or the destination package, as parser.RenameHelpers does
+func RenameHelpers(body string, helpers []parser.Helper, target *parser.Target, destDir string) (string, []parser.Helper, map[string]string) {
+	if len(helpers) == 0 {
+		return body, helpers, nil
+	}
+	owner := target.GetDisplayName()
+	taken := DeclaredNames(func(o string) bool { return o == owner }, filepath.Dir(target.FilePath), destDir)
+	return parser.RenameHelpers(body, helpers, taken)
+}
@@ -0,0 +1,347 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	goparser "go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/rail44/mantra/internal/analysis"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// TargetFileName names the file a target is written to when every target gets a file of
+// its own: the source file's name, the receiver type for methods and the function name,
+// e.g. user_service_createuser_gen.go
+func TargetFileName(sourcePath string, target *parser.Target) string {
+	parts := []string{strings.TrimSuffix(filepath.Base(sourcePath), ".go")}
+	if target.Receiver != nil {
+		receiver := strings.TrimPrefix(target.Receiver.Type, "*")
+		if target.FuncDecl != nil && target.FuncDecl.Recv != nil && len(target.FuncDecl.Recv.List) > 0 {
+			receiver = analysis.ReceiverTypeName(target.FuncDecl.Recv.List[0].Type)
+		}
+		parts = append(parts, receiver)
+	}
+	parts = append(parts, target.Name)
+	return strings.ToLower(strings.Join(parts, "_")) + "_gen.go"
+}
+
+// CheckTargetFileNames returns an error if targets of files would be written to the same file
+// of their own, or to a file named like a source file, which is copied to the destination.
+// TargetFileName lowercases names and joins them with underscores, so user.go with
+// Service.CreateUser and user_service.go with CreateUser would share a file, as would Get
+// and get
+func CheckTargetFileNames(files []*parser.FileInfo) error {
+	owners := make(map[string]string)
+	for _, fileInfo := range files {
+		name := filepath.Base(fileInfo.FilePath)
+		owners[strings.ToLower(name)] = "source file " + name
+	}
+	for _, fileInfo := range files {
+		for _, target := range fileInfo.Targets {
+			name := TargetFileName(fileInfo.FilePath, target)
+			owner := fmt.Sprintf("%s of %s", target.GetDisplayName(), filepath.Base(fileInfo.FilePath))
+			if other, ok := owners[name]; ok {
+				return fmt.Errorf("per_target_files: %s and %s would both be written to %s; rename one of them", other, owner, name)
+			}
+			owners[name] = owner
+		}
+	}
+	return nil
+}
+
+// TargetPath returns the destination of a target's own file
+func (g *Generator) TargetPath(sourcePath string, target *parser.Target) string {
+	return filepath.Join(g.config.Dest, TargetFileName(sourcePath, target))
+}
+
+// WrittenFiles returns the files written for the source file by the last GenerateFile
+func (g *Generator) WrittenFiles(sourcePath string) []string {
+	return g.written[sourcePath]
+}
+
+// Stubs returns the content of every file generated from the source file while generation
+// is in progress, keyed by destination path. See TargetStubs
+func (g *Generator) Stubs(fileInfo *parser.FileInfo, targetsToGenerate map[string]bool) (map[string][]byte, error) {
+	if !g.config.PerTargetFiles {
+		content, err := g.TargetStubs(fileInfo, targetsToGenerate)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{g.OutputPath(fileInfo.FilePath): content}, nil
+	}
+
+	// Every target starts as a stub; files of targets that are kept are read back below
+	results := make([]*parser.GenerationResult, len(fileInfo.Targets))
+	for i, target := range fileInfo.Targets {
+		results[i] = &parser.GenerationResult{Target: target}
+	}
+	content, err := g.generateFileContent(fileInfo, results, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate file content: %w", err)
+	}
+	files, err := g.splitTargets(fileInfo, content)
+	if err != nil {
+		return nil, err
+	}
+
+	stubs := make(map[string][]byte, len(files))
+	for path, content := range files {
+		stubs[path] = []byte(content)
+	}
+	for _, target := range fileInfo.Targets {
+		if targetsToGenerate[target.GetDisplayName()] {
+			continue
+		}
+		path := g.TargetPath(fileInfo.FilePath, target)
+		if existing, err := os.ReadFile(path); err == nil {
+			stubs[path] = existing
+		}
+	}
+	return stubs, nil
+}
+
+// targetFileHeader marks a target's own file with the source file it was generated from,
+// so files of targets that were removed or renamed can be found
+func targetFileHeader(sourcePath string) string {
+	return fmt.Sprintf("// Code generated by mantra from %s; DO NOT EDIT.", filepath.Base(sourcePath))
+}
+
+// splitTargets moves the target functions of a generated file into files of their own.
+// It returns the content of every file keyed by destination path: the generated file
+// without the targets, and one file per target with the imports it uses
+func (g *Generator) splitTargets(fileInfo *parser.FileInfo, content string) (map[string]string, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, fileInfo.FilePath, content, goparser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse generated content: %w", err)
+	}
+
+	// Build constraints apply to the targets as much as to the rest of the file
+	var constraint string
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if strings.HasPrefix(comment.Text, "//go:build ") {
+				constraint = comment.Text
+			}
+		}
+	}
+
+	var importDecls []string
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			importDecls = append(importDecls, content[fset.Position(gen.Pos()).Offset:fset.Position(gen.End()).Offset])
+		}
+	}
+
+	type span struct{ start, end int }
+	var moved []span
+	files := make(map[string]string)
+	for _, funcDecl := range analysis.FuncDecls(file) {
+		for _, target := range fileInfo.Targets {
+			if !g.isTargetFunction(funcDecl, target) {
+				continue
+			}
+			start := funcDecl.Pos()
+			if funcDecl.Doc != nil {
+				start = funcDecl.Doc.Pos()
+			}
+			end := helpersEnd(file, funcDecl, target.GetDisplayName())
+			s := span{fset.Position(start).Offset, fset.Position(end).Offset}
+			moved = append(moved, s)
+
+			var buf strings.Builder
+			buf.WriteString(targetFileHeader(fileInfo.FilePath) + "\n\n")
+			if constraint != "" {
+				buf.WriteString(constraint + "\n\n")
+			}
+			fmt.Fprintf(&buf, "package %s\n\n", file.Name.Name)
+			for _, imports := range importDecls {
+				buf.WriteString(imports + "\n\n")
+			}
+			buf.WriteString(content[s.start:s.end] + "\n")
+
+			pruned, err := g.pruneImports(fileInfo, buf.String(), true)
+			if err != nil {
+				return nil, fmt.Errorf("failed to write file of %s: %w", target.GetDisplayName(), err)
+			}
+			files[g.TargetPath(fileInfo.FilePath, target)] = pruned
+			break
+		}
+	}
+
+	var rest strings.Builder
+	last := 0
+	for _, s := range moved {
+		rest.WriteString(content[last:s.start])
+		last = s.end
+	}
+	rest.WriteString(content[last:])
+	pruned, err := g.pruneImports(fileInfo, rest.String(), false)
+	if err != nil {
+		return nil, err
+	}
+	files[g.OutputPath(fileInfo.FilePath)] = pruned
+	return files, nil
+}
+
+// helpersEnd returns the end of the helper functions written below a target's declaration,
+// which move with the target, or the end of the declaration when it has none
+func helpersEnd(file *ast.File, funcDecl *ast.FuncDecl, owner string) token.Pos {
+	end := funcDecl.End()
+	for i, decl := range file.Decls {
+		// Function variables are declared by the GenDecl holding them
+		if decl.Pos() > funcDecl.Pos() || decl.End() < funcDecl.End() {
+			continue
+		}
+		for _, next := range file.Decls[i+1:] {
+			helper, ok := next.(*ast.FuncDecl)
+			if !ok || !isHelperOf(helper, owner) {
+				break
+			}
+			end = helper.End()
+		}
+		break
+	}
+	return end
+}
+
+// isHelperOf reports whether funcDecl is a helper function written for the target owner
+func isHelperOf(funcDecl *ast.FuncDecl, owner string) bool {
+	name, ok := helperOwner(funcDecl)
+	return ok && name == owner
+}
+
+// pruneImports removes the imports content no longer uses once targets were moved.
+// Blank imports stay in the generated file for their side effects and are dropped from
+// the files of targets
+func (g *Generator) pruneImports(fileInfo *parser.FileInfo, content string, dropBlank bool) (string, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", content, goparser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated content: %w", err)
+	}
+
+	// Package selectors are identifiers the file does not declare
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	type unused struct{ name, path string }
+	var remove []unused
+	names := g.importNames(fileInfo)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		specName := ""
+		if spec.Name != nil {
+			specName = spec.Name.Name
+		}
+		switch name := specName; name {
+		case "_":
+			if dropBlank {
+				remove = append(remove, unused{name, importPath})
+			}
+		case ".":
+			// Dot imports cannot be traced to their uses
+		default:
+			if name == "" {
+				if name = names[importPath]; name == "" {
+					name = path.Base(importPath)
+				}
+			}
+			if !used[name] {
+				remove = append(remove, unused{specName, importPath})
+			}
+		}
+	}
+	for _, r := range remove {
+		astutil.DeleteNamedImport(fset, file, r.name, r.path)
+	}
+
+	var buf strings.Builder
+	if err := format.Node(&buf, fset, file); err != nil {
+		return "", fmt.Errorf("failed to format generated content: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// importNames returns the package names of the imports of the source file's package,
+// which can differ from the last element of their paths (e.g. "gopkg.in/yaml.v3")
+func (g *Generator) importNames(fileInfo *parser.FileInfo) map[string]string {
+	dir := filepath.Dir(fileInfo.FilePath)
+	if names, ok := g.importNamesByDir[dir]; ok {
+		return names
+	}
+
+	names := ImportNames(dir)
+	g.importNamesByDir[dir] = names
+	return names
+}
+
+// ImportNames returns the package names of the imports of the package in dir by import
+// path. Imports that cannot be loaded are left out
+func ImportNames(dir string) map[string]string {
+	names := make(map[string]string)
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedImports, Dir: dir}
+	if pkgs, err := packages.Load(buildenv.Apply(cfg), "."); err == nil {
+		for _, pkg := range pkgs {
+			for importPath, imported := range pkg.Imports {
+				names[importPath] = imported.Name
+			}
+		}
+	}
+	return names
+}
+
+// removeStaleTargetFiles removes the files of targets of the source file that were not
+// written in this run: targets removed from the source, or every target file when they
+// are no longer split. Removed files are backed up like overwritten ones
+func (g *Generator) removeStaleTargetFiles(sourcePath string, written map[string]bool) error {
+	stem := strings.TrimSuffix(filepath.Base(sourcePath), ".go")
+	candidates, err := filepath.Glob(filepath.Join(g.config.Dest, stem+"_*_gen.go"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(candidates)
+
+	header := targetFileHeader(sourcePath)
+	for _, candidate := range candidates {
+		if written[candidate] {
+			continue
+		}
+		data, err := os.ReadFile(candidate)
+		if err != nil || !strings.HasPrefix(string(data), header+"\n") {
+			continue // Another source file's target, or not generated by mantra
+		}
+		if err := g.backup(candidate); err != nil {
+			return err
+		}
+		if err := os.Remove(candidate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestGenerateFilePerTargetFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "user_service.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package users
+
+import "strings"
+
+type Service struct {
+	name string
+}
+
+// mantra: Greet the user by name
+func CreateUser(name string) string {
+	panic("not implemented")
+}
+
+// mantra: Return the name
+func (s *Service) Name() string {
+	panic("not implemented")
+}
+
+func normalize(name string) string {
+	return strings.ToLower(name)
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	results := []*parser.GenerationResult{
+		{Target: fileInfo.Targets[0], Success: true, Implementation: `return fmt.Sprintf("hello %s", name)`},
+		{
+			Target:         fileInfo.Targets[1],
+			Success:        true,
+			Implementation: "return title(s.name)",
+			Helpers:        []parser.Helper{{Name: "title", Signature: "(name string) string", Body: "return strings.ToUpper(name)"}},
+		},
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "users", PerTargetFiles: true})
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(destDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		return string(data)
+	}
+
+	main := read("user_service.go")
+	if strings.Contains(main, "func CreateUser") || strings.Contains(main, "func (s *Service) Name") || strings.Contains(main, "func title") {
+		t.Errorf("Expected targets to be moved out of the generated file, got:\n%s", main)
+	}
+	if !strings.Contains(main, `"strings"`) || strings.Contains(main, `"fmt"`) {
+		t.Errorf("Expected the generated file to import only strings, got:\n%s", main)
+	}
+
+	create := read("user_service_createuser_gen.go")
+	if !strings.HasPrefix(create, "// Code generated by mantra from user_service.go; DO NOT EDIT.\n") {
+		t.Errorf("Expected target file header, got:\n%s", create)
+	}
+	for _, want := range []string{"package generated", `"fmt"`, "// mantra: Greet the user by name", "// mantra:v3:", `return fmt.Sprintf("hello %s", name)`} {
+		if !strings.Contains(create, want) {
+			t.Errorf("Expected %q in target file, got:\n%s", want, create)
+		}
+	}
+	if strings.Contains(create, `"strings"`) {
+		t.Errorf("Expected unused imports to be dropped from the target file, got:\n%s", create)
+	}
+	name := read("user_service_service_name_gen.go")
+	for _, want := range []string{"return title(s.name)", "// mantra:helper:(*Service).Name\nfunc title(name string) string", `"strings"`} {
+		if !strings.Contains(name, want) {
+			t.Errorf("Expected %q in the method's file with its helper, got:\n%s", want, name)
+		}
+	}
+
+	// Switching back to one file per source file removes the target files
+	gen = New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "users"})
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "user_service_createuser_gen.go")); !os.IsNotExist(err) {
+		t.Errorf("Expected target file to be removed, got %v", err)
+	}
+	if main := read("user_service.go"); !strings.Contains(main, "return title(s.name)") {
+		t.Errorf("Expected targets back in the generated file, got:\n%s", main)
+	}
+}
+
+func TestCheckTargetFileNames(t *testing.T) {
+	method := func(receiver, name string) *parser.Target {
+		return &parser.Target{Name: name, Receiver: &parser.Receiver{Name: "s", Type: "*" + receiver}}
+	}
+	tests := []struct {
+		name  string
+		files []*parser.FileInfo
+		want  string
+	}{
+		{"distinct", []*parser.FileInfo{
+			{FilePath: "user.go", Targets: []*parser.Target{method("Service", "CreateUser"), {Name: "CreateUser"}}},
+		}, ""},
+		{"stem and receiver", []*parser.FileInfo{
+			{FilePath: "user.go", Targets: []*parser.Target{method("Service", "CreateUser")}},
+			{FilePath: "user_service.go", Targets: []*parser.Target{{Name: "CreateUser"}}},
+		}, "user_service_createuser_gen.go"},
+		{"case", []*parser.FileInfo{
+			{FilePath: "cache.go", Targets: []*parser.Target{{Name: "Get"}, {Name: "get"}}},
+		}, "cache_get_gen.go"},
+		{"source file", []*parser.FileInfo{
+			{FilePath: "cache.go", Targets: []*parser.Target{{Name: "Get"}}},
+			{FilePath: "cache_get_gen.go"},
+		}, "source file cache_get_gen.go"},
+	}
+	for _, tt := range tests {
+		err := CheckTargetFileNames(tt.files)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: expected an error mentioning %s, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// summaryHeader opens the comment block listing the targets of a generated file
+const summaryHeader = "// Targets generated by mantra (target, model, generated at):"
+
+// summaryEntry is a line of the summary block
+type summaryEntry struct {
+	target string
+	model  string // Model, "rule: <name>" or "failed"
+	at     string // RFC 3339 time of generation, "-" if unknown
+}
+
+// readSummary returns the entries of the summary block in content by target
+func readSummary(content string) map[string]summaryEntry {
+	entries := make(map[string]summaryEntry)
+	_, block, ok := strings.Cut(content, summaryHeader+"\n")
+	if !ok {
+		return entries
+	}
+	for _, line := range strings.Split(block, "\n") {
+		text, ok := strings.CutPrefix(line, "//")
+		if !ok {
+			break
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			continue
+		}
+		entries[fields[0]] = summaryEntry{
+			target: fields[0],
+			model:  strings.Join(fields[1:len(fields)-1], " "),
+			at:     fields[len(fields)-1],
+		}
+	}
+	return entries
+}
+
+// appendSummary appends the summary block listing the targets of results to content.
+// Targets kept from an earlier run keep their entry in existing, the file's previous content
+func (g *Generator) appendSummary(content, existing string, results []*parser.GenerationResult) string {
+	if len(results) == 0 {
+		return content
+	}
+
+	previous := readSummary(existing)
+	now := time.Now().UTC().Format(time.RFC3339)
+	entries := make([]summaryEntry, 0, len(results))
+	width, modelWidth := 0, 0
+	for _, result := range results {
+		name := strings.ReplaceAll(result.Target.GetDisplayName(), " ", "") // Entries are split on spaces
+		entry := summaryEntry{target: name, model: g.config.Model, at: now}
+		switch {
+		case result.Reused:
+			entry = summaryEntry{target: name, model: "-", at: "-"}
+			if kept, ok := previous[name]; ok {
+				entry = kept
+			}
+		case !result.Success:
+			entry.model = "failed"
+		case result.Rule != "":
+			entry.model = "rule: " + result.Rule
+		case result.Degraded:
+			entry.model += " (single prompt)"
+		}
+		if entry.model == "" {
+			entry.model = "-"
+		}
+		entries = append(entries, entry)
+		width, modelWidth = max(width, len(name)), max(modelWidth, len(entry.model))
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(content, "\n"))
+	b.WriteString("\n\n" + summaryHeader + "\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "//   %-*s  %-*s  %s\n", width, entry.target, modelWidth, entry.model, entry.at)
+	}
+	return b.String()
+}
+
+// fileResults returns the results of the targets written to path, in source order
+func (g *Generator) fileResults(fileInfo *parser.FileInfo, results []*parser.GenerationResult, path string) []*parser.GenerationResult {
+	byTarget := make(map[string]*parser.GenerationResult, len(results))
+	for _, result := range results {
+		byTarget[g.getTargetKey(result.Target)] = result
+	}
+
+	var inFile []*parser.GenerationResult
+	for _, target := range fileInfo.Targets {
+		result, ok := byTarget[g.getTargetKey(target)]
+		if !ok {
+			continue
+		}
+		if g.config.PerTargetFiles && g.TargetPath(fileInfo.FilePath, target) != path {
+			continue
+		}
+		inFile = append(inFile, result)
+	}
+	return inFile
+}
+
+// readExisting returns the content of a generated file before it is replaced, or ""
+func readExisting(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,207 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	goparser "go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rail44/mantra/internal/imports"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// TestKind is a kind of test function generated for targets marked with a directive, and
+// written to a test file of its own next to each generated file
+type TestKind struct {
+	Name       string // Shown to users, e.g. "benchmark"
+	Directive  string // Directive marking targets, written after "// mantra:"
+	Prefix     string // Prefix of function names, e.g. "Benchmark"
+	Param      string // Parameter of the function, e.g. "b *testing.B"
+	FileSuffix string // Replaces ".go" in the source file's name
+
+	Marked   func(*parser.Target) bool             // Whether the target asks for the function
+	Note     func(*parser.Target) string           // Text following the directive
+	FuncName func(*parser.Target) string           // Name of the target's function
+	Body     func(*parser.GenerationResult) string // Generated body (empty if none)
+}
+
+// Benchmarks are generated for targets marked with // mantra:bench
+var Benchmarks = TestKind{
+	Name:       "benchmark",
+	Directive:  "bench",
+	Prefix:     "Benchmark",
+	Param:      "b *testing.B",
+	FileSuffix: "_bench_test.go",
+	Marked:     func(t *parser.Target) bool { return t.Bench },
+	Note:       func(t *parser.Target) string { return t.BenchNote },
+	FuncName:   (*parser.Target).BenchmarkName,
+	Body:       func(r *parser.GenerationResult) string { return r.Benchmark },
+}
+
+// FuzzTests are generated for targets marked with // mantra:fuzz
+var FuzzTests = TestKind{
+	Name:       "fuzz test",
+	Directive:  "fuzz",
+	Prefix:     "Fuzz",
+	Param:      "f *testing.F",
+	FileSuffix: "_fuzz_test.go",
+	Marked:     func(t *parser.Target) bool { return t.Fuzz },
+	Note:       func(t *parser.Target) string { return t.FuzzNote },
+	FuncName:   (*parser.Target).FuzzName,
+	Body:       func(r *parser.GenerationResult) string { return r.Fuzz },
+}
+
+// TestKinds lists every kind of generated test function
+var TestKinds = []TestKind{Benchmarks, FuzzTests}
+
+// TestFunc is a generated test function
+type TestFunc struct {
+	Name string // Function name, see TestKind.FuncName
+	Body string // Statements inside the function
+}
+
+// FileName returns the name of the file the functions of this kind generated for the source
+// file are written to, e.g. user_bench_test.go for user.go
+func (k TestKind) FileName(sourcePath string) string {
+	return strings.TrimSuffix(filepath.Base(sourcePath), ".go") + k.FileSuffix
+}
+
+// TestFilePath returns the destination path of the test functions of kind generated for the
+// source file
+func (g *Generator) TestFilePath(sourcePath string, kind TestKind) string {
+	return filepath.Join(g.config.Dest, kind.FileName(sourcePath))
+}
+
+// RenderTestFuncs returns a formatted test file of package packageName declaring the
+// functions. Besides "testing", it imports the packages the bodies refer to: those imported
+// by the source file (under the same name) and standard library packages. names holds the
+// package names of the source file's imports by path, as returned by ImportNames; imports
+// missing from it go by the last element of their path
+func RenderTestFuncs(packageName string, fileImports []parser.Import, names map[string]string, kind TestKind, funcs []TestFunc) ([]byte, error) {
+	byName := make(map[string]string, len(fileImports))
+	aliases := make(map[string]string, len(fileImports))
+	for _, imp := range fileImports {
+		if imp.Alias == "_" || imp.Alias == "." {
+			continue
+		}
+		name := imp.Alias
+		if name == "" {
+			if name = names[imp.Path]; name == "" {
+				name = path.Base(imp.Path)
+			}
+		} else {
+			aliases[imp.Path] = imp.Alias
+		}
+		byName[name] = imp.Path
+	}
+
+	required := []string{"testing"}
+	for _, fn := range funcs {
+		required = imports.MergeImports(required, imports.ReferencedImports(fn.Body, byName))
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "package %s\n\n%s\n\nimport (\n", packageName, generatedHeader)
+	for _, importPath := range required {
+		if alias, ok := aliases[importPath]; ok {
+			fmt.Fprintf(&buf, "\t%s %s\n", alias, strconv.Quote(importPath))
+		} else {
+			fmt.Fprintf(&buf, "\t%s\n", strconv.Quote(importPath))
+		}
+	}
+	buf.WriteString(")\n")
+	for _, fn := range funcs {
+		fmt.Fprintf(&buf, "\nfunc %s(%s) {\n%s\n}\n", fn.Name, kind.Param, strings.TrimSpace(fn.Body))
+	}
+
+	return format.Source([]byte(buf.String()))
+}
+
+// generateTestFuncs writes the test functions of kind for the source file's targets marked
+// with its directive. Functions generated in this run replace the existing ones; those of
+// targets not regenerated are kept, and those of targets no longer marked are dropped.
+// Returns the path written, or an empty string when the source file has no such functions
+func (g *Generator) generateTestFuncs(fileInfo *parser.FileInfo, results []*parser.GenerationResult, kind TestKind) (string, error) {
+	testPath := g.TestFilePath(fileInfo.FilePath, kind)
+
+	generated := make(map[string]string)
+	for _, result := range results {
+		if body := kind.Body(result); result.Success && body != "" {
+			generated[kind.FuncName(result.Target)] = body
+		}
+	}
+	existing, err := ExistingTestFuncs(testPath, kind)
+	if err != nil {
+		return "", err
+	}
+
+	var funcs []TestFunc
+	for _, target := range fileInfo.Targets {
+		if !kind.Marked(target) {
+			continue
+		}
+		name := kind.FuncName(target)
+		body, ok := generated[name]
+		if !ok {
+			body, ok = existing[name]
+		}
+		if ok {
+			funcs = append(funcs, TestFunc{Name: name, Body: body})
+		}
+	}
+
+	if len(funcs) == 0 {
+		if len(existing) == 0 {
+			return "", nil
+		}
+		if err := g.backup(testPath); err != nil {
+			return "", err
+		}
+		return "", os.Remove(testPath)
+	}
+
+	content, err := RenderTestFuncs(g.config.PackageName, fileInfo.Imports, g.importNames(fileInfo), kind, funcs)
+	if err != nil {
+		return "", fmt.Errorf("failed to format %ss: %w", kind.Name, err)
+	}
+	if err := g.writeOutput(testPath, content); err != nil {
+		return "", err
+	}
+	return testPath, nil
+}
+
+// ExistingTestFuncs returns the bodies of the functions of kind in a generated test file by
+// function name (empty if the file does not exist)
+func ExistingTestFuncs(testPath string, kind TestKind) (map[string]string, error) {
+	data, err := os.ReadFile(testPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, testPath, data, goparser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", testPath, err)
+	}
+
+	bodies := make(map[string]string)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || !strings.HasPrefix(fn.Name.Name, kind.Prefix) {
+			continue
+		}
+		start := fset.Position(fn.Body.Lbrace).Offset + 1
+		end := fset.Position(fn.Body.Rbrace).Offset
+		bodies[fn.Name.Name] = strings.TrimSpace(string(data[start:end]))
+	}
+	return bodies, nil
+}
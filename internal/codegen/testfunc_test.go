@@ -0,0 +1,222 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestGenerateFileBenchmarks(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "text.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	benched := `package text
+
+import "strings"
+
+// mantra: Upper-case s
+// mantra:bench with a long ASCII string
+func Upper(s string) string {
+	panic("not implemented")
+}
+
+type Counter struct{}
+
+// mantra:bench
+// mantra: Count the words of s
+func (c *Counter) Count(s string) int {
+	panic("not implemented")
+}
+
+var _ = strings.ToUpper
+`
+	if err := os.WriteFile(source, []byte(benched), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "text"})
+	benchPath := filepath.Join(destDir, "text_bench_test.go")
+
+	results := []*parser.GenerationResult{
+		{Target: fileInfo.Targets[0], Success: true, Implementation: "return strings.ToUpper(s)",
+			Benchmark: "s := strings.Repeat(\"a\", 4096)\nfor b.Loop() {\n\tUpper(s)\n}"},
+		{Target: fileInfo.Targets[1], Success: true, Implementation: "return len(strings.Fields(s))",
+			Benchmark: "var c Counter\nfor b.Loop() {\n\tc.Count(\"a b c\")\n}"},
+	}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+	if !slices.Contains(gen.WrittenFiles(source), benchPath) {
+		t.Errorf("Expected %s among the written files, got %v", benchPath, gen.WrittenFiles(source))
+	}
+
+	data, err := os.ReadFile(benchPath)
+	if err != nil {
+		t.Fatalf("Failed to read benchmarks: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		"package generated\n\n" + generatedHeader,
+		"import (\n\t\"strings\"\n\t\"testing\"\n)",
+		"func BenchmarkUpper(b *testing.B) {\n\ts := strings.Repeat(\"a\", 4096)",
+		"func BenchmarkCounter_Count(b *testing.B) {\n\tvar c Counter",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected benchmarks to contain %q, got:\n%s", want, content)
+		}
+	}
+
+	// A target regenerated without a benchmark keeps the existing one
+	results[0].Benchmark = "for b.Loop() {\n\tUpper(\"abc\")\n}"
+	results[1].Benchmark = ""
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+	data, err = os.ReadFile(benchPath)
+	if err != nil {
+		t.Fatalf("Failed to read benchmarks: %v", err)
+	}
+	content = string(data)
+	if !strings.Contains(content, "Upper(\"abc\")") || !strings.Contains(content, "c.Count(\"a b c\")") {
+		t.Errorf("Expected the new benchmark of Upper and the kept benchmark of Count, got:\n%s", content)
+	}
+	if strings.Contains(content, "\"strings\"") {
+		t.Errorf("Expected unused imports to be dropped, got:\n%s", content)
+	}
+
+	// Without // mantra:bench the benchmark file is removed by the next run
+	gen = New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "text"})
+	unbenched := strings.ReplaceAll(benched, "// mantra:bench with a long ASCII string\n", "")
+	unbenched = strings.ReplaceAll(unbenched, "// mantra:bench\n", "")
+	if err := os.WriteFile(source, []byte(unbenched), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if fileInfo, err = parser.ParseFileInfo(source); err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	if err := gen.GenerateFile(fileInfo, []*parser.GenerationResult{
+		{Target: fileInfo.Targets[0], Success: true, Implementation: "return strings.ToUpper(s)"},
+		{Target: fileInfo.Targets[1], Success: true, Implementation: "return len(strings.Fields(s))"},
+	}); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+	if _, err := os.Stat(benchPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the benchmark file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(benchPath + BackupSuffix); err != nil {
+		t.Errorf("Expected a backup of the removed benchmark file: %v", err)
+	}
+}
+
+func TestGenerateFileFuzzTests(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "codec.go")
+	destDir := filepath.Join(tempDir, "generated")
+
+	if err := os.WriteFile(source, []byte(`package codec
+
+import "strconv"
+
+// mantra: Parse a decimal number
+// mantra:fuzz
+// mantra:bench
+func Parse(s string) (int, error) {
+	panic("not implemented")
+}
+
+var _ = strconv.Atoi
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	gen := New(&Config{Dest: destDir, PackageName: "generated", SourcePackage: "codec"})
+	fuzzPath := gen.TestFilePath(source, FuzzTests)
+	if fuzzPath != filepath.Join(destDir, "codec_fuzz_test.go") {
+		t.Errorf("Unexpected fuzz test path %s", fuzzPath)
+	}
+
+	results := []*parser.GenerationResult{{
+		Target: fileInfo.Targets[0], Success: true, Implementation: "return strconv.Atoi(s)",
+		Fuzz: "f.Add(\"42\")\nf.Add(\"-\")\nf.Fuzz(func(t *testing.T, s string) {\n\tif n, err := Parse(s); err == nil && strconv.Itoa(n) != s {\n\t\tt.Skip()\n\t}\n})",
+	}}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+	if !slices.Contains(gen.WrittenFiles(source), fuzzPath) {
+		t.Errorf("Expected %s among the written files, got %v", fuzzPath, gen.WrittenFiles(source))
+	}
+
+	data, err := os.ReadFile(fuzzPath)
+	if err != nil {
+		t.Fatalf("Failed to read fuzz tests: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		"import (\n\t\"strconv\"\n\t\"testing\"\n)",
+		"func FuzzParse(f *testing.F) {\n\tf.Add(\"42\")",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected fuzz tests to contain %q, got:\n%s", want, content)
+		}
+	}
+
+	// The benchmark phase gave up, so no benchmark file is written
+	if _, err := os.Stat(gen.TestFilePath(source, Benchmarks)); !os.IsNotExist(err) {
+		t.Errorf("Expected no benchmark file, got %v", err)
+	}
+}
+
+func TestRenderTestFuncsVersionedImports(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/app\n\ngo 1.21\n",
+		"yaml.v3/yaml.go": "package yaml\n\nfunc Marshal(v any) ([]byte, error) { return nil, nil }\n",
+		"lib/v2/lib.go":   "package lib\n\nfunc Version() string { return \"2\" }\n",
+		"app/app.go": `package app
+
+import (
+	"example.com/app/lib/v2"
+	"example.com/app/yaml.v3"
+)
+
+var _, _ = lib.Version, yaml.Marshal
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	fileInfo, err := parser.ParseFileInfo(filepath.Join(root, "app", "app.go"))
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	body := "for b.Loop() {\n\t_, _ = yaml.Marshal(lib.Version())\n}"
+	content, err := RenderTestFuncs("app", fileInfo.Imports, ImportNames(filepath.Dir(fileInfo.FilePath)), Benchmarks, []TestFunc{{Name: "BenchmarkEncode", Body: body}})
+	if err != nil {
+		t.Fatalf("RenderTestFuncs failed: %v", err)
+	}
+	for _, want := range []string{`"example.com/app/yaml.v3"`, `"example.com/app/lib/v2"`} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected import %s, got:\n%s", want, content)
+		}
+	}
+}
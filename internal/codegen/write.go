@@ -0,0 +1,145 @@
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BackupSuffix is appended to a generated file's path to name its backup
+const BackupSuffix = ".bak"
+
+// CreatedSuffix is appended to a generated file's path to name the marker recording that the
+// run created it, so Rollback removes it rather than restoring a backup
+const CreatedSuffix = ".created"
+
+// writeOutput atomically replaces the output file, backing up the version that existed
+// before this generator first touched it so a run can be undone with Rollback
+func (g *Generator) writeOutput(path string, data []byte) error {
+	if err := g.backup(path); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// backup backs up path the first time the run touches it. The backups and markers of
+// earlier runs in its directory are removed first, so Rollback only undoes the latest run
+func (g *Generator) backup(path string) error {
+	if g.touched[path] {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	if !g.cleared[dir] {
+		if err := clearBackups(dir); err != nil {
+			return fmt.Errorf("failed to remove backups of an earlier run: %w", err)
+		}
+		g.cleared[dir] = true
+	}
+	if err := backupFile(path); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	g.touched[path] = true
+	return nil
+}
+
+// clearBackups removes the backups and markers in dir
+func clearBackups(dir string) error {
+	for _, suffix := range []string{BackupSuffix, CreatedSuffix} {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"+suffix))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backupFile copies path to its backup. A missing file has nothing to restore, so it is
+// marked as created instead
+func backupFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return writeFileAtomic(path+CreatedSuffix, nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path+BackupSuffix, data, 0644)
+}
+
+// writeFileAtomic writes data to a temporary file in the target directory and renames it
+// into place, so a crash never leaves a partially written file behind
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Rollback undoes the latest run that wrote to dest: it restores every backed-up file to
+// its previous version, removes the files the run created and then the backups and markers.
+// It returns the restored and the removed paths.
+func Rollback(dest string) (restored, removed []string, err error) {
+	backups, err := filepath.Glob(filepath.Join(dest, "*"+BackupSuffix))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(backups)
+
+	for _, backup := range backups {
+		path := strings.TrimSuffix(backup, BackupSuffix)
+		data, err := os.ReadFile(backup)
+		if err != nil {
+			return restored, removed, fmt.Errorf("failed to read backup %s: %w", backup, err)
+		}
+		if err := writeFileAtomic(path, data, 0644); err != nil {
+			return restored, removed, fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+		if err := os.Remove(backup); err != nil {
+			return restored, removed, fmt.Errorf("failed to remove backup %s: %w", backup, err)
+		}
+		restored = append(restored, path)
+	}
+
+	markers, err := filepath.Glob(filepath.Join(dest, "*"+CreatedSuffix))
+	if err != nil {
+		return restored, removed, err
+	}
+	sort.Strings(markers)
+
+	for _, marker := range markers {
+		path := strings.TrimSuffix(marker, CreatedSuffix)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return restored, removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		if err := os.Remove(marker); err != nil {
+			return restored, removed, fmt.Errorf("failed to remove marker %s: %w", marker, err)
+		}
+		removed = append(removed, path)
+	}
+	return restored, removed, nil
+}
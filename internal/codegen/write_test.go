@@ -0,0 +1,138 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteOutputBacksUpOncePerRun(t *testing.T) {
+	destDir := t.TempDir()
+	path := filepath.Join(destDir, "user.go")
+	if err := os.WriteFile(path, []byte("previous run"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	gen := New(&Config{Dest: destDir})
+	// Stub preparation followed by the final write must keep the pre-run version
+	for _, content := range []string{"stub", "generated"} {
+		if err := gen.writeOutput(path, []byte(content)); err != nil {
+			t.Fatalf("writeOutput failed: %v", err)
+		}
+	}
+
+	assertContent(t, path, "generated")
+	assertContent(t, path+BackupSuffix, "previous run")
+
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatalf("Failed to read dest: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the file and its backup, got %d entries", len(entries))
+	}
+
+	restored, removed, err := Rollback(destDir)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if len(restored) != 1 || restored[0] != path || len(removed) != 0 {
+		t.Errorf("Expected %s to be restored, got %v and removed %v", path, restored, removed)
+	}
+	assertContent(t, path, "previous run")
+	if _, err := os.Stat(path + BackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected backup to be removed after rollback")
+	}
+}
+
+func TestWriteOutputRemovesStaleBackupForNewFile(t *testing.T) {
+	destDir := t.TempDir()
+	path := filepath.Join(destDir, "user.go")
+	if err := os.WriteFile(path+BackupSuffix, []byte("stale"), 0644); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+
+	gen := New(&Config{Dest: destDir})
+	if err := gen.writeOutput(path, []byte("generated")); err != nil {
+		t.Fatalf("writeOutput failed: %v", err)
+	}
+
+	if _, err := os.Stat(path + BackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected stale backup to be removed")
+	}
+}
+
+func TestRollbackRemovesCreatedFiles(t *testing.T) {
+	destDir := t.TempDir()
+	existing := filepath.Join(destDir, "user.go")
+	if err := os.WriteFile(existing, []byte("previous run"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	created := filepath.Join(destDir, "user_Service_CreateUser_gen.go")
+
+	gen := New(&Config{Dest: destDir})
+	for path, content := range map[string]string{existing: "generated", created: "new target file"} {
+		if err := gen.writeOutput(path, []byte(content)); err != nil {
+			t.Fatalf("writeOutput failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(created + BackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup of a created file")
+	}
+
+	restored, removed, err := Rollback(destDir)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if len(restored) != 1 || len(removed) != 1 || removed[0] != created {
+		t.Errorf("Expected %s restored and %s removed, got %v and %v", existing, created, restored, removed)
+	}
+	assertContent(t, existing, "previous run")
+
+	// Nothing of the run is left behind
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatalf("Failed to read dest: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only %s after rollback, got %d entries", filepath.Base(existing), len(entries))
+	}
+}
+
+func TestRollbackUndoesLatestRunOnly(t *testing.T) {
+	destDir := t.TempDir()
+	first := filepath.Join(destDir, "user.go")
+	second := filepath.Join(destDir, "order.go")
+	if err := os.WriteFile(second, []byte("before both runs"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// The first run creates user.go, the second only rewrites order.go
+	if err := New(&Config{Dest: destDir}).writeOutput(first, []byte("run 1")); err != nil {
+		t.Fatalf("writeOutput failed: %v", err)
+	}
+	if err := New(&Config{Dest: destDir}).writeOutput(second, []byte("run 2")); err != nil {
+		t.Fatalf("writeOutput failed: %v", err)
+	}
+
+	restored, removed, err := Rollback(destDir)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if len(restored) != 1 || restored[0] != second || len(removed) != 0 {
+		t.Errorf("Expected only %s restored, got %v and removed %v", second, restored, removed)
+	}
+	assertContent(t, first, "run 1")
+	assertContent(t, second, "before both runs")
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if string(data) != want {
+		t.Errorf("Expected %s to contain %q, got %q", filepath.Base(path), want, string(data))
+	}
+}
@@ -0,0 +1,113 @@
+package coder
+
+import (
+	"fmt"
+	"log/slog"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/llm"
+	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/phase"
+)
+
+// candidate is one implementation attempt for a target
+type candidate struct {
+	code    string
+	helpers []parser.Helper // Helper functions generated with code
+	failure *parser.FailureReason
+	clean   bool // check_code reported no issues
+}
+
+// candidateTemperatures returns the implementation temperature of each candidate,
+// or nil when only a single implementation is requested
+func candidateTemperatures(cfg *config.CandidatesConfig) []float32 {
+	if cfg == nil || cfg.Count <= 1 {
+		return nil
+	}
+
+	temperatures := make([]float32, cfg.Count)
+	for i := range temperatures {
+		if i < len(cfg.Temperatures) {
+			temperatures[i] = cfg.Temperatures[i]
+			continue
+		}
+		// Spread the remaining candidates between 0.2 and 0.8
+		temperatures[i] = 0.2 + 0.6*float32(i)/float32(cfg.Count-1)
+	}
+	return temperatures
+}
+
+// executeCandidates generates one implementation per temperature in parallel and returns the
+// first candidate check_code reports as clean, falling back to the first accepted candidate.
+// The helpers of the selected candidate are set on runner for the phases that follow
+func (t *TargetCoder) executeCandidates(runner *phase.Runner, contextResult map[string]any, temperatures []float32) (string, *parser.FailureReason) {
+	runners := make([]*phase.Runner, len(temperatures))
+	for i, temperature := range temperatures {
+		logger := t.logger.With(slog.Int("candidate", i+1))
+		client := t.client
+		if i > 0 {
+			var err error
+			client, err = llm.NewClient(t.coder.clientConfig, t.coder.httpClient, logger)
+			if err != nil {
+				return "", &parser.FailureReason{
+					Phase:   "implementation",
+					Message: fmt.Sprintf("Failed to create AI client for candidate %d: %v", i+1, err),
+					Context: "Check your API configuration and network connection",
+				}
+			}
+			t.candidateClients = append(t.candidateClients, client)
+		}
+		runners[i] = runner.Fork(client, logger)
+		runners[i].SetImplementationTemperature(temperature)
+	}
+
+	candidates := make([]candidate, len(temperatures))
+	var g errgroup.Group
+	for i := range runners {
+		g.Go(func() error {
+			code, failure := t.executeImplementation(runners[i], contextResult)
+			candidates[i] = candidate{code: code, helpers: runners[i].Helpers(), failure: failure}
+			if failure != nil {
+				return nil
+			}
+
+			result, err := runners[i].CheckImplementation(t.ctx, t.target.Target, t.target.FileInfo, t.projectRoot, code)
+			if err != nil {
+				t.logger.Warn("Failed to check candidate", slog.Int("candidate", i+1), slog.String("error", err.Error()))
+				return nil
+			}
+			candidates[i].clean = result.Valid
+			return nil
+		})
+	}
+	g.Wait()
+
+	selected, failure := t.selectCandidate(candidates)
+	if failure != nil {
+		return "", failure
+	}
+	runner.SetHelpers(selected.helpers)
+	return selected.code, nil
+}
+
+// selectCandidate picks the first clean candidate, then the first accepted one
+func (t *TargetCoder) selectCandidate(candidates []candidate) (candidate, *parser.FailureReason) {
+	for i, c := range candidates {
+		if c.failure == nil && c.clean {
+			t.logger.Info(fmt.Sprintf("Selected candidate %d of %d", i+1, len(candidates)))
+			return c, nil
+		}
+	}
+
+	for i, c := range candidates {
+		if c.failure == nil {
+			t.logger.Warn(fmt.Sprintf("No candidate passed check_code cleanly; using candidate %d of %d", i+1, len(candidates)))
+			return c, nil
+		}
+	}
+
+	// Every candidate failed; report the first failure
+	return candidate{}, candidates[0].failure
+}
@@ -0,0 +1,44 @@
+package coder
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestCandidateTemperatures(t *testing.T) {
+	if got := candidateTemperatures(&config.CandidatesConfig{Count: 1}); got != nil {
+		t.Errorf("Expected no candidates for count 1, got %v", got)
+	}
+
+	got := candidateTemperatures(&config.CandidatesConfig{Count: 3, Temperatures: []float32{0}})
+	if expected := []float32{0, 0.5, 0.8}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("candidateTemperatures() = %v, want %v", got, expected)
+	}
+}
+
+func TestSelectCandidate(t *testing.T) {
+	coder := &TargetCoder{logger: slog.Default()}
+	failure := &parser.FailureReason{Phase: "implementation", Message: "no result"}
+
+	selected, reason := coder.selectCandidate([]candidate{
+		{failure: failure},
+		{code: "return 1"},
+		{code: "return 2", clean: true},
+	})
+	if reason != nil || selected.code != "return 2" {
+		t.Errorf("Expected the clean candidate, got %q (%v)", selected.code, reason)
+	}
+
+	selected, reason = coder.selectCandidate([]candidate{{failure: failure}, {code: "return 1"}})
+	if reason != nil || selected.code != "return 1" {
+		t.Errorf("Expected the first accepted candidate, got %q (%v)", selected.code, reason)
+	}
+
+	if _, reason = coder.selectCandidate([]candidate{{failure: failure}, {failure: &parser.FailureReason{}}}); reason != failure {
+		t.Errorf("Expected the first failure, got %v", reason)
+	}
+}
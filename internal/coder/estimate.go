@@ -0,0 +1,91 @@
+package coder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/estimate"
+	"github.com/rail44/mantra/internal/phase"
+)
+
+// EstimateTargets builds the prompts each target would send, without calling the provider,
+// and returns the phases it would run with the size of their first request
+func (c *ParallelCoder) EstimateTargets(ctx context.Context, targets []TargetContext) ([]estimate.Target, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	projectRoot := findProjectRoot(filepath.Dir(targets[0].Target.FilePath))
+
+	var estimates []estimate.Target
+	for _, tc := range targets {
+		explanation, err := c.explain(ctx, tc, projectRoot)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rule-based targets have no prompts and so run no phases
+		target := estimate.Target{Name: tc.Target.GetDisplayName()}
+		for _, prompt := range explanation.Prompts {
+			p := estimate.Phase{Name: prompt.Phase, Tokens: prompt.Tokens}
+			if prompt.Phase == phase.PhaseImplementation {
+				p.Runs = len(candidateTemperatures(c.config.Candidates))
+			}
+			target.Phases = append(target.Phases, p)
+		}
+		estimates = append(estimates, target)
+	}
+	return estimates, nil
+}
+
+// Explanation is what generating a target would send to the model
+type Explanation struct {
+	Rule    string                      // Rule that writes the body without a model (empty if none)
+	Body    string                      // Body synthesized by Rule
+	Fast    bool                        // Context gathering is skipped
+	Context *pkgcontext.RelevantContext // Static context selected for the target (nil if the package failed to load)
+	Prompts []phase.Prompt              // First request of each phase, in order
+}
+
+// ExplainTarget builds the static context and the prompts of a target without calling the provider
+func (c *ParallelCoder) ExplainTarget(ctx context.Context, tc TargetContext) (*Explanation, error) {
+	return c.explain(ctx, tc, findProjectRoot(filepath.Dir(tc.Target.FilePath)))
+}
+
+// explain mirrors Generate: fast targets skip context gathering and get the package context
+func (c *ParallelCoder) explain(ctx context.Context, tc TargetContext, projectRoot string) (*Explanation, error) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	t := NewTargetCoder(ctx, c, tc, projectRoot, logger, nil)
+	runner := phase.NewRunnerWithOptions(nil, logger, c.phaseOptions())
+	runner.SetPreviousFailure(tc.PreviousFailure)
+
+	explanation := &Explanation{}
+	if body, rule, ok := c.synthesize(tc.Target); ok {
+		explanation.Rule, explanation.Body = rule, body
+		return explanation, nil
+	}
+
+	fc, err := c.fileContext(ctx, tc.Target.FilePath)
+	if err == nil {
+		runner.SetFileContext(fc)
+		explanation.Context = fc.ForTarget(tc.Target)
+		if t.isFast(fc) {
+			explanation.Fast = true
+			runner.SetPackageContext(formatPackageContext(fc))
+		}
+	}
+
+	explanation.Prompts, err = runner.Prompts(ctx, tc.Target, tc.FileContent, phase.PromptOptions{
+		DestDir:       c.config.Dest,
+		ProjectRoot:   projectRoot,
+		GatherContext: !explanation.Fast,
+		Review:        c.config.Review != nil && c.config.Review.Enabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", tc.Target.GetDisplayName(), err)
+	}
+	return explanation, nil
+}
@@ -0,0 +1,75 @@
+package coder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/llm"
+	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/phase"
+)
+
+func TestExplainTarget(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	source := filepath.Join(root, "cart.go")
+	content := `package shop
+
+type Cart struct {
+	items []int
+}
+
+// mantra: Sum all item prices
+func (c *Cart) Total() int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(source, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	c, err := NewParallelCoder(&llm.ClientConfig{}, &config.Config{Dest: filepath.Join(root, "generated")})
+	if err != nil {
+		t.Fatalf("Failed to create coder: %v", err)
+	}
+	explanation, err := c.ExplainTarget(context.Background(), TargetContext{
+		Target:          fileInfo.Targets[0],
+		FileContent:     content,
+		FileInfo:        fileInfo,
+		PreviousFailure: &parser.FailureReason{Phase: "implementation", Message: "undefined: sum", Attempt: "return sum(c.items)"},
+	})
+	if err != nil {
+		t.Fatalf("ExplainTarget failed: %v", err)
+	}
+
+	if explanation.Context == nil || !strings.Contains(explanation.Context.Types["Cart"], "items []int") {
+		t.Errorf("Expected the Cart definition in the static context, got %+v", explanation.Context)
+	}
+	var impl *phase.Prompt
+	for i := range explanation.Prompts {
+		if explanation.Prompts[i].Phase == phase.PhaseImplementation {
+			impl = &explanation.Prompts[i]
+		}
+	}
+	if impl == nil {
+		t.Fatalf("Expected an implementation prompt, got %+v", explanation.Prompts)
+	}
+	for _, want := range []string{"Sum all item prices", "undefined: sum", "return sum(c.items)"} {
+		if !strings.Contains(impl.User, want) {
+			t.Errorf("Expected %q in the implementation prompt, got:\n%s", want, impl.User)
+		}
+	}
+	if impl.Tokens == 0 || len(impl.Tools) == 0 {
+		t.Errorf("Expected token estimate and tools, got %d tokens and %v", impl.Tokens, impl.Tools)
+	}
+}
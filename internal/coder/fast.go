@@ -0,0 +1,39 @@
+package coder
+
+import (
+	"strings"
+
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/lint"
+)
+
+// isFast reports whether the target skips context gathering, either because it is
+// marked with // mantra:fast or because its instruction is short and only mentions known identifiers
+func (t *TargetCoder) isFast(fc *pkgcontext.FileContext) bool {
+	target := t.target.Target
+	if target.Fast {
+		return true
+	}
+
+	fast := t.coder.config.Fast
+	if fast == nil || fast.MaxWords <= 0 || len(strings.Fields(target.Instruction)) > fast.MaxWords {
+		return false
+	}
+
+	for _, ident := range lint.Identifiers(target.Instruction) {
+		if !lint.Known(ident, target, fc) {
+			t.logger.Debug("Instruction mentions unknown identifier, gathering context", "identifier", ident)
+			return false
+		}
+	}
+	return true
+}
+
+// formatPackageContext renders package declarations for use in place of gathered context
+func formatPackageContext(fc *pkgcontext.FileContext) string {
+	declarations := fc.PackageDeclarations()
+	if declarations == "" {
+		return ""
+	}
+	return "## Package Declarations\n```go\n" + declarations + "```\n"
+}
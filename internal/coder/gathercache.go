@@ -0,0 +1,70 @@
+package coder
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/rail44/mantra/internal/deps"
+	"github.com/rail44/mantra/internal/gathercache"
+	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/phase"
+)
+
+// contextCache returns the context gathering cache of the package in dir, opened once per run
+// so methods generated in parallel share it. Returns nil when caching is disabled
+func (c *ParallelCoder) contextCache(dir string) *gathercache.Cache {
+	c.contextCachesMu.Lock()
+	defer c.contextCachesMu.Unlock()
+	if c.contextCaches == nil {
+		c.contextCaches = make(map[string]*gathercache.Cache)
+	}
+	cache, ok := c.contextCaches[dir]
+	if !ok {
+		cache = c.config.ContextCache(dir)
+		c.contextCaches[dir] = cache
+	}
+	return cache
+}
+
+// executeCachedContextGathering gathers context for a method, starting from what was
+// gathered for other methods of its receiver type, and stores the combined result for the
+// methods after it. Methods of the same type gather one at a time so each one can build
+// on the last
+func (t *TargetCoder) executeCachedContextGathering(runner *phase.Runner) (map[string]any, *parser.FailureReason) {
+	target := t.target.Target
+	dir := filepath.Dir(target.FilePath)
+	cache := t.coder.contextCache(dir)
+	if cache == nil {
+		return runner.ExecuteContextGathering(t.ctx, target, t.target.FileContent, t.coder.config.Dest)
+	}
+	// Cached results are only reused while the declarations they describe are unchanged
+	idx, err := deps.BuildIndex(dir)
+	if err != nil {
+		t.logger.Warn("Failed to index package, not caching context", slog.String("error", err.Error()))
+		return runner.ExecuteContextGathering(t.ctx, target, t.target.FileContent, t.coder.config.Dest)
+	}
+
+	receiver := strings.TrimPrefix(target.Receiver.Type, "*")
+	unlock := cache.Lock(receiver)
+	defer unlock()
+
+	cached, err := cache.Get(receiver, idx.FingerprintNames)
+	if err != nil {
+		t.logger.Warn("Failed to read cached context", slog.String("error", err.Error()))
+	}
+	if cached != nil {
+		t.logger.Info("Seeding context gathering from cache", slog.String("receiver", receiver))
+		runner.SetCachedContext(receiver, cached)
+	}
+
+	result, failureReason := runner.ExecuteContextGathering(t.ctx, target, t.target.FileContent, t.coder.config.Dest)
+	if failureReason != nil {
+		return nil, failureReason
+	}
+	result = phase.MergeContextResults(result, cached)
+	if err := cache.Put(receiver, result, idx.FingerprintNames); err != nil {
+		t.logger.Warn("Failed to cache context", slog.String("error", err.Error()))
+	}
+	return result, nil
+}
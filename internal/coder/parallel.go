@@ -6,39 +6,118 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"log/slog"
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/rail44/mantra/internal/codegen"
 	"github.com/rail44/mantra/internal/config"
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/gathercache"
+	"github.com/rail44/mantra/internal/imports"
 	"github.com/rail44/mantra/internal/llm"
 	"github.com/rail44/mantra/internal/log"
 	"github.com/rail44/mantra/internal/parser"
 	"github.com/rail44/mantra/internal/phase"
+	"github.com/rail44/mantra/internal/progress"
+	"github.com/rail44/mantra/internal/provenance"
+	"github.com/rail44/mantra/internal/rules"
+	"github.com/rail44/mantra/internal/status"
+	"github.com/rail44/mantra/internal/tools/impl"
 	"github.com/rail44/mantra/internal/ui"
 )
 
+// MaxParallelTargets is the number of targets generated at the same time
+const MaxParallelTargets = 16
+
 // ParallelCoder handles parallel code generation for multiple targets
 type ParallelCoder struct {
 	clientConfig *llm.ClientConfig
 	config       *config.Config
 	logger       *slog.Logger
-	httpClient   *http.Client // Shared HTTP client for connection pooling
+	httpClient   *http.Client                   // Shared HTTP client for connection pooling
+	onResult     func(*parser.GenerationResult) // Called as each target finishes
+	provenance   *provenance.Scanner            // Built once per run when provenance scanning is configured
+	overlay      map[string][]byte              // Stubs of generated files, seen by go/packages instead of dest
+	status       *status.Tracker                // Progress served by --status-addr (optional)
+	progress     *progress.Stream               // Progress events written by --progress jsonl (optional)
+	runSnippet   bool                           // tools.run_snippet is enabled and snippets can run isolated
+
+	fileContextsMu sync.Mutex
+	fileContexts   map[string]*fileContextEntry // Static context shared by targets in the same file
+
+	destPathOnce sync.Once
+	destPath     string // Import path of the destination package, see destImportPath
+
+	rulesMu      sync.Mutex
+	rulePackages map[string]*rules.Package // Declarations used by rule-based generation, by package directory
+
+	contextCachesMu sync.Mutex
+	contextCaches   map[string]*gathercache.Cache // Context gathering results by receiver type, by package directory
+
+	toolsUnsupported atomic.Bool // The model answered with code instead of calling tools, see generateSingleShot
+
+	siblingsMu sync.Mutex
+	siblings   map[string][]*parser.GenerationResult // Accepted methods by receiver type, see recordSibling
+}
+
+// fileContextEntry lazily builds the shared context for one source file
+type fileContextEntry struct {
+	once sync.Once
+	fc   *pkgcontext.FileContext
+	err  error
 }
 
 // NewParallelCoder creates a new parallel coder
-func NewParallelCoder(clientConfig *llm.ClientConfig, cfg *config.Config) *ParallelCoder {
+func NewParallelCoder(clientConfig *llm.ClientConfig, cfg *config.Config) (*ParallelCoder, error) {
+	httpClient, err := llm.NewHTTPClient(5*time.Minute, clientConfig.Transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	// Snippets run the model's code, which is only allowed without network access
+	runSnippet := cfg.Tools != nil && cfg.Tools.RunSnippet
+	if runSnippet {
+		if err := impl.SnippetIsolation(); err != nil {
+			slog.Warn("run_snippet disabled, as snippets cannot run without network access", slog.String("error", err.Error()))
+			runSnippet = false
+		}
+	}
+
 	return &ParallelCoder{
 		clientConfig: clientConfig,
 		config:       cfg,
 		logger:       slog.Default(),
-		httpClient: &http.Client{
-			Timeout: 5 * time.Minute,
-		},
-	}
+		httpClient:   httpClient,
+		runSnippet:   runSnippet,
+	}, nil
+}
+
+// OnResult registers a callback invoked as soon as each target finishes.
+// The callback may be called concurrently from multiple goroutines.
+func (c *ParallelCoder) OnResult(fn func(*parser.GenerationResult)) {
+	c.onResult = fn
+}
+
+// SetOverlay makes package loading during generation see the given file contents, such as
+// stubs of the generated files, in place of those on disk
+func (c *ParallelCoder) SetOverlay(overlay map[string][]byte) {
+	c.overlay = overlay
+}
+
+// SetStatusTracker also reports target progress to the given tracker
+func (c *ParallelCoder) SetStatusTracker(tracker *status.Tracker) {
+	c.status = tracker
+}
+
+// SetProgressStream also writes target progress events to the given stream
+func (c *ParallelCoder) SetProgressStream(stream *progress.Stream) {
+	c.progress = stream
 }
 
 // TargetContext contains a target and its associated file context
@@ -47,6 +126,9 @@ type TargetContext struct {
 	Index       int
 	FileContent string
 	FileInfo    *parser.FileInfo
+	// PreviousFailure is the failure recorded by the last generation of the target, shown to
+	// the implementation phase so it does not repeat the same mistake (nil if none)
+	PreviousFailure *parser.FailureReason
 }
 
 // ExecuteTargets generates implementations for all targets in parallel
@@ -58,29 +140,80 @@ func (c *ParallelCoder) ExecuteTargets(ctx context.Context, targets []TargetCont
 	// Get project root from the first target's file path
 	projectRoot := findProjectRoot(filepath.Dir(targets[0].Target.FilePath))
 
+	scanner, err := c.provenanceScanner(ctx, projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	c.provenance = scanner
+
 	uiProgram := ui.NewProgramWithOptions(ui.ProgramOptions{
-		Plain: c.config.Plain,
+		Plain:    c.config.Plain,
+		Static:   c.config.UI != nil && c.config.UI.Static,
+		Status:   c.status,
+		Progress: c.progress,
 	})
 
 	// Thread-safe collections for collecting results
 	var mu sync.Mutex
 	var allResults []*parser.GenerationResult
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Start TUI in background
 	tuiDone := make(chan *ui.Model, 1)
 	go func() {
 		model, _ := uiProgram.Start()
+		// Quitting the TUI (q or Ctrl+C) cancels targets that are still running
+		if model != nil && model.IsTUIEnabled() {
+			cancel()
+		}
 		tuiDone <- model
 	}()
 
+	// Register every target up front, so the UI shows the whole run from the start
+	for _, tc := range targets {
+		file, err := filepath.Rel(projectRoot, tc.Target.FilePath)
+		if err != nil {
+			file = tc.Target.FilePath
+		}
+		uiProgram.AddTarget(tc.Target.GetDisplayName(), file, tc.Index, len(targets))
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(16)
+	g.SetLimit(MaxParallelTargets)
+
+	// Targets referenced by another target's instruction are generated first and their
+	// implementations are passed to the dependents. Launching in topological order keeps
+	// dependents that wait from starving their dependencies of errgroup slots
+	deps := targetDependencies(targets)
+	done := make([]chan struct{}, len(targets))
+	results := make([]*parser.GenerationResult, len(targets))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
 
 	// Process each target in parallel
-	for _, tc := range targets {
+	for _, pos := range topologicalOrder(deps) {
+		tc := targets[pos]
 		g.Go(func() error {
-			// Register target with UI
-			uiProgram.AddTarget(tc.Target.GetDisplayName(), tc.Index, len(targets))
+			defer close(done[pos])
+
+			// Wait for dependencies
+			startTime := time.Now()
+			var depResults []*parser.GenerationResult
+			var waitErr error
+			for _, dep := range deps[pos] {
+				select {
+				case <-done[dep]:
+					depResults = append(depResults, results[dep])
+				case <-ctx.Done():
+					waitErr = ctx.Err()
+				}
+				if waitErr != nil {
+					break
+				}
+			}
 
 			handler := log.NewCallbackHandler(
 				uiProgram.SendLog,
@@ -91,11 +224,24 @@ func (c *ParallelCoder) ExecuteTargets(ctx context.Context, targets []TargetCont
 			})
 
 			coder := NewTargetCoder(ctx, c, tc, projectRoot, slog.New(handler), uiProgram)
-			result := coder.Generate()
+			coder.dependencies = depResults
+			var result *parser.GenerationResult
+			if waitErr != nil {
+				// Cancelled targets are reported as failed rather than left out
+				result = coder.failureResult(startTime, "initialization", fmt.Sprintf("Cancelled while waiting for dependencies: %v", waitErr), "")
+			} else {
+				result = coder.Generate()
+			}
+			results[pos] = result
+			c.recordSibling(result)
 
 			mu.Lock()
 			allResults = append(allResults, result)
 			mu.Unlock()
+
+			if c.onResult != nil {
+				c.onResult(result)
+			}
 			return nil
 		})
 	}
@@ -110,9 +256,9 @@ func (c *ParallelCoder) ExecuteTargets(ctx context.Context, targets []TargetCont
 	finalModel := <-tuiDone
 
 	// Display logs for failed targets
-	// Only needed in TUI mode where logs are captured
+	// Only needed in TUI and static mode where logs are captured
 	// In plain mode, logs are already displayed in real-time
-	if finalModel.IsTUIEnabled() {
+	if finalModel.IsTUIEnabled() || finalModel.IsStatic() {
 		c.displayFailedTargetLogs(ctx, finalModel)
 	}
 
@@ -121,12 +267,16 @@ func (c *ParallelCoder) ExecuteTargets(ctx context.Context, targets []TargetCont
 
 // TargetCoder handles the code generation for a single target
 type TargetCoder struct {
-	ctx         context.Context
-	coder       *ParallelCoder
-	target      TargetContext
-	projectRoot string
-	uiProgram   *ui.Program
-	logger      *slog.Logger
+	ctx          context.Context
+	coder        *ParallelCoder
+	target       TargetContext
+	projectRoot  string
+	uiProgram    *ui.Program
+	logger       *slog.Logger
+	client       *llm.Client
+	dependencies []*parser.GenerationResult // Results of targets this target's instruction refers to
+
+	candidateClients []*llm.Client // Clients of additional implementation candidates
 }
 
 // NewTargetCoder creates a new target coder
@@ -151,29 +301,216 @@ func (t *TargetCoder) Generate() *parser.GenerationResult {
 	// Mark target as running
 	t.markRunning()
 
+	// Trivial targets matching a rule need no model; benchmarks and fuzz tests do, so
+	// targets marked with // mantra:bench or // mantra:fuzz are left to it. Rule bodies pass
+	// the checks of generated ones, and go to the model when they don't
+	if body, rule, ok := t.coder.synthesize(t.target.Target); ok && !t.target.Target.Bench && !t.target.Target.Fuzz {
+		checker := phase.NewRunnerWithOptions(nil, t.logger, t.coder.phaseOptions())
+		if fc, err := t.coder.fileContext(t.ctx, t.target.Target.FilePath); err == nil {
+			checker.SetFileContext(fc)
+		}
+		failure := checker.CheckAccepted(t.ctx, t.target.Target, t.target.FileInfo, t.projectRoot, body)
+		if failure == nil {
+			t.logger.Info("Generated by rule, skipping phases", slog.String("rule", rule))
+			result := t.successResult(startTime, body)
+			result.Rule = rule
+			return result
+		}
+		t.logger.Info("Rule body rejected, generating with the model", slog.String("rule", rule), slog.String("reason", failure.Message))
+	}
+
 	// Create LLM client
 	client, err := t.createClient()
 	if err != nil {
 		return t.failureResult(startTime, "initialization", fmt.Sprintf("Failed to create AI client: %v", err), "Check your API configuration and network connection")
 	}
+	t.client = client
 
 	// Execute phases
-	runner := phase.NewRunner(client, t.logger)
+	runner := phase.NewRunnerWithOptions(client, t.logger, t.coder.phaseOptions())
+	runner.SetDependencyContext(formatDependencyContext(t.dependencies))
+	runner.SetPreviousFailure(t.target.PreviousFailure)
+	fc, err := t.coder.fileContext(t.ctx, t.target.Target.FilePath)
+	if err == nil {
+		runner.SetFileContext(fc)
+	} else {
+		t.logger.Warn("Failed to build shared file context", slog.String("error", err.Error()))
+	}
+
+	// Models that cannot call tools get a single prompt once one target found out
+	if t.coder.toolsUnsupported.Load() {
+		return t.generateSingleShot(runner, startTime, nil)
+	}
 
-	// Phase 1: Context Gathering
-	contextResult, failureReason := t.executeContextGathering(runner)
+	// Phase 1: Context Gathering, skipped for simple targets
+	var contextResult map[string]any
+	if fc != nil && t.isFast(fc) {
+		t.logger.Info("Fast mode: skipping context gathering")
+		runner.SetPackageContext(formatPackageContext(fc))
+	} else {
+		var failureReason *parser.FailureReason
+		contextResult, failureReason = t.executeContextGathering(runner)
+		if failureReason != nil && runner.ToolsUnsupported() {
+			return t.generateSingleShot(runner, startTime, nil)
+		}
+		if failureReason != nil {
+			return t.phaseFailureResult(startTime, failureReason)
+		}
+	}
+
+	// Phase 2: Implementation, optionally as several candidates. Methods of the same type
+	// accepted while this one gathered context are shown so it can call them
+	runner.SetSiblingContext(formatSiblingContext(t.coder.acceptedSiblings(t.target.Target), t.dependencies))
+	implementation, failureReason := t.implement(runner, contextResult)
+	if failureReason != nil && runner.ToolsUnsupported() {
+		return t.generateSingleShot(runner, startTime, contextResult)
+	}
+
+	// One focused gathering round for identifiers the implementation could not resolve
+	if missing := phase.MissingIdentifiers(failureReason); len(missing) > 0 {
+		t.logger.Info("Implementation missed context, gathering again", slog.String("identifiers", strings.Join(missing, ", ")))
+		focused, gatherFailure := runner.ExecuteFocusedContextGathering(t.ctx, t.target.Target, t.target.FileContent, t.coder.config.Dest, missing)
+		if gatherFailure == nil {
+			implementation, failureReason = t.implement(runner, phase.MergeContextResults(contextResult, focused))
+		} else {
+			t.logger.Warn("Focused context gathering failed", slog.String("reason", gatherFailure.Message))
+		}
+	}
 	if failureReason != nil {
 		return t.phaseFailureResult(startTime, failureReason)
 	}
 
-	// Phase 2: Implementation
-	implementation, failureReason := t.executeImplementation(runner, contextResult)
+	// Phase 3: Self-review (optional)
+	if review := t.coder.config.Review; review != nil && review.Enabled {
+		implementation = runner.ExecuteReview(t.ctx, t.target.Target, t.target.FileContent, t.target.FileInfo, t.projectRoot, implementation)
+	}
+
+	// User-defined phases, in configured order
+	implementation, failureReason = runner.ExecuteCustomPhases(t.ctx, t.target.Target, t.target.FileContent, t.target.FileInfo, t.projectRoot, implementation)
 	if failureReason != nil {
 		return t.phaseFailureResult(startTime, failureReason)
 	}
 
+	// Benchmark and fuzz test of the accepted implementation (// mantra:bench, // mantra:fuzz)
+	var benchmark, fuzz string
+	if t.target.Target.Bench {
+		benchmark = runner.ExecuteTestFunc(t.ctx, codegen.Benchmarks, t.target.Target, t.target.FileContent, t.target.FileInfo, t.projectRoot, implementation)
+	}
+	if t.target.Target.Fuzz {
+		fuzz = runner.ExecuteTestFunc(t.ctx, codegen.FuzzTests, t.target.Target, t.target.FileContent, t.target.FileInfo, t.projectRoot, implementation)
+	}
+
 	// Success
-	return t.successResult(startTime, implementation)
+	result := t.successResult(startTime, implementation)
+	result.Helpers = runner.Helpers()
+	result.Benchmark = benchmark
+	result.Fuzz = fuzz
+	return result
+}
+
+// generateSingleShot generates the target with a single prompt without tools, for models
+// that answer with code instead of calling tools. Phases after implementation need tools
+// and are skipped; the result is marked as degraded in the report
+func (t *TargetCoder) generateSingleShot(runner *phase.Runner, startTime time.Time, contextResult map[string]any) *parser.GenerationResult {
+	if t.coder.toolsUnsupported.CompareAndSwap(false, true) {
+		t.logger.Warn("Model does not call tools, generating remaining targets with single prompts")
+	}
+	implementation, failureReason := runner.ExecuteSingleShot(t.ctx, t.target.Target, t.target.FileContent, t.target.FileInfo, t.projectRoot, contextResult)
+	var result *parser.GenerationResult
+	if failureReason != nil {
+		result = t.phaseFailureResult(startTime, failureReason)
+	} else {
+		result = t.successResult(startTime, implementation)
+	}
+	result.Degraded = true
+	return result
+}
+
+// phaseOptions builds optional phase behavior from the configuration
+func (c *ParallelCoder) phaseOptions() phase.Options {
+	opts := phase.Options{
+		Conventions:      c.config.Conventions,
+		ForbiddenImports: c.config.ForbiddenImports,
+		DestImportPath:   c.destImportPath(),
+		DestDir:          c.config.Dest,
+		GoVersion:        c.config.TargetGoVersion(),
+		NoPanic:          c.config.NoPanic,
+		Helpers:          c.config.Helpers,
+		Provenance:       c.provenance,
+		Templates:        c.config.Templates,
+		CustomPhases:     c.config.PhaseSpecs(),
+		Overlay:          c.overlay,
+		MaxRounds:        c.config.MaxRounds(),
+	}
+	if c.config.Review != nil {
+		opts.ReviewRubric = c.config.Review.Rubric
+	}
+	if c.config.Tools != nil {
+		opts.RunSnippet = c.runSnippet
+		opts.CheckMaxIssues = c.config.Tools.CheckMaxIssues
+		// Validated when the config is loaded
+		opts.RunSnippetTimeout, _ = time.ParseDuration(c.config.Tools.RunSnippetTimeout)
+	}
+	return opts
+}
+
+// destImportPath returns the import path of the destination package, or "" when it is not
+// inside a module
+func (c *ParallelCoder) destImportPath() string {
+	c.destPathOnce.Do(func() {
+		if c.config.Dest == "" {
+			return
+		}
+		importPath, err := imports.PackagePath(c.config.Dest)
+		if err != nil {
+			c.logger.Debug("Destination import path unknown, self-imports are not checked", slog.String("error", err.Error()))
+			return
+		}
+		c.destPath = importPath
+	})
+	return c.destPath
+}
+
+// fileContext returns the static context for a source file, building it on first use
+func (c *ParallelCoder) fileContext(ctx context.Context, filePath string) (*pkgcontext.FileContext, error) {
+	c.fileContextsMu.Lock()
+	if c.fileContexts == nil {
+		c.fileContexts = make(map[string]*fileContextEntry)
+	}
+	entry, exists := c.fileContexts[filePath]
+	if !exists {
+		entry = &fileContextEntry{}
+		c.fileContexts[filePath] = entry
+	}
+	c.fileContextsMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.fc, entry.err = pkgcontext.NewFileContext(ctx, filePath)
+		if entry.err == nil {
+			entry.fc.SetExpansionLimits(c.config.ContextLimits())
+			entry.fc.SetDocLines(c.config.ContextDocLines())
+		}
+	})
+	return entry.fc, entry.err
+}
+
+// provenanceScanner indexes the configured corpus. Returns nil if provenance scanning is not configured
+func (c *ParallelCoder) provenanceScanner(ctx context.Context, projectRoot string) (*provenance.Scanner, error) {
+	cfg := c.config.Provenance
+	if cfg == nil || (len(cfg.Corpus) == 0 && !cfg.ScanVendor) {
+		return nil, nil
+	}
+
+	roots := append([]string{}, cfg.Corpus...)
+	if cfg.ScanVendor {
+		roots = append(roots, filepath.Join(projectRoot, "vendor"))
+	}
+
+	scanner, err := provenance.NewScanner(ctx, roots, cfg.MinTokens, cfg.Fail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build provenance index: %w", err)
+	}
+	return scanner, nil
 }
 
 // createClient creates a new LLM client for this target
@@ -181,11 +518,23 @@ func (t *TargetCoder) createClient() (*llm.Client, error) {
 	return llm.NewClient(t.coder.clientConfig, t.coder.httpClient, t.logger)
 }
 
-// executeContextGathering executes the context gathering phase
+// executeContextGathering executes the context gathering phase. Methods go through the
+// cache of their receiver type
 func (t *TargetCoder) executeContextGathering(runner *phase.Runner) (map[string]any, *parser.FailureReason) {
+	if t.target.Target.Receiver != nil {
+		return t.executeCachedContextGathering(runner)
+	}
 	return runner.ExecuteContextGathering(t.ctx, t.target.Target, t.target.FileContent, t.coder.config.Dest)
 }
 
+// implement runs the implementation phase, as several candidates when configured
+func (t *TargetCoder) implement(runner *phase.Runner, contextResult map[string]any) (string, *parser.FailureReason) {
+	if temperatures := candidateTemperatures(t.coder.config.Candidates); temperatures != nil {
+		return t.executeCandidates(runner, contextResult, temperatures)
+	}
+	return t.executeImplementation(runner, contextResult)
+}
+
 // executeImplementation executes the implementation phase
 func (t *TargetCoder) executeImplementation(runner *phase.Runner, contextResult map[string]any) (string, *parser.FailureReason) {
 	return runner.ExecuteImplementation(t.ctx, t.target.Target, t.target.FileContent, t.target.FileInfo, t.projectRoot, contextResult)
@@ -195,20 +544,26 @@ func (t *TargetCoder) executeImplementation(runner *phase.Runner, contextResult
 func (t *TargetCoder) successResult(startTime time.Time, implementation string) *parser.GenerationResult {
 	duration := time.Since(startTime).Round(time.Millisecond)
 	t.logger.Info("Successfully generated implementation", "duration", duration)
-	t.markComplete()
 
-	return &parser.GenerationResult{
+	// The code of a region is written into the hand-written body around it
+	if region := t.target.Target.Region; region != nil {
+		implementation = region.Splice(codegen.CleanCode(implementation))
+	}
+
+	// Statistics are recorded first so they are reported with the completion
+	result := t.withUsage(&parser.GenerationResult{
 		Target:         t.target.Target,
 		Success:        true,
 		Implementation: implementation,
 		Duration:       duration,
-	}
+	})
+	t.markComplete()
+	return result
 }
 
 // failureResult creates a failure result
 func (t *TargetCoder) failureResult(startTime time.Time, phase, message, context string) *parser.GenerationResult {
-	t.markFailed()
-	return &parser.GenerationResult{
+	result := t.withUsage(&parser.GenerationResult{
 		Target:  t.target.Target,
 		Success: false,
 		FailureReason: &parser.FailureReason{
@@ -217,18 +572,40 @@ func (t *TargetCoder) failureResult(startTime time.Time, phase, message, context
 			Context: context,
 		},
 		Duration: time.Since(startTime).Round(time.Millisecond),
-	}
+	})
+	t.markFailed()
+	return result
 }
 
 // phaseFailureResult creates a failure result from a phase error
 func (t *TargetCoder) phaseFailureResult(startTime time.Time, failureReason *parser.FailureReason) *parser.GenerationResult {
-	t.markFailed()
-	return &parser.GenerationResult{
+	result := t.withUsage(&parser.GenerationResult{
 		Target:        t.target.Target,
 		Success:       false,
 		FailureReason: failureReason,
 		Duration:      time.Since(startTime).Round(time.Millisecond),
+	})
+	t.markFailed()
+	return result
+}
+
+// withUsage records the client's token usage and request statistics on the result
+func (t *TargetCoder) withUsage(result *parser.GenerationResult) *parser.GenerationResult {
+	if t.client != nil {
+		usage := t.client.Usage()
+		for _, client := range t.candidateClients {
+			usage = usage.Add(client.Usage())
+		}
+		result.PromptTokens = usage.PromptTokens
+		result.CompletionTokens = usage.CompletionTokens
+		result.CachedTokens = usage.CachedTokens
+		result.Rounds = usage.Rounds
+		result.ToolCalls = usage.ToolCalls
+		result.ToolErrors = usage.ToolErrors
+		result.Provider = t.client.GetProviderName()
+		t.uiProgram.SetStats(t.target.Index, usage.Rounds, usage.TotalToolCalls())
 	}
+	return result
 }
 
 // UI callback methods
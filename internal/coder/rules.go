@@ -0,0 +1,31 @@
+package coder
+
+import (
+	"path/filepath"
+
+	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/rules"
+)
+
+// synthesize returns a rule-based body for the target when [rules] is enabled and one
+// of the rules applies. Packages that fail to parse, and regions of hand-written functions,
+// are left to the model
+func (c *ParallelCoder) synthesize(target *parser.Target) (body, rule string, ok bool) {
+	if c.config.Rules == nil || !c.config.Rules.Enabled || target.Region != nil {
+		return "", "", false
+	}
+
+	dir := filepath.Dir(target.FilePath)
+	c.rulesMu.Lock()
+	if c.rulePackages == nil {
+		c.rulePackages = make(map[string]*rules.Package)
+	}
+	pkg, loaded := c.rulePackages[dir]
+	if !loaded {
+		pkg, _ = rules.Load(dir)
+		c.rulePackages[dir] = pkg
+	}
+	c.rulesMu.Unlock()
+
+	return pkg.Synthesize(target)
+}
@@ -0,0 +1,180 @@
+package coder
+
+import (
+	"go/ast"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rail44/mantra/internal/analysis"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// targetDependencies returns, for each target position, the positions of other targets
+// its instruction or body refers to. Instructions count only qualified names, pkg.Func for
+// functions and Type.Method or recv.Method for methods, as bare names such as Get or Close
+// are common words. Edges that would close a cycle are dropped so that scheduling can
+// never deadlock
+func targetDependencies(targets []TargetContext) [][]int {
+	// Methods are also named through the receiver variables of their type, as in s.Validate
+	receivers := make(map[string][]string)
+	for _, tc := range targets {
+		typ := receiverTypeName(tc.Target)
+		if typ != "" && tc.Target.Receiver.Name != "" && !slices.Contains(receivers[typ], tc.Target.Receiver.Name) {
+			receivers[typ] = append(receivers[typ], tc.Target.Receiver.Name)
+		}
+	}
+	patterns := make([]*regexp.Regexp, len(targets))
+	for j, tc := range targets {
+		patterns[j] = referencePattern(tc, receivers)
+	}
+
+	deps := make([][]int, len(targets))
+	for i, tc := range targets {
+		for j, other := range targets {
+			if i == j || (other.Target.Name == tc.Target.Name && receiverTypeName(other.Target) == receiverTypeName(tc.Target)) {
+				continue
+			}
+			if (patterns[j] != nil && patterns[j].MatchString(tc.Target.Instruction)) || bodyReferences(tc.Target, other.Target) {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	// Drop back edges found by depth-first search
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(targets))
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		kept := deps[i][:0]
+		for _, j := range deps[i] {
+			if state[j] == visiting {
+				continue
+			}
+			if state[j] == unvisited {
+				visit(j)
+			}
+			kept = append(kept, j)
+		}
+		deps[i] = kept
+		state[i] = visited
+	}
+	for i := range targets {
+		if state[i] == unvisited {
+			visit(i)
+		}
+	}
+
+	return deps
+}
+
+// topologicalOrder returns target positions ordered so that dependencies come first.
+// Targets without dependencies keep their original relative order
+func topologicalOrder(deps [][]int) []int {
+	order := make([]int, 0, len(deps))
+	placed := make([]bool, len(deps))
+
+	var place func(i int)
+	place = func(i int) {
+		if placed[i] {
+			return
+		}
+		placed[i] = true
+		for _, j := range deps[i] {
+			place(j)
+		}
+		order = append(order, i)
+	}
+	for i := range deps {
+		place(i)
+	}
+
+	return order
+}
+
+// referencePattern returns the pattern matching qualified mentions of a target in an
+// instruction, or nil if the target cannot be named with a qualifier
+func referencePattern(tc TargetContext, receivers map[string][]string) *regexp.Regexp {
+	var qualifiers []string
+	if typ := receiverTypeName(tc.Target); typ != "" {
+		qualifiers = append(qualifiers, regexp.QuoteMeta(typ), `\(\*`+regexp.QuoteMeta(typ)+`\)`)
+		for _, name := range receivers[typ] {
+			qualifiers = append(qualifiers, regexp.QuoteMeta(name))
+		}
+	} else if tc.FileInfo != nil && tc.FileInfo.PackageName != "" {
+		qualifiers = append(qualifiers, regexp.QuoteMeta(tc.FileInfo.PackageName))
+	}
+	if len(qualifiers) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(^|[^\w.])(` + strings.Join(qualifiers, "|") + `)\.` + regexp.QuoteMeta(tc.Target.Name) + `($|[^\w])`)
+}
+
+// bodyReferences reports whether the body of target, such as the hand-written code around a
+// region, refers to other: a call of a function of the same package, or a method selected
+// on the target's own receiver
+func bodyReferences(target, other *parser.Target) bool {
+	if target.FuncDecl == nil || target.FuncDecl.Body == nil || filepath.Dir(target.FilePath) != filepath.Dir(other.FilePath) {
+		return false
+	}
+	otherType := receiverTypeName(other)
+	receiver := ""
+	if otherType != "" && otherType == receiverTypeName(target) {
+		receiver = target.Receiver.Name
+	}
+
+	found := false
+	ast.Inspect(target.FuncDecl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if ident, ok := n.Fun.(*ast.Ident); ok && otherType == "" && ident.Name == other.Name {
+				found = true
+			}
+		case *ast.SelectorExpr:
+			if ident, ok := n.X.(*ast.Ident); ok && receiver != "" && ident.Name == receiver && n.Sel.Name == other.Name {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// receiverTypeName returns the name of the type a method target belongs to, or "" for
+// functions
+func receiverTypeName(target *parser.Target) string {
+	if target.Receiver == nil {
+		return ""
+	}
+	if fn := target.FuncDecl; fn != nil && fn.Recv != nil && len(fn.Recv.List) > 0 {
+		return analysis.ReceiverTypeName(fn.Recv.List[0].Type)
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(target.Receiver.Type, "*"), "[")
+	return name
+}
+
+// formatDependencyContext renders accepted implementations of dependencies for use as prompt context
+func formatDependencyContext(results []*parser.GenerationResult) string {
+	var sb strings.Builder
+	for _, result := range results {
+		if result == nil || !result.Success {
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("## Implementations Generated Earlier in This Run\n")
+			sb.WriteString("These targets are referenced by the instruction and already have accepted implementations:\n")
+		}
+		sb.WriteString("```go\n")
+		sb.WriteString(result.Target.GetFunctionSignature())
+		sb.WriteString(" {\n")
+		sb.WriteString(result.Implementation)
+		sb.WriteString("\n}\n```\n")
+	}
+	return sb.String()
+}
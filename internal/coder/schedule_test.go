@@ -0,0 +1,93 @@
+package coder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestTargetDependencyOrdering(t *testing.T) {
+	fileInfo := &parser.FileInfo{PackageName: "shop"}
+	newTarget := func(name, instruction string) TargetContext {
+		return TargetContext{Target: &parser.Target{Name: name, Instruction: instruction}, FileInfo: fileInfo}
+	}
+	targets := []TargetContext{
+		newTarget("Checkout", "Validate the cart with shop.ValidateCart, then charge using shop.ChargeCard"),
+		newTarget("ValidateCart", "Ensure every item is in stock"),
+		newTarget("ChargeCard", "Charge the card; on failure call shop.Checkout again"), // Cycle with Checkout
+		newTarget("Cart", "Return the cart for a session (not shop.CartItems)"),
+	}
+
+	deps := targetDependencies(targets)
+	expectedDeps := [][]int{{1, 2}, nil, {}, nil}
+	for i := range targets {
+		if len(deps[i]) != len(expectedDeps[i]) || (len(deps[i]) > 0 && !reflect.DeepEqual(deps[i], expectedDeps[i])) {
+			t.Errorf("deps[%d] = %v, want %v", i, deps[i], expectedDeps[i])
+		}
+	}
+
+	order := topologicalOrder(deps)
+	if expected := []int{1, 2, 0, 3}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("order = %v, want %v", order, expected)
+	}
+}
+
+func TestTargetDependenciesQualified(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cart.go")
+	if err := os.WriteFile(path, []byte(`package shop
+
+type Cart struct{}
+
+// mantra: Return the item with the given id
+func (c *Cart) Get(id string) string {
+	panic("not implemented")
+}
+
+// mantra: Sum the prices of the items, looking each up with c.Get
+func (c *Cart) Total() int {
+	panic("not implemented")
+}
+
+// mantra: Get the cart of the session and Close it after use
+func Session(id string) *Cart {
+	panic("not implemented")
+}
+
+// mantra: Check out the cart returned by shop.Session
+func Checkout(id string) error {
+	panic("not implemented")
+}
+
+func Report(id string) string {
+	cart := Session(id)
+	// mantra:region format describe the cart
+	panic("not implemented")
+	// mantra:end
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	fileInfo, err := parser.ParseFileInfo(path)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	var targets []TargetContext
+	for _, target := range fileInfo.Targets {
+		targets = append(targets, TargetContext{Target: target, FileInfo: fileInfo})
+	}
+	if len(targets) != 5 {
+		t.Fatalf("Expected 5 targets, got %d", len(targets))
+	}
+
+	// Bare names such as Get and Close are words, not references; the body of Report calls Session
+	deps := targetDependencies(targets)
+	expectedDeps := [][]int{nil, {0}, nil, {2}, {2}}
+	for i := range targets {
+		if len(deps[i]) != len(expectedDeps[i]) || (len(deps[i]) > 0 && !reflect.DeepEqual(deps[i], expectedDeps[i])) {
+			t.Errorf("deps of %s = %v, want %v", targets[i].Target.Name, deps[i], expectedDeps[i])
+		}
+	}
+}
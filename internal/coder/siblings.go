@@ -0,0 +1,75 @@
+package coder
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// maxSiblingBodyLines is the number of lines of an accepted method's body shown to the other
+// methods of its receiver type; longer bodies are cut with a note of what was left out
+const maxSiblingBodyLines = 15
+
+// siblingKey identifies the receiver type of a method across the package, "" for functions
+func siblingKey(target *parser.Target) string {
+	if target.Receiver == nil {
+		return ""
+	}
+	return filepath.Dir(target.FilePath) + "\x00" + strings.TrimPrefix(target.Receiver.Type, "*")
+}
+
+// recordSibling keeps an accepted method so the methods of its receiver type generated
+// after it can build on it instead of duplicating its logic
+func (c *ParallelCoder) recordSibling(result *parser.GenerationResult) {
+	key := siblingKey(result.Target)
+	if key == "" || !result.Success {
+		return
+	}
+	c.siblingsMu.Lock()
+	defer c.siblingsMu.Unlock()
+	if c.siblings == nil {
+		c.siblings = make(map[string][]*parser.GenerationResult)
+	}
+	c.siblings[key] = append(c.siblings[key], result)
+}
+
+// acceptedSiblings returns the methods of target's receiver type accepted so far in this
+// run, in the order they finished
+func (c *ParallelCoder) acceptedSiblings(target *parser.Target) []*parser.GenerationResult {
+	key := siblingKey(target)
+	if key == "" {
+		return nil
+	}
+	c.siblingsMu.Lock()
+	defer c.siblingsMu.Unlock()
+	return slices.Clone(c.siblings[key])
+}
+
+// formatSiblingContext renders accepted methods of the target's receiver type for the
+// implementation prompt. Methods already shown as dependencies are left out
+func formatSiblingContext(results, dependencies []*parser.GenerationResult) string {
+	var sb strings.Builder
+	for _, result := range results {
+		if slices.Contains(dependencies, result) {
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("## Methods of the Same Type Generated Earlier in This Run\n")
+			sb.WriteString("Call these instead of repeating their logic:\n")
+		}
+		sb.WriteString("```go\n")
+		sb.WriteString(result.Target.GetFunctionSignature())
+		sb.WriteString(" {\n")
+		lines := strings.Split(result.Implementation, "\n")
+		if len(lines) > maxSiblingBodyLines {
+			omitted := len(lines) - maxSiblingBodyLines
+			lines = append(lines[:maxSiblingBodyLines], fmt.Sprintf("// ... %d more lines", omitted))
+		}
+		sb.WriteString(strings.Join(lines, "\n"))
+		sb.WriteString("\n}\n```\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,46 @@
+package coder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestSiblingContext(t *testing.T) {
+	newResult := func(name, receiver, body string, success bool) *parser.GenerationResult {
+		target := &parser.Target{Name: name, FilePath: "/src/shop/cart.go"}
+		if receiver != "" {
+			target.Receiver = &parser.Receiver{Name: "c", Type: receiver}
+		}
+		return &parser.GenerationResult{Target: target, Implementation: body, Success: success}
+	}
+	subtotal := newResult("Subtotal", "*Cart", "return c.sum()", true)
+	total := newResult("Total", "Cart", strings.Repeat("x++\n", 20)+"return x", true)
+	failed := newResult("Tax", "*Cart", "", false)
+	function := newResult("NewCart", "", "return &Cart{}", true)
+
+	c := &ParallelCoder{}
+	for _, result := range []*parser.GenerationResult{subtotal, failed, function, total} {
+		c.recordSibling(result)
+	}
+
+	siblings := c.acceptedSiblings(newResult("Discount", "*Cart", "", false).Target)
+	if len(siblings) != 2 || siblings[0] != subtotal || siblings[1] != total {
+		t.Fatalf("Expected Subtotal and Total, got %v", siblings)
+	}
+	if got := c.acceptedSiblings(function.Target); got != nil {
+		t.Errorf("Expected no siblings for a function, got %v", got)
+	}
+
+	context := formatSiblingContext(siblings, []*parser.GenerationResult{subtotal})
+	if strings.Contains(context, "Subtotal") {
+		t.Errorf("Expected dependencies to be left out, got:\n%s", context)
+	}
+	if !strings.Contains(context, "func (c Cart) Total()") || !strings.Contains(context, "// ... 6 more lines") {
+		t.Errorf("Expected Total with its body cut, got:\n%s", context)
+	}
+	if got := formatSiblingContext(nil, nil); got != "" {
+		t.Errorf("Expected no context without siblings, got %q", got)
+	}
+}
@@ -1,15 +1,37 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"go/token"
+	"go/version"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/codegen"
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/conventions"
+	"github.com/rail44/mantra/internal/diff"
+	"github.com/rail44/mantra/internal/gathercache"
+	"github.com/rail44/mantra/internal/history"
+	"github.com/rail44/mantra/internal/llm"
+	"github.com/rail44/mantra/internal/phase"
+	"github.com/rail44/mantra/internal/prompt"
+	"github.com/rail44/mantra/internal/stats"
 )
 
+// ProgressJSONL writes one JSON line per progress event to stdout (--progress jsonl)
+const ProgressJSONL = "jsonl"
+
 // Config represents the complete configuration for mantra
 type Config struct {
 	// Required fields
@@ -18,61 +40,619 @@ type Config struct {
 	Dest  string `toml:"dest"`
 
 	// Optional fields
-	APIKey   string `toml:"api_key"`
-	LogLevel string `toml:"log_level"`
-	Plain    bool   `toml:"-"` // CLI flag, not from config file
+	APIKey          string     `toml:"api_key"`
+	APIKeyCmd       string     `toml:"api_key_cmd"` // Shell command printing the API key (e.g. "op read op://dev/openai/key")
+	LogLevel        string     `toml:"log_level"`
+	SummaryFile     string     `toml:"summary_file"`     // Markdown run summary output path
+	ConventionsFile string     `toml:"conventions_file"` // Conventions file path (default: .mantra/conventions.toml)
+	TemplatesDir    string     `toml:"templates_dir"`    // Prompt template overrides (default: .mantra/templates)
+	PackageName     string     `toml:"package_name"`     // Generated package name (default: base name of dest)
+	Plain           bool       `toml:"-"`                // CLI flag, not from config file
+	StatusAddr      string     `toml:"-"`                // CLI flag: address serving live progress (empty disables)
+	Progress        string     `toml:"-"`                // CLI flag: machine-readable progress format (ProgressJSONL or empty)
+	ProgressFile    string     `toml:"-"`                // CLI flag: file kept up to date with the run state as JSON (empty disables)
+	Diff            *diff.Diff `toml:"-"`                // CLI flag: generate only files whose pending targets the diff touches (nil considers all)
+
+	// Import paths generated code must never use ("path/..." forbids a whole subtree)
+	ForbiddenImports []string `toml:"forbidden_imports"`
+
+	// Go release generated code must compile with, e.g. "1.21" (default: any)
+	GoVersion string `toml:"go_version"`
+
+	// Reject generated code that calls panic, for every target (// mantra:no_panic sets it per target)
+	NoPanic bool `toml:"no_panic"`
+
+	// Let the model write private helper functions below the target
+	Helpers bool `toml:"helpers"`
+
+	// Build flags and environment packages are loaded and snippets run with
+	Build *BuildConfig `toml:"build"`
+
+	// Turn blank imports used by generated code into regular imports instead of adding a second import
+	ConvertBlankImports bool `toml:"convert_blank_imports"`
+
+	// Write each target to a file of its own instead of the file mirroring its source file
+	PerTargetFiles bool `toml:"per_target_files"`
+
+	// End each generated file with a comment listing its targets, models and generation times
+	GenerationSummary bool `toml:"generation_summary"`
+
+	// Bodies written into targets that failed to generate instead of panic("not implemented")
+	Fallback *FallbackConfig `toml:"fallback"`
+
+	// API key stored in the platform keychain, as an alternative to api_key and api_key_cmd
+	APIKeyKeychain *KeychainConfig `toml:"api_key_keychain"`
+
+	// Proxy and TLS settings for reaching the AI provider
+	HTTP *HTTPConfig `toml:"http"`
+
+	// Extra headers sent with every provider request (values support ${VAR} expansion)
+	Headers map[string]string `toml:"headers"`
+
+	// How requests are authenticated (default: api key as a bearer token)
+	Auth *AuthConfig `toml:"auth"`
+
+	// Request pacing per AI provider
+	RateLimit *RateLimitConfig `toml:"rate_limit"`
 
 	// OpenRouter configuration
 	OpenRouter *OpenRouterConfig `toml:"openrouter"`
+
+	// Provider prompt caching of system prompts and shared package context
+	PromptCache *PromptCacheConfig `toml:"prompt_cache"`
+
+	// Provider prices used by mantra estimate
+	Pricing *PricingConfig `toml:"pricing"`
+
+	// Notification hooks fired when a run finishes
+	Notify *NotifyConfig `toml:"notify"`
+
+	// Git integration for generated files
+	Git *GitConfig `toml:"git"`
+
+	// Optional tools available to the model
+	Tools *ToolsConfig `toml:"tools"`
+
+	// Multiple implementation candidates per target
+	Candidates *CandidatesConfig `toml:"candidates"`
+
+	// Self-review of accepted implementations
+	Review *ReviewConfig `toml:"review"`
+
+	// User-defined phases run in order on each accepted implementation
+	Phases []PhaseConfig `toml:"phases"`
+
+	// Rounds of tool calls each built-in phase may take
+	Rounds *RoundsConfig `toml:"rounds"`
+
+	// Pruning of old tool results in long tool-calling sessions
+	Transcript *TranscriptConfig `toml:"transcript"`
+
+	// Single-phase generation for simple targets
+	Fast *FastConfig `toml:"fast"`
+
+	// Bounds of the package types and doc comments added to each target's context
+	Context *ContextConfig `toml:"context"`
+
+	// Rule-based generation of trivial targets without a model
+	Rules *RulesConfig `toml:"rules"`
+
+	// Checks of instructions before generation
+	Lint *LintConfig `toml:"lint"`
+
+	// Extra checks when deciding which targets are up-to-date
+	Detect *DetectConfig `toml:"detect"`
+
+	// Scanning of generated code for verbatim copies of known sources
+	Provenance *ProvenanceConfig `toml:"provenance"`
+
+	// Rolling history of accepted implementations shown by mantra history
+	History *HistoryConfig `toml:"history"`
+
+	// Local statistics of tool use and rounds shown by mantra stats (opt-in)
+	Stats *StatsConfig `toml:"stats"`
+
+	// Git hooks installed by mantra hooks install
+	Hooks *HooksConfig `toml:"hooks"`
+
+	// Progress display of generate
+	UI *UIConfig `toml:"ui"`
+
+	// Project conventions enforced on generated code, loaded from ConventionsFile
+	Conventions *conventions.Conventions `toml:"-"`
+
+	// Prompt templates with overrides from TemplatesDir applied
+	Templates *prompt.Templates `toml:"-"`
+
+	files   []string          // Config files merged into this configuration, project config first
+	sources map[string]string // Where each key was last set, see recordSource
+}
+
+// AuthConfig customizes how provider requests are authenticated
+type AuthConfig struct {
+	Header          string  `toml:"header"`           // Header carrying the credential (default: Authorization)
+	Scheme          *string `toml:"scheme"`           // Prefix before the credential (default: "Bearer" for Authorization, none otherwise)
+	TokenCmd        string  `toml:"token_cmd"`        // Shell command printing the credential instead of the api key (e.g. a JWT)
+	RefreshInterval string  `toml:"refresh_interval"` // How long a token_cmd credential is reused (e.g. "10m"; default: whole run)
+}
+
+// ToolsConfig enables optional tools for the implementation phase
+type ToolsConfig struct {
+	RunSnippet        bool   `toml:"run_snippet"`         // Allow running the generated body with example inputs
+	RunSnippetTimeout string `toml:"run_snippet_timeout"` // Timeout per run_snippet call (e.g. "20s")
+	CheckMaxIssues    int    `toml:"check_max_issues"`    // Issues after which check_code stops running analyzers (0: no limit)
+	MaxParallel       int    `toml:"max_parallel"`        // Tool calls of one round run at the same time (0: default of 8)
+}
+
+// CandidatesConfig requests several implementations per target and keeps the first clean one
+type CandidatesConfig struct {
+	Count        int       `toml:"count"`        // Implementations generated in parallel (0 or 1 disables)
+	Temperatures []float32 `toml:"temperatures"` // Temperature per candidate (default: spread between 0.2 and 0.8)
+}
+
+// ReviewConfig enables a self-review phase after implementation
+type ReviewConfig struct {
+	Enabled bool     `toml:"enabled"` // Run the self-review phase (costs an extra round-trip)
+	Rubric  []string `toml:"rubric"`  // Review checklist (default: built-in rubric)
+}
+
+// PhaseConfig defines an extra phase run after implementation (and self-review)
+type PhaseConfig struct {
+	Name             string   `toml:"name"`
+	SystemPrompt     string   `toml:"system_prompt"`
+	SystemPromptFile string   `toml:"system_prompt_file"` // Read into SystemPrompt when set
+	Tools            []string `toml:"tools"`              // Subset of check_code, inspect, search, list_errors, call_examples, run_snippet
+	Temperature      *float32 `toml:"temperature"`        // Default: 0.2
+	ResultSchema     string   `toml:"result_schema"`      // JSON Schema of the result tool (default: success, code, comments)
+	ResultSchemaFile string   `toml:"result_schema_file"` // Read into ResultSchema when set
+	Required         bool     `toml:"required"`           // Fail the target when the phase fails instead of keeping the implementation
+	MaxRounds        int      `toml:"max_rounds"`         // Rounds of tool calls (default: 30)
+}
+
+// RoundsConfig bounds the rounds of tool calls of the built-in phases. Past half of its
+// rounds a phase is reminded to finish, more firmly as they run out
+type RoundsConfig struct {
+	ContextGathering int `toml:"context_gathering"` // Default: 30
+	Implementation   int `toml:"implementation"`    // Default: 30
+	Review           int `toml:"review"`            // Default: 30
+	Tests            int `toml:"tests"`             // Benchmark and fuzz test phases (default: 30)
+}
+
+// TranscriptConfig keeps long tool-calling sessions within the model's context by
+// summarizing old tool results
+type TranscriptConfig struct {
+	KeepRounds int `toml:"keep_rounds"` // Rounds whose tool results are kept in full; older ones become a one-line summary (0 keeps all)
+}
+
+// MaxRounds returns the configured rounds of tool calls by built-in phase name. Phases
+// left out take llm.DefaultMaxRounds
+func (c *Config) MaxRounds() map[string]int {
+	if c.Rounds == nil {
+		return nil
+	}
+	return map[string]int{
+		phase.PhaseContextGathering: c.Rounds.ContextGathering,
+		phase.PhaseImplementation:   c.Rounds.Implementation,
+		phase.PhaseReview:           c.Rounds.Review,
+		phase.PhaseBenchmark:        c.Rounds.Tests,
+		phase.PhaseFuzz:             c.Rounds.Tests,
+	}
+}
+
+// defaultPhaseTemperature is the temperature of custom phases that do not set one
+const defaultPhaseTemperature = 0.2
+
+// PhaseSpecs converts the configured phases for the phase runner
+func (c *Config) PhaseSpecs() []phase.CustomPhaseSpec {
+	specs := make([]phase.CustomPhaseSpec, 0, len(c.Phases))
+	for _, p := range c.Phases {
+		spec := phase.CustomPhaseSpec{
+			Name:         p.Name,
+			SystemPrompt: p.SystemPrompt,
+			Tools:        p.Tools,
+			Temperature:  defaultPhaseTemperature,
+			Required:     p.Required,
+			MaxRounds:    p.MaxRounds,
+		}
+		if p.Temperature != nil {
+			spec.Temperature = *p.Temperature
+		}
+		if p.ResultSchema != "" {
+			spec.ResultSchema = json.RawMessage(p.ResultSchema)
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// FastConfig controls when targets skip context gathering without a // mantra:fast marker
+type FastConfig struct {
+	MaxWords int `toml:"max_words"` // Instructions with at most this many words and only known identifiers (0 disables)
+}
+
+// ContextConfig bounds how far types referenced by a target's types are expanded into its
+// context, for packages with large type graphs, and how much of their docs is kept
+type ContextConfig struct {
+	MaxDepth    *int  `toml:"max_depth"`     // Levels of referenced types added (default: 3, 0 disables)
+	MaxPerLevel int   `toml:"max_per_level"` // Types added per level, most referenced first (0: no limit)
+	MaxBytes    int   `toml:"max_bytes"`     // Bytes of type definitions in a target's context (0: no limit)
+	DocLines    *int  `toml:"doc_lines"`     // Lines kept of each doc comment of directly used types and their methods (default: 3, 0 disables)
+	Cache       *bool `toml:"cache"`         // Seed context gathering of methods with what was gathered for their receiver type (default: true)
+}
+
+// RulesConfig enables deterministic bodies for getters, setters, delegation to an
+// embedded field and map access, reserving the model for the remaining targets
+type RulesConfig struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// LintConfig flags vague instructions, unstated error handling and unknown identifiers
+// before targets are generated
+type LintConfig struct {
+	Enabled bool `toml:"enabled"` // Lint pending targets and log the issues found
+	Model   bool `toml:"model"`   // Also have the model review each instruction (one extra request per target)
+	Strict  bool `toml:"strict"`  // Refuse to generate flagged targets (same as --strict)
+}
+
+// BuildConfig matches package loading to the project's own builds, e.g. for vendored
+// dependencies or build tags
+type BuildConfig struct {
+	Flags []string          `toml:"flags"` // Build flags such as "-mod=vendor" or "-tags=integration"
+	Env   map[string]string `toml:"env"`   // Environment such as GOFLAGS or GONOSUMCHECK (values support ${VAR} expansion)
+}
+
+// FallbackConfig holds the templates of bodies written into targets that failed to
+// generate, so the package stays usable and the failure surfaces at runtime. Templates
+// see {{.Target}}, {{.Phase}}, {{.Message}} and {{.Zero}} (the zero values of the results
+// before a trailing error, each followed by ", "), and quote writes a Go string literal
+type FallbackConfig struct {
+	Body      string `toml:"body"`       // Targets without an error result (default: keep the source body)
+	ErrorBody string `toml:"error_body"` // Targets whose last result is an error (default: body)
+}
+
+// DetectConfig enables extra staleness checks for generated targets
+type DetectConfig struct {
+	SignatureImpact bool `toml:"signature_impact"` // Mark up-to-date targets that no longer compile against current declarations as outdated
+}
+
+// HTTPConfig configures the HTTP client used for AI provider requests
+type HTTPConfig struct {
+	Proxy              string `toml:"proxy"`                // Proxy URL, overriding HTTP_PROXY/HTTPS_PROXY
+	CAFile             string `toml:"ca_file"`              // Additional trusted CA bundle (PEM)
+	CertFile           string `toml:"cert_file"`            // Client certificate for mutual TLS (PEM)
+	KeyFile            string `toml:"key_file"`             // Client certificate key (PEM)
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify"` // Skip server certificate verification (testing only)
+
+	CompressRequests    bool   `toml:"compress_requests"`       // gzip request bodies (falls back if the server rejects them)
+	MaxIdleConnsPerHost int    `toml:"max_idle_conns_per_host"` // Idle keep-alive connections per host (default: 16)
+	MaxConnsPerHost     int    `toml:"max_conns_per_host"`      // Connection limit per host (default: unlimited)
+	IdleConnTimeout     string `toml:"idle_conn_timeout"`       // How long idle connections are kept (e.g. "90s")
+}
+
+// RateLimitConfig paces requests so parallel runs stay under provider rate limits
+type RateLimitConfig struct {
+	RateLimit
+
+	// Limits for specific providers, keyed by endpoint host (e.g. "api.openai.com")
+	Providers map[string]RateLimit `toml:"providers"`
+}
+
+// RateLimit holds per-minute limits; zero means unlimited
+type RateLimit struct {
+	RequestsPerMinute int `toml:"requests_per_minute"`
+	TokensPerMinute   int `toml:"tokens_per_minute"`
+}
+
+// LimitsFor returns the limits for the provider at host, falling back to the defaults
+func (c *RateLimitConfig) LimitsFor(host string) RateLimit {
+	if limits, ok := c.Providers[host]; ok {
+		return limits
+	}
+	return c.RateLimit
+}
+
+// PricingConfig holds provider prices for cost estimates
+type PricingConfig struct {
+	Price
+
+	// Prices for specific models, keyed by model name
+	Models map[string]Price `toml:"models"`
+}
+
+// Price is a price in USD per million tokens
+type Price struct {
+	Input  float64 `toml:"input"`
+	Output float64 `toml:"output"`
+}
+
+// PriceFor returns the price of model, falling back to the default price
+func (c *PricingConfig) PriceFor(model string) Price {
+	if price, ok := c.Models[model]; ok {
+		return price
+	}
+	return c.Price
 }
 
 // OpenRouterConfig represents OpenRouter-specific configuration
 type OpenRouterConfig struct {
-	Providers []string `toml:"providers"`
+	OpenRouterPreferences
+
+	// Preferences for specific models, overriding the defaults above field by field
+	Models map[string]OpenRouterPreferences `toml:"models"`
+}
+
+// OpenRouterPreferences mirrors OpenRouter's provider routing options
+type OpenRouterPreferences struct {
+	Providers      []string `toml:"providers"`       // Only use these providers
+	Order          []string `toml:"order"`           // Try these providers first, in order
+	Ignore         []string `toml:"ignore"`          // Never use these providers
+	AllowFallbacks *bool    `toml:"allow_fallbacks"` // Allow providers outside order/providers as fallbacks
+	Quantizations  []string `toml:"quantizations"`   // Accepted quantizations (e.g. "fp8", "bf16")
+}
+
+// PromptCacheConfig marks the parts of prompts repeated across targets as cacheable, so
+// providers bill them at their cached rate after the first target
+type PromptCacheConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Style   string `toml:"style"` // "anthropic" (cache_control breakpoints) or "openai" (prompt_cache_key); default: from the model name
+}
+
+// promptCacheStyles lists the accepted prompt_cache.style values
+var promptCacheStyles = []string{llm.PromptCacheAnthropic, llm.PromptCacheOpenAI}
+
+// StyleFor returns how prompts of model are marked for caching, or "" when caching is disabled.
+// Claude and Gemini models only cache blocks marked with cache_control; other providers
+// cache prefixes automatically and only need a key to route requests to the same cache
+func (p *PromptCacheConfig) StyleFor(model string) string {
+	if p == nil || !p.Enabled {
+		return ""
+	}
+	if p.Style != "" {
+		return p.Style
+	}
+	name := strings.ToLower(model)
+	if strings.Contains(name, "claude") || strings.Contains(name, "anthropic") || strings.Contains(name, "gemini") {
+		return llm.PromptCacheAnthropic
+	}
+	return llm.PromptCacheOpenAI
+}
+
+// openRouterQuantizations lists the quantization levels accepted by OpenRouter
+var openRouterQuantizations = []string{"int4", "int8", "fp4", "fp6", "fp8", "fp16", "bf16", "fp32", "unknown"}
+
+// PreferencesFor returns the routing preferences for model, applying its overrides to the defaults
+func (c *OpenRouterConfig) PreferencesFor(model string) OpenRouterPreferences {
+	prefs := c.OpenRouterPreferences
+	override, ok := c.Models[model]
+	if !ok {
+		return prefs
+	}
+
+	if override.Providers != nil {
+		prefs.Providers = override.Providers
+	}
+	if override.Order != nil {
+		prefs.Order = override.Order
+	}
+	if override.Ignore != nil {
+		prefs.Ignore = override.Ignore
+	}
+	if override.AllowFallbacks != nil {
+		prefs.AllowFallbacks = override.AllowFallbacks
+	}
+	if override.Quantizations != nil {
+		prefs.Quantizations = override.Quantizations
+	}
+	return prefs
+}
+
+// ProvenanceConfig configures scanning generated code for long verbatim copies of known sources
+type ProvenanceConfig struct {
+	Corpus     []string `toml:"corpus"`      // Directories of Go sources to compare generated code against
+	ScanVendor bool     `toml:"scan_vendor"` // Also compare against the project's vendor directory
+	MinTokens  int      `toml:"min_tokens"`  // Shortest verbatim token run that is reported (default: 60)
+	Fail       bool     `toml:"fail"`        // Fail the target on a match instead of only warning
+}
+
+// StatsConfig records, on this machine only, which tools each target called, which calls
+// failed and how many rounds it took
+type StatsConfig struct {
+	Enabled bool   `toml:"enabled"` // Record statistics of every generate run
+	File    string `toml:"file"`    // Statistics file (default: .mantra/stats.jsonl next to the project config)
+}
+
+// UIConfig configures the progress display of generate
+type UIConfig struct {
+	Static bool `toml:"static"` // Print a line per target update instead of the animated TUI (also with NO_COLOR or TERM=dumb)
+}
+
+// HooksConfig configures the git hooks installed by mantra hooks install
+type HooksConfig struct {
+	SkipEnv []string `toml:"skip_env"` // Environment variables that skip the hook when set (default: ["CI"])
+}
+
+// envNamePattern matches environment variable names a hook script can test
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// HistoryConfig configures the rolling history of accepted implementations
+type HistoryConfig struct {
+	Dir  string `toml:"dir"`  // History directory (default: .mantra/history next to the project config)
+	Keep *int   `toml:"keep"` // Implementations kept per target (default: 5, 0 disables)
+}
+
+// GitConfig represents git integration for generated files
+type GitConfig struct {
+	Add           bool   `toml:"add"`            // Stage generated files after each run
+	Commit        bool   `toml:"commit"`         // Commit generated files after each run (implies add)
+	CommitMessage string `toml:"commit_message"` // text/template for the commit message, executed with the run report
+}
+
+// NotifyConfig represents notification hooks for run completion
+type NotifyConfig struct {
+	Command    string `toml:"command"`     // Shell command to execute; receives the JSON payload on stdin
+	WebhookURL string `toml:"webhook_url"` // URL to POST the JSON payload to
+	OnFailure  bool   `toml:"on_failure"`  // Also notify as soon as the first target fails
 }
 
 // Load loads configuration from mantra.toml
 func Load(targetPath string) (*Config, error) {
-	// Find config file starting from target directory
-	configPath, err := findConfigFile(targetPath)
+	// Find config files from the project root down to the target directory
+	configPaths, err := findConfigFiles(targetPath)
 	if err != nil {
 		return nil, err
 	}
 
-	// Read and parse config file
+	// Package configs are merged over the configs of their parent directories
+	var cfg Config
+	for _, configPath := range configPaths {
+		if err := cfg.decodeFile(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate required fields
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	// Read phase prompt and schema files, then check the schemas compile
+	for i := range cfg.Phases {
+		if err := cfg.Phases[i].loadFiles(); err != nil {
+			return nil, err
+		}
+	}
+	for _, spec := range cfg.PhaseSpecs() {
+		if _, err := phase.NewCustomResultSchema(spec.ResultSchema); err != nil {
+			return nil, fmt.Errorf("invalid configuration: phases %q: %w", spec.Name, err)
+		}
+	}
+
+	// Load conventions; an explicitly configured file must exist
+	conventionsRequired := cfg.ConventionsFile != ""
+	if !conventionsRequired {
+		cfg.ConventionsFile = nearestDefault(configPaths, conventions.DefaultFile)
+	}
+	cfg.Conventions, err = conventions.Load(cfg.ConventionsFile, conventionsRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load prompt templates; an explicitly configured directory must exist
+	if cfg.TemplatesDir != "" {
+		if _, err := os.Stat(cfg.TemplatesDir); err != nil {
+			return nil, fmt.Errorf("failed to access templates_dir: %w", err)
+		}
+	} else {
+		cfg.TemplatesDir = nearestDefault(configPaths, prompt.DefaultTemplatesDir)
+	}
+	cfg.Templates, err = prompt.LoadTemplates(cfg.TemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// decodeFile merges one config file into c and resolves the relative paths it sets against
+// its directory. Tables are merged key by key; arrays replace the values of earlier files
+func (c *Config) decodeFile(configPath string) error {
 	configData, err := os.ReadFile(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Parse TOML
-	var cfg Config
-	if _, err := toml.Decode(string(configData), &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	md, err := toml.Decode(string(configData), c)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
+	c.files = append(c.files, configPath)
+	c.recordFileSources(md, configPath)
 
 	// Validate and warn about hardcoded API keys (before expansion)
-	if cfg.APIKey != "" && !strings.Contains(cfg.APIKey, "${") && strings.HasPrefix(cfg.APIKey, "sk-") {
-		fmt.Fprintf(os.Stderr, "Warning: API key appears to be hardcoded in mantra.toml. Consider using environment variables: api_key = \"${OPENROUTER_API_KEY}\"\n")
+	if md.IsDefined("api_key") && !strings.Contains(c.APIKey, "${") && strings.HasPrefix(c.APIKey, "sk-") {
+		fmt.Fprintf(os.Stderr, "Warning: API key appears to be hardcoded in %s. Consider using environment variables: api_key = \"${OPENROUTER_API_KEY}\"\n", configPath)
 	}
 
-	// Validate required fields
-	if err := cfg.validate(); err != nil {
-		return nil, err
+	// A file that configures the API key replaces the key source of earlier files
+	if md.IsDefined("api_key") || md.IsDefined("api_key_cmd") || md.IsDefined("api_key_keychain") {
+		if !md.IsDefined("api_key") {
+			c.APIKey = ""
+		}
+		if !md.IsDefined("api_key_cmd") {
+			c.APIKeyCmd = ""
+		}
+		if !md.IsDefined("api_key_keychain") {
+			c.APIKeyKeychain = nil
+		}
 	}
 
-	// Normalize paths
-	cfg.Dest = normalizePath(cfg.Dest, filepath.Dir(configPath))
+	// Paths set by earlier files are already absolute and stay unchanged
+	configDir := filepath.Dir(configPath)
+	paths := []*string{&c.Dest, &c.SummaryFile, &c.ConventionsFile, &c.TemplatesDir}
+	if c.HTTP != nil {
+		paths = append(paths, &c.HTTP.CAFile, &c.HTTP.CertFile, &c.HTTP.KeyFile)
+	}
+	if c.History != nil {
+		paths = append(paths, &c.History.Dir)
+	}
+	if c.Stats != nil {
+		paths = append(paths, &c.Stats.File)
+	}
+	if c.Provenance != nil {
+		for i := range c.Provenance.Corpus {
+			paths = append(paths, &c.Provenance.Corpus[i])
+		}
+	}
+	for i := range c.Phases {
+		paths = append(paths, &c.Phases[i].SystemPromptFile, &c.Phases[i].ResultSchemaFile)
+	}
+	for _, path := range paths {
+		if *path != "" {
+			*path = normalizePath(*path, configDir)
+		}
+	}
+	return nil
+}
 
-	return &cfg, nil
+// nearestDefault resolves a default path against the innermost config directory where it
+// exists, falling back to the directory of the project config
+func nearestDefault(configPaths []string, path string) string {
+	for i := len(configPaths) - 1; i > 0; i-- {
+		candidate := normalizePath(path, filepath.Dir(configPaths[i]))
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return normalizePath(path, filepath.Dir(configPaths[0]))
 }
 
-// findConfigFile searches for mantra.toml starting from the given path
-func findConfigFile(startPath string) (string, error) {
+// loadFiles reads the system prompt and result schema files of a phase
+func (p *PhaseConfig) loadFiles() error {
+	if p.SystemPromptFile != "" {
+		data, err := os.ReadFile(p.SystemPromptFile)
+		if err != nil {
+			return fmt.Errorf("failed to read system_prompt_file of phase %q: %w", p.Name, err)
+		}
+		p.SystemPrompt = string(data)
+	}
+	if p.ResultSchemaFile != "" {
+		data, err := os.ReadFile(p.ResultSchemaFile)
+		if err != nil {
+			return fmt.Errorf("failed to read result_schema_file of phase %q: %w", p.Name, err)
+		}
+		p.ResultSchema = string(data)
+	}
+	return nil
+}
+
+// findConfigFiles returns the mantra.toml files from the given path up to the project
+// root, outermost first. The outermost file is the project config; the others are
+// package configs that override it
+func findConfigFiles(startPath string) ([]string, error) {
 	// Convert to absolute path
 	absPath, err := filepath.Abs(startPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
 	// If startPath is a file, start from its directory
@@ -81,24 +661,52 @@ func findConfigFile(startPath string) (string, error) {
 		absPath = filepath.Dir(absPath)
 	}
 
-	// Search upward for mantra.toml
+	// Search upward for mantra.toml, up to the project root
+	var configPaths []string
+	root := projectRoot(absPath)
 	currentDir := absPath
 	for {
 		configPath := filepath.Join(currentDir, "mantra.toml")
 		if _, err := os.Stat(configPath); err == nil {
-			return configPath, nil
+			configPaths = append(configPaths, configPath)
+			if root == "" {
+				// Outside a project only the nearest config applies
+				break
+			}
 		}
 
 		// Move to parent directory
 		parentDir := filepath.Dir(currentDir)
-		if parentDir == currentDir {
-			// Reached root directory
+		if currentDir == root || parentDir == currentDir {
 			break
 		}
 		currentDir = parentDir
 	}
 
-	return "", fmt.Errorf("mantra.toml not found. Create one with:\n\nmodel = \"devstral\"\nurl = \"http://localhost:11434/v1\"\ndest = \"./generated\"\n\nSee: https://github.com/rail44/mantra#configuration")
+	if len(configPaths) == 0 {
+		return nil, fmt.Errorf("mantra.toml not found. Create one with:\n\nmodel = \"devstral\"\nurl = \"http://localhost:11434/v1\"\ndest = \"./generated\"\n\nSee: https://github.com/rail44/mantra#configuration")
+	}
+	slices.Reverse(configPaths)
+	return configPaths, nil
+}
+
+// projectRoot returns the root of the project holding dir: the VCS root, or without one
+// the outermost directory with a go.mod. It returns "" when dir is in neither
+func projectRoot(dir string) string {
+	var root string
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			root = dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return root
+		}
+		dir = parent
+	}
 }
 
 // expandEnvVars expands ${VAR_NAME} environment variables in the string
@@ -130,10 +738,221 @@ func (c *Config) validate() error {
 	}
 	if c.URL == "" {
 		errors = append(errors, "url is required")
+	} else if u, err := url.Parse(c.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		errors = append(errors, fmt.Sprintf("url must be an absolute URL, got %q", c.URL))
 	}
 	if c.Dest == "" {
 		errors = append(errors, "dest is required")
 	}
+	keySources := 0
+	for _, set := range []bool{c.APIKey != "", c.APIKeyCmd != "", c.APIKeyKeychain != nil} {
+		if set {
+			keySources++
+		}
+	}
+	if keySources > 1 {
+		errors = append(errors, "only one of api_key, api_key_cmd and api_key_keychain may be set")
+	}
+	if c.APIKeyKeychain != nil && c.APIKeyKeychain.Service == "" {
+		errors = append(errors, "api_key_keychain.service is required")
+	}
+	if c.PackageName != "" && !token.IsIdentifier(c.PackageName) {
+		errors = append(errors, fmt.Sprintf("package_name must be a valid Go identifier, got %q", c.PackageName))
+	}
+	if c.Tools != nil && c.Tools.RunSnippetTimeout != "" {
+		if _, err := time.ParseDuration(c.Tools.RunSnippetTimeout); err != nil {
+			errors = append(errors, fmt.Sprintf("tools.run_snippet_timeout is invalid: %v", err))
+		}
+	}
+	if c.Tools != nil && c.Tools.CheckMaxIssues < 0 {
+		errors = append(errors, "tools.check_max_issues must not be negative")
+	}
+	if c.Tools != nil && c.Tools.MaxParallel < 0 {
+		errors = append(errors, "tools.max_parallel must not be negative")
+	}
+
+	if c.Candidates != nil {
+		if c.Candidates.Count < 0 {
+			errors = append(errors, "candidates.count must not be negative")
+		}
+		for _, temperature := range c.Candidates.Temperatures {
+			if temperature < 0 || temperature > 2 {
+				errors = append(errors, fmt.Sprintf("candidates.temperatures must be between 0 and 2, got %g", temperature))
+			}
+		}
+	}
+
+	if c.HTTP != nil {
+		if (c.HTTP.CertFile == "") != (c.HTTP.KeyFile == "") {
+			errors = append(errors, "http.cert_file and http.key_file must be set together")
+		}
+		if c.HTTP.MaxIdleConnsPerHost < 0 || c.HTTP.MaxConnsPerHost < 0 {
+			errors = append(errors, "http.max_idle_conns_per_host and http.max_conns_per_host must not be negative")
+		}
+		if c.HTTP.IdleConnTimeout != "" {
+			if _, err := time.ParseDuration(c.HTTP.IdleConnTimeout); err != nil {
+				errors = append(errors, fmt.Sprintf("http.idle_conn_timeout is invalid: %v", err))
+			}
+		}
+		if c.HTTP.Proxy != "" {
+			if u, err := url.Parse(c.HTTP.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+				errors = append(errors, fmt.Sprintf("http.proxy must be an absolute URL, got %q", c.HTTP.Proxy))
+			}
+		}
+	}
+
+	if c.OpenRouter != nil {
+		checkQuantizations := func(field string, quantizations []string) {
+			for _, q := range quantizations {
+				if !slices.Contains(openRouterQuantizations, q) {
+					errors = append(errors, fmt.Sprintf("%s contains unknown value %q (valid: %s)", field, q, strings.Join(openRouterQuantizations, ", ")))
+				}
+			}
+		}
+		checkQuantizations("openrouter.quantizations", c.OpenRouter.Quantizations)
+		for _, model := range slices.Sorted(maps.Keys(c.OpenRouter.Models)) {
+			checkQuantizations(fmt.Sprintf("openrouter.models.%q.quantizations", model), c.OpenRouter.Models[model].Quantizations)
+		}
+	}
+
+	if c.PromptCache != nil && c.PromptCache.Style != "" && !slices.Contains(promptCacheStyles, c.PromptCache.Style) {
+		errors = append(errors, fmt.Sprintf("prompt_cache.style must be one of %s, got %q", strings.Join(promptCacheStyles, ", "), c.PromptCache.Style))
+	}
+
+	if c.RateLimit != nil {
+		limits := []RateLimit{c.RateLimit.RateLimit}
+		for _, host := range slices.Sorted(maps.Keys(c.RateLimit.Providers)) {
+			limits = append(limits, c.RateLimit.Providers[host])
+		}
+		for _, limit := range limits {
+			if limit.RequestsPerMinute < 0 || limit.TokensPerMinute < 0 {
+				errors = append(errors, "rate_limit values must not be negative")
+				break
+			}
+		}
+	}
+
+	if c.History != nil && c.History.Keep != nil && *c.History.Keep < 0 {
+		errors = append(errors, "history.keep must not be negative")
+	}
+
+	if c.Hooks != nil {
+		for _, name := range c.Hooks.SkipEnv {
+			if !envNamePattern.MatchString(name) {
+				errors = append(errors, fmt.Sprintf("hooks.skip_env: %q is not an environment variable name", name))
+			}
+		}
+	}
+
+	if c.Pricing != nil {
+		prices := []Price{c.Pricing.Price}
+		for _, model := range slices.Sorted(maps.Keys(c.Pricing.Models)) {
+			prices = append(prices, c.Pricing.Models[model])
+		}
+		for _, price := range prices {
+			if price.Input < 0 || price.Output < 0 {
+				errors = append(errors, "pricing values must not be negative")
+				break
+			}
+		}
+	}
+
+	phaseNames := make(map[string]bool)
+	for i, p := range c.Phases {
+		field := fmt.Sprintf("phases[%d]", i)
+		if p.Name == "" {
+			errors = append(errors, field+".name is required")
+		} else if phaseNames[p.Name] {
+			errors = append(errors, fmt.Sprintf("%s.name %q is used by another phase", field, p.Name))
+		}
+		phaseNames[p.Name] = true
+		if (p.SystemPrompt == "") == (p.SystemPromptFile == "") {
+			errors = append(errors, field+" must set exactly one of system_prompt and system_prompt_file")
+		}
+		if p.ResultSchema != "" && p.ResultSchemaFile != "" {
+			errors = append(errors, field+" must not set both result_schema and result_schema_file")
+		}
+		for _, tool := range p.Tools {
+			if !slices.Contains(phase.CustomPhaseTools, tool) {
+				errors = append(errors, fmt.Sprintf("%s.tools contains unknown tool %q (valid: %s)", field, tool, strings.Join(phase.CustomPhaseTools, ", ")))
+			}
+			if tool == "run_snippet" && (c.Tools == nil || !c.Tools.RunSnippet) {
+				errors = append(errors, fmt.Sprintf("%s.tools contains run_snippet, which requires tools.run_snippet = true", field))
+			}
+		}
+		if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+			errors = append(errors, fmt.Sprintf("%s.temperature must be between 0 and 2, got %g", field, *p.Temperature))
+		}
+		if p.MaxRounds < 0 {
+			errors = append(errors, fmt.Sprintf("%s.max_rounds must not be negative, got %d", field, p.MaxRounds))
+		}
+	}
+	if c.Transcript != nil && c.Transcript.KeepRounds < 0 {
+		errors = append(errors, fmt.Sprintf("transcript.keep_rounds must not be negative, got %d", c.Transcript.KeepRounds))
+	}
+	if r := c.Rounds; r != nil && (r.ContextGathering < 0 || r.Implementation < 0 || r.Review < 0 || r.Tests < 0) {
+		errors = append(errors, "rounds values must not be negative")
+	}
+
+	for name := range c.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			errors = append(errors, fmt.Sprintf("headers contains invalid header name %q", name))
+		}
+	}
+	if c.Auth != nil {
+		if strings.ContainsAny(c.Auth.Header, " :\r\n") {
+			errors = append(errors, fmt.Sprintf("auth.header is not a valid header name: %q", c.Auth.Header))
+		}
+		if c.Auth.RefreshInterval != "" {
+			if c.Auth.TokenCmd == "" {
+				errors = append(errors, "auth.refresh_interval requires auth.token_cmd")
+			} else if _, err := time.ParseDuration(c.Auth.RefreshInterval); err != nil {
+				errors = append(errors, fmt.Sprintf("auth.refresh_interval is invalid: %v", err))
+			}
+		}
+	}
+
+	if c.GoVersion != "" && !version.IsValid("go"+c.TargetGoVersion()) {
+		errors = append(errors, fmt.Sprintf("go_version %q is not a Go release such as \"1.21\"", c.GoVersion))
+	}
+
+	if c.Build != nil {
+		for _, flag := range c.Build.Flags {
+			if !strings.HasPrefix(flag, "-") {
+				errors = append(errors, fmt.Sprintf("build.flags must be flags such as \"-mod=vendor\", got %q", flag))
+			}
+		}
+		for name := range c.Build.Env {
+			if name == "" || strings.ContainsAny(name, "= ") {
+				errors = append(errors, fmt.Sprintf("build.env contains invalid variable name %q", name))
+			}
+		}
+	}
+
+	if c.Fallback != nil {
+		if _, err := c.Fallback.Templates(); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	if c.Fast != nil && c.Fast.MaxWords < 0 {
+		errors = append(errors, "fast.max_words must not be negative")
+	}
+
+	if c.Context != nil {
+		if c.Context.MaxDepth != nil && *c.Context.MaxDepth < 0 {
+			errors = append(errors, "context.max_depth must not be negative")
+		}
+		if c.Context.MaxPerLevel < 0 {
+			errors = append(errors, "context.max_per_level must not be negative")
+		}
+		if c.Context.MaxBytes < 0 {
+			errors = append(errors, "context.max_bytes must not be negative")
+		}
+		if c.Context.DocLines != nil && *c.Context.DocLines < 0 {
+			errors = append(errors, "context.doc_lines must not be negative")
+		}
+	}
 
 	// Check for unexpanded environment variables
 	if strings.Contains(c.APIKey, "${") {
@@ -166,11 +985,52 @@ func normalizePath(path, configDir string) string {
 	return filepath.Join(configDir, path)
 }
 
-// GetPackageName returns the package name based on the destination directory
+// GetPackageName returns the configured package name, or one based on the destination directory
 func (c *Config) GetPackageName() string {
+	if c.PackageName != "" {
+		return c.PackageName
+	}
 	return filepath.Base(c.Dest)
 }
 
+// TargetGoVersion returns the Go release generated code must compile with, without the
+// "go" prefix ("1.21"), or an empty string if any release is fine
+func (c *Config) TargetGoVersion() string {
+	return strings.TrimPrefix(c.GoVersion, "go")
+}
+
+// BuildEnv returns the build configuration packages are loaded with, or nil if none is set
+func (c *Config) BuildEnv() *buildenv.Config {
+	if c.Build == nil {
+		return nil
+	}
+	env := make(map[string]string, len(c.Build.Env))
+	for name, value := range c.Build.Env {
+		env[name] = expandEnvVars(value)
+	}
+	return &buildenv.Config{Flags: c.Build.Flags, Env: env}
+}
+
+// Templates parses the fallback bodies. Returns nil when no body is configured
+func (f *FallbackConfig) Templates() (*codegen.Fallback, error) {
+	if f == nil || (f.Body == "" && f.ErrorBody == "") {
+		return nil, nil
+	}
+	fallback := &codegen.Fallback{}
+	var err error
+	if f.Body != "" {
+		if fallback.Body, err = codegen.ParseFallback("body", f.Body); err != nil {
+			return nil, fmt.Errorf("fallback.body is not a valid template: %w", err)
+		}
+	}
+	if f.ErrorBody != "" {
+		if fallback.ErrorBody, err = codegen.ParseFallback("error_body", f.ErrorBody); err != nil {
+			return nil, fmt.Errorf("fallback.error_body is not a valid template: %w", err)
+		}
+	}
+	return fallback, nil
+}
+
 // GetAPIKey returns the API key with environment variables expanded
 func (c *Config) GetAPIKey() string {
 	if c.APIKey == "" {
@@ -180,3 +1040,97 @@ func (c *Config) GetAPIKey() string {
 	// Expand environment variables
 	return expandEnvVars(c.APIKey)
 }
+
+// ContextLimits returns the bounds of referenced type expansion in target contexts
+func (c *Config) ContextLimits() pkgcontext.ExpansionLimits {
+	limits := pkgcontext.DefaultExpansionLimits
+	if c.Context == nil {
+		return limits
+	}
+	if c.Context.MaxDepth != nil {
+		limits.Depth = *c.Context.MaxDepth
+	}
+	limits.PerLevel = c.Context.MaxPerLevel
+	limits.MaxBytes = c.Context.MaxBytes
+	return limits
+}
+
+// ContextDocLines returns the lines kept of each doc comment in target contexts
+func (c *Config) ContextDocLines() int {
+	if c.Context == nil || c.Context.DocLines == nil {
+		return pkgcontext.DefaultDocLines
+	}
+	return *c.Context.DocLines
+}
+
+// ContextCache opens the context gathering cache of the package in pkgDir, kept next to the
+// project config like history. Returns nil when caching is disabled
+func (c *Config) ContextCache(pkgDir string) *gathercache.Cache {
+	if c.Context != nil && c.Context.Cache != nil && !*c.Context.Cache {
+		return nil
+	}
+	absPkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		absPkgDir = pkgDir
+	}
+	root := c.projectRoot(absPkgDir)
+	pkg, err := filepath.Rel(root, absPkgDir)
+	if err != nil || strings.HasPrefix(pkg, "..") {
+		pkg = filepath.Base(absPkgDir)
+	}
+	return gathercache.Open(normalizePath(gathercache.DefaultDir, root), filepath.ToSlash(pkg))
+}
+
+// HistoryStore opens the history of the package in pkgDir. Packages are kept apart by
+// their path relative to the project config. Returns nil when history is disabled
+func (c *Config) HistoryStore(pkgDir string) *history.Store {
+	keep := history.DefaultKeep
+	if c.History != nil && c.History.Keep != nil {
+		keep = *c.History.Keep
+	}
+
+	absPkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		absPkgDir = pkgDir
+	}
+	root := c.projectRoot(absPkgDir)
+	dir := normalizePath(history.DefaultDir, root)
+	if c.History != nil && c.History.Dir != "" {
+		dir = c.History.Dir
+	}
+
+	pkg, err := filepath.Rel(root, absPkgDir)
+	if err != nil || strings.HasPrefix(pkg, "..") {
+		pkg = filepath.Base(absPkgDir)
+	}
+	return history.Open(dir, filepath.ToSlash(pkg), keep)
+}
+
+// HookSkipEnv returns the environment variables that skip installed git hooks when set
+func (c *Config) HookSkipEnv() []string {
+	if c.Hooks == nil || c.Hooks.SkipEnv == nil {
+		return []string{"CI"}
+	}
+	return c.Hooks.SkipEnv
+}
+
+// StatsFile returns the file statistics of runs are kept in, whether or not recording is
+// enabled
+func (c *Config) StatsFile(pkgDir string) string {
+	if c.Stats != nil && c.Stats.File != "" {
+		return c.Stats.File
+	}
+	absPkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		absPkgDir = pkgDir
+	}
+	return normalizePath(stats.DefaultFile, c.projectRoot(absPkgDir))
+}
+
+// projectRoot returns the directory of the outermost config file, or pkgDir if none was found
+func (c *Config) projectRoot(pkgDir string) string {
+	if len(c.files) > 0 {
+		return filepath.Dir(c.files[0])
+	}
+	return pkgDir
+}
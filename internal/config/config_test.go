@@ -0,0 +1,430 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/history"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mantra.toml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+// writeGoMod marks dir as the project root, up to which package configs are merged
+func writeGoMod(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/project\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+}
+
+func TestLoadMergesPackageConfig(t *testing.T) {
+	outside := t.TempDir()
+	root := filepath.Join(outside, "project")
+	pkgDir := filepath.Join(root, "services", "billing")
+
+	// Configs above the project root are not merged
+	writeConfig(t, outside, `api_key = "outside"
+
+[review]
+enabled = false
+`)
+	writeConfig(t, root, `model = "qwen2.5-coder:32b"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+forbidden_imports = ["unsafe"]
+
+[review]
+enabled = true
+
+[http]
+proxy = "http://proxy.example:3128"
+`)
+	writeConfig(t, pkgDir, `model = "gpt-4"
+dest = "./impl"
+package_name = "billingimpl"
+forbidden_imports = ["reflect"]
+
+[http]
+compress_requests = true
+`)
+	writeGoMod(t, root)
+	conventionsDir := filepath.Join(pkgDir, ".mantra")
+	if err := os.MkdirAll(conventionsDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(conventionsDir, "conventions.toml"), []byte("[errors]\nrequire_wrap = false\n"), 0644); err != nil {
+		t.Fatalf("Failed to write conventions: %v", err)
+	}
+
+	cfg, err := Load(pkgDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Model != "gpt-4" || cfg.URL != "http://localhost:11434/v1" {
+		t.Errorf("Expected package model and project url, got %q and %q", cfg.Model, cfg.URL)
+	}
+	if want := filepath.Join(pkgDir, "impl"); cfg.Dest != want {
+		t.Errorf("Expected dest %q relative to the package config, got %q", want, cfg.Dest)
+	}
+	if cfg.GetPackageName() != "billingimpl" {
+		t.Errorf("Expected package name override, got %q", cfg.GetPackageName())
+	}
+	if len(cfg.ForbiddenImports) != 1 || cfg.ForbiddenImports[0] != "reflect" {
+		t.Errorf("Expected package forbidden_imports to replace the project's, got %v", cfg.ForbiddenImports)
+	}
+	if cfg.Review == nil || !cfg.Review.Enabled {
+		t.Error("Expected review settings to be inherited")
+	}
+	if cfg.APIKey != "" {
+		t.Errorf("Expected no api_key from outside the project, got %q", cfg.APIKey)
+	}
+	if cfg.HTTP == nil || cfg.HTTP.Proxy != "http://proxy.example:3128" || !cfg.HTTP.CompressRequests {
+		t.Errorf("Expected http tables to be merged, got %+v", cfg.HTTP)
+	}
+	if want := filepath.Join(conventionsDir, "conventions.toml"); cfg.ConventionsFile != want {
+		t.Errorf("Expected package conventions %q, got %q", want, cfg.ConventionsFile)
+	}
+	if cfg.Conventions.Errors.RequireWrap {
+		t.Error("Expected package conventions to be loaded")
+	}
+
+	// Other packages only see the project config
+	cfg, err = Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Model != "qwen2.5-coder:32b" || cfg.GetPackageName() != "generated" {
+		t.Errorf("Expected project settings, got model %q and package %q", cfg.Model, cfg.GetPackageName())
+	}
+}
+
+func TestSettingsSources(t *testing.T) {
+	root := t.TempDir()
+	writeGoMod(t, root)
+	pkgDir := filepath.Join(root, "pkg")
+	t.Setenv("MANTRA_TEST_KEY", "secret")
+
+	writeConfig(t, root, `model = "qwen2.5-coder:32b"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+api_key = "${MANTRA_TEST_KEY}"
+
+[headers]
+Authorization = "Bearer ${MANTRA_TEST_KEY}"
+X-Api-Token = "literal-token"
+
+[[phases]]
+name = "Security Review"
+system_prompt = "Review"
+`)
+	writeConfig(t, pkgDir, `model = "gpt-4"
+`)
+
+	cfg, err := Load(pkgDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cfg.OverrideLogLevel("debug")
+
+	settings := make(map[string]Setting)
+	for _, s := range cfg.Settings() {
+		settings[s.Key] = s
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		source string
+		origin string
+	}{
+		{"model", `"gpt-4"`, SourceFile, filepath.Join(pkgDir, "mantra.toml")},
+		{"url", `"http://localhost:11434/v1"`, SourceFile, filepath.Join(root, "mantra.toml")},
+		{"api_key", `"${MANTRA_TEST_KEY}"`, SourceEnv, "MANTRA_TEST_KEY"},
+		{"headers.Authorization", `"Bearer ${MANTRA_TEST_KEY}"`, SourceEnv, "MANTRA_TEST_KEY"},
+		{"headers.X-Api-Token", `"********"`, SourceFile, filepath.Join(root, "mantra.toml")},
+		{"log_level", `"debug"`, SourceFlag, "--log-level"},
+		{"package_name", `"generated"`, SourceDefault, ""},
+		{"phases[0].name", `"Security Review"`, SourceFile, filepath.Join(root, "mantra.toml")},
+		{"phases[0].required", "false", SourceFile, filepath.Join(root, "mantra.toml")},
+	}
+	for _, tt := range tests {
+		got, ok := settings[tt.key]
+		if !ok {
+			t.Errorf("Expected setting %s", tt.key)
+			continue
+		}
+		if got.Value != tt.value || got.Source != tt.source || got.Origin != tt.origin {
+			t.Errorf("Expected %s = %s from %s (%s), got %s from %s (%s)", tt.key, tt.value, tt.source, tt.origin, got.Value, got.Source, got.Origin)
+		}
+	}
+	if len(cfg.Files()) != 2 {
+		t.Errorf("Expected 2 config files, got %v", cfg.Files())
+	}
+}
+
+func TestValidateInvariants(t *testing.T) {
+	root := t.TempDir()
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "localhost:11434"
+dest = "./generated"
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "url must be an absolute URL") {
+		t.Errorf("Expected url error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[pricing]
+input = 0.15
+output = 0.60
+
+[pricing.models."gpt-4"]
+input = -1.0
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "pricing values must not be negative") {
+		t.Errorf("Expected pricing error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[history]
+keep = -1
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "history.keep must not be negative") {
+		t.Errorf("Expected history error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[rounds]
+implementation = -5
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "rounds values must not be negative") {
+		t.Errorf("Expected rounds error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[transcript]
+keep_rounds = -1
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "transcript.keep_rounds must not be negative") {
+		t.Errorf("Expected transcript error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[tools]
+max_parallel = -2
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "tools.max_parallel must not be negative") {
+		t.Errorf("Expected tools error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[[phases]]
+name = "Probe"
+system_prompt = "Run the code"
+tools = ["run_snippet"]
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "requires tools.run_snippet") {
+		t.Errorf("Expected run_snippet without the opt-in to be rejected, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[hooks]
+skip_env = ["CI", "$(reboot)"]
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "hooks.skip_env") {
+		t.Errorf("Expected hooks error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+go_version = "1.x"
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "go_version") {
+		t.Errorf("Expected go_version error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[build]
+flags = ["mod=vendor"]
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "build.flags") {
+		t.Errorf("Expected build.flags error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[fallback]
+error_body = "return {{.Zero}ErrNotGenerated"
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "fallback.error_body") {
+		t.Errorf("Expected fallback.error_body error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "m"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+[prompt_cache]
+enabled = true
+style = "gemini"
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "prompt_cache.style") {
+		t.Errorf("Expected prompt_cache.style error, got %v", err)
+	}
+
+	writeConfig(t, root, `url = "http://localhost:11434/v1"
+dest = "."
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "model is required") {
+		t.Errorf("Expected model error, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "."
+`)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := cfg.CheckPackage(root); err == nil {
+		t.Error("Expected the source package as dest to be rejected")
+	}
+	if err := cfg.CheckPackage(filepath.Join(root, "other")); err != nil {
+		t.Errorf("Expected other packages to be accepted, got %v", err)
+	}
+}
+
+func TestResolveAPIKey(t *testing.T) {
+	root := t.TempDir()
+	writeGoMod(t, root)
+	pkgDir := filepath.Join(root, "pkg")
+	t.Setenv("MANTRA_TEST_KEY", "from-env")
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+api_key = "${MANTRA_TEST_KEY}"
+`)
+	writeConfig(t, pkgDir, `api_key_cmd = "printf '  from-cmd\n'"
+`)
+
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if key, err := cfg.ResolveAPIKey(context.Background()); err != nil || key != "from-env" {
+		t.Errorf("Expected key from env, got %q (%v)", key, err)
+	}
+
+	// The package config replaces the inherited key source
+	cfg, err = Load(pkgDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if key, err := cfg.ResolveAPIKey(context.Background()); err != nil || key != "from-cmd" {
+		t.Errorf("Expected key from command, got %q (%v)", key, err)
+	}
+
+	cfg.APIKeyCmd = "exit 3"
+	if _, err := cfg.ResolveAPIKey(context.Background()); err == nil {
+		t.Error("Expected failing command to be reported")
+	}
+
+	// Cancelling the run is not reported as a timeout
+	cfg.APIKeyCmd = "sleep 5"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cfg.ResolveAPIKey(ctx); !errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected the cancellation to be reported, got %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+api_key = "plain-key"
+api_key_cmd = "echo key"
+`)
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "only one of api_key") {
+		t.Errorf("Expected conflicting key sources to be rejected, got %v", err)
+	}
+}
+
+func TestHistoryStore(t *testing.T) {
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "billing")
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+`)
+	if err := os.MkdirAll(pkgDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	cfg, err := Load(pkgDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	store := cfg.HistoryStore(pkgDir)
+	if err := store.Record(&parser.Target{Name: "Double"}, history.Entry{Body: "return n * 2"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	// Packages are kept apart by their path below the project config
+	if _, err := os.Stat(filepath.Join(root, ".mantra", "history", "billing", "Double.json")); err != nil {
+		t.Errorf("Expected history next to the project config: %v", err)
+	}
+
+	writeConfig(t, root, `model = "gpt-4"
+url = "http://localhost:11434/v1"
+dest = "./generated"
+
+[history]
+keep = 0
+`)
+	if cfg, err = Load(pkgDir); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.HistoryStore(pkgDir) != nil {
+		t.Error("Expected keep = 0 to disable history")
+	}
+}
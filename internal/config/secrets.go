@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// apiKeyCommandTimeout bounds api_key_cmd and keychain lookups, which may prompt for unlock
+const apiKeyCommandTimeout = 30 * time.Second
+
+// KeychainConfig identifies an API key stored in the platform keychain
+// (macOS Keychain via security, or the Secret Service via secret-tool on Linux)
+type KeychainConfig struct {
+	Service string `toml:"service"` // Service name of the stored secret
+	Account string `toml:"account"` // Account name (optional)
+}
+
+// ResolveAPIKey returns the API key from api_key (with environment variables expanded),
+// the output of api_key_cmd, or the platform keychain, whichever is configured
+func (c *Config) ResolveAPIKey(ctx context.Context) (string, error) {
+	switch {
+	case c.APIKeyCmd != "":
+		key, err := runSecretCommand(ctx, "sh", "-c", c.APIKeyCmd)
+		if err != nil {
+			return "", fmt.Errorf("api_key_cmd failed: %w", err)
+		}
+		return key, nil
+	case c.APIKeyKeychain != nil:
+		name, args, err := keychainCommand(c.APIKeyKeychain)
+		if err != nil {
+			return "", err
+		}
+		key, err := runSecretCommand(ctx, name, args...)
+		if err != nil {
+			return "", fmt.Errorf("failed to read api key from keychain service %q: %w", c.APIKeyKeychain.Service, err)
+		}
+		return key, nil
+	default:
+		return c.GetAPIKey(), nil
+	}
+}
+
+// keychainCommand returns the platform command that prints the secret
+func keychainCommand(kc *KeychainConfig) (string, []string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		args := []string{"find-generic-password", "-s", kc.Service, "-w"}
+		if kc.Account != "" {
+			args = append(args, "-a", kc.Account)
+		}
+		return "security", args, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		args := []string{"lookup", "service", kc.Service}
+		if kc.Account != "" {
+			args = append(args, "account", kc.Account)
+		}
+		return "secret-tool", args, nil
+	default:
+		return "", nil, fmt.Errorf("api_key_keychain is not supported on %s; use api_key_cmd instead", runtime.GOOS)
+	}
+}
+
+// runSecretCommand runs a command and returns its trimmed output. Stderr is passed through
+// so password managers can prompt; the secret itself is never logged
+func runSecretCommand(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiKeyCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("timed out after %s", apiKeyCommandTimeout)
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return "", fmt.Errorf("command printed no key")
+	}
+	return key, nil
+}
+
+// RequestHeaders returns the configured extra headers with environment variables expanded
+func (c *Config) RequestHeaders() map[string]string {
+	if len(c.Headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(c.Headers))
+	for name, value := range c.Headers {
+		headers[name] = expandEnvVars(value)
+	}
+	return headers
+}
+
+// AuthScheme returns the prefix sent before the credential
+func (a *AuthConfig) AuthScheme() string {
+	if a.Scheme != nil {
+		return *a.Scheme
+	}
+	if a.Header == "" || strings.EqualFold(a.Header, "Authorization") {
+		return "Bearer"
+	}
+	return ""
+}
+
+// FetchToken runs token_cmd and returns the credential it prints
+func (a *AuthConfig) FetchToken(ctx context.Context) (string, error) {
+	token, err := runSecretCommand(ctx, "sh", "-c", a.TokenCmd)
+	if err != nil {
+		return "", fmt.Errorf("auth.token_cmd failed: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Setting sources, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Setting is one resolved configuration value and where it came from
+type Setting struct {
+	Key    string // Dotted TOML key, e.g. "http.proxy" or "phases[0].name"
+	Value  string // TOML representation of the value
+	Source string // One of the Source constants
+	Origin string // Config file for SourceFile, variable names for SourceEnv, flag name for SourceFlag
+}
+
+// settingDefaults are shown for keys whose empty value means a built-in default
+var settingDefaults = map[string]string{
+	"log_level": `"info"`,
+}
+
+var (
+	envVarPattern  = regexp.MustCompile(`\$\{([^}]+)\}`) // ${VAR_NAME} references
+	bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Files returns the config files the configuration was merged from, project config first
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// OverrideLogLevel applies the --log-level flag and records it as the source of log_level
+func (c *Config) OverrideLogLevel(level string) {
+	if level == "" {
+		return
+	}
+	c.LogLevel = level
+	c.recordSource("log_level", SourceFlag+":--log-level")
+}
+
+// recordSource notes where a key was last set; origin is "file:<path>" or "flag:<name>"
+func (c *Config) recordSource(key, origin string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[key] = origin
+}
+
+// recordFileSources notes the keys a config file defines
+func (c *Config) recordFileSources(md toml.MetaData, configPath string) {
+	for _, key := range md.Keys() {
+		c.recordSource(key.String(), SourceFile+":"+configPath)
+	}
+}
+
+// Settings returns every resolved setting in declaration order. API keys and header values
+// are masked unless they are environment variable references
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	flattenSettings("", reflect.ValueOf(c).Elem(), &settings)
+
+	for i := range settings {
+		s := &settings[i]
+		s.Source, s.Origin = c.sourceOf(s.Key)
+
+		switch s.Key {
+		case "package_name":
+			if c.PackageName == "" {
+				s.Value = fmt.Sprintf("%q", c.GetPackageName())
+			}
+		case "api_key":
+			maskSecret(s, c.APIKey)
+		default:
+			// Header values often carry tokens
+			if strings.HasPrefix(s.Key, "headers.") {
+				if value, err := strconv.Unquote(s.Value); err == nil {
+					maskSecret(s, value)
+				}
+			}
+		}
+		if s.Source == SourceDefault {
+			if value, ok := settingDefaults[s.Key]; ok && s.Value == `""` {
+				s.Value = value
+			}
+		}
+	}
+	return settings
+}
+
+// maskSecret masks the value of a secret setting unless it is read from environment
+// variables, which are then reported as its source
+func maskSecret(s *Setting, value string) {
+	if refs := envVarPattern.FindAllStringSubmatch(value, -1); len(refs) > 0 {
+		names := make([]string, len(refs))
+		for i, ref := range refs {
+			names[i] = ref[1]
+		}
+		s.Source, s.Origin = SourceEnv, strings.Join(names, ", ")
+	} else if value != "" {
+		s.Value = `"********"`
+	}
+}
+
+// sourceOf returns the source of a flattened key. Keys inside arrays of tables take the
+// source of the array, which is replaced as a whole by the last file that sets it
+func (c *Config) sourceOf(key string) (string, string) {
+	if i := strings.Index(key, "["); i >= 0 {
+		key = key[:i]
+	}
+	origin, ok := c.sources[key]
+	if !ok {
+		return SourceDefault, ""
+	}
+	source, detail, _ := strings.Cut(origin, ":")
+	return source, detail
+}
+
+// flattenSettings appends the leaf values of v under prefix, following toml struct tags
+func flattenSettings(prefix string, v reflect.Value, settings *[]Setting) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			flattenSettings(prefix, v.Elem(), settings)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Anonymous {
+				flattenSettings(prefix, v.Field(i), settings)
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			flattenSettings(joinKey(prefix, name), v.Field(i), settings)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, k := range keys {
+			flattenSettings(joinKey(prefix, k.String()), v.MapIndex(k), settings)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				flattenSettings(fmt.Sprintf("%s[%d]", prefix, i), v.Index(i), settings)
+			}
+			return
+		}
+		*settings = append(*settings, Setting{Key: prefix, Value: formatValue(v)})
+	default:
+		*settings = append(*settings, Setting{Key: prefix, Value: formatValue(v)})
+	}
+}
+
+// joinKey appends a key part, quoting it when it is not a bare TOML key
+func joinKey(prefix, name string) string {
+	if !bareKeyPattern.MatchString(name) {
+		name = fmt.Sprintf("%q", name)
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// formatValue renders a scalar or a slice of scalars in TOML syntax
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// CheckPackage validates the configuration against the package being generated.
+// Generated files are written to dest, so dest must not be the source package itself
+func (c *Config) CheckPackage(pkgDir string) error {
+	absPkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	absDest, err := filepath.Abs(c.Dest)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if sameDir(absPkgDir, absDest) {
+		return fmt.Errorf("invalid configuration: dest %s is the source package directory; generated files would overwrite the sources", c.Dest)
+	}
+	return nil
+}
+
+// sameDir reports whether two absolute paths name the same directory
+func sameDir(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
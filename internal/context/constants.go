@@ -0,0 +1,91 @@
+package context
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ConstBlock is a package-level const declaration with its members in declaration order.
+// Enum groups built with iota are kept whole, so every value of the enum is known
+type ConstBlock struct {
+	Names      []string        // Member names in declaration order
+	Types      map[string]bool // Names of the package types of the members
+	Definition string          // The block with the type and value of each member
+}
+
+// constBlocks returns the package-level const declarations of pkg in source order
+func (l *PackageLoader) constBlocks(pkg *packages.Package) []*ConstBlock {
+	if pkg == nil || pkg.TypesInfo == nil {
+		return nil
+	}
+
+	var blocks []*ConstBlock
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.CONST {
+				continue
+			}
+			if block := l.newConstBlock(pkg, genDecl); block != nil {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+	return blocks
+}
+
+// constBlockOf returns the const declaration of pkg that declares name, or nil
+func (l *PackageLoader) constBlockOf(pkg *packages.Package, name string) *ConstBlock {
+	for _, block := range l.constBlocks(pkg) {
+		for _, member := range block.Names {
+			if member == name {
+				return block
+			}
+		}
+	}
+	return nil
+}
+
+// newConstBlock describes a const declaration. Members are written with their resolved type
+// and value, as iota and implicit repetition are hard to read out of context
+func (l *PackageLoader) newConstBlock(pkg *packages.Package, decl *ast.GenDecl) *ConstBlock {
+	block := &ConstBlock{Types: make(map[string]bool)}
+
+	var lines []string
+	for _, spec := range decl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for _, ident := range valueSpec.Names {
+			obj, ok := pkg.TypesInfo.Defs[ident].(*types.Const)
+			if !ok || ident.Name == "_" {
+				continue
+			}
+			block.Names = append(block.Names, ident.Name)
+
+			line := ident.Name
+			if basic, ok := obj.Type().(*types.Basic); !ok || basic.Info()&types.IsUntyped == 0 {
+				line += " " + l.typeString(obj.Type())
+			}
+			if named, ok := types.Unalias(obj.Type()).(*types.Named); ok && named.Obj().Pkg() == pkg.Types {
+				block.Types[named.Obj().Name()] = true
+			}
+			lines = append(lines, line+" = "+obj.Val().String())
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if len(lines) == 1 && !decl.Lparen.IsValid() {
+		block.Definition = "const " + lines[0]
+	} else {
+		block.Definition = "const (\n    " + strings.Join(lines, "\n    ") + "\n)"
+	}
+	return block
+}
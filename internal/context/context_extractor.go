@@ -1,8 +1,11 @@
 package context
 
 import (
+	"context"
 	"fmt"
+	"go/types"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/rail44/mantra/internal/analysis"
@@ -10,88 +13,168 @@ import (
 
 // GetContextForTarget extracts context for a specific target using go/packages
 func (l *PackageLoader) GetContextForTarget(targetPath string, directlyUsedTypes map[string]bool, targetMethodName string) (*RelevantContext, error) {
-	if err := l.Load(); err != nil {
+	fc, err := l.newFileContext(context.Background(), targetPath)
+	if err != nil {
 		return nil, err
 	}
+	return fc.selectContext(directlyUsedTypes, targetMethodName), nil
+}
 
-	ctx := &RelevantContext{
-		Types:       make(map[string]string),
-		Methods:     make(map[string][]analysis.MethodInfo),
-		PackageName: l.pkg.Name,
-	}
-
-	// Extract imports from the target file first (needed for type simplification)
-	var targetImports []*ImportInfo
-	if len(l.pkg.Syntax) > 0 {
-		// Find the file matching targetPath
-		for _, file := range l.pkg.Syntax {
-			pos := l.pkg.Fset.Position(file.Pos())
-			if filepath.Base(pos.Filename) == filepath.Base(targetPath) {
-				targetImports = ExtractImportInfo(file)
-				ctx.Imports = targetImports
-				break
-			}
-		}
+// newFileContext loads the package and extracts the context shared by every target in targetPath
+func (l *PackageLoader) newFileContext(ctx context.Context, targetPath string) (*FileContext, error) {
+	if err := l.LoadContext(ctx); err != nil {
+		return nil, err
 	}
 
-	// Store imports for use in type simplification
-	l.targetImports = targetImports
+	fc := &FileContext{
+		loader:      l,
+		definitions: make(map[string]string),
+		limits:      DefaultExpansionLimits,
+		docLines:    DefaultDocLines,
+	}
+
+	// Extract imports from the target file first; types are qualified as the file refers to them
+	l.importNames = nil
+	for _, file := range l.pkg.Syntax {
+		pos := l.pkg.Fset.Position(file.Pos())
+		if filepath.Base(pos.Filename) == filepath.Base(targetPath) {
+			fc.imports = ExtractImportInfo(file)
+			l.importNames = resolveImports(fc.imports, file, l.pkg.TypesInfo)
+			break
+		}
+	}
 
 	// Get all types in the package
 	allTypes, err := l.GetAllTypes()
 	if err != nil {
 		return nil, err
 	}
+	fc.types = allTypes
+	for name, typeInfo := range allTypes {
+		fc.definitions[name] = l.buildCompleteTypeDefinition(typeInfo)
+	}
+	fc.constants = l.constBlocks(l.pkg)
+	fc.typeDocs, fc.methodDocs = l.typeDocs()
+
+	return fc, nil
+}
 
-	// Add directly used types
-	for typeName := range directlyUsedTypes {
-		if typeInfo, exists := allTypes[typeName]; exists {
-			ctx.Types[typeName] = l.buildCompleteTypeDefinition(typeInfo)
-			if len(typeInfo.Methods) > 0 {
-				// Filter out the method being implemented to avoid recursive calls
-				var filteredMethods []analysis.MethodInfo
-				for _, method := range typeInfo.Methods {
-					if method.Name != targetMethodName {
-						filteredMethods = append(filteredMethods, method)
+// selectContext picks the types and methods relevant to a target from the shared file context
+func (fc *FileContext) selectContext(directlyUsedTypes map[string]bool, targetMethodName string) *RelevantContext {
+	ctx := &RelevantContext{
+		Imports:     fc.imports,
+		Types:       make(map[string]string),
+		Methods:     make(map[string][]analysis.MethodInfo),
+		TypeDocs:    make(map[string]string),
+		PackageName: fc.loader.pkg.Name,
+	}
+
+	// Add directly used types; they are always included, in name order so the byte budget
+	// leaves the same room on every run
+	var frontier []string
+	size := 0
+	for _, typeName := range sortedKeys(directlyUsedTypes) {
+		typeInfo, exists := fc.types[typeName]
+		if !exists {
+			continue
+		}
+		ctx.Types[typeName] = fc.definitions[typeName]
+		size += len(ctx.Types[typeName])
+		frontier = append(frontier, typeName)
+		if doc := trimDoc(fc.typeDocs[typeName], fc.docLines); doc != "" {
+			ctx.TypeDocs[typeName] = doc
+		}
+		if len(typeInfo.Methods) > 0 {
+			// Filter out the method being implemented to avoid recursive calls
+			var filteredMethods []analysis.MethodInfo
+			for _, method := range typeInfo.Methods {
+				if method.Name != targetMethodName {
+					if method.Promoted == "" {
+						method.Doc = trimDoc(fc.methodDocs[typeName][method.Name], fc.docLines)
 					}
-				}
-				if len(filteredMethods) > 0 {
-					ctx.Methods[typeName] = filteredMethods
+					filteredMethods = append(filteredMethods, method)
 				}
 			}
+			if len(filteredMethods) > 0 {
+				ctx.Methods[typeName] = filteredMethods
+			}
 		}
 	}
 
-	// Recursively add referenced types (up to 3 levels)
-	for i := 0; i < 3; i++ {
-		initialCount := len(ctx.Types)
-		typesToCheck := make(map[string]string)
-		for name, def := range ctx.Types {
-			typesToCheck[name] = def
+	// Add referenced types level by level within the expansion limits. Types referenced by
+	// more types of the previous level come first, then by name
+	limits := fc.limits
+	for level := 0; level < limits.Depth && len(frontier) > 0; level++ {
+		references := make(map[string]int)
+		for _, name := range frontier {
+			for refType := range analysis.ExtractReferencedTypesFromDefinition(ctx.Types[name]) {
+				if _, included := ctx.Types[refType]; included {
+					continue
+				}
+				if _, exists := fc.types[refType]; exists {
+					references[refType]++
+				}
+			}
 		}
 
-		for _, typeDef := range typesToCheck {
-			referencedTypes := analysis.ExtractReferencedTypesFromDefinition(typeDef)
-			for refType := range referencedTypes {
-				if _, exists := ctx.Types[refType]; !exists {
-					if typeInfo, exists := allTypes[refType]; exists {
-						ctx.Types[refType] = l.buildCompleteTypeDefinition(typeInfo)
-						if len(typeInfo.Methods) > 0 {
-							// For referenced types, include all methods (they're not the receiver)
-							ctx.Methods[refType] = typeInfo.Methods
-						}
-					}
-				}
+		candidates := sortedKeys(references)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return references[candidates[i]] > references[candidates[j]]
+		})
+
+		frontier = nil
+		for _, refType := range candidates {
+			if limits.PerLevel > 0 && len(frontier) == limits.PerLevel {
+				break
+			}
+			definition := fc.definitions[refType]
+			if limits.MaxBytes > 0 && size+len(definition) > limits.MaxBytes {
+				continue
+			}
+			ctx.Types[refType] = definition
+			size += len(definition)
+			frontier = append(frontier, refType)
+			if methods := fc.types[refType].Methods; len(methods) > 0 {
+				// For referenced types, include all methods (they're not the receiver)
+				ctx.Methods[refType] = methods
 			}
 		}
+	}
 
-		// If no new types were added, stop recursion
-		if len(ctx.Types) == initialCount {
-			break
+	// Add whole const blocks declaring constants of the types, so enums come with every value
+	for _, block := range fc.constants {
+		for typeName := range block.Types {
+			if _, included := ctx.Types[typeName]; included {
+				ctx.Constants = append(ctx.Constants, block.Definition)
+				break
+			}
 		}
 	}
 
-	return ctx, nil
+	return ctx
+}
+
+// trimDoc returns the first lines of a doc comment, or "" when lines is 0
+func trimDoc(doc string, lines int) string {
+	doc = strings.TrimSpace(doc)
+	if doc == "" || lines <= 0 {
+		return ""
+	}
+	parts := strings.Split(doc, "\n")
+	if len(parts) > lines {
+		parts = parts[:lines]
+	}
+	return strings.Join(parts, "\n")
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // buildCompleteTypeDefinition builds a complete type definition including fields
@@ -101,9 +184,11 @@ func (l *PackageLoader) buildCompleteTypeDefinition(typeInfo *TypeInfo) string {
 		var builder strings.Builder
 		builder.WriteString(fmt.Sprintf("type %s struct {\n", typeInfo.Name))
 		for _, field := range typeInfo.Fields {
-			// Simplify the field type for readability
-			fieldType := l.simplifyFieldTypeName(field.Type)
-			builder.WriteString(fmt.Sprintf("    %s %s\n", field.Name, fieldType))
+			if field.Name == "" {
+				builder.WriteString(fmt.Sprintf("    %s\n", field.Type))
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("    %s %s\n", field.Name, field.Type))
 		}
 		builder.WriteString("}")
 		return builder.String()
@@ -123,106 +208,25 @@ func (l *PackageLoader) buildCompleteTypeDefinition(typeInfo *TypeInfo) string {
 	}
 }
 
-// simplifyFieldTypeName simplifies type names for better readability
-func (l *PackageLoader) simplifyFieldTypeName(typeName string) string {
-	// Handle map types specially
-	if strings.HasPrefix(typeName, "map[") {
-		// Find the closing bracket for the key type
-		bracketPos := strings.Index(typeName, "]")
-		if bracketPos > 0 {
-			keyPart := typeName[:bracketPos+1]   // e.g., "map[string]"
-			valueType := typeName[bracketPos+1:] // e.g., "github.com/rail44/mantra/examples/simple.cacheItem"
-
-			// Simplify the value type
-			simplifiedValue := l.simplifyTypeName(valueType)
-			return keyPart + simplifiedValue
-		}
+// qualifier qualifies types of other packages as the target file refers to them: by the name
+// it imports them under, unqualified when dot-imported, and by package name otherwise
+func (l *PackageLoader) qualifier(pkg *types.Package) string {
+	if l.pkg != nil && pkg == l.pkg.Types {
+		return ""
 	}
-
-	// Handle slice types
-	if strings.HasPrefix(typeName, "[]") {
-		elemType := typeName[2:]
-		return "[]" + l.simplifyTypeName(elemType)
-	}
-
-	// Handle pointer types
-	if strings.HasPrefix(typeName, "*") {
-		baseType := typeName[1:]
-		return "*" + l.simplifyTypeName(baseType)
+	switch name := l.importNames[pkg.Path()]; name {
+	case ".":
+		return ""
+	case "", "_":
+		return pkg.Name()
+	default:
+		return name
 	}
-
-	// For regular types, use the general simplification
-	return l.simplifyTypeName(typeName)
 }
 
-// simplifyTypeName simplifies type names based on import context
-func (l *PackageLoader) simplifyTypeName(typeName string) string {
-	if l.pkg == nil {
-		return typeName
-	}
-
-	// For types with package qualification (contains dot)
-	if strings.Contains(typeName, ".") {
-		// Find the last dot to separate package from type name
-		lastDot := strings.LastIndex(typeName, ".")
-		if lastDot > 0 {
-			pkgPath := typeName[:lastDot]
-			typeNamePart := typeName[lastDot+1:]
-
-			// Check if this is the current package
-			if pkgPath == l.pkg.PkgPath {
-				// Same package - no qualification needed
-				return typeNamePart
-			}
-
-			// Check imports to find the correct alias
-			if l.targetImports != nil {
-				for _, imp := range l.targetImports {
-					if imp.Path == pkgPath {
-						// Skip blank imports - they can't be referenced directly
-						if imp.IsBlank {
-							// Return the full path as we can't use blank imports
-							// The generated code will need to add a proper import
-							return typeName
-						}
-						// Found the import - use its identifier
-						identifier := imp.GetIdentifier()
-						return identifier + "." + typeNamePart
-					}
-				}
-			}
-
-			// If not found in imports, try to extract package name from path
-			// This handles cases where the full package path is in the type name
-			if strings.Contains(pkgPath, "/") {
-				// Extract the last segment as package name
-				segments := strings.Split(pkgPath, "/")
-				packageName := segments[len(segments)-1]
-
-				// Check if this package is imported
-				if l.targetImports != nil {
-					for _, imp := range l.targetImports {
-						if imp.Path == pkgPath || strings.HasSuffix(imp.Path, "/"+packageName) {
-							// Skip blank imports
-							if imp.IsBlank {
-								return typeName
-							}
-							identifier := imp.GetIdentifier()
-							return identifier + "." + typeNamePart
-						}
-					}
-				}
-
-				// Default to using the last segment
-				return packageName + "." + typeNamePart
-			}
-
-			// For simple package names (like "time"), keep as is
-			return typeName
-		}
-	}
-
-	return typeName
+// typeString formats t as it is written in the target file
+func (l *PackageLoader) typeString(t types.Type) string {
+	return types.TypeString(t, l.qualifier)
 }
 
 // getFunctionImplementation extracts function body from AST
@@ -0,0 +1,389 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetContextForTargetQualifiesTypesAsImported(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/client\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	source := filepath.Join(dir, "client.go")
+	if err := os.WriteFile(source, []byte(`package client
+
+import (
+	"math/rand/v2"
+	u "net/url"
+	. "time"
+)
+
+type Client struct {
+	Base    *u.URL
+	Timeout Duration
+	Query   map[string][]u.Values
+	Rand    *rand.Rand
+	Hook    func(*u.URL) Duration
+}
+
+func (c *Client) Resolve(path string, at Time) (*u.URL, error) {
+	return c.Base.Parse(path)
+}
+
+// mantra: Fetch the resource
+func (c *Client) Fetch(path string) error {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	ctx, err := NewPackageLoader(dir).GetContextForTarget(source, map[string]bool{"Client": true}, "Fetch")
+	if err != nil {
+		t.Fatalf("Failed to get context: %v", err)
+	}
+
+	def := ctx.Types["Client"]
+	for _, want := range []string{
+		"Base *u.URL",
+		"Timeout Duration",
+		"Query map[string][]u.Values",
+		"Rand *rand.Rand",
+		"Hook func(*u.URL) Duration",
+	} {
+		if !strings.Contains(def, want) {
+			t.Errorf("Expected %q in the definition, got:\n%s", want, def)
+		}
+	}
+
+	methods := ctx.Methods["Client"]
+	if len(methods) != 1 || methods[0].Signature != "Resolve(path string, at Time) (*u.URL, error)" || methods[0].Receiver != "*Client" {
+		t.Errorf("Expected Resolve qualified as imported, got %+v", methods)
+	}
+
+	identifiers := make(map[string]string)
+	for _, imp := range ctx.Imports {
+		identifiers[imp.Path] = imp.GetIdentifier()
+	}
+	if identifiers["math/rand/v2"] != "rand" || identifiers["net/url"] != "u" || identifiers["time"] != "." {
+		t.Errorf("Expected identifiers resolved by the type checker, got %v", identifiers)
+	}
+}
+
+func TestGetContextForTargetExpansionLimits(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	source := filepath.Join(dir, "shop.go")
+	if err := os.WriteFile(source, []byte(`package shop
+
+type Order struct {
+	Customer Customer
+	Lines    []Line
+	Address  Address
+}
+
+type Invoice struct {
+	Customer Customer
+	Total    int
+}
+
+type Customer struct {
+	Account Account
+}
+
+type Line struct {
+	SKU string
+}
+
+type Address struct {
+	Street string
+}
+
+type Account struct {
+	ID string
+}
+
+// mantra: Bill the order
+func Bill(o Order, i Invoice) error {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	loader := NewPackageLoader(dir)
+	fc, err := loader.newFileContext(t.Context(), source)
+	if err != nil {
+		t.Fatalf("Failed to load context: %v", err)
+	}
+	direct := map[string]bool{"Order": true, "Invoice": true}
+
+	tests := []struct {
+		name   string
+		limits ExpansionLimits
+		want   []string
+	}{
+		{"default", DefaultExpansionLimits, []string{"Account", "Address", "Customer", "Invoice", "Line", "Order"}},
+		{"no expansion", ExpansionLimits{}, []string{"Invoice", "Order"}},
+		{"one level", ExpansionLimits{Depth: 1}, []string{"Address", "Customer", "Invoice", "Line", "Order"}},
+		{"most referenced first", ExpansionLimits{Depth: 3, PerLevel: 2}, []string{"Account", "Address", "Customer", "Invoice", "Order"}},
+		{"byte budget", ExpansionLimits{Depth: 3, MaxBytes: len(fc.definitions["Order"]) + len(fc.definitions["Invoice"]) + len(fc.definitions["Line"])}, []string{"Invoice", "Line", "Order"}},
+		{"direct types over budget", ExpansionLimits{Depth: 3, MaxBytes: 1}, []string{"Invoice", "Order"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc.SetExpansionLimits(tt.limits)
+			ctx := fc.selectContext(direct, "Bill")
+			if got := sortedKeys(ctx.Types); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected types %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetContextForTargetAliasesAndDefinedTypes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	source := filepath.Join(dir, "shop.go")
+	if err := os.WriteFile(source, []byte(`package shop
+
+import "time"
+
+type Status int
+
+func (s Status) String() string { return "" }
+
+type Customer struct {
+	Name string
+}
+
+func (c *Customer) Greet() string { return c.Name }
+
+type Buyer = Customer
+
+type Client = Buyer
+
+type Timeout = time.Duration
+
+// mantra: Describe the client
+func Describe(c Client, s Status, t Timeout) string {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	ctx, err := NewPackageLoader(dir).GetContextForTarget(source, map[string]bool{"Client": true, "Status": true, "Timeout": true}, "Describe")
+	if err != nil {
+		t.Fatalf("Failed to get context: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"Client":   "type Client = Buyer",
+		"Buyer":    "type Buyer = Customer",
+		"Timeout":  "type Timeout = time.Duration",
+		"Status":   "type Status int",
+		"Customer": "type Customer struct {\n    Name string\n}",
+	} {
+		if got := ctx.Types[name]; got != want {
+			t.Errorf("Expected %s defined as %q, got %q", name, want, got)
+		}
+	}
+
+	if methods := ctx.Methods["Status"]; len(methods) != 1 || methods[0].Signature != "String() string" {
+		t.Errorf("Expected the methods of the defined type, got %+v", methods)
+	}
+	if methods := ctx.Methods["Client"]; len(methods) != 1 || methods[0].Name != "Greet" {
+		t.Errorf("Expected the methods of the aliased type, got %+v", methods)
+	}
+}
+
+func TestGetContextForTargetConstBlocks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	source := filepath.Join(dir, "shop.go")
+	if err := os.WriteFile(source, []byte(`package shop
+
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusPaid
+	_
+	StatusShipped
+)
+
+const DefaultStatus = StatusPending
+
+type Unused int
+
+const UnusedValue Unused = 1
+
+const maxItems = 10
+
+// mantra: Name the status
+func (s Status) Name() string {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	ctx, err := NewPackageLoader(dir).GetContextForTarget(source, map[string]bool{"Status": true}, "Name")
+	if err != nil {
+		t.Fatalf("Failed to get context: %v", err)
+	}
+
+	want := []string{
+		"const (\n    StatusPending Status = 0\n    StatusPaid Status = 1\n    StatusShipped Status = 3\n)",
+		"const DefaultStatus Status = 0",
+	}
+	if strings.Join(ctx.Constants, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected const blocks %q, got %q", want, ctx.Constants)
+	}
+}
+
+func TestGetContextForTargetDocs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/cache\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	source := filepath.Join(dir, "cache.go")
+	if err := os.WriteFile(source, []byte(`package cache
+
+// Cache holds entries by key.
+// Keys are case-sensitive.
+// The zero value is not usable.
+// Use New to create one.
+type Cache struct {
+	entries map[string]Entry
+	store   Store
+}
+
+// Entry is a cached value
+type Entry struct {
+	Value string
+}
+
+// Store persists entries
+type Store interface {
+	// Save writes the entry; it must not be called with the lock held
+	Save(key string, e Entry) error
+}
+
+// Len returns the number of entries.
+// It does not count expired ones.
+func (c *Cache) Len() int { return len(c.entries) }
+
+// mantra: Put the value
+func (c *Cache) Put(key, value string) {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	loader := NewPackageLoader(dir)
+	fc, err := loader.newFileContext(t.Context(), source)
+	if err != nil {
+		t.Fatalf("Failed to load context: %v", err)
+	}
+	direct := map[string]bool{"Cache": true, "Store": true}
+
+	ctx := fc.selectContext(direct, "Put")
+	if want := "Cache holds entries by key.\nKeys are case-sensitive.\nThe zero value is not usable."; ctx.TypeDocs["Cache"] != want {
+		t.Errorf("Expected the doc trimmed to three lines %q, got %q", want, ctx.TypeDocs["Cache"])
+	}
+	if _, ok := ctx.TypeDocs["Entry"]; ok {
+		t.Errorf("Expected no doc for the referenced type Entry, got %q", ctx.TypeDocs["Entry"])
+	}
+	if methods := ctx.Methods["Cache"]; len(methods) != 1 || methods[0].Doc != "Len returns the number of entries.\nIt does not count expired ones." {
+		t.Errorf("Expected the documented methods of Cache without Put, got %+v", methods)
+	}
+	if methods := ctx.Methods["Store"]; len(methods) != 1 || methods[0].Doc != "Save writes the entry; it must not be called with the lock held" {
+		t.Errorf("Expected the documented interface methods of Store, got %+v", methods)
+	}
+	if fc.types["Cache"].Methods[0].Doc != "" {
+		t.Errorf("Expected the shared type information to be left unchanged, got %+v", fc.types["Cache"].Methods)
+	}
+
+	fc.SetDocLines(0)
+	ctx = fc.selectContext(direct, "Put")
+	if len(ctx.TypeDocs) != 0 || ctx.Methods["Cache"][0].Doc != "" {
+		t.Errorf("Expected no docs when disabled, got %v and %+v", ctx.TypeDocs, ctx.Methods["Cache"])
+	}
+}
+
+func TestGetContextForTargetPromotedMethods(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/emb\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	source := filepath.Join(dir, "emb.go")
+	if err := os.WriteFile(source, []byte(`package emb
+
+import "strings"
+
+type base struct{}
+
+func (base) id() string { return "" }
+
+type Writer struct {
+	*strings.Builder
+	base
+	n int
+}
+
+// Count returns the number of writes
+func (w *Writer) Count() int { return w.n }
+
+// mantra: Write s
+func (w *Writer) Put(s string) {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	ctx, err := NewPackageLoader(dir).GetContextForTarget(source, map[string]bool{"Writer": true}, "Put")
+	if err != nil {
+		t.Fatalf("Failed to get context: %v", err)
+	}
+
+	if want := "type Writer struct {\n    *strings.Builder\n    base\n    n int\n}"; ctx.Types["Writer"] != want {
+		t.Errorf("Expected embedded fields without names %q, got %q", want, ctx.Types["Writer"])
+	}
+	if _, ok := ctx.Types["base"]; !ok {
+		t.Errorf("Expected the embedded type to be expanded, got %v", sortedKeys(ctx.Types))
+	}
+
+	methods := make(map[string]string)
+	for _, method := range ctx.Methods["Writer"] {
+		methods[method.Signature] = method.Receiver + " " + method.Promoted
+	}
+	for signature, want := range map[string]string{
+		"Count() int":                        "*Writer ",
+		"WriteString(s string) (int, error)": "Writer *strings.Builder",
+		"Len() int":                          "Writer *strings.Builder",
+		"id() string":                        "Writer base",
+	} {
+		if got, ok := methods[signature]; !ok || got != want {
+			t.Errorf("Expected %s with receiver and origin %q, got %q", signature, want, got)
+		}
+	}
+	for _, unexported := range []string{"grow(n int)", "copyCheck()"} {
+		if _, ok := methods[unexported]; ok {
+			t.Errorf("Expected unexported methods of strings.Builder to be left out, got %s", unexported)
+		}
+	}
+}
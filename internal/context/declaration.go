@@ -6,6 +6,7 @@ type Declaration interface {
 	GetKind() string
 	GetPackage() string
 	IsFound() bool
+	GetWarning() string
 }
 
 // baseDeclaration contains common fields
@@ -14,12 +15,16 @@ type baseDeclaration struct {
 	Name    string
 	Kind    string
 	Package string
+	Warning string // Caveat about using the declaration from the target package
 }
 
 func (d *baseDeclaration) GetName() string    { return d.Name }
 func (d *baseDeclaration) GetKind() string    { return d.Kind }
 func (d *baseDeclaration) GetPackage() string { return d.Package }
 func (d *baseDeclaration) IsFound() bool      { return d.Found }
+func (d *baseDeclaration) GetWarning() string { return d.Warning }
+
+func (d *baseDeclaration) setWarning(warning string) { d.Warning = warning }
 
 // NotFoundDeclaration represents a declaration that wasn't found
 type NotFoundDeclaration struct {
@@ -58,6 +63,7 @@ type ConstantDeclaration struct {
 	baseDeclaration
 	Type  string
 	Value string
+	Group string // Const block the constant is declared in, members in order (empty if alone)
 	Doc   string // Documentation comment
 }
 
@@ -69,11 +75,13 @@ type VariableDeclaration struct {
 	Doc         string // Documentation comment
 }
 
-// TypeAliasDeclaration represents a type alias
+// TypeAliasDeclaration represents a type alias (Kind "alias") or a defined type that is
+// not a struct or interface (Kind "type")
 type TypeAliasDeclaration struct {
 	baseDeclaration
 	Definition string
-	Type       string
+	Type       string // Underlying type of a defined type, the declared type of an alias
+	Resolved   string // Type a chain of aliases ends in, if not the declared one
 	Methods    []MethodInfo
 	Doc        string // Documentation comment
 }
@@ -90,5 +98,6 @@ type MethodInfo struct {
 	Name      string `json:"name"`
 	Signature string `json:"signature"`
 	Receiver  string `json:"receiver,omitempty"`
-	Doc       string `json:"doc,omitempty"` // Documentation comment
+	Promoted  string `json:"promoted_from,omitempty"` // Type of the embedded field the method is promoted from
+	Doc       string `json:"doc,omitempty"`           // Documentation comment
 }
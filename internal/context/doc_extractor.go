@@ -2,18 +2,22 @@ package context
 
 import (
 	"fmt"
+	"go/ast"
 	"go/doc"
 	"go/types"
-	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
 
-// getPackageDocs extracts documentation from a packages.Package
+// getPackageDocs extracts documentation from a packages.Package. doc.NewFromFiles takes the
+// doc comments off the files it reads, so the documentation of each package is read once
 func (l *PackageLoader) getPackageDocs(pkg *packages.Package) (*doc.Package, error) {
 	if pkg == nil || len(pkg.Syntax) == 0 {
 		return nil, nil // No syntax available, can't extract docs
 	}
+	if docPkg, ok := l.docs[pkg.PkgPath]; ok {
+		return docPkg, nil
+	}
 
 	// Create doc.Package directly from AST files using the modern API
 	// Use doc.AllDecls to include non-exported declarations as well
@@ -21,20 +25,16 @@ func (l *PackageLoader) getPackageDocs(pkg *packages.Package) (*doc.Package, err
 	if err != nil {
 		return nil, fmt.Errorf("failed to create doc package: %w", err)
 	}
+	if l.docs == nil {
+		l.docs = make(map[string]*doc.Package)
+	}
+	l.docs[pkg.PkgPath] = docPkg
 
 	return docPkg, nil
 }
 
 // extractMethodsForDeclarationWithDoc gets methods with documentation if available
 func (l *PackageLoader) extractMethodsForDeclarationWithDoc(typ types.Type, pkg *packages.Package, typeName string) []MethodInfo {
-	var methods []MethodInfo
-
-	// Get method set for both value and pointer types
-	mset := types.NewMethodSet(typ)
-	ptrMset := types.NewMethodSet(types.NewPointer(typ))
-
-	seen := make(map[string]bool)
-
 	// Get method documentation if available
 	var methodDocs map[string]string
 	if pkg != nil && typeName != "" && len(pkg.Syntax) > 0 {
@@ -53,39 +53,56 @@ func (l *PackageLoader) extractMethodsForDeclarationWithDoc(typ types.Type, pkg
 		}
 	}
 
-	// Add methods from pointer type (includes all methods)
-	for i := 0; i < ptrMset.Len(); i++ {
-		method := ptrMset.At(i).Obj().(*types.Func)
-		sig := method.Type().(*types.Signature)
-
+	var methods []MethodInfo
+	for _, method := range l.extractMethods(typ) {
 		methodInfo := MethodInfo{
-			Name:      method.Name(),
-			Signature: l.formatSignature(method.Name(), sig),
-			Receiver:  l.simplifyTypeName("*" + strings.TrimPrefix(typ.String(), "*")),
+			Name:      method.Name,
+			Signature: method.Signature,
+			Receiver:  method.Receiver,
+			Promoted:  method.Promoted,
 		}
-
-		// Add documentation if available
-		if methodDocs != nil {
-			if doc, exists := methodDocs[method.Name()]; exists {
-				methodInfo.Doc = doc
-			}
+		// Promoted methods are documented with the embedded type
+		if method.Promoted == "" {
+			methodInfo.Doc = methodDocs[method.Name]
 		}
+		methods = append(methods, methodInfo)
+	}
 
-		// Check if it's a value receiver method
-		if i < mset.Len() {
-			valueMethod := mset.At(i).Obj().(*types.Func)
-			if valueMethod.Name() == method.Name() {
-				methodInfo.Receiver = l.simplifyTypeName(strings.TrimPrefix(typ.String(), "*"))
-			}
-		}
+	return methods
+}
 
-		if !seen[method.Name()] {
-			methods = append(methods, methodInfo)
-			seen[method.Name()] = true
+// typeDocs returns the doc comments of the types of the loaded package and of their
+// methods, including those of interface methods
+func (l *PackageLoader) typeDocs() (map[string]string, map[string]map[string]string) {
+	typeDocs := make(map[string]string)
+	methodDocs := make(map[string]map[string]string)
+
+	docPkg, err := l.getPackageDocs(l.pkg)
+	if err != nil || docPkg == nil {
+		return typeDocs, methodDocs
+	}
+	for _, t := range docPkg.Types {
+		typeDocs[t.Name] = t.Doc
+		methods := make(map[string]string)
+		for _, method := range t.Methods {
+			methods[method.Name] = method.Doc
 		}
+		for _, spec := range t.Decl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok || typeSpec.Name.Name != t.Name {
+				continue
+			}
+			if iface, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+				for _, field := range iface.Methods.List {
+					if field.Doc != nil && len(field.Names) > 0 {
+						methods[field.Names[0].Name] = field.Doc.Text()
+					}
+				}
+			}
+		}
+		methodDocs[t.Name] = methods
 	}
-
-	return methods
+	return typeDocs, methodDocs
 }
 
 // attachDocumentation attaches documentation to a declaration
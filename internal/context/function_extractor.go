@@ -1,8 +1,11 @@
 package context
 
 import (
+	"context"
 	"fmt"
+	"go/types"
 	"path/filepath"
+	"strings"
 
 	"github.com/rail44/mantra/internal/analysis"
 	"github.com/rail44/mantra/internal/parser"
@@ -12,31 +15,163 @@ import (
 type RelevantContext struct {
 	Imports     []*ImportInfo                    // All imports with structured information
 	Types       map[string]string                // Type definitions (name -> definition)
-	Methods     map[string][]analysis.MethodInfo // Type methods (typeName -> methods)
+	Methods     map[string][]analysis.MethodInfo // Type methods (typeName -> methods); those of directly used types carry their docs
+	TypeDocs    map[string]string                // Doc comments of directly used types, trimmed (typeName -> doc)
+	Constants   []string                         // Const blocks declaring constants of the types, in source order
 	PackageName string                           // Package name
+	Constructor *ConstructorInfo                 // Set when the target is a constructor (NewX returning X or *X)
+	Receiver    *ReceiverState                   // Set when the target is a method of a struct with a mutex or fields other methods use
 }
 
-// ExtractFunctionContext extracts context using go/packages for accurate type resolution
-func ExtractFunctionContext(filePath string, target *parser.Target) (*RelevantContext, error) {
-	// Create package loader for the directory containing the file
-	packagePath := filepath.Dir(filePath)
-	loader := NewPackageLoader(packagePath)
+// ConstructorInfo describes the type built by a constructor target
+type ConstructorInfo struct {
+	TypeName string               // Constructed type name
+	Fields   []analysis.FieldInfo // Struct fields to wire, with simplified types
+	Options  []string             // Functional option types applicable to the constructed type
+}
 
-	// Identify types directly referenced in function signature
-	directlyUsedTypes := extractDirectlyUsedTypes(target)
+// FileContext holds the package context shared by every target in a source file.
+// It is built once per file and is safe for concurrent use
+type FileContext struct {
+	loader      *PackageLoader
+	imports     []*ImportInfo                // Imports of the source file
+	types       map[string]*TypeInfo         // All types in the package
+	definitions map[string]string            // Complete type definitions by type name
+	constants   []*ConstBlock                // Package-level const declarations in source order
+	limits      ExpansionLimits              // Bounds of referenced type expansion
+	typeDocs    map[string]string            // Doc comments of the package types by type name
+	methodDocs  map[string]map[string]string // Doc comments of methods by type and method name
+	docLines    int                          // Lines kept of each doc comment (0: no docs)
+}
+
+// ExpansionLimits bounds how many types referenced by a target's types, and by those in
+// turn, are added to its context
+type ExpansionLimits struct {
+	Depth    int // Levels of referenced types added (0: none)
+	PerLevel int // Types added per level (0: no limit)
+	MaxBytes int // Bytes of type definitions in a target's context (0: no limit)
+}
+
+// DefaultExpansionLimits adds three levels of referenced types without further limits
+var DefaultExpansionLimits = ExpansionLimits{Depth: 3}
+
+// DefaultDocLines is the number of lines kept of each doc comment in a target's context
+const DefaultDocLines = 3
+
+// NewFileContext loads the package containing filePath and extracts the context shared by its targets
+func NewFileContext(ctx context.Context, filePath string) (*FileContext, error) {
+	loader := NewPackageLoader(filepath.Dir(filePath))
+	fc, err := loader.newFileContext(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract context: %w", err)
+	}
+	return fc, nil
+}
+
+// SetExpansionLimits bounds the referenced types added to the context of each target.
+// It must be called before the context is shared
+func (fc *FileContext) SetExpansionLimits(limits ExpansionLimits) {
+	fc.limits = limits
+}
 
-	// Get context using the package loader
+// SetDocLines sets the lines kept of the doc comments of directly used types and their
+// methods; 0 leaves docs out. It must be called before the context is shared
+func (fc *FileContext) SetDocLines(lines int) {
+	fc.docLines = lines
+}
+
+// ForTarget selects the context relevant to a target in the file
+func (fc *FileContext) ForTarget(target *parser.Target) *RelevantContext {
 	// Pass the target method name to exclude it from the methods list
 	targetMethodName := ""
 	if target.Receiver != nil {
 		targetMethodName = target.Name
 	}
-	ctx, err := loader.GetContextForTarget(filePath, directlyUsedTypes, targetMethodName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract context: %w", err)
+
+	// Identify types directly referenced in function signature
+	ctx := fc.selectContext(extractDirectlyUsedTypes(target), targetMethodName)
+
+	if typeName := target.ConstructedType(); typeName != "" {
+		ctx.Constructor = fc.loader.GetConstructorInfo(typeName)
+	}
+	if target.Receiver != nil && target.FuncDecl != nil && target.FuncDecl.Recv != nil && len(target.FuncDecl.Recv.List) > 0 {
+		typeName := analysis.ReceiverTypeName(target.FuncDecl.Recv.List[0].Type)
+		ctx.Receiver = fc.loader.GetReceiverState(typeName, target.Name)
+	}
+
+	return ctx
+}
+
+// PackageNames returns the names declared by the packages the file imports, by import path.
+// Imports the type checker could not resolve are left out
+func (fc *FileContext) PackageNames() map[string]string {
+	names := make(map[string]string, len(fc.imports))
+	for _, imp := range fc.imports {
+		if imp.PackageName != "" {
+			names[imp.Path] = imp.PackageName
+		}
+	}
+	return names
+}
+
+// HasIdentifier reports whether name resolves to a package-level declaration or an import of the file.
+// For qualified names (pkg.Name, Type.Method) the selector is resolved when the qualifier is a declaration
+func (fc *FileContext) HasIdentifier(name string) bool {
+	parts := strings.Split(name, ".")
+	for _, imp := range fc.imports {
+		if imp.GetIdentifier() == parts[0] {
+			return true
+		}
+	}
+
+	obj := fc.loader.pkg.Types.Scope().Lookup(parts[0])
+	if obj == nil {
+		return false
+	}
+	for _, part := range parts[1:] {
+		obj, _, _ = types.LookupFieldOrMethod(obj.Type(), true, fc.loader.pkg.Types, part)
+		if obj == nil {
+			return false
+		}
 	}
+	return true
+}
+
+// PackageDeclarations renders every package-level declaration, for prompts that skip context gathering
+func (fc *FileContext) PackageDeclarations() string {
+	pkg := fc.loader.pkg.Types
+	qualifier := func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		return other.Name()
+	}
+
+	var sb strings.Builder
+	for _, name := range pkg.Scope().Names() {
+		obj := pkg.Scope().Lookup(name)
+		sb.WriteString(types.ObjectString(obj, qualifier))
+		sb.WriteString("\n")
+
+		if named, ok := obj.Type().(*types.Named); ok {
+			if _, isTypeName := obj.(*types.TypeName); isTypeName {
+				for i := 0; i < named.NumMethods(); i++ {
+					sb.WriteString(types.ObjectString(named.Method(i), qualifier))
+					sb.WriteString("\n")
+				}
+			}
+		}
+	}
+	return sb.String()
+}
 
-	return ctx, nil
+// ExtractFunctionContext extracts context using go/packages for accurate type resolution
+func ExtractFunctionContext(ctx context.Context, filePath string, target *parser.Target) (*RelevantContext, error) {
+	fc, err := NewFileContext(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return fc.ForTarget(target), nil
 }
 
 // extractDirectlyUsedTypes extracts types directly used in function signature
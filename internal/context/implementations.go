@@ -0,0 +1,247 @@
+package context
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/rail44/mantra/internal/buildenv"
+)
+
+// MethodImplementationDeclaration represents an interface method resolved to its concrete implementations
+type MethodImplementationDeclaration struct {
+	baseDeclaration
+	Interface       string
+	Signature       string           // Interface method signature
+	Implementations []Implementation // Most likely implementation first
+}
+
+// Implementation is a concrete method implementing an interface method
+type Implementation struct {
+	TypeName string `json:"type"`
+	Package  string `json:"package"`
+	Location string `json:"location"`
+	Source   string `json:"implementation,omitempty"` // Only set for the most likely implementation
+}
+
+// interfaceMethod resolves Interface.Method to the concrete implementations found in the project
+func (l *PackageLoader) interfaceMethod(ctx context.Context, obj *types.TypeName, method, pkgName string) (Declaration, error) {
+	notFound := &NotFoundDeclaration{baseDeclaration: baseDeclaration{Name: obj.Name() + "." + method, Kind: "not_found", Found: false}}
+
+	pkgs, err := l.projectPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Project packages are type-checked separately, so the interface has to be
+	// looked up again there for types.Implements to compare like with like
+	if obj.Pkg() == nil {
+		return notFound, nil
+	}
+	projectObj := lookupTypeName(pkgs, obj.Pkg().Path(), obj.Name())
+	if projectObj == nil {
+		return notFound, nil
+	}
+	iface, ok := projectObj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return notFound, nil
+	}
+
+	var ifaceMethod *types.Func
+	for i := 0; i < iface.NumMethods(); i++ {
+		if iface.Method(i).Name() == method {
+			ifaceMethod = iface.Method(i)
+			break
+		}
+	}
+	if ifaceMethod == nil {
+		return notFound, nil
+	}
+
+	result := &MethodImplementationDeclaration{
+		baseDeclaration: baseDeclaration{
+			Name:    obj.Name() + "." + method,
+			Kind:    "interface_method",
+			Package: pkgName,
+			Found:   true,
+		},
+		Interface: obj.Name(),
+		Signature: l.formatSignature(method, ifaceMethod.Type().(*types.Signature)),
+	}
+
+	type candidate struct {
+		impl Implementation
+		fn   *types.Func
+		rank int
+	}
+	var candidates []candidate
+
+	for _, pkg := range pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() || types.IsInterface(tn.Type()) {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 {
+				continue
+			}
+
+			ptr := types.NewPointer(named)
+			if !types.Implements(named, iface) && !types.Implements(ptr, iface) {
+				continue
+			}
+
+			fnObj, _, _ := types.LookupFieldOrMethod(ptr, true, ifaceMethod.Pkg(), method)
+			fn, ok := fnObj.(*types.Func)
+			if !ok {
+				continue
+			}
+
+			pos := pkg.Fset.Position(fn.Pos())
+			candidates = append(candidates, candidate{
+				impl: Implementation{
+					TypeName: name,
+					Package:  pkg.PkgPath,
+					Location: fmt.Sprintf("%s:%d", filepath.Base(pos.Filename), pos.Line),
+				},
+				fn:   fn,
+				rank: l.implementationRank(name, obj.Name(), pkg),
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].rank != candidates[j].rank {
+			return candidates[i].rank < candidates[j].rank
+		}
+		return candidates[i].impl.Package+"."+candidates[i].impl.TypeName < candidates[j].impl.Package+"."+candidates[j].impl.TypeName
+	})
+
+	for i, c := range candidates {
+		if i == 0 {
+			c.impl.Source = methodSource(pkgs, c.fn)
+		}
+		result.Implementations = append(result.Implementations, c.impl)
+	}
+
+	return result, nil
+}
+
+// implementationRank orders implementations so real ones come before test doubles,
+// and ones in the current package or named after the interface come first. Lower is better
+func (l *PackageLoader) implementationRank(typeName, ifaceName string, pkg *packages.Package) int {
+	rank := 0
+
+	lower := strings.ToLower(typeName)
+	for _, marker := range []string{"mock", "fake", "stub", "noop", "dummy", "spy"} {
+		if strings.Contains(lower, marker) {
+			rank += 4
+			break
+		}
+	}
+
+	if pkg.PkgPath != l.pkg.PkgPath {
+		rank += 2
+	}
+
+	if !strings.Contains(lower, strings.ToLower(ifaceName)) {
+		rank++
+	}
+
+	return rank
+}
+
+// methodSource returns the source of fn's declaration, or "" if it is not in a loaded package
+func methodSource(pkgs []*packages.Package, fn *types.Func) string {
+	for _, pkg := range pkgs {
+		if fn.Pkg() == nil || pkg.PkgPath != fn.Pkg().Path() {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Name.Pos() != fn.Pos() {
+					continue
+				}
+				var buf bytes.Buffer
+				if err := printer.Fprint(&buf, pkg.Fset, &printer.CommentedNode{Node: fd, Comments: file.Comments}); err != nil {
+					return ""
+				}
+				return buf.String()
+			}
+		}
+	}
+	return ""
+}
+
+// lookupTypeName finds the type name path.name among pkgs and their transitive imports
+func lookupTypeName(pkgs []*packages.Package, path, name string) *types.TypeName {
+	var found *types.TypeName
+	packages.Visit(pkgs, func(pkg *packages.Package) bool {
+		if found != nil {
+			return false
+		}
+		if pkg.PkgPath == path && pkg.Types != nil {
+			found, _ = pkg.Types.Scope().Lookup(name).(*types.TypeName)
+			return false
+		}
+		return true
+	}, nil)
+	return found
+}
+
+// projectPackages loads every package of the main module once and reuses them afterwards
+func (l *PackageLoader) projectPackages(ctx context.Context) ([]*packages.Package, error) {
+	if l.projectPkgs != nil {
+		return l.projectPkgs, nil
+	}
+
+	// Outside a module only the current package is available
+	if l.pkg.Module == nil || l.pkg.Module.Dir == "" {
+		l.projectPkgs = []*packages.Package{l.pkg}
+		return l.projectPkgs, nil
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName |
+			packages.NeedFiles |
+			packages.NeedImports |
+			packages.NeedDeps |
+			packages.NeedTypes |
+			packages.NeedSyntax |
+			packages.NeedTypesInfo |
+			packages.NeedModule,
+		Dir:     l.pkg.Module.Dir,
+		Context: ctx,
+		Overlay: l.overlay,
+	}
+
+	pkgs, err := packages.Load(buildenv.Apply(cfg), "./...")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project packages: %w", err)
+	}
+
+	// Packages with errors are skipped; the rest are still useful
+	var loaded []*packages.Package
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) == 0 && pkg.Types != nil {
+			loaded = append(loaded, pkg)
+		}
+	}
+
+	l.projectPkgs = loaded
+	return l.projectPkgs, nil
+}
@@ -2,6 +2,7 @@ package context
 
 import (
 	"go/ast"
+	"go/types"
 	"strings"
 )
 
@@ -17,6 +18,10 @@ type ImportInfo struct {
 
 	// IsBlank indicates if this is a blank import (alias == "_")
 	IsBlank bool
+
+	// PackageName is the name declared by the imported package, as resolved by the type
+	// checker (empty if unknown)
+	PackageName string
 }
 
 // GetIdentifier returns the identifier to use for this import in code
@@ -25,8 +30,10 @@ func (i *ImportInfo) GetIdentifier() string {
 	if i.Alias != "" && i.Alias != "_" {
 		return i.Alias
 	}
-	// Extract package name from path (last segment)
-	// This is a simplified version - real implementation might need more logic
+	if i.PackageName != "" {
+		return i.PackageName
+	}
+	// Without type information, assume the package is named after the last path segment
 	if idx := lastIndexByte(i.Path, '/'); idx >= 0 {
 		return i.Path[idx+1:]
 	}
@@ -62,3 +69,24 @@ func ExtractImportInfo(node *ast.File) []*ImportInfo {
 
 	return imports
 }
+
+// resolveImports sets the package names of imports, extracted from file, from the type
+// checker's information and returns the identifier file refers to each import path by:
+// the name it is imported under, "." for dot imports and "_" for blank ones
+func resolveImports(imports []*ImportInfo, file *ast.File, info *types.Info) map[string]string {
+	names := make(map[string]string, len(file.Imports))
+	if info == nil {
+		return names
+	}
+	for i, spec := range file.Imports {
+		pkgName := info.PkgNameOf(spec)
+		if pkgName == nil {
+			continue
+		}
+		names[pkgName.Imported().Path()] = pkgName.Name()
+		if i < len(imports) {
+			imports[i].PackageName = pkgName.Imported().Name()
+		}
+	}
+	return names
+}
@@ -1,16 +1,25 @@
 package context
 
 import (
+	"context"
 	"fmt"
+	"go/doc"
+	"os"
+	"path/filepath"
 
 	"golang.org/x/tools/go/packages"
+
+	"github.com/rail44/mantra/internal/buildenv"
 )
 
 // PackageLoader provides go/packages based type resolution
 type PackageLoader struct {
-	packagePath   string
-	pkg           *packages.Package
-	targetImports []*ImportInfo // Imports from the target file for type simplification
+	packagePath string
+	pkg         *packages.Package
+	importNames map[string]string       // Identifiers of the target file's imports by path, for qualifying types
+	projectPkgs []*packages.Package     // Every package of the main module, loaded on demand
+	overlay     map[string][]byte       // In-memory file contents by absolute path (optional)
+	docs        map[string]*doc.Package // Documentation by package path, read once as reading consumes the doc comments
 }
 
 // NewPackageLoader creates a new package loader
@@ -20,8 +29,25 @@ func NewPackageLoader(packagePath string) *PackageLoader {
 	}
 }
 
-// Load loads the package information
+// WithOverlay makes the loader see the given file contents in place of those on disk.
+// The package directory may exist only in the overlay
+func (l *PackageLoader) WithOverlay(overlay map[string][]byte) *PackageLoader {
+	l.overlay = overlay
+	return l
+}
+
+// Load loads the package information. Subsequent calls reuse the loaded package
 func (l *PackageLoader) Load() error {
+	return l.LoadContext(context.Background())
+}
+
+// LoadContext loads the package information, aborting when ctx is done.
+// Subsequent calls reuse the loaded package
+func (l *PackageLoader) LoadContext(ctx context.Context) error {
+	if l.pkg != nil {
+		return nil
+	}
+
 	cfg := &packages.Config{
 		Mode: packages.NeedName |
 			packages.NeedFiles |
@@ -31,11 +57,25 @@ func (l *PackageLoader) Load() error {
 			packages.NeedTypes |
 			packages.NeedTypesSizes |
 			packages.NeedSyntax |
-			packages.NeedTypesInfo,
-		Dir: l.packagePath,
+			packages.NeedTypesInfo |
+			packages.NeedModule,
+		Dir:     l.packagePath,
+		Context: ctx,
+		Overlay: l.overlay,
+	}
+
+	// A destination package that has not been written yet only exists in the overlay,
+	// so it is loaded by path from the nearest existing directory
+	pattern := "."
+	if _, err := os.Stat(l.packagePath); os.IsNotExist(err) && len(l.overlay) > 0 {
+		absPath, err := filepath.Abs(l.packagePath)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		cfg.Dir, pattern = existingParent(absPath), absPath
 	}
 
-	pkgs, err := packages.Load(cfg, ".")
+	pkgs, err := packages.Load(buildenv.Apply(cfg), pattern)
 	if err != nil {
 		return fmt.Errorf("failed to load package: %w", err)
 	}
@@ -44,17 +84,32 @@ func (l *PackageLoader) Load() error {
 		return fmt.Errorf("no packages found in %s", l.packagePath)
 	}
 
-	l.pkg = pkgs[0]
+	pkg := pkgs[0]
 
 	// Check for package errors
-	if len(l.pkg.Errors) > 0 {
+	if len(pkg.Errors) > 0 {
 		// Return the first error for simplicity
-		return fmt.Errorf("package has errors: %v", l.pkg.Errors[0])
+		return fmt.Errorf("package has errors: %v", pkg.Errors[0])
 	}
 
-	if l.pkg.Types == nil {
+	if pkg.Types == nil {
 		return fmt.Errorf("type information not available for package")
 	}
 
+	l.pkg = pkg
 	return nil
 }
+
+// existingParent returns the nearest ancestor of dir that exists on disk
+func existingParent(dir string) string {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package context
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ReceiverState describes how the existing methods of a receiver type use its fields, so a
+// new method can follow the same locking conventions
+type ReceiverState struct {
+	TypeName string
+	Fields   []FieldAccess // Fields other than mutexes accessed by at least one method, in declaration order
+	Mutexes  []string      // Fields of type sync.Mutex or sync.RWMutex
+}
+
+// FieldAccess lists the methods reading and writing one field
+type FieldAccess struct {
+	Name      string
+	Type      string
+	ReadBy    []string // Methods that only read the field
+	WrittenBy []string // Methods that assign, modify or take the address of the field
+	GuardedBy string   // Mutex held by every method accessing the field (empty if none)
+}
+
+// methodAccess is what one method body does with the fields of its receiver
+type methodAccess struct {
+	reads, writes map[string]bool
+	locks         map[string]bool      // Mutex fields the method locks
+	first         map[string]token.Pos // First access of each field
+}
+
+// GetReceiverState summarizes the field accesses of the methods of a struct type, leaving out
+// the method being generated. Returns nil if the package is not loaded
+func (l *PackageLoader) GetReceiverState(typeName, exclude string) *ReceiverState {
+	if l.pkg == nil {
+		return nil
+	}
+	return NewReceiverState(l.pkg, typeName, exclude)
+}
+
+// NewReceiverState summarizes the field accesses of the methods of a struct type in pkg,
+// leaving out the method named exclude. Returns nil if the type is not a struct, or has no
+// mutex and no other method accesses its fields
+func NewReceiverState(pkg *packages.Package, typeName, exclude string) *ReceiverState {
+	if pkg.Types == nil || pkg.TypesInfo == nil {
+		return nil
+	}
+	obj, ok := pkg.Types.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok || st.NumFields() == 0 {
+		return nil
+	}
+
+	state := &ReceiverState{TypeName: typeName}
+	for i := range st.NumFields() {
+		if isMutex(st.Field(i).Type()) {
+			state.Mutexes = append(state.Mutexes, st.Field(i).Name())
+		}
+	}
+
+	methods := make(map[string]*methodAccess)
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || fn.Name.Name == exclude {
+				continue
+			}
+			recv := receiverVar(pkg.TypesInfo, fn)
+			if recv == nil || !isReceiverOf(recv.Type(), obj) {
+				continue
+			}
+			methods[fn.Name.Name] = accessesOf(pkg.TypesInfo, fn.Body, recv)
+		}
+	}
+
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	qualifier := func(other *types.Package) string {
+		if other == pkg.Types {
+			return ""
+		}
+		return other.Name()
+	}
+	for i := range st.NumFields() {
+		field := st.Field(i)
+		if slices.Contains(state.Mutexes, field.Name()) {
+			continue
+		}
+		access := FieldAccess{Name: field.Name(), Type: types.TypeString(field.Type(), qualifier)}
+		var guards map[string]bool
+		for _, name := range names {
+			m := methods[name]
+			switch {
+			case m.writes[field.Name()]:
+				access.WrittenBy = append(access.WrittenBy, name)
+			case m.reads[field.Name()]:
+				access.ReadBy = append(access.ReadBy, name)
+			default:
+				continue
+			}
+			if guards == nil {
+				guards = maps.Clone(m.locks)
+			}
+			for mu := range guards {
+				if !m.locks[mu] {
+					delete(guards, mu)
+				}
+			}
+		}
+		if len(access.ReadBy)+len(access.WrittenBy) == 0 {
+			continue
+		}
+		if len(guards) > 0 {
+			held := make([]string, 0, len(guards))
+			for mu := range guards {
+				held = append(held, mu)
+			}
+			sort.Strings(held)
+			access.GuardedBy = held[0]
+		}
+		state.Fields = append(state.Fields, access)
+	}
+
+	if len(state.Fields) == 0 && len(state.Mutexes) == 0 {
+		return nil
+	}
+	return state
+}
+
+// Unguarded returns the first access of a method to each field the state reports guarded,
+// when the method does not lock the guarding mutex
+func (s *ReceiverState) Unguarded(info *types.Info, fn *ast.FuncDecl) map[string]token.Pos {
+	recv := receiverVar(info, fn)
+	if recv == nil || fn.Body == nil {
+		return nil
+	}
+	m := accessesOf(info, fn.Body, recv)
+	unguarded := make(map[string]token.Pos)
+	for _, field := range s.Fields {
+		if pos, ok := m.first[field.Name]; ok && field.GuardedBy != "" && !m.locks[field.GuardedBy] {
+			unguarded[field.Name] = pos
+		}
+	}
+	return unguarded
+}
+
+// receiverVar returns the named receiver of a method (nil for functions and unnamed receivers)
+func receiverVar(info *types.Info, fn *ast.FuncDecl) *types.Var {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 || len(fn.Recv.List[0].Names) == 0 {
+		return nil
+	}
+	recv, _ := info.Defs[fn.Recv.List[0].Names[0]].(*types.Var)
+	return recv
+}
+
+// accessesOf collects the receiver fields a method body reads, writes and locks
+func accessesOf(info *types.Info, body *ast.BlockStmt, recv *types.Var) *methodAccess {
+	m := &methodAccess{
+		reads:  make(map[string]bool),
+		writes: make(map[string]bool),
+		locks:  make(map[string]bool),
+		first:  make(map[string]token.Pos),
+	}
+
+	// field returns the receiver field an expression refers to (r.f, r.f.x, r.f[i], *r.f, ...)
+	var field func(expr ast.Expr) string
+	field = func(expr ast.Expr) string {
+		switch e := expr.(type) {
+		case *ast.SelectorExpr:
+			if ident, ok := e.X.(*ast.Ident); ok && info.Uses[ident] == recv {
+				if sel := info.Selections[e]; sel != nil && sel.Kind() == types.FieldVal {
+					return fieldAt(recv.Type(), sel.Index()[0])
+				}
+				return ""
+			}
+			return field(e.X)
+		case *ast.IndexExpr:
+			return field(e.X)
+		case *ast.StarExpr:
+			return field(e.X)
+		case *ast.ParenExpr:
+			return field(e.X)
+		}
+		return ""
+	}
+	write := func(expr ast.Expr) {
+		if name := field(expr); name != "" {
+			m.writes[name] = true
+		}
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE {
+				for _, lhs := range n.Lhs {
+					write(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			write(n.X)
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN {
+				write(n.Key)
+				if n.Value != nil {
+					write(n.Value)
+				}
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				write(n.X)
+			}
+		case *ast.CallExpr:
+			if ident, ok := n.Fun.(*ast.Ident); ok && (ident.Name == "delete" || ident.Name == "clear") && len(n.Args) > 0 {
+				if _, builtin := info.Uses[ident].(*types.Builtin); builtin {
+					write(n.Args[0])
+				}
+			}
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok {
+				break
+			}
+			selection := info.Selections[sel]
+			if selection == nil || selection.Kind() != types.MethodVal {
+				break
+			}
+			name := field(sel.X)
+			if ident, ok := sel.X.(*ast.Ident); ok && info.Uses[ident] == recv && len(selection.Index()) > 1 {
+				// A method promoted from an embedded field, e.g. r.Lock() on an embedded sync.Mutex
+				name = fieldAt(recv.Type(), selection.Index()[0])
+			}
+			if name == "" {
+				break
+			}
+			switch sel.Sel.Name {
+			case "Lock", "RLock":
+				if isMutex(fieldType(recv.Type(), name)) {
+					m.locks[name] = true
+					return true
+				}
+			}
+			// Methods with pointer receivers may modify the field
+			if sig, ok := selection.Obj().Type().(*types.Signature); ok && sig.Recv() != nil {
+				if _, ptr := sig.Recv().Type().(*types.Pointer); ptr {
+					m.writes[name] = true
+				}
+			}
+		case *ast.SelectorExpr:
+			if name := field(n); name != "" {
+				m.reads[name] = true
+				if _, seen := m.first[name]; !seen {
+					m.first[name] = n.Pos()
+				}
+			}
+		}
+		return true
+	})
+	return m
+}
+
+// isReceiverOf reports whether t is the named type of obj or a pointer to it
+func isReceiverOf(t types.Type, obj *types.TypeName) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Origin().Obj() == obj
+}
+
+// receiverStruct returns the struct type underlying a receiver type
+func receiverStruct(t types.Type) *types.Struct {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	st, _ := t.Underlying().(*types.Struct)
+	return st
+}
+
+// fieldAt returns the name of the i-th field of a receiver's struct type
+func fieldAt(recvType types.Type, i int) string {
+	st := receiverStruct(recvType)
+	if st == nil || i >= st.NumFields() {
+		return ""
+	}
+	return st.Field(i).Name()
+}
+
+// fieldType returns the type of a field of a receiver's struct type (nil if not found)
+func fieldType(recvType types.Type, name string) types.Type {
+	st := receiverStruct(recvType)
+	if st == nil {
+		return nil
+	}
+	for i := range st.NumFields() {
+		if st.Field(i).Name() == name {
+			return st.Field(i).Type()
+		}
+	}
+	return nil
+}
+
+// isMutex reports whether t is sync.Mutex, sync.RWMutex or a pointer to either
+func isMutex(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != "sync" {
+		return false
+	}
+	return named.Obj().Name() == "Mutex" || named.Obj().Name() == "RWMutex"
+}
+
+// String describes the access in one line, as the prompt lists it
+func (a FieldAccess) String() string {
+	var parts []string
+	if len(a.ReadBy) > 0 {
+		parts = append(parts, "read by "+strings.Join(a.ReadBy, ", "))
+	}
+	if len(a.WrittenBy) > 0 {
+		parts = append(parts, "written by "+strings.Join(a.WrittenBy, ", "))
+	}
+	if a.GuardedBy != "" {
+		parts = append(parts, "guarded by "+a.GuardedBy)
+	}
+	return a.Name + " " + a.Type + ": " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,89 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetReceiverState(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/cache\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cache.go"), []byte(`package cache
+
+import (
+	"strings"
+	"sync"
+)
+
+type Cache struct {
+	mu     sync.RWMutex
+	items  map[string]string
+	hits   int
+	name   string
+	log    strings.Builder
+	unused bool
+}
+
+func (c *Cache) Get(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.items[key]
+	if ok {
+		c.hits++
+	}
+	return v, ok
+}
+
+func (c *Cache) Set(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = value
+	c.log.WriteString(key)
+}
+
+func (c *Cache) Name() string {
+	return c.name
+}
+
+// mantra: Remove the entry
+func (c *Cache) Delete(key string) {
+	delete(c.items, key)
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	loader := NewPackageLoader(dir)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Failed to load package: %v", err)
+	}
+	state := loader.GetReceiverState("Cache", "Delete")
+	if state == nil {
+		t.Fatal("Expected receiver state for Cache")
+	}
+	if strings.Join(state.Mutexes, ",") != "mu" {
+		t.Errorf("Expected mutex mu, got %v", state.Mutexes)
+	}
+
+	var got []string
+	for _, field := range state.Fields {
+		got = append(got, field.String())
+	}
+	want := []string{
+		"items map[string]string: read by Get; written by Set; guarded by mu",
+		"hits int: written by Get; guarded by mu",
+		"name string: read by Name",
+		"log strings.Builder: written by Set; guarded by mu",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected fields:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if state := loader.GetReceiverState("Missing", ""); state != nil {
+		t.Errorf("Expected no state for an unknown type, got %+v", state)
+	}
+}
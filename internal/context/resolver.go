@@ -1,6 +1,7 @@
 package context
 
 import (
+	"context"
 	"fmt"
 	"go/types"
 	"strings"
@@ -11,17 +12,23 @@ import (
 // GetDeclaration returns information about any declaration
 // Supports both local declarations and qualified names (e.g., "time.Time")
 func (l *PackageLoader) GetDeclaration(name string) (Declaration, error) {
-	if err := l.Load(); err != nil {
+	return l.GetDeclarationContext(context.Background(), name)
+}
+
+// GetDeclarationContext is GetDeclaration with loading aborted when ctx is done.
+// Interface methods (e.g., "Repository.Get") resolve to their concrete implementations in the project
+func (l *PackageLoader) GetDeclarationContext(ctx context.Context, name string) (Declaration, error) {
+	if err := l.LoadContext(ctx); err != nil {
 		return nil, err
 	}
 
 	// Split by dots and resolve step by step
 	parts := strings.Split(name, ".")
-	return l.resolveQualifiedName(parts)
+	return l.resolveQualifiedName(ctx, parts)
 }
 
 // resolveQualifiedName resolves a qualified name step by step
-func (l *PackageLoader) resolveQualifiedName(parts []string) (Declaration, error) {
+func (l *PackageLoader) resolveQualifiedName(ctx context.Context, parts []string) (Declaration, error) {
 	if len(parts) == 0 {
 		return &NotFoundDeclaration{
 			baseDeclaration: baseDeclaration{
@@ -40,7 +47,7 @@ func (l *PackageLoader) resolveQualifiedName(parts []string) (Declaration, error
 			return l.createDeclarationFromObjectWithPackageAndPkg(obj, l.pkg.Name, l.pkg)
 		}
 		// Nested access (e.g., TypeName.FieldName)
-		return l.resolveNestedAccess(obj, parts[1:], l.pkg.Name)
+		return l.resolveNestedAccess(ctx, obj, parts[1:], l.pkg.Name)
 	}
 
 	// Check if it's an imported package
@@ -65,7 +72,28 @@ func (l *PackageLoader) resolveQualifiedName(parts []string) (Declaration, error
 				}, nil
 			}
 			// Try to resolve in imported package
-			return l.resolveInPackage(imp, parts[1:], imp.Name)
+			return l.resolveInPackage(ctx, imp, parts[1:], imp.Name)
+		}
+	}
+
+	// Finally, look in project packages the target does not import
+	if len(parts) > 1 {
+		pkgs, err := l.projectPackages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			if pkg.PkgPath == l.pkg.PkgPath || pkg.Name != parts[0] {
+				continue
+			}
+			decl, err := l.resolveInPackage(ctx, pkg, parts[1:], pkg.Name)
+			if err != nil || !decl.IsFound() {
+				continue
+			}
+			if decl.GetWarning() == "" {
+				setWarning(decl, fmt.Sprintf("Package %s is not imported by the target file; add the import %q to use it", pkg.Name, pkg.PkgPath))
+			}
+			return decl, nil
 		}
 	}
 
@@ -73,8 +101,23 @@ func (l *PackageLoader) resolveQualifiedName(parts []string) (Declaration, error
 	return &NotFoundDeclaration{baseDeclaration: baseDeclaration{Name: strings.Join(parts, "."), Kind: "not_found", Found: false}}, nil
 }
 
+// setWarning attaches a usage warning to decl
+func setWarning(decl Declaration, warning string) {
+	if w, ok := decl.(interface{ setWarning(string) }); ok {
+		w.setWarning(warning)
+	}
+}
+
+// isProjectPackage reports whether pkg belongs to the same module as the target package
+func (l *PackageLoader) isProjectPackage(pkg *packages.Package) bool {
+	if l.pkg.Module == nil || pkg.Module == nil {
+		return false
+	}
+	return pkg.Module.Path == l.pkg.Module.Path
+}
+
 // resolveInPackage resolves a name within a specific package
-func (l *PackageLoader) resolveInPackage(pkg *packages.Package, parts []string, pkgName string) (Declaration, error) {
+func (l *PackageLoader) resolveInPackage(ctx context.Context, pkg *packages.Package, parts []string, pkgName string) (Declaration, error) {
 	if len(parts) == 0 || pkg.Types == nil || pkg.Types.Scope() == nil {
 		return &NotFoundDeclaration{
 			baseDeclaration: baseDeclaration{
@@ -96,16 +139,39 @@ func (l *PackageLoader) resolveInPackage(pkg *packages.Package, parts []string,
 		}, nil
 	}
 
+	// Unexported names of project packages can still inform the implementation;
+	// those of dependencies are not worth the noise
+	if !obj.Exported() && !l.isProjectPackage(pkg) {
+		return &NotFoundDeclaration{
+			baseDeclaration: baseDeclaration{
+				Name:  strings.Join(parts, "."),
+				Kind:  "not_found",
+				Found: false,
+			},
+			Error: fmt.Sprintf("'%s' is unexported in dependency package %s", parts[0], pkg.PkgPath),
+		}, nil
+	}
+
+	var decl Declaration
+	var err error
 	if len(parts) == 1 {
-		return l.createDeclarationFromObjectWithPackageAndPkg(obj, pkgName, pkg)
+		decl, err = l.createDeclarationFromObjectWithPackageAndPkg(obj, pkgName, pkg)
+	} else {
+		// Nested access within the imported package
+		decl, err = l.resolveNestedAccess(ctx, obj, parts[1:], pkgName)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Nested access within the imported package
-	return l.resolveNestedAccess(obj, parts[1:], pkgName)
+	if !obj.Exported() && decl.IsFound() {
+		setWarning(decl, fmt.Sprintf("'%s' is unexported in package %s and cannot be referenced from package %s; use it only as a pattern for the implementation", parts[0], pkg.PkgPath, l.pkg.Name))
+	}
+	return decl, nil
 }
 
 // resolveNestedAccess resolves nested field/method access
-func (l *PackageLoader) resolveNestedAccess(obj types.Object, parts []string, pkgName string) (Declaration, error) {
+func (l *PackageLoader) resolveNestedAccess(ctx context.Context, obj types.Object, parts []string, pkgName string) (Declaration, error) {
 	if len(parts) == 0 {
 		return l.createDeclarationFromObjectWithPackage(obj, pkgName)
 	}
@@ -123,6 +189,11 @@ func (l *PackageLoader) resolveNestedAccess(obj types.Object, parts []string, pk
 		return &NotFoundDeclaration{baseDeclaration: baseDeclaration{Name: obj.Name() + "." + strings.Join(parts, "."), Kind: "not_found", Found: false}}, nil
 	}
 
+	// Interface methods are answered with their concrete implementations
+	if tn, ok := obj.(*types.TypeName); ok && len(parts) == 1 && types.IsInterface(typ) {
+		return l.interfaceMethod(ctx, tn, parts[0], pkgName)
+	}
+
 	// For each remaining part, try to find it as a field or method
 	for i, part := range parts {
 		// Dereference pointer types
@@ -177,7 +248,7 @@ func (l *PackageLoader) createDeclarationFromObjectWithPackageAndPkg(obj types.O
 	case *types.Func:
 		return l.getFunctionDeclarationWithPackage(o, pkgName)
 	case *types.Const:
-		return l.getConstantDeclarationWithPackage(o, pkgName)
+		return l.getConstantDeclarationWithPackage(o, pkgName, pkg)
 	case *types.Var:
 		return l.getVariableDeclarationWithPackage(o, pkgName)
 	default:
@@ -189,6 +260,10 @@ func (l *PackageLoader) createDeclarationFromObjectWithPackageAndPkg(obj types.O
 func (l *PackageLoader) getTypeDeclarationWithPackageAndPkg(obj *types.TypeName, pkgName string, pkg *packages.Package) (Declaration, error) {
 	typ := obj.Type()
 
+	if obj.IsAlias() {
+		return l.getAliasDeclaration(obj, pkgName, pkg), nil
+	}
+
 	switch t := typ.Underlying().(type) {
 	case *types.Struct:
 		result := &StructDeclaration{
@@ -206,7 +281,7 @@ func (l *PackageLoader) getTypeDeclarationWithPackageAndPkg(obj *types.TypeName,
 			field := t.Field(i)
 			result.Fields = append(result.Fields, FieldInfo{
 				Name: field.Name(),
-				Type: l.simplifyFieldTypeName(field.Type().String()),
+				Type: l.typeString(field.Type()),
 				Tag:  t.Tag(i),
 			})
 		}
@@ -259,7 +334,8 @@ func (l *PackageLoader) getTypeDeclarationWithPackageAndPkg(obj *types.TypeName,
 		return result, nil
 
 	default:
-		// Type alias or basic type definition
+		// Defined type of a basic, slice, map or function type
+		underlying := l.typeString(t)
 		result := &TypeAliasDeclaration{
 			baseDeclaration: baseDeclaration{
 				Name:    obj.Name(),
@@ -267,8 +343,9 @@ func (l *PackageLoader) getTypeDeclarationWithPackageAndPkg(obj *types.TypeName,
 				Package: pkgName,
 				Found:   true,
 			},
-			Type:       t.String(),
-			Definition: fmt.Sprintf("type %s %s", obj.Name(), t),
+			Type:       underlying,
+			Definition: fmt.Sprintf("type %s %s", obj.Name(), underlying),
+			Methods:    l.extractMethodsForDeclarationWithDoc(typ, pkg, obj.Name()),
 		}
 
 		// Attach documentation if available
@@ -280,6 +357,40 @@ func (l *PackageLoader) getTypeDeclarationWithPackageAndPkg(obj *types.TypeName,
 	}
 }
 
+// getAliasDeclaration describes an alias (type A = B) by the type it is declared as. Methods
+// are those of the type its chain of aliases ends in, documented where that type is declared
+func (l *PackageLoader) getAliasDeclaration(obj *types.TypeName, pkgName string, pkg *packages.Package) Declaration {
+	declared := l.typeString(aliasedType(obj))
+	result := &TypeAliasDeclaration{
+		baseDeclaration: baseDeclaration{
+			Name:    obj.Name(),
+			Kind:    "alias",
+			Package: pkgName,
+			Found:   true,
+		},
+		Type:       declared,
+		Definition: fmt.Sprintf("type %s = %s", obj.Name(), declared),
+	}
+
+	if resolved := l.typeString(types.Unalias(obj.Type())); resolved != declared {
+		result.Resolved = resolved
+	}
+	if named, ok := types.Unalias(obj.Type()).(*types.Named); ok {
+		var docPkg *packages.Package
+		if pkg != nil && named.Obj().Pkg() == pkg.Types {
+			docPkg = pkg
+		}
+		result.Methods = l.extractMethodsForDeclarationWithDoc(named, docPkg, named.Obj().Name())
+	}
+
+	// Attach documentation if available
+	if pkg != nil {
+		l.attachDocumentation(result, obj.Name(), pkg)
+	}
+
+	return result
+}
+
 // getFunctionDeclarationWithPackage creates a function declaration
 func (l *PackageLoader) getFunctionDeclarationWithPackage(obj *types.Func, pkgName string) (Declaration, error) {
 	sig := obj.Type().(*types.Signature)
@@ -309,8 +420,9 @@ func (l *PackageLoader) getFunctionDeclarationWithPackage(obj *types.Func, pkgNa
 	return result, nil
 }
 
-// getConstantDeclarationWithPackage creates a constant declaration
-func (l *PackageLoader) getConstantDeclarationWithPackage(obj *types.Const, pkgName string) (Declaration, error) {
+// getConstantDeclarationWithPackage creates a constant declaration, with the whole const
+// block it is declared in when it has other members
+func (l *PackageLoader) getConstantDeclarationWithPackage(obj *types.Const, pkgName string, pkg *packages.Package) (Declaration, error) {
 	result := &ConstantDeclaration{
 		baseDeclaration: baseDeclaration{
 			Name:    obj.Name(),
@@ -318,10 +430,19 @@ func (l *PackageLoader) getConstantDeclarationWithPackage(obj *types.Const, pkgN
 			Package: pkgName,
 			Found:   true,
 		},
-		Type:  obj.Type().String(),
+		Type:  l.typeString(obj.Type()),
 		Value: obj.Val().String(),
 	}
 
+	if block := l.constBlockOf(pkg, obj.Name()); block != nil && len(block.Names) > 1 {
+		result.Group = block.Definition
+	}
+
+	// Attach documentation if available
+	if pkg != nil {
+		l.attachDocumentation(result, obj.Name(), pkg)
+	}
+
 	return result, nil
 }
 
@@ -0,0 +1,117 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetDeclarationAliases(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "shop.go"), []byte(`package shop
+
+// Status is the state of an order
+type Status int
+
+// String names the status
+func (s Status) String() string { return "" }
+
+type Customer struct {
+	Name string
+}
+
+// Greet greets the customer
+func (c *Customer) Greet() string { return c.Name }
+
+// Buyer is a customer placing an order
+type Buyer = Customer
+
+// Client is kept for compatibility
+type Client = Buyer
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	loader := NewPackageLoader(dir)
+
+	decl, err := loader.GetDeclaration("Client")
+	if err != nil {
+		t.Fatalf("Failed to get declaration: %v", err)
+	}
+	alias, ok := decl.(*TypeAliasDeclaration)
+	if !ok {
+		t.Fatalf("Expected an alias declaration, got %T", decl)
+	}
+	if alias.Kind != "alias" || alias.Definition != "type Client = Buyer" || alias.Type != "Buyer" || alias.Resolved != "Customer" {
+		t.Errorf("Expected Client declared as Buyer and resolved to Customer, got %+v", alias)
+	}
+	if alias.Doc != "Client is kept for compatibility\n" {
+		t.Errorf("Expected the alias's doc, got %q", alias.Doc)
+	}
+	if len(alias.Methods) != 1 || alias.Methods[0].Name != "Greet" || alias.Methods[0].Doc != "Greet greets the customer\n" {
+		t.Errorf("Expected the documented methods of Customer, got %+v", alias.Methods)
+	}
+
+	decl, err = loader.GetDeclaration("Status")
+	if err != nil {
+		t.Fatalf("Failed to get declaration: %v", err)
+	}
+	defined, ok := decl.(*TypeAliasDeclaration)
+	if !ok {
+		t.Fatalf("Expected a type declaration, got %T", decl)
+	}
+	if defined.Kind != "type" || defined.Definition != "type Status int" || defined.Resolved != "" {
+		t.Errorf("Expected Status defined as int, got %+v", defined)
+	}
+	if len(defined.Methods) != 1 || defined.Methods[0].Signature != "String() string" || defined.Methods[0].Doc != "String names the status\n" {
+		t.Errorf("Expected the documented methods of Status, got %+v", defined.Methods)
+	}
+}
+
+func TestGetDeclarationConstGroup(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "shop.go"), []byte(`package shop
+
+type Level uint8
+
+const (
+	// Debug is the most verbose level
+	Debug Level = iota + 1
+	Info
+	Warn
+)
+
+const limit = 3
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	loader := NewPackageLoader(dir)
+
+	decl, err := loader.GetDeclaration("Info")
+	if err != nil {
+		t.Fatalf("Failed to get declaration: %v", err)
+	}
+	constant, ok := decl.(*ConstantDeclaration)
+	if !ok {
+		t.Fatalf("Expected a constant declaration, got %T", decl)
+	}
+	if constant.Type != "Level" || constant.Value != "2" {
+		t.Errorf("Expected Info of type Level and value 2, got %+v", constant)
+	}
+	if want := "const (\n    Debug Level = 1\n    Info Level = 2\n    Warn Level = 3\n)"; constant.Group != want {
+		t.Errorf("Expected group %q, got %q", want, constant.Group)
+	}
+
+	decl, err = loader.GetDeclaration("limit")
+	if err != nil {
+		t.Fatalf("Failed to get declaration: %v", err)
+	}
+	if constant := decl.(*ConstantDeclaration); constant.Group != "" || constant.Type != "untyped int" {
+		t.Errorf("Expected a lone untyped constant without group, got %+v", constant)
+	}
+}
@@ -11,10 +11,10 @@ import (
 // TypeInfo holds complete information about a type
 type TypeInfo struct {
 	Name       string
-	Kind       string // "struct", "interface", "type"
+	Kind       string // "struct", "interface", "type", "alias"
 	Package    string
 	Definition string
-	Type       string // For type aliases
+	Type       string // For defined types the underlying type, for aliases the declared one
 	Fields     []analysis.FieldInfo
 	Methods    []analysis.MethodInfo
 }
@@ -72,18 +72,33 @@ func (l *PackageLoader) GetAllTypes() (map[string]*TypeInfo, error) {
 func (l *PackageLoader) extractTypeDetails(obj *types.TypeName, info *TypeInfo) {
 	typ := obj.Type()
 
+	if obj.IsAlias() {
+		// Aliases keep the name they are declared with; the aliased type is expanded on its own
+		info.Kind = "alias"
+		info.Type = l.typeString(aliasedType(obj))
+		info.Definition = fmt.Sprintf("type %s = %s", obj.Name(), info.Type)
+		if named, ok := types.Unalias(typ).(*types.Named); ok {
+			info.Methods = l.extractMethods(named)
+		}
+		return
+	}
+
 	switch underlying := typ.Underlying().(type) {
 	case *types.Struct:
 		info.Kind = "struct"
 		info.Definition = fmt.Sprintf("type %s struct", obj.Name())
 
-		// Extract fields
+		// Extract fields; embedded fields are written without a name
 		for i := 0; i < underlying.NumFields(); i++ {
 			field := underlying.Field(i)
-			info.Fields = append(info.Fields, analysis.FieldInfo{
+			fieldInfo := analysis.FieldInfo{
 				Name: field.Name(),
-				Type: field.Type().String(),
-			})
+				Type: l.typeString(field.Type()),
+			}
+			if field.Embedded() {
+				fieldInfo.Name = ""
+			}
+			info.Fields = append(info.Fields, fieldInfo)
 		}
 
 		// Extract methods
@@ -105,57 +120,87 @@ func (l *PackageLoader) extractTypeDetails(obj *types.TypeName, info *TypeInfo)
 
 	default:
 		info.Kind = "type"
-		info.Type = underlying.String()
-		info.Definition = fmt.Sprintf("type %s %s", obj.Name(), underlying)
+		info.Type = l.typeString(underlying)
+		info.Definition = fmt.Sprintf("type %s %s", obj.Name(), info.Type)
+
+		// Extract methods of named basic, slice, map and function types
+		info.Methods = l.extractMethods(typ)
+	}
+}
+
+// aliasedType returns the type an alias is declared as, which may itself be an alias,
+// rather than the type its chain of aliases ends in
+func aliasedType(obj *types.TypeName) types.Type {
+	if alias, ok := obj.Type().(*types.Alias); ok {
+		return alias.Rhs()
 	}
+	return obj.Type()
 }
 
-// extractMethods gets all methods for a type
+// extractMethods gets all methods callable on a type, including those promoted from embedded
+// fields. Unexported methods promoted from other packages are left out as they cannot be called
 func (l *PackageLoader) extractMethods(typ types.Type) []analysis.MethodInfo {
 	var methods []analysis.MethodInfo
 
-	// Get method set for both value and pointer types
+	// The pointer method set includes every method; the value method set tells value receivers
 	mset := types.NewMethodSet(typ)
 	ptrMset := types.NewMethodSet(types.NewPointer(typ))
+	receiver := strings.TrimPrefix(l.typeString(typ), "*")
 
-	seen := make(map[string]bool)
-
-	// Add methods from pointer type (includes all methods)
 	for i := 0; i < ptrMset.Len(); i++ {
-		method := ptrMset.At(i).Obj().(*types.Func)
+		selection := ptrMset.At(i)
+		method := selection.Obj().(*types.Func)
+		promoted := len(selection.Index()) > 1
+		if promoted && !method.Exported() && l.pkg != nil && method.Pkg() != l.pkg.Types {
+			continue
+		}
 		sig := method.Type().(*types.Signature)
 
 		methodInfo := analysis.MethodInfo{
 			Name:      method.Name(),
 			Signature: l.formatSignature(method.Name(), sig),
-			Receiver:  "*" + strings.TrimPrefix(typ.String(), "*"),
+			Receiver:  "*" + receiver,
 		}
-
-		// Check if it's a value receiver method
-		if i < mset.Len() {
-			valueMethod := mset.At(i).Obj().(*types.Func)
-			if valueMethod.Name() == method.Name() {
-				methodInfo.Receiver = l.simplifyTypeName(strings.TrimPrefix(typ.String(), "*"))
-			}
+		if mset.Lookup(method.Pkg(), method.Name()) != nil {
+			methodInfo.Receiver = receiver
 		}
-
-		if !seen[method.Name()] {
-			methods = append(methods, methodInfo)
-			seen[method.Name()] = true
+		if promoted {
+			methodInfo.Promoted = l.typeString(embeddedFieldType(typ, selection.Index()))
 		}
+		methods = append(methods, methodInfo)
 	}
 
 	return methods
 }
 
-// formatSignature formats a function/method signature
+// embeddedFieldType returns the type of the field of typ, embedded directly, that a
+// method selected by index is promoted through
+func embeddedFieldType(typ types.Type, index []int) types.Type {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if st, ok := typ.Underlying().(*types.Struct); ok && index[0] < st.NumFields() {
+		return st.Field(index[0]).Type()
+	}
+	return typ
+}
+
+// formatSignature formats a function/method signature as it is written in Go source:
+// types are qualified by package name, variadic parameters use ...T and result names are kept
 func (l *PackageLoader) formatSignature(name string, sig *types.Signature) string {
+	qualifier := l.qualifier
+
 	// Parameters
 	params := sig.Params()
 	var paramStrs []string
 	for i := 0; i < params.Len(); i++ {
 		param := params.At(i)
-		paramStr := param.Type().String()
+		paramStr := types.TypeString(param.Type(), qualifier)
+		if sig.Variadic() && i == params.Len()-1 {
+			if slice, ok := param.Type().(*types.Slice); ok {
+				paramStr = "..." + types.TypeString(slice.Elem(), qualifier)
+			}
+		}
 		if param.Name() != "" {
 			paramStr = param.Name() + " " + paramStr
 		}
@@ -165,19 +210,72 @@ func (l *PackageLoader) formatSignature(name string, sig *types.Signature) strin
 	// Results
 	results := sig.Results()
 	var resultStrs []string
+	named := false
 	for i := 0; i < results.Len(); i++ {
 		result := results.At(i)
-		resultStrs = append(resultStrs, result.Type().String())
+		resultStr := types.TypeString(result.Type(), qualifier)
+		if result.Name() != "" {
+			resultStr = result.Name() + " " + resultStr
+			named = true
+		}
+		resultStrs = append(resultStrs, resultStr)
 	}
 
-	// Format signature
+	// Format signature; named results always need parentheses
 	signatureStr := name + "(" + strings.Join(paramStrs, ", ") + ")"
 
-	if len(resultStrs) == 1 {
+	if len(resultStrs) == 1 && !named {
 		signatureStr += " " + resultStrs[0]
-	} else if len(resultStrs) > 1 {
+	} else if len(resultStrs) > 0 {
 		signatureStr += " (" + strings.Join(resultStrs, ", ") + ")"
 	}
 
 	return signatureStr
 }
+
+// GetConstructorInfo describes the fields and functional option types of the type built by a constructor.
+// Returns nil if the package is not loaded or the type is not found
+func (l *PackageLoader) GetConstructorInfo(typeName string) *ConstructorInfo {
+	if l.pkg == nil || l.pkg.Types == nil {
+		return nil
+	}
+
+	obj, ok := l.pkg.Types.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+
+	info := &ConstructorInfo{TypeName: typeName}
+
+	if st, ok := obj.Type().Underlying().(*types.Struct); ok {
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			info.Fields = append(info.Fields, analysis.FieldInfo{
+				Name: field.Name(),
+				Type: l.typeString(field.Type()),
+			})
+		}
+	}
+
+	// Functional options are package types of the form func(*T) or func(*T) error
+	scope := l.pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		optObj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		sig, ok := optObj.Type().Underlying().(*types.Signature)
+		if !ok || sig.Params().Len() != 1 {
+			continue
+		}
+		paramType := sig.Params().At(0).Type()
+		if ptr, ok := paramType.(*types.Pointer); ok {
+			paramType = ptr.Elem()
+		}
+		if types.Identical(paramType, obj.Type()) {
+			info.Options = append(info.Options, fmt.Sprintf("%s (%s)", name, types.TypeString(sig, types.RelativeTo(l.pkg.Types))))
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,87 @@
+package conventions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultFile is the conventions file looked up next to mantra.toml
+const DefaultFile = ".mantra/conventions.toml"
+
+// Conventions describes project coding conventions enforced on generated code
+type Conventions struct {
+	Errors      ErrorConventions       `toml:"errors"`
+	Concurrency ConcurrencyConventions `toml:"concurrency"`
+	Complexity  ComplexityConventions  `toml:"complexity"`
+	Injection   InjectionConventions   `toml:"injection"`
+}
+
+// ErrorConventions describes how generated code should construct and return errors
+type ErrorConventions struct {
+	RequireWrap       bool   `toml:"require_wrap"`       // fmt.Errorf with an error argument must wrap it with %w
+	LowercaseMessages bool   `toml:"lowercase_messages"` // Error strings must not start with a capital letter
+	ReuseSentinels    bool   `toml:"reuse_sentinels"`    // Reuse package sentinel errors instead of recreating their messages
+	Style             string `toml:"style"`              // How functions returning an error report failures: ErrorStyleSentinel, ErrorStyleWrap or ErrorStyleJoin (empty: any)
+}
+
+// Error styles of functions whose last result is an error
+const (
+	ErrorStyleSentinel = "sentinel" // Return package-level sentinel errors, or wrap one with %w; never create errors inline
+	ErrorStyleWrap     = "wrap"     // Wrap errors from calls with context via fmt.Errorf("...: %w", err) instead of returning them as is
+	ErrorStyleJoin     = "join"     // Combine several errors with errors.Join instead of fmt.Errorf with multiple errors
+)
+
+// ConcurrencyConventions describes how generated methods of types with mutexes must lock
+type ConcurrencyConventions struct {
+	BalancedLocks bool `toml:"balanced_locks"` // Every Lock/RLock in a body needs an Unlock/RUnlock of the same mutex, and vice versa
+	GuardedFields bool `toml:"guarded_fields"` // Fields the other methods access under a mutex must be accessed under it
+}
+
+// ComplexityConventions sets budgets generated bodies must stay within; function literals in a
+// body are measured on their own
+type ComplexityConventions struct {
+	MaxCyclomatic int `toml:"max_cyclomatic"` // Cyclomatic complexity of a body (0: no budget)
+	MaxLines      int `toml:"max_lines"`      // Lines of a formatted body (0: no budget)
+}
+
+// InjectionConventions keeps generated code testable when the receiver or the package provides
+// an injectable clock or random source: reading the real one directly is rejected
+type InjectionConventions struct {
+	Clock  bool `toml:"clock"`  // Forbid time.Now, time.Since and time.Until when a clock can be used instead
+	Random bool `toml:"random"` // Forbid the global math/rand functions when a random generator can be used instead
+}
+
+// Default returns the conventions used when no conventions file exists: none is enforced,
+// so each check is opted into by the conventions file
+func Default() *Conventions {
+	return &Conventions{}
+}
+
+// Load reads conventions from path. Settings omitted from the file keep their defaults.
+// If the file does not exist and required is false, the defaults are returned
+func Load(path string, required bool) (*Conventions, error) {
+	conv := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return conv, nil
+		}
+		return nil, fmt.Errorf("failed to read conventions file: %w", err)
+	}
+
+	if _, err := toml.Decode(string(data), conv); err != nil {
+		return nil, fmt.Errorf("failed to parse conventions file: %w", err)
+	}
+	switch conv.Errors.Style {
+	case "", ErrorStyleSentinel, ErrorStyleWrap, ErrorStyleJoin:
+	default:
+		return nil, fmt.Errorf("invalid errors.style %q in conventions file (expected %s, %s or %s)",
+			conv.Errors.Style, ErrorStyleSentinel, ErrorStyleWrap, ErrorStyleJoin)
+	}
+
+	return conv, nil
+}
@@ -0,0 +1,172 @@
+package deps
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Index maps package-level declaration names to their source text.
+// Methods are indexed by method name so that selector calls (r.helper()) resolve.
+type Index struct {
+	decls map[string][]string
+}
+
+// BuildIndex parses all non-test Go files in packageDir and indexes their declarations
+func BuildIndex(packageDir string) (*Index, error) {
+	files, err := filepath.Glob(filepath.Join(packageDir, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob files: %w", err)
+	}
+	sort.Strings(files)
+
+	index := &Index{decls: make(map[string][]string)}
+	fset := token.NewFileSet()
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		node, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+
+		for _, decl := range node.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				index.add(d.Name.Name, fset, d)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						index.add(s.Name.Name, fset, s)
+					case *ast.ValueSpec:
+						for _, name := range s.Names {
+							index.add(name.Name, fset, s)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// add records the source text of a declaration under the given name
+func (idx *Index) add(name string, fset *token.FileSet, node ast.Node) {
+	if name == "_" {
+		return
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, node); err != nil {
+		return
+	}
+	idx.decls[name] = append(idx.decls[name], buf.String())
+}
+
+// Fingerprint returns a hash over the declarations referenced by body.
+// The target's own name is excluded since its signature is covered by the target checksum.
+// Returns an empty string if the body references no indexed declarations.
+func (idx *Index) Fingerprint(body string, targetName string) string {
+	if idx == nil {
+		return ""
+	}
+
+	names := idx.Referenced(body)
+	h := fnv.New32a()
+	count := 0
+	for _, name := range names {
+		if name == targetName {
+			continue
+		}
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		for _, text := range idx.decls[name] {
+			h.Write([]byte(text))
+			h.Write([]byte{0})
+		}
+		count++
+	}
+
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// FingerprintNames returns a hash over the declarations of the given names. Qualified names
+// like "Cart.Total" or "time.Time" cover each of their parts that is declared in the package
+func (idx *Index) FingerprintNames(names []string) string {
+	h := fnv.New32a()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		if idx == nil {
+			continue
+		}
+		for _, part := range strings.Split(name, ".") {
+			for _, text := range idx.decls[part] {
+				h.Write([]byte(text))
+				h.Write([]byte{0})
+			}
+		}
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// Referenced returns the sorted names of indexed declarations referenced by body
+func (idx *Index) Referenced(body string) []string {
+	if idx == nil {
+		return nil
+	}
+
+	var names []string
+	for name := range References(body) {
+		if _, ok := idx.decls[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// References returns every identifier used in a function body, including selector names
+func References(body string) map[string]bool {
+	refs := make(map[string]bool)
+
+	src := "package p\nfunc _() {\n" + body + "\n}"
+	node, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return refs
+	}
+
+	if len(node.Decls) == 0 {
+		return refs
+	}
+	fn, ok := node.Decls[0].(*ast.FuncDecl)
+	if !ok || fn.Body == nil {
+		return refs
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.Ident:
+			refs[x.Name] = true
+		case *ast.SelectorExpr:
+			refs[x.Sel.Name] = true
+		}
+		return true
+	})
+
+	return refs
+}
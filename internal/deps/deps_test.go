@@ -0,0 +1,89 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprintTracksReferencedDeclarations(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "pkg.go")
+
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	base := `package pkg
+
+func helper(x int) int { return x * 2 }
+
+func unrelated() {}
+
+// mantra: double the input using helper
+func Target(x int) int {
+	panic("not implemented")
+}
+`
+	body := "return helper(x)"
+
+	write(base)
+	index, err := BuildIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	original := index.Fingerprint(body, "Target")
+	if original == "" {
+		t.Fatal("Expected a fingerprint for a body referencing helper")
+	}
+
+	// Changing an unrelated declaration keeps the fingerprint stable
+	write(base + "\nfunc another() {}\n")
+	index, _ = BuildIndex(tmpDir)
+	if got := index.Fingerprint(body, "Target"); got != original {
+		t.Errorf("Fingerprint changed after unrelated edit: %s != %s", got, original)
+	}
+
+	// Changing the helper changes the fingerprint
+	write(`package pkg
+
+func helper(x int) int { return x * 3 }
+
+func unrelated() {}
+`)
+	index, _ = BuildIndex(tmpDir)
+	if got := index.Fingerprint(body, "Target"); got == original {
+		t.Error("Expected fingerprint to change after editing helper")
+	}
+
+	// Bodies without project references have no fingerprint
+	if got := index.Fingerprint("return x + 1", "Target"); got != "" {
+		t.Errorf("Expected empty fingerprint, got %s", got)
+	}
+}
+
+func TestFingerprintNamesTracksNamedDeclarations(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "pkg.go")
+	write := func(content string) *Index {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		index, err := BuildIndex(tmpDir)
+		if err != nil {
+			t.Fatalf("BuildIndex failed: %v", err)
+		}
+		return index
+	}
+	names := []string{"Cart", "Cart.Total"}
+
+	original := write("package pkg\n\ntype Cart struct{ items []int }\n\nfunc unrelated() {}\n").FingerprintNames(names)
+	if got := write("package pkg\n\ntype Cart struct{ items []int }\n\nfunc unrelated() { _ = 1 }\n").FingerprintNames(names); got != original {
+		t.Errorf("Fingerprint changed after unrelated edit: %s != %s", got, original)
+	}
+	if got := write("package pkg\n\ntype Cart struct{ items []string }\n\nfunc unrelated() {}\n").FingerprintNames(names); got == original {
+		t.Error("Expected fingerprint to change after editing Cart")
+	}
+}
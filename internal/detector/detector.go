@@ -9,7 +9,10 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/rail44/mantra/internal/analysis"
 	"github.com/rail44/mantra/internal/checksum"
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/deps"
 	"github.com/rail44/mantra/internal/parser"
 )
 
@@ -22,6 +25,19 @@ const (
 	StatusCurrent                   // Generated and up-to-date
 )
 
+// String returns the status as shown to users
+func (s Status) String() string {
+	switch s {
+	case StatusUngenerated:
+		return "ungenerated"
+	case StatusOutdated:
+		return "outdated"
+	case StatusCurrent:
+		return "current"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
 // FileDetectionResult represents detection results for a single file.
 // It includes both the file information and any mantra targets found within it.
 // Files without mantra targets will have an empty Statuses slice, but still
@@ -35,13 +51,21 @@ type FileDetectionResult struct {
 type TargetStatus struct {
 	Target           *parser.Target
 	Status           Status
-	CurrentChecksum  string // Checksum of current declaration
-	ExistingChecksum string // Checksum found in generated file (if any)
-	ExistingImpl     string // Existing implementation (if checksum matches)
+	CurrentChecksum  string                // Checksum of current declaration
+	ExistingChecksum string                // Checksum found in generated file (if any)
+	ExistingImpl     string                // Existing implementation (if checksum matches)
+	ExistingHelpers  []parser.Helper       // Helper functions of the existing implementation
+	Reason           string                // Why the target is outdated (empty for checksum changes)
+	PreviousFailure  *parser.FailureReason // Failure recorded by the last generation (nil if it succeeded)
 }
 
 // DetectPackageTargets analyzes all Go files in a package directory and returns detection results for all files
 func DetectPackageTargets(packageDir string, generatedDir string) ([]*FileDetectionResult, error) {
+	return DetectPackageTargetsWithOptions(packageDir, generatedDir, Options{})
+}
+
+// DetectPackageTargetsWithOptions is DetectPackageTargets with optional checks enabled
+func DetectPackageTargetsWithOptions(packageDir string, generatedDir string, opts Options) ([]*FileDetectionResult, error) {
 	// Find all Go files in the package
 	files, err := filepath.Glob(filepath.Join(packageDir, "*.go"))
 	if err != nil {
@@ -50,6 +74,12 @@ func DetectPackageTargets(packageDir string, generatedDir string) ([]*FileDetect
 
 	var allResults []*FileDetectionResult
 
+	// Index package declarations to detect changes in dependencies of generated code
+	depIndex, err := deps.BuildIndex(packageDir)
+	if err != nil {
+		depIndex = nil // Dependency tracking is best-effort
+	}
+
 	// Process each source file
 	for _, sourceFile := range files {
 		// Skip test files
@@ -75,6 +105,28 @@ func DetectPackageTargets(packageDir string, generatedDir string) ([]*FileDetect
 			}
 		}
 
+		// Targets written to files of their own take precedence
+		for _, target := range fileInfo.Targets {
+			targetFile := filepath.Join(generatedDir, codegen.TargetFileName(sourceFile, target))
+			if impls, err := extractImplementationsFromFile(targetFile); err == nil {
+				if impl, ok := impls[target.Name]; ok {
+					existingImplementations[target.Name] = impl
+				}
+			}
+		}
+
+		// Test functions of targets marked with // mantra:bench or // mantra:fuzz
+		testFuncs := make(map[string]bool)
+		for _, kind := range codegen.TestKinds {
+			funcs, err := codegen.ExistingTestFuncs(filepath.Join(generatedDir, kind.FileName(sourceFile)), kind)
+			if err != nil {
+				continue // A broken test file is regenerated
+			}
+			for name := range funcs {
+				testFuncs[name] = true
+			}
+		}
+
 		// Create FileDetectionResult for this file
 		fileResult := &FileDetectionResult{
 			FileInfo: fileInfo,
@@ -90,14 +142,29 @@ func DetectPackageTargets(packageDir string, generatedDir string) ([]*FileDetect
 			var status Status
 			var existingChecksum string
 			var existingBody string
+			var existingHelpers []parser.Helper
+			var reason string
+			var previousFailure *parser.FailureReason
 
 			if exists {
-				existingChecksum = existingImpl.Checksum
-				if existingChecksum == currentChecksum {
+				previousFailure = existingImpl.Failure
+			}
+			if exists && existingImpl.Checksum.Hash != "" {
+				existingChecksum = existingImpl.Checksum.Hash
+				// Checksums of earlier formats are compared with the hash they were written
+				// with; the comment is rewritten in the current format on the next generation
+				if !checksum.Matches(target, existingImpl.Checksum) {
+					status = StatusOutdated
+				} else if changed := dependenciesChanged(depIndex, existingImpl, target.Name); changed != "" {
+					status = StatusOutdated
+					reason = changed
+				} else if missing := missingTestFunc(target, testFuncs); missing != "" {
+					status = StatusOutdated
+					reason = missing + " not generated yet"
+				} else {
 					status = StatusCurrent
 					existingBody = existingImpl.Body
-				} else {
-					status = StatusOutdated
+					existingHelpers = existingImpl.Helpers
 				}
 			} else {
 				status = StatusUngenerated
@@ -109,6 +176,9 @@ func DetectPackageTargets(packageDir string, generatedDir string) ([]*FileDetect
 				CurrentChecksum:  currentChecksum,
 				ExistingChecksum: existingChecksum,
 				ExistingImpl:     existingBody,
+				ExistingHelpers:  existingHelpers,
+				Reason:           reason,
+				PreviousFailure:  previousFailure,
 			})
 		}
 
@@ -116,13 +186,53 @@ func DetectPackageTargets(packageDir string, generatedDir string) ([]*FileDetect
 		allResults = append(allResults, fileResult)
 	}
 
+	if opts.SignatureImpact {
+		if err := flagBrokenImplementations(packageDir, allResults); err != nil {
+			return nil, err
+		}
+	}
+
 	return allResults, nil
 }
 
+// missingTestFunc returns the kind of the first test function the target is marked for but
+// which is not in the generated test files (empty if none is missing)
+func missingTestFunc(target *parser.Target, existing map[string]bool) string {
+	for _, kind := range codegen.TestKinds {
+		if kind.Marked(target) && !existing[kind.FuncName(target)] {
+			return kind.Name
+		}
+	}
+	return ""
+}
+
+// dependenciesChanged reports whether declarations referenced by an existing implementation
+// changed since it was generated. Returns a human readable reason, or an empty string.
+func dependenciesChanged(depIndex *deps.Index, impl *ImplementationInfo, targetName string) string {
+	// Files generated before dependency tracking have no fingerprint to compare against
+	if depIndex == nil || impl.Deps == "" {
+		return ""
+	}
+
+	code := parser.WithHelperCode(impl.Body, impl.Helpers)
+	if depIndex.Fingerprint(code, targetName) == impl.Deps {
+		return ""
+	}
+
+	referenced := depIndex.Referenced(code)
+	if len(referenced) == 0 {
+		return "referenced declarations were removed"
+	}
+	return fmt.Sprintf("referenced declarations changed: %s", strings.Join(referenced, ", "))
+}
+
 // ImplementationInfo holds checksum and implementation for a function
 type ImplementationInfo struct {
-	Checksum string
+	Checksum checksum.Checksum
+	Deps     string                // Fingerprint of referenced declarations (empty if not recorded)
+	Failure  *parser.FailureReason // Recorded failure of a target that was not generated
 	Body     string
+	Helpers  []parser.Helper // Helper functions written below the function
 }
 
 // extractImplementationsFromFile parses a generated file and extracts function checksums and implementations
@@ -139,46 +249,99 @@ func extractImplementationsFromFile(filePath string) (map[string]*Implementation
 	}
 
 	implementations := make(map[string]*ImplementationInfo)
+	helpers := make(map[string][]parser.Helper) // By display name of the target
+	owners := make(map[*ImplementationInfo]string)
 
-	// Walk through all functions
-	ast.Inspect(node, func(n ast.Node) bool {
-		funcDecl, ok := n.(*ast.FuncDecl)
-		if !ok {
-			return true
+	// Walk through all functions and function variables
+	for _, funcDecl := range analysis.FuncDecls(node) {
+		if owner, helper, ok := extractHelper(string(content), funcDecl, fset); ok {
+			helpers[owner] = append(helpers[owner], helper)
+			continue
 		}
 
 		// Look for checksum comment immediately before function
 		funcPos := fset.Position(funcDecl.Pos())
-		var foundChecksum string
+		var foundChecksum checksum.Checksum
+		var foundDeps string
 		for _, commentGroup := range node.Comments {
 			commentPos := fset.Position(commentGroup.End())
 			// Check if comment is right before function (within 2 lines)
 			if commentPos.Line >= funcPos.Line-2 && commentPos.Line < funcPos.Line {
 				for _, comment := range commentGroup.List {
-					if cs := checksum.ExtractFromComment(comment.Text); cs != "" {
+					if cs, ok := checksum.ParseComment(comment.Text); ok {
 						foundChecksum = cs
-						break
+					}
+					if fp := checksum.ExtractDepsFromComment(comment.Text); fp != "" {
+						foundDeps = fp
 					}
 				}
 			}
 		}
 
+		// Failed targets record the reason and the rejected attempt in their doc comment
+		var foundFailure *parser.FailureReason
+		if funcDecl.Doc != nil {
+			lines := make([]string, len(funcDecl.Doc.List))
+			for i, comment := range funcDecl.Doc.List {
+				lines[i] = comment.Text
+			}
+			foundFailure = checksum.ParseFailureComments(lines)
+		}
+		if foundFailure != nil {
+			implementations[funcDecl.Name.Name] = &ImplementationInfo{Failure: foundFailure}
+		}
+
 		// If we found a checksum, extract the function body
-		if foundChecksum != "" {
+		if foundChecksum.Hash != "" {
 			// Get the function body without panic check
 			bodyContent := extractFunctionBody(string(content), funcDecl, fset)
-			implementations[funcDecl.Name.Name] = &ImplementationInfo{
+			impl := &ImplementationInfo{
 				Checksum: foundChecksum,
+				Deps:     foundDeps,
 				Body:     bodyContent,
 			}
+			implementations[funcDecl.Name.Name] = impl
+			owners[impl] = displayName(funcDecl)
 		}
 
-		return true
-	})
+	}
+
+	for impl, owner := range owners {
+		impl.Helpers = helpers[owner]
+	}
 
 	return implementations, nil
 }
 
+// extractHelper returns the helper function a declaration marked with a helper comment
+// holds, and the display name of the target it belongs to
+func extractHelper(source string, funcDecl *ast.FuncDecl, fset *token.FileSet) (string, parser.Helper, bool) {
+	if funcDecl.Doc == nil || funcDecl.Body == nil || funcDecl.Recv != nil {
+		return "", parser.Helper{}, false
+	}
+	for _, comment := range funcDecl.Doc.List {
+		owner, ok := checksum.ParseHelperComment(comment.Text)
+		if !ok {
+			continue
+		}
+		signature := source[fset.Position(funcDecl.Name.End()).Offset:fset.Position(funcDecl.Body.Lbrace).Offset]
+		return owner, parser.Helper{
+			Name:      funcDecl.Name.Name,
+			Signature: strings.TrimSpace(signature),
+			Body:      extractFunctionBody(source, funcDecl, fset),
+		}, true
+	}
+	return "", parser.Helper{}, false
+}
+
+// displayName returns the name of a function as Target.GetDisplayName formats it
+func displayName(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return funcDecl.Name.Name
+	}
+	return fmt.Sprintf("(%s).%s", analysis.ExtractTypeString(funcDecl.Recv.List[0].Type), funcDecl.Name.Name)
+}
+
 // extractFunctionBody extracts the body content of a function from source
 func extractFunctionBody(source string, funcDecl *ast.FuncDecl, fset *token.FileSet) string {
 	if funcDecl.Body == nil {
@@ -0,0 +1,171 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestPreviousFailure(t *testing.T) {
+	pkgDir := t.TempDir()
+	destDir := filepath.Join(pkgDir, "generated")
+	source := filepath.Join(pkgDir, "calc.go")
+	if err := os.WriteFile(source, []byte(`package calc
+
+// Double returns twice n.
+// mantra: Double the input
+func Double(n int) int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	reason := &parser.FailureReason{
+		Phase:   "implementation",
+		Message: "check_code reported errors:\ncalc.go:6:9: undefined: doubled",
+		Attempt: "if n == 0 {\n\treturn 0\n}\n\nreturn doubled(n)",
+	}
+	target := fileInfo.Targets[0]
+	target.GenerationFailed = true
+	target.FailureReason = reason
+	gen := codegen.New(&codegen.Config{Dest: destDir, PackageName: "generated", SourcePackage: "calc"})
+	results := []*parser.GenerationResult{{Target: target, FailureReason: reason}}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("Failed to generate file: %v", err)
+	}
+
+	detected, err := DetectPackageTargets(pkgDir, destDir)
+	if err != nil {
+		t.Fatalf("Detection failed: %v", err)
+	}
+	status := detected[0].Statuses[0]
+	if status.Status != StatusUngenerated {
+		t.Errorf("Expected failed target to be ungenerated, got %v", status.Status)
+	}
+	got := status.PreviousFailure
+	if got == nil {
+		t.Fatal("Expected the recorded failure")
+	}
+	if got.Phase != "implementation" || got.Message != "check_code reported errors: calc.go:6:9: undefined: doubled" {
+		t.Errorf("Expected the failure reason on one line, got %q: %q", got.Phase, got.Message)
+	}
+	if got.Attempt != reason.Attempt {
+		t.Errorf("Expected attempt %q, got %q", reason.Attempt, got.Attempt)
+	}
+}
+
+func TestMissingBenchmark(t *testing.T) {
+	pkgDir := t.TempDir()
+	destDir := filepath.Join(pkgDir, "generated")
+	source := filepath.Join(pkgDir, "calc.go")
+	if err := os.WriteFile(source, []byte(`package calc
+
+// mantra: Double the input
+// mantra:bench
+// mantra:fuzz
+func Double(n int) int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	gen := codegen.New(&codegen.Config{Dest: destDir, PackageName: "generated", SourcePackage: "calc"})
+	results := []*parser.GenerationResult{{Target: fileInfo.Targets[0], Success: true, Implementation: "return n * 2"}}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("Failed to generate file: %v", err)
+	}
+
+	// The benchmark phase gave up, so the next run generates the target again
+	detected, err := DetectPackageTargets(pkgDir, destDir)
+	if err != nil {
+		t.Fatalf("Detection failed: %v", err)
+	}
+	if status := detected[0].Statuses[0]; status.Status != StatusOutdated || status.Reason != "benchmark not generated yet" {
+		t.Errorf("Expected the target to be outdated for its missing benchmark, got %v (%q)", status.Status, status.Reason)
+	}
+
+	results[0].Benchmark = "for b.Loop() {\n\tDouble(21)\n}"
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("Failed to generate file: %v", err)
+	}
+	detected, err = DetectPackageTargets(pkgDir, destDir)
+	if err != nil {
+		t.Fatalf("Detection failed: %v", err)
+	}
+	if status := detected[0].Statuses[0]; status.Status != StatusOutdated || status.Reason != "fuzz test not generated yet" {
+		t.Errorf("Expected the target to be outdated for its missing fuzz test, got %v (%q)", status.Status, status.Reason)
+	}
+
+	results[0].Fuzz = "f.Add(21)\nf.Fuzz(func(t *testing.T, n int) {\n\t_ = Double(n)\n})"
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("Failed to generate file: %v", err)
+	}
+	detected, err = DetectPackageTargets(pkgDir, destDir)
+	if err != nil {
+		t.Fatalf("Detection failed: %v", err)
+	}
+	if status := detected[0].Statuses[0]; status.Status != StatusCurrent {
+		t.Errorf("Expected the target with both test functions to be current, got %v (%q)", status.Status, status.Reason)
+	}
+}
+
+func TestExistingHelpers(t *testing.T) {
+	pkgDir := t.TempDir()
+	destDir := filepath.Join(pkgDir, "generated")
+	source := filepath.Join(pkgDir, "calc.go")
+	if err := os.WriteFile(source, []byte(`package calc
+
+// mantra: Sum the squares of ns
+func SumSquares(ns []int) int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	helper := parser.Helper{Name: "square", Signature: "(n int) int", Body: "return n * n"}
+	gen := codegen.New(&codegen.Config{Dest: destDir, PackageName: "generated", SourcePackage: "calc"})
+	results := []*parser.GenerationResult{{
+		Target:         fileInfo.Targets[0],
+		Success:        true,
+		Implementation: "total := 0\nfor _, n := range ns {\n\ttotal += square(n)\n}\nreturn total",
+		Helpers:        []parser.Helper{helper},
+	}}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("Failed to generate file: %v", err)
+	}
+
+	detected, err := DetectPackageTargetsWithOptions(pkgDir, destDir, Options{SignatureImpact: true})
+	if err != nil {
+		t.Fatalf("Detection failed: %v", err)
+	}
+	status := detected[0].Statuses[0]
+	if status.Status != StatusCurrent {
+		t.Fatalf("Expected the target calling its helper to be current, got %v (%q)", status.Status, status.Reason)
+	}
+	if len(status.ExistingHelpers) != 1 {
+		t.Fatalf("Expected the helper to be kept, got %+v", status.ExistingHelpers)
+	}
+	got := status.ExistingHelpers[0]
+	if got.Name != helper.Name || got.Signature != helper.Signature || strings.TrimSpace(got.Body) != helper.Body {
+		t.Errorf("Expected helper %+v, got %+v", helper, got)
+	}
+}
@@ -0,0 +1,235 @@
+package detector
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/rail44/mantra/internal/analysis"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/imports"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// Options configures target detection
+type Options struct {
+	// SignatureImpact type-checks up-to-date implementations against the current
+	// declarations and marks those that no longer compile as outdated
+	SignatureImpact bool
+}
+
+// flagBrokenImplementations type-checks the package as it would be generated with the
+// existing implementations of current targets, and marks targets whose bodies no
+// longer compile as outdated. Other targets keep their source bodies.
+func flagBrokenImplementations(packageDir string, results []*FileDetectionResult) error {
+	overlay := make(map[string][]byte)
+	current := make(map[string]map[string]*TargetStatus) // file -> function key -> status
+
+	for _, result := range results {
+		bodies := make(map[string]string)
+		helpers := make(map[string][]parser.Helper)
+		for _, status := range result.Statuses {
+			if status.Status != StatusCurrent || status.Target.FuncDecl == nil {
+				continue
+			}
+			key := funcKey(status.Target.FuncDecl)
+			bodies[key] = status.ExistingImpl
+			if current[result.FileInfo.FilePath] == nil {
+				current[result.FileInfo.FilePath] = make(map[string]*TargetStatus)
+			}
+			current[result.FileInfo.FilePath][key] = status
+			// Errors in helpers break the target they were written for
+			helpers[key] = status.ExistingHelpers
+			for _, helper := range status.ExistingHelpers {
+				current[result.FileInfo.FilePath][helper.Name] = status
+			}
+		}
+		if len(bodies) == 0 {
+			continue
+		}
+
+		content, err := withBodies(result.FileInfo.FilePath, result.FileInfo.SourceContent, bodies, helpers)
+		if err != nil {
+			return err
+		}
+		overlay[absPath(result.FileInfo.FilePath)] = content
+	}
+
+	if len(overlay) == 0 {
+		return nil
+	}
+
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     packageDir,
+		Overlay: overlay,
+	}
+	pkgs, err := packages.Load(buildenv.Apply(cfg), ".")
+	if err != nil {
+		return fmt.Errorf("failed to type-check package: %w", err)
+	}
+	if len(pkgs) == 0 {
+		return nil
+	}
+	pkg := pkgs[0]
+
+	for _, typeErr := range pkg.TypeErrors {
+		pos := pkg.Fset.Position(typeErr.Pos)
+		statuses := current[sourcePathFor(results, pos.Filename)]
+		if statuses == nil {
+			continue
+		}
+
+		fd := enclosingFunc(pkg, typeErr)
+		if fd == nil {
+			continue
+		}
+		status, ok := statuses[funcKey(fd)]
+		if !ok || status.Status != StatusCurrent {
+			continue
+		}
+
+		status.Status = StatusOutdated
+		status.ExistingImpl = ""
+		status.ExistingHelpers = nil
+		status.Reason = fmt.Sprintf("implementation no longer compiles: %s", typeErr.Msg)
+	}
+
+	return nil
+}
+
+// withBodies returns source with the bodies of the given functions replaced and their
+// helpers declared below them, adding imports the replacement bodies need
+func withBodies(filePath, source string, bodies map[string]string, helpers map[string][]parser.Helper) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, filePath, source, goparser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	type replacement struct {
+		start, end int
+		body       string
+		helpers    []parser.Helper
+	}
+	var replacements []replacement
+	var required []string
+	blankImports := imports.ExtractBlankImports(source)
+	for _, fd := range analysis.FuncDecls(file) {
+		if fd.Body == nil {
+			continue
+		}
+		key := funcKey(fd)
+		body, ok := bodies[key]
+		if !ok {
+			continue
+		}
+		replacements = append(replacements, replacement{
+			start:   fset.Position(fd.Body.Lbrace).Offset,
+			end:     fset.Position(fd.Body.Rbrace).Offset + 1,
+			body:    body,
+			helpers: helpers[key],
+		})
+		code := parser.WithHelperCode(body, helpers[key])
+		required = imports.MergeImports(required, imports.AnalyzeRequiredImports(code))
+		required = imports.MergeImports(required, imports.UsedBlankImports(code, blankImports))
+	}
+
+	// Replace from the bottom up so earlier offsets stay valid
+	sort.Slice(replacements, func(i, j int) bool { return replacements[i].start > replacements[j].start })
+	content := source
+	for _, r := range replacements {
+		text := "{\n" + r.body + "\n}"
+		for _, helper := range r.helpers {
+			text += "\n\n" + helper.Source()
+		}
+		content = content[:r.start] + text + content[r.end:]
+	}
+
+	fset = token.NewFileSet()
+	file, err = goparser.ParseFile(fset, filePath, content, goparser.ParseComments)
+	if err != nil {
+		// Bodies that do not even parse are reported by the type checker as syntax errors
+		return []byte(content), nil
+	}
+
+	// Like the generator, add what the bodies need; a regular import next to a blank one
+	// type-checks the same as converting it
+	for _, path := range required {
+		astutil.AddImport(fset, file, path)
+	}
+
+	var buf strings.Builder
+	if err := format.Node(&buf, fset, file); err != nil {
+		return []byte(content), nil
+	}
+	return []byte(buf.String()), nil
+}
+
+// enclosingFunc returns the function declaration containing a type error
+func enclosingFunc(pkg *packages.Package, typeErr types.Error) *ast.FuncDecl {
+	for _, file := range pkg.Syntax {
+		if file.Pos() > typeErr.Pos || typeErr.Pos > file.End() {
+			continue
+		}
+		for _, fd := range analysis.FuncDecls(file) {
+			if fd.Pos() <= typeErr.Pos && typeErr.Pos <= fd.End() {
+				return fd
+			}
+		}
+	}
+	return nil
+}
+
+// funcKey identifies a function by receiver base type and name (e.g., "Cache.Get")
+func funcKey(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	typ := fd.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+			continue
+		case *ast.ParenExpr:
+			typ = t.X
+			continue
+		case *ast.IndexExpr:
+			typ = t.X
+			continue
+		case *ast.IndexListExpr:
+			typ = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + fd.Name.Name
+		}
+		return fd.Name.Name
+	}
+}
+
+// sourcePathFor maps a type-checked file name back to the source path used in results
+func sourcePathFor(results []*FileDetectionResult, filename string) string {
+	for _, result := range results {
+		if absPath(result.FileInfo.FilePath) == absPath(filename) {
+			return result.FileInfo.FilePath
+		}
+	}
+	return ""
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
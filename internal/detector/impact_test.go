@@ -0,0 +1,90 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestSignatureImpact(t *testing.T) {
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "pricing")
+	taxDir := filepath.Join(root, "tax")
+	destDir := filepath.Join(root, "generated")
+	for _, dir := range []string{pkgDir, taxDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create package: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	source := filepath.Join(pkgDir, "pricing.go")
+	if err := os.WriteFile(source, []byte(`package pricing
+
+import _ "example.com/app/tax"
+
+// mantra: Return amount plus tax
+func Total(amount int) int {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	// The hand-written dependency lives in another package, outside dependency fingerprints
+	writeTax := func(params string) {
+		content := "package tax\n\nfunc For(" + params + ") int { return 0 }\n"
+		if err := os.WriteFile(filepath.Join(taxDir, "tax.go"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write tax package: %v", err)
+		}
+	}
+	writeTax("amount int")
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	gen := codegen.New(&codegen.Config{Dest: destDir, PackageName: "generated", SourcePackage: "pricing"})
+	var results []*parser.GenerationResult
+	for _, target := range fileInfo.Targets {
+		results = append(results, &parser.GenerationResult{Target: target, Success: true, Implementation: "return amount + tax.For(amount)"})
+	}
+	if err := gen.GenerateFile(fileInfo, results); err != nil {
+		t.Fatalf("Failed to generate file: %v", err)
+	}
+
+	// tax.For gains a parameter, which breaks the call in Total
+	writeTax("amount int, rate int")
+
+	statuses := func(opts Options) map[string]*TargetStatus {
+		detected, err := DetectPackageTargetsWithOptions(pkgDir, destDir, opts)
+		if err != nil {
+			t.Fatalf("Detection failed: %v", err)
+		}
+		byName := make(map[string]*TargetStatus)
+		for _, result := range detected {
+			for _, status := range result.Statuses {
+				byName[status.Target.Name] = status
+			}
+		}
+		return byName
+	}
+
+	if got := statuses(Options{})["Total"].Status; got != StatusCurrent {
+		t.Fatalf("Expected Total to be current without impact checking, got %v", got)
+	}
+
+	total := statuses(Options{SignatureImpact: true})["Total"]
+	if total.Status != StatusOutdated {
+		t.Fatalf("Expected Total to be outdated, got %v", total.Status)
+	}
+	if !strings.Contains(total.Reason, "no longer compiles") {
+		t.Errorf("Unexpected reason: %q", total.Reason)
+	}
+}
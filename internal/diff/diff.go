@@ -0,0 +1,152 @@
+// Package diff reads unified diffs, such as the output of git diff, to restrict generation
+// to the targets a change touches
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Diff holds the lines of each file a unified diff touches, numbered in the new version
+type Diff struct {
+	files map[string]map[int]bool // Touched lines by path as written in the diff
+}
+
+// Parse reads a unified diff. Added lines are touched, and removed lines touch the line that
+// now follows them. Deleted files are left out
+func Parse(r io.Reader) (*Diff, error) {
+	d := &Diff{files: make(map[string]map[int]bool)}
+
+	var lines map[int]bool   // Touched lines of the current file (nil outside a new file)
+	line := 0                // Line of the new version the next hunk line is on
+	oldLeft, newLeft := 0, 0 // Lines of each version left in the current hunk
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		inHunk := oldLeft > 0 || newLeft > 0
+		switch {
+		case inHunk && strings.HasPrefix(text, "+"):
+			if lines != nil {
+				lines[line] = true
+			}
+			line++
+			newLeft--
+		case inHunk && strings.HasPrefix(text, "-"):
+			if lines != nil {
+				lines[line] = true
+			}
+			oldLeft--
+		case inHunk && strings.HasPrefix(text, " "), inHunk && text == "":
+			line++
+			oldLeft--
+			newLeft--
+		case strings.HasPrefix(text, "+++ "):
+			lines = nil
+			path := diffPath(strings.TrimPrefix(text, "+++ "))
+			if path == "/dev/null" {
+				continue
+			}
+			if d.files[path] == nil {
+				d.files[path] = make(map[int]bool)
+			}
+			lines = d.files[path]
+		case strings.HasPrefix(text, "@@ "):
+			var err error
+			if oldLeft, line, newLeft, err = parseHunkHeader(text); err != nil {
+				return nil, err
+			}
+			if newLeft == 0 {
+				// Only removals: the hunk is positioned at the line before them
+				line++
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read diff: %w", err)
+	}
+	return d, nil
+}
+
+// diffPath returns the path of a file header, without its timestamp and quoting
+func diffPath(header string) string {
+	path, _, _ := strings.Cut(header, "\t")
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		path = unquoted
+	}
+	return path
+}
+
+// parseHunkHeader returns the line count of the old version, and the first line and line
+// count of the new version, of a hunk header like "@@ -12,5 +12,7 @@ func Name() {"
+func parseHunkHeader(header string) (oldCount, start, newCount int, err error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header: %s", header)
+	}
+	_, oldCount, err = parseRange(fields[1][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header: %s", header)
+	}
+	start, newCount, err = parseRange(fields[2][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header: %s", header)
+	}
+	return oldCount, start, newCount, nil
+}
+
+// parseRange parses a hunk range "start,count", where the count defaults to 1
+func parseRange(text string) (int, int, error) {
+	startText, countText, found := strings.Cut(text, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil || !found {
+		return start, 1, err
+	}
+	count, err := strconv.Atoi(countText)
+	return start, count, err
+}
+
+// Touches reports whether the diff changes any line from start to end of the file at
+// filePath. Diff paths are relative to where the diff was made, so a path matches the
+// files it is a suffix of, with or without a one-letter prefix directory like git's b/
+func (d *Diff) Touches(filePath string, start, end int) bool {
+	lines := d.lines(filePath)
+	for line := start; line <= end; line++ {
+		if lines[line] {
+			return true
+		}
+	}
+	return false
+}
+
+// TouchesFile reports whether the diff changes the file at filePath
+func (d *Diff) TouchesFile(filePath string) bool {
+	return len(d.lines(filePath)) > 0
+}
+
+// lines returns the touched lines of the file at filePath, from the longest diff path that
+// names it
+func (d *Diff) lines(filePath string) map[int]bool {
+	filePath = filepath.ToSlash(filePath)
+	var lines map[int]bool
+	longest := 0
+	for path, touched := range d.files {
+		match := path
+		if prefix, rest, ok := strings.Cut(path, "/"); !matchesPath(filePath, path) && ok && len(prefix) == 1 {
+			match = rest
+		}
+		if matchesPath(filePath, match) && len(match) > longest {
+			lines, longest = touched, len(match)
+		}
+	}
+	return lines
+}
+
+// matchesPath reports whether path names filePath, relative to one of its directories
+func matchesPath(filePath, path string) bool {
+	return filePath == path || strings.HasSuffix(filePath, "/"+path)
+}
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `diff --git a/pkg/user.go b/pkg/user.go
+index 83db48f..bf269f4 100644
+--- a/pkg/user.go
++++ b/pkg/user.go
+@@ -10,7 +10,8 @@ type User struct {
+ // mantra: Validate the name
+ func (u *User) Validate() error {
+-	panic("not implemented")
++	// changed
++	panic("not implemented")
+ }
+
+ // mantra: Greet the user
+@@ -30,3 +31,2 @@ func (u *User) Greet() string {
+ func helper() {
+--- removed with leading dashes
+ }
+diff --git a/pkg/old.go b/pkg/old.go
+deleted file mode 100644
+--- a/pkg/old.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-package pkg
+-
+-var x = 1
+diff --git a/pkg/new.go b/pkg/new.go
+new file mode 100644
+--- /dev/null
++++ b/pkg/new.go
+@@ -0,0 +1 @@
++package pkg
+`
+
+func TestParse(t *testing.T) {
+	d, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Failed to parse diff: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		file       string
+		start, end int
+		want       bool
+	}{
+		{"added lines", "/repo/pkg/user.go", 12, 13, true},
+		{"context after changes", "/repo/pkg/user.go", 14, 14, false},
+		{"context only", "/repo/pkg/user.go", 15, 20, false},
+		{"line after a removal", "/repo/pkg/user.go", 32, 32, true},
+		{"line before a removal", "/repo/pkg/user.go", 31, 31, false},
+		{"before hunks", "/repo/pkg/user.go", 1, 11, false},
+		{"other directory", "/repo/other/user.go", 1, 40, false},
+		{"directory named like the prefix", "/repo/b/pkg/user.go", 12, 12, true},
+		{"deleted file", "/repo/pkg/old.go", 1, 3, false},
+		{"new file", "/repo/pkg/new.go", 1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.Touches(tt.file, tt.start, tt.end); got != tt.want {
+				t.Errorf("Expected Touches(%s, %d, %d) = %v, got %v", tt.file, tt.start, tt.end, tt.want, got)
+			}
+		})
+	}
+
+	if !d.TouchesFile("/repo/pkg/user.go") || d.TouchesFile("/repo/pkg/old.go") {
+		t.Errorf("Expected user.go touched and old.go not")
+	}
+}
+
+func TestParseInvalidHunk(t *testing.T) {
+	if _, err := Parse(strings.NewReader("+++ b/x.go\n@@ -1 +a @@\n")); err == nil {
+		t.Error("Expected an error for an invalid hunk header")
+	}
+}
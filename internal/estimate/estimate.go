@@ -0,0 +1,145 @@
+// Package estimate predicts the token usage, cost and duration of a generation run
+package estimate
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Assumptions describe how a run proceeds. The number of tool rounds and the size of
+// completions are only known afterwards, so they are averages supplied by the user
+type Assumptions struct {
+	Rounds         int           // Chat completion requests per phase
+	OutputTokens   int           // Completion tokens per request
+	OutputTPS      float64       // Completion tokens generated per second
+	RequestLatency time.Duration // Fixed overhead per request (queueing, prompt processing)
+	Concurrency    int           // Targets generated in parallel
+}
+
+// DefaultAssumptions are typical of a tool-using coding model; Concurrency is left to the caller
+var DefaultAssumptions = Assumptions{
+	Rounds:         3,
+	OutputTokens:   500,
+	OutputTPS:      40,
+	RequestLatency: time.Second,
+}
+
+// Pricing is the provider's price in USD per million tokens
+type Pricing struct {
+	Input  float64
+	Output float64
+}
+
+// RateLimits bound the request and token throughput of the provider (0 means unlimited)
+type RateLimits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// Phase is one phase of a target and the size of its first request
+type Phase struct {
+	Name   string
+	Tokens int
+	Runs   int // Parallel runs, e.g. implementation candidates (0 counts as 1)
+}
+
+// Target is a target to be generated
+type Target struct {
+	Name   string
+	Phases []Phase
+}
+
+// TargetEstimate is the estimated usage of one target
+type TargetEstimate struct {
+	Name         string
+	Phases       []string
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+	Duration     time.Duration
+}
+
+// Estimate is the estimated usage of a run
+type Estimate struct {
+	Targets      []TargetEstimate
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64       // Zero without pricing
+	WallClock    time.Duration // With the configured concurrency and rate limits
+	RateLimited  bool          // Rate limits, not concurrency, bound the wall-clock time
+}
+
+// Compute estimates a run. Each round of a phase resends the conversation so far, which
+// grows by a completion and a tool result of about the same size per round
+func Compute(targets []Target, a Assumptions, pricing *Pricing, limits *RateLimits) *Estimate {
+	rounds := max(a.Rounds, 1)
+	perRound := a.RequestLatency
+	if a.OutputTPS > 0 {
+		perRound += time.Duration(float64(a.OutputTokens) / a.OutputTPS * float64(time.Second))
+	}
+
+	est := &Estimate{}
+	var durations []time.Duration
+	for _, target := range targets {
+		te := TargetEstimate{Name: target.Name}
+		for _, phase := range target.Phases {
+			runs := max(phase.Runs, 1)
+			te.Phases = append(te.Phases, phase.Name)
+			te.Requests += runs * rounds
+			te.InputTokens += runs * (rounds*phase.Tokens + a.OutputTokens*rounds*(rounds-1))
+			te.OutputTokens += runs * rounds * a.OutputTokens
+			// Parallel runs overlap
+			te.Duration += time.Duration(rounds) * perRound
+		}
+		if pricing != nil {
+			te.Cost = cost(te.InputTokens, te.OutputTokens, pricing)
+		}
+
+		est.Targets = append(est.Targets, te)
+		est.Requests += te.Requests
+		est.InputTokens += te.InputTokens
+		est.OutputTokens += te.OutputTokens
+		est.Cost += te.Cost
+		durations = append(durations, te.Duration)
+	}
+
+	est.WallClock = schedule(durations, max(a.Concurrency, 1))
+	if limits != nil {
+		if bound := minutes(est.Requests, limits.RequestsPerMinute); bound > est.WallClock {
+			est.WallClock, est.RateLimited = bound, true
+		}
+		if bound := minutes(est.InputTokens+est.OutputTokens, limits.TokensPerMinute); bound > est.WallClock {
+			est.WallClock, est.RateLimited = bound, true
+		}
+	}
+	return est
+}
+
+// cost returns the price of the given tokens in USD
+func cost(input, output int, p *Pricing) float64 {
+	return float64(input)/1e6*p.Input + float64(output)/1e6*p.Output
+}
+
+// minutes returns how long n units take at perMinute (0 when unlimited)
+func minutes(n, perMinute int) time.Duration {
+	if perMinute <= 0 {
+		return 0
+	}
+	return time.Duration(float64(n) / float64(perMinute) * float64(time.Minute))
+}
+
+// schedule returns the makespan of running the durations on slots workers, longest first
+func schedule(durations []time.Duration, slots int) time.Duration {
+	sorted := slices.Clone(durations)
+	slices.SortFunc(sorted, func(a, b time.Duration) int { return cmp.Compare(b, a) })
+
+	busy := make([]time.Duration, slots)
+	for _, d := range sorted {
+		i := slices.Index(busy, slices.Min(busy))
+		busy[i] += d
+	}
+	return slices.Max(busy)
+}
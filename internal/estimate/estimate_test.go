@@ -0,0 +1,59 @@
+package estimate
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCompute(t *testing.T) {
+	targets := []Target{
+		{Name: "Discount", Phases: []Phase{{Name: "Context Gathering", Tokens: 1000}, {Name: "Implementation", Tokens: 2000, Runs: 2}}},
+		{Name: "Total", Phases: []Phase{{Name: "Implementation", Tokens: 1000}}},
+	}
+	a := Assumptions{Rounds: 2, OutputTokens: 100, OutputTPS: 50, RequestLatency: time.Second, Concurrency: 1}
+
+	est := Compute(targets, a, &Pricing{Input: 1, Output: 10}, nil)
+
+	// Round 2 resends a completion and a tool result of 100 tokens each
+	discount := est.Targets[0]
+	if discount.Requests != 6 {
+		t.Errorf("Expected 6 requests, got %d", discount.Requests)
+	}
+	if want := (2*1000 + 200) + 2*(2*2000+200); discount.InputTokens != want {
+		t.Errorf("Expected %d input tokens, got %d", want, discount.InputTokens)
+	}
+	if discount.OutputTokens != 600 {
+		t.Errorf("Expected 600 output tokens, got %d", discount.OutputTokens)
+	}
+	// Candidates run in parallel: 2 phases x 2 rounds x (1s + 2s)
+	if discount.Duration != 12*time.Second {
+		t.Errorf("Expected 12s, got %s", discount.Duration)
+	}
+
+	if est.Requests != 8 || est.InputTokens != discount.InputTokens+2200 {
+		t.Errorf("Expected totals over both targets, got %d requests, %d input tokens", est.Requests, est.InputTokens)
+	}
+	wantCost := float64(est.InputTokens)/1e6*1 + float64(est.OutputTokens)/1e6*10
+	if math.Abs(est.Cost-wantCost) > 1e-9 {
+		t.Errorf("Expected cost %f, got %f", wantCost, est.Cost)
+	}
+	if est.WallClock != 18*time.Second {
+		t.Errorf("Expected targets to run one after another, got %s", est.WallClock)
+	}
+
+	a.Concurrency = 2
+	if est := Compute(targets, a, nil, nil); est.WallClock != 12*time.Second || est.Cost != 0 {
+		t.Errorf("Expected the longest target to bound the run without cost, got %s, %f", est.WallClock, est.Cost)
+	}
+}
+
+func TestComputeRateLimited(t *testing.T) {
+	targets := []Target{{Name: "Discount", Phases: []Phase{{Name: "Implementation", Tokens: 1000}}}}
+	a := Assumptions{Rounds: 3, OutputTokens: 100, Concurrency: 16}
+
+	est := Compute(targets, a, nil, &RateLimits{RequestsPerMinute: 1})
+	if !est.RateLimited || est.WallClock != 3*time.Minute {
+		t.Errorf("Expected 3 requests at 1 per minute to take 3m, got %s (rate limited: %v)", est.WallClock, est.RateLimited)
+	}
+}
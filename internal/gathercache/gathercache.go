@@ -0,0 +1,146 @@
+// Package gathercache keeps context gathering results per receiver type, within a run and
+// across runs, so later methods of the same type start from what earlier ones found
+package gathercache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultDir is where results are kept, relative to the project config file
+const DefaultDir = ".mantra/context-cache"
+
+// FingerprintFunc hashes the current declarations of the given identifiers, so a cached
+// result is only reused while what it describes is unchanged
+type FingerprintFunc func(identifiers []string) string
+
+// entry is the cached result of a receiver type
+type entry struct {
+	Identifiers []string       `json:"identifiers"` // Names of the gathered types, functions and constants
+	Fingerprint string         `json:"fingerprint"` // Fingerprint of Identifiers when the result was stored
+	Result      map[string]any `json:"result"`
+}
+
+// Cache keeps the results of a package in one JSON file per receiver type. A nil cache
+// keeps nothing, so callers need not check whether caching is enabled
+type Cache struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[string]*entry      // Results read or stored in this run, by receiver type
+	locks   map[string]*sync.Mutex // Serialize gathering per receiver type, see Lock
+}
+
+// Open returns the cache of a package's results under dir. pkg identifies the package
+// within dir
+func Open(dir, pkg string) *Cache {
+	return &Cache{
+		dir:     filepath.Join(dir, filepath.FromSlash(pkg)),
+		entries: make(map[string]*entry),
+		locks:   make(map[string]*sync.Mutex),
+	}
+}
+
+// Lock serializes context gathering for methods of receiver, so a method generated
+// alongside another one of the same type starts from its result instead of racing it.
+// It returns the unlock function
+func (c *Cache) Lock(receiver string) func() {
+	if c == nil {
+		return func() {}
+	}
+	c.mu.Lock()
+	lock, ok := c.locks[receiver]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.locks[receiver] = lock
+	}
+	c.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// Get returns the result gathered for another method of receiver, or nil when there is
+// none or the declarations it describes changed since
+func (c *Cache) Get(receiver string, fingerprint FingerprintFunc) (map[string]any, error) {
+	if c == nil {
+		return nil, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[receiver]
+	if !ok {
+		data, err := os.ReadFile(c.path(receiver))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read context cache: %w", err)
+		}
+		e = &entry{}
+		if err := json.Unmarshal(data, e); err != nil {
+			return nil, fmt.Errorf("failed to parse context cache %s: %w", c.path(receiver), err)
+		}
+		c.entries[receiver] = e
+	}
+	if e.Result == nil || fingerprint(e.Identifiers) != e.Fingerprint {
+		return nil, nil
+	}
+	return e.Result, nil
+}
+
+// Put stores the result gathered for a method of receiver
+func (c *Cache) Put(receiver string, result map[string]any, fingerprint FingerprintFunc) error {
+	if c == nil || result == nil {
+		return nil
+	}
+	identifiers := Identifiers(result)
+	e := &entry{Identifiers: identifiers, Fingerprint: fingerprint(identifiers), Result: result}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[receiver] = e
+
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode context cache: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create context cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.path(receiver), data, 0644); err != nil {
+		return fmt.Errorf("failed to write context cache: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a receiver type's result
+func (c *Cache) path(receiver string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", "[", "_", "]", "_", " ", "").Replace(receiver)
+	return filepath.Join(c.dir, name+".json")
+}
+
+// Identifiers returns the sorted names of the types, functions and constants in a context
+// gathering result
+func Identifiers(result map[string]any) []string {
+	var names []string
+	for _, key := range []string{"types", "functions", "constants"} {
+		items, _ := result[key].([]any)
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				if name, ok := m["name"].(string); ok && !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,77 @@
+package gathercache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	declarations := map[string]string{"Cart": "type Cart struct{ items []Item }", "Item": "type Item struct{}"}
+	fingerprint := func(names []string) string {
+		var sb strings.Builder
+		for _, name := range names {
+			sb.WriteString(name + "=" + declarations[name] + ";")
+		}
+		return sb.String()
+	}
+	result := map[string]any{
+		"types":     []any{map[string]any{"name": "Item"}, map[string]any{"name": "Cart"}},
+		"functions": []any{map[string]any{"name": "Cart.Total"}},
+	}
+
+	cache := Open(dir, "shop")
+	if got, err := cache.Get("Cart", fingerprint); err != nil || got != nil {
+		t.Fatalf("Expected no result before Put, got %v (%v)", got, err)
+	}
+	if err := cache.Put("Cart", result, fingerprint); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if got, _ := cache.Get("Cart", fingerprint); got == nil {
+		t.Error("Expected the stored result within the run")
+	}
+
+	// Another run reads the result from disk
+	reopened := Open(dir, "shop")
+	got, err := reopened.Get("Cart", fingerprint)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if types, _ := got["types"].([]any); len(types) != 2 {
+		t.Errorf("Expected 2 types, got %v", got["types"])
+	}
+	if got, _ := Open(dir, "other").Get("Cart", fingerprint); got != nil {
+		t.Errorf("Expected packages to be kept apart, got %v", got)
+	}
+
+	// Changing a gathered declaration invalidates the result
+	declarations["Item"] = "type Item struct{ price int }"
+	if got, _ := Open(dir, "shop").Get("Cart", fingerprint); got != nil {
+		t.Errorf("Expected a stale result to be dropped, got %v", got)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var cache *Cache
+	fingerprint := func([]string) string { return "" }
+
+	cache.Lock("Cart")()
+	if err := cache.Put("Cart", map[string]any{}, fingerprint); err != nil {
+		t.Errorf("Expected Put on a nil cache to succeed, got %v", err)
+	}
+	if got, err := cache.Get("Cart", fingerprint); got != nil || err != nil {
+		t.Errorf("Expected nothing from a nil cache, got %v (%v)", got, err)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	result := map[string]any{
+		"types":     []any{map[string]any{"name": "Item"}, map[string]any{"name": "Cart"}},
+		"functions": []any{map[string]any{"name": "Cart.Total"}, map[string]any{"name": "Item"}},
+		"constants": []any{map[string]any{"value": "unnamed"}},
+	}
+	got := strings.Join(Identifiers(result), ",")
+	if got != "Cart,Cart.Total,Item" {
+		t.Errorf("Expected Cart,Cart.Total,Item, got %s", got)
+	}
+}
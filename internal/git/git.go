@@ -0,0 +1,126 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/report"
+)
+
+// DefaultCommitMessage is used when no commit_message template is configured
+const DefaultCommitMessage = `mantra: generate {{.Package}}
+
+{{.Succeeded}} succeeded, {{.Failed}} failed, {{.UpToDate}} up-to-date
+{{range .Targets}}
+- {{.Name}}: {{.Status}}{{end}}
+`
+
+// Integrator stages and commits generated files
+type Integrator struct {
+	config *config.GitConfig
+	dir    string // Directory used as the git working directory
+}
+
+// New creates a git integrator operating in dir. Returns nil if git integration is disabled
+func New(cfg *config.GitConfig, dir string) *Integrator {
+	if cfg == nil || (!cfg.Add && !cfg.Commit) {
+		return nil
+	}
+	return &Integrator{
+		config: cfg,
+		dir:    dir,
+	}
+}
+
+// Apply stages the written files and commits them if configured.
+// Returns the commit message used, or an empty string if no commit was created
+func (i *Integrator) Apply(ctx context.Context, rep *report.Report) (string, error) {
+	if i == nil || len(rep.FilesWritten) == 0 {
+		return "", nil
+	}
+
+	if _, err := i.run(ctx, append([]string{"add", "--"}, rep.FilesWritten...)...); err != nil {
+		return "", fmt.Errorf("failed to stage generated files: %w", err)
+	}
+
+	if !i.config.Commit {
+		return "", nil
+	}
+
+	// Skip committing when the generated files did not change
+	changed, err := i.hasStagedChanges(ctx, rep.FilesWritten)
+	if err != nil {
+		return "", err
+	}
+	if !changed {
+		return "", nil
+	}
+
+	message, err := RenderMessage(i.config.CommitMessage, rep)
+	if err != nil {
+		return "", err
+	}
+
+	args := append([]string{"commit", "-m", message, "--"}, rep.FilesWritten...)
+	if _, err := i.run(ctx, args...); err != nil {
+		return "", fmt.Errorf("failed to commit generated files: %w", err)
+	}
+
+	return message, nil
+}
+
+// RenderMessage executes the commit message template with the run report
+func RenderMessage(tmpl string, rep *report.Report) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultCommitMessage
+	}
+
+	t, err := template.New("commit").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit_message template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, rep); err != nil {
+		return "", fmt.Errorf("failed to render commit message: %w", err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// hasStagedChanges reports whether any of the given paths differ from HEAD in the index
+func (i *Integrator) hasStagedChanges(ctx context.Context, paths []string) (bool, error) {
+	_, err := i.run(ctx, append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...)
+	if err == nil {
+		return false, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("failed to inspect staged changes: %w", err)
+}
+
+// run executes a git command in the integrator's directory
+func (i *Integrator) run(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = i.dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
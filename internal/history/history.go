@@ -0,0 +1,161 @@
+// Package history keeps the last accepted implementations of each target, so earlier
+// versions can be compared and restored without going through version control
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// DefaultDir is where history is kept, relative to the project config file
+const DefaultDir = ".mantra/history"
+
+// DefaultKeep is the number of implementations kept per target unless configured
+const DefaultKeep = 5
+
+// Entry is one accepted implementation of a target
+type Entry struct {
+	Version  int             `json:"version"` // Increases with every recorded implementation of the target
+	Time     time.Time       `json:"time"`
+	Model    string          `json:"model,omitempty"`
+	Checksum string          `json:"checksum"` // Checksum of the declaration the body was generated for
+	Body     string          `json:"body"`
+	Helpers  []parser.Helper `json:"helpers,omitempty"` // Helper functions the body calls, as written
+}
+
+// Store keeps the history of a package's targets in one JSON file per target. A nil store
+// records nothing, so callers need not check whether history is enabled
+type Store struct {
+	dir  string
+	keep int
+}
+
+// Open returns the store of a package's history under dir, keeping the last keep
+// implementations of each target. pkg identifies the package within dir
+func Open(dir, pkg string, keep int) *Store {
+	if keep <= 0 {
+		return nil
+	}
+	return &Store{dir: filepath.Join(dir, filepath.FromSlash(pkg)), keep: keep}
+}
+
+// TargetKey names a target in the history: the function name, qualified by the receiver
+// type for methods (e.g. "Cart.Total")
+func TargetKey(target *parser.Target) string {
+	if target.Receiver == nil {
+		return target.Name
+	}
+	return strings.TrimPrefix(target.Receiver.Type, "*") + "." + target.Name
+}
+
+// Record appends an implementation of the target, dropping the oldest beyond the limit.
+// A body and helpers identical to the latest entry's are not recorded again
+func (s *Store) Record(target *parser.Target, entry Entry) error {
+	if s == nil {
+		return nil
+	}
+	entries, err := s.Entries(target)
+	if err != nil {
+		return err
+	}
+
+	if n := len(entries); n > 0 {
+		if entries[n-1].Body == entry.Body && slices.Equal(entries[n-1].Helpers, entry.Helpers) {
+			return nil
+		}
+		entry.Version = entries[n-1].Version + 1
+	} else {
+		entry.Version = 1
+	}
+	entries = append(entries, entry)
+	if len(entries) > s.keep {
+		entries = entries[len(entries)-s.keep:]
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return os.WriteFile(s.path(target), data, 0644)
+}
+
+// Entries returns the recorded implementations of the target, oldest first
+func (s *Store) Entries(target *parser.Target) ([]Entry, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(s.path(target))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse history of %s: %w", TargetKey(target), err)
+	}
+	return entries, nil
+}
+
+// path returns the file holding the target's history
+func (s *Store) path(target *parser.Target) string {
+	return filepath.Join(s.dir, TargetKey(target)+".json")
+}
+
+// Diff returns a line diff turning old into new, prefixing lines with "-", "+" or " "
+func Diff(old, new string) string {
+	a, b := splitLines(old), splitLines(new)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
+
+// splitLines splits a body into lines, ignoring a trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,87 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestRecordKeepsLastEntries(t *testing.T) {
+	store := Open(t.TempDir(), "shop/cart", 2)
+	target := &parser.Target{Name: "Total", Receiver: &parser.Receiver{Name: "c", Type: "*Cart"}}
+
+	for _, body := range []string{"return 1", "return 2", "return 2", "return 3"} {
+		if err := store.Record(target, Entry{Body: body}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	entries, err := store.Entries(target)
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	// The repeated body is not recorded, so version 3 is the third distinct body
+	if entries[0].Version != 2 || entries[0].Body != "return 2" {
+		t.Errorf("Expected version 2 with return 2, got %+v", entries[0])
+	}
+	if entries[1].Version != 3 || entries[1].Body != "return 3" {
+		t.Errorf("Expected version 3 with return 3, got %+v", entries[1])
+	}
+}
+
+func TestRecordKeepsHelpers(t *testing.T) {
+	store := Open(t.TempDir(), "shop", 5)
+	target := &parser.Target{Name: "Label"}
+	helper := parser.Helper{Name: "quote", Signature: "(n int) string", Body: "return strconv.Quote(n)"}
+
+	// The same body with other helpers is another implementation
+	for _, entry := range []Entry{
+		{Body: "return quote(n)"},
+		{Body: "return quote(n)", Helpers: []parser.Helper{helper}},
+		{Body: "return quote(n)", Helpers: []parser.Helper{helper}},
+	} {
+		if err := store.Record(target, entry); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	entries, err := store.Entries(target)
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if len(entries[1].Helpers) != 1 || entries[1].Helpers[0] != helper {
+		t.Errorf("Expected the helper to be kept, got %+v", entries[1].Helpers)
+	}
+}
+
+func TestDisabledStore(t *testing.T) {
+	store := Open(t.TempDir(), "shop", 0)
+	if store != nil {
+		t.Fatal("Expected keep 0 to disable history")
+	}
+	target := &parser.Target{Name: "Double"}
+	if err := store.Record(target, Entry{Body: "return n * 2"}); err != nil {
+		t.Errorf("Expected nil store to ignore Record, got %v", err)
+	}
+	if entries, err := store.Entries(target); err != nil || entries != nil {
+		t.Errorf("Expected no entries, got %v, %v", entries, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := "total := 0\nfor _, v := range items {\n\ttotal += v\n}\nreturn total"
+	new := "total := 0\nfor _, v := range items {\n\ttotal += v.Price\n}\nreturn total"
+	want := " total := 0\n for _, v := range items {\n-\ttotal += v\n+\ttotal += v.Price\n }\n return total\n"
+	if got := Diff(old, new); got != want {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", want, got)
+	}
+	if got := Diff("", "return 1"); got != "+return 1\n" {
+		t.Errorf("Expected an added line, got %q", got)
+	}
+}
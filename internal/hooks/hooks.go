@@ -0,0 +1,161 @@
+// Package hooks installs git hooks that keep generated code from being committed or pushed
+// while it is stale
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Hooks that can be installed
+const (
+	PreCommit = "pre-commit"
+	PrePush   = "pre-push"
+)
+
+// marker identifies hooks written by Install, which it may replace
+const marker = "# Installed by mantra hooks install"
+
+// ErrExists is returned when a hook not written by mantra is already installed
+var ErrExists = errors.New("hook already exists")
+
+// Package is a package checked by the hook, with paths relative to the repository root
+type Package struct {
+	Dir  string // Package directory
+	Dest string // Directory of the generated files; empty if unknown
+}
+
+// Options describes the hook to install
+type Options struct {
+	Hook     string    // PreCommit or PrePush
+	Packages []Package // Packages to check
+	Generate bool      // Generate the targets the staged changes touch before checking (pre-commit only)
+	SkipEnv  []string  // Environment variables that skip the hook when set, e.g. CI
+}
+
+// Script renders the hook: it exits early when a skip variable is set, optionally generates
+// and stages the targets the staged changes touch, and fails when a package has pending
+// targets. As mantra check reads the working tree, a pre-commit hook also fails while the
+// package or its generated files have unstaged changes, which the commit would not hold
+func Script(opts Options) (string, error) {
+	if opts.Hook != PreCommit && opts.Hook != PrePush {
+		return "", fmt.Errorf("unsupported hook %q (expected %s or %s)", opts.Hook, PreCommit, PrePush)
+	}
+	if opts.Generate && opts.Hook != PreCommit {
+		return "", fmt.Errorf("generating applies to %s hooks only", PreCommit)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString(marker + "\n")
+	sb.WriteString("# Fails when generated code is stale; bypass once with --no-verify\n\n")
+	for _, name := range opts.SkipEnv {
+		fmt.Fprintf(&sb, "[ -n \"${%s:-}\" ] && exit 0\n", name)
+	}
+	if len(opts.SkipEnv) > 0 {
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("status=0\n")
+	for _, pkg := range opts.Packages {
+		quoted := shellQuote(filepath.ToSlash(pkg.Dir))
+		paths := quoted
+		dest := ""
+		if pkg.Dest != "" {
+			dest = shellQuote(filepath.ToSlash(pkg.Dest))
+			paths += " " + dest
+		}
+		if opts.Generate {
+			fmt.Fprintf(&sb, "git diff --cached | mantra generate --diff %s || status=1\n", quoted)
+			// Only Go files, leaving out the backups and markers kept for mantra rollback
+			if dest != "" {
+				fmt.Fprintf(&sb, "[ ! -e %s ] || git add -u -- %s || status=1\n", dest, dest)
+				fmt.Fprintf(&sb, "for f in %s/*.go; do [ ! -e \"$f\" ] || git add -- \"$f\" || status=1; done\n", dest)
+			}
+		}
+		fmt.Fprintf(&sb, "mantra check %s || status=1\n", quoted)
+		if opts.Hook == PreCommit {
+			fmt.Fprintf(&sb, "git diff --quiet -- %s || { echo \"mantra: unstaged changes in \"%s\" would not be committed; stage or stash them\" >&2; status=1; }\n", paths, quoted)
+		}
+	}
+	sb.WriteString("exit $status\n")
+	return sb.String(), nil
+}
+
+// Install writes the hook into the hooks directory of the repository containing dir and
+// returns its path. A hook not written by mantra is only replaced with force
+func Install(ctx context.Context, dir string, opts Options, force bool) (string, error) {
+	script, err := Script(opts)
+	if err != nil {
+		return "", err
+	}
+
+	hooksDir, err := gitPath(ctx, dir, "hooks")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(hooksDir, opts.Hook)
+
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil && !force && !strings.Contains(string(existing), marker):
+		return "", fmt.Errorf("%w: %s", ErrExists, path)
+	case err != nil && !os.IsNotExist(err):
+		return "", fmt.Errorf("failed to read hook: %w", err)
+	}
+
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to make hook executable: %w", err)
+	}
+	return path, nil
+}
+
+// RepoRoot returns the root of the working tree containing dir
+func RepoRoot(ctx context.Context, dir string) (string, error) {
+	return git(ctx, dir, "rev-parse", "--show-toplevel")
+}
+
+// gitPath resolves a path inside the git directory of the repository containing dir,
+// following core.hooksPath for hooks
+func gitPath(ctx context.Context, dir, name string) (string, error) {
+	path, err := git(ctx, dir, "rev-parse", "--git-path", name)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
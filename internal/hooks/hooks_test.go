@@ -0,0 +1,137 @@
+package hooks
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	script, err := Script(Options{Hook: PreCommit, Packages: []Package{{Dir: "pkg/user", Dest: "pkg/user/generated"}, {Dir: "it's"}}, Generate: true, SkipEnv: []string{"CI", "MANTRA_SKIP"}})
+	if err != nil {
+		t.Fatalf("Failed to render script: %v", err)
+	}
+	for _, want := range []string{
+		"#!/bin/sh\n" + marker + "\n",
+		"[ -n \"${CI:-}\" ] && exit 0\n[ -n \"${MANTRA_SKIP:-}\" ] && exit 0\n",
+		"git diff --cached | mantra generate --diff 'pkg/user' || status=1\n" +
+			"[ ! -e 'pkg/user/generated' ] || git add -u -- 'pkg/user/generated' || status=1\n" +
+			"for f in 'pkg/user/generated'/*.go; do [ ! -e \"$f\" ] || git add -- \"$f\" || status=1; done\n" +
+			"mantra check 'pkg/user' || status=1\n" +
+			"git diff --quiet -- 'pkg/user' 'pkg/user/generated' || {",
+		"mantra check 'it'\\''s' || status=1\ngit diff --quiet -- 'it'\\''s' || {",
+		"exit $status\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected %q in the script:\n%s", want, script)
+		}
+	}
+
+	if _, err := Script(Options{Hook: PrePush, Generate: true}); err == nil {
+		t.Error("Expected an error generating in a pre-push hook")
+	}
+	if _, err := Script(Options{Hook: "post-merge"}); err == nil {
+		t.Error("Expected an error for an unsupported hook")
+	}
+}
+
+func TestInstall(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to init repository: %v\n%s", err, out)
+	}
+	ctx := t.Context()
+	opts := Options{Hook: PreCommit, Packages: []Package{{Dir: "."}}}
+
+	path, err := Install(ctx, dir, opts, false)
+	if err != nil {
+		t.Fatalf("Failed to install hook: %v", err)
+	}
+	if want := filepath.Join(dir, ".git", "hooks", PreCommit); path != want {
+		t.Errorf("Expected hook at %s, got %s", want, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm()&0111 == 0 {
+		t.Fatalf("Expected an executable hook, got %v (%v)", info, err)
+	}
+
+	// A mantra hook is replaced, other hooks only with force
+	if _, err := Install(ctx, dir, opts, false); err != nil {
+		t.Errorf("Expected the mantra hook to be replaced, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nmake lint\n"), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+	if _, err := Install(ctx, dir, opts, false); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected ErrExists for a foreign hook, got %v", err)
+	}
+	if _, err := Install(ctx, dir, opts, true); err != nil {
+		t.Errorf("Expected the hook to be replaced with force, got %v", err)
+	}
+	if content, _ := os.ReadFile(path); !strings.Contains(string(content), "mantra check '.'") {
+		t.Errorf("Expected the mantra hook, got:\n%s", content)
+	}
+}
+
+func TestScriptStagesGeneratedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	run := func(name string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(name, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "PATH="+filepath.Join(dir, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+		out, err := cmd.CombinedOutput()
+		if err != nil && name != "sh" {
+			t.Fatalf("%s %v failed: %v\n%s", name, args, err, out)
+		}
+		if err != nil {
+			return "failed: " + string(out)
+		}
+		return string(out)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// A stand-in for mantra that generates a file when asked to and checks nothing
+	write("bin/mantra", "#!/bin/sh\n[ \"$1\" = generate ] && mkdir -p pkg/generated && echo 'package generated' > pkg/generated/gen.go && touch pkg/generated/gen.go.created\nexit 0\n")
+	write("pkg/user.go", "package pkg\n")
+	run("git", "init", "-q")
+	run("git", "-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "init")
+	run("git", "add", "pkg/user.go")
+
+	script, err := Script(Options{Hook: PreCommit, Packages: []Package{{Dir: "pkg", Dest: "pkg/generated"}}, Generate: true})
+	if err != nil {
+		t.Fatalf("Failed to render script: %v", err)
+	}
+	write("hook.sh", script)
+
+	if out := run("sh", "hook.sh"); strings.HasPrefix(out, "failed") {
+		t.Fatalf("Expected the hook to pass, got %s", out)
+	}
+	if staged := run("git", "diff", "--cached", "--name-only"); !strings.Contains(staged, "pkg/generated/gen.go\n") || strings.Contains(staged, ".created") {
+		t.Errorf("Expected only the generated file to be staged, got %q", staged)
+	}
+
+	// Unstaged changes are not what check saw committed
+	write("pkg/user.go", "package pkg\n\nvar x int\n")
+	if out := run("sh", "hook.sh"); !strings.Contains(out, "unstaged changes") {
+		t.Errorf("Expected the hook to fail on unstaged changes, got %q", out)
+	}
+}
@@ -0,0 +1,177 @@
+// Package ignore matches paths against .gitignore and .mantraignore rules
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileNames are the ignore files read in each directory, in order of precedence
+var FileNames = []string{".gitignore", ".mantraignore"}
+
+// rule is one pattern line of an ignore file
+type rule struct {
+	base    string // Slash-separated directory of the ignore file, relative to the root ("" for the root)
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher holds the rules of the ignore files loaded so far. Later rules take precedence,
+// and rules only apply to paths below the directory of their ignore file
+type Matcher struct {
+	root  string
+	rules []rule
+}
+
+// New creates a matcher for root with no rules loaded
+func New(root string) *Matcher {
+	return &Matcher{root: root}
+}
+
+// LoadDir reads the ignore files in relDir (relative to the root, "." for the root) if they exist
+func (m *Matcher) LoadDir(relDir string) error {
+	for _, name := range FileNames {
+		f, err := os.Open(filepath.Join(m.root, relDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = m.AddPatterns(relDir, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPatterns adds gitignore-style patterns that apply below relDir
+func (m *Matcher) AddPatterns(relDir string, r io.Reader) error {
+	base := filepath.ToSlash(filepath.Clean(relDir))
+	if base == "." {
+		base = ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if rule, ok := parseRule(base, scanner.Text()); ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+	return scanner.Err()
+}
+
+// Match reports whether relPath (relative to the root) is ignored. As with git, a path
+// inside an ignored directory is only excluded through that directory, so callers walking
+// the tree should skip directories that match
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		p := relPath
+		if r.base != "" {
+			if !strings.HasPrefix(relPath, r.base+"/") {
+				continue
+			}
+			p = strings.TrimPrefix(relPath, r.base+"/")
+		}
+		if r.re.MatchString(p) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// parseRule converts one ignore file line to a rule; blank lines and comments yield false
+func parseRule(base, line string) (rule, bool) {
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the ignore file's directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if anchored {
+		r.re = regexp.MustCompile("^" + expr + "$")
+	} else {
+		r.re = regexp.MustCompile("(^|/)" + expr + "$")
+	}
+	return r, true
+}
+
+// globToRegexp translates a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			if _, err := regexp.Compile("[" + class + "]"); err != nil {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
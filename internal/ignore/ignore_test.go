@@ -0,0 +1,73 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	m := New(t.TempDir())
+	if err := m.AddPatterns(".", strings.NewReader(`# build output
+bin/
+*.pb.go
+!keep.pb.go
+/generated
+docs/**/*.go
+`)); err != nil {
+		t.Fatalf("AddPatterns failed: %v", err)
+	}
+	if err := m.AddPatterns("internal", strings.NewReader("fixtures\n")); err != nil {
+		t.Fatalf("AddPatterns failed: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"bin", true, true},
+		{"cmd/bin", true, true},
+		{"bin", false, false},
+		{"api/user.pb.go", false, true},
+		{"api/keep.pb.go", false, false},
+		{"generated", true, true},
+		{"pkg/generated", true, false},
+		{"docs/a/b/example.go", false, true},
+		{"docs/example.md", false, false},
+		{"internal/fixtures", true, true},
+		{"fixtures", true, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("tmp/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
+	// .mantraignore is read after .gitignore, so it can re-include paths
+	if err := os.WriteFile(filepath.Join(root, ".mantraignore"), []byte("!tmp/\nlarge/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .mantraignore: %v", err)
+	}
+
+	m := New(root)
+	if err := m.LoadDir("."); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	if m.Match("tmp", true) {
+		t.Error("Expected tmp to be re-included by .mantraignore")
+	}
+	if !m.Match("large", true) {
+		t.Error("Expected large to be ignored")
+	}
+	if err := m.LoadDir("missing"); err != nil {
+		t.Errorf("Expected directories without ignore files to be skipped, got %v", err)
+	}
+}
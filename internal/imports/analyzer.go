@@ -4,6 +4,7 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"path"
 	"sort"
 	"strings"
 )
@@ -187,3 +188,79 @@ func ExtractBlankImports(fileContent string) []string {
 
 	return blankImports
 }
+
+// UsedBlankImports returns the blank imports referenced through package selectors in code.
+// A blank import is matched by the last element of its path
+func UsedBlankImports(code string, blankImports []string) []string {
+	if len(blankImports) == 0 {
+		return nil
+	}
+	byName := make(map[string]string, len(blankImports))
+	blank := make(map[string]bool, len(blankImports))
+	for _, importPath := range blankImports {
+		byName[path.Base(importPath)] = importPath
+		blank[importPath] = true
+	}
+
+	var used []string
+	for _, importPath := range ReferencedImports(code, byName) {
+		if blank[importPath] {
+			used = append(used, importPath)
+		}
+	}
+	return used
+}
+
+// ReferencedImports returns the import paths referenced through package selectors in code.
+// fileImports maps identifiers available in the file to their import paths; identifiers not
+// found there are resolved through StandardPackages
+func ReferencedImports(code string, fileImports map[string]string) []string {
+	fileContent := "package temp\nfunc temp() {\n" + code + "\n}"
+
+	node, err := parser.ParseFile(token.NewFileSet(), "temp.go", fileContent, 0)
+	if err != nil {
+		return nil
+	}
+
+	referenced := make(map[string]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if path, exists := fileImports[ident.Name]; exists {
+			referenced[path] = true
+		} else if path, exists := StandardPackages[ident.Name]; exists {
+			referenced[path] = true
+		}
+		return true
+	})
+
+	var paths []string
+	for path := range referenced {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// MatchForbidden returns the rule in forbidden matching importPath.
+// A rule matches its exact path, and rules ending in "/..." also match every package below it
+func MatchForbidden(importPath string, forbidden []string) (string, bool) {
+	for _, rule := range forbidden {
+		if prefix, ok := strings.CutSuffix(rule, "/..."); ok {
+			if importPath == prefix || strings.HasPrefix(importPath, prefix+"/") {
+				return rule, true
+			}
+			continue
+		}
+		if importPath == rule {
+			return rule, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,90 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestForbiddenImportDetection(t *testing.T) {
+	code := `
+	p := unsafe.Pointer(&x)
+	v := refl.ValueOf(p)
+	return fmt.Sprint(v)
+	`
+	fileImports := map[string]string{
+		"unsafe": "unsafe",
+		"refl":   "reflect",
+	}
+
+	referenced := ReferencedImports(code, fileImports)
+	expected := []string{"fmt", "reflect", "unsafe"}
+	if !reflect.DeepEqual(referenced, expected) {
+		t.Fatalf("Expected %v, got %v", expected, referenced)
+	}
+
+	forbidden := []string{"unsafe", "example.com/legacy/..."}
+	tests := []struct {
+		path    string
+		matched bool
+	}{
+		{"unsafe", true},
+		{"reflect", false},
+		{"example.com/legacy", true},
+		{"example.com/legacy/store", true},
+		{"example.com/legacyx", false},
+	}
+	for _, tt := range tests {
+		if _, matched := MatchForbidden(tt.path, forbidden); matched != tt.matched {
+			t.Errorf("MatchForbidden(%q) = %v, want %v", tt.path, matched, tt.matched)
+		}
+	}
+}
+
+func TestUsedBlankImports(t *testing.T) {
+	code := `
+	id := uuid.New()
+	return strings.ToUpper(id.String())
+	`
+	blank := []string{"github.com/google/uuid", "github.com/lib/pq", "strings"}
+
+	used := UsedBlankImports(code, blank)
+	expected := []string{"github.com/google/uuid", "strings"}
+	if !reflect.DeepEqual(used, expected) {
+		t.Fatalf("Expected %v, got %v", expected, used)
+	}
+}
+
+func TestSelfImports(t *testing.T) {
+	code := `
+	v := gen.Version
+	return fmt.Sprint(v)
+	`
+	fileImports := map[string]string{"gen": "example.com/app/generated"}
+
+	if self := SelfImports(code, fileImports, "example.com/app/generated"); !reflect.DeepEqual(self, []string{"example.com/app/generated"}) {
+		t.Errorf("Expected the destination import, got %v", self)
+	}
+	if self := SelfImports(code, fileImports, "example.com/app/other"); self != nil {
+		t.Errorf("Expected no self imports, got %v", self)
+	}
+}
+
+func TestPackagePath(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	tests := map[string]string{
+		root:                                   "example.com/app",
+		filepath.Join(root, "internal", "gen"): "example.com/app/internal/gen", // Not created yet
+	}
+	for dir, expected := range tests {
+		got, err := PackagePath(dir)
+		if err != nil || got != expected {
+			t.Errorf("PackagePath(%q) = %q, %v; want %q", dir, got, err, expected)
+		}
+	}
+}
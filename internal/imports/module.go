@@ -0,0 +1,53 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+)
+
+// PackagePath returns the import path of the package in dir, derived from the go.mod of the
+// enclosing module. dir does not need to exist yet, so the path of a destination package
+// is known before anything is generated into it
+func PackagePath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			modulePath := modfile.ModulePath(data)
+			if modulePath == "" {
+				return "", fmt.Errorf("no module path in %s", filepath.Join(root, "go.mod"))
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(modulePath, filepath.ToSlash(rel)), nil
+		}
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("no go.mod found above %s", abs)
+		}
+	}
+}
+
+// SelfImports returns the import paths referenced by code that are the package the code is
+// generated into. Code of a package referring to the package itself through an import does
+// not compile: Go reports an import cycle
+func SelfImports(code string, fileImports map[string]string, selfPath string) []string {
+	if selfPath == "" {
+		return nil
+	}
+	var self []string
+	for _, importPath := range ReferencedImports(code, fileImports) {
+		if importPath == selfPath {
+			self = append(self, importPath)
+		}
+	}
+	return self
+}
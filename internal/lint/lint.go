@@ -0,0 +1,230 @@
+// Package lint flags instructions likely to produce the wrong implementation before any
+// tokens are spent on them: vague wording, error results the instruction says nothing
+// about, and identifiers that do not resolve in the package
+package lint
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/tools"
+)
+
+// Kinds of issues
+const (
+	KindVague             = "vague"
+	KindErrorHandling     = "error-handling"
+	KindUnknownIdentifier = "unknown-identifier"
+	KindUnknownTool       = "unknown-tool" // Named by // mantra:tools but not selectable
+	KindModel             = "model"        // Raised by the model review
+)
+
+// Issue is one problem found in an instruction
+type Issue struct {
+	Kind    string
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Kind + ": " + i.Message
+}
+
+// Resolver resolves identifiers against the target's package
+type Resolver interface {
+	// HasIdentifier reports whether name is a package-level declaration or import,
+	// resolving selectors of qualified names
+	HasIdentifier(name string) bool
+}
+
+// minWords is the shortest instruction not flagged as vague
+const minWords = 3
+
+// vaguePhrases say nothing about the expected behavior
+var vaguePhrases = []string{
+	"do the thing", "do something", "something", "stuff", "whatever", "etc", "and so on",
+	"as needed", "as appropriate", "appropriately", "properly", "correctly", "handle it",
+	"implement this", "implement it", "todo", "tbd", "same as before", "you know",
+}
+
+// errorWords show that an instruction covers the failure cases of an error result
+var errorWords = map[string]bool{
+	"error": true, "errors": true, "err": true, "fail": true, "fails": true, "failure": true,
+	"failed": true, "invalid": true, "wrap": true, "wraps": true, "wrapped": true,
+	"missing": true, "if": true, "unless": true, "when": true, "otherwise": true,
+	"propagate": true, "propagates": true,
+}
+
+var (
+	backtickPattern   = regexp.MustCompile("`([^`]+)`")
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+)
+
+// Check lints the instruction of a target. Identifiers are only checked when resolver is
+// not nil
+func Check(target *parser.Target, resolver Resolver) []Issue {
+	var issues []Issue
+	words := strings.Fields(strings.ToLower(backtickPattern.ReplaceAllString(target.Instruction, " x ")))
+
+	if len(words) < minWords {
+		issues = append(issues, Issue{KindVague, fmt.Sprintf("instruction has fewer than %d words", minWords)})
+	}
+	padded := " " + strings.Join(trimWords(words), " ") + " "
+	for _, phrase := range vaguePhrases {
+		if strings.Contains(padded, " "+phrase+" ") {
+			issues = append(issues, Issue{KindVague, fmt.Sprintf("vague wording %q", phrase)})
+		}
+	}
+
+	if returnsError(target) && !mentionsErrors(trimWords(words)) {
+		issues = append(issues, Issue{KindErrorHandling, "returns an error but the instruction does not say when"})
+	}
+
+	for _, name := range target.Tools {
+		if !slices.Contains(tools.Selectable, name) {
+			issues = append(issues, Issue{KindUnknownTool, fmt.Sprintf("mantra:tools lists %q (valid: %s)", name, strings.Join(tools.Selectable, ", "))})
+		}
+	}
+
+	if resolver != nil {
+		for _, ident := range Identifiers(target.Instruction) {
+			if !Known(ident, target, resolver) {
+				issues = append(issues, Issue{KindUnknownIdentifier, fmt.Sprintf("%s does not resolve in the package", ident)})
+			}
+		}
+	}
+	return issues
+}
+
+// Completer sends a single prompt to a model
+type Completer interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// modelPrompt asks for one issue per line so the reply can be parsed without a schema
+const modelPrompt = `You review instructions given to a code generator before it implements a Go function.
+List what is ambiguous or underspecified in the instruction below: unclear behavior,
+missing edge cases, or unstated error handling. Answer with one issue per line, each
+starting with "- ". Answer with exactly "OK" if the instruction is clear enough.
+
+Signature: %s
+Instruction: %s`
+
+// CheckWithModel asks the model to review the instruction of a target
+func CheckWithModel(ctx context.Context, completer Completer, target *parser.Target) ([]Issue, error) {
+	reply, err := completer.Generate(ctx, fmt.Sprintf(modelPrompt, target.GetFunctionSignature(), target.Instruction))
+	if err != nil {
+		return nil, fmt.Errorf("model review failed: %w", err)
+	}
+
+	var issues []Issue
+	for _, line := range strings.Split(reply, "\n") {
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok && strings.TrimSpace(item) != "" {
+			issues = append(issues, Issue{KindModel, strings.TrimSpace(item)})
+		}
+	}
+	return issues, nil
+}
+
+// returnsError reports whether the last result of the target is an error
+func returnsError(target *parser.Target) bool {
+	return len(target.Returns) > 0 && target.Returns[len(target.Returns)-1].Type == "error"
+}
+
+func mentionsErrors(words []string) bool {
+	for _, word := range words {
+		if errorWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// trimWords strips punctuation around each word
+func trimWords(words []string) []string {
+	trimmed := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.Trim(word, `.,;:!?()"'`); word != "" {
+			trimmed = append(trimmed, word)
+		}
+	}
+	return trimmed
+}
+
+// Identifiers returns the words of an instruction that look like Go identifiers:
+// backticked names, qualified names (pkg.Name) and mixed-case words (GetUser, userID)
+func Identifiers(instruction string) []string {
+	var idents []string
+	seen := make(map[string]bool)
+	add := func(word string) {
+		word = strings.TrimSuffix(word, "()")
+		if identifierPattern.MatchString(word) && !seen[word] {
+			seen[word] = true
+			idents = append(idents, word)
+		}
+	}
+
+	for _, match := range backtickPattern.FindAllStringSubmatch(instruction, -1) {
+		add(match[1])
+	}
+
+	for _, word := range strings.Fields(backtickPattern.ReplaceAllString(instruction, " ")) {
+		word = strings.TrimRight(strings.TrimLeft(word, `("'`), `.,;:!?()"'`)
+		if isQualifiedName(word) || isMixedCase(word) {
+			add(word)
+		}
+	}
+
+	return idents
+}
+
+// isQualifiedName reports whether word looks like pkg.Name rather than an abbreviation such as e.g
+func isQualifiedName(word string) bool {
+	parts := strings.Split(word, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, part := range parts {
+		if len(part) < 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// isMixedCase reports whether word has an upper case letter after its first character,
+// excluding all-caps acronyms such as JSON or ID
+func isMixedCase(word string) bool {
+	hasLower := false
+	innerUpper := false
+	for i, r := range word {
+		if unicode.IsLower(r) {
+			hasLower = true
+		} else if i > 0 && unicode.IsUpper(r) {
+			innerUpper = true
+		}
+	}
+	return hasLower && innerUpper
+}
+
+// Known reports whether ident names a parameter, receiver, builtin or package declaration
+func Known(ident string, target *parser.Target, resolver Resolver) bool {
+	root, _, _ := strings.Cut(ident, ".")
+	if target.Receiver != nil && target.Receiver.Name == root {
+		return true
+	}
+	for _, param := range target.Params {
+		if param.Name == root {
+			return true
+		}
+	}
+	if root == ident && types.Universe.Lookup(ident) != nil {
+		return true
+	}
+	return resolver.HasIdentifier(ident)
+}
@@ -0,0 +1,89 @@
+package lint
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestIdentifiers(t *testing.T) {
+	instruction := "Return the JSON encoding of `cfg` using json.Marshal; see e.g. the userID field and GetUser()."
+
+	got := Identifiers(instruction)
+	expected := []string{"cfg", "json.Marshal", "userID", "GetUser"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Identifiers() = %v, want %v", got, expected)
+	}
+}
+
+// declarations resolves a fixed set of names
+type declarations map[string]bool
+
+func (d declarations) HasIdentifier(name string) bool { return d[name] }
+
+func TestCheck(t *testing.T) {
+	resolver := declarations{"User": true, "Store.Find": true}
+
+	tests := []struct {
+		instruction string
+		returns     []parser.Return
+		tools       []string
+		kinds       []string
+	}{
+		{"Load the User with the given id from Store.Find", []parser.Return{{Type: "*User"}}, nil, nil},
+		{"Do the thing", nil, nil, []string{KindVague}},
+		{"Load it", nil, nil, []string{KindVague}},
+		{"Fetch the user record properly", nil, nil, []string{KindVague}},
+		{"Load the user from the database", []parser.Return{{Type: "*User"}, {Type: "error"}}, nil, []string{KindErrorHandling}},
+		{"Load the user, returning an error if it is missing", []parser.Return{{Type: "*User"}, {Type: "error"}}, nil, nil},
+		{"Load the user through UserCache.Get", []parser.Return{{Type: "*User"}}, nil, []string{KindUnknownIdentifier}},
+		{"Combine `id` with the prefix", nil, nil, nil},
+		{"Hash the id with the pepper", nil, []string{"inspect", "read_func"}, []string{KindUnknownTool}},
+	}
+
+	for _, tt := range tests {
+		target := &parser.Target{
+			Name:        "Load",
+			Params:      []parser.Param{{Name: "id", Type: "string"}},
+			Returns:     tt.returns,
+			Instruction: tt.instruction,
+			Tools:       tt.tools,
+		}
+		var kinds []string
+		for _, issue := range Check(target, resolver) {
+			kinds = append(kinds, issue.Kind)
+		}
+		if !reflect.DeepEqual(kinds, tt.kinds) {
+			t.Errorf("%q: expected issues %v, got %v", tt.instruction, tt.kinds, kinds)
+		}
+	}
+}
+
+// reply is a Completer answering every prompt with the same text
+type reply string
+
+func (r reply) Generate(ctx context.Context, prompt string) (string, error) {
+	return string(r), nil
+}
+
+func TestCheckWithModel(t *testing.T) {
+	target := &parser.Target{Name: "Load", Instruction: "Load the user"}
+
+	issues, err := CheckWithModel(context.Background(), reply("Issues:\n- Which store to read from\n- What to do when the user is missing\n"), target)
+	if err != nil {
+		t.Fatalf("CheckWithModel failed: %v", err)
+	}
+	if len(issues) != 2 || issues[1].Message != "What to do when the user is missing" {
+		t.Errorf("Expected 2 model issues, got %v", issues)
+	}
+
+	issues, err = CheckWithModel(context.Background(), reply("OK"), target)
+	if err != nil {
+		t.Fatalf("CheckWithModel failed: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Authenticator adds credentials to requests sent to the AI provider
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+}
+
+// TokenSource returns the credential to send with a request
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken returns a token source that always returns token
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// CachedToken returns a token source that reuses the token of src until ttl has passed,
+// for short-lived tokens such as JWTs. A zero ttl fetches the token once. Safe for
+// concurrent use by the clients of parallel targets
+func CachedToken(src TokenSource, ttl time.Duration) TokenSource {
+	var (
+		mu      sync.Mutex
+		token   string
+		fetched time.Time
+	)
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !fetched.IsZero() && (ttl == 0 || time.Since(fetched) < ttl) {
+			return token, nil
+		}
+		t, err := src(ctx)
+		if err != nil {
+			return "", err
+		}
+		token, fetched = t, time.Now()
+		return token, nil
+	}
+}
+
+// HeaderAuth sends a token in a request header, optionally prefixed by a scheme
+// (e.g. "Authorization: Bearer <token>" or "api-key: <token>")
+type HeaderAuth struct {
+	Header string // Header name (default: Authorization)
+	Scheme string // Prefix separated from the token by a space (empty sends the token alone)
+	Token  TokenSource
+}
+
+// BearerAuth returns the default authenticator, sending apiKey as a bearer token
+func BearerAuth(apiKey string) *HeaderAuth {
+	return &HeaderAuth{Scheme: "Bearer", Token: StaticToken(apiKey)}
+}
+
+// Authenticate sets the header on req
+func (a *HeaderAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := a.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	header := a.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	if a.Scheme != "" {
+		token = a.Scheme + " " + token
+	}
+	req.Header.Set(header, token)
+	return nil
+}
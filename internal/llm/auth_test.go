@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestOpenAIClientHeadersAndAuth(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	fetches := 0
+	token := CachedToken(func(context.Context) (string, error) {
+		fetches++
+		return "jwt-" + strconv.Itoa(fetches), nil
+	}, time.Hour)
+
+	client, err := NewOpenAIClientWithOptions(&OpenAIClientOptions{
+		BaseURL: server.URL,
+		Model:   "test",
+		Headers: map[string]string{"X-Tenant-ID": "acme", "X-Title": "custom"},
+		Auth:    &HeaderAuth{Header: "api-key", Token: token},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for range 2 {
+		if _, err := client.makeRequest(context.Background(), OpenAIRequest{Model: "test"}); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+	}
+
+	if got.Get("X-Tenant-ID") != "acme" || got.Get("X-Title") != "custom" {
+		t.Errorf("Expected configured headers, got %v", got)
+	}
+	if got.Get("api-key") != "jwt-1" || got.Get("Authorization") != "" {
+		t.Errorf("Expected token in api-key header only, got %v", got)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected cached token to be fetched once, got %d", fetches)
+	}
+
+	// The default sends the API key as a bearer token
+	client, err = NewOpenAIClientWithOptions(&OpenAIClientOptions{BaseURL: server.URL, APIKey: "sk-test"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.makeRequest(context.Background(), OpenAIRequest{Model: "test"}); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if got.Get("Authorization") != "Bearer sk-test" {
+		t.Errorf("Expected bearer token, got %q", got.Get("Authorization"))
+	}
+}
@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Prompt cache styles, selecting the provider-specific fields that mark prompts as cacheable
+const (
+	PromptCacheAnthropic = "anthropic" // cache_control breakpoints on content blocks (Anthropic and Gemini models, e.g. through OpenRouter)
+	PromptCacheOpenAI    = "openai"    // prompt_cache_key routing requests with the same prefix to the same cache
+)
+
+// CacheControl marks the end of a prompt prefix the provider should cache
+type CacheControl struct {
+	Type string `json:"type"`
+}
+
+// ephemeralCache is the only cache_control type providers accept
+var ephemeralCache = &CacheControl{Type: "ephemeral"}
+
+// contentPart is a text block of a message sent with a cache breakpoint
+type contentPart struct {
+	Type         string        `json:"type"`
+	Text         string        `json:"text"`
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+}
+
+// MarshalJSON sends messages marked for caching with their content as a text block carrying
+// cache_control, the only form breakpoints can be set in. Other messages keep string content
+func (m OpenAIMessage) MarshalJSON() ([]byte, error) {
+	type message OpenAIMessage
+	if m.CacheControl == nil {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		message
+		Content []contentPart `json:"content"`
+	}{
+		message: message(m),
+		Content: []contentPart{{Type: "text", Text: m.Content, CacheControl: m.CacheControl}},
+	})
+}
+
+// SetSharedContext sets context shared by every target of the package. It is sent as a
+// message of its own between the system prompt and the prompt, so it stays part of the
+// prefix providers cache
+func (c *OpenAIClient) SetSharedContext(sharedContext string) {
+	c.sharedContext = sharedContext
+}
+
+// PromptCache returns the prompt cache style requests are sent with ("" when disabled)
+func (c *OpenAIClient) PromptCache() string {
+	return c.promptCache
+}
+
+// initialMessages returns the first messages of a conversation: the system prompt, the
+// shared context when set, and the prompt. With Anthropic-style caching each ends a cache
+// breakpoint, so later targets reuse the system prompt and shared context and later rounds
+// of a target reuse its prompt as well
+func (c *OpenAIClient) initialMessages(prompt string) []OpenAIMessage {
+	messages := []OpenAIMessage{{Role: "system", Content: c.systemPrompt}}
+	if c.sharedContext != "" {
+		messages = append(messages, OpenAIMessage{Role: "user", Content: c.sharedContext})
+	}
+	messages = append(messages, OpenAIMessage{Role: "user", Content: prompt})
+
+	if c.promptCache == PromptCacheAnthropic {
+		for i := range messages {
+			if messages[i].Content != "" {
+				messages[i].CacheControl = ephemeralCache
+			}
+		}
+	}
+	return messages
+}
+
+// promptCacheKey returns the key requests sharing the system prompt and shared context are
+// routed to the same cache with, for providers that cache prefixes automatically
+func (c *OpenAIClient) promptCacheKey() string {
+	if c.promptCache != PromptCacheOpenAI {
+		return ""
+	}
+	h := sha256.New()
+	for _, part := range []string{c.model, c.systemPrompt, c.sharedContext} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return "mantra-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
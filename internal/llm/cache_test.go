@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// terminalExecutor finishes the conversation on the first tool call
+type terminalExecutor struct{}
+
+func (terminalExecutor) Execute(ctx context.Context, toolName string, params map[string]any) (any, error) {
+	return map[string]any{"success": true}, nil
+}
+
+func (terminalExecutor) IsTerminal(toolName string) bool { return true }
+
+func TestPromptCache(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, req)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"result","arguments":"{}"}}]}}],
+			"usage":{"prompt_tokens":2000,"completion_tokens":10,"total_tokens":2010,"prompt_tokens_details":{"cached_tokens":1500}}}`))
+	}))
+	defer server.Close()
+
+	generate := func(style string) (*OpenAIClient, map[string]any) {
+		client, err := NewOpenAIClientWithOptions(&OpenAIClientOptions{BaseURL: server.URL, Model: "m", PromptCache: style})
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		client.SetSystemPrompt("system")
+		client.SetSharedContext("package declarations")
+		if _, err := client.Generate(context.Background(), "target prompt", nil, terminalExecutor{}); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		return client, requests[len(requests)-1]
+	}
+
+	client, req := generate(PromptCacheAnthropic)
+	messages := req["messages"].([]any)
+	if len(messages) != 3 {
+		t.Fatalf("Expected system, shared context and prompt messages, got %v", messages)
+	}
+	for i, text := range []string{"system", "package declarations", "target prompt"} {
+		parts, ok := messages[i].(map[string]any)["content"].([]any)
+		if !ok || len(parts) != 1 {
+			t.Fatalf("Expected message %d to be sent as one content block, got %v", i, messages[i])
+		}
+		part := parts[0].(map[string]any)
+		cache, _ := part["cache_control"].(map[string]any)
+		if part["text"] != text || cache["type"] != "ephemeral" {
+			t.Errorf("Expected %q with an ephemeral cache breakpoint, got %v", text, part)
+		}
+	}
+	if _, ok := req["prompt_cache_key"]; ok {
+		t.Errorf("Expected no prompt_cache_key with anthropic caching, got %v", req["prompt_cache_key"])
+	}
+	if usage := client.Usage(); usage.CachedTokens != 1500 {
+		t.Errorf("Expected 1500 cached tokens, got %d", usage.CachedTokens)
+	}
+
+	_, first := generate(PromptCacheOpenAI)
+	_, second := generate(PromptCacheOpenAI)
+	key, _ := first["prompt_cache_key"].(string)
+	if key == "" || second["prompt_cache_key"] != key {
+		t.Errorf("Expected the same prompt_cache_key for the same prefix, got %v and %v", first["prompt_cache_key"], second["prompt_cache_key"])
+	}
+	if content := first["messages"].([]any)[0].(map[string]any)["content"]; content != "system" {
+		t.Errorf("Expected string content without cache breakpoints, got %v", content)
+	}
+
+	_, req = generate("")
+	if _, ok := req["prompt_cache_key"]; ok {
+		t.Errorf("Expected no prompt_cache_key with caching disabled")
+	}
+}
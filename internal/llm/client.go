@@ -6,15 +6,24 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/rail44/mantra/internal/ratelimit"
 )
 
 // ClientConfig represents the configuration for connecting to an AI provider
 type ClientConfig struct {
-	URL      string        // URL for the API endpoint (e.g., "http://localhost:11434/v1" for Ollama)
-	APIKey   string        // API key for providers that require authentication
-	Model    string        // Model to use
-	Timeout  time.Duration // Request timeout
-	Provider []string      // OpenRouter provider specification (e.g., ["Cerebras"])
+	URL              string             // URL for the API endpoint (e.g., "http://localhost:11434/v1" for Ollama)
+	APIKey           string             // API key for providers that require authentication
+	Model            string             // Model to use
+	Timeout          time.Duration      // Request timeout
+	Provider         *ProviderSpec      // OpenRouter provider routing preferences
+	Transport        *TransportConfig   // Proxy and TLS settings (nil uses the defaults)
+	RateLimiter      *ratelimit.Limiter // Shared pacing of requests to the provider (nil disables)
+	Headers          map[string]string  // Extra headers sent with every request
+	Auth             Authenticator      // Request authentication (nil sends APIKey as a bearer token)
+	PromptCache      string             // Prompt cache style, PromptCacheAnthropic or PromptCacheOpenAI (empty disables)
+	KeepRounds       int                // Rounds whose tool results are kept in full; older ones are summarized (0 keeps all)
+	MaxParallelTools int                // Tool calls of one round run at the same time (0 uses DefaultMaxParallelTools)
 }
 
 type Client struct {
@@ -42,13 +51,19 @@ func NewClient(clientConfig *ClientConfig, httpClient *http.Client, logger *slog
 
 	// Create provider with provided HTTP client
 	opts := &OpenAIClientOptions{
-		APIKey:       clientConfig.APIKey,
-		BaseURL:      url,
-		Model:        clientConfig.Model,
-		Temperature:  0.7,        // Default, will be overridden by phase
-		HTTPClient:   httpClient, // Can be nil, will be created if needed
-		ProviderSpec: clientConfig.Provider,
-		Logger:       logger,
+		APIKey:           clientConfig.APIKey,
+		BaseURL:          url,
+		Model:            clientConfig.Model,
+		Temperature:      0.7,        // Default, will be overridden by phase
+		HTTPClient:       httpClient, // Can be nil, will be created if needed
+		ProviderSpec:     clientConfig.Provider,
+		RateLimiter:      clientConfig.RateLimiter,
+		Headers:          clientConfig.Headers,
+		Auth:             clientConfig.Auth,
+		PromptCache:      clientConfig.PromptCache,
+		KeepRounds:       clientConfig.KeepRounds,
+		MaxParallelTools: clientConfig.MaxParallelTools,
+		Logger:           logger,
 	}
 
 	provider, err := NewOpenAIClientWithOptions(opts)
@@ -73,6 +88,11 @@ func (c *Client) GetProviderName() string {
 	return c.provider.Name()
 }
 
+// Usage returns the token usage accumulated by this client
+func (c *Client) Usage() Usage {
+	return c.provider.Usage()
+}
+
 // SetTools sets the tools available for the AI to use
 func (c *Client) SetTools(tools []Tool, executor ToolExecutor) {
 	c.tools = tools
@@ -84,11 +104,26 @@ func (c *Client) SetTemperature(temperature float32) {
 	c.provider.SetTemperature(temperature)
 }
 
+// SetMaxRounds sets the rounds of tool calls a generation may take (0: DefaultMaxRounds)
+func (c *Client) SetMaxRounds(maxRounds int) {
+	c.provider.SetMaxRounds(maxRounds)
+}
+
 // SetSystemPrompt sets the system prompt
 func (c *Client) SetSystemPrompt(systemPrompt string) {
 	c.provider.SetSystemPrompt(systemPrompt)
 }
 
+// SetSharedContext sets context shared by every target, sent before the prompt so providers can cache it
+func (c *Client) SetSharedContext(sharedContext string) {
+	c.provider.SetSharedContext(sharedContext)
+}
+
+// CachesPrompts reports whether requests mark prompts as cacheable
+func (c *Client) CachesPrompts() bool {
+	return c.provider.PromptCache() != ""
+}
+
 // SetLogger sets the logger for the client
 func (c *Client) SetLogger(logger *slog.Logger) {
 	c.logger = logger
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// gzipRoundTripper compresses request bodies with gzip. When the server answers
+// 415 Unsupported Media Type, the request is retried uncompressed and compression
+// is disabled for the rest of the run
+type gzipRoundTripper struct {
+	next        http.RoundTripper
+	unsupported atomic.Bool
+}
+
+// RoundTrip sends req with a gzip-compressed body when compression is supported
+func (t *gzipRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || t.unsupported.Load() || req.Header.Get("Content-Encoding") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to compress request body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress request body: %w", err)
+	}
+
+	gzipReq := withBody(req, compressed.Bytes())
+	gzipReq.Header.Set("Content-Encoding", "gzip")
+	resp, err := t.next.RoundTrip(gzipReq)
+	if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, err
+	}
+
+	// The server does not accept compressed bodies; retry as is
+	resp.Body.Close()
+	t.unsupported.Store(true)
+	return t.next.RoundTrip(withBody(req, body))
+}
+
+// withBody returns a copy of req that sends body
+func withBody(req *http.Request, body []byte) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.ContentLength = int64(len(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return clone
+}
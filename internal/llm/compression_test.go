@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipRoundTripper(t *testing.T) {
+	var received []string
+	rejectGzip := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			if rejectGzip {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("Failed to open gzip body: %v", err)
+				return
+			}
+			body = zr
+		}
+		data, _ := io.ReadAll(body)
+		received = append(received, r.Header.Get("Content-Encoding")+":"+string(data))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClient(0, &TransportConfig{CompressRequests: true})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	post := func() {
+		resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"a":1}`))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Unexpected status %d", resp.StatusCode)
+		}
+	}
+
+	post()
+	rejectGzip = true
+	post() // Rejected compressed, retried uncompressed
+	post() // Compression stays disabled
+
+	expected := []string{`gzip:{"a":1}`, `:{"a":1}`, `:{"a":1}`}
+	if strings.Join(received, " ") != strings.Join(expected, " ") {
+		t.Errorf("Server received %v, want %v", received, expected)
+	}
+}
@@ -2,10 +2,16 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
+// ErrToolsUnsupported is returned when the model answers the first two rounds with code
+// instead of calling a tool, as models without tool-calling support do
+var ErrToolsUnsupported = errors.New("model answers with code instead of calling tools")
+
 // Generate sends a prompt with tool definitions and handles tool calls
 func (c *OpenAIClient) Generate(ctx context.Context, prompt string, tools []Tool, executor ToolExecutor) (string, error) {
 	var toolExecutionTime time.Duration
@@ -18,28 +24,42 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, tools []Tool
 	// System prompt is set but not logged
 
 	// Build initial messages with system prompt
-	messages := []OpenAIMessage{
-		{
-			Role:    "system",
-			Content: c.systemPrompt,
-		},
-		{
-			Role:    "user",
-			Content: prompt,
-		},
-	}
+	messages := c.initialMessages(prompt)
+	cacheKey := c.promptCacheKey()
 
 	// Maximum rounds of tool calls to prevent infinite loops
-	const maxRounds = 30
+	maxRounds := c.maxRounds
+	if maxRounds <= 0 {
+		maxRounds = DefaultMaxRounds
+	}
+
+	hasResultTool := false
+	for _, tool := range tools {
+		if tool.Function.Name == "result" {
+			hasResultTool = true
+			break
+		}
+	}
 
 	// Track if result tool has been called
 	resultToolCalled := false
+	toolsCalled := false
+
+	// Calls made so far by tool and arguments, see executeToolsParallel
+	repeats := make(map[string]int)
+
+	// Round each tool result was added in, until it is pruned
+	resultRounds := make(map[int]int)
 
 	for round := 0; round < maxRounds; round++ {
 
 		// Use the current temperature set by the phase
 		temperature := c.currentTemperature
 
+		if c.keepToolRounds > 0 {
+			pruneToolResults(messages, resultRounds, round, c.keepToolRounds)
+		}
+
 		req := OpenAIRequest{
 			Model:             c.model,
 			Messages:          messages,
@@ -48,6 +68,11 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, tools []Tool
 			ToolChoice:        "auto",
 			ParallelToolCalls: true,
 			Provider:          c.providerSpec,
+			PromptCacheKey:    cacheKey,
+		}
+		if len(tools) == 0 {
+			// Prompts without tools, as sent to models that cannot call them
+			req.ToolChoice, req.ParallelToolCalls = nil, false
 		}
 
 		// Make API call
@@ -58,11 +83,20 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, tools []Tool
 			return "", err
 		}
 
+		c.usage.PromptTokens += resp.Usage.PromptTokens
+		c.usage.CompletionTokens += resp.Usage.CompletionTokens
+		c.usage.CachedTokens += resp.Usage.PromptTokensDetails.CachedTokens
+		c.usage.Rounds++
+
 		if len(resp.Choices) == 0 {
 			return "", fmt.Errorf("no response choices returned")
 		}
 
 		responseMsg := resp.Choices[0].Message
+		if executor == nil {
+			// Without an executor tool calls cannot be answered
+			responseMsg.ToolCalls = nil
+		}
 
 		// Fix missing Type field for Mistral API compatibility
 		for i := range responseMsg.ToolCalls {
@@ -86,13 +120,10 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, tools []Tool
 
 		// Check if we have tool calls
 		if len(responseMsg.ToolCalls) == 0 {
-			// Check if there's a result tool available but not used
-			hasResultTool := false
-			for _, tool := range tools {
-				if tool.Function.Name == "result" {
-					hasResultTool = true
-					break
-				}
+			// Still answering with code after the reminder, the model cannot call tools
+			if hasResultTool && !toolsCalled && round == 1 && looksLikeCode(responseMsg.Content) {
+				logger.Warn("Model answered with code instead of calling tools")
+				return "", ErrToolsUnsupported
 			}
 
 			// If result tool exists but wasn't called yet, prompt the AI to use it
@@ -114,15 +145,32 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, tools []Tool
 			return "", fmt.Errorf("model returned empty response without tool calls")
 		}
 
+		toolsCalled = true
+		if c.usage.ToolCalls == nil {
+			c.usage.ToolCalls = make(map[string]int)
+		}
+		for _, toolCall := range responseMsg.ToolCalls {
+			c.usage.ToolCalls[toolCall.Function.Name]++
+		}
+
 		// Execute all tool calls in parallel
-		toolResults, wasResultCalled := c.executeToolsParallel(ctx, responseMsg.ToolCalls, executor, &toolExecutionTime, &toolCallCount, logger)
+		toolResults, wasResultCalled := c.executeToolsParallel(ctx, responseMsg.ToolCalls, executor, repeats, &toolExecutionTime, &toolCallCount, logger)
 		if wasResultCalled {
 			resultToolCalled = true
 		}
 
 		// Add all tool results to messages
+		for i := range toolResults {
+			resultRounds[len(messages)+i] = round
+		}
 		messages = append(messages, toolResults...)
 
+		// Past half of the rounds, remind the model to finish before they run out
+		if nudge := roundsNudge(round+1, maxRounds, hasResultTool); nudge != "" && !resultToolCalled {
+			logger.Debug("Nudging model to finish", "round", round+1, "max_rounds", maxRounds)
+			messages = append(messages, OpenAIMessage{Role: "user", Content: nudge})
+		}
+
 		// Check if any tool is terminal
 		for _, toolCall := range responseMsg.ToolCalls {
 			if toolCall.Type == "function" && executor.IsTerminal(toolCall.Function.Name) {
@@ -139,3 +187,40 @@ func (c *OpenAIClient) Generate(ctx context.Context, prompt string, tools []Tool
 	logger.Warn("Reached maximum rounds of tool calls", "max_rounds", maxRounds)
 	return "", fmt.Errorf("exceeded maximum rounds (%d) of tool calls", maxRounds)
 }
+
+// DefaultMaxRounds is the number of rounds of tool calls a phase may take unless configured
+const DefaultMaxRounds = 30
+
+// roundsNudge returns the reminder sent after used of maxRounds rounds, growing firmer as
+// the rounds run out: at half, at three quarters and before the last round. It returns ""
+// after other rounds. Reminders are user messages like the result() reminder, as not every
+// provider accepts system messages after the first
+func roundsNudge(used, maxRounds int, hasResultTool bool) string {
+	finish := "give your final answer"
+	if hasResultTool {
+		finish = "call result()"
+	}
+	left := maxRounds - used
+	switch {
+	case left == 1:
+		return fmt.Sprintf("This is your last round of tool calls. Do not explore any further: %s now with what you have.", finish)
+	case used == maxRounds*3/4 && left > 1:
+		return fmt.Sprintf("Only %d rounds of tool calls are left. Stop exploring and %s now.", left, finish)
+	case used == maxRounds/2 && left > 1:
+		return fmt.Sprintf("You have used %d of your %d rounds of tool calls. Wrap up and %s now.", used, maxRounds, finish)
+	}
+	return ""
+}
+
+// looksLikeCode reports whether a text answer holds Go code, in a code block or as statements
+func looksLikeCode(content string) bool {
+	if strings.Contains(content, "```") {
+		return true
+	}
+	for _, marker := range []string{"func ", "return ", ":= ", "if err != nil"} {
+		if strings.Contains(content, marker) {
+			return true
+		}
+	}
+	return false
+}
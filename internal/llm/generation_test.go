@@ -0,0 +1,244 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// exploringExecutor answers every tool call without finishing the conversation
+type exploringExecutor struct{}
+
+func (exploringExecutor) Execute(ctx context.Context, toolName string, params map[string]any) (any, error) {
+	return map[string]any{"found": "nothing"}, nil
+}
+
+func (exploringExecutor) IsTerminal(toolName string) bool { return false }
+
+func TestGenerateNudgesBeforeRoundsRunOut(t *testing.T) {
+	var nudges []string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests++
+		if last := req.Messages[len(req.Messages)-1]; last.Role == "user" && requests > 1 {
+			nudges = append(nudges, last.Content)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"search","arguments":"{}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClientWithOptions(&OpenAIClientOptions{BaseURL: server.URL, Model: "m"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetMaxRounds(8)
+	tools := []Tool{{Type: "function", Function: ToolFunction{Name: "search"}}, {Type: "function", Function: ToolFunction{Name: "result"}}}
+	_, err = client.Generate(context.Background(), "prompt", tools, exploringExecutor{})
+	if err == nil || !strings.Contains(err.Error(), "exceeded maximum rounds (8)") {
+		t.Fatalf("Expected the rounds to run out, got %v", err)
+	}
+	if requests != 8 {
+		t.Errorf("Expected 8 requests, got %d", requests)
+	}
+
+	// After 4, 6 and 7 of 8 rounds
+	if len(nudges) != 3 {
+		t.Fatalf("Expected 3 nudges, got %q", nudges)
+	}
+	for i, want := range []string{"used 4 of your 8 rounds", "Only 2 rounds", "last round"} {
+		if !strings.Contains(nudges[i], want) || !strings.Contains(nudges[i], "result()") {
+			t.Errorf("Expected nudge %d to contain %q and result(), got %q", i, want, nudges[i])
+		}
+	}
+}
+
+func TestRoundsNudge(t *testing.T) {
+	if got := roundsNudge(15, DefaultMaxRounds, false); !strings.Contains(got, "give your final answer") {
+		t.Errorf("Expected a nudge without result() at half the rounds, got %q", got)
+	}
+	for _, used := range []int{1, 14, 16, 21} {
+		if got := roundsNudge(used, DefaultMaxRounds, true); got != "" {
+			t.Errorf("Expected no nudge after %d rounds, got %q", used, got)
+		}
+	}
+	if got := roundsNudge(1, 2, true); !strings.Contains(got, "last round") {
+		t.Errorf("Expected the last round nudge for a budget of 2, got %q", got)
+	}
+}
+
+// countingExecutor counts the calls of each tool and finishes on result
+type countingExecutor struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	running int // Calls running at the moment
+	peak    int // Most calls running at the same time
+}
+
+func (e *countingExecutor) Execute(ctx context.Context, toolName string, params map[string]any) (any, error) {
+	e.mu.Lock()
+	e.calls[toolName]++
+	e.running++
+	e.peak = max(e.peak, e.running)
+	e.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	e.mu.Lock()
+	e.running--
+	e.mu.Unlock()
+	return map[string]any{"found": "nothing"}, nil
+}
+
+func (e *countingExecutor) IsTerminal(toolName string) bool { return toolName == "result" }
+
+func TestGenerateCutsOffRepeatedCalls(t *testing.T) {
+	var lastToolResult string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if last := req.Messages[len(req.Messages)-1]; last.Role == "tool" {
+			lastToolResult = last.Content
+		}
+		requests++
+		// Key order differs, the arguments do not
+		call := `{"id":"1","type":"function","function":{"name":"search","arguments":"{\"query\":\"Cart\",\"limit\":5}"}}`
+		if requests%2 == 0 {
+			call = `{"id":"1","type":"function","function":{"name":"search","arguments":"{\"limit\":5,\"query\":\"Cart\"}"}}`
+		}
+		if requests == 4 {
+			if !strings.Contains(lastToolResult, "repeated_call") {
+				t.Errorf("Expected the third identical call to be cut off, got %s", lastToolResult)
+			}
+			call = `{"id":"2","type":"function","function":{"name":"result","arguments":"{}"}}`
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[` + call + `]}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClientWithOptions(&OpenAIClientOptions{BaseURL: server.URL, Model: "m"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	executor := &countingExecutor{calls: make(map[string]int)}
+	if _, err := client.Generate(context.Background(), "prompt", nil, executor); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if executor.calls["search"] != 2 || executor.calls["result"] != 1 {
+		t.Errorf("Expected search to run twice and result once, got %v", executor.calls)
+	}
+}
+
+func TestGenerateSharesResultsOfIdenticalCalls(t *testing.T) {
+	var toolResults map[string]string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role       string `json:"role"`
+				Content    string `json:"content"`
+				ToolCallID string `json:"tool_call_id"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests++
+		if requests == 2 {
+			toolResults = make(map[string]string)
+			for _, msg := range req.Messages {
+				if msg.Role == "tool" {
+					toolResults[msg.ToolCallID] = msg.Content
+				}
+			}
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"r","type":"function","function":{"name":"result","arguments":"{}"}}]}}]}`))
+			return
+		}
+		var calls []string
+		for i, query := range []string{"Cart", "Cart", "User", "Order", "Item", "Price"} {
+			calls = append(calls, fmt.Sprintf(`{"id":"c%d","type":"function","function":{"name":"search","arguments":"{\"query\":\"%s\"}"}}`, i, query))
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[` + strings.Join(calls, ",") + `]}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClientWithOptions(&OpenAIClientOptions{BaseURL: server.URL, Model: "m", MaxParallelTools: 2})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	executor := &countingExecutor{calls: make(map[string]int)}
+	if _, err := client.Generate(context.Background(), "prompt", nil, executor); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if executor.calls["search"] != 5 {
+		t.Errorf("Expected the identical search to run once, got %d searches", executor.calls["search"])
+	}
+	if executor.peak > 2 {
+		t.Errorf("Expected at most 2 calls at the same time, got %d", executor.peak)
+	}
+	if len(toolResults) != 6 || toolResults["c1"] == "" || toolResults["c1"] != toolResults["c0"] {
+		t.Errorf("Expected every call answered and the duplicate sharing the result, got %v", toolResults)
+	}
+}
+
+func TestGenerateDetectsMissingToolSupport(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		requests = append(requests, req)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + "```go\\nreturn a + b\\n```" + `"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClientWithOptions(&OpenAIClientOptions{BaseURL: server.URL, Model: "m"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	tools := []Tool{{Type: "function", Function: ToolFunction{Name: "result"}}}
+	if _, err := client.Generate(context.Background(), "prompt", tools, exploringExecutor{}); !errors.Is(err, ErrToolsUnsupported) {
+		t.Fatalf("Expected ErrToolsUnsupported, got %v", err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("Expected detection after the reminder round, got %d requests", len(requests))
+	}
+
+	// Without tools the answer is returned as is, and no tool options are sent
+	answer, err := client.Generate(context.Background(), "prompt", nil, nil)
+	if err != nil || !strings.Contains(answer, "return a + b") {
+		t.Fatalf("Expected the code answer, got %q (%v)", answer, err)
+	}
+	last := requests[len(requests)-1]
+	if _, ok := last["tool_choice"]; ok {
+		t.Errorf("Expected no tool_choice without tools, got %v", last["tool_choice"])
+	}
+	if _, ok := last["parallel_tool_calls"]; ok {
+		t.Errorf("Expected no parallel_tool_calls without tools, got %v", last["parallel_tool_calls"])
+	}
+}
@@ -13,8 +13,71 @@ type Provider interface {
 	// SetTemperature sets the temperature for generation
 	SetTemperature(temperature float32)
 
+	// SetMaxRounds sets the rounds of tool calls a Generate call may take (0: DefaultMaxRounds)
+	SetMaxRounds(maxRounds int)
+
 	// SetSystemPrompt sets the system prompt
 	SetSystemPrompt(systemPrompt string)
+
+	// SetSharedContext sets context shared by every target, sent before the prompt
+	SetSharedContext(sharedContext string)
+
+	// PromptCache returns the prompt cache style requests are sent with ("" when disabled)
+	PromptCache() string
+
+	// Usage returns the token usage and request statistics accumulated across all requests
+	Usage() Usage
+}
+
+// Usage represents token consumption and request statistics for a provider
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int            // Prompt tokens served from the provider's prompt cache
+	Rounds           int            // Chat completion requests made
+	ToolCalls        map[string]int // Tool calls requested by the model, by tool name
+	ToolErrors       map[string]int // Tool calls that returned an error, by tool name
+}
+
+// TotalToolCalls returns the number of tool calls across all tools
+func (u Usage) TotalToolCalls() int {
+	total := 0
+	for _, n := range u.ToolCalls {
+		total += n
+	}
+	return total
+}
+
+// Add returns the combined usage of u and other
+func (u Usage) Add(other Usage) Usage {
+	total := Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		CachedTokens:     u.CachedTokens + other.CachedTokens,
+		Rounds:           u.Rounds + other.Rounds,
+		ToolCalls:        make(map[string]int, len(u.ToolCalls)),
+	}
+	for name, n := range u.ToolCalls {
+		total.ToolCalls[name] += n
+	}
+	for name, n := range other.ToolCalls {
+		total.ToolCalls[name] += n
+	}
+	if len(u.ToolErrors)+len(other.ToolErrors) > 0 {
+		total.ToolErrors = make(map[string]int, len(u.ToolErrors))
+		for name, n := range u.ToolErrors {
+			total.ToolErrors[name] += n
+		}
+		for name, n := range other.ToolErrors {
+			total.ToolErrors[name] += n
+		}
+	}
+	return total
+}
+
+// TotalTokens returns the sum of prompt and completion tokens
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
 }
 
 // ToolExecutor executes tool calls
@@ -8,20 +8,31 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/rail44/mantra/internal/ratelimit"
 )
 
 // OpenAIClient implements Provider for OpenAI API and compatible services
 type OpenAIClient struct {
-	apiKey             string
 	baseURL            string
 	model              string
 	currentTemperature float32 // Current temperature to use
 	systemPrompt       string  // Current system prompt
+	sharedContext      string  // Context shared by all targets, sent after the system prompt
+	promptCache        string  // Prompt cache style (PromptCacheAnthropic, PromptCacheOpenAI or "" to disable)
+	maxRounds          int     // Rounds of tool calls of the current phase (0: DefaultMaxRounds)
+	keepToolRounds     int     // Rounds whose tool results are kept in full, older ones are pruned (0 keeps all)
+	maxParallelTools   int     // Tool calls of one round run at the same time (0 uses DefaultMaxParallelTools)
 	httpClient         *http.Client
-	providerSpec       *ProviderSpec // OpenRouter-specific provider routing
+	providerSpec       *ProviderSpec      // OpenRouter-specific provider routing
+	rateLimiter        *ratelimit.Limiter // Paces requests across all clients of the run
+	headers            map[string]string  // Extra headers sent with every request
+	auth               Authenticator
 	logger             *slog.Logger
+	usage              Usage // Accumulated token usage and request statistics
 }
 
 // OpenAIRequest represents a chat completion request
@@ -32,12 +43,23 @@ type OpenAIRequest struct {
 	Tools             []Tool          `json:"tools,omitempty"`
 	ToolChoice        any             `json:"tool_choice,omitempty"`
 	ParallelToolCalls bool            `json:"parallel_tool_calls,omitempty"`
-	Provider          *ProviderSpec   `json:"provider,omitempty"` // OpenRouter provider specification
+	Provider          *ProviderSpec   `json:"provider,omitempty"`         // OpenRouter provider specification
+	PromptCacheKey    string          `json:"prompt_cache_key,omitempty"` // Routes requests with the same prefix to the same cache
 }
 
 // ProviderSpec allows specifying provider routing for OpenRouter
 type ProviderSpec struct {
-	Only []string `json:"only,omitempty"` // List of providers to use (e.g., ["Cerebras"])
+	Only           []string `json:"only,omitempty"`            // List of providers to use (e.g., ["Cerebras"])
+	Order          []string `json:"order,omitempty"`           // Providers to try first, in order
+	Ignore         []string `json:"ignore,omitempty"`          // Providers never to use
+	AllowFallbacks *bool    `json:"allow_fallbacks,omitempty"` // Whether other providers may serve the request (nil uses OpenRouter's default)
+	Quantizations  []string `json:"quantizations,omitempty"`   // Accepted model quantizations (e.g., ["fp8", "bf16"])
+}
+
+// IsZero reports whether the spec sets no routing preferences
+func (s *ProviderSpec) IsZero() bool {
+	return s == nil || (len(s.Only) == 0 && len(s.Order) == 0 && len(s.Ignore) == 0 &&
+		s.AllowFallbacks == nil && len(s.Quantizations) == 0)
 }
 
 // OpenAIMessage represents a message in the chat
@@ -47,6 +69,8 @@ type OpenAIMessage struct {
 	Reasoning  string     `json:"reasoning,omitempty"` // For models that support reasoning
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+
+	CacheControl *CacheControl `json:"-"` // Cache breakpoint after this message, see MarshalJSON
 }
 
 // OpenAIResponse represents a chat completion response
@@ -64,20 +88,30 @@ type OpenAIResponse struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
+
+		PromptTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
 	} `json:"usage"`
 	Provider string `json:"provider,omitempty"` // OpenRouter provider info
 }
 
 // OpenAIClientOptions contains options for creating an OpenAI client
 type OpenAIClientOptions struct {
-	APIKey       string
-	BaseURL      string
-	Model        string
-	Temperature  float32
-	SystemPrompt string
-	HTTPClient   *http.Client
-	ProviderSpec []string // For OpenRouter provider routing
-	Logger       *slog.Logger
+	APIKey           string
+	BaseURL          string
+	Model            string
+	Temperature      float32
+	SystemPrompt     string
+	HTTPClient       *http.Client
+	ProviderSpec     *ProviderSpec      // For OpenRouter provider routing
+	RateLimiter      *ratelimit.Limiter // Shared request pacing (nil disables)
+	Headers          map[string]string  // Extra headers sent with every request
+	Auth             Authenticator      // Request authentication (nil sends APIKey as a bearer token)
+	PromptCache      string             // Prompt cache style (empty disables)
+	KeepRounds       int                // Rounds whose tool results are kept in full, see pruneToolResults (0 keeps all)
+	MaxParallelTools int                // Tool calls of one round run at the same time (0 uses DefaultMaxParallelTools)
+	Logger           *slog.Logger
 }
 
 // NewOpenAIClient creates a new OpenAI API client
@@ -109,31 +143,33 @@ func NewOpenAIClientWithOptions(opts *OpenAIClientOptions) (*OpenAIClient, error
 	}
 
 	client := &OpenAIClient{
-		apiKey:             opts.APIKey,
 		baseURL:            strings.TrimSuffix(opts.BaseURL, "/"),
 		model:              opts.Model,
 		currentTemperature: opts.Temperature,
 		systemPrompt:       opts.SystemPrompt,
 		httpClient:         httpClient,
+		rateLimiter:        opts.RateLimiter,
+		headers:            opts.Headers,
+		auth:               opts.Auth,
+		promptCache:        opts.PromptCache,
+		keepToolRounds:     opts.KeepRounds,
+		maxParallelTools:   opts.MaxParallelTools,
 		logger:             opts.Logger,
 	}
+	if client.auth == nil {
+		client.auth = BearerAuth(opts.APIKey)
+	}
 
 	// Set provider spec if provided
-	if len(opts.ProviderSpec) > 0 {
-		client.providerSpec = &ProviderSpec{
-			Only: opts.ProviderSpec,
-		}
-	}
+	client.SetProviderSpec(opts.ProviderSpec)
 
 	return client, nil
 }
 
 // SetProviderSpec sets OpenRouter provider routing specification
-func (c *OpenAIClient) SetProviderSpec(providers []string) {
-	if len(providers) > 0 {
-		c.providerSpec = &ProviderSpec{
-			Only: providers,
-		}
+func (c *OpenAIClient) SetProviderSpec(spec *ProviderSpec) {
+	if !spec.IsZero() {
+		c.providerSpec = spec
 	}
 }
 
@@ -142,16 +178,47 @@ func (c *OpenAIClient) SetTemperature(temperature float32) {
 	c.currentTemperature = temperature
 }
 
+// SetMaxRounds sets the rounds of tool calls a Generate call may take (0: DefaultMaxRounds)
+func (c *OpenAIClient) SetMaxRounds(maxRounds int) {
+	c.maxRounds = maxRounds
+}
+
 // SetSystemPrompt sets the system prompt
 func (c *OpenAIClient) SetSystemPrompt(systemPrompt string) {
 	c.systemPrompt = systemPrompt
 	// Logging is deferred to Generate() where we have access to the context
 }
 
-// Name returns the provider name
+// Usage returns the token usage and request statistics accumulated across all requests
+func (c *OpenAIClient) Usage() Usage {
+	usage := c.usage
+	usage.ToolCalls = make(map[string]int, len(c.usage.ToolCalls))
+	for name, n := range c.usage.ToolCalls {
+		usage.ToolCalls[name] = n
+	}
+	if c.usage.ToolErrors != nil {
+		usage.ToolErrors = make(map[string]int, len(c.usage.ToolErrors))
+		for name, n := range c.usage.ToolErrors {
+			usage.ToolErrors[name] = n
+		}
+	}
+	return usage
+}
+
+// Name returns the provider name derived from the endpoint host (e.g. "openrouter.ai (Cerebras)")
 func (c *OpenAIClient) Name() string {
-	// Return a simple name based on the model being used
-	return "OpenAI API"
+	name := "OpenAI API"
+	if u, err := url.Parse(c.baseURL); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	if c.providerSpec != nil {
+		if len(c.providerSpec.Only) > 0 {
+			name += " (" + strings.Join(c.providerSpec.Only, ", ") + ")"
+		} else if len(c.providerSpec.Order) > 0 {
+			name += " (" + strings.Join(c.providerSpec.Order, " > ") + ")"
+		}
+	}
+	return name
 }
 
 // makeRequest makes a non-streaming request to the API
@@ -167,13 +234,29 @@ func (c *OpenAIClient) makeRequest(ctx context.Context, req OpenAIRequest) (*Ope
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 
 	// Add app identification headers (primarily for OpenRouter, but safe for all providers)
 	// These headers help with app discovery on platforms that support them
 	httpReq.Header.Set("HTTP-Referer", "https://github.com/rail44/mantra")
 	httpReq.Header.Set("X-Title", "mantra")
 
+	// Configured headers may replace the ones above; authentication is applied last
+	for name, value := range c.headers {
+		httpReq.Header.Set(name, value)
+	}
+	if err := c.auth.Authenticate(ctx, httpReq); err != nil {
+		return nil, fmt.Errorf("failed to authenticate request: %w", err)
+	}
+
+	estimatedTokens := ratelimit.EstimateTokens(len(jsonData))
+	waited, err := c.rateLimiter.Wait(ctx, estimatedTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for rate limit: %w", err)
+	}
+	if waited > 0 {
+		c.logger.Debug("Delayed request for rate limit", slog.Duration("waited", waited))
+	}
+
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -195,6 +278,9 @@ func (c *OpenAIClient) makeRequest(ctx context.Context, req OpenAIRequest) (*Ope
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	if result.Usage.TotalTokens > 0 {
+		c.rateLimiter.Record(estimatedTokens, result.Usage.TotalTokens)
+	}
 
 	return &result, nil
 }
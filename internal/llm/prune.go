@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// prunedDigestLength is the number of characters of a pruned tool result kept as its digest
+const prunedDigestLength = 100
+
+// pruneToolResults replaces the contents of tool results added more than keep rounds
+// before round with a one-line summary, so long sessions stay within the context limit
+// while the latest results stay complete. resultRounds maps the index of each tool result
+// not yet pruned to the round it was added in; pruned results are removed from it
+func pruneToolResults(messages []OpenAIMessage, resultRounds map[int]int, round, keep int) {
+	var names map[string]string
+	for i, added := range resultRounds {
+		if round-added <= keep {
+			continue
+		}
+		delete(resultRounds, i)
+		if names == nil {
+			names = toolCallNames(messages)
+		}
+		msg := &messages[i]
+		summary := fmt.Sprintf("[%s result from round %d, pruned from %d bytes] %s", names[msg.ToolCallID], added+1, len(msg.Content), digest(msg.Content))
+		if len(summary) < len(msg.Content) {
+			msg.Content = summary
+		}
+	}
+}
+
+// toolCallNames returns the tool name of every call in messages by call ID
+func toolCallNames(messages []OpenAIMessage) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Function.Name
+		}
+	}
+	return names
+}
+
+// digest returns the start of content on one line, cut at prunedDigestLength characters
+func digest(content string) string {
+	line := strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(line) <= prunedDigestLength {
+		return line
+	}
+	return string([]rune(line)[:prunedDigestLength]) + "…"
+}
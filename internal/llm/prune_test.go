@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPruneToolResults(t *testing.T) {
+	long := "{\"definition\": \"type Cart struct {\\n\\titems []Item\\n}\",\n" + strings.Repeat("  \"padding\": 0,\n", 30) + "}"
+	messages := []OpenAIMessage{
+		{Role: "user", Content: "prompt"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "a", Function: ToolCallFunction{Name: "inspect"}}}},
+		{Role: "tool", ToolCallID: "a", Content: long},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "b", Function: ToolCallFunction{Name: "search"}}, {ID: "c", Function: ToolCallFunction{Name: "search"}}}},
+		{Role: "tool", ToolCallID: "b", Content: long},
+		{Role: "tool", ToolCallID: "c", Content: "[]"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "d", Function: ToolCallFunction{Name: "inspect"}}}},
+		{Role: "tool", ToolCallID: "d", Content: long},
+	}
+	resultRounds := map[int]int{2: 0, 4: 1, 5: 1, 7: 2}
+
+	// Before round 3, keeping 1 round prunes the results of rounds 0 and 1
+	pruneToolResults(messages, resultRounds, 3, 1)
+
+	if got := messages[2].Content; !strings.HasPrefix(got, "[inspect result from round 1, pruned from") || !strings.Contains(got, `] {"definition": "type Cart struct`) {
+		t.Errorf("Expected a summary of the inspect result, got %q", got)
+	}
+	if got := messages[4].Content; !strings.HasPrefix(got, "[search result from round 2") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Expected a cut summary of the search result, got %q", got)
+	}
+	if messages[5].Content != "[]" {
+		t.Errorf("Expected results shorter than their summary to be kept, got %q", messages[5].Content)
+	}
+	if messages[7].Content != long {
+		t.Errorf("Expected the latest result to be kept, got %q", messages[7].Content)
+	}
+	if len(resultRounds) != 1 || resultRounds[7] != 2 {
+		t.Errorf("Expected only the kept result to remain tracked, got %v", resultRounds)
+	}
+}
@@ -3,6 +3,7 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sort"
@@ -10,6 +11,8 @@ import (
 	"time"
 
 	"golang.org/x/sync/errgroup"
+
+	"github.com/rail44/mantra/internal/tools"
 )
 
 // toolResult represents the result of a single tool execution
@@ -19,16 +22,74 @@ type toolResult struct {
 	message    OpenAIMessage
 	duration   time.Duration
 	isTerminal bool
+	failed     bool // The call could not be parsed or the tool returned an error
+}
+
+// maxRepeatedCalls is the number of times a tool runs with the same arguments in one
+// generation. Later identical calls get a synthetic result telling the model to finish
+const maxRepeatedCalls = 2
+
+// repeatedCallResult is the result of a call repeating one that already ran maxRepeatedCalls times
+func repeatedCallResult(toolName string) string {
+	body := map[string]any{"error": map[string]any{
+		"type":    "repeated_call",
+		"message": fmt.Sprintf("%s was already called %d times with these arguments and the answer above is final. Do not call it again: proceed with what you have and call result() now.", toolName, maxRepeatedCalls),
+	}}
+	data, _ := json.Marshal(body)
+	return string(data)
+}
+
+// callKey identifies a tool call by its name and arguments, independent of key order
+func callKey(toolName string, params map[string]any) string {
+	args, _ := json.Marshal(params)
+	return toolName + "\x00" + string(args)
+}
+
+// DefaultMaxParallelTools is the number of tool calls of one round run at the same time
+// unless configured
+const DefaultMaxParallelTools = 8
+
+// parseToolArguments decodes the arguments of a tool call, returning a message for the
+// model if they are not a JSON object
+func parseToolArguments(tc *ToolCall) (map[string]any, string) {
+	// Check if Arguments is already a string (double-encoded by some providers like Mistral)
+	var argStr string
+	if err := json.Unmarshal(tc.Function.Arguments, &argStr); err == nil {
+		// It was double-encoded, use the decoded string
+		tc.Function.Arguments = json.RawMessage(argStr)
+	}
+
+	var params map[string]any
+	if err := json.Unmarshal(tc.Function.Arguments, &params); err != nil {
+		errorMsg := fmt.Sprintf("failed to parse tool arguments: %v", err)
+		if tc.Function.Name == "result" {
+			// Usually a long body cut off by the output limit
+			errorMsg += "; if the code is long, send it in parts with result_append and pass the rest to result"
+		}
+		return nil, errorMsg
+	}
+	return params, ""
 }
 
-// executeToolsParallel executes multiple tool calls in parallel using channels for efficient result collection
-func (c *OpenAIClient) executeToolsParallel(ctx context.Context, toolCalls []ToolCall, executor ToolExecutor, toolExecutionTime *time.Duration, toolCallCount *int, logger *slog.Logger) ([]OpenAIMessage, bool) {
+// executeToolsParallel executes multiple tool calls in parallel using channels for efficient result collection.
+// repeats counts the calls of the generation by callKey, to cut off loops of identical calls.
+// Identical calls of one round run once and share the result
+func (c *OpenAIClient) executeToolsParallel(ctx context.Context, toolCalls []ToolCall, executor ToolExecutor, repeats map[string]int, toolExecutionTime *time.Duration, toolCallCount *int, logger *slog.Logger) ([]OpenAIMessage, bool) {
 	results := make(chan toolResult, len(toolCalls))
 	resultToolCalled := false
 	mu := &sync.Mutex{}
 
 	// Use errgroup with limited concurrency
 	g, ctx := errgroup.WithContext(ctx)
+	limit := c.maxParallelTools
+	if limit <= 0 {
+		limit = DefaultMaxParallelTools
+	}
+	g.SetLimit(limit)
+
+	// Index of the call that runs, by callKey, and of the call each duplicate copies
+	firstCalls := make(map[string]int)
+	duplicates := make(map[int]int)
 
 	// Execute all tools in parallel
 	for i, toolCall := range toolCalls {
@@ -41,32 +102,56 @@ func (c *OpenAIClient) executeToolsParallel(ctx context.Context, toolCalls []Too
 		index := i
 		tc := toolCall
 
-		g.Go(func() error {
-
-			// Parse arguments
-			// Check if Arguments is already a string (double-encoded by some providers like Mistral)
-			var argStr string
-			if err := json.Unmarshal(tc.Function.Arguments, &argStr); err == nil {
-				// It was double-encoded, use the decoded string
-				tc.Function.Arguments = json.RawMessage(argStr)
+		params, parseError := parseToolArguments(&tc)
+		isTerminal := executor.IsTerminal(tc.Function.Name)
+		if parseError == "" && !isTerminal {
+			key := callKey(tc.Function.Name, params)
+			if first, ok := firstCalls[key]; ok {
+				logger.Debug("Sharing the result of an identical call", slog.String("tool", tc.Function.Name))
+				duplicates[index] = first
+				continue
 			}
+			firstCalls[key] = index
+		}
 
-			var params map[string]any
-			if err := json.Unmarshal(tc.Function.Arguments, &params); err != nil {
-				errorMsg := fmt.Sprintf("failed to parse tool arguments: %v", err)
-				logger.Error(errorMsg)
+		g.Go(func() error {
+			if parseError != "" {
+				logger.Error(parseError)
 				results <- toolResult{
 					index:      index,
 					toolCallID: tc.ID,
+					failed:     true,
 					message: OpenAIMessage{
 						Role:       "tool",
-						Content:    errorMsg,
+						Content:    parseError,
 						ToolCallID: tc.ID,
 					},
 				}
 				return nil
 			}
 
+			// A model stuck calling the same tool with the same arguments is told to finish
+			if !isTerminal {
+				key := callKey(tc.Function.Name, params)
+				mu.Lock()
+				repeats[key]++
+				repeated := repeats[key] > maxRepeatedCalls
+				mu.Unlock()
+				if repeated {
+					logger.Warn("Tool called repeatedly with the same arguments", slog.String("tool", tc.Function.Name))
+					results <- toolResult{
+						index:      index,
+						toolCallID: tc.ID,
+						message: OpenAIMessage{
+							Role:       "tool",
+							Content:    repeatedCallResult(tc.Function.Name),
+							ToolCallID: tc.ID,
+						},
+					}
+					return nil
+				}
+			}
+
 			// Execute tool with timing
 			toolStart := time.Now()
 			result, err := executor.Execute(ctx, tc.Function.Name, params)
@@ -76,12 +161,24 @@ func (c *OpenAIClient) executeToolsParallel(ctx context.Context, toolCalls []Too
 			var resultContent string
 			if err != nil {
 				// For errors, create a structured error response
-				errorResponse := map[string]any{
-					"error": map[string]any{
-						"message": err.Error(),
-						"type":    "tool_error",
-					},
+				errorBody := map[string]any{
+					"message": err.Error(),
+					"type":    "tool_error",
+				}
+				// Expose the code, details and retry hints of tool errors so the model can correct its call
+				var toolErr *tools.ToolError
+				if errors.As(err, &toolErr) {
+					errorBody["message"] = toolErr.Message
+					errorBody["code"] = toolErr.Code
+					errorBody["retryable"] = toolErr.Retryable
+					if toolErr.Details != "" {
+						errorBody["details"] = toolErr.Details
+					}
+					if toolErr.Suggestion != "" {
+						errorBody["suggestion"] = toolErr.Suggestion
+					}
 				}
+				errorResponse := map[string]any{"error": errorBody}
 				if resultBytes, marshalErr := json.Marshal(errorResponse); marshalErr == nil {
 					resultContent = string(resultBytes)
 				} else {
@@ -126,14 +223,12 @@ func (c *OpenAIClient) executeToolsParallel(ctx context.Context, toolCalls []Too
 				}
 			}
 
-			// Check if this is a terminal tool
-			isTerminal := executor.IsTerminal(tc.Function.Name)
-
 			results <- toolResult{
 				index:      index,
 				toolCallID: tc.ID,
 				duration:   elapsed,
 				isTerminal: isTerminal,
+				failed:     err != nil,
 				message: OpenAIMessage{
 					Role:       "tool",
 					Content:    resultContent,
@@ -163,9 +258,28 @@ func (c *OpenAIClient) executeToolsParallel(ctx context.Context, toolCalls []Too
 		if result.isTerminal {
 			resultToolCalled = true
 		}
+		if result.failed {
+			if c.usage.ToolErrors == nil {
+				c.usage.ToolErrors = make(map[string]int)
+			}
+			c.usage.ToolErrors[toolCalls[result.index].Function.Name]++
+		}
 		mu.Unlock()
 	}
 
+	// Duplicates answer with the result of the call they repeat
+	byIndex := make(map[int]toolResult, len(resultSlice))
+	for _, result := range resultSlice {
+		byIndex[result.index] = result
+	}
+	for index, first := range duplicates {
+		result := byIndex[first]
+		result.index = index
+		result.toolCallID = toolCalls[index].ID
+		result.message.ToolCallID = toolCalls[index].ID
+		resultSlice = append(resultSlice, result)
+	}
+
 	// Sort results by original index to maintain order
 	sort.Slice(resultSlice, func(i, j int) bool {
 		return resultSlice[i].index < resultSlice[j].index
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TransportConfig configures how the HTTP client reaches the AI provider
+type TransportConfig struct {
+	ProxyURL           string // Proxy for all requests; empty uses HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	CAFile             string // PEM bundle trusted in addition to the system roots
+	CertFile           string // PEM client certificate for mutual TLS
+	KeyFile            string // PEM private key for CertFile
+	InsecureSkipVerify bool   // Skip server certificate verification
+
+	CompressRequests    bool          // Send gzip-compressed request bodies, falling back when the server rejects them
+	MaxIdleConnsPerHost int           // Idle connections kept per host (0 uses DefaultMaxIdleConnsPerHost)
+	MaxConnsPerHost     int           // Limit on connections per host (0 means no limit)
+	IdleConnTimeout     time.Duration // How long idle connections are kept (0 uses the Go default)
+}
+
+// DefaultMaxIdleConnsPerHost matches the number of targets generated in parallel,
+// so connections are reused instead of being reopened for every request
+const DefaultMaxIdleConnsPerHost = 16
+
+// NewHTTPClient creates an HTTP client for AI provider requests
+func NewHTTPClient(timeout time.Duration, cfg *TransportConfig) (*http.Client, error) {
+	if cfg == nil {
+		cfg = &TransportConfig{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if transport.MaxIdleConns < transport.MaxIdleConnsPerHost {
+		transport.MaxIdleConns = transport.MaxIdleConnsPerHost
+	}
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	var roundTripper http.RoundTripper = transport
+	if cfg.CompressRequests {
+		roundTripper = &gzipRoundTripper{next: transport}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: roundTripper,
+	}, nil
+}
+
+// tlsConfig builds the TLS configuration from the CA bundle and client certificate settings
+func (cfg *TransportConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
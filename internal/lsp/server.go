@@ -0,0 +1,321 @@
+// Package lsp is a minimal language server offering a code action that generates the
+// implementation of the mantra target under the cursor and applies it as a workspace edit
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rail44/mantra/internal/history"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// CommandGenerate is the command the code action runs
+const CommandGenerate = "mantra.generate"
+
+// ImplementFunc generates the target named name in the source file at filePath and returns
+// its body without the surrounding braces
+type ImplementFunc func(ctx context.Context, filePath, name string) (string, error)
+
+// Server serves one client over a pair of streams, usually stdin and stdout
+type Server struct {
+	in        *bufio.Reader
+	out       io.Writer
+	implement ImplementFunc
+	logger    *slog.Logger
+
+	mu        sync.Mutex        // Guards out, documents and nextID
+	documents map[string]string // Open documents by URI
+	nextID    int               // ID of the next request sent to the client
+	running   sync.WaitGroup    // Commands in progress
+}
+
+// NewServer creates a server reading from in and writing to out
+func NewServer(in io.Reader, out io.Writer, implement ImplementFunc) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		implement: implement,
+		logger:    slog.Default(),
+		documents: make(map[string]string),
+	}
+}
+
+// Run serves requests until the client sends exit or closes the input. Generation runs
+// in the background, so the editor stays responsive while a target is generated
+func (s *Server) Run(ctx context.Context) error {
+	// Generations still running when the client exits are canceled
+	defer s.running.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for {
+		body, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read message: %w", err)
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.replyError(json.RawMessage("null"), codeParseError, err.Error())
+			continue
+		}
+		switch {
+		case msg.Method == "exit":
+			return nil
+		case msg.Method == "":
+			// Response to a request of ours, e.g. workspace/applyEdit
+		case msg.ID == nil:
+			s.notification(msg)
+		default:
+			s.request(ctx, msg)
+		}
+	}
+}
+
+// notification handles a message the client expects no reply to
+func (s *Server) notification(msg message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.documents[params.TextDocument.URI] = params.TextDocument.Text
+		}
+	case "textDocument/didChange":
+		// Full document sync: the last change holds the whole text
+		var params didChangeParams
+		if json.Unmarshal(msg.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			s.documents[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		}
+	case "textDocument/didClose":
+		var params didCloseParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			delete(s.documents, params.TextDocument.URI)
+		}
+	}
+}
+
+// request handles a message the client expects a reply to
+func (s *Server) request(ctx context.Context, msg message) {
+	switch msg.Method {
+	case "initialize":
+		s.reply(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":       map[string]any{"openClose": true, "change": 1},
+				"codeActionProvider":     true,
+				"executeCommandProvider": map[string]any{"commands": []string{CommandGenerate}},
+			},
+			"serverInfo": map[string]any{"name": "mantra"},
+		})
+	case "shutdown":
+		s.reply(msg.ID, nil)
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.replyError(msg.ID, codeInvalidParams, err.Error())
+			return
+		}
+		s.reply(msg.ID, s.codeActions(params))
+	case "workspace/executeCommand":
+		var params executeCommandParams
+		var args generateArgs
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Command != CommandGenerate || len(params.Arguments) != 1 {
+			s.replyError(msg.ID, codeInvalidParams, fmt.Sprintf("unsupported command %q", params.Command))
+			return
+		}
+		if err := json.Unmarshal(params.Arguments[0], &args); err != nil {
+			s.replyError(msg.ID, codeInvalidParams, err.Error())
+			return
+		}
+		s.running.Add(1)
+		go func() {
+			defer s.running.Done()
+			if err := s.generate(ctx, args); err != nil {
+				s.logger.Error("generation failed", slog.String("target", args.Target), slog.String("error", err.Error()))
+				s.replyError(msg.ID, codeRequestFailed, err.Error())
+				return
+			}
+			s.reply(msg.ID, nil)
+		}()
+	default:
+		s.replyError(msg.ID, codeMethodNotFound, "method not supported: "+msg.Method)
+	}
+}
+
+// codeActions offers generation for the targets whose mantra comment the range starts in,
+// and for the target whose function the range starts in while its body is still a stub
+func (s *Server) codeActions(params codeActionParams) []CodeAction {
+	s.mu.Lock()
+	text, ok := s.documents[params.TextDocument.URI]
+	s.mu.Unlock()
+	if !ok {
+		return []CodeAction{}
+	}
+
+	actions := []CodeAction{}
+	line := params.Range.Start.Line + 1
+	for _, target := range s.targets(params.TextDocument.URI, text) {
+		start, end := target.Lines()
+		funcLine := target.TokenSet.Position(target.FuncDecl.Pos()).Line
+		onComment := line >= start && line < funcLine
+		onStub := line >= funcLine && line <= end && target.HasPanic
+		if !onComment && !onStub {
+			continue
+		}
+		name := history.TargetKey(target)
+		actions = append(actions, CodeAction{
+			Title: "Generate implementation with mantra",
+			Kind:  "refactor.rewrite",
+			Command: &Command{
+				Title:     "Generate " + name,
+				Command:   CommandGenerate,
+				Arguments: []any{generateArgs{URI: params.TextDocument.URI, Target: name}},
+			},
+		})
+	}
+	return actions
+}
+
+// generate runs the pipeline for a target and asks the client to replace its body
+func (s *Server) generate(ctx context.Context, args generateArgs) error {
+	path, err := uriToPath(args.URI)
+	if err != nil {
+		return err
+	}
+
+	// Generation reads the package from disk
+	s.mu.Lock()
+	text, ok := s.documents[args.URI]
+	s.mu.Unlock()
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if ok && text != string(saved) {
+		return fmt.Errorf("save %s before generating", path)
+	}
+	text = string(saved)
+
+	body, err := s.implement(ctx, path, args.Target)
+	if err != nil {
+		return err
+	}
+
+	// The document may have changed while the model was working
+	s.mu.Lock()
+	if current, ok := s.documents[args.URI]; ok {
+		text = current
+	}
+	s.mu.Unlock()
+	edit, err := s.bodyEdit(args.URI, text, args.Target, body)
+	if err != nil {
+		return err
+	}
+	return s.send("workspace/applyEdit", map[string]any{
+		"label": "Generate " + args.Target,
+		"edit":  WorkspaceEdit{Changes: map[string][]TextEdit{args.URI: {edit}}},
+	})
+}
+
+// bodyEdit replaces the body of the target named name in text, braces included. For a
+// region only the lines between its markers are replaced, keeping the hand-written code
+func (s *Server) bodyEdit(uri, text, name, body string) (TextEdit, error) {
+	for _, target := range s.targets(uri, text) {
+		if history.TargetKey(target) != name || target.FuncDecl.Body == nil {
+			continue
+		}
+		if region := target.Region; region != nil {
+			var sb strings.Builder
+			for _, line := range strings.Split(body, "\n") {
+				if line != "" {
+					sb.WriteString(region.Indent + line)
+				}
+				sb.WriteString("\n")
+			}
+			return TextEdit{
+				Range:   Range{Start: position(text, region.Start), End: position(text, region.End)},
+				NewText: sb.String(),
+			}, nil
+		}
+		file := target.TokenSet.File(target.FuncDecl.Pos())
+		lbrace := file.Offset(target.FuncDecl.Body.Lbrace)
+		rbrace := file.Offset(target.FuncDecl.Body.Rbrace)
+
+		var sb strings.Builder
+		sb.WriteString("{\n")
+		for _, line := range strings.Split(body, "\n") {
+			if line != "" {
+				sb.WriteString("\t" + line)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("}")
+		return TextEdit{
+			Range:   Range{Start: position(text, lbrace), End: position(text, rbrace+1)},
+			NewText: sb.String(),
+		}, nil
+	}
+	return TextEdit{}, fmt.Errorf("target %s is no longer in the document", name)
+}
+
+// targets parses the mantra targets of a document. Documents that do not parse have none
+func (s *Server) targets(uri, text string) []*parser.Target {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return nil
+	}
+	info, err := parser.ParseSource(path, []byte(text))
+	if err != nil {
+		return nil
+	}
+	var targets []*parser.Target
+	for _, target := range info.Targets {
+		if target.FuncDecl != nil && target.TokenSet != nil {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// reply sends the result of a request
+func (s *Server) reply(id json.RawMessage, result any) {
+	s.write(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+// replyError sends the error of a request
+func (s *Server) replyError(id json.RawMessage, code int, text string) {
+	s.write(errorResponse{JSONRPC: "2.0", ID: id, Error: responseError{Code: code, Message: text}})
+}
+
+// send sends a request to the client without waiting for its response
+func (s *Server) send(method string, params any) error {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.mu.Unlock()
+	return s.write(request{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+}
+
+func (s *Server) write(v any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := writeMessage(s.out, v); err != nil {
+		s.logger.Error("failed to write message", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
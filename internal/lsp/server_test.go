@@ -0,0 +1,218 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const source = `package user
+
+// mantra: Greet the user by name
+func Greet(name string) string {
+	panic("not implemented")
+}
+
+func helper() {}
+`
+
+func TestServerGeneratesTarget(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "user.go")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	uri := "file://" + filepath.ToSlash(path)
+
+	var in bytes.Buffer
+	send := func(v any) {
+		if err := writeMessage(&in, v); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	codeAction := func(id, line int) {
+		send(map[string]any{"jsonrpc": "2.0", "id": id, "method": "textDocument/codeAction", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"range":        map[string]any{"start": map[string]any{"line": line, "character": 0}, "end": map[string]any{"line": line, "character": 0}},
+		}})
+	}
+	send(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}})
+	send(map[string]any{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "go", "version": 1, "text": source},
+	}})
+	codeAction(2, 2) // On the mantra comment
+	codeAction(3, 4) // In the stub body
+	codeAction(4, 7) // Outside any target
+	send(map[string]any{"jsonrpc": "2.0", "id": 5, "method": "workspace/executeCommand", "params": map[string]any{
+		"command":   CommandGenerate,
+		"arguments": []any{map[string]any{"uri": uri, "target": "Greet"}},
+	}})
+
+	var implemented string
+	implement := func(ctx context.Context, filePath, name string) (string, error) {
+		implemented = name
+		return "if name == \"\" {\n\treturn \"Hello\"\n}\n\nreturn \"Hello, \" + name", nil
+	}
+	var out bytes.Buffer
+	if err := NewServer(&in, &out, implement).Run(t.Context()); err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if implemented != "Greet" {
+		t.Errorf("Expected Greet to be implemented, got %q", implemented)
+	}
+
+	messages := make(map[string]map[string]json.RawMessage)
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var msg map[string]json.RawMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("Invalid message %s: %v", body, err)
+		}
+		key := string(msg["id"])
+		if method := msg["method"]; method != nil {
+			key = string(method)
+		}
+		messages[key] = msg
+	}
+
+	for id, want := range map[string]int{"2": 1, "3": 1, "4": 0} {
+		var actions []CodeAction
+		if err := json.Unmarshal(messages[id]["result"], &actions); err != nil {
+			t.Fatalf("Invalid code actions for request %s: %v", id, err)
+		}
+		if len(actions) != want {
+			t.Errorf("Expected %d code actions for request %s, got %d", want, id, len(actions))
+		}
+	}
+	if _, ok := messages["5"]["error"]; ok || messages["5"] == nil {
+		t.Errorf("Expected executeCommand to succeed, got %s", messages["5"]["error"])
+	}
+
+	var applyEdit struct {
+		Edit WorkspaceEdit `json:"edit"`
+	}
+	if err := json.Unmarshal(messages[`"workspace/applyEdit"`]["params"], &applyEdit); err != nil {
+		t.Fatalf("Expected workspace/applyEdit, got %v", err)
+	}
+	edits := applyEdit.Edit.Changes[uri]
+	if len(edits) != 1 {
+		t.Fatalf("Expected one edit, got %v", applyEdit.Edit.Changes)
+	}
+	edit := edits[0]
+	if edit.Range.Start != (Position{Line: 3, Character: 31}) || edit.Range.End != (Position{Line: 5, Character: 1}) {
+		t.Errorf("Expected the body range, got %+v", edit.Range)
+	}
+	want := "{\n\tif name == \"\" {\n\t\treturn \"Hello\"\n\t}\n\n\treturn \"Hello, \" + name\n}"
+	if edit.NewText != want {
+		t.Errorf("Expected new text:\n%s\ngot:\n%s", want, edit.NewText)
+	}
+}
+
+func TestServerRefusesUnsavedDocument(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "user.go")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	uri := "file://" + filepath.ToSlash(path)
+
+	var in bytes.Buffer
+	writeMessage(&in, map[string]any{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]any{
+		"textDocument": map[string]any{"uri": uri, "text": source + "\n// edited\n"},
+	}})
+	writeMessage(&in, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "workspace/executeCommand", "params": map[string]any{
+		"command":   CommandGenerate,
+		"arguments": []any{map[string]any{"uri": uri, "target": "Greet"}},
+	}})
+
+	implement := func(ctx context.Context, filePath, name string) (string, error) {
+		t.Error("Expected no generation for an unsaved document")
+		return "", nil
+	}
+	var out bytes.Buffer
+	if err := NewServer(&in, &out, implement).Run(t.Context()); err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "before generating") {
+		t.Errorf("Expected an error asking to save, got %s", out.String())
+	}
+}
+
+func TestServerGeneratesRegion(t *testing.T) {
+	const regionSource = `package user
+
+func Greet(name string) string {
+	name = strings.TrimSpace(name)
+	// mantra:region greeting build the greeting
+	panic("not implemented")
+	// mantra:end
+}
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "user.go")
+	if err := os.WriteFile(path, []byte(regionSource), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	uri := "file://" + filepath.ToSlash(path)
+
+	var in bytes.Buffer
+	writeMessage(&in, map[string]any{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]any{
+		"textDocument": map[string]any{"uri": uri, "text": regionSource},
+	}})
+	writeMessage(&in, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "workspace/executeCommand", "params": map[string]any{
+		"command":   CommandGenerate,
+		"arguments": []any{map[string]any{"uri": uri, "target": "Greet"}},
+	}})
+
+	implement := func(ctx context.Context, filePath, name string) (string, error) {
+		return "if name == \"\" {\n\treturn \"Hello\"\n}\nreturn \"Hello, \" + name", nil
+	}
+	var out bytes.Buffer
+	if err := NewServer(&in, &out, implement).Run(t.Context()); err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+
+	var applyEdit struct {
+		Edit WorkspaceEdit `json:"edit"`
+	}
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			t.Fatalf("Expected workspace/applyEdit, got %s", out.String())
+		}
+		var msg map[string]json.RawMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("Invalid message %s: %v", body, err)
+		}
+		if string(msg["method"]) == `"workspace/applyEdit"` {
+			if err := json.Unmarshal(msg["params"], &applyEdit); err != nil {
+				t.Fatalf("Invalid workspace/applyEdit: %v", err)
+			}
+			break
+		}
+	}
+
+	// Only the region's lines are replaced; the hand-written code around it stays
+	edits := applyEdit.Edit.Changes[uri]
+	if len(edits) != 1 {
+		t.Fatalf("Expected one edit, got %v", applyEdit.Edit.Changes)
+	}
+	edit := edits[0]
+	if edit.Range.Start != (Position{Line: 5, Character: 0}) || edit.Range.End != (Position{Line: 6, Character: 0}) {
+		t.Errorf("Expected the range of the region's lines, got %+v", edit.Range)
+	}
+	want := "\tif name == \"\" {\n\t\treturn \"Hello\"\n\t}\n\treturn \"Hello, \" + name\n"
+	if edit.NewText != want {
+		t.Errorf("Expected new text:\n%s\ngot:\n%s", want, edit.NewText)
+	}
+}
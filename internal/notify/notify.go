@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/rail44/mantra/internal/config"
+	"github.com/rail44/mantra/internal/report"
+)
+
+// Event names sent in the notification payload
+const (
+	EventCompleted    = "completed"
+	EventFailed       = "failed"
+	EventFirstFailure = "first_failure"
+)
+
+// Payload is the JSON document delivered to commands and webhooks
+type Payload struct {
+	Event  string         `json:"event"`
+	Report *report.Report `json:"report"`
+	Error  string         `json:"error,omitempty"` // Why the run failed, for EventFailed
+}
+
+// Notifier delivers run notifications via a shell command and/or a webhook
+type Notifier struct {
+	config     *config.NotifyConfig
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+// New creates a notifier. Returns nil if no notification hooks are configured
+func New(cfg *config.NotifyConfig, logger *slog.Logger) *Notifier {
+	if cfg == nil || (cfg.Command == "" && cfg.WebhookURL == "") {
+		return nil
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Notifier{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+// OnFailure reports whether the notifier should fire on the first failed target
+func (n *Notifier) OnFailure() bool {
+	return n != nil && n.config.OnFailure
+}
+
+// Notify sends the payload to every configured hook. Errors are logged, not returned,
+// so that a broken hook never fails the generation run
+func (n *Notifier) Notify(ctx context.Context, event string, rep *report.Report) {
+	n.send(ctx, Payload{Event: event, Report: rep})
+}
+
+// NotifyFailed sends EventFailed with the error that ended the run. The hooks run even when
+// ctx was cancelled, as cancellation is one of the failures reported
+func (n *Notifier) NotifyFailed(ctx context.Context, rep *report.Report, runErr error) {
+	n.send(context.WithoutCancel(ctx), Payload{Event: EventFailed, Report: rep, Error: runErr.Error()})
+}
+
+// send delivers the payload to every configured hook
+func (n *Notifier) send(ctx context.Context, p Payload) {
+	if n == nil {
+		return
+	}
+
+	payload, err := json.Marshal(p)
+	if err != nil {
+		n.logger.Error("failed to encode notification payload", slog.String("error", err.Error()))
+		return
+	}
+
+	if n.config.Command != "" {
+		if err := n.runCommand(ctx, p, payload); err != nil {
+			n.logger.Warn("notification command failed", slog.String("error", err.Error()))
+		}
+	}
+
+	if n.config.WebhookURL != "" {
+		if err := n.postWebhook(ctx, payload); err != nil {
+			n.logger.Warn("notification webhook failed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// runCommand executes the configured shell command with the payload on stdin
+func (n *Notifier) runCommand(ctx context.Context, p Payload, payload []byte) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", n.config.Command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"MANTRA_EVENT="+p.Event,
+		"MANTRA_PACKAGE="+p.Report.Package,
+		"MANTRA_SUCCEEDED="+strconv.Itoa(p.Report.Succeeded()),
+		"MANTRA_FAILED="+strconv.Itoa(p.Report.Failed()),
+		"MANTRA_ERROR="+p.Error,
+	)
+	return cmd.Run()
+}
+
+// postWebhook POSTs the payload to the configured webhook URL
+func (n *Notifier) postWebhook(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.config.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
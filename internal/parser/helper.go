@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"strings"
+)
+
+// Helper is a private function generated with a target's body and written below the target
+type Helper struct {
+	Name      string // Unexported function name
+	Signature string // Type parameters, parameters and results, e.g. "(line string) (key, value string)"
+	Body      string // Statements inside the function
+}
+
+// Source returns the declaration of the helper
+func (h Helper) Source() string {
+	return "func " + h.Name + h.Signature + " {\n" + h.Body + "\n}"
+}
+
+// Validate reports a helper that is not an unexported function with a valid signature and body
+func (h Helper) Validate() error {
+	if !token.IsIdentifier(h.Name) || h.Name == "_" {
+		return fmt.Errorf("helper name %q is not a valid identifier", h.Name)
+	}
+	if token.IsExported(h.Name) || h.Name == "init" || h.Name == "main" {
+		return fmt.Errorf("helper %s must be unexported and must not be init or main", h.Name)
+	}
+	if !strings.HasPrefix(h.Signature, "(") && !strings.HasPrefix(h.Signature, "[") {
+		return fmt.Errorf("signature of helper %s must start with its parameters, e.g. (s string) int, got %q", h.Name, h.Signature)
+	}
+
+	file, err := goparser.ParseFile(token.NewFileSet(), "", "package p\n\n"+h.Source()+"\n", 0)
+	if err != nil {
+		return fmt.Errorf("helper %s is not a valid function: %w", h.Name, err)
+	}
+	if len(file.Decls) != 1 {
+		return fmt.Errorf("helper %s must declare a single function; its body closes the function early", h.Name)
+	}
+	if fn, ok := file.Decls[0].(*ast.FuncDecl); !ok || fn.Recv != nil || fn.Name.Name != h.Name {
+		return fmt.Errorf("helper %s must be a function without a receiver", h.Name)
+	}
+	return nil
+}
+
+// HelpersFromJSON converts and validates the helpers of a result or tool call, a JSON array of
+// objects with name, signature and body. Names must be unique
+func HelpersFromJSON(value any) ([]Helper, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("helpers must be an array, got %T", value)
+	}
+
+	helpers := make([]Helper, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		fields, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("helpers[%d] must be an object, got %T", i, item)
+		}
+		var helper Helper
+		for name, dst := range map[string]*string{"name": &helper.Name, "signature": &helper.Signature, "body": &helper.Body} {
+			value, ok := fields[name].(string)
+			if !ok {
+				return nil, fmt.Errorf("helpers[%d].%s must be a string", i, name)
+			}
+			*dst = strings.TrimSpace(value)
+		}
+		if err := helper.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[helper.Name] {
+			return nil, fmt.Errorf("helper %s is declared twice", helper.Name)
+		}
+		seen[helper.Name] = true
+		helpers = append(helpers, helper)
+	}
+	return helpers, nil
+}
+
+// WithHelperCode returns body followed by the helpers written as function literals: the code
+// a target's implementation runs, as scanned for imports, panics and dependencies. Generic
+// helpers, which have no literal form, contribute their bodies only
+func WithHelperCode(body string, helpers []Helper) string {
+	if len(helpers) == 0 {
+		return body
+	}
+	var b strings.Builder
+	b.WriteString(body)
+	for _, helper := range helpers {
+		if strings.HasPrefix(helper.Signature, "[") {
+			b.WriteString("\n{\n" + helper.Body + "\n}")
+			continue
+		}
+		b.WriteString("\n_ = func" + helper.Signature + " {\n" + helper.Body + "\n}")
+	}
+	return b.String()
+}
+
+// RenameHelpers renames the helpers whose names are taken, adding the first numeric suffix
+// that is free, e.g. isVowel2, and rewrites their references in body and the helpers' bodies.
+// It returns the body, the helpers and the new names by old name (nil if none changed)
+func RenameHelpers(body string, helpers []Helper, taken map[string]bool) (string, []Helper, map[string]string) {
+	used := make(map[string]bool, len(helpers))
+	for _, helper := range helpers {
+		used[helper.Name] = true
+	}
+
+	var renamed map[string]string
+	for _, helper := range helpers {
+		if !taken[helper.Name] {
+			continue
+		}
+		for n := 2; ; n++ {
+			name := fmt.Sprintf("%s%d", helper.Name, n)
+			if !taken[name] && !used[name] {
+				if renamed == nil {
+					renamed = make(map[string]string)
+				}
+				renamed[helper.Name] = name
+				used[name] = true
+				break
+			}
+		}
+	}
+	if renamed == nil {
+		return body, helpers, nil
+	}
+
+	result := make([]Helper, len(helpers))
+	for i, helper := range helpers {
+		if name, ok := renamed[helper.Name]; ok {
+			helper.Name = name
+		}
+		helper.Body = renameIdents(helper.Body, renamed)
+		result[i] = helper
+	}
+	return renameIdents(body, renamed), result, renamed
+}
+
+// renameIdents rewrites the identifiers of a function body named in renamed. Field and method
+// names of selectors are left alone; a body that does not parse is returned unchanged
+func renameIdents(body string, renamed map[string]string) string {
+	const prefix = "package p\nfunc _() {\n"
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", prefix+body+"\n}", goparser.SkipObjectResolution)
+	if err != nil {
+		return body
+	}
+
+	selected := make(map[*ast.Ident]bool)
+	var idents []*ast.Ident
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			selected[n.Sel] = true
+		case *ast.Ident:
+			if _, ok := renamed[n.Name]; ok && !selected[n] {
+				idents = append(idents, n)
+			}
+		}
+		return true
+	})
+
+	// Rewrite from the end so earlier offsets stay valid
+	for i := len(idents) - 1; i >= 0; i-- {
+		ident := idents[i]
+		start := fset.Position(ident.Pos()).Offset - len(prefix)
+		body = body[:start] + renamed[ident.Name] + body[start+len(ident.Name):]
+	}
+	return body
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHelpersFromJSON(t *testing.T) {
+	helper := func(name, signature, body string) map[string]any {
+		return map[string]any{"name": name, "signature": signature, "body": body}
+	}
+
+	tests := []struct {
+		name    string
+		value   any
+		wantErr string // Substring of the error, empty if valid
+	}{
+		{"none", nil, ""},
+		{"valid", []any{helper("isVowel", " (r rune) bool ", "return r == 'a'"), helper("keys", "[K comparable, V any](m map[K]V) []K", "return nil")}, ""},
+		{"not an array", helper("isVowel", "(r rune) bool", "return true"), "must be an array"},
+		{"missing body", []any{map[string]any{"name": "isVowel", "signature": "(r rune) bool"}}, "helpers[0].body must be a string"},
+		{"exported", []any{helper("IsVowel", "(r rune) bool", "return true")}, "must be unexported"},
+		{"init", []any{helper("init", "()", "")}, "must be unexported"},
+		{"invalid name", []any{helper("is-vowel", "(r rune) bool", "return true")}, "not a valid identifier"},
+		{"signature without parameters", []any{helper("isVowel", "bool", "return true")}, "must start with its parameters"},
+		{"invalid body", []any{helper("isVowel", "(r rune) bool", "return r ==")}, "not a valid function"},
+		{"body closing the function", []any{helper("isVowel", "(r rune) bool", "return true\n}\n\nfunc other() {")}, "single function"},
+		{"duplicate", []any{helper("isVowel", "(r rune) bool", "return true"), helper("isVowel", "(r rune) bool", "return false")}, "declared twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helpers, err := HelpersFromJSON(tt.value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected valid helpers, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v (%+v)", tt.wantErr, err, helpers)
+			}
+		})
+	}
+
+	helpers, _ := HelpersFromJSON([]any{helper("isVowel", " (r rune) bool ", "return r == 'a'")})
+	if len(helpers) != 1 || helpers[0].Signature != "(r rune) bool" {
+		t.Fatalf("Expected the trimmed helper, got %+v", helpers)
+	}
+	if got, want := helpers[0].Source(), "func isVowel(r rune) bool {\nreturn r == 'a'\n}"; got != want {
+		t.Errorf("Expected source %q, got %q", want, got)
+	}
+}
+
+func TestRenameHelpers(t *testing.T) {
+	helpers := []Helper{
+		{Name: "trim", Signature: "(s string) string", Body: "return strings.TrimSpace(s)"},
+		{Name: "words", Signature: "(s string) []string", Body: "return strings.Fields(trim(s))"},
+		{Name: "trim2", Signature: "(s string) string", Body: "return s"},
+	}
+	body := "c.trim = true\nreturn len(words(trim(s))) + len(trim2(s))"
+
+	got, renamedHelpers, renamed := RenameHelpers(body, helpers, map[string]bool{"trim": true, "words": true, "words2": true})
+	if renamed["trim"] != "trim3" || renamed["words"] != "words3" || len(renamed) != 2 {
+		t.Fatalf("Expected trim and words renamed past taken names, got %v", renamed)
+	}
+	if want := "c.trim = true\nreturn len(words3(trim3(s))) + len(trim2(s))"; got != want {
+		t.Errorf("Expected body %q, got %q", want, got)
+	}
+	if renamedHelpers[0].Name != "trim3" || renamedHelpers[1].Body != "return strings.Fields(trim3(s))" || renamedHelpers[2].Name != "trim2" {
+		t.Errorf("Expected renamed helpers and references, got %+v", renamedHelpers)
+	}
+	if helpers[0].Name != "trim" {
+		t.Errorf("Expected the given helpers to be left unchanged, got %+v", helpers)
+	}
+
+	if got, _, renamed := RenameHelpers(body, helpers, map[string]bool{"other": true}); renamed != nil || got != body {
+		t.Errorf("Expected no renames without collisions, got %v (%q)", renamed, got)
+	}
+}
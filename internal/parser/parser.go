@@ -6,8 +6,12 @@ import (
 	"go/parser"
 	"go/token"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/rail44/mantra/internal/analysis"
 )
@@ -39,30 +43,55 @@ type FailureReason struct {
 	Phase   string // Phase where failure occurred ("context_gathering" or "implementation")
 	Message string // Detailed failure message
 	Context string // Additional context information
+	Attempt string // Rejected implementation, if any, shown to the model on the next run
 }
 
 // GenerationResult represents the result of generating implementation for a target
 type GenerationResult struct {
-	Target         *Target        // The target function that was processed
-	Success        bool           // Whether generation succeeded
-	Implementation string         // Generated implementation code (when Success=true)
-	FailureReason  *FailureReason // Detailed failure information (when Success=false)
-	Duration       time.Duration  // Time taken for generation
+	Target           *Target        // The target function that was processed
+	Success          bool           // Whether generation succeeded
+	Implementation   string         // Generated implementation code (when Success=true)
+	FailureReason    *FailureReason // Detailed failure information (when Success=false)
+	Duration         time.Duration  // Time taken for generation
+	PromptTokens     int            // Prompt tokens consumed across all phases
+	CompletionTokens int            // Completion tokens consumed across all phases
+	CachedTokens     int            // Prompt tokens served from the provider's prompt cache
+	Rounds           int            // Chat completion requests made across all phases
+	ToolCalls        map[string]int // Tool calls by tool name across all phases
+	ToolErrors       map[string]int // Tool calls that returned an error, by tool name
+	Provider         string         // Name of the provider that served the requests
+	Rule             string         // Rule that synthesized the implementation without a model (empty if none)
+	Degraded         bool           // Generated with a single prompt without tools, as the model does not call them
+	Reused           bool           // Existing implementation kept, as the target is up to date
+	Benchmark        string         // Body of the generated benchmark (// mantra:bench; empty if none)
+	Fuzz             string         // Body of the generated fuzz test (// mantra:fuzz; empty if none)
+	Helpers          []Helper       // Private helper functions the implementation calls (when Success=true)
 }
 
-// Target represents a function or method to generate
+// Target represents a function, method or function variable to generate
 type Target struct {
 	Name        string         // Function or method name
 	Receiver    *Receiver      // Receiver for methods (nil for functions)
 	Params      []Param        // Function parameters
 	Returns     []Return       // Return values
 	Instruction string         // Content from // mantra: comment
+	Fast        bool           // Skip context gathering (// mantra:fast)
+	Tools       []string       // Tools offered to the model (// mantra:tools inspect,check_code); nil offers all
+	NoPanic     bool           // Generated code must not call panic (// mantra:no_panic)
+	Bench       bool           // Also generate a benchmark of the implementation (// mantra:bench)
+	BenchNote   string         // What the benchmark should measure, written after // mantra:bench
+	Fuzz        bool           // Also generate a fuzz test of the implementation (// mantra:fuzz)
+	FuzzNote    string         // What the fuzz test should exercise, written after // mantra:fuzz
+	FuncVar     bool           // Function variable (var Name = func(...) ...) rather than a declared function
+	Region      *Region        // Generated region of a hand-written function (// mantra:region); nil generates the whole body
 	FilePath    string         // Source file path
 	HasPanic    bool           // Whether function contains panic("not implemented")
 	FuncDecl    *ast.FuncDecl  // AST node for the function declaration
 	TokenSet    *token.FileSet // Token file set for position information
+	commentPos  token.Pos      // Position of the mantra comment
 	// Generation result fields (set during processing)
 	Implementation   string         // Generated implementation (temporary storage)
+	Helpers          []Helper       // Private helper functions generated with the implementation
 	GenerationFailed bool           // Whether generation failed for this target
 	FailureReason    *FailureReason // Detailed failure information (when GenerationFailed=true)
 }
@@ -81,6 +110,7 @@ type Param struct {
 
 // Return represents return value
 type Return struct {
+	Name string // Result name (empty for unnamed results)
 	Type string // Return type
 }
 
@@ -91,7 +121,12 @@ func ParseFileInfo(filePath string) (*FileInfo, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
+	return ParseSource(filePath, sourceContent)
+}
 
+// ParseSource is ParseFileInfo for the content of filePath as given, e.g. an unsaved
+// editor buffer
+func ParseSource(filePath string, sourceContent []byte) (*FileInfo, error) {
 	fset := token.NewFileSet()
 	node, err := parser.ParseFile(fset, filePath, sourceContent, parser.ParseComments)
 	if err != nil {
@@ -117,7 +152,7 @@ func ParseFileInfo(filePath string) (*FileInfo, error) {
 	}
 
 	// Parse targets using existing logic
-	targets, err := parseTargetsFromNode(node, fset, filePath)
+	targets, err := parseTargetsFromNode(node, fset, filePath, sourceContent)
 	if err != nil {
 		return nil, err
 	}
@@ -135,24 +170,103 @@ func ParseFile(filePath string) ([]*Target, error) {
 	return fileInfo.Targets, nil
 }
 
+// mantraComment is a // mantra: comment group preceding a function
+type mantraComment struct {
+	instruction string
+	fast        bool     // Marked with // mantra:fast
+	tools       []string // Listed by // mantra:tools
+	noPanic     bool     // Marked with // mantra:no_panic
+	bench       bool     // Marked with // mantra:bench
+	benchNote   string   // Text following // mantra:bench
+	fuzz        bool     // Marked with // mantra:fuzz
+	fuzzNote    string   // Text following // mantra:fuzz
+}
+
+// cutFastMarker strips the "fast" marker written directly after "// mantra:" (as in "// mantra:fast")
+func cutFastMarker(text string) (string, bool) {
+	rest, ok := strings.CutPrefix(text, "fast")
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return text, false
+	}
+	return rest, true
+}
+
+// noPanicDirective is written on a line of its own after "// mantra:" to forbid panics in the
+// generated body
+const noPanicDirective = "no_panic"
+
+// cutNoteDirective parses a directive such as "bench" or "fuzz" written directly after
+// "// mantra:" (as in "// mantra:bench with 1 KiB inputs"), returning the optional note
+// following it
+func cutNoteDirective(text, directive string) (string, bool) {
+	rest, ok := strings.CutPrefix(text, directive)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// cutToolsDirective parses a "tools" directive written directly after "// mantra:" (as in
+// "// mantra:tools inspect,check_code"), returning the tool names. A directive listing no
+// tools returns an empty, non-nil list
+func cutToolsDirective(text string) ([]string, bool) {
+	rest, ok := strings.CutPrefix(text, "tools")
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, false
+	}
+	names := strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if names == nil {
+		names = []string{}
+	}
+	return names, true
+}
+
 // parseTargetsFromNode extracts targets from parsed AST node
-func parseTargetsFromNode(node *ast.File, fset *token.FileSet, filePath string) ([]*Target, error) {
+func parseTargetsFromNode(node *ast.File, fset *token.FileSet, filePath string, content []byte) ([]*Target, error) {
 	var targets []*Target
 
 	// Map to store mantra comments by position
-	mantraComments := make(map[token.Pos]string)
+	mantraComments := make(map[token.Pos]mantraComment)
 
 	// First pass: collect all // mantra: comments
 	for _, commentGroup := range node.Comments {
+		if hasRegionMarker(commentGroup) {
+			// Region markers inside a function, read with the function
+			continue
+		}
+
 		var mantraInstruction strings.Builder
 		foundMantra := false
+		fast := false
+		noPanic := false
+		bench, fuzz := false, false
+		var benchNote, fuzzNote string
+		var toolNames []string
 
 		for _, comment := range commentGroup.List {
 			text := strings.TrimSpace(comment.Text)
-			if strings.HasPrefix(text, "// mantra:") {
+			directive, isMantra := strings.CutPrefix(text, "// mantra:")
+			if names, ok := cutToolsDirective(directive); isMantra && ok {
+				// A directive line, not part of the instruction
+				toolNames = names
+			} else if isMantra && strings.TrimSpace(directive) == noPanicDirective {
+				noPanic = true
+			} else if note, ok := cutNoteDirective(directive, "bench"); isMantra && ok {
+				bench = true
+				benchNote = note
+			} else if note, ok := cutNoteDirective(directive, "fuzz"); isMantra && ok {
+				fuzz = true
+				fuzzNote = note
+			} else if isMantra {
 				foundMantra = true
-				instruction := strings.TrimSpace(strings.TrimPrefix(text, "// mantra:"))
-				mantraInstruction.WriteString(instruction)
+				instruction := directive
+				if rest, ok := cutFastMarker(instruction); ok {
+					fast = true
+					instruction = rest
+				}
+				mantraInstruction.WriteString(strings.TrimSpace(instruction))
 			} else if foundMantra && strings.HasPrefix(text, "//") {
 				// Continuation of mantra comment
 				additionalText := strings.TrimSpace(strings.TrimPrefix(text, "//"))
@@ -165,100 +279,167 @@ func parseTargetsFromNode(node *ast.File, fset *token.FileSet, filePath string)
 
 		if foundMantra {
 			// Store comment with its end position
-			mantraComments[commentGroup.End()] = mantraInstruction.String()
+			instruction := mantraInstruction.String()
+			if fast {
+				// A bare // mantra:fast line leaves nothing before the continuation lines
+				instruction = strings.TrimPrefix(instruction, "\n")
+			}
+			mantraComments[commentGroup.End()] = mantraComment{
+				instruction: instruction,
+				fast:        fast,
+				tools:       toolNames,
+				noPanic:     noPanic,
+				bench:       bench,
+				benchNote:   benchNote,
+				fuzz:        fuzz,
+				fuzzNote:    fuzzNote,
+			}
 		}
 	}
 
-	// Second pass: find functions with mantra comments
-	ast.Inspect(node, func(n ast.Node) bool {
-		switch x := n.(type) {
+	// Targets declared for this file in the sidecar file of the package
+	declared, sidecarPath, err := sidecarTargets(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Second pass: find functions and function variables with mantra comments
+	for _, decl := range node.Decls {
+		var x *ast.FuncDecl
+		funcVar := false
+		switch d := decl.(type) {
 		case *ast.FuncDecl:
-			// Check if there's a mantra comment immediately before this function
-			var instruction string
-			var found bool
-
-			// Look for mantra comment right before function
-			for pos, instr := range mantraComments {
-				if pos < x.Pos() && x.Pos()-pos < maxCommentGap {
-					instruction = instr
-					found = true
-					break
-				}
-			}
+			x = d
+		case *ast.GenDecl:
+			x, funcVar = analysis.FuncVarDecl(d), true
+		}
+		if x == nil {
+			continue
+		}
 
-			if !found {
-				return true
+		// Check if there's a mantra comment immediately before this function
+		var comment mantraComment
+		var commentPos token.Pos
+		var found bool
+
+		// Look for mantra comment right before function
+		for pos, c := range mantraComments {
+			if pos < x.Pos() && x.Pos()-pos < maxCommentGap {
+				comment = c
+				commentPos = pos
+				found = true
+				break
 			}
+		}
 
-			// Check if function contains panic("not implemented")
-			hasPanic := containsNotImplementedPanic(x.Body)
+		// Functions without a mantra comment may be declared in the sidecar file
+		if def, ok := declared[funcKey(x)]; ok {
+			if found {
+				return nil, fmt.Errorf("%s: %s has a mantra comment and is declared in %s", fset.Position(x.Pos()), x.Name.Name, sidecarPath)
+			}
+			comment, found = def.comment(), true
+			delete(declared, funcKey(x))
+		}
 
-			target := &Target{
-				Name:        x.Name.Name,
-				Instruction: instruction,
-				FilePath:    filePath,
-				HasPanic:    hasPanic,
-				FuncDecl:    x,
-				TokenSet:    fset,
+		// Or have a generated region
+		var region *Region
+		if !found {
+			var err error
+			region, comment, commentPos, err = parseRegion(node, x, fset, content)
+			if err != nil {
+				return nil, err
 			}
+			if region == nil {
+				continue
+			}
+		}
 
-			// Parse receiver for methods
-			if x.Recv != nil && len(x.Recv.List) > 0 {
-				recv := x.Recv.List[0]
-				target.Receiver = &Receiver{
-					Type: analysis.ExtractTypeString(recv.Type),
-				}
-				if len(recv.Names) > 0 {
-					target.Receiver.Name = recv.Names[0].Name
-				}
+		// Check if function contains panic("not implemented")
+		hasPanic := containsNotImplementedPanic(x.Body)
+
+		target := &Target{
+			Name:        x.Name.Name,
+			Instruction: comment.instruction,
+			Fast:        comment.fast,
+			Tools:       comment.tools,
+			NoPanic:     comment.noPanic,
+			Bench:       comment.bench,
+			BenchNote:   comment.benchNote,
+			Fuzz:        comment.fuzz,
+			FuzzNote:    comment.fuzzNote,
+			FuncVar:     funcVar,
+			Region:      region,
+			FilePath:    filePath,
+			HasPanic:    hasPanic,
+			FuncDecl:    x,
+			TokenSet:    fset,
+			commentPos:  commentPos,
+		}
+
+		// Parse receiver for methods
+		if x.Recv != nil && len(x.Recv.List) > 0 {
+			recv := x.Recv.List[0]
+			target.Receiver = &Receiver{
+				Type: analysis.ExtractTypeString(recv.Type),
+			}
+			if len(recv.Names) > 0 {
+				target.Receiver.Name = recv.Names[0].Name
 			}
+		}
 
-			// Parse parameters
-			if x.Type.Params != nil {
-				for _, field := range x.Type.Params.List {
-					paramType := analysis.ExtractTypeString(field.Type)
-					if len(field.Names) == 0 {
-						// Unnamed parameter
+		// Parse parameters
+		if x.Type.Params != nil {
+			for _, field := range x.Type.Params.List {
+				paramType := analysis.ExtractTypeString(field.Type)
+				if len(field.Names) == 0 {
+					// Unnamed parameter
+					target.Params = append(target.Params, Param{
+						Type: paramType,
+					})
+				} else {
+					// Named parameters
+					for _, name := range field.Names {
 						target.Params = append(target.Params, Param{
+							Name: name.Name,
 							Type: paramType,
 						})
-					} else {
-						// Named parameters
-						for _, name := range field.Names {
-							target.Params = append(target.Params, Param{
-								Name: name.Name,
-								Type: paramType,
-							})
-						}
 					}
 				}
 			}
+		}
 
-			// Parse return values
-			if x.Type.Results != nil {
-				for _, field := range x.Type.Results.List {
-					retType := analysis.ExtractTypeString(field.Type)
-					// Return values can have multiple types in one field
-					if len(field.Names) == 0 {
+		// Parse return values
+		if x.Type.Results != nil {
+			for _, field := range x.Type.Results.List {
+				retType := analysis.ExtractTypeString(field.Type)
+				// Return values can have multiple types in one field
+				if len(field.Names) == 0 {
+					target.Returns = append(target.Returns, Return{
+						Type: retType,
+					})
+				} else {
+					// Named results are part of the signature the body sees
+					for _, name := range field.Names {
 						target.Returns = append(target.Returns, Return{
+							Name: name.Name,
 							Type: retType,
 						})
-					} else {
-						// Named returns (rare but possible)
-						for range field.Names {
-							target.Returns = append(target.Returns, Return{
-								Type: retType,
-							})
-						}
 					}
 				}
 			}
-
-			targets = append(targets, target)
 		}
-		return true
-	})
 
+		targets = append(targets, target)
+	}
+
+	if len(declared) > 0 {
+		var missing []string
+		for _, def := range declared {
+			missing = append(missing, def.Function)
+		}
+		slices.Sort(missing)
+		return nil, fmt.Errorf("%s: %s not declared in %s", sidecarPath, strings.Join(missing, ", "), filepath.Base(filePath))
+	}
 	return targets, nil
 }
 
@@ -322,19 +503,24 @@ func (t *Target) GetFunctionSignature() string {
 
 	sig.WriteString(")")
 
-	// Add return values
+	// Add return values; named results always need parentheses
 	if len(t.Returns) > 0 {
 		sig.WriteString(" ")
-		if len(t.Returns) > 1 {
+		parens := len(t.Returns) > 1 || t.Returns[0].Name != ""
+		if parens {
 			sig.WriteString("(")
 		}
 		for i, ret := range t.Returns {
 			if i > 0 {
 				sig.WriteString(", ")
 			}
+			if ret.Name != "" {
+				sig.WriteString(ret.Name)
+				sig.WriteString(" ")
+			}
 			sig.WriteString(ret.Type)
 		}
-		if len(t.Returns) > 1 {
+		if parens {
 			sig.WriteString(")")
 		}
 	}
@@ -342,6 +528,21 @@ func (t *Target) GetFunctionSignature() string {
 	return sig.String()
 }
 
+// ConstructedType returns the type built by a constructor target
+// (e.g. "Service" for func NewService(...) *Service), or an empty string if the target is not a constructor
+func (t *Target) ConstructedType() string {
+	if t.Receiver != nil || !strings.HasPrefix(t.Name, "New") || len(t.Returns) == 0 {
+		return ""
+	}
+
+	// Only T and *T count; slices, maps and qualified types are not constructed here
+	typeName := strings.TrimPrefix(t.Returns[0].Type, "*")
+	if typeName != analysis.CleanTypeName(typeName) || analysis.IsBuiltinType(typeName) {
+		return ""
+	}
+	return typeName
+}
+
 // GetDisplayName returns a display name for the target (e.g., "(*Repository).GetUser" for methods)
 func (t *Target) GetDisplayName() string {
 	if t.Receiver != nil {
@@ -349,3 +550,46 @@ func (t *Target) GetDisplayName() string {
 	}
 	return t.Name
 }
+
+// Lines returns the first and last source line of the target, from its mantra comment to
+// the end of its body
+func (t *Target) Lines() (int, int) {
+	if t.FuncDecl == nil || t.TokenSet == nil {
+		return 0, 0
+	}
+	start := t.FuncDecl.Pos()
+	if t.FuncDecl.Doc != nil && t.FuncDecl.Doc.Pos() < start {
+		start = t.FuncDecl.Doc.Pos()
+	}
+	if t.commentPos.IsValid() && t.commentPos < start {
+		start = t.commentPos
+	}
+	return t.TokenSet.Position(start).Line, t.TokenSet.Position(t.FuncDecl.End()).Line
+}
+
+// BenchmarkName returns the name of the benchmark generated for the target, e.g.
+// "BenchmarkParse" for Parse and "BenchmarkCache_Get" for (*Cache).Get
+func (t *Target) BenchmarkName() string {
+	return t.testFuncName("Benchmark")
+}
+
+// FuzzName returns the name of the fuzz test generated for the target, e.g. "FuzzParse"
+func (t *Target) FuzzName() string {
+	return t.testFuncName("Fuzz")
+}
+
+// testFuncName names a test function of the target. The first letter after the prefix is
+// upper-cased, as go test only runs functions named that way
+func (t *Target) testFuncName(prefix string) string {
+	name := upperFirst(t.Name)
+	if t.Receiver != nil && t.FuncDecl != nil && t.FuncDecl.Recv != nil && len(t.FuncDecl.Recv.List) > 0 {
+		name = upperFirst(analysis.ReceiverTypeName(t.FuncDecl.Recv.List[0].Type)) + "_" + t.Name
+	}
+	return prefix + name
+}
+
+// upperFirst upper-cases the first letter of s
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
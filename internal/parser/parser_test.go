@@ -3,6 +3,7 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -239,3 +240,386 @@ func TestGetFunctionSignature(t *testing.T) {
 		})
 	}
 }
+
+func TestConstructedType(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   Target
+		expected string
+	}{
+		{
+			name:     "Pointer constructor with error",
+			target:   Target{Name: "NewService", Returns: []Return{{Type: "*Service"}, {Type: "error"}}},
+			expected: "Service",
+		},
+		{
+			name:     "Value constructor",
+			target:   Target{Name: "NewConfig", Returns: []Return{{Type: "Config"}}},
+			expected: "Config",
+		},
+		{
+			name:     "Slice return is not a constructor",
+			target:   Target{Name: "NewUsers", Returns: []Return{{Type: "[]User"}}},
+			expected: "",
+		},
+		{
+			name:     "Qualified return is not a constructor",
+			target:   Target{Name: "NewClient", Returns: []Return{{Type: "*http.Client"}}},
+			expected: "",
+		},
+		{
+			name:     "Method is not a constructor",
+			target:   Target{Name: "NewUser", Receiver: &Receiver{Type: "*Factory"}, Returns: []Return{{Type: "*User"}}},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.target.ConstructedType(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseFastMarker(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.go")
+
+	testContent := `package test
+
+// mantra:fast Add a and b
+func Add(a, b int) int {
+	panic("not implemented")
+}
+
+// mantra:fast
+// Subtract b from a
+func Sub(a, b int) int {
+	panic("not implemented")
+}
+
+// mantra: fast lookup of the user cache
+func Lookup(id string) string {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	targets, err := ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	expected := map[string]struct {
+		instruction string
+		fast        bool
+	}{
+		"Add":    {"Add a and b", true},
+		"Sub":    {"Subtract b from a", true},
+		"Lookup": {"fast lookup of the user cache", false},
+	}
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %d targets, got %d", len(expected), len(targets))
+	}
+	for _, target := range targets {
+		want := expected[target.Name]
+		if target.Instruction != want.instruction || target.Fast != want.fast {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", target.Name, target.Instruction, target.Fast, want.instruction, want.fast)
+		}
+	}
+}
+
+func TestParseToolsDirective(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.go")
+
+	testContent := `package test
+
+// mantra: Hash the password with bcrypt
+// mantra:tools inspect, check_code
+func Hash(password string) string {
+	panic("not implemented")
+}
+
+// mantra:tools
+// mantra: Compare the hashes in constant time
+func Equal(a, b string) bool {
+	panic("not implemented")
+}
+
+// mantra: tools are listed in the README
+// mantra:no_panic
+func Describe() string {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	targets, err := ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	expected := map[string]struct {
+		instruction string
+		tools       []string
+	}{
+		"Hash":     {"Hash the password with bcrypt", []string{"inspect", "check_code"}},
+		"Equal":    {"Compare the hashes in constant time", []string{}},
+		"Describe": {"tools are listed in the README", nil},
+	}
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %d targets, got %d", len(expected), len(targets))
+	}
+	for _, target := range targets {
+		if target.NoPanic != (target.Name == "Describe") {
+			t.Errorf("%s: expected NoPanic only for Describe, got %v", target.Name, target.NoPanic)
+		}
+		want := expected[target.Name]
+		if target.Instruction != want.instruction || (target.Tools == nil) != (want.tools == nil) ||
+			strings.Join(target.Tools, ",") != strings.Join(want.tools, ",") {
+			t.Errorf("%s: got (%q, %#v), want (%q, %#v)", target.Name, target.Instruction, target.Tools, want.instruction, want.tools)
+		}
+	}
+}
+
+func TestParseBenchDirective(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.go")
+
+	testContent := `package test
+
+// mantra: Tokenize the input
+// mantra:bench with a 4 KiB document
+func Tokenize(input string) []string {
+	panic("not implemented")
+}
+
+type lexer struct{}
+
+// mantra:bench
+// mantra: Read the next token
+func (l *lexer) next() string {
+	panic("not implemented")
+}
+
+// mantra: benchmarks are not generated for this one
+func Describe() string {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	targets, err := ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	expected := map[string]struct {
+		instruction string
+		bench       bool
+		note        string
+		benchName   string
+	}{
+		"Tokenize": {"Tokenize the input", true, "with a 4 KiB document", "BenchmarkTokenize"},
+		"next":     {"Read the next token", true, "", "BenchmarkLexer_next"},
+		"Describe": {"benchmarks are not generated for this one", false, "", "BenchmarkDescribe"},
+	}
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %d targets, got %d", len(expected), len(targets))
+	}
+	for _, target := range targets {
+		want := expected[target.Name]
+		if target.Instruction != want.instruction || target.Bench != want.bench || target.BenchNote != want.note {
+			t.Errorf("%s: got (%q, %v, %q), want (%q, %v, %q)", target.Name, target.Instruction, target.Bench, target.BenchNote, want.instruction, want.bench, want.note)
+		}
+		if name := target.BenchmarkName(); name != want.benchName {
+			t.Errorf("%s: expected benchmark name %s, got %s", target.Name, want.benchName, name)
+		}
+	}
+}
+
+func TestParseFuzzDirective(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.go")
+
+	testContent := `package test
+
+// mantra: Parse a duration such as 1h30m
+// mantra:fuzz with malformed units
+// mantra:bench
+func ParseDuration(s string) (int64, error) {
+	panic("not implemented")
+}
+
+type decoder struct{}
+
+// mantra:fuzz
+// mantra: Decode a varint
+func (d *decoder) varint(b []byte) (uint64, int) {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	targets, err := ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(targets))
+	}
+
+	parse, varint := targets[0], targets[1]
+	if parse.Instruction != "Parse a duration such as 1h30m" || !parse.Fuzz || parse.FuzzNote != "with malformed units" || !parse.Bench {
+		t.Errorf("ParseDuration: got (%q, %v, %q, %v)", parse.Instruction, parse.Fuzz, parse.FuzzNote, parse.Bench)
+	}
+	if name := parse.FuzzName(); name != "FuzzParseDuration" {
+		t.Errorf("Expected fuzz test name FuzzParseDuration, got %s", name)
+	}
+	if varint.Instruction != "Decode a varint" || !varint.Fuzz || varint.FuzzNote != "" || varint.Bench {
+		t.Errorf("varint: got (%q, %v, %q, %v)", varint.Instruction, varint.Fuzz, varint.FuzzNote, varint.Bench)
+	}
+	if name := varint.FuzzName(); name != "FuzzDecoder_varint" {
+		t.Errorf("Expected fuzz test name FuzzDecoder_varint, got %s", name)
+	}
+}
+
+func TestParseFuncVars(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.go")
+
+	testContent := `package test
+
+// mantra: Parse a comma-separated list
+var ParseList = func(s string) (items []string, err error) {
+	panic("not implemented")
+}
+
+// mantra: Grouped variables are not targets
+var (
+	Format = func(items []string) string {
+		panic("not implemented")
+	}
+)
+
+// mantra: Variables without a function literal are not targets
+var Handler func(string) error
+
+// mantra: Trim spaces
+func Trim(s string) string {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	targets, err := ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(targets))
+	}
+
+	parseList := targets[0]
+	if parseList.Name != "ParseList" || !parseList.FuncVar || !parseList.HasPanic {
+		t.Errorf("Expected function variable ParseList with a panic stub, got %+v", parseList)
+	}
+	if got := parseList.GetFunctionSignature(); got != "func ParseList(s string) (items []string, err error)" {
+		t.Errorf("Expected literal's signature, got %s", got)
+	}
+	if parseList.FuncDecl.Doc == nil || parseList.FuncDecl.Doc.Text() != "mantra: Parse a comma-separated list\n" {
+		t.Errorf("Expected the variable's doc comment, got %+v", parseList.FuncDecl.Doc)
+	}
+	if targets[1].Name != "Trim" || targets[1].FuncVar {
+		t.Errorf("Expected function Trim, got %+v", targets[1])
+	}
+}
+
+func TestParsedSignatures(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.go")
+
+	// Each target's signature is rendered exactly as declared
+	signatures := []string{
+		"func Join(sep string, parts ...string) string",
+		"func Divide(a int, b int) (q int, err error)",
+		"func Count(items []string) (n int)",
+		"func (b *Buffer) Write(p []byte, _ ...any) (int, error)",
+		"func Checksum(data [32]byte) [8]byte",
+	}
+	content := "package test\n\ntype Buffer struct{}\n"
+	for _, sig := range signatures {
+		content += "\n// mantra: do it\n" + sig + " {\n\tpanic(\"not implemented\")\n}\n"
+	}
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	targets, err := ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+	if len(targets) != len(signatures) {
+		t.Fatalf("Expected %d targets, got %d", len(signatures), len(targets))
+	}
+	for i, target := range targets {
+		if got := target.GetFunctionSignature(); got != signatures[i] {
+			t.Errorf("Expected %q, got %q", signatures[i], got)
+		}
+	}
+}
+
+func TestTargetLines(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.go")
+
+	testContent := `package test
+
+// Add sums its arguments.
+// mantra: Add a and b
+func Add(a, b int) int {
+	panic("not implemented")
+}
+
+// mantra: Subtract b from a
+
+func Sub(a, b int) int { panic("not implemented") }
+
+// mantra: Double n
+var Double = func(n int) int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	targets, err := ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	expected := map[string][2]int{"Add": {3, 7}, "Sub": {9, 11}, "Double": {13, 16}}
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %d targets, got %d", len(expected), len(targets))
+	}
+	for _, target := range targets {
+		start, end := target.Lines()
+		if want := expected[target.Name]; start != want[0] || end != want[1] {
+			t.Errorf("%s: got lines %d-%d, want %d-%d", target.Name, start, end, want[0], want[1])
+		}
+	}
+}
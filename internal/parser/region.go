@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+const (
+	// regionDirective opens a generated region inside a hand-written function, as in
+	// "// mantra:region validate check the request fields"
+	regionDirective = "region"
+	// regionEndDirective closes the region, written on a line of its own as "// mantra:end"
+	regionEndDirective = "end"
+)
+
+// Region is the generated part of an otherwise hand-written function: the lines between
+// // mantra:region and // mantra:end. The target's instruction is the region's, and the
+// rest of the body is kept as written
+type Region struct {
+	Name   string // Name written after // mantra:region
+	Before string // Body lines up to and including the // mantra:region comment
+	After  string // Body lines from // mantra:end on
+	Indent string // Indentation of the // mantra:region comment, given to the generated lines
+	Start  int    // Offset of the first line of the region in the source
+	End    int    // Offset of the line of // mantra:end in the source
+}
+
+// Splice returns the function body with code as the region, indented to its markers
+func (r *Region) Splice(code string) string {
+	lines := strings.Split(strings.TrimSpace(code), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = r.Indent + line
+		}
+	}
+	return r.Before + strings.Join(lines, "\n") + "\n" + r.After
+}
+
+// Code returns the code of the region in a body returned by Splice, without the indentation
+// of its markers
+func (r *Region) Code(body string) string {
+	code := strings.TrimSuffix(strings.TrimPrefix(body, r.Before), "\n"+r.After)
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, r.Indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findRegion returns the comments opening and closing the region in body, or nil if the
+// body has no // mantra:region comment. Other // mantra: comments in the body are not
+// markers
+func findRegion(file *ast.File, body *ast.BlockStmt) (start, end *ast.Comment, err error) {
+	if body == nil {
+		return nil, nil, nil
+	}
+	for _, group := range file.Comments {
+		if group.Pos() <= body.Lbrace || group.End() >= body.Rbrace {
+			continue
+		}
+		for _, comment := range group.List {
+			switch directive, _ := regionMarker(comment); directive {
+			case regionDirective:
+				if start != nil {
+					return nil, nil, fmt.Errorf("only one // mantra:region is allowed in a function")
+				}
+				start = comment
+			case regionEndDirective:
+				if start == nil {
+					return nil, nil, fmt.Errorf("// mantra:end without // mantra:region")
+				}
+				if end != nil {
+					return nil, nil, fmt.Errorf("only one // mantra:end is allowed in a function")
+				}
+				end = comment
+			}
+		}
+	}
+	if start != nil && end == nil {
+		_, rest := regionMarker(start)
+		name, _, _ := strings.Cut(rest, " ")
+		return nil, nil, fmt.Errorf("// mantra:region %s is not closed by // mantra:end", name)
+	}
+	return start, end, nil
+}
+
+// regionMarker returns the directive of a comment opening or closing a region ("region"
+// or "end") and the text following it, or "" if the comment is no marker
+func regionMarker(comment *ast.Comment) (string, string) {
+	directive, ok := strings.CutPrefix(strings.TrimSpace(comment.Text), "// mantra:")
+	if !ok {
+		return "", ""
+	}
+	if strings.TrimSpace(directive) == regionEndDirective {
+		return regionEndDirective, ""
+	}
+	if rest, ok := cutNoteDirective(directive, regionDirective); ok {
+		return regionDirective, rest
+	}
+	return "", ""
+}
+
+// RegionLines returns the first and last line of the region in body, between the lines of
+// its // mantra:region comment and // mantra:end. ok is false if body has no complete region
+func RegionLines(fset *token.FileSet, file *ast.File, body *ast.BlockStmt) (first, last int, ok bool) {
+	start, end, err := findRegion(file, body)
+	if err != nil || start == nil {
+		return 0, 0, false
+	}
+	opening := start
+	if lines := continuationLines(file, start); len(lines) > 0 {
+		opening = lines[len(lines)-1]
+	}
+	return fset.Position(opening.End()).Line + 1, fset.Position(end.Pos()).Line - 1, true
+}
+
+// hasRegionMarker reports whether a comment group opens or closes a region
+func hasRegionMarker(group *ast.CommentGroup) bool {
+	for _, comment := range group.List {
+		if directive, _ := regionMarker(comment); directive != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRegion reads the region of a function without a mantra comment. It returns a nil
+// region if the function has none
+func parseRegion(file *ast.File, fn *ast.FuncDecl, fset *token.FileSet, content []byte) (*Region, mantraComment, token.Pos, error) {
+	start, end, err := findRegion(file, fn.Body)
+	if err != nil {
+		return nil, mantraComment{}, token.NoPos, fmt.Errorf("%s: %s: %w", fset.Position(fn.Pos()), fn.Name.Name, err)
+	}
+	if start == nil {
+		return nil, mantraComment{}, token.NoPos, nil
+	}
+
+	// The instruction follows the name, and continues on the following comment lines
+	_, rest := regionMarker(start)
+	name, instruction, _ := strings.Cut(rest, " ")
+	var text strings.Builder
+	text.WriteString(strings.TrimSpace(instruction))
+	last := start
+	for _, comment := range continuationLines(file, start) {
+		if line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//")); line != "" {
+			text.WriteString("\n" + line)
+		}
+		last = comment
+	}
+	if name == "" || strings.TrimSpace(text.String()) == "" {
+		return nil, mantraComment{}, token.NoPos, fmt.Errorf("%s: // mantra:region needs a name and an instruction", fset.Position(start.Pos()))
+	}
+
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	src := string(content)
+	lineStart := strings.LastIndexByte(src[:offset(start.Pos())], '\n') + 1
+	indent := src[lineStart:offset(start.Pos())]
+	if strings.TrimSpace(indent) != "" {
+		return nil, mantraComment{}, token.NoPos, fmt.Errorf("%s: // mantra:region must be on a line of its own", fset.Position(start.Pos()))
+	}
+
+	// The region spans the lines between the end of the opening comment and the closing one
+	regionStart := offset(last.End())
+	if i := strings.IndexByte(src[regionStart:], '\n'); i >= 0 {
+		regionStart += i + 1
+	}
+	regionEnd := strings.LastIndexByte(src[:offset(end.Pos())], '\n') + 1
+	if regionEnd < regionStart {
+		return nil, mantraComment{}, token.NoPos, fmt.Errorf("%s: // mantra:end must be on a line of its own", fset.Position(end.Pos()))
+	}
+
+	region := &Region{
+		Name:   name,
+		Before: strings.TrimPrefix(src[offset(fn.Body.Lbrace)+1:regionStart], "\n"),
+		After:  strings.TrimRight(src[regionEnd:offset(fn.Body.Rbrace)], " \t\n"),
+		Indent: indent,
+		Start:  regionStart,
+		End:    regionEnd,
+	}
+	return region, mantraComment{instruction: strings.TrimSpace(text.String())}, last.End(), nil
+}
+
+// continuationLines returns the // comments following start in its comment group, up to
+// the next region marker
+func continuationLines(file *ast.File, start *ast.Comment) []*ast.Comment {
+	for _, group := range file.Comments {
+		for i, comment := range group.List {
+			if comment != start {
+				continue
+			}
+			var lines []*ast.Comment
+			for _, next := range group.List[i+1:] {
+				if directive, _ := regionMarker(next); directive != "" || !strings.HasPrefix(next.Text, "//") {
+					break
+				}
+				lines = append(lines, next)
+			}
+			return lines
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRegion(t *testing.T) {
+	source := `package test
+
+import "errors"
+
+type Request struct {
+	Name string
+	Size int
+}
+
+func Handle(req *Request) error {
+	if req == nil {
+		return errors.New("nil request")
+	}
+
+	// mantra:region validate reject requests without a name
+	// and sizes above 1024
+	panic("not implemented")
+	// mantra:end
+
+	return nil
+}
+
+func Plain() {
+	// mantra: not a region
+}
+`
+	fileInfo, err := ParseSource("test.go", []byte(source))
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	if len(fileInfo.Targets) != 1 {
+		t.Fatalf("Expected 1 target, got %d", len(fileInfo.Targets))
+	}
+
+	target := fileInfo.Targets[0]
+	if target.Name != "Handle" || target.Region == nil {
+		t.Fatalf("Expected region target Handle, got %+v", target)
+	}
+	if target.Instruction != "reject requests without a name\nand sizes above 1024" {
+		t.Errorf("Expected the region's instruction, got %q", target.Instruction)
+	}
+	if target.Region.Name != "validate" || target.Region.Indent != "\t" {
+		t.Errorf("Expected region validate indented by a tab, got %+v", target.Region)
+	}
+
+	body := target.Region.Splice("if req.Name == \"\" {\n\treturn errors.New(\"missing name\")\n}")
+	want := `	if req == nil {
+		return errors.New("nil request")
+	}
+
+	// mantra:region validate reject requests without a name
+	// and sizes above 1024
+	if req.Name == "" {
+		return errors.New("missing name")
+	}
+	// mantra:end
+
+	return nil`
+	if body != want {
+		t.Errorf("Expected spliced body:\n%s\ngot:\n%s", want, body)
+	}
+	if code := target.Region.Code(body); code != "if req.Name == \"\" {\n\treturn errors.New(\"missing name\")\n}" {
+		t.Errorf("Expected the region code back from the spliced body, got %q", code)
+	}
+	if span := source[target.Region.Start:target.Region.End]; span != "\tpanic(\"not implemented\")\n" {
+		t.Errorf("Expected the offsets to span the region's lines, got %q", span)
+	}
+}
+
+func TestParseRegionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"unclosed", "\t// mantra:region check verify input\n\treturn\n", "not closed"},
+		{"end without region", "\t// mantra:end\n", "without // mantra:region"},
+		{"two regions", "\t// mantra:region a do a\n\t// mantra:end\n\t// mantra:region b do b\n\t// mantra:end\n", "only one"},
+		{"no instruction", "\t// mantra:region check\n\t// mantra:end\n", "needs a name and an instruction"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "package test\n\nfunc F() {\n" + tt.body + "}\n"
+			_, err := ParseSource("test.go", []byte(source))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
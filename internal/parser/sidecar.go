@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rail44/mantra/internal/analysis"
+)
+
+// SidecarNames are the files declaring targets outside the source, looked up in the
+// directory of the package in this order. Only the first one found is read
+var SidecarNames = []string{"mantra.targets.yaml", "mantra.targets.yml", "mantra.targets.json"}
+
+// SidecarTarget declares a target in a sidecar file instead of a // mantra: comment, with
+// the options the directives would set
+type SidecarTarget struct {
+	File            string   `yaml:"file" json:"file"`                         // Source file, relative to the sidecar file
+	Function        string   `yaml:"function" json:"function"`                 // "Parse", or "Cache.Get" and "(*Cache).Get" for methods
+	Instruction     string   `yaml:"instruction" json:"instruction"`           // Instruction, as written after // mantra:
+	InstructionFile string   `yaml:"instruction_file" json:"instruction_file"` // File holding the instruction, relative to the sidecar file
+	Fast            bool     `yaml:"fast" json:"fast"`
+	Tools           []string `yaml:"tools" json:"tools"` // nil offers all, an empty list none
+	NoPanic         bool     `yaml:"no_panic" json:"no_panic"`
+	Bench           bool     `yaml:"bench" json:"bench"`
+	BenchNote       string   `yaml:"bench_note" json:"bench_note"`
+	Fuzz            bool     `yaml:"fuzz" json:"fuzz"`
+	FuzzNote        string   `yaml:"fuzz_note" json:"fuzz_note"`
+}
+
+// sidecarFile is the layout of a sidecar file
+type sidecarFile struct {
+	Targets []SidecarTarget `yaml:"targets" json:"targets"`
+}
+
+// LoadSidecar reads the sidecar file of a package directory, resolving instruction files.
+// It returns the path of the file read, or "" when the directory has none
+func LoadSidecar(dir string) ([]SidecarTarget, string, error) {
+	for _, name := range SidecarNames {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		var file sidecarFile
+		if filepath.Ext(name) == ".json" {
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.DisallowUnknownFields()
+			err = decoder.Decode(&file)
+		} else {
+			decoder := yaml.NewDecoder(bytes.NewReader(data))
+			decoder.KnownFields(true)
+			if err = decoder.Decode(&file); err != nil && len(bytes.TrimSpace(data)) == 0 {
+				err = nil // An empty file declares no targets
+			}
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if err := resolveSidecar(dir, file.Targets); err != nil {
+			return nil, "", fmt.Errorf("%s: %w", path, err)
+		}
+		return file.Targets, path, nil
+	}
+	return nil, "", nil
+}
+
+// resolveSidecar validates the declared targets and reads their instruction files
+func resolveSidecar(dir string, targets []SidecarTarget) error {
+	seen := make(map[string]bool)
+	for i := range targets {
+		def := &targets[i]
+		if def.File == "" || def.Function == "" {
+			return fmt.Errorf("target %d needs a file and a function", i+1)
+		}
+		if _, err := os.Stat(filepath.Join(dir, def.File)); err != nil {
+			return fmt.Errorf("%s: file not found", def.File)
+		}
+		key := filepath.Clean(def.File) + ":" + sidecarFuncKey(def.Function)
+		if seen[key] {
+			return fmt.Errorf("%s %s is declared twice", def.File, def.Function)
+		}
+		seen[key] = true
+
+		switch {
+		case def.Instruction != "" && def.InstructionFile != "":
+			return fmt.Errorf("%s %s: set either instruction or instruction_file", def.File, def.Function)
+		case def.InstructionFile != "":
+			data, err := os.ReadFile(filepath.Join(dir, def.InstructionFile))
+			if err != nil {
+				return fmt.Errorf("%s %s: failed to read instruction file: %w", def.File, def.Function, err)
+			}
+			def.Instruction = string(data)
+		}
+		def.Instruction = strings.TrimSpace(def.Instruction)
+		if def.Instruction == "" {
+			return fmt.Errorf("%s %s has no instruction", def.File, def.Function)
+		}
+	}
+	return nil
+}
+
+// sidecarFuncKey normalizes a function name of a sidecar file: methods are keyed by the
+// name of their receiver type, whether or not written as a pointer
+func sidecarFuncKey(function string) string {
+	function = strings.ReplaceAll(function, " ", "")
+	recv, name, ok := strings.Cut(function, ").")
+	if !ok {
+		recv, name, ok = strings.Cut(function, ".")
+		if !ok {
+			return function
+		}
+	}
+	recv = strings.TrimLeft(recv, "(*")
+	if i := strings.IndexByte(recv, '['); i >= 0 {
+		recv = recv[:i] // Type parameters of generic receivers
+	}
+	return recv + "." + name
+}
+
+// funcKey is sidecarFuncKey for a declared function
+func funcKey(fn *ast.FuncDecl) string {
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		return analysis.ReceiverTypeName(fn.Recv.List[0].Type) + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// comment returns the declaration as the mantra comment it stands for
+func (def *SidecarTarget) comment() mantraComment {
+	return mantraComment{
+		instruction: def.Instruction,
+		fast:        def.Fast,
+		tools:       def.Tools,
+		noPanic:     def.NoPanic,
+		bench:       def.Bench,
+		benchNote:   def.BenchNote,
+		fuzz:        def.Fuzz,
+		fuzzNote:    def.FuzzNote,
+	}
+}
+
+// sidecarTargets returns the targets the sidecar file of the package declares in filePath,
+// by function key
+func sidecarTargets(filePath string) (map[string]*SidecarTarget, string, error) {
+	dir := filepath.Dir(filePath)
+	defs, path, err := LoadSidecar(dir)
+	if err != nil || len(defs) == 0 {
+		return nil, path, err
+	}
+	declared := make(map[string]*SidecarTarget)
+	for i := range defs {
+		if filepath.Join(dir, defs[i].File) == filepath.Clean(filePath) {
+			declared[sidecarFuncKey(defs[i].Function)] = &defs[i]
+		}
+	}
+	return declared, path, nil
+}
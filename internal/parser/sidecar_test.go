@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const sidecarSource = `package shop
+
+type Cart struct {
+	items []int
+}
+
+// Total is declared in the sidecar file
+func (c *Cart) Total() int {
+	panic("not implemented")
+}
+
+// mantra: Apply a percentage discount
+func Discount(price, percent int) int {
+	panic("not implemented")
+}
+
+func Count(items []int) int {
+	panic("not implemented")
+}
+`
+
+func writeSidecarPackage(t *testing.T, name, sidecar string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "shop.go"), []byte(sidecarSource), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(sidecar), 0644); err != nil {
+		t.Fatalf("Failed to write sidecar file: %v", err)
+	}
+	return filepath.Join(dir, "shop.go")
+}
+
+func TestParseSidecarTargets(t *testing.T) {
+	yamlSidecar := `targets:
+  - file: shop.go
+    function: (*Cart).Total
+    instruction_file: prompts/total.md
+    no_panic: true
+    tools: [check_code]
+  - file: shop.go
+    function: Count
+    instruction: |
+      Count the items
+      that are positive
+    fast: true
+`
+	jsonSidecar := `{"targets": [
+  {"file": "shop.go", "function": "Cart.Total", "instruction_file": "prompts/total.md", "no_panic": true, "tools": ["check_code"]},
+  {"file": "shop.go", "function": "Count", "instruction": "Count the items\nthat are positive", "fast": true}
+]}`
+
+	for name, sidecar := range map[string]string{"mantra.targets.yaml": yamlSidecar, "mantra.targets.json": jsonSidecar} {
+		t.Run(name, func(t *testing.T) {
+			source := writeSidecarPackage(t, name, sidecar)
+			prompts := filepath.Join(filepath.Dir(source), "prompts")
+			if err := os.MkdirAll(prompts, 0755); err != nil {
+				t.Fatalf("Failed to create prompts dir: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(prompts, "total.md"), []byte("Sum all item prices\n"), 0644); err != nil {
+				t.Fatalf("Failed to write instruction file: %v", err)
+			}
+
+			targets, err := ParseFile(source)
+			if err != nil {
+				t.Fatalf("Failed to parse file: %v", err)
+			}
+			if len(targets) != 3 {
+				t.Fatalf("Expected 3 targets, got %d", len(targets))
+			}
+
+			total, discount, count := targets[0], targets[1], targets[2]
+			if total.GetDisplayName() != "(*Cart).Total" || total.Instruction != "Sum all item prices" || !total.NoPanic || !slices.Equal(total.Tools, []string{"check_code"}) {
+				t.Errorf("Expected Total with the instruction file and options, got %+v", total)
+			}
+			if discount.Instruction != "Apply a percentage discount" {
+				t.Errorf("Expected Discount from its comment, got %+v", discount)
+			}
+			if count.Instruction != "Count the items\nthat are positive" || !count.Fast || count.Tools != nil {
+				t.Errorf("Expected fast target Count offering all tools, got %+v", count)
+			}
+		})
+	}
+}
+
+func TestParseSidecarErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sidecar string
+		want    string
+	}{
+		{"unknown function", "targets:\n  - {file: shop.go, function: Missing, instruction: do it}\n", "Missing not declared in shop.go"},
+		{"unknown file", "targets:\n  - {file: cart.go, function: Count, instruction: do it}\n", "cart.go: file not found"},
+		{"also commented", "targets:\n  - {file: shop.go, function: Discount, instruction: do it}\n", "has a mantra comment"},
+		{"no instruction", "targets:\n  - {file: shop.go, function: Count}\n", "has no instruction"},
+		{"declared twice", "targets:\n  - {file: shop.go, function: Cart.Total, instruction: a}\n  - {file: shop.go, function: (*Cart).Total, instruction: b}\n", "declared twice"},
+		{"unknown field", "targets:\n  - {file: shop.go, function: Count, instructions: do it}\n", "instructions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile(writeSidecarPackage(t, "mantra.targets.yaml", tt.sidecar))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ package phase
 const (
 	PhaseContextGathering = "Context Gathering"
 	PhaseImplementation   = "Implementation"
+	PhaseReview           = "Self-Review"
+	PhaseBenchmark        = "Benchmark"
+	PhaseFuzz             = "Fuzz Test"
 )
 
 // Phase states for Context Gathering
@@ -23,8 +23,9 @@ type ContextGatheringPhase struct {
 	schema      schemas.ResultSchema
 }
 
-// NewContextGatheringPhase creates a new context gathering phase
-func NewContextGatheringPhase(temperature float32, packagePath string, logger *slog.Logger) *ContextGatheringPhase {
+// NewContextGatheringPhase creates a new context gathering phase. The overlay supplies files
+// of packagePath that only exist in memory (optional)
+func NewContextGatheringPhase(temperature float32, packagePath string, logger *slog.Logger, overlay map[string][]byte) *ContextGatheringPhase {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -37,7 +38,9 @@ func NewContextGatheringPhase(temperature float32, packagePath string, logger *s
 
 	// Initialize tools for context gathering (limited to current package)
 	tools := []tools.Tool{
-		impl.NewInspectTool(packagePath), // Use go/packages for accurate type info including implementations
+		impl.NewInspectTool(packagePath).WithOverlay(overlay), // Use go/packages for accurate type info including implementations
+		impl.NewListErrorsTool(packagePath).WithOverlay(overlay),
+		impl.NewCallExamplesTool(packagePath),
 		impl.NewResultTool(
 			"context gathering",
 			phase.schema,
@@ -80,22 +83,28 @@ func (p *ContextGatheringPhase) SystemPrompt() string {
 ## Input Structure
 
 - <target>: The function signature to implement
+- <constructor>: Present for constructors (NewX); fields to wire and idioms to follow
 - <context>: Initial context from function signature
 	- Receiver and parameter type definitions
 	- Implemented methods for each type (excluding the method being implemented)
   - Already imported packages
 - <instruction>: Natural language description of what the function should do
+- <additional_context>: Implementations of related targets generated earlier in this run, if any
 
 ## Available Tools
 
 - inspect(): Get detail of identifier
 	- types, package, function and variable from current scope
+- list_errors(): List sentinel errors, error types and error helpers of the package (scope "module" adds other packages)
+- call_examples(): Show real calls of a function or method in the project with surrounding lines
 - result(): Submit the final result and complete this phase
 
 ## Process
 1. Gather additional context using the tools
 	- Use inspect() to get details of unclear identifier
 	- Prevent to use inspect() on standard library unless necessary
+	- Use list_errors() when the function returns errors, and report the errors to reuse in "constants" and "types"
+	- Use call_examples() on functions the implementation will call when their usage is unclear, and report useful examples in "functions"
 2. When you have enough context or cannot proceed, call the result() tool
 
 ## Result Tool Usage
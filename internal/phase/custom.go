@@ -0,0 +1,265 @@
+package phase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/rail44/mantra/internal/prompt"
+	"github.com/rail44/mantra/internal/tools"
+	"github.com/rail44/mantra/internal/tools/impl"
+	"github.com/rail44/mantra/internal/tools/schemas"
+)
+
+// CustomPhaseTools lists the tools a custom phase may request
+var CustomPhaseTools = []string{"check_code", "inspect", "search", "list_errors", "call_examples", "run_snippet"}
+
+// DefaultCustomResultSchema is the result schema of custom phases that do not define one
+var DefaultCustomResultSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"success": {
+			"type": "boolean",
+			"description": "Whether the phase completed"
+		},
+		"code": {
+			"type": "string",
+			"description": "The complete revised function body, only if the implementation was changed"
+		},
+		"comments": {
+			"type": "string",
+			"description": "Short summary of the outcome"
+		},
+		"error": {
+			"type": "object",
+			"properties": {
+				"message": {"type": "string"},
+				"details": {"type": "string"}
+			},
+			"required": ["message"]
+		}
+	},
+	"required": ["success"]
+}`)
+
+// CustomPhaseSpec describes a user-defined phase run on each accepted implementation
+type CustomPhaseSpec struct {
+	Name         string
+	SystemPrompt string
+	Tools        []string // Subset of CustomPhaseTools
+	Temperature  float32
+	ResultSchema json.RawMessage // JSON Schema of the result tool (nil uses DefaultCustomResultSchema)
+	Required     bool            // Fail the target when the phase fails instead of keeping the implementation
+	MaxRounds    int             // Rounds of tool calls (0: llm.DefaultMaxRounds)
+}
+
+// CustomPhase runs a user-defined system prompt against an accepted implementation
+type CustomPhase struct {
+	spec       CustomPhaseSpec
+	tools      []tools.Tool
+	logger     *slog.Logger
+	result     any
+	completed  bool
+	mu         sync.Mutex
+	schema     schemas.ResultSchema
+	resultTool *impl.ResultTool
+}
+
+// NewCustomPhase creates a custom phase from its spec
+func NewCustomPhase(spec CustomPhaseSpec, projectRoot string, logger *slog.Logger, opts Options) (*CustomPhase, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	schema, err := NewCustomResultSchema(spec.ResultSchema)
+	if err != nil {
+		return nil, fmt.Errorf("phase %q: %w", spec.Name, err)
+	}
+
+	phase := &CustomPhase{
+		spec:   spec,
+		logger: logger,
+		schema: schema,
+	}
+
+	for _, name := range spec.Tools {
+		switch name {
+		case "check_code":
+			phase.tools = append(phase.tools, newCheckCodeTool(projectRoot, opts))
+		case "inspect":
+			phase.tools = append(phase.tools, impl.NewInspectTool(projectRoot).WithOverlay(opts.Overlay))
+		case "search":
+			phase.tools = append(phase.tools, impl.NewSearchTool(projectRoot))
+		case "list_errors":
+			phase.tools = append(phase.tools, impl.NewListErrorsTool(projectRoot).WithOverlay(opts.Overlay))
+		case "call_examples":
+			phase.tools = append(phase.tools, impl.NewCallExamplesTool(projectRoot))
+		case "run_snippet":
+			// Only offered where the run enabled it, see Options.RunSnippet
+			if opts.RunSnippet {
+				phase.tools = append(phase.tools, impl.NewRunSnippetTool(opts.RunSnippetTimeout))
+			}
+		default:
+			return nil, fmt.Errorf("phase %q: unknown tool %q", spec.Name, name)
+		}
+	}
+	phase.resultTool = impl.NewResultTool(spec.Name, phase.schema, phase.storeResult)
+	phase.tools = append(phase.tools, phase.resultTool, phase.resultTool.AppendTool())
+
+	return phase, nil
+}
+
+// storeResult stores the result from the result tool
+func (p *CustomPhase) storeResult(result any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result = result
+	p.completed = true
+	return nil
+}
+
+// Name returns the name of this phase
+func (p *CustomPhase) Name() string {
+	return p.spec.Name
+}
+
+// Temperature returns the configured temperature
+func (p *CustomPhase) Temperature() float32 {
+	return p.spec.Temperature
+}
+
+// Tools returns the requested tools and the result tools
+func (p *CustomPhase) Tools() []tools.Tool {
+	return p.tools
+}
+
+// SystemPrompt returns the configured system prompt followed by the input and result conventions
+func (p *CustomPhase) SystemPrompt() string {
+	return p.spec.SystemPrompt + "\n\n" + customPhaseConventions
+}
+
+// customPhaseConventions tells the model how custom phase input and output are structured
+const customPhaseConventions = `## Input Structure
+- <target>: The function signature that was implemented
+- <context>: Types and packages available to the implementation
+- <instruction>: Natural language description of what the function should do
+- <additional_context>: The current implementation
+
+## Completing the Phase
+
+ALWAYS finish by calling the result() tool with JSON matching its schema, including "success".
+If you change the implementation, pass the complete revised function body as "code"; it must pass check_code.
+If the body is too long for a single call, send it in numbered parts with result_append(index, chunk) and pass the remainder as "code".
+If you cannot complete the phase, call result() with success=false and an "error" object with a "message".`
+
+// PromptBuilderWithImplementation returns a prompt builder that presents the current implementation
+func (p *CustomPhase) PromptBuilderWithImplementation(signature, code string) *prompt.Builder {
+	return p.PromptBuilder().WithAdditionalContext("## Current Implementation\n```go\n" + signature + " {\n" + code + "\n}\n```")
+}
+
+// PromptBuilder returns a prompt builder configured for the phase
+func (p *CustomPhase) PromptBuilder() *prompt.Builder {
+	builder := prompt.NewBuilder(p.logger)
+	builder.SetUseTools(true)
+	return builder
+}
+
+// Result returns the phase result and whether it's complete
+func (p *CustomPhase) Result() (any, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.result, p.completed
+}
+
+// Reset clears the phase state for reuse
+func (p *CustomPhase) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result = nil
+	p.completed = false
+	p.resultTool.Reset()
+}
+
+// ResultSchema returns the schema for this phase's result tool
+func (p *CustomPhase) ResultSchema() schemas.ResultSchema {
+	return p.schema
+}
+
+// customResultSchema validates results against a user-supplied JSON Schema
+type customResultSchema struct {
+	raw      json.RawMessage
+	compiled *jsonschema.Schema
+}
+
+// NewCustomResultSchema compiles a custom phase result schema. The schema must describe an
+// object with a boolean "success" property, which the runner uses to detect failures
+func NewCustomResultSchema(raw json.RawMessage) (schemas.ResultSchema, error) {
+	if len(raw) == 0 {
+		raw = DefaultCustomResultSchema
+	}
+
+	var shape struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(raw, &shape); err != nil {
+		return nil, fmt.Errorf("invalid result schema: %w", err)
+	}
+	if shape.Properties["success"].Type != "boolean" || !slices.Contains(shape.Required, "success") {
+		return nil, fmt.Errorf("result schema must require a boolean \"success\" property")
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid result schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("result.json", doc); err != nil {
+		return nil, fmt.Errorf("invalid result schema: %w", err)
+	}
+	compiled, err := compiler.Compile("result.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid result schema: %w", err)
+	}
+
+	return &customResultSchema{raw: raw, compiled: compiled}, nil
+}
+
+// Schema returns the JSON schema for custom phase results
+func (s *customResultSchema) Schema() json.RawMessage {
+	return s.raw
+}
+
+// Validate checks the result against the schema and that failures explain themselves
+func (s *customResultSchema) Validate(data any) error {
+	dataMap, ok := data.(map[string]any)
+	if !ok {
+		return fmt.Errorf("expected object, got %T", data)
+	}
+	if err := s.compiled.Validate(dataMap); err != nil {
+		return err
+	}
+
+	if success, _ := dataMap["success"].(bool); !success {
+		errorMap, ok := dataMap["error"].(map[string]any)
+		if !ok {
+			return fmt.Errorf("error field is required when success is false")
+		}
+		if _, ok := errorMap["message"].(string); !ok {
+			return fmt.Errorf("error.message must be a string")
+		}
+	}
+	return nil
+}
+
+// Transform returns the result map unchanged
+func (s *customResultSchema) Transform(data any) (any, error) {
+	return data.(map[string]any), nil
+}
@@ -0,0 +1,119 @@
+package phase
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestCustomResultSchema(t *testing.T) {
+	if _, err := NewCustomResultSchema(json.RawMessage(`{"type": "object", "properties": {"ok": {"type": "boolean"}}}`)); err == nil {
+		t.Error("Expected a schema without a required success property to be rejected")
+	}
+
+	schema, err := NewCustomResultSchema(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"success": {"type": "boolean"},
+			"findings": {"type": "array", "items": {"type": "string"}},
+			"error": {"type": "object"}
+		},
+		"required": ["success", "findings"]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"valid", `{"success": true, "findings": ["unchecked input"]}`, ""},
+		{"missing field", `{"success": true}`, "findings"},
+		{"wrong item type", `{"success": true, "findings": [1]}`, "findings"},
+		{"failure without error", `{"success": false, "findings": []}`, "error field is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]any
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatalf("Invalid test data: %v", err)
+			}
+			err := schema.Validate(data)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid result, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewCustomPhaseTools(t *testing.T) {
+	spec := CustomPhaseSpec{Name: "Security Review", SystemPrompt: "Review", Tools: []string{"check_code", "search"}}
+	p, err := NewCustomPhase(spec, t.TempDir(), nil, Options{})
+	if err != nil {
+		t.Fatalf("Failed to create phase: %v", err)
+	}
+
+	var names []string
+	for _, tool := range p.Tools() {
+		names = append(names, tool.Name())
+	}
+	if got := strings.Join(names, ","); got != "check_code,search,result,result_append" {
+		t.Errorf("Expected requested tools followed by the result tools, got %s", got)
+	}
+
+	// run_snippet is only offered when the run enabled it
+	spec.Tools = []string{"run_snippet"}
+	for _, enabled := range []bool{false, true} {
+		p, err := NewCustomPhase(spec, t.TempDir(), nil, Options{RunSnippet: enabled})
+		if err != nil {
+			t.Fatalf("Failed to create phase: %v", err)
+		}
+		if got := len(p.Tools()) == 3; got != enabled {
+			t.Errorf("Expected run_snippet offered %v, got %d tools", enabled, len(p.Tools()))
+		}
+	}
+
+	spec.Tools = []string{"write_file"}
+	if _, err := NewCustomPhase(spec, t.TempDir(), nil, Options{}); err == nil {
+		t.Error("Expected an unknown tool to be rejected")
+	}
+}
+
+func TestTargetToolsDirective(t *testing.T) {
+	p, err := NewCustomPhase(CustomPhaseSpec{Name: "audit", SystemPrompt: "Audit the code.", Tools: []string{"check_code", "inspect", "search"}}, t.TempDir(), nil, Options{})
+	if err != nil {
+		t.Fatalf("Failed to create phase: %v", err)
+	}
+
+	names := func(target *parser.Target) ([]string, string) {
+		phaseTools, systemPrompt := targetTools(p, target, false)
+		var names []string
+		for _, tool := range phaseTools {
+			names = append(names, tool.Name())
+		}
+		return names, systemPrompt
+	}
+
+	all, systemPrompt := names(&parser.Target{Name: "Hash"})
+	if strings.Join(all, ",") != "check_code,inspect,search,result,result_append" || systemPrompt != p.SystemPrompt() {
+		t.Errorf("Expected every tool without a directive, got %v", all)
+	}
+
+	restricted, systemPrompt := names(&parser.Target{Name: "Hash", Tools: []string{"inspect"}})
+	if strings.Join(restricted, ",") != "inspect,result,result_append" {
+		t.Errorf("Expected inspect and the result tools, got %v", restricted)
+	}
+	if !strings.Contains(systemPrompt, "Only these tools are available for this function: inspect, result, result_append.") {
+		t.Errorf("Expected the system prompt to list the available tools, got:\n%s", systemPrompt)
+	}
+}
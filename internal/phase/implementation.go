@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 
+	"github.com/rail44/mantra/internal/parser"
 	"github.com/rail44/mantra/internal/prompt"
 	"github.com/rail44/mantra/internal/tools"
 	"github.com/rail44/mantra/internal/tools/impl"
@@ -22,10 +24,16 @@ type ImplementationPhase struct {
 	completed   bool
 	mu          sync.Mutex
 	schema      schemas.ResultSchema
+	resultTool  *impl.ResultTool
+	checkCode   *impl.CheckCodeTool
+	runSnippet  bool     // Whether the run_snippet tool is available
+	forbidden   []string // Import paths generated code must not use
+	goVersion   string   // Go release generated code must compile with (empty: any)
+	helpers     bool     // Whether private helper functions may be generated
 }
 
 // NewImplementationPhase creates a new implementation phase
-func NewImplementationPhase(temperature float32, projectRoot string, logger *slog.Logger) *ImplementationPhase {
+func NewImplementationPhase(temperature float32, projectRoot string, logger *slog.Logger, opts Options) *ImplementationPhase {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -34,17 +42,24 @@ func NewImplementationPhase(temperature float32, projectRoot string, logger *slo
 		temperature: temperature,
 		projectRoot: projectRoot,
 		logger:      logger,
-		schema:      &implementationResultSchema{},
+		schema:      &implementationResultSchema{helpers: opts.Helpers},
+		runSnippet:  opts.RunSnippet,
+		forbidden:   opts.ForbiddenImports,
+		goVersion:   opts.GoVersion,
+		helpers:     opts.Helpers,
 	}
+	phase.resultTool = impl.NewResultTool("implementation", phase.schema, phase.storeResult)
+	phase.checkCode = newCheckCodeTool(projectRoot, opts).WithHelpers(opts.Helpers)
 
 	// Initialize tools for implementation/validation
 	tools := []tools.Tool{
-		impl.NewCheckCodeTool(projectRoot),
-		impl.NewResultTool(
-			"implementation",
-			phase.schema,
-			phase.storeResult,
-		),
+		phase.checkCode,
+		phase.resultTool,
+		phase.resultTool.AppendTool(),
+	}
+
+	if opts.RunSnippet {
+		tools = append(tools, impl.NewRunSnippetTool(opts.RunSnippetTimeout))
 	}
 
 	phase.tools = tools
@@ -77,10 +92,57 @@ func (p *ImplementationPhase) Tools() []tools.Tool {
 
 // SystemPrompt returns the system prompt for implementation
 func (p *ImplementationPhase) SystemPrompt() string {
-	return `You are an expert Go developer. Your task: generate ONLY the code that replaces <IMPLEMENT_HERE>.
+	text := implementationSystemPrompt
+	if p.runSnippet {
+		text = strings.Replace(text, checkCodeToolLine, checkCodeToolLine+runSnippetToolLine, 1)
+		text = strings.Replace(text, checkCodeStep, checkCodeStep+runSnippetStep, 1)
+	}
+	if len(p.forbidden) > 0 {
+		text += "\n\n## Forbidden Packages\n\nNever use these packages (\"path/...\" includes subpackages):\n"
+		for _, path := range p.forbidden {
+			text += "- " + path + "\n"
+		}
+	}
+	if p.goVersion != "" {
+		text += goVersionSection(p.goVersion)
+	}
+	if p.helpers {
+		text += helpersSection
+	}
+	return text
+}
+
+// helpersSection lets the model split the implementation into private helper functions
+const helpersSection = `
+
+## Helper Functions
+
+You may move parts of the implementation into private helper functions. Pass them as "helpers" to both check_code and result(), each with:
+- name: an unexported name that does not collide with declarations in the package
+- signature: parameters and results, e.g. "(line string) (key, value string)"
+- body: the statements inside the helper
+Helpers are written below the target. Only add them when they make the body clearly simpler.
+A helper whose name is already taken in the package is renamed with a numeric suffix, e.g. isVowel2, and its calls are updated; check_code lists such renames in "renamed_helpers".
+`
+
+// goVersionSection tells the model which Go release the code must compile with
+func goVersionSection(version string) string {
+	return fmt.Sprintf("\n\n## Go Version\n\nThe project targets Go %s. Use only language features and standard library APIs available in Go %s; check_code rejects anything newer.\n", version, version)
+}
+
+const (
+	checkCodeToolLine  = "- check_code(): Validate your code syntax, structure and gofmt formatting\n"
+	runSnippetToolLine = "- run_snippet(): Run your code with example calls taken from <instruction> to catch runtime panics\n"
+	checkCodeStep      = "3. Validate your implementation with check_code tool\n"
+	runSnippetStep     = "   - If <instruction> gives example inputs/outputs, run them with run_snippet and compare the results\n"
+)
+
+// implementationSystemPrompt is the base system prompt for implementation
+const implementationSystemPrompt = `You are an expert Go developer. Your task: generate ONLY the code that replaces <IMPLEMENT_HERE>.
 
 ## Input Structure
 - <target>: The function signature to implement
+- <constructor>: Present for constructors (NewX); fields to wire and idioms to follow
 - <context>: Initial context from function signature
 	- Receiver and parameter type definitions
 	- Implemented methods for each type (excluding the method being implemented)
@@ -91,8 +153,9 @@ func (p *ImplementationPhase) SystemPrompt() string {
 
 ## Available Tool
 
-- check_code(): Validate your code syntax and structure
+- check_code(): Validate your code syntax, structure and gofmt formatting
 - result(): Submit the final result and complete this phase
+- result_append(): Send part of a long function body before calling result()
 
 ## Process
 
@@ -113,6 +176,9 @@ Call result() with JSON containing:
   "code": "..."  // Your generated function body
 }
 
+If the function body is too long for a single call, send it in parts with result_append({"index": 0, "chunk": "..."})
+(index 0, 1, 2, ...) and then call result() with the remaining code; parts and "code" are joined without separators.
+
 ### For failures:
 {
   "success": false,
@@ -127,7 +193,6 @@ Call result() with JSON containing:
 - ALWAYS call the result() tool to complete the phase
 - Use success: false when you cannot gather enough context
 - Provide clear error messages to help diagnose issues`
-}
 
 // PromptBuilder returns a prompt builder configured for implementation
 func (p *ImplementationPhase) PromptBuilder() *prompt.Builder {
@@ -153,12 +218,19 @@ func (p *ImplementationPhase) Result() (any, bool) {
 	return p.result, p.completed
 }
 
+// LastAttempt returns the last code passed to check_code, the model's latest attempt when the
+// phase fails (empty if check_code was never called)
+func (p *ImplementationPhase) LastAttempt() string {
+	return p.checkCode.LastCode()
+}
+
 // Reset clears the phase state for reuse
 func (p *ImplementationPhase) Reset() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.result = nil
 	p.completed = false
+	p.resultTool.Reset()
 }
 
 // ResultSchema returns the schema for this phase's result tool
@@ -167,10 +239,17 @@ func (p *ImplementationPhase) ResultSchema() schemas.ResultSchema {
 }
 
 // implementationResultSchema defines the schema for implementation phase results
-type implementationResultSchema struct{}
+type implementationResultSchema struct {
+	helpers bool // Whether results may carry private helper functions
+}
 
 // Schema returns the JSON schema for implementation results
 func (s *implementationResultSchema) Schema() json.RawMessage {
+	helpers := ""
+	if s.helpers {
+		helpers = `,
+			"helpers": ` + impl.HelpersSchema
+	}
 	return json.RawMessage(`{
 		"type": "object",
 		"properties": {
@@ -196,7 +275,7 @@ func (s *implementationResultSchema) Schema() json.RawMessage {
 				},
 				"required": ["message"],
 				"additionalProperties": false
-			}
+			}` + helpers + `
 		},
 		"required": ["success"],
 		"additionalProperties": false
@@ -257,6 +336,15 @@ func (s *implementationResultSchema) Validate(data any) error {
 		return fmt.Errorf("code cannot be empty")
 	}
 
+	if helpers, ok := dataMap["helpers"]; ok {
+		if !s.helpers {
+			return fmt.Errorf("helpers are not enabled")
+		}
+		if _, err := parser.HelpersFromJSON(helpers); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,92 @@
+package phase
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// missingIdentifierPatterns match compiler and model phrasings of unresolved names
+var missingIdentifierPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`undefined: ([\w.]+)`),
+	regexp.MustCompile(`undeclared name: (\w+)`),
+	regexp.MustCompile(`has no field or method (\w+)`),
+	regexp.MustCompile("(?i)unknown (?:identifier|type|function|method|field)s?:? [`'\"]?([\\w.]+)"),
+	regexp.MustCompile("(?i)(?:cannot|could not|couldn't) (?:find|resolve) (?:the )?(?:identifier|type|function|method|field)?:? ?[`'\"]([\\w.]+)"),
+}
+
+// MissingIdentifiers returns the identifiers an implementation failure reports as unresolved,
+// in order of appearance. Other failures return nil
+func MissingIdentifiers(reason *parser.FailureReason) []string {
+	if reason == nil || reason.Phase != "implementation" {
+		return nil
+	}
+
+	text := reason.Message + "\n" + reason.Context
+	var identifiers []string
+	for _, pattern := range missingIdentifierPatterns {
+		for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			name := strings.Trim(text[match[2]:match[3]], ".")
+			if name != "" && !slices.Contains(identifiers, name) {
+				identifiers = append(identifiers, name)
+			}
+		}
+	}
+	return identifiers
+}
+
+// missingIdentifiersContext asks context gathering to focus on unresolved identifiers
+func missingIdentifiersContext(identifiers []string) string {
+	var b strings.Builder
+	b.WriteString("## Missing Identifiers\n")
+	b.WriteString("A previous implementation attempt failed because these identifiers could not be resolved. ")
+	b.WriteString("Find their definitions, or the correct names if they do not exist as written:\n")
+	for _, identifier := range identifiers {
+		b.WriteString("- " + identifier + "\n")
+	}
+	return b.String()
+}
+
+// MergeContextResults combines two context gathering results, appending the discovered
+// types, functions and constants of extra to those of base. Items of extra named like one
+// of base are left out
+func MergeContextResults(base, extra map[string]any) map[string]any {
+	if base == nil {
+		return extra
+	}
+	if extra == nil {
+		return base
+	}
+
+	merged := make(map[string]any, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for _, key := range []string{"types", "functions", "constants"} {
+		items, _ := base[key].([]any)
+		names := make(map[string]bool, len(items))
+		for _, item := range items {
+			names[itemName(item)] = true
+		}
+		more, _ := extra[key].([]any)
+		combined := slices.Clip(items)
+		for _, item := range more {
+			if name := itemName(item); name == "" || !names[name] {
+				combined = append(combined, item)
+			}
+		}
+		if len(combined) > len(items) {
+			merged[key] = combined
+		}
+	}
+	return merged
+}
+
+// itemName returns the name of a discovered type, function or constant ("" if unnamed)
+func itemName(item any) string {
+	m, _ := item.(map[string]any)
+	name, _ := m["name"].(string)
+	return name
+}
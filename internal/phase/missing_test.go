@@ -0,0 +1,76 @@
+package phase
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestMissingIdentifiers(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason *parser.FailureReason
+		want   []string
+	}{
+		{
+			name: "compiler errors",
+			reason: &parser.FailureReason{
+				Phase:   "implementation",
+				Message: "check_code keeps failing",
+				Context: "undefined: store.ErrNotFound; c.items.Len undefined (type []Item has no field or method Len); undefined: store.ErrNotFound",
+			},
+			want: []string{"store.ErrNotFound", "Len"},
+		},
+		{
+			name:   "model phrasing",
+			reason: &parser.FailureReason{Phase: "implementation", Message: "Unknown type `OrderStatus` in context"},
+			want:   []string{"OrderStatus"},
+		},
+		{
+			name:   "other phase",
+			reason: &parser.FailureReason{Phase: "context_gathering", Message: "undefined: Foo"},
+		},
+		{
+			name:   "unrelated failure",
+			reason: &parser.FailureReason{Phase: "implementation", Message: "Instruction is ambiguous"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MissingIdentifiers(tt.reason); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeContextResults(t *testing.T) {
+	base := map[string]any{
+		"success": true,
+		"types":   []any{map[string]any{"name": "Order"}},
+	}
+	extra := map[string]any{
+		"success":   true,
+		"types":     []any{map[string]any{"name": "OrderStatus"}},
+		"functions": []any{map[string]any{"name": "Lookup"}},
+	}
+
+	merged := MergeContextResults(base, extra)
+	if types := merged["types"].([]any); len(types) != 2 {
+		t.Errorf("Expected 2 types, got %v", types)
+	}
+	if functions := merged["functions"].([]any); len(functions) != 1 {
+		t.Errorf("Expected 1 function, got %v", functions)
+	}
+	if types := base["types"].([]any); len(types) != 1 {
+		t.Errorf("Expected base to be unchanged, got %v", types)
+	}
+	if types := MergeContextResults(merged, extra)["types"].([]any); len(types) != 2 {
+		t.Errorf("Expected types already in base to be left out, got %v", types)
+	}
+	if MergeContextResults(nil, extra)["functions"] == nil {
+		t.Error("Expected extra to be used when base is nil")
+	}
+}
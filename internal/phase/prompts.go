@@ -0,0 +1,117 @@
+package phase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/rail44/mantra/internal/llm"
+	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/ratelimit"
+)
+
+// Prompt is the first request a phase would send
+type Prompt struct {
+	Phase  string
+	System string
+	User   string
+	Tools  []string // Names of the tools offered to the model
+	Tokens int      // Estimated size of the system prompt, user prompt and tool definitions
+}
+
+// PromptOptions selects the phases Prompts includes
+type PromptOptions struct {
+	DestDir       string
+	ProjectRoot   string
+	GatherContext bool // Include context gathering (skipped for fast targets)
+	Review        bool // Include the self-review phase
+}
+
+// Prompts builds the prompts the target's phases would send, without calling the provider,
+// and estimates their size. Review and custom phases are built with an empty implementation
+func (r *Runner) Prompts(ctx context.Context, target *parser.Target, fileContent string, opts PromptOptions) ([]Prompt, error) {
+	var prompts []Prompt
+	projectRoot := opts.ProjectRoot
+
+	if opts.GatherContext {
+		packagePath := opts.DestDir
+		if packagePath == "" {
+			packagePath = filepath.Dir(target.FilePath)
+		}
+		contextPhase := NewContextGatheringPhase(0.6, packagePath, r.logger, r.options.Overlay)
+		builder := contextPhase.PromptBuilder().WithFileContext(r.fileContext).WithTemplates(r.options.Templates)
+		if r.dependencyContext != "" {
+			builder.WithAdditionalContext(r.dependencyContext)
+		}
+		userPrompt, err := builder.BuildForTarget(ctx, target, fileContent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build context gathering prompt: %w", err)
+		}
+		prompts = append(prompts, r.newPrompt(contextPhase, target, userPrompt))
+	}
+
+	implPhase := NewImplementationPhase(r.implTemperature, projectRoot, r.logger, r.options)
+	contextMarkdown := r.packageContext
+	if r.dependencyContext != "" {
+		contextMarkdown += "\n" + r.dependencyContext
+	}
+	if r.previousFailure != nil {
+		contextMarkdown += "\n" + formatPreviousFailure(r.previousFailure)
+	}
+	userPrompt, err := implPhase.PromptBuilderWithContext(contextMarkdown).
+		WithFileContext(r.fileContext).
+		WithTemplates(r.options.Templates).
+		BuildForTarget(ctx, target, fileContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build implementation prompt: %w", err)
+	}
+	prompts = append(prompts, r.newPrompt(implPhase, target, userPrompt))
+
+	if opts.Review {
+		reviewPhase := NewReviewPhase(0.2, projectRoot, r.logger, r.options)
+		userPrompt, err := reviewPhase.PromptBuilderWithImplementation(target.GetFunctionSignature(), "").
+			WithFileContext(r.fileContext).
+			WithTemplates(r.options.Templates).
+			BuildForTarget(ctx, target, fileContent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build review prompt: %w", err)
+		}
+		prompts = append(prompts, r.newPrompt(reviewPhase, target, userPrompt))
+	}
+
+	for _, spec := range r.options.CustomPhases {
+		customPhase, err := NewCustomPhase(spec, projectRoot, r.logger, r.options)
+		if err != nil {
+			return nil, err
+		}
+		userPrompt, err := customPhase.PromptBuilderWithImplementation(target.GetFunctionSignature(), "").
+			WithFileContext(r.fileContext).
+			WithTemplates(r.options.Templates).
+			BuildForTarget(ctx, target, fileContent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s prompt: %w", spec.Name, err)
+		}
+		prompts = append(prompts, r.newPrompt(customPhase, target, userPrompt))
+	}
+
+	return prompts, nil
+}
+
+// newPrompt describes a phase's first request and estimates its tokens
+func (r *Runner) newPrompt(p Phase, target *parser.Target, userPrompt string) Prompt {
+	phaseTools, systemPrompt := targetTools(p, target, r.noPanic(target))
+	if _, testCode := p.(*TestFuncPhase); !testCode {
+		systemPrompt += r.errorStyleSection(target)
+	}
+	prompt := Prompt{Phase: p.Name(), System: systemPrompt, User: userPrompt}
+	for _, tool := range phaseTools {
+		prompt.Tools = append(prompt.Tools, tool.Name())
+	}
+	payload := len(prompt.System) + len(userPrompt)
+	if definitions, err := json.Marshal(llm.ConvertToAITools(phaseTools)); err == nil {
+		payload += len(definitions)
+	}
+	prompt.Tokens = ratelimit.EstimateTokens(payload)
+	return prompt
+}
@@ -0,0 +1,290 @@
+package phase
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/rail44/mantra/internal/prompt"
+	"github.com/rail44/mantra/internal/tools"
+	"github.com/rail44/mantra/internal/tools/impl"
+	"github.com/rail44/mantra/internal/tools/schemas"
+)
+
+// DefaultReviewRubric lists what the self-review phase checks an implementation against
+var DefaultReviewRubric = []string{
+	"Every requirement in <instruction> is implemented, including edge cases it mentions",
+	"Inputs that can be nil, empty, zero or out of range are handled without panicking",
+	"Errors are checked and returned (wrapped with context) rather than ignored",
+	"The function behaves correctly for concurrent callers when the receiver holds shared state",
+	"The code uses only identifiers that exist in <context> or the package",
+}
+
+// ReviewPhase represents the phase where AI critiques an accepted implementation
+type ReviewPhase struct {
+	temperature float32
+	tools       []tools.Tool
+	logger      *slog.Logger
+	result      any
+	completed   bool
+	mu          sync.Mutex
+	schema      schemas.ResultSchema
+	resultTool  *impl.ResultTool
+	rubric      []string
+	goVersion   string // Go release corrections must compile with (empty: any)
+}
+
+// NewReviewPhase creates a new self-review phase
+func NewReviewPhase(temperature float32, projectRoot string, logger *slog.Logger, opts Options) *ReviewPhase {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	rubric := opts.ReviewRubric
+	if len(rubric) == 0 {
+		rubric = DefaultReviewRubric
+	}
+
+	phase := &ReviewPhase{
+		temperature: temperature,
+		logger:      logger,
+		schema:      &reviewResultSchema{},
+		rubric:      rubric,
+		goVersion:   opts.GoVersion,
+	}
+	phase.resultTool = impl.NewResultTool("review", phase.schema, phase.storeResult)
+
+	// Corrections are validated with the same checks as the implementation phase
+	phase.tools = []tools.Tool{
+		newCheckCodeTool(projectRoot, opts),
+		phase.resultTool,
+		phase.resultTool.AppendTool(),
+	}
+
+	return phase
+}
+
+// storeResult stores the result from the result tool
+func (p *ReviewPhase) storeResult(result any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result = result
+	p.completed = true
+	return nil
+}
+
+// Name returns the name of this phase
+func (p *ReviewPhase) Name() string {
+	return PhaseReview
+}
+
+// Temperature returns the temperature for review
+func (p *ReviewPhase) Temperature() float32 {
+	return p.temperature
+}
+
+// Tools returns the review tools
+func (p *ReviewPhase) Tools() []tools.Tool {
+	return p.tools
+}
+
+// SystemPrompt returns the system prompt for review, including the rubric
+func (p *ReviewPhase) SystemPrompt() string {
+	var rubric strings.Builder
+	for i, item := range p.rubric {
+		rubric.WriteString(fmt.Sprintf("%d. %s\n", i+1, item))
+	}
+	text := strings.Replace(reviewSystemPrompt, "{{RUBRIC}}", rubric.String(), 1)
+	if p.goVersion != "" {
+		text += goVersionSection(p.goVersion)
+	}
+	return text
+}
+
+// reviewSystemPrompt is the system prompt for review; {{RUBRIC}} is replaced with the rubric items
+const reviewSystemPrompt = `You are a senior Go reviewer. Your task: review the implementation of <target> written by another developer and either approve it or return a corrected function body.
+
+## Input Structure
+- <target>: The function signature that was implemented
+- <context>: Types and packages available to the implementation
+- <instruction>: Natural language description of what the function should do
+- <additional_context>: The implementation under review
+
+## Rubric
+
+{{RUBRIC}}
+## Available Tool
+
+- check_code(): Validate a corrected function body
+- result(): Submit your verdict and complete this phase
+- result_append(): Send part of a long corrected body before calling result()
+
+## Process
+
+1. Check the implementation against every rubric item
+2. If it satisfies all of them, approve it unchanged
+3. Otherwise, write a corrected function body and validate it with check_code
+4. Call the result() tool
+
+## Result Tool Usage
+
+Call result() with JSON containing:
+
+### To approve:
+
+{
+  "success": true,
+  "approved": true,
+  "comments": "..."  // Optional short summary
+}
+
+### To correct:
+
+{
+  "success": true,
+  "approved": false,
+  "code": "...",     // The complete corrected function body
+  "comments": "..."  // Which rubric items were violated and how they were fixed
+}
+
+A corrected body too long for a single call can be sent in parts with result_append({"index": 0, "chunk": "..."}),
+followed by result() with the remaining code.
+
+### If you cannot review:
+{
+  "success": false,
+  "error": {
+    "message": "Brief description of what prevented the review",
+    "details": "Specific details"
+  }
+}
+
+## Important
+
+- ALWAYS call the result() tool to complete the phase
+- Only correct real defects; do not rewrite working code for style
+- A corrected body must pass check_code`
+
+// PromptBuilderWithImplementation returns a prompt builder that presents the implementation under review
+func (p *ReviewPhase) PromptBuilderWithImplementation(signature, code string) *prompt.Builder {
+	builder := p.PromptBuilder()
+	return builder.WithAdditionalContext("## Implementation Under Review\n```go\n" + signature + " {\n" + code + "\n}\n```")
+}
+
+// PromptBuilder returns a prompt builder configured for review
+func (p *ReviewPhase) PromptBuilder() *prompt.Builder {
+	builder := prompt.NewBuilder(p.logger)
+	builder.SetUseTools(true)
+	return builder
+}
+
+// Result returns the phase result and whether it's complete
+func (p *ReviewPhase) Result() (any, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.result, p.completed
+}
+
+// Reset clears the phase state for reuse
+func (p *ReviewPhase) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result = nil
+	p.completed = false
+	p.resultTool.Reset()
+}
+
+// ResultSchema returns the schema for this phase's result tool
+func (p *ReviewPhase) ResultSchema() schemas.ResultSchema {
+	return p.schema
+}
+
+// reviewResultSchema defines the schema for review phase results
+type reviewResultSchema struct{}
+
+// Schema returns the JSON schema for review results
+func (s *reviewResultSchema) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"success": {
+				"type": "boolean",
+				"description": "Whether the review was completed"
+			},
+			"approved": {
+				"type": "boolean",
+				"description": "Whether the implementation is approved unchanged"
+			},
+			"code": {
+				"type": "string",
+				"description": "The corrected function body (required when approved is false)"
+			},
+			"comments": {
+				"type": "string",
+				"description": "Short summary of the review"
+			},
+			"error": {
+				"type": "object",
+				"properties": {
+					"message": {
+						"type": "string",
+						"description": "Error message explaining what went wrong"
+					},
+					"details": {
+						"type": "string",
+						"description": "Additional details about what was missing or failed"
+					}
+				},
+				"required": ["message"],
+				"additionalProperties": false
+			}
+		},
+		"required": ["success"],
+		"additionalProperties": false
+	}`)
+}
+
+// Validate checks if the data conforms to the review schema
+func (s *reviewResultSchema) Validate(data any) error {
+	dataMap, ok := data.(map[string]any)
+	if !ok {
+		return fmt.Errorf("expected object, got %T", data)
+	}
+
+	success, ok := dataMap["success"].(bool)
+	if !ok {
+		return fmt.Errorf("success must be a boolean")
+	}
+
+	if !success {
+		errorMap, ok := dataMap["error"].(map[string]any)
+		if !ok {
+			return fmt.Errorf("error field is required when success is false")
+		}
+		if _, ok := errorMap["message"].(string); !ok {
+			return fmt.Errorf("error.message must be a string")
+		}
+		return nil
+	}
+
+	approved, ok := dataMap["approved"].(bool)
+	if !ok {
+		return fmt.Errorf("approved must be a boolean when success is true")
+	}
+
+	if !approved {
+		code, ok := dataMap["code"].(string)
+		if !ok || strings.TrimSpace(code) == "" {
+			return fmt.Errorf("code is required when approved is false")
+		}
+	}
+
+	return nil
+}
+
+// Transform returns the result map unchanged
+func (s *reviewResultSchema) Transform(data any) (any, error) {
+	return data.(map[string]any), nil
+}
@@ -2,16 +2,32 @@ package phase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"log/slog"
 
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/codegen"
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/conventions"
 	"github.com/rail44/mantra/internal/formatter"
+	"github.com/rail44/mantra/internal/imports"
 	"github.com/rail44/mantra/internal/llm"
 	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/prompt"
+	"github.com/rail44/mantra/internal/provenance"
 	"github.com/rail44/mantra/internal/tools"
+	"github.com/rail44/mantra/internal/tools/impl"
+	"golang.org/x/tools/go/packages"
 )
 
 // TargetEvent represents a target execution event with phase information
@@ -21,23 +37,153 @@ type TargetEvent struct {
 	Time        time.Time
 }
 
+// Options controls optional phase behavior
+type Options struct {
+	Conventions       *conventions.Conventions // Project conventions enforced by check_code (nil disables)
+	ForbiddenImports  []string                 // Import paths generated code must not use
+	DestImportPath    string                   // Import path of the package generated code is written to (empty: unchecked)
+	DestDir           string                   // Directory of the package generated code is written to, for helper names
+	GoVersion         string                   // Go release generated code must compile with, e.g. "1.21" (empty: any)
+	NoPanic           bool                     // Reject generated code that calls panic, for every target
+	Provenance        *provenance.Scanner      // Scanner for verbatim copies of known sources (nil disables)
+	RunSnippet        bool                     // Enable the run_snippet tool in the implementation phase and custom phases listing it
+	RunSnippetTimeout time.Duration            // Timeout for a single run_snippet invocation (0 uses the default)
+	Templates         *prompt.Templates        // Prompt templates (nil uses the built-in ones)
+	ReviewRubric      []string                 // Self-review rubric (nil uses DefaultReviewRubric)
+	CustomPhases      []CustomPhaseSpec        // User-defined phases run in order after implementation
+	Overlay           map[string][]byte        // Stubs of generated files not yet written to dest, by absolute path
+	CheckMaxIssues    int                      // Issues after which check_code stops running analyzers (0: no limit)
+	Helpers           bool                     // Let the implementation phase generate private helper functions
+	MaxRounds         map[string]int           // Rounds of tool calls by built-in phase name (missing or 0: llm.DefaultMaxRounds)
+}
+
 // Runner handles phase execution
 type Runner struct {
-	client      *llm.Client
-	logger      *slog.Logger
-	phaseLogger *slog.Logger // Current phase-aware logger
+	client            *llm.Client
+	logger            *slog.Logger
+	phaseLogger       *slog.Logger // Current phase-aware logger
+	options           Options
+	dependencyContext string                // Implementations of targets referenced by the instruction
+	cachedContext     string                // Context gathered for other methods of the receiver type
+	siblingContext    string                // Accepted methods of the receiver type generated earlier in the run
+	previousFailure   *parser.FailureReason // Failure of the target's last generation
+	packageContext    string                // Static package context used in place of context gathering
+	fileContext       *pkgcontext.FileContext
+	implTemperature   float32         // Implementation phase temperature
+	helpers           []parser.Helper // Helpers of the last accepted implementation
+	toolsUnsupported  *atomic.Bool    // The model answered with code instead of calling tools; shared with forks
 }
 
+// defaultImplementationTemperature is the implementation temperature used unless overridden
+const defaultImplementationTemperature = 0.2
+
 // NewRunner creates a new phase runner
 func NewRunner(client *llm.Client, logger *slog.Logger) *Runner {
+	return NewRunnerWithOptions(client, logger, Options{})
+}
+
+// NewRunnerWithOptions creates a new phase runner with optional phase behavior
+func NewRunnerWithOptions(client *llm.Client, logger *slog.Logger, opts Options) *Runner {
 	return &Runner{
-		client: client,
-		logger: logger,
+		client:           client,
+		logger:           logger,
+		options:          opts,
+		implTemperature:  defaultImplementationTemperature,
+		toolsUnsupported: &atomic.Bool{},
 	}
 }
 
+// Fork returns a runner with the same settings that generates with a different client and logger
+func (r *Runner) Fork(client *llm.Client, logger *slog.Logger) *Runner {
+	fork := *r
+	fork.client = client
+	fork.logger = logger
+	fork.phaseLogger = nil
+	fork.helpers = nil
+	return &fork
+}
+
+// SetImplementationTemperature overrides the temperature of the implementation phase
+func (r *Runner) SetImplementationTemperature(temperature float32) {
+	r.implTemperature = temperature
+}
+
+// SetDependencyContext sets implementations of other targets to include in both phases' prompts
+func (r *Runner) SetDependencyContext(dependencyContext string) {
+	r.dependencyContext = dependencyContext
+}
+
+// SetCachedContext sets the context gathered for other methods of the target's receiver
+// type, so context gathering only explores what the target needs beyond it
+func (r *Runner) SetCachedContext(receiver string, result map[string]any) {
+	r.cachedContext = ""
+	if markdown := formatter.FormatContextAsMarkdown(result); markdown != "" {
+		r.cachedContext = fmt.Sprintf("## Context Already Gathered for Other Methods of %s\n%s\nThis context is up to date. Only look up what this function needs beyond it.\n", receiver, markdown)
+	}
+}
+
+// SetSiblingContext sets the accepted methods of the target's receiver type to include in
+// the implementation phase's prompt
+func (r *Runner) SetSiblingContext(siblingContext string) {
+	r.siblingContext = siblingContext
+}
+
+// SetPreviousFailure sets the failure recorded by the target's last generation, shown to the
+// implementation phase together with the rejected attempt
+func (r *Runner) SetPreviousFailure(reason *parser.FailureReason) {
+	r.previousFailure = reason
+}
+
+// SetPackageContext sets static package context for the implementation phase,
+// used when context gathering is skipped
+func (r *Runner) SetPackageContext(packageContext string) {
+	r.packageContext = packageContext
+}
+
+// SetFileContext sets the static context shared by all targets in the target's file.
+// When unset, each phase extracts the context itself
+func (r *Runner) SetFileContext(fc *pkgcontext.FileContext) {
+	r.fileContext = fc
+}
+
+// ToolsUnsupported reports whether a phase failed because the model answered with code
+// instead of calling tools. ExecuteSingleShot generates without them
+func (r *Runner) ToolsUnsupported() bool {
+	return r.toolsUnsupported.Load()
+}
+
+// Helpers returns the helper functions generated with the last accepted implementation
+func (r *Runner) Helpers() []parser.Helper {
+	return r.helpers
+}
+
+// SetHelpers sets the helper functions of the accepted implementation, as when it was
+// generated by another runner
+func (r *Runner) SetHelpers(helpers []parser.Helper) {
+	r.helpers = helpers
+}
+
+// toolContext creates the context of a phase's tools, declaring the helpers of the
+// accepted implementation next to the target
+func (r *Runner) toolContext(fileInfo *parser.FileInfo, target *parser.Target, projectRoot string) *tools.Context {
+	toolContext := tools.NewContext(fileInfo, target, projectRoot)
+	toolContext.Helpers = r.helpers
+	return toolContext
+}
+
 // ExecuteContextGathering executes the context gathering phase
 func (r *Runner) ExecuteContextGathering(ctx context.Context, target *parser.Target, fileContent string, destDir string) (map[string]any, *parser.FailureReason) {
+	return r.executeContextGathering(ctx, target, fileContent, destDir, "")
+}
+
+// ExecuteFocusedContextGathering runs another context gathering round that looks up
+// identifiers a failed implementation attempt could not resolve
+func (r *Runner) ExecuteFocusedContextGathering(ctx context.Context, target *parser.Target, fileContent string, destDir string, identifiers []string) (map[string]any, *parser.FailureReason) {
+	return r.executeContextGathering(ctx, target, fileContent, destDir, missingIdentifiersContext(identifiers))
+}
+
+// executeContextGathering executes the context gathering phase, with focus added to the prompt when set
+func (r *Runner) executeContextGathering(ctx context.Context, target *parser.Target, fileContent string, destDir string, focus string) (map[string]any, *parser.FailureReason) {
 	// Context is passed through for cancellation
 
 	// Setup phase
@@ -46,7 +192,7 @@ func (r *Runner) ExecuteContextGathering(ctx context.Context, target *parser.Tar
 	if packagePath == "" {
 		packagePath = filepath.Dir(target.FilePath)
 	}
-	contextPhase := NewContextGatheringPhase(0.6, packagePath, r.logger)
+	contextPhase := NewContextGatheringPhase(0.6, packagePath, r.logger, r.options.Overlay)
 	contextPhase.Reset() // Ensure clean state
 
 	// Create tool context
@@ -54,8 +200,18 @@ func (r *Runner) ExecuteContextGathering(ctx context.Context, target *parser.Tar
 	r.configureClientForPhase(contextPhase, toolContext)
 
 	// Build prompt
-	contextPromptBuilder := contextPhase.PromptBuilder()
-	initialPrompt, err := contextPromptBuilder.BuildForTarget(target, fileContent)
+	contextPromptBuilder := contextPhase.PromptBuilder().WithFileContext(r.fileContext).WithTemplates(r.options.Templates)
+	additionalContext := r.dependencyContext
+	if focus == "" && r.cachedContext != "" {
+		additionalContext = strings.TrimPrefix(additionalContext+"\n"+r.cachedContext, "\n")
+	}
+	if focus != "" {
+		additionalContext = strings.TrimPrefix(additionalContext+"\n"+focus, "\n")
+	}
+	if additionalContext != "" {
+		contextPromptBuilder.WithAdditionalContext(additionalContext)
+	}
+	initialPrompt, err := contextPromptBuilder.BuildForTarget(ctx, target, fileContent)
 	if err != nil {
 		r.logger.Error("Failed to build prompt", "error", err.Error())
 		return nil, &parser.FailureReason{
@@ -69,6 +225,7 @@ func (r *Runner) ExecuteContextGathering(ctx context.Context, target *parser.Tar
 	r.phaseLogger.Info("Analyzing...")
 	_, err = r.client.Generate(ctx, initialPrompt)
 	if err != nil {
+		r.noteToolsUnsupported(err)
 		r.logger.Error("Context gathering failed", "error", err.Error())
 		return nil, &parser.FailureReason{
 			Phase:   "context_gathering",
@@ -86,17 +243,37 @@ func (r *Runner) ExecuteImplementation(ctx context.Context, target *parser.Targe
 	// Context is passed through for cancellation
 
 	// Setup phase
-	implPhase := NewImplementationPhase(0.2, projectRoot, r.logger)
+	implPhase := NewImplementationPhase(r.implTemperature, projectRoot, r.logger, r.options)
 	implPhase.Reset() // Ensure clean state
+	r.helpers = nil
 
 	// Create tool context for static analysis
-	toolContext := tools.NewContext(fileInfo, target, projectRoot)
+	toolContext := r.toolContext(fileInfo, target, projectRoot)
 	r.configureClientForPhase(implPhase, toolContext)
 
 	// Build prompt with context
 	contextResultMarkdown := formatter.FormatContextAsMarkdown(contextResult)
-	implPromptBuilder := implPhase.PromptBuilderWithContext(contextResultMarkdown)
-	implPrompt, err := implPromptBuilder.BuildForTarget(target, fileContent)
+	if r.packageContext != "" {
+		if r.client.CachesPrompts() {
+			// Sent ahead of the prompt, where it is part of the prefix shared by all targets
+			r.client.SetSharedContext(r.packageContext)
+		} else {
+			contextResultMarkdown += "\n" + r.packageContext
+		}
+	}
+	if r.dependencyContext != "" {
+		contextResultMarkdown += "\n" + r.dependencyContext
+	}
+	if r.siblingContext != "" {
+		contextResultMarkdown += "\n" + r.siblingContext
+	}
+	if r.previousFailure != nil {
+		contextResultMarkdown += "\n" + formatPreviousFailure(r.previousFailure)
+	}
+	implPromptBuilder := implPhase.PromptBuilderWithContext(contextResultMarkdown).
+		WithFileContext(r.fileContext).
+		WithTemplates(r.options.Templates)
+	implPrompt, err := implPromptBuilder.BuildForTarget(ctx, target, fileContent)
 	if err != nil {
 		r.logger.Error("Failed to build implementation prompt", "error", err.Error())
 		return "", &parser.FailureReason{
@@ -110,23 +287,41 @@ func (r *Runner) ExecuteImplementation(ctx context.Context, target *parser.Targe
 	r.phaseLogger.Info("Generating...")
 	_, err = r.client.Generate(ctx, implPrompt)
 	if err != nil {
+		r.noteToolsUnsupported(err)
 		r.logger.Error("Implementation failed", "error", err.Error())
 		return "", &parser.FailureReason{
 			Phase:   "implementation",
 			Message: "AI implementation generation failed: " + err.Error(),
 			Context: "May be due to complex requirements or AI service issues",
+			Attempt: implPhase.LastAttempt(),
 		}
 	}
 
 	// Process result
 	result, failureReason := r.processResult(implPhase, "implementation")
 	if failureReason != nil {
+		failureReason.Attempt = implPhase.LastAttempt()
 		return "", failureReason
 	}
 
 	// Extract implementation code
 	if result != nil {
 		if code, hasCode := result["code"].(string); hasCode {
+			// Validated by the result schema; names taken in either package are renamed as
+			// check_code reported
+			helpers, _ := parser.HelpersFromJSON(result["helpers"])
+			var renamed map[string]string
+			code, helpers, renamed = codegen.RenameHelpers(code, helpers, target, r.options.DestDir)
+			for old, name := range renamed {
+				r.phaseLogger.Info("Renamed helper", "from", old, "to", name)
+			}
+			// The model validated the code with check_code already
+			scanned := parser.WithHelperCode(code, helpers)
+			if failure := r.checkAccepted(ctx, target, fileInfo, projectRoot, scanned, false); failure != nil {
+				failure.Attempt = code
+				return "", failure
+			}
+			r.helpers = helpers
 			return code, nil
 		}
 		return "", &parser.FailureReason{
@@ -143,6 +338,431 @@ func (r *Runner) ExecuteImplementation(ctx context.Context, target *parser.Targe
 	}
 }
 
+// ExecuteReview executes the self-review phase on an accepted implementation and returns the
+// implementation to keep: the original when approved, or the reviewer's correction.
+// Review problems never fail the target; the original implementation is kept instead
+func (r *Runner) ExecuteReview(ctx context.Context, target *parser.Target, fileContent string, fileInfo *parser.FileInfo, projectRoot string, code string) string {
+	reviewPhase := NewReviewPhase(0.2, projectRoot, r.logger, r.options)
+	reviewPhase.Reset() // Ensure clean state
+
+	toolContext := r.toolContext(fileInfo, target, projectRoot)
+	r.configureClientForPhase(reviewPhase, toolContext)
+
+	reviewPrompt, err := reviewPhase.PromptBuilderWithImplementation(target.GetFunctionSignature(), code).
+		WithFileContext(r.fileContext).
+		WithTemplates(r.options.Templates).
+		BuildForTarget(ctx, target, fileContent)
+	if err != nil {
+		r.phaseLogger.Warn("Failed to build review prompt, keeping implementation", "error", err.Error())
+		return code
+	}
+
+	r.phaseLogger.Info("Reviewing...")
+	if _, err := r.client.Generate(ctx, reviewPrompt); err != nil {
+		r.phaseLogger.Warn("Review failed, keeping implementation", "error", err.Error())
+		return code
+	}
+
+	result, failureReason := r.processResult(reviewPhase, "review")
+	if failureReason != nil {
+		r.phaseLogger.Warn("Review did not complete, keeping implementation", "reason", failureReason.Message)
+		return code
+	}
+
+	comments, _ := result["comments"].(string)
+	if approved, _ := result["approved"].(bool); approved {
+		r.phaseLogger.Info("Implementation approved", "comments", comments)
+		return code
+	}
+
+	corrected := strings.TrimSpace(result["code"].(string))
+	if failure := r.checkAccepted(ctx, target, fileInfo, projectRoot, corrected, true); failure != nil {
+		r.phaseLogger.Warn("Correction rejected, keeping implementation", "reason", failure.Message)
+		return code
+	}
+
+	r.phaseLogger.Info("Implementation corrected by review", "comments", comments)
+	return corrected
+}
+
+// ExecuteCustomPhases runs the configured custom phases in order on an accepted implementation
+// and returns the implementation to keep. A phase that returns "code" replaces the
+// implementation if the revision passes the same checks as the implementation phase.
+// Failures of optional phases keep the current implementation; required phases fail the target
+func (r *Runner) ExecuteCustomPhases(ctx context.Context, target *parser.Target, fileContent string, fileInfo *parser.FileInfo, projectRoot string, code string) (string, *parser.FailureReason) {
+	for _, spec := range r.options.CustomPhases {
+		revised, failure := r.executeCustomPhase(ctx, spec, target, fileContent, fileInfo, projectRoot, code)
+		if failure != nil {
+			if spec.Required {
+				return "", failure
+			}
+			r.logger.Warn(fmt.Sprintf("%s did not complete, keeping implementation", spec.Name), "reason", failure.Message)
+			continue
+		}
+		code = revised
+	}
+	return code, nil
+}
+
+// executeCustomPhase runs a single custom phase and returns the implementation it settles on
+func (r *Runner) executeCustomPhase(ctx context.Context, spec CustomPhaseSpec, target *parser.Target, fileContent string, fileInfo *parser.FileInfo, projectRoot string, code string) (string, *parser.FailureReason) {
+	customPhase, err := NewCustomPhase(spec, projectRoot, r.logger, r.options)
+	if err != nil {
+		return "", &parser.FailureReason{
+			Phase:   spec.Name,
+			Message: err.Error(),
+			Context: "Check the phase definition in mantra.toml",
+		}
+	}
+	customPhase.Reset() // Ensure clean state
+
+	toolContext := r.toolContext(fileInfo, target, projectRoot)
+	r.configureClientForPhase(customPhase, toolContext)
+
+	phasePrompt, err := customPhase.PromptBuilderWithImplementation(target.GetFunctionSignature(), code).
+		WithFileContext(r.fileContext).
+		WithTemplates(r.options.Templates).
+		BuildForTarget(ctx, target, fileContent)
+	if err != nil {
+		return "", &parser.FailureReason{
+			Phase:   spec.Name,
+			Message: fmt.Sprintf("Failed to build %s prompt: %s", spec.Name, err.Error()),
+			Context: "Prompt construction error",
+		}
+	}
+
+	r.phaseLogger.Info("Running...")
+	if _, err := r.client.Generate(ctx, phasePrompt); err != nil {
+		return "", &parser.FailureReason{
+			Phase:   spec.Name,
+			Message: fmt.Sprintf("AI %s failed: %s", spec.Name, err.Error()),
+			Context: "May be due to AI service issues",
+		}
+	}
+
+	result, failureReason := r.processResult(customPhase, spec.Name)
+	if failureReason != nil {
+		return "", failureReason
+	}
+
+	comments, _ := result["comments"].(string)
+	revised, _ := result["code"].(string)
+	revised = strings.TrimSpace(revised)
+	if revised == "" || revised == strings.TrimSpace(code) {
+		r.phaseLogger.Info("Implementation kept", "comments", comments)
+		return code, nil
+	}
+
+	if failure := r.checkAccepted(ctx, target, fileInfo, projectRoot, revised, true); failure != nil {
+		failure.Phase = spec.Name
+		return "", failure
+	}
+
+	r.phaseLogger.Info("Implementation revised", "comments", comments)
+	return revised, nil
+}
+
+// ExecuteTestFunc executes the phase writing a test function of kind for a target marked with
+// its directive and returns the function's body. Test function problems never fail the
+// target; an empty string is returned instead and the target is written without the function
+func (r *Runner) ExecuteTestFunc(ctx context.Context, kind codegen.TestKind, target *parser.Target, fileContent string, fileInfo *parser.FileInfo, projectRoot string, code string) string {
+	testPhase := NewTestFuncPhase(kind, 0.2, projectRoot, code, r.logger, r.options)
+	testPhase.Reset() // Ensure clean state
+
+	toolContext := r.toolContext(fileInfo, target, projectRoot)
+	r.configureClientForPhase(testPhase, toolContext)
+
+	name := kind.FuncName(target)
+	testPrompt, err := testPhase.PromptBuilderWithImplementation(target.GetFunctionSignature(), code, name, kind.Note(target)).
+		WithFileContext(r.fileContext).
+		WithTemplates(r.options.Templates).
+		BuildForTarget(ctx, target, fileContent)
+	if err != nil {
+		r.phaseLogger.Warn("Failed to build prompt, skipping "+kind.Name, "error", err.Error())
+		return ""
+	}
+
+	r.phaseLogger.Info("Writing " + kind.Name + "...")
+	if _, err := r.client.Generate(ctx, testPrompt); err != nil {
+		r.phaseLogger.Warn("Generation failed, skipping "+kind.Name, "error", err.Error())
+		return ""
+	}
+
+	result, failureReason := r.processResult(testPhase, kind.Name)
+	if failureReason != nil {
+		r.phaseLogger.Warn("Phase did not complete, skipping "+kind.Name, "reason", failureReason.Message)
+		return ""
+	}
+
+	body := strings.TrimSpace(result["code"].(string))
+	checked, err := testPhase.Checker().Execute(ctx, map[string]any{"code": body})
+	if err != nil {
+		r.phaseLogger.Warn("Failed to check "+kind.Name+", skipping it", "error", err.Error())
+		return ""
+	}
+	if check, ok := checked.(*impl.CheckCodeResult); !ok || !check.Valid {
+		r.phaseLogger.Warn("Result does not pass " + testPhase.Checker().Name() + ", skipping " + kind.Name)
+		return ""
+	}
+
+	r.phaseLogger.Info("Generated "+kind.Name, "name", name)
+	return body
+}
+
+// noteToolsUnsupported records that the model cannot call tools when err says so
+func (r *Runner) noteToolsUnsupported(err error) {
+	if errors.Is(err, llm.ErrToolsUnsupported) {
+		r.toolsUnsupported.Store(true)
+	}
+}
+
+// singleShotSystemPrompt replaces the phase system prompts for models that cannot call tools
+const singleShotSystemPrompt = `You are an expert Go developer implementing the body of a single function.
+Tools are not available: answer with only the function body in one Go code block,
+without the signature, the surrounding braces or any explanation.`
+
+// ExecuteSingleShot generates the implementation with one prompt and no tools, for models
+// that answer with code instead of calling tools. The body is taken from the answer and must
+// pass check_code, as there are no rounds to correct it in
+func (r *Runner) ExecuteSingleShot(ctx context.Context, target *parser.Target, fileContent string, fileInfo *parser.FileInfo, projectRoot string, contextResult map[string]any) (string, *parser.FailureReason) {
+	implPhase := NewImplementationPhase(r.implTemperature, projectRoot, r.logger, r.options)
+	r.phaseLogger = r.logger.With(slog.String("phase", PhaseImplementation))
+	r.helpers = nil
+	r.client.SetTemperature(r.implTemperature)
+	r.client.SetMaxRounds(1)
+	r.client.SetSystemPrompt(singleShotSystemPrompt)
+	r.client.SetSharedContext("")
+	r.client.SetTools(nil, nil)
+	r.client.SetLogger(r.phaseLogger)
+
+	contextMarkdown := formatter.FormatContextAsMarkdown(contextResult)
+	for _, section := range []string{r.packageContext, r.dependencyContext, r.siblingContext} {
+		if section != "" {
+			contextMarkdown += "\n" + section
+		}
+	}
+	prompt, err := implPhase.PromptBuilderWithContext(contextMarkdown).
+		WithFileContext(r.fileContext).
+		WithTemplates(r.options.Templates).
+		BuildForTarget(ctx, target, fileContent)
+	if err != nil {
+		return "", &parser.FailureReason{
+			Phase:   "implementation",
+			Message: "Failed to build implementation prompt: " + err.Error(),
+			Context: "Prompt construction error",
+		}
+	}
+
+	r.phaseLogger.Info("Generating without tools...")
+	answer, err := r.client.Generate(ctx, prompt)
+	if err != nil {
+		return "", &parser.FailureReason{
+			Phase:   "implementation",
+			Message: "AI implementation generation failed: " + err.Error(),
+			Context: "Generated with a single prompt, as the model does not call tools",
+		}
+	}
+	code := codegen.CleanCode(answer)
+
+	if failure := r.checkAccepted(ctx, target, fileInfo, projectRoot, code, true); failure != nil {
+		failure.Attempt = code
+		return "", failure
+	}
+	return code, nil
+}
+
+// CheckAccepted runs the checks of accepted implementations, check_code included, on code
+// produced without a model, such as the bodies synthesized by rules
+func (r *Runner) CheckAccepted(ctx context.Context, target *parser.Target, fileInfo *parser.FileInfo, projectRoot string, code string) *parser.FailureReason {
+	return r.checkAccepted(ctx, target, fileInfo, projectRoot, code, true)
+}
+
+// checkAccepted runs the checks every accepted implementation passes, whichever path
+// produced it: forbidden imports, panic calls and the provenance scan, then check_code when
+// compile is set
+func (r *Runner) checkAccepted(ctx context.Context, target *parser.Target, fileInfo *parser.FileInfo, projectRoot string, code string, compile bool) *parser.FailureReason {
+	if failure := r.checkForbiddenImports(code, fileInfo); failure != nil {
+		return failure
+	}
+	if failure := r.checkPanics(code, target); failure != nil {
+		return failure
+	}
+	if failure := r.checkProvenance(code); failure != nil {
+		return failure
+	}
+	if !compile {
+		return nil
+	}
+
+	check, err := r.CheckImplementation(ctx, target, fileInfo, projectRoot, code)
+	if err != nil {
+		return &parser.FailureReason{Phase: "implementation", Message: err.Error()}
+	}
+	if !check.Valid {
+		issues := make([]string, 0, len(check.Issues))
+		for _, issue := range check.Issues {
+			issues = append(issues, fmt.Sprintf("%d:%d: %s", issue.Line, issue.Column, issue.Message))
+		}
+		return &parser.FailureReason{
+			Phase:   "implementation",
+			Message: "Implementation does not pass check_code: " + strings.Join(issues, "; "),
+			Context: "The code has compile or convention errors",
+		}
+	}
+	return nil
+}
+
+// CheckImplementation runs check_code on an accepted implementation, as used to rank candidates.
+// Its formatting is not checked, as accepted bodies are formatted when written
+func (r *Runner) CheckImplementation(ctx context.Context, target *parser.Target, fileInfo *parser.FileInfo, projectRoot string, code string) (*impl.CheckCodeResult, error) {
+	checker := newCheckCodeTool(projectRoot, r.options)
+	checker.SetContext(r.toolContext(fileInfo, target, projectRoot))
+
+	result, err := checker.Execute(ctx, map[string]any{"code": impl.FormatBody(code)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check implementation: %w", err)
+	}
+	checkResult, ok := result.(*impl.CheckCodeResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected check_code result type %T", result)
+	}
+	return checkResult, nil
+}
+
+// formatPreviousFailure describes the failure of the target's last generation for the
+// implementation prompt
+func formatPreviousFailure(reason *parser.FailureReason) string {
+	var sb strings.Builder
+	sb.WriteString("## Previous Failed Attempt\n")
+	sb.WriteString("The last generation of this function failed. Avoid repeating the same mistake.\n")
+	if reason.Phase != "" {
+		fmt.Fprintf(&sb, "Failed in %s: %s\n", reason.Phase, reason.Message)
+	} else {
+		fmt.Fprintf(&sb, "Failure: %s\n", reason.Message)
+	}
+	if reason.Attempt != "" {
+		sb.WriteString("Rejected implementation:\n```go\n")
+		sb.WriteString(reason.Attempt)
+		sb.WriteString("\n```\n")
+	}
+	return sb.String()
+}
+
+// newCheckCodeTool creates the check_code tool with every configured check
+func newCheckCodeTool(projectRoot string, opts Options) *impl.CheckCodeTool {
+	return impl.NewCheckCodeTool(projectRoot).
+		WithConventions(opts.Conventions).
+		WithForbiddenImports(opts.ForbiddenImports).
+		WithDestPackage(opts.DestImportPath).
+		WithDestDir(opts.DestDir).
+		WithGoVersion(opts.GoVersion).
+		WithNoPanic(opts.NoPanic).
+		WithOverlay(opts.Overlay).
+		WithMaxIssues(opts.CheckMaxIssues)
+}
+
+// checkForbiddenImports rejects implementations that use packages forbidden by configuration
+// or the destination package itself, which the generated code would import in a cycle
+func (r *Runner) checkForbiddenImports(code string, fileInfo *parser.FileInfo) *parser.FailureReason {
+	if len(r.options.ForbiddenImports) == 0 && r.options.DestImportPath == "" {
+		return nil
+	}
+
+	fileImports := r.fileImports(fileInfo)
+	if self := imports.SelfImports(code, fileImports, r.options.DestImportPath); len(self) > 0 {
+		r.logger.Warn("Generated code imports its own package", slog.String("import", self[0]))
+		return &parser.FailureReason{
+			Phase:   "implementation",
+			Message: fmt.Sprintf("Generated code imports %s, the package it is generated into, which would be an import cycle", self[0]),
+			Context: "Refer to declarations of the package without a package qualifier",
+		}
+	}
+
+	var violations []string
+	for _, importPath := range imports.ReferencedImports(code, fileImports) {
+		if rule, matched := imports.MatchForbidden(importPath, r.options.ForbiddenImports); matched {
+			violations = append(violations, fmt.Sprintf("%s (forbidden by %q)", importPath, rule))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	r.logger.Warn("Generated code uses forbidden imports", slog.String("imports", strings.Join(violations, ", ")))
+	return &parser.FailureReason{
+		Phase:   "implementation",
+		Message: "Generated code uses forbidden imports: " + strings.Join(violations, ", "),
+		Context: "Remove the package from forbidden_imports in mantra.toml or adjust the instruction",
+	}
+}
+
+// fileImports returns the import paths of the target file by the name code refers to them
+// by. Unaliased imports go by the name their package declares, which can differ from the
+// last element of the path (e.g. "gopkg.in/yaml.v3")
+func (r *Runner) fileImports(fileInfo *parser.FileInfo) map[string]string {
+	fileImports := make(map[string]string)
+	if fileInfo == nil {
+		return fileImports
+	}
+
+	var names map[string]string
+	if r.fileContext != nil {
+		names = r.fileContext.PackageNames()
+	} else {
+		names = packageNames(filepath.Dir(fileInfo.FilePath))
+	}
+	for _, imp := range fileInfo.Imports {
+		name := imp.Alias
+		if name == "" || name == "_" || name == "." {
+			if name = names[imp.Path]; name == "" {
+				name = path.Base(imp.Path)
+			}
+		}
+		fileImports[name] = imp.Path
+	}
+	return fileImports
+}
+
+// packageNames returns the names declared by the packages the package in dir imports, by
+// import path, or nil if the package fails to load
+func packageNames(dir string) map[string]string {
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedImports, Dir: dir}
+	pkgs, err := packages.Load(buildenv.Apply(cfg), ".")
+	if err != nil {
+		return nil
+	}
+	names := make(map[string]string)
+	for _, pkg := range pkgs {
+		for importPath, imported := range pkg.Imports {
+			names[importPath] = imported.Name
+		}
+	}
+	return names
+}
+
+// checkProvenance reports generated code that reproduces long verbatim runs of the configured corpus
+func (r *Runner) checkProvenance(code string) *parser.FailureReason {
+	matches := r.options.Provenance.Scan(code)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(matches))
+	for i, m := range matches {
+		descriptions[i] = m.String()
+	}
+	r.logger.Warn("Generated code matches known sources verbatim", slog.String("matches", strings.Join(descriptions, "; ")))
+
+	if !r.options.Provenance.Blocking() {
+		return nil
+	}
+	return &parser.FailureReason{
+		Phase:   "implementation",
+		Message: "Generated code reproduces known sources verbatim: " + strings.Join(descriptions, "; "),
+		Context: "Rewrite the instruction or review the matching sources; set provenance.fail = false to only warn",
+	}
+}
+
 // processResult processes the result from a phase
 func (r *Runner) processResult(p Phase, phaseName string) (map[string]any, *parser.FailureReason) {
 	phaseResult, completed := p.Result()
@@ -203,14 +823,32 @@ func (r *Runner) processResult(p Phase, phaseName string) (map[string]any, *pars
 
 // configureClientForPhase configures the AI client with phase-specific settings
 func (r *Runner) configureClientForPhase(p Phase, toolContext *tools.Context) {
+	var target *parser.Target
+	if toolContext != nil {
+		target = toolContext.Target
+	}
+	// Get tools once, restricted by the target's // mantra:tools directive
+	// Benchmarks and fuzz tests are test code, which the target's no-panic rule does not cover
+	_, testCode := p.(*TestFuncPhase)
+	phaseTools, systemPrompt := targetTools(p, target, r.noPanic(target) && !testCode)
+	if !testCode {
+		systemPrompt += r.errorStyleSection(target)
+	}
+
+	maxRounds := r.options.MaxRounds[p.Name()]
+	if custom, ok := p.(*CustomPhase); ok {
+		maxRounds = custom.spec.MaxRounds
+	}
+
 	r.client.SetTemperature(p.Temperature())
-	r.client.SetSystemPrompt(p.SystemPrompt())
+	r.client.SetMaxRounds(maxRounds)
+	r.client.SetSystemPrompt(systemPrompt)
+	r.client.SetSharedContext("")
 
 	// Create and store phase-aware logger
 	r.phaseLogger = r.logger.With(slog.String("phase", p.Name()))
 
-	// Get tools once and convert/create executor
-	phaseTools := p.Tools()
+	// Convert tools and create executor
 	aiTools := llm.ConvertToAITools(phaseTools)
 	executor := tools.NewExecutor(phaseTools, r.phaseLogger)
 
@@ -224,3 +862,102 @@ func (r *Runner) configureClientForPhase(p Phase, toolContext *tools.Context) {
 	// Update client's logger to include phase information
 	r.client.SetLogger(r.phaseLogger)
 }
+
+// targetTools returns the tools of a phase offered for target and the system prompt to send.
+// When the target's // mantra:tools directive leaves tools out, the prompt says which
+// remain, since phase prompts refer to all of their tools. Targets that must not panic
+// get a section saying so
+func targetTools(p Phase, target *parser.Target, noPanic bool) ([]tools.Tool, string) {
+	all := p.Tools()
+	systemPrompt := p.SystemPrompt()
+	if noPanic {
+		systemPrompt += noPanicSection
+	}
+	if target == nil || target.Tools == nil {
+		return all, systemPrompt
+	}
+	allowed := tools.Allowed(all, target.Tools)
+	if len(allowed) == len(all) {
+		return all, systemPrompt
+	}
+
+	names := make([]string, len(allowed))
+	for i, tool := range allowed {
+		names[i] = tool.Name()
+	}
+	return allowed, systemPrompt + "\n\n## Available Tools\n\nOnly these tools are available for this function: " +
+		strings.Join(names, ", ") + ". Skip any step above that needs another tool.\n"
+}
+
+// noPanicSection tells the model the function must not panic
+const noPanicSection = "\n\n## No Panics\n\nThis function must never panic: do not call panic, and avoid operations " +
+	"that panic on bad input such as unchecked indexing, nil map writes or failing type assertions. " +
+	"Report failures through the error result, or return zero values when the function has none. " +
+	"check_code reports every panic call.\n"
+
+// errorStyleSections tell the model how functions returning an error report failures, by
+// conventions.ErrorConventions.Style
+var errorStyleSections = map[string]string{
+	conventions.ErrorStyleSentinel: "\n\n## Error Style\n\nThis project reports failures with package-level sentinel errors. " +
+		"Return an existing sentinel (var ErrXxx = errors.New(...)), or wrap one with fmt.Errorf(\"...: %w\", ErrXxx) to add detail. " +
+		"Do not create errors inline with errors.New or fmt.Errorf without %w; if no sentinel fits, say so in your result. " +
+		"check_code reports every violation as error_style.\n",
+	conventions.ErrorStyleWrap: "\n\n## Error Style\n\nThis project wraps errors with context. " +
+		"When a call fails, return fmt.Errorf(\"doing x: %w\", err) instead of err as is, so the message says where it failed " +
+		"and callers can still match it with errors.Is/As. check_code reports every violation as error_style.\n",
+	conventions.ErrorStyleJoin: "\n\n## Error Style\n\nThis project combines several errors with errors.Join. " +
+		"When more than one error must be reported, e.g. validating several fields or closing several resources, " +
+		"return errors.Join(errs...) instead of folding them into one fmt.Errorf message. " +
+		"check_code reports every violation as error_style.\n",
+}
+
+// errorStyleSection returns the error style section for target, or nothing when the project
+// sets no style or the function does not return an error last
+func (r *Runner) errorStyleSection(target *parser.Target) string {
+	if r.options.Conventions == nil || target == nil || len(target.Returns) == 0 || target.Returns[len(target.Returns)-1].Type != "error" {
+		return ""
+	}
+	return errorStyleSections[r.options.Conventions.Errors.Style]
+}
+
+// noPanic reports whether code generated for target must not call panic, by configuration
+// or the target's // mantra:no_panic directive
+func (r *Runner) noPanic(target *parser.Target) bool {
+	return r.options.NoPanic || (target != nil && target.NoPanic)
+}
+
+// checkPanics rejects implementations that call panic when they must not
+func (r *Runner) checkPanics(code string, target *parser.Target) *parser.FailureReason {
+	if !r.noPanic(target) {
+		return nil
+	}
+	calls := panicCalls(code)
+	if calls == 0 {
+		return nil
+	}
+
+	r.logger.Warn("Generated code calls panic", slog.Int("calls", calls))
+	return &parser.FailureReason{
+		Phase:   "implementation",
+		Message: fmt.Sprintf("Generated code calls panic %d time(s), but the function must not panic", calls),
+		Context: "no_panic is set in mantra.toml or by // mantra:no_panic on the function",
+	}
+}
+
+// panicCalls counts the calls of panic in a function body
+func panicCalls(code string) int {
+	file, err := goparser.ParseFile(token.NewFileSet(), "", "package p\nfunc _() {\n"+code+"\n}", 0)
+	if err != nil {
+		return 0
+	}
+	calls := 0
+	ast.Inspect(file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if ident, ok := ast.Unparen(call.Fun).(*ast.Ident); ok && ident.Name == "panic" && ident.Obj == nil {
+				calls++
+			}
+		}
+		return true
+	})
+	return calls
+}
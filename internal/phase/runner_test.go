@@ -0,0 +1,188 @@
+package phase
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/conventions"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestCheckPanics(t *testing.T) {
+	code := `if len(b) == 0 {
+	panic("empty")
+}
+panic := func(string) {}
+panic("shadowed")
+return nil`
+
+	tests := []struct {
+		name    string
+		options Options
+		target  *parser.Target
+		reject  bool
+	}{
+		{"allowed", Options{}, &parser.Target{Name: "Parse"}, false},
+		{"configured", Options{NoPanic: true}, &parser.Target{Name: "Parse"}, true},
+		{"directive", Options{}, &parser.Target{Name: "Parse", NoPanic: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRunnerWithOptions(nil, slog.Default(), tt.options)
+			failure := r.checkPanics(code, tt.target)
+			if (failure != nil) != tt.reject {
+				t.Fatalf("Expected rejection %v, got %+v", tt.reject, failure)
+			}
+			if failure != nil && !strings.Contains(failure.Message, "calls panic 1 time(s)") {
+				t.Errorf("Expected the shadowed panic to be ignored, got %q", failure.Message)
+			}
+
+			_, systemPrompt := targetTools(NewImplementationPhase(0, t.TempDir(), nil, tt.options), tt.target, r.noPanic(tt.target))
+			if strings.Contains(systemPrompt, "## No Panics") != tt.reject {
+				t.Errorf("Expected the no-panic section %v, got:\n%s", tt.reject, systemPrompt)
+			}
+		})
+	}
+}
+
+func TestErrorStyleSection(t *testing.T) {
+	returnsError := &parser.Target{Name: "Parse", Returns: []parser.Return{{Type: "int"}, {Type: "error"}}}
+	returnsInt := &parser.Target{Name: "Len", Returns: []parser.Return{{Type: "int"}}}
+	wrap := conventions.Default()
+	wrap.Errors.Style = conventions.ErrorStyleWrap
+
+	tests := []struct {
+		name    string
+		options Options
+		target  *parser.Target
+		want    string
+	}{
+		{"no conventions", Options{}, returnsError, ""},
+		{"no style", Options{Conventions: conventions.Default()}, returnsError, ""},
+		{"no error result", Options{Conventions: wrap}, returnsInt, ""},
+		{"wrap", Options{Conventions: wrap}, returnsError, "wraps errors with context"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			section := NewRunnerWithOptions(nil, slog.Default(), tt.options).errorStyleSection(tt.target)
+			if tt.want == "" && section != "" || !strings.Contains(section, tt.want) {
+				t.Errorf("Expected a section containing %q, got %q", tt.want, section)
+			}
+		})
+	}
+}
+
+func TestCheckAccepted(t *testing.T) {
+	root := t.TempDir()
+	source := `package app
+
+func Count(items []string) int {
+	panic("not implemented")
+}
+`
+	path := filepath.Join(root, "app.go")
+	for name, content := range map[string]string{"go.mod": "module example.com/app\n\ngo 1.21\n", "app.go": source} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	fileInfo := &parser.FileInfo{FilePath: path, PackageName: "app", SourceContent: source}
+	target := &parser.Target{
+		Name:     "Count",
+		FilePath: path,
+		Params:   []parser.Param{{Name: "items", Type: "[]string"}},
+		Returns:  []parser.Return{{Type: "int"}},
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		compile bool
+		want    string
+	}{
+		{"clean", "return len(items)", true, ""},
+		{"panic", `panic("empty")`, false, "calls panic"},
+		{"compile error", "return len(missing)", true, "does not pass check_code"},
+		{"compile skipped", "return len(missing)", false, ""},
+	}
+	r := NewRunnerWithOptions(nil, slog.Default(), Options{NoPanic: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := r.checkAccepted(context.Background(), target, fileInfo, root, tt.code, tt.compile)
+			if tt.want == "" {
+				if failure != nil {
+					t.Errorf("Expected the code to be accepted, got %+v", failure)
+				}
+				return
+			}
+			if failure == nil || !strings.Contains(failure.Message, tt.want) {
+				t.Errorf("Expected a failure containing %q, got %+v", tt.want, failure)
+			}
+		})
+	}
+}
+
+func TestCheckForbiddenImportsVersioned(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/app\n\ngo 1.21\n",
+		"yaml.v3/yaml.go": "package yaml\n\nfunc Marshal(v any) ([]byte, error) { return nil, nil }\n",
+		"lib/v2/lib.go":   "package lib\n\nfunc Version() string { return \"2\" }\n",
+		"app/app.go": `package app
+
+import (
+	"example.com/app/lib/v2"
+	"example.com/app/yaml.v3"
+)
+
+var _, _ = lib.Version, yaml.Marshal
+
+func Encode(v any) ([]byte, error) {
+	panic("not implemented")
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	fileInfo, err := parser.ParseFileInfo(filepath.Join(root, "app", "app.go"))
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"return yaml.Marshal(v)", "example.com/app/yaml.v3"},
+		{"_ = lib.Version()\nreturn nil, nil", "example.com/app/lib/v2"},
+	}
+	options := Options{ForbiddenImports: []string{"example.com/app/yaml.v3", "example.com/app/lib/v2"}}
+	loaded := NewRunnerWithOptions(nil, slog.Default(), options)
+	fc, err := pkgcontext.NewFileContext(context.Background(), fileInfo.FilePath)
+	if err != nil {
+		t.Fatalf("Failed to build file context: %v", err)
+	}
+	shared := NewRunnerWithOptions(nil, slog.Default(), options)
+	shared.SetFileContext(fc)
+
+	for name, r := range map[string]*Runner{"loaded": loaded, "file context": shared} {
+		for _, tt := range tests {
+			failure := r.checkForbiddenImports(tt.code, fileInfo)
+			if failure == nil || !strings.Contains(failure.Message, tt.want) {
+				t.Errorf("%s: expected %q to be rejected for %s, got %+v", name, tt.code, tt.want, failure)
+			}
+		}
+	}
+}
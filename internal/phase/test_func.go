@@ -0,0 +1,254 @@
+package phase
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/prompt"
+	"github.com/rail44/mantra/internal/tools"
+	"github.com/rail44/mantra/internal/tools/impl"
+	"github.com/rail44/mantra/internal/tools/schemas"
+)
+
+// TestFuncPhase represents the phase where AI writes a test function of an accepted
+// implementation: a benchmark for targets marked with // mantra:bench, or a fuzz test for
+// targets marked with // mantra:fuzz
+type TestFuncPhase struct {
+	kind        codegen.TestKind
+	temperature float32
+	tools       []tools.Tool
+	logger      *slog.Logger
+	result      any
+	completed   bool
+	mu          sync.Mutex
+	schema      schemas.ResultSchema
+	resultTool  *impl.ResultTool
+	checker     *impl.CheckTestFuncTool
+}
+
+// NewTestFuncPhase creates a new phase writing a test function of kind for the given implementation
+func NewTestFuncPhase(kind codegen.TestKind, temperature float32, projectRoot, implementation string, logger *slog.Logger, opts Options) *TestFuncPhase {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	phase := &TestFuncPhase{
+		kind:        kind,
+		temperature: temperature,
+		logger:      logger,
+		schema:      &implementationResultSchema{},
+		checker: impl.NewCheckTestFuncTool(kind, projectRoot, implementation).
+			WithGoVersion(opts.GoVersion).
+			WithOverlay(opts.Overlay),
+	}
+	phase.resultTool = impl.NewResultTool(kind.Name, phase.schema, phase.storeResult)
+
+	phase.tools = []tools.Tool{
+		phase.checker,
+		phase.resultTool,
+		phase.resultTool.AppendTool(),
+	}
+
+	return phase
+}
+
+// storeResult stores the result from the result tool
+func (p *TestFuncPhase) storeResult(result any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result = result
+	p.completed = true
+	return nil
+}
+
+// Name returns the name of this phase
+func (p *TestFuncPhase) Name() string {
+	if p.kind.Directive == codegen.FuzzTests.Directive {
+		return PhaseFuzz
+	}
+	return PhaseBenchmark
+}
+
+// Temperature returns the temperature for test function generation
+func (p *TestFuncPhase) Temperature() float32 {
+	return p.temperature
+}
+
+// Tools returns the tools of the phase
+func (p *TestFuncPhase) Tools() []tools.Tool {
+	return p.tools
+}
+
+// Checker returns the check tool of the phase, used to validate the submitted function
+func (p *TestFuncPhase) Checker() *impl.CheckTestFuncTool {
+	return p.checker
+}
+
+// SystemPrompt returns the system prompt for the kind of test function
+func (p *TestFuncPhase) SystemPrompt() string {
+	if p.kind.Directive == codegen.FuzzTests.Directive {
+		return fuzzSystemPrompt
+	}
+	return benchSystemPrompt
+}
+
+// benchSystemPrompt is the system prompt for benchmark generation
+const benchSystemPrompt = `You are an expert Go developer writing benchmarks. Your task: write the body of a Go benchmark function measuring <target>, whose implementation is given in <additional_context>.
+
+## Input Structure
+- <target>: The function signature being benchmarked
+- <context>: Types and packages available in the package
+- <instruction>: Natural language description of what the function does
+- <additional_context>: The implementation to benchmark, the benchmark's signature and what to measure
+
+## Available Tool
+
+- check_bench(): Compile the benchmark body against the implementation and check its loop
+- result(): Submit the benchmark body and complete this phase
+- result_append(): Send part of a long body before calling result()
+
+## Benchmark Rules
+
+1. Write only the statements inside the benchmark function; b is the *testing.B
+2. Build inputs before the loop, so their setup is not measured
+3. Run the measured call in exactly one loop: for b.Loop() { ... } (Go 1.24 and later) or for range b.N { ... }
+4. Never mix b.Loop() and b.N, and call b.Loop() only as the loop condition
+5. Use realistic inputs; a benchmark of empty or trivial inputs measures nothing
+6. Keep results alive when using b.N, e.g. by assigning them to a variable declared outside the loop, so the call is not optimized away
+7. The benchmark is in the same package, so refer to its declarations without a qualifier
+
+## Process
+
+1. Read the implementation and choose representative inputs
+2. Write the benchmark body and validate it with check_bench
+3. Fix every reported issue and validate again
+4. Call the result() tool
+
+## Result Tool Usage
+
+Call result() with JSON containing:
+
+### On success:
+
+{
+  "success": true,
+  "code": "..."  // The benchmark body
+}
+
+A body too long for a single call can be sent in parts with result_append({"index": 0, "chunk": "..."}),
+followed by result() with the remaining code.
+
+### If you cannot write a benchmark:
+{
+  "success": false,
+  "error": {
+    "message": "Brief description of what prevented the benchmark",
+    "details": "Specific details"
+  }
+}
+
+## Important
+
+- ALWAYS call the result() tool to complete the phase
+- The body must pass check_bench`
+
+// fuzzSystemPrompt is the system prompt for fuzz test generation
+const fuzzSystemPrompt = `You are an expert Go developer writing fuzz tests. Your task: write the body of a Go fuzz test exercising <target>, whose implementation is given in <additional_context>.
+
+## Input Structure
+- <target>: The function signature being fuzzed
+- <context>: Types and packages available in the package
+- <instruction>: Natural language description of what the function does
+- <additional_context>: The implementation to fuzz, the fuzz test's signature and what to exercise
+
+## Available Tool
+
+- check_fuzz(): Compile the fuzz test body against the implementation and check its seed corpus and fuzz function
+- result(): Submit the fuzz test body and complete this phase
+- result_append(): Send part of a long body before calling result()
+
+## Fuzz Test Rules
+
+1. Write only the statements inside the fuzz test function; f is the *testing.F
+2. Seed the corpus with f.Add calls before f.Fuzz: the examples, edge cases and malformed inputs the instruction describes
+3. Call f.Fuzz exactly once: f.Fuzz(func(t *testing.T, ...) { ... })
+4. The fuzz function takes a *testing.T followed by arguments of type string, []byte, bool, or an integer or float type; build other inputs, such as structs or receivers, from them inside the function
+5. Every f.Add call passes one value of exactly the matching type for each argument after t, e.g. f.Add(int64(1)) for an int64 argument
+6. Inside the fuzz function use only t, never f
+7. Check properties that hold for every input: no panics, errors for invalid input, round trips, invariants from the instruction. Call t.Skip for inputs outside the function's domain
+8. The fuzz test is in the same package, so refer to its declarations without a qualifier
+
+## Process
+
+1. Read the implementation and the instruction, and choose seeds and properties
+2. Write the fuzz test body and validate it with check_fuzz
+3. Fix every reported issue and validate again
+4. Call the result() tool
+
+## Result Tool Usage
+
+Call result() with JSON containing:
+
+### On success:
+
+{
+  "success": true,
+  "code": "..."  // The fuzz test body
+}
+
+A body too long for a single call can be sent in parts with result_append({"index": 0, "chunk": "..."}),
+followed by result() with the remaining code.
+
+### If you cannot write a fuzz test:
+{
+  "success": false,
+  "error": {
+    "message": "Brief description of what prevented the fuzz test",
+    "details": "Specific details"
+  }
+}
+
+## Important
+
+- ALWAYS call the result() tool to complete the phase
+- The body must pass check_fuzz`
+
+// PromptBuilderWithImplementation returns a prompt builder that presents the implementation,
+// the signature of the test function and the note of the target's directive
+func (p *TestFuncPhase) PromptBuilderWithImplementation(signature, code, funcName, note string) *prompt.Builder {
+	additional := "## Implementation Under Test\n```go\n" + signature + " {\n" + code + "\n}\n```\n\n" +
+		"## Signature\n```go\nfunc " + funcName + "(" + p.kind.Param + ")\n```"
+	if note != "" {
+		additional += "\n\n## Focus\n" + note
+	}
+	return p.PromptBuilder().WithAdditionalContext(additional)
+}
+
+// PromptBuilder returns a prompt builder configured for test function generation
+func (p *TestFuncPhase) PromptBuilder() *prompt.Builder {
+	builder := prompt.NewBuilder(p.logger)
+	builder.SetUseTools(true)
+	return builder
+}
+
+// Result returns the phase result and whether it's complete
+func (p *TestFuncPhase) Result() (any, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.result, p.completed
+}
+
+// Reset clears the phase state for reuse
+func (p *TestFuncPhase) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result = nil
+	p.completed = false
+	p.resultTool.Reset()
+}
+
+// ResultSchema returns the schema for this phase's result tool
+func (p *TestFuncPhase) ResultSchema() schemas.ResultSchema {
+	return p.schema
+}
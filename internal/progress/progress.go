@@ -0,0 +1,167 @@
+// Package progress writes generation progress as a stream of JSON lines for orchestration systems
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventRunStarted      = "run_started"
+	EventTargetAdded     = "target_added"
+	EventTargetRunning   = "target_running"
+	EventPhase           = "phase"
+	EventToolCall        = "tool_call"
+	EventTargetCompleted = "target_completed"
+	EventTargetFailed    = "target_failed"
+	EventRunFinished     = "run_finished"
+)
+
+// Event is one line of the stream
+type Event struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Package string    `json:"package,omitempty"` // Run events
+	Index   int       `json:"index,omitempty"`   // Target events; indexes start at 1
+	Total   int       `json:"total,omitempty"`   // Number of targets in the run
+	Target  string    `json:"target,omitempty"`
+	Phase   string    `json:"phase,omitempty"`
+	Tool    string    `json:"tool,omitempty"`
+	Error   string    `json:"error,omitempty"` // Tool error or failure of the run
+
+	Rounds    int `json:"rounds,omitempty"`     // Chat completion requests made by a finished target
+	ToolCalls int `json:"tool_calls,omitempty"` // Tool calls made by a finished target
+
+	Succeeded *int `json:"succeeded,omitempty"` // Run summary
+	Failed    *int `json:"failed,omitempty"`
+}
+
+// Stream writes events as JSON lines. It is safe for concurrent use, and a nil stream
+// discards all events so callers need not check whether the stream is enabled
+type Stream struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	targets map[int]*targetState
+}
+
+// targetState is what the stream remembers about a target between events
+type targetState struct {
+	name      string
+	phase     string
+	rounds    int
+	toolCalls int
+}
+
+// NewStream creates a stream writing to w
+func NewStream(w io.Writer) *Stream {
+	return &Stream{
+		enc:     json.NewEncoder(w),
+		targets: make(map[int]*targetState),
+	}
+}
+
+// RunStarted reports the start of a run over pkg
+func (s *Stream) RunStarted(pkg string) {
+	s.emit(Event{Event: EventRunStarted, Package: pkg})
+}
+
+// RunFinished reports the end of a run. A non-nil err means the run failed
+func (s *Stream) RunFinished(pkg string, succeeded, failed int, err error) {
+	e := Event{Event: EventRunFinished, Package: pkg, Succeeded: &succeeded, Failed: &failed}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.emit(e)
+}
+
+// TargetAdded reports a target that is about to be generated
+func (s *Stream) TargetAdded(name string, index, total int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.targets[index] = &targetState{name: name}
+	s.mu.Unlock()
+	s.emit(Event{Event: EventTargetAdded, Index: index, Total: total, Target: name})
+}
+
+// TargetRunning reports that generation of a target started
+func (s *Stream) TargetRunning(index int) {
+	s.emitTarget(Event{Event: EventTargetRunning, Index: index})
+}
+
+// Phase reports the phase of a target, emitting an event only when it changes
+func (s *Stream) Phase(index int, phase string) {
+	if s == nil || phase == "" {
+		return
+	}
+	s.mu.Lock()
+	state := s.targets[index]
+	changed := state != nil && state.phase != phase
+	if changed {
+		state.phase = phase
+	}
+	s.mu.Unlock()
+	if changed {
+		s.emitTarget(Event{Event: EventPhase, Index: index})
+	}
+}
+
+// ToolCall reports a finished tool call of a target; errMsg is empty if the call succeeded
+func (s *Stream) ToolCall(index int, tool, errMsg string) {
+	s.emitTarget(Event{Event: EventToolCall, Index: index, Tool: tool, Error: errMsg})
+}
+
+// Stats records the request statistics reported with the target's completion
+func (s *Stream) Stats(index, rounds, toolCalls int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state := s.targets[index]; state != nil {
+		state.rounds, state.toolCalls = rounds, toolCalls
+	}
+}
+
+// TargetCompleted reports a target whose implementation was accepted
+func (s *Stream) TargetCompleted(index int) {
+	s.emitTarget(Event{Event: EventTargetCompleted, Index: index})
+}
+
+// TargetFailed reports a target that failed
+func (s *Stream) TargetFailed(index int) {
+	s.emitTarget(Event{Event: EventTargetFailed, Index: index})
+}
+
+// emitTarget fills in what the stream knows about the event's target and writes it
+func (s *Stream) emitTarget(e Event) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if state := s.targets[e.Index]; state != nil {
+		e.Target = state.name
+		e.Phase = state.phase
+		if e.Event == EventTargetCompleted || e.Event == EventTargetFailed {
+			e.Rounds, e.ToolCalls = state.rounds, state.toolCalls
+		}
+	}
+	s.mu.Unlock()
+	s.emit(e)
+}
+
+func (s *Stream) emit(e Event) {
+	if s == nil {
+		return
+	}
+	e.Time = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Encode appends the newline; a failed write only loses progress output
+	_ = s.enc.Encode(e)
+}
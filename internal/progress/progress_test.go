@@ -0,0 +1,64 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStreamWritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	stream := NewStream(&buf)
+
+	stream.RunStarted("shop")
+	stream.TargetAdded("Cart.Total", 1, 1)
+	stream.TargetRunning(1)
+	stream.Phase(1, "Implementation")
+	stream.Phase(1, "Implementation") // Unchanged phases are not repeated
+	stream.ToolCall(1, "check_code", "")
+	stream.ToolCall(1, "inspect", "not found")
+	stream.Stats(1, 4, 2)
+	stream.TargetCompleted(1)
+	stream.RunFinished("shop", 1, 0, errors.New("git integration failed"))
+
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Failed to decode line %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e.Event)
+	}
+	want := []string{EventRunStarted, EventTargetAdded, EventTargetRunning, EventPhase, EventToolCall, EventToolCall, EventTargetCompleted, EventRunFinished}
+	if strings.Join(kinds, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected events %v, got %v", want, kinds)
+	}
+
+	if e := events[5]; e.Target != "Cart.Total" || e.Phase != "Implementation" || e.Tool != "inspect" || e.Error != "not found" {
+		t.Errorf("Expected failed inspect call in implementation, got %+v", e)
+	}
+	if e := events[6]; e.Rounds != 4 || e.ToolCalls != 2 {
+		t.Errorf("Expected completion with stats, got %+v", e)
+	}
+	if e := events[7]; e.Succeeded == nil || *e.Succeeded != 1 || e.Failed == nil || *e.Failed != 0 || e.Error != "git integration failed" {
+		t.Errorf("Expected run summary with error, got %+v", e)
+	}
+}
+
+func TestNilStreamDiscardsEvents(t *testing.T) {
+	var stream *Stream
+	stream.RunStarted("shop")
+	stream.TargetAdded("Cart.Total", 1, 1)
+	stream.Phase(1, "Implementation")
+	stream.ToolCall(1, "check_code", "")
+	stream.Stats(1, 1, 1)
+	stream.TargetFailed(1)
+	stream.RunFinished("shop", 0, 1, nil)
+}
@@ -1,11 +1,12 @@
 package prompt
 
 import (
+	"context"
 	"fmt"
+	"go/ast"
 	"log/slog"
-	"strings"
 
-	"github.com/rail44/mantra/internal/context"
+	pkgcontext "github.com/rail44/mantra/internal/context"
 	"github.com/rail44/mantra/internal/parser"
 )
 
@@ -13,6 +14,8 @@ import (
 type Builder struct {
 	useTools          bool
 	additionalContext string
+	fileContext       *pkgcontext.FileContext // Shared context for the target's file (optional)
+	templates         *Templates              // Prompt templates (defaults when nil)
 	logger            *slog.Logger
 }
 
@@ -33,87 +36,62 @@ func (b *Builder) WithAdditionalContext(context string) *Builder {
 	return b
 }
 
+// WithFileContext reuses context already extracted for the target's file instead of extracting it again
+func (b *Builder) WithFileContext(fc *pkgcontext.FileContext) *Builder {
+	b.fileContext = fc
+	return b
+}
+
+// WithTemplates renders prompts with the given templates instead of the built-in ones
+func (b *Builder) WithTemplates(t *Templates) *Builder {
+	b.templates = t
+	return b
+}
+
 // SetUseTools enables or disables tool usage instructions in prompts
 func (b *Builder) SetUseTools(useTools bool) {
 	b.useTools = useTools
 }
 
 // BuildForTarget creates a prompt for a specific generation target
-func (b *Builder) BuildForTarget(target *parser.Target, fileContent string) (string, error) {
+func (b *Builder) BuildForTarget(ctx context.Context, target *parser.Target, fileContent string) (string, error) {
+	if b.fileContext != nil {
+		return b.buildPromptWithContext(b.fileContext.ForTarget(target), target)
+	}
+
 	// Use function-focused context extraction for reliable type information
-	ctx, err := context.ExtractFunctionContext(target.FilePath, target)
+	relevant, err := pkgcontext.ExtractFunctionContext(ctx, target.FilePath, target)
 	if err != nil {
 		b.logger.Error("context extraction failed", slog.String("error", err.Error()))
 		return "", fmt.Errorf("context extraction failed: %w", err)
 	}
 
-	return b.buildPromptWithContext(ctx, target), nil
+	return b.buildPromptWithContext(relevant, target)
 }
 
 // buildPromptWithContext builds a prompt using the extracted context
-func (b *Builder) buildPromptWithContext(ctx *context.RelevantContext, target *parser.Target) string {
-	var prompt strings.Builder
-
-	// DevStral最適化：XMLタグで構造化
-	prompt.WriteString("<context>\n")
-
-	// All imports are treated as available packages for the AI
-	if len(ctx.Imports) > 0 {
-		prompt.WriteString("Available packages:\n")
-		for _, imp := range ctx.Imports {
-			identifier := imp.GetIdentifier()
-
-			// For blank imports, we still show them as available packages
-			// The AI doesn't need to know about the blank import detail
-			if imp.Path == identifier {
-				// Standard library or simple package
-				prompt.WriteString(fmt.Sprintf("- %s\n", imp.Path))
-			} else if imp.Alias != "" && imp.Alias != "_" && imp.Alias != identifier {
-				// Custom alias (excluding blank imports)
-				prompt.WriteString(fmt.Sprintf("- %s \"%s\"\n", imp.Alias, imp.Path))
-			} else {
-				// Package with different identifier
-				prompt.WriteString(fmt.Sprintf("- %s \"%s\"\n", identifier, imp.Path))
-			}
-		}
-		prompt.WriteString("\n")
+func (b *Builder) buildPromptWithContext(ctx *pkgcontext.RelevantContext, target *parser.Target) (string, error) {
+	templates := b.templates
+	if templates == nil {
+		templates = DefaultTemplates()
 	}
+	return templates.Render(newTemplateData(ctx, target, b.additionalContext))
+}
 
-	// 関数シグネチャに関連する型情報を優先的に表示
-	if len(ctx.Types) > 0 {
-		prompt.WriteString("Available types:\n")
-		for typeName, typeDef := range ctx.Types {
-			prompt.WriteString(fmt.Sprintf("```go\n%s\n```\n", typeDef))
-
-			// Include methods for this type if available
-			if methods, exists := ctx.Methods[typeName]; exists && len(methods) > 0 {
-				prompt.WriteString("\nMethods:\n")
-				for _, method := range methods {
-					prompt.WriteString(fmt.Sprintf("- %s\n", method.Signature))
-				}
-			}
-			prompt.WriteString("\n")
-		}
+// hasVariadicParam reports whether the target's last parameter is variadic
+func hasVariadicParam(target *parser.Target) bool {
+	if target.FuncDecl == nil || target.FuncDecl.Type.Params == nil {
+		return false
 	}
-
-	prompt.WriteString("</context>\n\n")
-
-	prompt.WriteString("<target>\n")
-	prompt.WriteString(fmt.Sprintf("```go\n%s {\n    <IMPLEMENT_HERE>\n}\n```\n", target.GetFunctionSignature()))
-	prompt.WriteString("</target>\n\n")
-
-	prompt.WriteString("<instruction>\n")
-	prompt.WriteString(fmt.Sprintf("%s\n", target.Instruction))
-	prompt.WriteString("</instruction>\n")
-
-	// Add additional context if provided
-	if b.additionalContext != "" {
-		prompt.WriteString("\n<additional_context>\n")
-		prompt.WriteString(b.additionalContext)
-		prompt.WriteString("\n</additional_context>\n")
+	params := target.FuncDecl.Type.Params.List
+	if len(params) == 0 {
+		return false
 	}
+	_, ok := params[len(params)-1].Type.(*ast.Ellipsis)
+	return ok
+}
 
-	fullPrompt := prompt.String()
-
-	return fullPrompt
+// returnsError reports whether the target's last return value is an error
+func returnsError(target *parser.Target) bool {
+	return len(target.Returns) > 0 && target.Returns[len(target.Returns)-1].Type == "error"
 }
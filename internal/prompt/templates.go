@@ -0,0 +1,192 @@
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// DefaultTemplatesDir is where template overrides are looked up, relative to the config file
+const DefaultTemplatesDir = ".mantra/templates"
+
+// rootTemplate is the template rendered to produce a prompt
+const rootTemplate = "prompt.tmpl"
+
+//go:embed templates/*.tmpl
+var defaultTemplateFS embed.FS
+
+// Templates renders target prompts from Go text/template files
+type Templates struct {
+	tmpl *template.Template
+}
+
+// TemplateData is the data passed to prompt templates
+type TemplateData struct {
+	Imports               []string                    // Available packages, e.g. `fmt` or `slog "log/slog"`
+	Types                 []TypeData                  // Types relevant to the target, sorted by name
+	Constants             []string                    // Const blocks declaring constants of those types
+	Signature             string                      // Function signature of the target
+	Target                *parser.Target              // The target being generated
+	Instruction           string                      // Natural language instruction from the mantra comment
+	Constructor           *pkgcontext.ConstructorInfo // Set when the target is a constructor
+	ConstructorGuidelines []string                    // Wiring guidelines for constructor targets
+	Receiver              *pkgcontext.ReceiverState   // How other methods use the receiver's fields (nil if none do)
+	AdditionalContext     string                      // Context gathered earlier (empty if none)
+	Context               *pkgcontext.RelevantContext // Raw extracted context for custom sections
+}
+
+// TypeData describes a type available to the target
+type TypeData struct {
+	Name       string
+	Definition string
+	Doc        string   // Doc comment as // lines (empty if none)
+	Methods    []string // Method signatures, followed by their doc on one line
+}
+
+// docComment formats doc as Go line comments
+func docComment(doc string) string {
+	if doc == "" {
+		return ""
+	}
+	lines := strings.Split(doc, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("// "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DefaultTemplates returns the built-in prompt templates
+func DefaultTemplates() *Templates {
+	return &Templates{tmpl: template.Must(parseDefaultTemplates())}
+}
+
+// LoadTemplates returns the built-in templates with any *.tmpl files in dir overriding them.
+// A file named like a built-in template (e.g. target.tmpl) replaces it; other files define
+// additional templates that an overridden prompt.tmpl can include. A missing dir is not an error.
+func LoadTemplates(dir string) (*Templates, error) {
+	tmpl, err := parseDefaultTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates in %s: %w", dir, err)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+		}
+		if _, err := tmpl.New(filepath.Base(path)).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+		}
+	}
+
+	return &Templates{tmpl: tmpl}, nil
+}
+
+// parseDefaultTemplates parses the embedded templates
+func parseDefaultTemplates() (*template.Template, error) {
+	tmpl, err := template.New(rootTemplate).ParseFS(defaultTemplateFS, "templates/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse default templates: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Render executes the prompt template with data
+func (t *Templates) Render(data *TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.ExecuteTemplate(&buf, rootTemplate, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return strings.TrimRight(buf.String(), "\n") + "\n", nil
+}
+
+// newTemplateData collects everything the templates need for a target
+func newTemplateData(ctx *pkgcontext.RelevantContext, target *parser.Target, additionalContext string) *TemplateData {
+	data := &TemplateData{
+		Signature:         target.GetFunctionSignature(),
+		Target:            target,
+		Instruction:       target.Instruction,
+		AdditionalContext: additionalContext,
+		Context:           ctx,
+	}
+	if target.FuncVar {
+		// Show the variable as declared: var Name = func(...) ...
+		data.Signature = "var " + target.Name + " = func" + strings.TrimPrefix(data.Signature, "func "+target.Name)
+	}
+
+	// All imports are treated as available packages for the AI
+	for _, imp := range ctx.Imports {
+		identifier := imp.GetIdentifier()
+
+		// For blank imports, we still show them as available packages
+		// The AI doesn't need to know about the blank import detail
+		if imp.Path == identifier {
+			// Standard library or simple package
+			data.Imports = append(data.Imports, imp.Path)
+		} else if imp.Alias != "" && imp.Alias != "_" && imp.Alias != identifier {
+			// Custom alias (excluding blank imports)
+			data.Imports = append(data.Imports, fmt.Sprintf("%s \"%s\"", imp.Alias, imp.Path))
+		} else {
+			// Package with different identifier
+			data.Imports = append(data.Imports, fmt.Sprintf("%s \"%s\"", identifier, imp.Path))
+		}
+	}
+
+	// Sort types so the prompt is stable across runs
+	typeNames := make([]string, 0, len(ctx.Types))
+	for name := range ctx.Types {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		typeData := TypeData{Name: name, Definition: ctx.Types[name], Doc: docComment(ctx.TypeDocs[name])}
+		for _, method := range ctx.Methods[name] {
+			signature := method.Signature
+			if method.Promoted != "" {
+				signature += " (promoted from embedded " + method.Promoted + ")"
+			}
+			if method.Doc != "" {
+				signature += " // " + strings.Join(strings.Fields(method.Doc), " ")
+			}
+			typeData.Methods = append(typeData.Methods, signature)
+		}
+		data.Types = append(data.Types, typeData)
+	}
+
+	data.Constants = ctx.Constants
+	data.Receiver = ctx.Receiver
+
+	if ctx.Constructor != nil {
+		data.Constructor = ctx.Constructor
+		data.ConstructorGuidelines = constructorGuidelines(target)
+	}
+
+	return data
+}
+
+// constructorGuidelines describes how to wire the type built by a constructor target
+func constructorGuidelines(target *parser.Target) []string {
+	guidelines := []string{
+		"Assign each dependency parameter to the field of the matching name or type",
+		"Initialize maps, channels and other fields that must not be nil; set documented defaults",
+	}
+	if hasVariadicParam(target) {
+		guidelines = append(guidelines, "Set defaults first, then apply the variadic options in order (for _, opt := range opts { opt(v) })")
+	}
+	if returnsError(target) {
+		guidelines = append(guidelines, "Validate required dependencies (e.g. nil checks) and return an error instead of a partially built value")
+	}
+	return guidelines
+}
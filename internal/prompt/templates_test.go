@@ -0,0 +1,114 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/analysis"
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestLoadTemplates_OverridesSection(t *testing.T) {
+	tmpDir := t.TempDir()
+	override := "<task>\n{{.Instruction}}\n</task>\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "instruction.tmpl"), []byte(override), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	templates, err := LoadTemplates(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	target := &parser.Target{Name: "Add", Instruction: "Add two numbers"}
+	ctx := &pkgcontext.RelevantContext{Types: map[string]string{"B": "type B int", "A": "type A int"}}
+	prompt, err := templates.Render(newTemplateData(ctx, target, ""))
+	if err != nil {
+		t.Fatalf("Failed to render prompt: %v", err)
+	}
+
+	if !strings.Contains(prompt, "<task>\nAdd two numbers\n</task>\n") || strings.Contains(prompt, "<instruction>") {
+		t.Errorf("Instruction section was not overridden:\n%s", prompt)
+	}
+	// Sections that were not overridden keep the built-in layout
+	if !strings.Contains(prompt, "<target>\n```go\nfunc Add() {") {
+		t.Errorf("Expected built-in target section:\n%s", prompt)
+	}
+	if strings.Index(prompt, "type A int") > strings.Index(prompt, "type B int") {
+		t.Errorf("Expected types sorted by name:\n%s", prompt)
+	}
+}
+
+func TestDefaultTemplates_Docs(t *testing.T) {
+	ctx := &pkgcontext.RelevantContext{
+		Types:    map[string]string{"Cache": "type Cache struct{}"},
+		TypeDocs: map[string]string{"Cache": "Cache holds entries.\n\nThe zero value is not usable."},
+		Methods: map[string][]analysis.MethodInfo{"Cache": {
+			{Name: "Len", Signature: "Len() int", Doc: "Len returns the number of entries.\nIt does not count expired ones."},
+			{Name: "Reset", Signature: "Reset()"},
+			{Name: "Grow", Signature: "Grow(n int)", Promoted: "*strings.Builder"},
+		}},
+	}
+	prompt, err := DefaultTemplates().Render(newTemplateData(ctx, &parser.Target{Name: "Put"}, ""))
+	if err != nil {
+		t.Fatalf("Failed to render prompt: %v", err)
+	}
+
+	for _, want := range []string{
+		"```go\n// Cache holds entries.\n//\n// The zero value is not usable.\ntype Cache struct{}\n```",
+		"- Len() int // Len returns the number of entries. It does not count expired ones.\n",
+		"- Reset()\n",
+		"- Grow(n int) (promoted from embedded *strings.Builder)\n",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestLoadTemplates_MissingDirUsesDefaults(t *testing.T) {
+	templates, err := LoadTemplates(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	target := &parser.Target{Name: "Add", Instruction: "Add two numbers"}
+	got, err := templates.Render(newTemplateData(&pkgcontext.RelevantContext{}, target, ""))
+	if err != nil {
+		t.Fatalf("Failed to render prompt: %v", err)
+	}
+	want, err := DefaultTemplates().Render(newTemplateData(&pkgcontext.RelevantContext{}, target, ""))
+	if err != nil {
+		t.Fatalf("Failed to render prompt: %v", err)
+	}
+	if got != want {
+		t.Errorf("Expected default prompt, got:\n%s", got)
+	}
+}
+
+func TestDefaultTemplates_Region(t *testing.T) {
+	target := &parser.Target{
+		Name:        "Handle",
+		Params:      []parser.Param{{Name: "req", Type: "*Request"}},
+		Returns:     []parser.Return{{Type: "error"}},
+		Instruction: "reject requests without a name",
+		Region: &parser.Region{
+			Name:   "validate",
+			Before: "\tlog.Println(req)\n\t// mantra:region validate reject requests without a name\n",
+			After:  "\t// mantra:end\n\treturn nil",
+			Indent: "\t",
+		},
+	}
+	prompt, err := DefaultTemplates().Render(newTemplateData(&pkgcontext.RelevantContext{}, target, ""))
+	if err != nil {
+		t.Fatalf("Failed to render prompt: %v", err)
+	}
+
+	want := "```go\nfunc Handle(req *Request) error {\n\tlog.Println(req)\n\t// mantra:region validate reject requests without a name\n\t<IMPLEMENT_HERE>\n\t// mantra:end\n\treturn nil\n}\n```"
+	if !strings.Contains(prompt, want) {
+		t.Errorf("Expected the hand-written body around the region:\n%s", prompt)
+	}
+}
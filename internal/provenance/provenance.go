@@ -0,0 +1,175 @@
+package provenance
+
+import (
+	"context"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMinTokens is the default length of a verbatim token run that is reported
+const DefaultMinTokens = 60
+
+// Location identifies where an indexed token window starts
+type Location struct {
+	File string
+	Line int
+}
+
+// Match describes a run of generated code found verbatim in the corpus
+type Match struct {
+	Source Location // First matching location in the corpus
+	Line   int      // Line in the generated body where the match starts
+	Tokens int      // Length of the matching run in tokens
+}
+
+// String formats the match for logs and failure messages
+func (m Match) String() string {
+	return fmt.Sprintf("body line %d matches %s:%d (%d tokens)", m.Line, m.Source.File, m.Source.Line, m.Tokens)
+}
+
+// Scanner detects long verbatim token sequences shared with a corpus of Go sources
+type Scanner struct {
+	minTokens int
+	windows   map[uint64]Location
+	block     bool
+}
+
+// NewScanner indexes all .go files under the given roots. Missing roots are skipped.
+// If block is true, Blocking reports that matches should fail generation
+func NewScanner(ctx context.Context, roots []string, minTokens int, block bool) (*Scanner, error) {
+	if minTokens <= 0 {
+		minTokens = DefaultMinTokens
+	}
+
+	s := &Scanner{
+		minTokens: minTokens,
+		windows:   make(map[uint64]Location),
+		block:     block,
+	}
+
+	for _, root := range roots {
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".go") {
+				return nil
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			s.index(path, src)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to index corpus %s: %w", root, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Blocking reports whether matches should fail generation rather than only be reported
+func (s *Scanner) Blocking() bool {
+	return s != nil && s.block
+}
+
+// Scan returns the verbatim runs of at least minTokens tokens shared between body and the corpus
+func (s *Scanner) Scan(body string) []Match {
+	if s == nil || len(s.windows) == 0 {
+		return nil
+	}
+
+	toks := tokenize([]byte(body))
+	var matches []Match
+	for i := 0; i+s.minTokens <= len(toks); i++ {
+		loc, ok := s.windows[hashWindow(toks[i:i+s.minTokens])]
+		if !ok {
+			continue
+		}
+
+		// Extend over consecutive matching windows so one copied block is reported once
+		end := i + 1
+		for end+s.minTokens <= len(toks) {
+			if _, ok := s.windows[hashWindow(toks[end:end+s.minTokens])]; !ok {
+				break
+			}
+			end++
+		}
+
+		matches = append(matches, Match{
+			Source: loc,
+			Line:   toks[i].line,
+			Tokens: end - i + s.minTokens - 1,
+		})
+		i = end + s.minTokens - 2
+	}
+
+	return matches
+}
+
+// index records every token window of src
+func (s *Scanner) index(path string, src []byte) {
+	toks := tokenize(src)
+	for i := 0; i+s.minTokens <= len(toks); i++ {
+		h := hashWindow(toks[i : i+s.minTokens])
+		if _, exists := s.windows[h]; !exists {
+			s.windows[h] = Location{File: path, Line: toks[i].line}
+		}
+	}
+}
+
+// tok is a normalized source token
+type tok struct {
+	text string
+	line int
+}
+
+// tokenize splits Go source into tokens, ignoring comments, whitespace and semicolons
+func tokenize(src []byte) []tok {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var sc scanner.Scanner
+	sc.Init(file, src, nil, 0)
+
+	var toks []tok
+	for {
+		pos, t, lit := sc.Scan()
+		if t == token.EOF {
+			break
+		}
+		if t == token.SEMICOLON {
+			continue
+		}
+		text := t.String()
+		if lit != "" {
+			text = lit
+		}
+		toks = append(toks, tok{text: text, line: fset.Position(pos).Line})
+	}
+	return toks
+}
+
+// hashWindow hashes a window of tokens
+func hashWindow(toks []tok) uint64 {
+	h := fnv.New64a()
+	for _, t := range toks {
+		h.Write([]byte(t.text))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
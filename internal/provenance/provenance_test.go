@@ -0,0 +1,49 @@
+package provenance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanReportsVerbatimRuns(t *testing.T) {
+	corpus := t.TempDir()
+	source := `package lib
+
+// Reverse reverses a slice in place
+func Reverse(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(corpus, "lib.go"), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write corpus file: %v", err)
+	}
+
+	scanner, err := NewScanner(context.Background(), []string{corpus, filepath.Join(corpus, "missing")}, 20, true)
+	if err != nil {
+		t.Fatalf("Failed to build scanner: %v", err)
+	}
+
+	// Copied loop with different formatting and comments still matches
+	copied := `
+	// swap elements
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] =
+			s[j], s[i]
+	}
+	return s`
+	matches := scanner.Scan(copied)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %+v", matches)
+	}
+	if matches[0].Line != 3 || matches[0].Source.Line != 5 {
+		t.Errorf("Unexpected match location: %+v", matches[0])
+	}
+
+	if matches := scanner.Scan("return strings.ToUpper(s)"); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %+v", matches)
+	}
+}
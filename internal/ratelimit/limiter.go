@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// burstWindow is how much of the per-minute budget may be spent at once.
+// Keeping it well below a minute spreads the initial burst of parallel targets
+const burstWindow = 10 * time.Second
+
+// Limiter paces requests to stay under requests-per-minute and tokens-per-minute limits.
+// Callers are served in the order they call Wait. It is safe for concurrent use
+type Limiter struct {
+	mu       sync.Mutex
+	requests *bucket // nil when requests are unlimited
+	tokens   *bucket // nil when tokens are unlimited
+	now      func() time.Time
+}
+
+// bucket is a token bucket whose balance may go negative to queue reservations
+type bucket struct {
+	perSecond float64
+	capacity  float64
+	available float64
+	last      time.Time
+}
+
+// New creates a limiter; a limit of zero or less disables that dimension.
+// It returns nil when both limits are disabled, and a nil *Limiter never waits
+func New(requestsPerMinute, tokensPerMinute int) *Limiter {
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		return nil
+	}
+
+	l := &Limiter{now: time.Now}
+	l.requests = newBucket(requestsPerMinute, l.now())
+	l.tokens = newBucket(tokensPerMinute, l.now())
+	return l
+}
+
+// newBucket creates a full bucket for a per-minute limit, or nil when unlimited
+func newBucket(perMinute int, now time.Time) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	perSecond := float64(perMinute) / 60
+	capacity := max(perSecond*burstWindow.Seconds(), 1)
+	return &bucket{
+		perSecond: perSecond,
+		capacity:  capacity,
+		available: capacity,
+		last:      now,
+	}
+}
+
+// refill adds the budget accumulated since the last update
+func (b *bucket) refill(now time.Time) {
+	b.available = min(b.available+now.Sub(b.last).Seconds()*b.perSecond, b.capacity)
+	b.last = now
+}
+
+// reserve takes n from the bucket and returns how long the caller must wait for it
+func (b *bucket) reserve(n float64, now time.Time) time.Duration {
+	b.refill(now)
+	b.available -= n
+	if b.available >= 0 {
+		return 0
+	}
+	return time.Duration(-b.available / b.perSecond * float64(time.Second))
+}
+
+// Wait blocks until a request estimated to use estimatedTokens may be sent and returns how long it waited
+func (l *Limiter) Wait(ctx context.Context, estimatedTokens int) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	var delay time.Duration
+	if l.requests != nil {
+		delay = max(delay, l.requests.reserve(1, now))
+	}
+	if l.tokens != nil {
+		delay = max(delay, l.tokens.reserve(float64(estimatedTokens), now))
+	}
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		l.release(estimatedTokens)
+		return 0, ctx.Err()
+	}
+}
+
+// Record corrects the token budget once the actual usage of a request is known
+func (l *Limiter) Record(estimatedTokens, actualTokens int) {
+	if l == nil || l.tokens == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.refill(l.now())
+	l.tokens.available -= float64(actualTokens - estimatedTokens)
+}
+
+// release returns a reservation that was not used
+func (l *Limiter) release(estimatedTokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.requests != nil {
+		l.requests.available++
+	}
+	if l.tokens != nil {
+		l.tokens.available += float64(estimatedTokens)
+	}
+}
+
+// EstimateTokens roughly estimates the tokens in a request payload of the given size in bytes
+func EstimateTokens(payloadBytes int) int {
+	return payloadBytes / 4
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterReservations(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(60, 0) // 1 request per second, bursts of 10
+	l.now = func() time.Time { return now }
+	l.requests.last = now
+	l.requests.available = l.requests.capacity
+
+	// The burst is served immediately
+	for i := 0; i < 10; i++ {
+		if delay := l.requests.reserve(1, now); delay != 0 {
+			t.Fatalf("Request %d delayed by %v within burst", i, delay)
+		}
+	}
+
+	// Further requests queue one second apart
+	if delay := l.requests.reserve(1, now); delay != time.Second {
+		t.Errorf("Expected 1s delay, got %v", delay)
+	}
+	if delay := l.requests.reserve(1, now); delay != 2*time.Second {
+		t.Errorf("Expected 2s delay, got %v", delay)
+	}
+
+	// Budget refills over time
+	now = now.Add(5 * time.Second)
+	if delay := l.requests.reserve(1, now); delay != 0 {
+		t.Errorf("Expected no delay after refill, got %v", delay)
+	}
+}
+
+func TestLimiterTokensRecord(t *testing.T) {
+	l := New(0, 600) // 10 tokens per second, bursts of 100
+	if l.requests != nil {
+		t.Fatalf("Expected unlimited requests")
+	}
+
+	if waited, err := l.Wait(context.Background(), 100); err != nil || waited != 0 {
+		t.Fatalf("Expected first request to pass, waited %v: %v", waited, err)
+	}
+
+	// The request used far more than estimated; the next one must wait
+	l.Record(100, 150)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Wait(ctx, 10); err == nil {
+		t.Errorf("Expected cancellation while waiting for token budget")
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	l := New(0, 0)
+	if l != nil {
+		t.Fatalf("Expected nil limiter when unlimited")
+	}
+	if waited, err := l.Wait(context.Background(), 1000); err != nil || waited != 0 {
+		t.Errorf("Nil limiter should never wait")
+	}
+	l.Record(1, 2)
+}
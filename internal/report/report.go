@@ -0,0 +1,261 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// Target status values used in the report
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Report summarizes a single generation run
+type Report struct {
+	Package          string          `json:"package"`
+	StartedAt        time.Time       `json:"started_at"`
+	Duration         time.Duration   `json:"duration"`
+	Targets          []TargetSummary `json:"targets"`
+	UpToDate         int             `json:"up_to_date"`    // Targets skipped because they were current
+	FilesWritten     []string        `json:"files_written"` // Destination files written during the run
+	BlankImports     []ImportChange  `json:"blank_imports_converted,omitempty"`
+	PromptTokens     int             `json:"prompt_tokens"`
+	CompletionTokens int             `json:"completion_tokens"`
+	CachedTokens     int             `json:"cached_tokens,omitempty"` // Prompt tokens served from the provider's prompt cache
+}
+
+// TargetSummary summarizes the outcome for a single target
+type TargetSummary struct {
+	Name             string         `json:"name"`
+	File             string         `json:"file"`
+	Status           string         `json:"status"`
+	Duration         time.Duration  `json:"duration"`
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	CachedTokens     int            `json:"cached_tokens,omitempty"`
+	Rounds           int            `json:"rounds"`
+	ToolCalls        map[string]int `json:"tool_calls,omitempty"`
+	Provider         string         `json:"provider,omitempty"`
+	Degraded         bool           `json:"degraded,omitempty"` // Generated with a single prompt, as the model does not call tools
+	FailurePhase     string         `json:"failure_phase,omitempty"`
+	FailureMessage   string         `json:"failure_message,omitempty"`
+}
+
+// ImportChange records a blank import turned into a regular import in a generated file
+type ImportChange struct {
+	File string `json:"file"`
+	Path string `json:"path"`
+}
+
+// New creates a report for a run of the given package
+func New(pkg string, startedAt time.Time) *Report {
+	return &Report{
+		Package:   pkg,
+		StartedAt: startedAt,
+	}
+}
+
+// AddResults records generation results in the report
+func (r *Report) AddResults(results []*parser.GenerationResult) {
+	for _, result := range results {
+		summary := TargetSummary{
+			Name:             result.Target.GetDisplayName(),
+			File:             filepath.Base(result.Target.FilePath),
+			Status:           StatusCompleted,
+			Duration:         result.Duration,
+			PromptTokens:     result.PromptTokens,
+			CompletionTokens: result.CompletionTokens,
+			CachedTokens:     result.CachedTokens,
+			Rounds:           result.Rounds,
+			ToolCalls:        result.ToolCalls,
+			Provider:         result.Provider,
+			Degraded:         result.Degraded,
+		}
+		if !result.Success {
+			summary.Status = StatusFailed
+			if result.FailureReason != nil {
+				summary.FailurePhase = result.FailureReason.Phase
+				summary.FailureMessage = result.FailureReason.Message
+			}
+		}
+		r.Targets = append(r.Targets, summary)
+		r.PromptTokens += result.PromptTokens
+		r.CompletionTokens += result.CompletionTokens
+		r.CachedTokens += result.CachedTokens
+	}
+}
+
+// AddFile records a destination file written during the run
+func (r *Report) AddFile(path string) {
+	r.FilesWritten = append(r.FilesWritten, path)
+}
+
+// AddBlankImportConversion records a blank import converted in a generated file
+func (r *Report) AddBlankImportConversion(file, importPath string) {
+	r.BlankImports = append(r.BlankImports, ImportChange{File: file, Path: importPath})
+}
+
+// Finish records the total duration of the run
+func (r *Report) Finish() {
+	r.Duration = time.Since(r.StartedAt).Round(time.Millisecond)
+}
+
+// Succeeded returns the number of successfully generated targets
+func (r *Report) Succeeded() int {
+	count := 0
+	for _, t := range r.Targets {
+		if t.Status == StatusCompleted {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of failed targets
+func (r *Report) Failed() int {
+	return len(r.Targets) - r.Succeeded()
+}
+
+// WriteText writes a plain text summary suitable for the terminal
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "=== Summary: %d succeeded, %d failed, %d up-to-date (%s) ===\n",
+		r.Succeeded(), r.Failed(), r.UpToDate, r.Duration)
+
+	for _, t := range r.Targets {
+		icon := "[OK]"
+		if t.Status == StatusFailed {
+			icon = "[FAIL]"
+		}
+		fmt.Fprintf(w, "  %-6s %s (%s, %s tokens, %d rounds)\n", icon, t.Name, t.Duration, formatTokens(t.PromptTokens, t.CompletionTokens), t.Rounds)
+		if len(t.ToolCalls) > 0 {
+			fmt.Fprintf(w, "         tools: %s\n", formatToolCalls(t.ToolCalls))
+		}
+		if t.Degraded {
+			fmt.Fprintln(w, "         degraded: the model does not call tools, generated with a single prompt")
+		}
+		if t.FailureMessage != "" {
+			fmt.Fprintf(w, "         %s: %s\n", t.FailurePhase, t.FailureMessage)
+		}
+	}
+
+	if r.PromptTokens > 0 || r.CompletionTokens > 0 {
+		fmt.Fprintf(w, "Tokens: %s%s\n", formatTokens(r.PromptTokens, r.CompletionTokens), formatCached(r.CachedTokens))
+	}
+
+	if len(r.FilesWritten) > 0 {
+		fmt.Fprintln(w, "Files written:")
+		for _, f := range r.FilesWritten {
+			fmt.Fprintf(w, "  %s\n", f)
+		}
+	}
+
+	if len(r.BlankImports) > 0 {
+		fmt.Fprintln(w, "Blank imports converted:")
+		for _, c := range r.BlankImports {
+			fmt.Fprintf(w, "  %s: %s\n", c.File, c.Path)
+		}
+	}
+}
+
+// Markdown renders the summary as a markdown document
+func (r *Report) Markdown() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# mantra run summary: %s\n\n", r.Package))
+	sb.WriteString(fmt.Sprintf("- Started: %s\n", r.StartedAt.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("- Duration: %s\n", r.Duration))
+	sb.WriteString(fmt.Sprintf("- Succeeded: %d\n", r.Succeeded()))
+	sb.WriteString(fmt.Sprintf("- Failed: %d\n", r.Failed()))
+	sb.WriteString(fmt.Sprintf("- Up-to-date: %d\n", r.UpToDate))
+	sb.WriteString(fmt.Sprintf("- Tokens: %s%s\n\n", formatTokens(r.PromptTokens, r.CompletionTokens), formatCached(r.CachedTokens)))
+
+	if len(r.Targets) > 0 {
+		sb.WriteString("## Targets\n\n")
+		sb.WriteString("| Target | File | Status | Duration | Prompt tokens | Completion tokens | Rounds | Tool calls | Provider | Failure |\n")
+		sb.WriteString("|---|---|---|---|---|---|---|---|---|---|\n")
+		for _, t := range r.Targets {
+			status := t.Status
+			if t.Degraded {
+				status += " (degraded)"
+			}
+			failure := ""
+			if t.FailureMessage != "" {
+				failure = fmt.Sprintf("%s: %s", t.FailurePhase, escapeTableCell(t.FailureMessage))
+			}
+			sb.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s | %d | %d | %d | %s | %s | %s |\n",
+				t.Name, t.File, status, t.Duration, t.PromptTokens, t.CompletionTokens,
+				t.Rounds, formatToolCalls(t.ToolCalls), t.Provider, failure))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(r.FilesWritten) > 0 {
+		sb.WriteString("## Files written\n\n")
+		for _, f := range r.FilesWritten {
+			sb.WriteString(fmt.Sprintf("- `%s`\n", f))
+		}
+	}
+
+	if len(r.BlankImports) > 0 {
+		sb.WriteString("\n## Blank imports converted\n\n")
+		for _, c := range r.BlankImports {
+			sb.WriteString(fmt.Sprintf("- `%s`: `%s`\n", c.File, c.Path))
+		}
+	}
+
+	return sb.String()
+}
+
+// WriteMarkdownFile writes the markdown summary to the given path
+func (r *Report) WriteMarkdownFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create summary directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(r.Markdown()), 0644); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	return nil
+}
+
+// formatTokens formats prompt/completion token counts
+func formatTokens(prompt, completion int) string {
+	return fmt.Sprintf("%d in / %d out", prompt, completion)
+}
+
+// formatCached formats the prompt tokens served from cache, if any (e.g. " (12000 cached)")
+func formatCached(cached int) string {
+	if cached == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d cached)", cached)
+}
+
+// formatToolCalls formats per-tool call counts sorted by tool name (e.g. "check_code=2, inspect=3")
+func formatToolCalls(calls map[string]int) string {
+	names := make([]string, 0, len(calls))
+	for name := range calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, calls[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// escapeTableCell makes a string safe for use inside a markdown table cell
+func escapeTableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
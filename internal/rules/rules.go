@@ -0,0 +1,416 @@
+// Package rules synthesizes the bodies of trivial targets, such as getters and setters,
+// deterministically from the declarations of the source package, without calling a model
+package rules
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rail44/mantra/internal/analysis"
+	pkgparser "github.com/rail44/mantra/internal/parser"
+)
+
+// Names of the rules, reported with a synthesized body
+const (
+	RuleGetter    = "getter"
+	RuleSetter    = "setter"
+	RuleDelegate  = "delegate"
+	RuleMapGet    = "map get"
+	RuleMapSet    = "map set"
+	RuleMapDelete = "map delete"
+	RuleMapHas    = "map has"
+)
+
+// maxWords is the longest instruction a rule is applied to. Longer instructions usually
+// carry conditions the rules cannot express
+const maxWords = 12
+
+// qualifiers are words that make an instruction more than a plain access
+var qualifiers = map[string]bool{
+	"if": true, "unless": true, "when": true, "while": true, "or": true, "but": true,
+	"only": true, "except": true, "after": true, "before": true, "and": true, "then": true,
+	"validate": true, "validates": true, "error": true, "errors": true, "lock": true,
+	"locks": true, "copy": true, "copies": true, "sorted": true, "trimmed": true,
+	"default": true, "otherwise": true, "increment": true, "increments": true,
+}
+
+// Verbs opening the instruction of each kind of target
+var (
+	getVerbs      = wordSet("return", "returns", "get", "gets", "fetch", "fetches", "lookup", "look", "looks", "find", "finds")
+	setVerbs      = wordSet("set", "sets", "assign", "assigns", "store", "stores", "put", "puts", "add", "adds", "insert", "inserts")
+	deleteVerbs   = wordSet("delete", "deletes", "remove", "removes")
+	hasVerbs      = wordSet("has", "have", "contains", "contain", "check", "checks", "report", "reports", "exists", "whether")
+	delegateVerbs = wordSet("delegate", "delegates", "forward", "forwards")
+)
+
+// Package holds the struct types and methods of a source package
+type Package struct {
+	structs map[string]*ast.StructType
+	methods map[string]map[string]*ast.FuncType // Signatures by receiver type and method name
+	aliases map[string]string
+}
+
+// Load parses the non-test files of the package in dir
+func Load(dir string) (*Package, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob files: %w", err)
+	}
+	sort.Strings(paths)
+
+	p := &Package{
+		structs: make(map[string]*ast.StructType),
+		methods: make(map[string]map[string]*ast.FuncType),
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		files = append(files, file)
+
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil || len(d.Recv.List) == 0 {
+					continue
+				}
+				recv := analysis.ReceiverTypeName(d.Recv.List[0].Type)
+				if p.methods[recv] == nil {
+					p.methods[recv] = make(map[string]*ast.FuncType)
+				}
+				p.methods[recv][d.Name.Name] = d.Type
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						if st, ok := ts.Type.(*ast.StructType); ok {
+							p.structs[ts.Name.Name] = st
+						}
+					}
+				}
+			}
+		}
+	}
+	p.aliases = analysis.TypeAliases(files...)
+	return p, nil
+}
+
+// field is one field of a receiver struct
+type field struct {
+	name     string
+	typ      ast.Expr
+	embedded bool
+}
+
+// Synthesize returns the body of a target whose instruction and signature match one of
+// the rules, with the name of that rule. ok is false when no rule applies unambiguously
+func (p *Package) Synthesize(target *pkgparser.Target) (body, rule string, ok bool) {
+	if p == nil || target.Receiver == nil || target.FuncDecl == nil || target.FuncDecl.Recv == nil {
+		return "", "", false
+	}
+	recv := target.Receiver.Name
+	if recv == "" || recv == "_" {
+		return "", "", false
+	}
+	for _, param := range target.Params {
+		if param.Name == "" || param.Name == "_" {
+			return "", "", false
+		}
+	}
+
+	words := instructionWords(target.Instruction)
+	if len(words) == 0 || len(words) > maxWords {
+		return "", "", false
+	}
+	for _, word := range words {
+		if qualifiers[word] {
+			return "", "", false
+		}
+	}
+
+	// Fields of a receiver holding a mutex may only be accessed under it, which no rule does
+	fields := p.fields(target.FuncDecl.Recv.List[0].Type)
+	if slices.ContainsFunc(fields, isMutex) {
+		return "", "", false
+	}
+	verb := words[0]
+	switch {
+	case delegateVerbs[verb]:
+		return p.delegate(target, recv, fields, words)
+	case len(target.Params) == 0 && len(target.Returns) == 1 && getVerbs[verb]:
+		if f := namedField(fields, target, words, "Get"); f != nil && analysis.ExtractTypeString(f.typ) == target.Returns[0].Type {
+			return fmt.Sprintf("return %s.%s", recv, f.name), RuleGetter, true
+		}
+	case len(target.Params) == 1 && len(target.Returns) == 0 && setVerbs[verb] && isPointer(target.FuncDecl.Recv.List[0].Type):
+		if f := namedField(fields, target, words, "Set"); f != nil && analysis.ExtractTypeString(f.typ) == target.Params[0].Type {
+			return fmt.Sprintf("%s.%s = %s", recv, f.name, target.Params[0].Name), RuleSetter, true
+		}
+	}
+	return mapAccess(target, recv, isPointer(target.FuncDecl.Recv.List[0].Type), fields, words)
+}
+
+// fields returns the fields of the struct a receiver belongs to
+func (p *Package) fields(recv ast.Expr) []field {
+	name := analysis.ReceiverTypeName(recv)
+	if alias, ok := p.aliases[name]; ok {
+		name = alias
+	}
+	st := p.structs[name]
+	if st == nil {
+		return nil
+	}
+
+	var fields []field
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			fields = append(fields, field{name: embeddedName(f.Type), typ: f.Type, embedded: true})
+			continue
+		}
+		for _, n := range f.Names {
+			fields = append(fields, field{name: n.Name, typ: f.Type})
+		}
+	}
+	return fields
+}
+
+// mapAccess matches get, set, delete and membership tests on a map field. Sets create the
+// map when it is nil, so they need a pointer receiver
+func mapAccess(target *pkgparser.Target, recv string, pointer bool, fields []field, words []string) (string, string, bool) {
+	verb := words[0]
+	params, returns := target.Params, target.Returns
+
+	// Mentioned map fields win; otherwise the only map field keyed by the first parameter
+	var candidates []*field
+	for _, mentioned := range []bool{true, false} {
+		for i := range fields {
+			f := &fields[i]
+			mt, ok := f.typ.(*ast.MapType)
+			if !ok || len(params) == 0 || analysis.ExtractTypeString(mt.Key) != params[0].Type {
+				continue
+			}
+			if !mentioned || mentions(words, f.name) {
+				candidates = append(candidates, f)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+	if len(candidates) != 1 {
+		return "", "", false
+	}
+	f := candidates[0]
+	value := analysis.ExtractTypeString(f.typ.(*ast.MapType).Value)
+	m := recv + "." + f.name
+	key := params[0].Name
+
+	switch {
+	case getVerbs[verb] && len(params) == 1 && len(returns) == 1 && returns[0].Type == value:
+		return fmt.Sprintf("return %s[%s]", m, key), RuleMapGet, true
+	case getVerbs[verb] && len(params) == 1 && len(returns) == 2 && returns[0].Type == value && returns[1].Type == "bool" && unused(target, "value", "ok"):
+		return fmt.Sprintf("value, ok := %s[%s]\nreturn value, ok", m, key), RuleMapGet, true
+	case setVerbs[verb] && len(params) == 2 && len(returns) == 0 && params[1].Type == value && pointer:
+		return fmt.Sprintf("if %s == nil {\n\t%s = make(%s)\n}\n%s[%s] = %s", m, m, analysis.ExtractTypeString(f.typ), m, key, params[1].Name), RuleMapSet, true
+	case deleteVerbs[verb] && len(params) == 1 && len(returns) == 0:
+		return fmt.Sprintf("delete(%s, %s)", m, key), RuleMapDelete, true
+	case hasVerbs[verb] && len(params) == 1 && len(returns) == 1 && returns[0].Type == "bool" && unused(target, "ok"):
+		return fmt.Sprintf("_, ok := %s[%s]\nreturn ok", m, key), RuleMapHas, true
+	}
+	return "", "", false
+}
+
+// delegate forwards the call to the method of the same name and signature on an embedded field
+func (p *Package) delegate(target *pkgparser.Target, recv string, fields []field, words []string) (string, string, bool) {
+	var candidates []*field
+	for _, mentioned := range []bool{true, false} {
+		for i := range fields {
+			f := &fields[i]
+			if !f.embedded || (mentioned && !mentions(words, f.name)) {
+				continue
+			}
+			if method := p.method(f.name, target.Name); method != nil && sameSignature(method, target.FuncDecl.Type) {
+				candidates = append(candidates, f)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+	if len(candidates) != 1 {
+		return "", "", false
+	}
+
+	args := make([]string, len(target.Params))
+	for i, param := range target.Params {
+		args[i] = param.Name
+		if strings.HasPrefix(param.Type, "...") {
+			args[i] += "..."
+		}
+	}
+	call := fmt.Sprintf("%s.%s.%s(%s)", recv, candidates[0].name, target.Name, strings.Join(args, ", "))
+	if len(target.Returns) > 0 {
+		call = "return " + call
+	}
+	return call, RuleDelegate, true
+}
+
+// method returns the signature of a method declared in the package on typeName
+func (p *Package) method(typeName, name string) *ast.FuncType {
+	if alias, ok := p.aliases[typeName]; ok {
+		typeName = alias
+	}
+	return p.methods[typeName][name]
+}
+
+// namedField returns the field a getter or setter accesses: the one named by the method
+// (GetName, SetName or Name) or else the only field the instruction mentions
+func namedField(fields []field, target *pkgparser.Target, words []string, prefix string) *field {
+	name := strings.TrimPrefix(target.Name, prefix)
+	for i := range fields {
+		if !fields[i].embedded && strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+
+	var found *field
+	for i := range fields {
+		if fields[i].embedded || !mentions(words, fields[i].name) {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = &fields[i]
+	}
+	return found
+}
+
+// sameSignature reports whether two function types have the same parameter and result types
+func sameSignature(a, b *ast.FuncType) bool {
+	return slices.Equal(fieldTypes(a.Params), fieldTypes(b.Params)) &&
+		slices.Equal(fieldTypes(a.Results), fieldTypes(b.Results))
+}
+
+// fieldTypes returns one type per parameter or result, repeating grouped types
+func fieldTypes(list *ast.FieldList) []string {
+	if list == nil {
+		return nil
+	}
+	var types []string
+	for _, f := range list.List {
+		n := max(len(f.Names), 1)
+		for range n {
+			types = append(types, analysis.ExtractTypeString(f.Type))
+		}
+	}
+	return types
+}
+
+// embeddedName returns the field name of an embedded type: T for T, *T and pkg.T
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// isMutex reports whether a field is, or points to, a sync.Mutex or sync.RWMutex
+func isMutex(f field) bool {
+	typ := f.typ
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	sel, ok := typ.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Mutex" && sel.Sel.Name != "RWMutex" {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "sync"
+}
+
+func isPointer(expr ast.Expr) bool {
+	for {
+		switch t := expr.(type) {
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.StarExpr:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// unused reports whether none of names is taken by the receiver, a parameter or a named result
+func unused(target *pkgparser.Target, names ...string) bool {
+	for _, name := range names {
+		if target.Receiver.Name == name {
+			return false
+		}
+		for _, param := range target.Params {
+			if param.Name == name {
+				return false
+			}
+		}
+		for _, ret := range target.Returns {
+			if ret.Name == name {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mentions reports whether the instruction words include name, alone or selected (s.name),
+// ignoring case
+func mentions(words []string, name string) bool {
+	for _, word := range words {
+		if _, sel, ok := strings.Cut(word, "."); ok {
+			word = sel
+		}
+		if strings.EqualFold(word, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// instructionWords splits an instruction into lower-case words without punctuation
+func instructionWords(instruction string) []string {
+	var result []string
+	for _, word := range strings.Fields(instruction) {
+		word = strings.ToLower(strings.Trim(word, "`\"'.,;:!?()"))
+		if word != "" {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+func wordSet(list ...string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, w := range list {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestSynthesize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "types.go"), []byte(`package store
+
+import "sync"
+
+type Logger struct{}
+
+func (l *Logger) Log(format string, args ...any) {}
+
+type Store struct {
+	*Logger
+	name  string
+	items map[string]int
+}
+
+type Registry = Store
+
+type Counter struct {
+	mu    sync.Mutex
+	total int
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write types: %v", err)
+	}
+	source := filepath.Join(dir, "store.go")
+	if err := os.WriteFile(source, []byte(`package store
+
+// mantra: Return the name
+func (s *Store) Name() string {
+	panic("not implemented")
+}
+
+// mantra: Set the name
+func (s *Store) SetName(name string) {
+	panic("not implemented")
+}
+
+// mantra: Look up the count of an item
+func (s *Store) Count(key string) (int, bool) {
+	panic("not implemented")
+}
+
+// mantra: Store the count of an item
+func (s *Store) Put(key string, count int) {
+	panic("not implemented")
+}
+
+// mantra: Remove an item from items
+func (r *Registry) Remove(key string) {
+	panic("not implemented")
+}
+
+// mantra: Report whether the item exists
+func (s Store) Has(key string) bool {
+	panic("not implemented")
+}
+
+// mantra: Delegate to the embedded Logger
+func (s *Store) Log(format string, args ...any) {
+	panic("not implemented")
+}
+
+// mantra: Return the name if set, or "anonymous" otherwise
+func (s *Store) DisplayName() string {
+	panic("not implemented")
+}
+
+// mantra: Set the name
+func (s Store) Rename(name string) {
+	panic("not implemented")
+}
+
+// mantra: Return the number of items
+func (s *Store) Len() int {
+	panic("not implemented")
+}
+
+// mantra: Return the total
+func (c *Counter) Total() int {
+	panic("not implemented")
+}
+
+// mantra: Put the count of an item
+func (s Store) Add(key string, count int) {
+	panic("not implemented")
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	fileInfo, err := parser.ParseFileInfo(source)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	pkg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		rule string
+		body string
+	}{
+		{RuleGetter, "return s.name"},
+		{RuleSetter, "s.name = name"},
+		{RuleMapGet, "value, ok := s.items[key]\nreturn value, ok"},
+		{RuleMapSet, "if s.items == nil {\n\ts.items = make(map[string]int)\n}\ns.items[key] = count"},
+		{RuleMapDelete, "delete(r.items, key)"},
+		{RuleMapHas, "_, ok := s.items[key]\nreturn ok"},
+		{RuleDelegate, "s.Logger.Log(format, args...)"},
+		{"", ""}, // Conditional instruction
+		{"", ""}, // Setter on a value receiver
+		{"", ""}, // No matching field
+		{"", ""}, // Receiver holding a mutex
+		{"", ""}, // Map set on a value receiver, which cannot create a nil map
+	}
+	if len(fileInfo.Targets) != len(tests) {
+		t.Fatalf("Expected %d targets, got %d", len(tests), len(fileInfo.Targets))
+	}
+	for i, tt := range tests {
+		target := fileInfo.Targets[i]
+		body, rule, ok := pkg.Synthesize(target)
+		if ok != (tt.rule != "") || rule != tt.rule || body != tt.body {
+			t.Errorf("%s: expected rule %q with body %q, got %q with %q (ok=%v)", target.Name, tt.rule, tt.body, rule, body, ok)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package stats keeps opt-in statistics of generation runs on the local machine: which
+// tools the model calls, how often the calls fail and how many rounds targets take, so
+// prompts and tool descriptions can be tuned against real runs
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+// DefaultFile is where statistics are kept, relative to the project config file
+const DefaultFile = ".mantra/stats.jsonl"
+
+// Record is the outcome of generating one target with a model
+type Record struct {
+	Run        time.Time      `json:"run"` // Start of the run, shared by its records
+	Package    string         `json:"package"`
+	Target     string         `json:"target"`
+	Model      string         `json:"model,omitempty"`
+	Success    bool           `json:"success"`
+	Phase      string         `json:"phase,omitempty"` // Phase the target failed in
+	Rounds     int            `json:"rounds"`
+	ToolCalls  map[string]int `json:"tool_calls,omitempty"`
+	ToolErrors map[string]int `json:"tool_errors,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+}
+
+// NewRecords returns the records of a run's results. Targets written by a rule made no
+// requests and are left out
+func NewRecords(run time.Time, pkg, model string, results []*parser.GenerationResult) []Record {
+	var records []Record
+	for _, result := range results {
+		if result.Rule != "" || result.Target == nil {
+			continue
+		}
+		record := Record{
+			Run:        run,
+			Package:    pkg,
+			Target:     result.Target.GetDisplayName(),
+			Model:      model,
+			Success:    result.Success,
+			Rounds:     result.Rounds,
+			ToolCalls:  result.ToolCalls,
+			ToolErrors: result.ToolErrors,
+			DurationMS: result.Duration.Milliseconds(),
+		}
+		if result.FailureReason != nil {
+			record.Phase = result.FailureReason.Phase
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Append adds records to the file at path, one JSON object per line
+func Append(path string, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create stats directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open stats file: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to write stats: %w", err)
+		}
+	}
+	return f.Close()
+}
+
+// Read returns the records in the file at path, oldest first. A missing file has none
+func Read(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stats file: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stats file: %w", err)
+	}
+	return records, nil
+}
+
+// Filter selects the records to summarize
+type Filter struct {
+	Since   time.Time // Runs started before are left out (zero keeps all)
+	Model   string    // Only records of this model (empty keeps all)
+	Package string    // Only records of this package (empty keeps all)
+}
+
+// Match reports whether the record passes the filter
+func (f Filter) Match(r Record) bool {
+	return !r.Run.Before(f.Since) &&
+		(f.Model == "" || r.Model == f.Model) &&
+		(f.Package == "" || r.Package == f.Package)
+}
+
+// Summary aggregates records across runs
+type Summary struct {
+	Runs      int
+	Targets   int // Generated targets, counted once per run
+	Succeeded int
+	Rounds    int // Requests across all targets
+	MaxRounds int
+	Tools     []ToolSummary  // Most called first
+	Failures  map[string]int // Failed targets by phase
+}
+
+// ToolSummary aggregates the calls of one tool
+type ToolSummary struct {
+	Name    string
+	Calls   int
+	Errors  int
+	Targets int // Targets that called the tool at least once
+}
+
+// AverageRounds returns the mean number of requests per target
+func (s *Summary) AverageRounds() float64 {
+	if s.Targets == 0 {
+		return 0
+	}
+	return float64(s.Rounds) / float64(s.Targets)
+}
+
+// ErrorRate returns the share of calls that returned an error
+func (t ToolSummary) ErrorRate() float64 {
+	if t.Calls == 0 {
+		return 0
+	}
+	return float64(t.Errors) / float64(t.Calls)
+}
+
+// Summarize aggregates the records that pass the filter
+func Summarize(records []Record, filter Filter) *Summary {
+	summary := &Summary{Failures: make(map[string]int)}
+	runs := make(map[time.Time]bool)
+	tools := make(map[string]*ToolSummary)
+	tool := func(name string) *ToolSummary {
+		if tools[name] == nil {
+			tools[name] = &ToolSummary{Name: name}
+		}
+		return tools[name]
+	}
+
+	for _, r := range records {
+		if !filter.Match(r) {
+			continue
+		}
+		runs[r.Run] = true
+		summary.Targets++
+		if r.Success {
+			summary.Succeeded++
+		} else {
+			summary.Failures[r.Phase]++
+		}
+		summary.Rounds += r.Rounds
+		summary.MaxRounds = max(summary.MaxRounds, r.Rounds)
+		for name, n := range r.ToolCalls {
+			t := tool(name)
+			t.Calls += n
+			t.Targets++
+		}
+		for name, n := range r.ToolErrors {
+			tool(name).Errors += n
+		}
+	}
+
+	summary.Runs = len(runs)
+	for _, t := range tools {
+		summary.Tools = append(summary.Tools, *t)
+	}
+	sort.Slice(summary.Tools, func(i, j int) bool {
+		if summary.Tools[i].Calls != summary.Tools[j].Calls {
+			return summary.Tools[i].Calls > summary.Tools[j].Calls
+		}
+		return summary.Tools[i].Name < summary.Tools[j].Name
+	})
+	return summary
+}
@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rail44/mantra/internal/parser"
+)
+
+func TestAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".mantra", "stats.jsonl")
+
+	records, err := Read(path)
+	if err != nil || records != nil {
+		t.Fatalf("Expected no records for a missing file, got %v, %v", records, err)
+	}
+
+	run := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	results := []*parser.GenerationResult{
+		{
+			Target:     &parser.Target{Name: "Load"},
+			Success:    true,
+			Rounds:     3,
+			ToolCalls:  map[string]int{"inspect": 2, "check_code": 1},
+			ToolErrors: map[string]int{"inspect": 1},
+			Duration:   1500 * time.Millisecond,
+		},
+		{
+			Target:        &parser.Target{Name: "Save"},
+			Rounds:        5,
+			FailureReason: &parser.FailureReason{Phase: "implementation", Message: "too many rounds"},
+		},
+		{Target: &parser.Target{Name: "Name"}, Success: true, Rule: "getter"},
+	}
+	if err := Append(path, NewRecords(run, "users", "model-a", results)); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := Append(path, NewRecords(run.Add(time.Hour), "users", "model-b", results[:1])); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	records, err = Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records (rule results left out), got %d", len(records))
+	}
+	first := records[0]
+	if first.Target != "Load" || first.Model != "model-a" || !first.Run.Equal(run) || first.DurationMS != 1500 {
+		t.Errorf("Unexpected first record: %+v", first)
+	}
+	if first.ToolErrors["inspect"] != 1 {
+		t.Errorf("Expected 1 inspect error, got %v", first.ToolErrors)
+	}
+	if records[1].Phase != "implementation" || records[1].Success {
+		t.Errorf("Expected failed record in implementation phase, got %+v", records[1])
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	run := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Run: run, Package: "users", Model: "a", Success: true, Rounds: 2, ToolCalls: map[string]int{"inspect": 3, "check_code": 1}, ToolErrors: map[string]int{"inspect": 1}},
+		{Run: run, Package: "users", Model: "a", Phase: "implementation", Rounds: 6, ToolCalls: map[string]int{"check_code": 3}, ToolErrors: map[string]int{"check_code": 2}},
+		{Run: run.Add(time.Hour), Package: "orders", Model: "b", Success: true, Rounds: 1, ToolCalls: map[string]int{"search": 1}},
+	}
+
+	summary := Summarize(records, Filter{})
+	if summary.Runs != 2 || summary.Targets != 3 || summary.Succeeded != 2 {
+		t.Errorf("Expected 2 runs, 3 targets, 2 succeeded, got %d, %d, %d", summary.Runs, summary.Targets, summary.Succeeded)
+	}
+	if summary.MaxRounds != 6 || summary.AverageRounds() != 3 {
+		t.Errorf("Expected max 6 and average 3 rounds, got %d and %v", summary.MaxRounds, summary.AverageRounds())
+	}
+	if summary.Failures["implementation"] != 1 {
+		t.Errorf("Expected 1 implementation failure, got %v", summary.Failures)
+	}
+
+	var names []string
+	for _, tool := range summary.Tools {
+		names = append(names, tool.Name)
+	}
+	if len(names) != 3 || names[0] != "check_code" || names[1] != "inspect" || names[2] != "search" {
+		t.Fatalf("Expected tools ordered by calls then name, got %v", names)
+	}
+	checkCode := summary.Tools[0]
+	if checkCode.Calls != 4 || checkCode.Errors != 2 || checkCode.Targets != 2 || checkCode.ErrorRate() != 0.5 {
+		t.Errorf("Unexpected check_code summary: %+v", checkCode)
+	}
+
+	filtered := Summarize(records, Filter{Since: run.Add(time.Minute)})
+	if filtered.Targets != 1 || filtered.Tools[0].Name != "search" {
+		t.Errorf("Expected only the later run, got %+v", filtered)
+	}
+	if filtered := Summarize(records, Filter{Model: "a", Package: "orders"}); filtered.Targets != 0 {
+		t.Errorf("Expected no records for model a in orders, got %d", filtered.Targets)
+	}
+}
@@ -0,0 +1,272 @@
+// Package status serves live generation progress over HTTP, or writes it to a file, for runs
+// without a terminal
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Target is the progress of one target
+type Target struct {
+	Index     int        `json:"index"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`         // pending, running, completed or failed
+	Phase     string     `json:"phase"`          // Current phase (e.g., "Implementation")
+	Step      string     `json:"step,omitempty"` // Latest log message of the target
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Rounds    int        `json:"rounds,omitempty"`     // Chat completion requests made (set when finished)
+	ToolCalls int        `json:"tool_calls,omitempty"` // Tool calls made (set when finished)
+}
+
+// Snapshot is the state of a run at one point in time
+type Snapshot struct {
+	Package   string    `json:"package"`
+	State     string    `json:"state"`           // detecting, generating, finished or failed
+	Error     string    `json:"error,omitempty"` // Why the run failed
+	StartedAt time.Time `json:"started_at"`
+	Targets   []Target  `json:"targets"`
+}
+
+// Run states
+const (
+	StateDetecting  = "detecting"
+	StateGenerating = "generating"
+	StateFinished   = "finished"
+	StateFailed     = "failed"
+)
+
+// Tracker records the progress of a run. It is safe for concurrent use, and a nil
+// tracker ignores all updates so callers need not check whether status is enabled
+type Tracker struct {
+	mu       sync.Mutex
+	snapshot Snapshot
+	file     string // Rewritten with every update (empty if disabled)
+}
+
+// NewTracker creates a tracker for a run over pkg
+func NewTracker(pkg string) *Tracker {
+	return &Tracker{snapshot: Snapshot{
+		Package:   pkg,
+		State:     StateDetecting,
+		StartedAt: time.Now(),
+	}}
+}
+
+// SetState records the state of the run
+func (t *Tracker) SetState(state string) {
+	t.update(func(s *Snapshot) { s.State = state })
+}
+
+// Finish records the end of the run: finished, or failed with err
+func (t *Tracker) Finish(err error) {
+	t.update(func(s *Snapshot) {
+		if err != nil {
+			s.State = StateFailed
+			s.Error = err.Error()
+			return
+		}
+		s.State = StateFinished
+	})
+}
+
+// AddTarget registers a target; indexes start at 1
+func (t *Tracker) AddTarget(name string, index int) {
+	t.update(func(s *Snapshot) {
+		s.Targets = append(s.Targets, Target{
+			Index:     index,
+			Name:      name,
+			Status:    "pending",
+			Phase:     "Initializing",
+			StartedAt: time.Now(),
+		})
+	})
+}
+
+// SetStatus records the status of a target, marking it ended when it completes or fails
+func (t *Tracker) SetStatus(index int, status string) {
+	t.updateTarget(index, func(target *Target) {
+		target.Status = status
+		if status == "completed" || status == "failed" {
+			now := time.Now()
+			target.EndedAt = &now
+		}
+	})
+}
+
+// SetStep records the current phase (if not empty) and latest step of a target
+func (t *Tracker) SetStep(index int, phase, step string) {
+	t.updateTarget(index, func(target *Target) {
+		if phase != "" {
+			target.Phase = phase
+		}
+		target.Step = step
+	})
+}
+
+// SetStats records the request statistics of a finished target
+func (t *Tracker) SetStats(index, rounds, toolCalls int) {
+	t.updateTarget(index, func(target *Target) {
+		target.Rounds = rounds
+		target.ToolCalls = toolCalls
+	})
+}
+
+// Snapshot returns a copy of the current state
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.snapshot
+	s.Targets = slices.Clone(s.Targets)
+	return s
+}
+
+func (t *Tracker) update(fn func(*Snapshot)) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.snapshot)
+	if t.file != "" {
+		if err := writeSnapshot(t.file, t.snapshot); err != nil {
+			slog.Debug("failed to write progress file", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// WriteFile keeps the state as JSON in the file at path, replacing it atomically after
+// every update so editors polling it never read a partial state
+func (t *Tracker) WriteFile(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := writeSnapshot(path, t.snapshot); err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	t.file = path
+	return nil
+}
+
+// writeSnapshot writes s to a temporary file next to path and renames it into place
+func writeSnapshot(path string, s Snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (t *Tracker) updateTarget(index int, fn func(*Target)) {
+	t.update(func(s *Snapshot) {
+		for i := range s.Targets {
+			if s.Targets[i].Index == index {
+				fn(&s.Targets[i])
+				return
+			}
+		}
+	})
+}
+
+// Handler serves the tracker's state as an HTML page at / and as JSON at /status.json
+func (t *Tracker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(t.Snapshot()); err != nil {
+			slog.Debug("failed to write status", slog.String("error", err.Error()))
+		}
+	})
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := pageTemplate.Execute(w, t.Snapshot()); err != nil {
+			slog.Debug("failed to write status page", slog.String("error", err.Error()))
+		}
+	})
+	return mux
+}
+
+// Serve starts serving the tracker on addr (e.g. ":9120") and returns the bound address.
+// The server shuts down when ctx is done
+func (t *Tracker) Serve(ctx context.Context, addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           t.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("status server stopped", slog.String("error", err.Error()))
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	return listener.Addr().String(), nil
+}
+
+// pageTemplate renders the status page, refreshing itself every two seconds
+var pageTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+{{if eq .State "detecting" "generating"}}<meta http-equiv="refresh" content="2">{{end}}
+<title>mantra: {{.Package}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.3em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+.completed { color: #2a7d2a; }
+.failed { color: #c0392b; }
+.running { color: #1f5fa8; }
+</style>
+</head>
+<body>
+<h1>{{.Package}}</h1>
+<p>State: {{.State}}{{with .Error}} ({{.}}){{end}} &middot; started {{.StartedAt.Format "15:04:05"}}</p>
+<table>
+<tr><th>#</th><th>Target</th><th>Status</th><th>Phase</th><th>Step</th></tr>
+{{range .Targets}}<tr class="{{.Status}}"><td>{{.Index}}</td><td>{{.Name}}</td><td>{{.Status}}</td><td>{{.Phase}}</td><td>{{.Step}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,117 @@
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrackerHandler(t *testing.T) {
+	tracker := NewTracker("shop")
+	tracker.SetState(StateGenerating)
+	tracker.AddTarget("Discount", 1)
+	tracker.AddTarget("Cart.Total", 2)
+	tracker.SetStatus(1, "running")
+	tracker.SetStep(1, "Implementation", "Calling check_code")
+	tracker.SetStatus(2, "completed")
+	tracker.SetStats(2, 3, 5)
+
+	server := httptest.NewServer(tracker.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/status.json")
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if snapshot.Package != "shop" || snapshot.State != StateGenerating {
+		t.Errorf("Expected shop generating, got %s %s", snapshot.Package, snapshot.State)
+	}
+	if len(snapshot.Targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(snapshot.Targets))
+	}
+	running := snapshot.Targets[0]
+	if running.Status != "running" || running.Phase != "Implementation" || running.Step != "Calling check_code" || running.EndedAt != nil {
+		t.Errorf("Expected running target in implementation, got %+v", running)
+	}
+	completed := snapshot.Targets[1]
+	if completed.Status != "completed" || completed.EndedAt == nil || completed.Rounds != 3 || completed.ToolCalls != 5 {
+		t.Errorf("Expected completed target with stats, got %+v", completed)
+	}
+
+	page, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("Failed to get status page: %v", err)
+	}
+	defer page.Body.Close()
+	var body strings.Builder
+	if _, err := io.Copy(&body, page.Body); err != nil {
+		t.Fatalf("Failed to read status page: %v", err)
+	}
+	for _, want := range []string{"Cart.Total", "Calling check_code", `http-equiv="refresh"`} {
+		if !strings.Contains(body.String(), want) {
+			t.Errorf("Expected page to contain %q, got:\n%s", want, body.String())
+		}
+	}
+}
+
+func TestNilTrackerIgnoresUpdates(t *testing.T) {
+	var tracker *Tracker
+	tracker.SetState(StateFinished)
+	tracker.AddTarget("Discount", 1)
+	tracker.SetStatus(1, "failed")
+	tracker.SetStep(1, "Implementation", "step")
+	tracker.SetStats(1, 1, 1)
+}
+
+func TestTrackerWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	tracker := NewTracker("shop")
+	if err := tracker.WriteFile(path); err != nil {
+		t.Fatalf("Failed to enable progress file: %v", err)
+	}
+
+	read := func() Snapshot {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read progress file: %v", err)
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			t.Fatalf("Failed to decode progress file: %v", err)
+		}
+		return snapshot
+	}
+	if snapshot := read(); snapshot.State != StateDetecting {
+		t.Errorf("Expected the initial state to be written, got %s", snapshot.State)
+	}
+
+	tracker.AddTarget("Discount", 1)
+	tracker.SetStep(1, "Implementation", "Calling check_code")
+	snapshot := read()
+	if len(snapshot.Targets) != 1 || snapshot.Targets[0].Step != "Calling check_code" {
+		t.Errorf("Expected the step to be written, got %+v", snapshot.Targets)
+	}
+
+	tracker.Finish(errors.New("provider unavailable"))
+	if snapshot := read(); snapshot.State != StateFailed || snapshot.Error != "provider unavailable" {
+		t.Errorf("Expected the failed run to be written, got %s %q", snapshot.State, snapshot.Error)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the progress file, got %d entries", len(entries))
+	}
+}
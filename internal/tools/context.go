@@ -17,6 +17,10 @@ type Context struct {
 	// ProjectRoot is the root directory of the project
 	ProjectRoot string
 
+	// Helpers are the private helper functions of the target's accepted implementation,
+	// declared with it when it is checked or run
+	Helpers []parser.Helper
+
 	// Additional context that might be needed by tools
 	Metadata map[string]any
 }
@@ -47,6 +51,7 @@ func (c *Context) Clone() *Context {
 		FileInfo:    c.FileInfo,
 		Target:      c.Target,
 		ProjectRoot: c.ProjectRoot,
+		Helpers:     c.Helpers,
 		Metadata:    metadata,
 	}
 }
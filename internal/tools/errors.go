@@ -1,10 +1,25 @@
 package tools
 
+import (
+	"context"
+	"errors"
+)
+
+// Error codes shared by all tools
+const (
+	CodeNotFound      = "not_found"      // The requested tool, symbol or target does not exist
+	CodeInvalidParams = "invalid_params" // The call itself is wrong and must be changed before retrying
+	CodeInternal      = "internal"       // The tool failed for reasons the model cannot fix
+	CodeTimeout       = "timeout"        // The tool ran out of time; a smaller request may succeed
+)
+
 // ToolError represents an error from tool execution
 type ToolError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Details    string `json:"details,omitempty"`
+	Retryable  bool   `json:"retryable"`            // Repeating the same call may succeed
+	Suggestion string `json:"suggestion,omitempty"` // What the model should do next
 }
 
 func (e *ToolError) Error() string {
@@ -13,3 +28,45 @@ func (e *ToolError) Error() string {
 	}
 	return e.Message
 }
+
+// NewNotFoundError reports a missing tool, symbol or target
+func NewNotFoundError(message, suggestion string) *ToolError {
+	return &ToolError{Code: CodeNotFound, Message: message, Suggestion: suggestion}
+}
+
+// NewInvalidParamsError reports a call that must be changed before it can succeed
+func NewInvalidParamsError(message, suggestion string) *ToolError {
+	return &ToolError{Code: CodeInvalidParams, Message: message, Suggestion: suggestion}
+}
+
+// NewInternalError reports a tool failure the model cannot fix by changing its call
+func NewInternalError(message string, err error) *ToolError {
+	toolErr := &ToolError{
+		Code:       CodeInternal,
+		Message:    message,
+		Suggestion: "Continue without this tool's result",
+	}
+	if err != nil {
+		toolErr.Details = err.Error()
+	}
+	return toolErr
+}
+
+// AsToolError classifies any error returned by a tool into the shared taxonomy.
+// Errors that are already ToolErrors are returned unchanged
+func AsToolError(err error) *ToolError {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &ToolError{
+			Code:       CodeTimeout,
+			Message:    "Tool timed out",
+			Details:    err.Error(),
+			Retryable:  true,
+			Suggestion: "Retry with a narrower request, or continue with what you already know",
+		}
+	}
+	return NewInternalError("Tool failed", err)
+}
@@ -3,14 +3,19 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"log/slog"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
 // Executor handles tool execution with context and logging
 type Executor struct {
 	tools   map[string]Tool
+	schemas map[string]*jsonschema.Schema // Compiled parameter schemas by tool name
 	timeout time.Duration
 	logger  *slog.Logger
 	context *Context // Shared context for tools
@@ -23,11 +28,23 @@ func NewExecutor(tools []Tool, logger *slog.Logger) *Executor {
 	}
 
 	toolMap := make(map[string]Tool)
+	schemas := make(map[string]*jsonschema.Schema)
 	for _, tool := range tools {
 		toolMap[tool.Name()] = tool
+
+		// A tool whose schema does not compile still runs, just without validation
+		schema, err := compileParamsSchema(tool)
+		if err != nil {
+			logger.Warn("skipping parameter validation",
+				slog.String("tool", tool.Name()),
+				slog.String("error", err.Error()))
+			continue
+		}
+		schemas[tool.Name()] = schema
 	}
 	return &Executor{
 		tools:   toolMap,
+		schemas: schemas,
 		timeout: 30 * time.Second, // Default timeout
 		logger:  logger,
 		context: nil, // Will be set via SetContext if needed
@@ -52,9 +69,17 @@ func (e *Executor) Execute(ctx context.Context, toolName string, params map[stri
 	// Get the tool from map
 	tool, exists := e.tools[toolName]
 	if !exists {
-		return nil, &ToolError{
-			Code:    "tool_not_found",
-			Message: fmt.Sprintf("Tool %q not found", toolName),
+		return nil, NewNotFoundError(
+			fmt.Sprintf("Tool %q not found", toolName),
+			fmt.Sprintf("Use one of: %s", strings.Join(e.toolNames(), ", ")))
+	}
+
+	// Reject malformed calls before they reach the tool
+	if schema, ok := e.schemas[toolName]; ok {
+		if err := validateParams(toolName, schema, params); err != nil {
+			e.logger.Warn(fmt.Sprintf("Tool '%s' called with invalid parameters", toolName),
+				slog.String("error", err.Error()))
+			return nil, err
 		}
 	}
 
@@ -76,8 +101,16 @@ func (e *Executor) Execute(ctx context.Context, toolName string, params map[stri
 		if name, ok := params["name"].(string); ok {
 			e.logger.Info(fmt.Sprintf("Reading function: %s", name))
 		}
+	case "list_errors":
+		e.logger.Info("Listing declared errors")
+	case "call_examples":
+		if symbol, ok := params["symbol"].(string); ok {
+			e.logger.Info(fmt.Sprintf("Finding calls of: %s", symbol))
+		}
 	case "check_code":
 		e.logger.Info("Validating generated code")
+	case "run_snippet":
+		e.logger.Info("Running generated code with example inputs")
 	default:
 		e.logger.Info(fmt.Sprintf("Executing tool: %s", toolName))
 	}
@@ -95,8 +128,22 @@ func (e *Executor) Execute(ctx context.Context, toolName string, params map[stri
 	if err != nil {
 		e.logger.Error(fmt.Sprintf("Tool '%s' failed", toolName),
 			slog.String("error", err.Error()))
-		return nil, err
+		// Tools killed by the executor timeout often report a secondary error (e.g. a killed process)
+		if execCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			err = fmt.Errorf("%w after %s: %v", context.DeadlineExceeded, e.timeout, err)
+		}
+		return nil, AsToolError(err)
 	}
 
 	return result, nil
 }
+
+// toolNames returns the registered tool names in sorted order
+func (e *Executor) toolNames() []string {
+	names := make([]string, 0, len(e.tools))
+	for name := range e.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
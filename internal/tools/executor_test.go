@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoTool returns its parameters and records whether it ran
+type echoTool struct {
+	called bool
+}
+
+func (t *echoTool) Name() string        { return "echo" }
+func (t *echoTool) Description() string { return "Echo parameters" }
+func (t *echoTool) IsTerminal() bool    { return false }
+
+func (t *echoTool) ParametersSchema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"pattern": {"type": "string"},
+			"kind": {"type": "string", "enum": ["func", "type"]},
+			"limit": {"type": "integer"}
+		},
+		"required": ["pattern"],
+		"additionalProperties": false
+	}`)
+}
+
+func (t *echoTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	t.called = true
+	return params, nil
+}
+
+func TestExecutorValidatesParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		issues []string // Substrings expected in the error details; empty means valid
+	}{
+		{"valid", `{"pattern": "New*", "limit": 5}`, nil},
+		{"missing required", `{"limit": 5}`, []string{"(root)", "pattern"}},
+		{"wrong type", `{"pattern": "New*", "limit": "five"}`, []string{"/limit", "integer"}},
+		{"fractional integer", `{"pattern": "New*", "limit": 2.5}`, []string{"/limit"}},
+		{"enum", `{"pattern": "New*", "kind": "struct"}`, []string{"/kind"}},
+		{"unknown property", `{"pattern": "New*", "query": "x"}`, []string{"query"}},
+		{"several violations", `{"kind": "struct"}`, []string{"(root)", "/kind"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params map[string]any
+			if err := json.Unmarshal([]byte(tt.params), &params); err != nil {
+				t.Fatalf("Invalid test params: %v", err)
+			}
+
+			tool := &echoTool{}
+			executor := NewExecutor([]Tool{tool}, nil)
+			_, err := executor.Execute(context.Background(), "echo", params)
+
+			if len(tt.issues) == 0 {
+				if err != nil {
+					t.Fatalf("Expected valid params, got %v", err)
+				}
+				if !tool.called {
+					t.Error("Expected tool to run")
+				}
+				return
+			}
+
+			var toolErr *ToolError
+			if !errors.As(err, &toolErr) {
+				t.Fatalf("Expected ToolError, got %v", err)
+			}
+			if toolErr.Code != CodeInvalidParams {
+				t.Errorf("Expected invalid_params, got %s", toolErr.Code)
+			}
+			for _, issue := range tt.issues {
+				if !strings.Contains(toolErr.Details, issue) {
+					t.Errorf("Expected details to mention %q, got %q", issue, toolErr.Details)
+				}
+			}
+			if tool.called {
+				t.Error("Expected tool not to run with invalid params")
+			}
+		})
+	}
+}
+
+// slowTool blocks until its context is done and then reports an unrelated failure
+type slowTool struct{ echoTool }
+
+func (t *slowTool) Name() string { return "slow" }
+
+func (t *slowTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	<-ctx.Done()
+	return nil, errors.New("signal: killed")
+}
+
+func TestExecutorErrorTaxonomy(t *testing.T) {
+	executor := NewExecutor([]Tool{&echoTool{}, &slowTool{}}, nil)
+	executor.timeout = 10 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		tool      string
+		params    map[string]any
+		code      string
+		retryable bool
+	}{
+		{"unknown tool", "grep", map[string]any{}, CodeNotFound, false},
+		{"invalid params", "echo", map[string]any{}, CodeInvalidParams, false},
+		{"timeout", "slow", map[string]any{"pattern": "New*"}, CodeTimeout, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executor.Execute(context.Background(), tt.tool, tt.params)
+
+			var toolErr *ToolError
+			if !errors.As(err, &toolErr) {
+				t.Fatalf("Expected ToolError, got %v", err)
+			}
+			if toolErr.Code != tt.code {
+				t.Errorf("Expected code %s, got %s", tt.code, toolErr.Code)
+			}
+			if toolErr.Retryable != tt.retryable {
+				t.Errorf("Expected retryable=%v, got %v", tt.retryable, toolErr.Retryable)
+			}
+			if toolErr.Suggestion == "" {
+				t.Error("Expected a suggestion")
+			}
+		})
+	}
+}
+
+func TestAsToolErrorKeepsToolErrors(t *testing.T) {
+	original := NewNotFoundError("Symbol not found", "Search first")
+	if got := AsToolError(fmt.Errorf("inspect: %w", original)); got != original {
+		t.Errorf("Expected wrapped ToolError to be returned unchanged, got %+v", got)
+	}
+
+	if got := AsToolError(errors.New("disk full")); got.Code != CodeInternal || got.Retryable {
+		t.Errorf("Expected non-retryable internal error, got %+v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package impl
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// checkBenchLoop reports benchmarks that do not run the measured code in a loop driven by
+// b.Loop() or b.N, use both, or call b.Loop() other than as the condition of a for loop.
+// Without such a loop the measured time is not divided by the right number of iterations
+func checkBenchLoop(mapper *PositionMapper) []Issue {
+	fn := mapper.funcDecl
+	if len(fn.Type.Params.List) == 0 || len(fn.Type.Params.List[0].Names) == 0 {
+		return nil
+	}
+	b := fn.Type.Params.List[0].Names[0].Name
+
+	isB := func(expr ast.Expr, field string) bool {
+		sel, ok := ast.Unparen(expr).(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != field {
+			return false
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		return ok && ident.Name == b
+	}
+	isLoopCall := func(expr ast.Expr) bool {
+		call, ok := ast.Unparen(expr).(*ast.CallExpr)
+		return ok && len(call.Args) == 0 && isB(call.Fun, "Loop")
+	}
+
+	var (
+		issues               []Issue
+		loopConds, loopCalls int
+		boundingN            int
+		firstLoopCall        token.Pos
+		firstN               token.Pos
+		firstUnboundedN      token.Pos
+	)
+	report := func(pos token.Pos, message string) {
+		line, column := mapper.ToRelativePosition(pos)
+		issues = append(issues, Issue{Code: "bench_loop", Message: message, Line: line, Column: column})
+	}
+
+	// Uses of b.N inside the condition of a for loop or the operand of a range bound a loop
+	bounds := make(map[ast.Node]bool)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ForStmt:
+			if n.Cond != nil {
+				if isLoopCall(n.Cond) {
+					loopConds++
+				}
+				markN(n.Cond, isB, bounds)
+			}
+		case *ast.RangeStmt:
+			markN(n.X, isB, bounds)
+		case *ast.CallExpr:
+			if isLoopCall(n) {
+				loopCalls++
+				if !firstLoopCall.IsValid() {
+					firstLoopCall = n.Pos()
+				}
+			}
+		case *ast.SelectorExpr:
+			if isB(n, "N") {
+				if !firstN.IsValid() {
+					firstN = n.Pos()
+				}
+				if bounds[n] {
+					boundingN++
+				} else if !firstUnboundedN.IsValid() {
+					firstUnboundedN = n.Pos()
+				}
+			}
+		}
+		return true
+	})
+
+	switch {
+	case loopCalls > 0 && firstN.IsValid():
+		report(firstN, fmt.Sprintf("the benchmark uses both %[1]s.Loop() and %[1]s.N; use one of them, preferably for %[1]s.Loop() { ... }", b))
+	case loopCalls > loopConds:
+		report(firstLoopCall, fmt.Sprintf("%[1]s.Loop() must only be called as the condition of a for loop: for %[1]s.Loop() { ... }", b))
+	case loopCalls == 0 && boundingN == 0:
+		pos := fn.Body.Lbrace
+		if firstUnboundedN.IsValid() {
+			pos = firstUnboundedN
+		}
+		report(pos, fmt.Sprintf("the measured code must run in a loop: for %[1]s.Loop() { ... } or for range %[1]s.N { ... }", b))
+	}
+	return issues
+}
+
+// markN records the uses of b.N within expr
+func markN(expr ast.Expr, isB func(ast.Expr, string) bool, bounds map[ast.Node]bool) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && isB(sel, "N") {
+			bounds[sel] = true
+		}
+		return true
+	})
+}
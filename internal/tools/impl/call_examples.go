@@ -0,0 +1,261 @@
+package impl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rail44/mantra/internal/tools"
+)
+
+// maxCallExampleContext caps the lines shown around each call
+const maxCallExampleContext = 10
+
+// CallExamplesTool shows how a function or method is called elsewhere in the module
+type CallExamplesTool struct {
+	root string
+}
+
+// NewCallExamplesTool creates a new call_examples tool for the module containing dir
+func NewCallExamplesTool(dir string) *CallExamplesTool {
+	return &CallExamplesTool{
+		root: findModuleRoot(dir),
+	}
+}
+
+// Name returns the tool name
+func (t *CallExamplesTool) Name() string {
+	return "call_examples"
+}
+
+// Description returns what this tool does
+func (t *CallExamplesTool) Description() string {
+	return "Show real calls of a function or method in the project, each with surrounding lines, to learn how it is used"
+}
+
+// ParametersSchema returns the JSON Schema for parameters
+func (t *CallExamplesTool) ParametersSchema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"symbol": {
+				"type": "string",
+				"description": "Function or method to find calls of, e.g. 'Parse', 'store.Open' or 'Cache.Get'"
+			},
+			"limit": {
+				"type": "integer",
+				"default": 5,
+				"description": "Maximum number of examples"
+			},
+			"context_lines": {
+				"type": "integer",
+				"default": 3,
+				"description": "Lines shown before and after each call (at most 10)"
+			}
+		},
+		"required": ["symbol"],
+		"additionalProperties": false
+	}`)
+}
+
+// Execute runs the call_examples tool
+func (t *CallExamplesTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	symbol, ok := params["symbol"].(string)
+	if !ok || strings.TrimSpace(symbol) == "" {
+		return nil, tools.NewInvalidParamsError("Parameter 'symbol' is required and must be a string", "Pass a function name such as 'Parse' or a method such as 'Cache.Get'")
+	}
+
+	limit := 5
+	if l, ok := params["limit"].(float64); ok {
+		limit = int(l)
+	}
+	if limit <= 0 {
+		return nil, tools.NewInvalidParamsError("Parameter 'limit' must be positive", "Omit limit or pass a number such as 5")
+	}
+
+	contextLines := 3
+	if c, ok := params["context_lines"].(float64); ok {
+		contextLines = min(max(int(c), 0), maxCallExampleContext)
+	}
+
+	qualifier, name := "", strings.TrimSpace(symbol)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		qualifier, name = strings.Trim(name[:i], "(*)"), name[i+1:]
+	}
+
+	examples, total, err := t.find(ctx, qualifier, name, limit, contextLines)
+	if err != nil {
+		return nil, err
+	}
+	return CallExamplesResult{
+		Symbol:   symbol,
+		Examples: examples,
+		Total:    total,
+	}, nil
+}
+
+// IsTerminal returns false as call_examples doesn't end the phase
+func (t *CallExamplesTool) IsTerminal() bool {
+	return false
+}
+
+// CallExamplesResult represents the calls found
+type CallExamplesResult struct {
+	Symbol   string        `json:"symbol"`
+	Examples []CallExample `json:"examples"`
+	Total    int           `json:"total"` // Calls found, including those past the limit
+}
+
+// CallExample is a call with the lines around it
+type CallExample struct {
+	Location string `json:"location"`
+	Function string `json:"function,omitempty"` // Function containing the call
+	Snippet  string `json:"snippet"`
+}
+
+// find collects the calls of name in the module. Calls in non-test files come first, and a
+// function contributes at most one example while others are left
+func (t *CallExamplesTool) find(ctx context.Context, qualifier, name string, limit, contextLines int) ([]CallExample, int, error) {
+	dirs, err := moduleDirs(ctx, t.root)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	// Tests show usage as well, but after the code using it for real
+	slices.SortStableFunc(files, func(a, b string) int {
+		aTest, bTest := strings.HasSuffix(a, "_test.go"), strings.HasSuffix(b, "_test.go")
+		switch {
+		case aTest == bTest:
+			return 0
+		case bTest:
+			return -1
+		}
+		return 1
+	})
+
+	// Calls of a function in its own body are recursion, not usage
+	self := name
+	if ast.IsExported(qualifier) {
+		self = qualifier + "." + name
+	}
+
+	var examples, repeated []CallExample
+	total := 0
+	seenFuncs := make(map[string]bool)
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || !bytes.Contains(content, []byte(name)) {
+			continue
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, content, 0)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(t.root, path)
+		if err != nil {
+			rel = path
+		}
+		lines := strings.Split(string(content), "\n")
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || funcDisplayName(fn) == self {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || !callsSymbol(call, file.Name.Name, qualifier, name) {
+					return true
+				}
+				total++
+				start, end := fset.Position(call.Pos()).Line, fset.Position(call.End()).Line
+				example := CallExample{
+					Location: fmt.Sprintf("%s:%d", filepath.ToSlash(rel), start),
+					Function: funcDisplayName(fn),
+					Snippet:  snippetLines(lines, start-contextLines, end+contextLines),
+				}
+				key := rel + ":" + example.Function
+				if seenFuncs[key] {
+					repeated = append(repeated, example)
+				} else {
+					seenFuncs[key] = true
+					examples = append(examples, example)
+				}
+				return true
+			})
+		}
+	}
+
+	examples = append(examples, repeated...)
+	return examples[:min(len(examples), limit)], total, nil
+}
+
+// callsSymbol reports whether call, made in package pkg, calls name, qualified by a package or
+// type name when qualifier is set. Methods are matched by name, as the type of the receiver is
+// not known
+func callsSymbol(call *ast.CallExpr, pkg, qualifier, name string) bool {
+	fun := call.Fun
+	if index, ok := fun.(*ast.IndexExpr); ok {
+		fun = index.X // Explicit type arguments
+	} else if index, ok := fun.(*ast.IndexListExpr); ok {
+		fun = index.X
+	}
+
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return (qualifier == "" || qualifier == pkg) && f.Name == name
+	case *ast.SelectorExpr:
+		if f.Sel.Name != name {
+			return false
+		}
+		// A lowercase qualifier names a package, which must be the one called
+		if qualifier != "" && !ast.IsExported(qualifier) {
+			x, ok := f.X.(*ast.Ident)
+			return ok && x.Name == qualifier
+		}
+		return true
+	}
+	return false
+}
+
+// funcDisplayName returns the name of a function, as Type.Method for methods
+func funcDisplayName(fn *ast.FuncDecl) string {
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		return receiverTypeName(fn.Recv.List[0].Type) + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// snippetLines returns the numbered lines from first to last, clamped to the file
+func snippetLines(lines []string, first, last int) string {
+	first, last = max(first, 1), min(last, len(lines))
+	var b strings.Builder
+	for i := first; i <= last; i++ {
+		fmt.Fprintf(&b, "%d: %s\n", i, lines[i-1])
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,99 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCallExamplesTool(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/store.go": `package store
+
+type Store struct{}
+
+func Open(path string) (*Store, error) {
+	return Open(path + ".db")
+}
+
+func (s *Store) Get(key string) string { return "" }
+`,
+		"api/handler.go": `package api
+
+import "example.com/app/store"
+
+func Handle() {
+	s, err := store.Open("data")
+	if err != nil {
+		return
+	}
+	s.Get("a")
+	s.Get("b")
+}
+
+func Reset() {
+	db.Open("other")
+}
+`,
+		"api/handler_test.go": `package api
+
+import (
+	"testing"
+
+	"example.com/app/store"
+)
+
+func TestHandle(t *testing.T) {
+	store.Open("test")
+}
+`,
+		"testdata/fixture.go": `package fixture
+
+func f() { store.Open("fixture") }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tool := NewCallExamplesTool(filepath.Join(root, "store"))
+	result, err := tool.Execute(context.Background(), map[string]any{"symbol": "store.Open", "context_lines": float64(1)})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	found := result.(CallExamplesResult)
+	if found.Total != 2 || len(found.Examples) != 2 {
+		t.Fatalf("Expected the calls in Handle and TestHandle, got %+v", found)
+	}
+	first := found.Examples[0]
+	if first.Location != "api/handler.go:6" || first.Function != "Handle" {
+		t.Errorf("Expected the call in Handle first, got %+v", first)
+	}
+	want := "5: func Handle() {\n6: \ts, err := store.Open(\"data\")\n7: \tif err != nil {"
+	if first.Snippet != want {
+		t.Errorf("Expected snippet:\n%s\ngot:\n%s", want, first.Snippet)
+	}
+	if found.Examples[1].Function != "TestHandle" {
+		t.Errorf("Expected the test call last, got %+v", found.Examples[1])
+	}
+
+	// Methods match on any receiver, one example per function before repeats
+	result, err = tool.Execute(context.Background(), map[string]any{"symbol": "Store.Get", "limit": float64(1)})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	found = result.(CallExamplesResult)
+	if found.Total != 2 || len(found.Examples) != 1 || !strings.Contains(found.Examples[0].Snippet, `s.Get("a")`) {
+		t.Errorf("Expected 1 of 2 calls of Get, got %+v", found)
+	}
+}
@@ -1,7 +1,6 @@
 package impl
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,9 +10,14 @@ import (
 	"go/token"
 	"go/types"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/packages"
@@ -23,6 +27,11 @@ import (
 	"honnef.co/go/tools/stylecheck"
 	"honnef.co/go/tools/unused"
 
+	astanalysis "github.com/rail44/mantra/internal/analysis"
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/conventions"
+	"github.com/rail44/mantra/internal/imports"
 	pkgparser "github.com/rail44/mantra/internal/parser"
 	"github.com/rail44/mantra/internal/tools"
 )
@@ -30,7 +39,19 @@ import (
 // CheckCodeTool validates Go code using staticcheck analyzers
 type CheckCodeTool struct {
 	projectRoot string
-	context     *tools.Context // Stored context from SetContext
+	context     *tools.Context           // Stored context from SetContext
+	conventions *conventions.Conventions // Project conventions checked in addition to staticcheck (optional)
+	forbidden   []string                 // Import paths generated code must not use
+	destPath    string                   // Import path of the package generated code is written to (optional)
+	overlay     map[string][]byte        // In-memory file contents by absolute path (optional)
+	goVersion   string                   // Go release generated code must compile with (optional)
+	noPanic     bool                     // Reject calls of panic in generated code
+	maxIssues   int                      // Issues after which no further analyzer is run (0: no limit)
+	helpers     bool                     // Accept helper functions declared with the body
+	destDir     string                   // Directory of the package generated code is written to (optional)
+
+	mu       sync.Mutex
+	lastCode string // Code of the last call, kept as the attempt of a failed generation
 }
 
 // NewCheckCodeTool creates a new code checking tool
@@ -40,6 +61,53 @@ func NewCheckCodeTool(projectRoot string) *CheckCodeTool {
 	}
 }
 
+// WithConventions enables checking the project's conventions on generated code
+func (t *CheckCodeTool) WithConventions(conv *conventions.Conventions) *CheckCodeTool {
+	t.conventions = conv
+	return t
+}
+
+// WithForbiddenImports rejects generated code that uses any of the given import paths
+func (t *CheckCodeTool) WithForbiddenImports(forbidden []string) *CheckCodeTool {
+	t.forbidden = forbidden
+	return t
+}
+
+// WithDestPackage rejects generated code that imports the package it is generated into,
+// which would be an import cycle once the code is written there
+func (t *CheckCodeTool) WithDestPackage(importPath string) *CheckCodeTool {
+	t.destPath = importPath
+	return t
+}
+
+// WithOverlay type-checks against in-memory file contents, such as stubs of the generated
+// package, in place of those on disk
+func (t *CheckCodeTool) WithOverlay(overlay map[string][]byte) *CheckCodeTool {
+	t.overlay = overlay
+	return t
+}
+
+// WithMaxIssues stops static analysis once the package errors and analyzer issues found reach
+// n (0: no limit); the issues of analyzers already running are cut to n
+func (t *CheckCodeTool) WithMaxIssues(n int) *CheckCodeTool {
+	t.maxIssues = n
+	return t
+}
+
+// WithHelpers accepts private helper functions declared below the target with the body. Without
+// them in a call, the helpers of the tool context are declared
+func (t *CheckCodeTool) WithHelpers(enabled bool) *CheckCodeTool {
+	t.helpers = enabled
+	return t
+}
+
+// WithDestDir renames helpers declared with the body whose names are taken in the directory of
+// the package generated code is written to, as they are renamed when written
+func (t *CheckCodeTool) WithDestDir(dir string) *CheckCodeTool {
+	t.destDir = dir
+	return t
+}
+
 // Name returns the tool name
 func (t *CheckCodeTool) Name() string {
 	return "check_code"
@@ -52,6 +120,20 @@ func (t *CheckCodeTool) Description() string {
 
 // ParametersSchema returns the JSON Schema for parameters
 func (t *CheckCodeTool) ParametersSchema() json.RawMessage {
+	if t.helpers {
+		return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"code": {
+				"type": "string",
+				"description": "The generated function body to validate"
+			},
+			"helpers": ` + HelpersSchema + `
+		},
+		"required": ["code"],
+		"additionalProperties": false
+	}`)
+	}
 	return json.RawMessage(`{
 		"type": "object",
 		"properties": {
@@ -65,6 +147,23 @@ func (t *CheckCodeTool) ParametersSchema() json.RawMessage {
 	}`)
 }
 
+// HelpersSchema is the JSON Schema of the helper functions declared with a body, shared with
+// the implementation result
+const HelpersSchema = `{
+				"type": "array",
+				"description": "Private helper functions the body calls, declared below the target",
+				"items": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string", "description": "Unexported function name"},
+						"signature": {"type": "string", "description": "Parameters and results, e.g. (line string) (key, value string)"},
+						"body": {"type": "string", "description": "Statements inside the helper"}
+					},
+					"required": ["name", "signature", "body"],
+					"additionalProperties": false
+				}
+			}`
+
 // SetContext implements ContextAwareTool interface
 func (t *CheckCodeTool) SetContext(toolCtx *tools.Context) {
 	t.context = toolCtx
@@ -79,50 +178,59 @@ func (t *CheckCodeTool) Execute(ctx context.Context, params map[string]any) (any
 	// Extract parameters
 	code, ok := params["code"].(string)
 	if !ok {
-		return nil, &tools.ToolError{
-			Code:    "invalid_params",
-			Message: "Parameter 'code' is required and must be a string",
-		}
+		return nil, tools.NewInvalidParamsError("Parameter 'code' is required and must be a string", "Pass the function body as 'code'")
 	}
 
 	// Trim whitespace to avoid issues with leading/trailing spaces
 	code = strings.TrimSpace(code)
+	t.mu.Lock()
+	t.lastCode = code
+	t.mu.Unlock()
 
 	// Get fileInfo and target from context
 	if t.context == nil {
-		return nil, &tools.ToolError{
-			Code:    "missing_context",
-			Message: "Tool context not set - this tool requires FileInfo and Target from context",
-		}
+		return nil, tools.NewInternalError("Tool context not set - this tool requires FileInfo and Target from context", nil)
 	}
 
 	fileInfo := t.context.FileInfo
 	target := t.context.Target
 
 	if fileInfo == nil {
-		return nil, &tools.ToolError{
-			Code:    "invalid_context",
-			Message: "FileInfo not found in context",
-		}
+		return nil, tools.NewInternalError("FileInfo not found in context", nil)
 	}
 
 	if target == nil {
-		return nil, &tools.ToolError{
-			Code:    "invalid_context",
-			Message: "Target not found in context",
+		return nil, tools.NewInternalError("Target not found in context", nil)
+	}
+
+	helpers := t.context.Helpers
+	var renamed map[string]string
+	if raw, ok := params["helpers"]; ok && t.helpers {
+		var err error
+		if helpers, err = pkgparser.HelpersFromJSON(raw); err != nil {
+			return nil, tools.NewInvalidParamsError(err.Error(), "Pass helpers as objects with an unexported name, a signature such as (s string) int, and a body")
 		}
+		// Checked as they will be written
+		code, helpers, renamed = codegen.RenameHelpers(code, helpers, target, t.destDir)
 	}
 
-	// Replace function body using AST manipulation
-	modified, err := t.replaceViaAST(fileInfo.SourceContent, target, code)
+	// Replace function body using AST manipulation; the code of a region goes between its
+	// markers in the hand-written body
+	body := code
+	if target.Region != nil {
+		body = target.Region.Splice(code)
+	}
+	modified, err := t.replaceViaAST(fileInfo.SourceContent, target, body, helpers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to replace function body: %w", err)
 	}
 
 	// Create overlay map for in-memory analysis
-	overlay := map[string][]byte{
-		fileInfo.FilePath: modified.Content,
+	overlay := make(map[string][]byte, len(t.overlay)+1)
+	for path, content := range t.overlay {
+		overlay[path] = content
 	}
+	overlay[fileInfo.FilePath] = modified.Content
 
 	// Configure packages.Load for type checking
 	cfg := &packages.Config{
@@ -130,8 +238,10 @@ func (t *CheckCodeTool) Execute(ctx context.Context, params map[string]any) (any
 			packages.NeedSyntax |
 			packages.NeedTypesInfo |
 			packages.NeedName |
+			packages.NeedImports |
 			packages.NeedFiles |
 			packages.NeedCompiledGoFiles,
+		Context: ctx,
 		Dir:     t.projectRoot,
 		Overlay: overlay,
 		Tests:   false,
@@ -139,13 +249,31 @@ func (t *CheckCodeTool) Execute(ctx context.Context, params map[string]any) (any
 
 	// Load the package
 	pkgPattern := filepath.Dir(fileInfo.FilePath)
-	pkgs, err := packages.Load(cfg, pkgPattern)
+	pkgs, err := packages.Load(buildenv.Apply(cfg), pkgPattern)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load packages: %w", err)
 	}
 
 	// Run analyzers with position filtering
-	return t.runAnalyzersWithFilter(pkgs, modified, fileInfo.FilePath)
+	result, err := t.runAnalyzersWithFilter(pkgs, modified, fileInfo.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bodies are written formatted; unformatted ones would change on the next generation
+	if issues := checkFormatting(code); len(issues) > 0 {
+		result.Issues = append(result.Issues, issues...)
+		result.Valid = false
+	}
+	result.Renamed = renamed
+	return result, nil
+}
+
+// LastCode returns the code of the last call (empty if never called)
+func (t *CheckCodeTool) LastCode() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastCode
 }
 
 // ModifiedFile holds the modified file content and position information
@@ -155,10 +283,12 @@ type ModifiedFile struct {
 	BodyStartPos token.Pos      // New body start position
 	BodyEndPos   token.Pos      // New body end position
 	FileSet      *token.FileSet // For position resolution
+	RegionLines  [2]int         // First and last line of the generated region (zero for whole bodies)
 }
 
-// replaceViaAST replaces function body using AST manipulation
-func (t *CheckCodeTool) replaceViaAST(sourceContent string, target *pkgparser.Target, newBody string) (*ModifiedFile, error) {
+// replaceViaAST replaces the body of the target function, located through the AST, and
+// declares the helpers below it
+func (t *CheckCodeTool) replaceViaAST(sourceContent string, target *pkgparser.Target, newBody string, helpers []pkgparser.Helper) (*ModifiedFile, error) {
 	// Create a new FileSet for position tracking
 	fset := token.NewFileSet()
 
@@ -180,56 +310,102 @@ func (t *CheckCodeTool) replaceViaAST(sourceContent string, target *pkgparser.Ta
 			maxLines = len(lines)
 		}
 		preview := strings.Join(lines[:maxLines], "\n")
-		return nil, fmt.Errorf("failed to parse new body: %w (preview: %q)", err, preview)
+		return nil, &tools.ToolError{
+			Code:       tools.CodeInvalidParams,
+			Message:    "Code is not a valid function body",
+			Details:    fmt.Sprintf("%v (preview: %q)", err, preview),
+			Suggestion: "Send only the statements inside the function, without the signature or markdown fences",
+		}
 	}
 
-	// Extract the new body
-	var newBodyStmt *ast.BlockStmt
-	for _, decl := range tempFile.Decls {
-		if fn, ok := decl.(*ast.FuncDecl); ok {
-			newBodyStmt = fn.Body
-			break
+	// The body must not close the function early, which would splice declarations into the file
+	if len(tempFile.Decls) != 1 {
+		return nil, &tools.ToolError{
+			Code:       tools.CodeInvalidParams,
+			Message:    "Code is not a single function body",
+			Details:    "The code closes the function and declares more after it",
+			Suggestion: "Send only the statements inside the function; declare helpers as function literals inside it",
 		}
 	}
 
-	if newBodyStmt == nil {
-		return nil, fmt.Errorf("failed to extract new body")
+	for _, helper := range helpers {
+		if err := helper.Validate(); err != nil {
+			return nil, &tools.ToolError{
+				Code:       tools.CodeInvalidParams,
+				Message:    "Invalid helper function",
+				Details:    err.Error(),
+				Suggestion: "Give each helper an unexported name, its parameters and results as signature, and the statements inside it as body",
+			}
+		}
 	}
 
-	// Find and replace the target function
-	var replacedFunc *ast.FuncDecl
-	replaced := false
-
-	ast.Inspect(file, func(n ast.Node) bool {
-		if fn, ok := n.(*ast.FuncDecl); ok {
-			if t.matchesTarget(fn, target) {
-				// Replace the function body
-				fn.Body = newBodyStmt
-				replacedFunc = fn
-				replaced = true
-				return false
-			}
+	// Find the target function
+	var targetFunc *ast.FuncDecl
+	for _, fn := range astanalysis.FuncDecls(file) {
+		if t.matchesTarget(fn, target) {
+			targetFunc = fn
+			break
 		}
-		return true
-	})
+	}
 
-	if !replaced {
+	if targetFunc == nil || targetFunc.Body == nil {
 		return nil, fmt.Errorf("target function not found: %s", target.Name)
 	}
 
-	// Format the modified AST back to source code
-	var buf bytes.Buffer
-	if err := format.Node(&buf, fset, file); err != nil {
-		return nil, fmt.Errorf("failed to format AST: %w", err)
+	// Splice the new body into the source text and format the result. Nodes parsed into
+	// another file set would be laid out by their foreign positions, breaking lines at random
+	// and shifting the line numbers reported back to the model
+	start := fset.Position(targetFunc.Body.Lbrace).Offset
+	end := fset.Position(targetFunc.Body.Rbrace).Offset + 1
+	var spliced strings.Builder
+	spliced.WriteString(sourceContent[:start] + "{\n" + newBody + "\n}")
+	for _, helper := range helpers {
+		spliced.WriteString("\n\n" + helper.Source())
+	}
+	spliced.WriteString(sourceContent[end:])
+	content, err := format.Source([]byte(spliced.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format modified source: %w", err)
+	}
+
+	// Positions refer to the modified file
+	fset = token.NewFileSet()
+	file, err = parser.ParseFile(fset, "source.go", content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse modified source: %w", err)
+	}
+	var replacedFunc *ast.FuncDecl
+	for _, fn := range astanalysis.FuncDecls(file) {
+		if t.matchesTarget(fn, target) {
+			replacedFunc = fn
+			break
+		}
+	}
+	if replacedFunc == nil || replacedFunc.Body == nil {
+		return nil, fmt.Errorf("target function not found: %s", target.Name)
 	}
 
-	return &ModifiedFile{
-		Content:      buf.Bytes(),
+	modified := &ModifiedFile{
+		Content:      content,
 		TargetFunc:   replacedFunc,
 		BodyStartPos: replacedFunc.Body.Pos(),
 		BodyEndPos:   replacedFunc.Body.End(),
 		FileSet:      fset,
-	}, nil
+	}
+	if target.Region != nil {
+		// Issues are reported for the region only, with lines relative to it
+		first, last, ok := pkgparser.RegionLines(fset, file, replacedFunc.Body)
+		if !ok {
+			return nil, &tools.ToolError{
+				Code:       tools.CodeInvalidParams,
+				Message:    "Code changes the region markers",
+				Details:    "The // mantra:region and // mantra:end comments around the region could not be found",
+				Suggestion: "Send only the statements of the region, without // mantra: comments",
+			}
+		}
+		modified.RegionLines = [2]int{first, last}
+	}
+	return modified, nil
 }
 
 // IsTerminal returns false as check_code tool doesn't end the phase
@@ -249,7 +425,12 @@ func (t *CheckCodeTool) matchesTarget(fn *ast.FuncDecl, target *pkgparser.Target
 		if fn.Recv == nil || len(fn.Recv.List) == 0 {
 			return false
 		}
-		// TODO: More sophisticated receiver type matching if needed
+		// The file is the target's own source, so receivers share their spelling up to
+		// pointers, parentheses and type parameters
+		if target.FuncDecl != nil && target.FuncDecl.Recv != nil && len(target.FuncDecl.Recv.List) > 0 &&
+			receiverTypeName(fn.Recv.List[0].Type) != receiverTypeName(target.FuncDecl.Recv.List[0].Type) {
+			return false
+		}
 	} else if fn.Recv != nil && len(fn.Recv.List) > 0 {
 		return false
 	}
@@ -278,15 +459,12 @@ func (t *CheckCodeTool) createPositionMapper(pkg *packages.Package, modified *Mo
 			continue
 		}
 
-		ast.Inspect(file, func(n ast.Node) bool {
-			if fn, ok := n.(*ast.FuncDecl); ok {
-				if fn.Name.Name == modified.TargetFunc.Name.Name {
-					targetFunc = fn
-					return false
-				}
+		for _, fn := range astanalysis.FuncDecls(file) {
+			if fn.Name.Name == modified.TargetFunc.Name.Name {
+				targetFunc = fn
+				break
 			}
-			return true
-		})
+		}
 	}
 
 	if targetFunc == nil {
@@ -296,11 +474,20 @@ func (t *CheckCodeTool) createPositionMapper(pkg *packages.Package, modified *Mo
 	// Get the start position (after opening brace)
 	bodyStart := targetFunc.Body.Lbrace + 1
 	startPos := pkg.Fset.Position(bodyStart)
+	bodyEnd := targetFunc.Body.Rbrace
+
+	// A region spans its lines of the body, and its first line is line 1
+	if first, last := modified.RegionLines[0], modified.RegionLines[1]; first > 0 {
+		file := pkg.Fset.File(bodyStart)
+		bodyStart = file.LineStart(first) - 1
+		bodyEnd = file.LineStart(last + 1)
+		startPos.Line = first - 1
+	}
 
 	return &PositionMapper{
 		funcDecl:      targetFunc,
 		bodyStart:     bodyStart,
-		bodyEnd:       targetFunc.Body.Rbrace,
+		bodyEnd:       bodyEnd,
 		fileSet:       pkg.Fset,
 		startPosition: startPos,
 	}, nil
@@ -313,8 +500,9 @@ func (pm *PositionMapper) IsInGeneratedCode(pos token.Pos) bool {
 
 // ToRelativePosition converts absolute position to relative position within function body
 func (pm *PositionMapper) ToRelativePosition(pos token.Pos) (line, column int) {
+	// The body starts on the line after the opening brace
 	absPosition := pm.fileSet.Position(pos)
-	relativeLine := absPosition.Line - pm.startPosition.Line + 1
+	relativeLine := absPosition.Line - pm.startPosition.Line
 	return relativeLine, absPosition.Column
 }
 
@@ -330,7 +518,7 @@ func (pm *PositionMapper) ParseErrorPosition(errPos string, targetFile string) (
 	}
 
 	line, _ = strconv.Atoi(parts[1])
-	line = line - pm.startPosition.Line + 1
+	line = line - pm.startPosition.Line
 	if line <= 0 {
 		return 0, 0
 	}
@@ -399,18 +587,91 @@ func runAnalyzer(analyzer *analysis.Analyzer, pkg *packages.Package, results map
 	return analyzer.Run(pass)
 }
 
-// runAnalyzerSafe runs an analyzer with panic recovery
-func runAnalyzerSafe(analyzer *analysis.Analyzer, pkg *packages.Package, results map[*analysis.Analyzer]any, report func(analysis.Diagnostic)) {
+// runAnalyzerSafe runs an analyzer with panic recovery and returns its result (nil if it failed)
+func runAnalyzerSafe(analyzer *analysis.Analyzer, pkg *packages.Package, results map[*analysis.Analyzer]any, report func(analysis.Diagnostic)) (result any) {
 	defer func() {
 		if r := recover(); r != nil {
-			// Silently skip analyzers that panic (usually due to missing dependencies)
-			_ = r
+			// Silently skip analyzers that panic (usually on packages with type errors)
+			result = nil
 		}
 	}()
 
-	if result, err := runAnalyzer(analyzer, pkg, results, report); err == nil && result != nil {
-		results[analyzer] = result
+	result, err := runAnalyzer(analyzer, pkg, results, report)
+	if err != nil {
+		return nil
 	}
+	return result
+}
+
+// runAnalyzers runs the analyzers on pkg on at most workers goroutines and returns the
+// diagnostics of each kept by keep, indexed like analyzers. An analyzer starts once the
+// analyzers it requires have finished; results holds the results of analyzers run before and
+// is read concurrently. Once limit diagnostics are kept (0: no limit), no further analyzer is
+// started
+func runAnalyzers(analyzers []*analysis.Analyzer, pkg *packages.Package, results map[*analysis.Analyzer]any, workers, limit int, keep func(analysis.Diagnostic) bool) [][]analysis.Diagnostic {
+	reporting := len(analyzers)
+	pending := make(map[*analysis.Analyzer]bool, len(analyzers))
+	for _, analyzer := range analyzers {
+		pending[analyzer] = true
+	}
+	// Requirements not run yet, such as the SSA form, run too; only analyzers report
+	for i := 0; i < len(analyzers); i++ {
+		for _, req := range analyzers[i].Requires {
+			if _, done := results[req]; !done && !pending[req] {
+				pending[req] = true
+				analyzers = append(analyzers, req)
+			}
+		}
+	}
+	diagnostics := make([][]analysis.Diagnostic, len(analyzers))
+	var kept atomic.Int64
+
+	for len(pending) > 0 {
+		// The pending analyzers whose requirements among them have finished run together
+		var ready []int
+		for i, analyzer := range analyzers {
+			if pending[analyzer] && !slices.ContainsFunc(analyzer.Requires, func(req *analysis.Analyzer) bool { return pending[req] }) {
+				ready = append(ready, i)
+			}
+		}
+		if len(ready) == 0 {
+			// Requirements in a cycle never finish; run them as they are
+			for i, analyzer := range analyzers {
+				if pending[analyzer] {
+					ready = append(ready, i)
+				}
+			}
+		}
+
+		outputs := make([]any, len(ready))
+		var g errgroup.Group
+		g.SetLimit(workers)
+		for j, i := range ready {
+			analyzer := analyzers[i]
+			delete(pending, analyzer)
+			if limit > 0 && kept.Load() >= int64(limit) {
+				continue
+			}
+			g.Go(func() error {
+				outputs[j] = runAnalyzerSafe(analyzer, pkg, results, func(diag analysis.Diagnostic) {
+					if i < reporting && keep(diag) {
+						diagnostics[i] = append(diagnostics[i], diag)
+						kept.Add(1)
+					}
+				})
+				return nil
+			})
+		}
+		_ = g.Wait()
+
+		for j, i := range ready {
+			if outputs[j] != nil {
+				results[analyzers[i]] = outputs[j]
+			}
+		}
+	}
+
+	return diagnostics[:reporting]
 }
 
 // runAnalyzersWithFilter runs staticcheck analyzers with position filtering
@@ -460,10 +721,22 @@ func (t *CheckCodeTool) runAnalyzersWithFilter(pkgs []*packages.Package, modifie
 		analyzersResults[inspect.Analyzer] = result
 	}
 
-	// Run all other analyzers
-	for _, analyzer := range allAnalyzers {
-		runAnalyzerSafe(analyzer, targetPkg, analyzersResults, func(diag analysis.Diagnostic) {
-			if mapper.IsInGeneratedCode(diag.Pos) {
+	// Run all other analyzers, stopping once the issues found reach the limit
+	limit := 0
+	if t.maxIssues > 0 {
+		limit = max(t.maxIssues-len(issues), 0)
+	}
+	if t.maxIssues == 0 || limit > 0 {
+		diagnostics := runAnalyzers(allAnalyzers, targetPkg, analyzersResults, runtime.GOMAXPROCS(0), limit, func(diag analysis.Diagnostic) bool {
+			return mapper.IsInGeneratedCode(diag.Pos)
+		})
+		found := 0
+		for i, analyzer := range allAnalyzers {
+			for _, diag := range diagnostics[i] {
+				if limit > 0 && found == limit {
+					break
+				}
+				found++
 				line, column := mapper.ToRelativePosition(diag.Pos)
 				issues = append(issues, Issue{
 					Code:    analyzer.Name,
@@ -472,7 +745,39 @@ func (t *CheckCodeTool) runAnalyzersWithFilter(pkgs []*packages.Package, modifie
 					Column:  column,
 				})
 			}
-		})
+		}
+	}
+
+	// Check project conventions
+	checked := len(issues)
+	if t.conventions != nil {
+		issues = append(issues, checkErrorConventions(targetPkg, mapper, t.conventions.Errors)...)
+		issues = append(issues, checkLocking(targetPkg, mapper, t.conventions.Concurrency)...)
+		issues = append(issues, checkComplexity(mapper, t.conventions.Complexity)...)
+		issues = append(issues, checkInjection(targetPkg, mapper, t.conventions.Injection)...)
+	}
+	if len(t.forbidden) > 0 || t.destPath != "" {
+		issues = append(issues, checkForbiddenImports(targetPkg, mapper, t.forbidden, t.destPath)...)
+	}
+	if t.goVersion != "" {
+		issues = append(issues, checkGoVersion(targetPkg, mapper, t.goVersion)...)
+	}
+	if t.noPanic || (t.context != nil && t.context.Target != nil && t.context.Target.NoPanic) {
+		issues = append(issues, checkPanics(targetPkg, mapper)...)
+	}
+	if t.context != nil && t.context.Target != nil {
+		issues = append(issues, checkSelfCalls(targetPkg, mapper, t.context.Target.Instruction)...)
+	}
+
+	// The checks above inspect the whole body; in a hand-written function only the region counts
+	if first, last := modified.RegionLines[0], modified.RegionLines[1]; first > 0 {
+		kept := issues[:checked]
+		for _, issue := range issues[checked:] {
+			if issue.Line >= 1 && issue.Line <= last-first+1 {
+				kept = append(kept, issue)
+			}
+		}
+		issues = kept
 	}
 
 	return &CheckCodeResult{
@@ -481,10 +786,57 @@ func (t *CheckCodeTool) runAnalyzersWithFilter(pkgs []*packages.Package, modifie
 	}, nil
 }
 
+// checkForbiddenImports reports uses of forbidden packages and of the destination package in
+// the generated body
+func checkForbiddenImports(pkg *packages.Package, mapper *PositionMapper, forbidden []string, destPath string) []Issue {
+	if pkg.TypesInfo == nil || mapper.funcDecl.Body == nil {
+		return nil
+	}
+
+	var issues []Issue
+	reported := make(map[string]bool)
+	ast.Inspect(mapper.funcDecl.Body, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		pkgName, ok := pkg.TypesInfo.Uses[ident].(*types.PkgName)
+		if !ok {
+			return true
+		}
+		path := pkgName.Imported().Path()
+		if path == destPath && !reported[path] {
+			reported[path] = true
+			line, column := mapper.ToRelativePosition(ident.Pos())
+			issues = append(issues, Issue{
+				Code: "self_import",
+				Message: fmt.Sprintf("package %q is the package this code is generated into; importing it would be an import cycle. Use its declarations without the %s. qualifier",
+					path, ident.Name),
+				Line:   line,
+				Column: column,
+			})
+		}
+		if rule, matched := imports.MatchForbidden(path, forbidden); matched && !reported[path] {
+			reported[path] = true
+			line, column := mapper.ToRelativePosition(ident.Pos())
+			issues = append(issues, Issue{
+				Code:    "forbidden_import",
+				Message: fmt.Sprintf("package %q must not be used in generated code (forbidden by %q); implement without it", path, rule),
+				Line:    line,
+				Column:  column,
+			})
+		}
+		return true
+	})
+
+	return issues
+}
+
 // CheckCodeResult represents the result of code checking
 type CheckCodeResult struct {
-	Valid  bool    `json:"valid"`
-	Issues []Issue `json:"issues,omitempty"`
+	Valid   bool              `json:"valid"`
+	Issues  []Issue           `json:"issues,omitempty"`
+	Renamed map[string]string `json:"renamed_helpers,omitempty"` // New names of helpers whose names were taken, by old name
 }
 
 // Issue represents a code issue found during checking
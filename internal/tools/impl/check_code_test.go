@@ -2,11 +2,14 @@ package impl
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/rail44/mantra/internal/conventions"
 	"github.com/rail44/mantra/internal/parser"
 	"github.com/rail44/mantra/internal/tools"
 )
@@ -207,3 +210,949 @@ go 1.21
 		t.Errorf("Expected to find unused variable issue, but didn't. Issues: %+v", checkResult.Issues)
 	}
 }
+
+func TestCheckCodeTool_ReportsErrorConventionViolations(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrNotFound = errors.New("not found")
+
+func Parse(s string) (int, error) {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	testCode := `
+	if s == "" {
+		return 0, errors.New("not found")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid number %q: %v", s, err)
+	}
+	return n, nil
+	`
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Parse",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "s", Type: "string"}},
+		Returns:  []parser.Return{{Type: "int"}, {Type: "error"}},
+	}
+
+	tool := NewCheckCodeTool(tmpDir).WithConventions(&conventions.Conventions{Errors: conventions.ErrorConventions{RequireWrap: true, LowercaseMessages: true, ReuseSentinels: true}})
+	tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+	result, err := tool.Execute(context.Background(), map[string]any{"code": testCode})
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	checkResult := result.(*CheckCodeResult)
+
+	found := make(map[string]bool)
+	for _, issue := range checkResult.Issues {
+		found[issue.Code] = true
+	}
+	for _, code := range []string{"error_sentinel", "error_wrap", "error_message_case"} {
+		if !found[code] {
+			t.Errorf("Expected %s issue, got %+v", code, checkResult.Issues)
+		}
+	}
+}
+
+func TestCheckCodeTool_ReportsErrorStyleViolations(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrEmpty = errors.New("empty input")
+
+func Parse(s string) (int, error) {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	testCode := `
+	if s == "" {
+		return 0, fmt.Errorf("parse: %w", ErrEmpty)
+	}
+	if s == "-" {
+		return 0, errors.New("no digits")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		_, err2 := strconv.Atoi(s[1:])
+		return 0, fmt.Errorf("negative: %w, %w", err, err2)
+	}
+	check := func() error { return err }
+	_ = check
+	return n, nil
+	`
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Parse",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "s", Type: "string"}},
+		Returns:  []parser.Return{{Type: "int"}, {Type: "error"}},
+	}
+
+	tests := []struct {
+		style string
+		lines []int
+	}{
+		{"", nil},
+		{conventions.ErrorStyleSentinel, []int{5}},
+		{conventions.ErrorStyleWrap, []int{9}},
+		{conventions.ErrorStyleJoin, []int{13}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			conv := &conventions.Conventions{Errors: conventions.ErrorConventions{Style: tt.style}}
+			tool := NewCheckCodeTool(tmpDir).WithConventions(conv)
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+			result, err := tool.Execute(context.Background(), map[string]any{"code": testCode})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+			var lines []int
+			for _, issue := range result.(*CheckCodeResult).Issues {
+				if issue.Code == "error_style" {
+					lines = append(lines, issue.Line)
+				}
+			}
+			if !slices.Equal(lines, tt.lines) {
+				t.Errorf("Expected error_style issues on lines %v, got %v (%+v)", tt.lines, lines, result.(*CheckCodeResult).Issues)
+			}
+		})
+	}
+}
+
+func TestCheckCodeTool_ReportsBypassedInjection(t *testing.T) {
+	testCode := `start := time.Now()
+n := rand.Intn(10)
+r := rand.New(rand.NewSource(1))
+_ = r
+return n, time.Since(start)`
+
+	tests := []struct {
+		name     string
+		decls    string
+		receiver *parser.Receiver
+		want     []string
+	}{
+		{
+			name:  "nothing injectable",
+			decls: "type Service struct{ name string }",
+			want:  nil,
+		},
+		{
+			name:     "receiver fields",
+			decls:    "type Clock interface{ Now() time.Time }\n\ntype Service struct {\n\tclock Clock\n\trng   *rand.Rand\n}",
+			receiver: &parser.Receiver{Name: "s", Type: "*Service"},
+			want:     []string{"clock_injection:1:s.clock.Now()", "rand_injection:2:s.rng", "clock_injection:5:s.clock.Now()"},
+		},
+		{
+			name:  "package variable",
+			decls: "var now = time.Now\n\ntype Service struct{}",
+			want:  []string{"clock_injection:1:now()", "clock_injection:5:now()"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			testFile := filepath.Join(tmpDir, "test.go")
+			signature := "func Roll() (int, time.Duration)"
+			if tt.receiver != nil {
+				signature = "func (s *Service) Roll() (int, time.Duration)"
+			}
+			testFileContent := "package test\n\nimport (\n\t\"math/rand\"\n\t\"time\"\n)\n\nvar _ = rand.Int\n\n" +
+				tt.decls + "\n\n" + signature + " {\n\tpanic(\"not implemented\")\n}\n"
+			if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+				t.Fatalf("Failed to write go.mod file: %v", err)
+			}
+
+			fileInfo := &parser.FileInfo{FilePath: testFile, PackageName: "test", SourceContent: testFileContent}
+			target := &parser.Target{
+				Name:     "Roll",
+				Receiver: tt.receiver,
+				FilePath: testFile,
+				Returns:  []parser.Return{{Type: "int"}, {Type: "time.Duration"}},
+			}
+			tool := NewCheckCodeTool(tmpDir).WithConventions(&conventions.Conventions{Injection: conventions.InjectionConventions{Clock: true, Random: true}})
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+			result, err := tool.Execute(context.Background(), map[string]any{"code": testCode})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+			var got []string
+			for _, issue := range result.(*CheckCodeResult).Issues {
+				if issue.Code == "clock_injection" || issue.Code == "rand_injection" {
+					suggestion := issue.Message[strings.LastIndex(issue.Message, "use ")+len("use ") : len(issue.Message)-len(" instead")]
+					got = append(got, fmt.Sprintf("%s:%d:%s", issue.Code, issue.Line, suggestion))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckCodeTool_ReportsSelfCalls(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+type Tree struct {
+	Left, Right *Tree
+}
+
+func (t *Tree) Size() int {
+	panic("not implemented")
+}
+
+func Sum(items []int) int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+	fileInfo := &parser.FileInfo{FilePath: testFile, PackageName: "test", SourceContent: testFileContent}
+
+	for _, tt := range []struct {
+		name        string
+		target      string
+		instruction string
+		code        string
+		want        []int
+	}{
+		{"function", "Sum", "add up the items", "if len(items) == 0 {\n\treturn 0\n}\nreturn items[0] + Sum(items[1:])", []int{4}},
+		{"method", "Size", "count the nodes", "if t == nil {\n\treturn 0\n}\nreturn 1 + t.Left.Size() + t.Right.Size()", []int{4, 4}},
+		{"recursion asked for", "Size", "count the nodes recursively", "if t == nil {\n\treturn 0\n}\nreturn 1 + t.Left.Size() + t.Right.Size()", nil},
+		{"no self call", "Sum", "add up the items", "total := 0\nfor _, item := range items {\n\ttotal += item\n}\nreturn total", nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &parser.Target{Name: tt.target, Instruction: tt.instruction, FilePath: testFile, Returns: []parser.Return{{Type: "int"}}}
+			if tt.target == "Size" {
+				target.Receiver = &parser.Receiver{Name: "t", Type: "*Tree"}
+			} else {
+				target.Params = []parser.Param{{Name: "items", Type: "[]int"}}
+			}
+			tool := NewCheckCodeTool(tmpDir)
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+			result, err := tool.Execute(context.Background(), map[string]any{"code": tt.code})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+
+			var got []int
+			for _, issue := range result.(*CheckCodeResult).Issues {
+				if issue.Code == "self_call" {
+					got = append(got, issue.Line)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected self_call issues on lines %v, got %+v", tt.want, result.(*CheckCodeResult).Issues)
+			}
+		})
+	}
+}
+
+func TestCheckCodeTool_ChecksRegionOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+func Sum(items []int) int {
+	if len(items) > 100 {
+		return Sum(items[:100])
+	}
+
+	// mantra:region total add up the items
+	panic("not implemented")
+	// mantra:end
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+	fileInfo, err := parser.ParseSource(testFile, []byte(testFileContent))
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	if len(fileInfo.Targets) != 1 || fileInfo.Targets[0].Region == nil {
+		t.Fatalf("Expected one region target, got %+v", fileInfo.Targets)
+	}
+
+	tool := NewCheckCodeTool(tmpDir)
+	tool.SetContext(tools.NewContext(fileInfo, fileInfo.Targets[0], tmpDir))
+	result, err := tool.Execute(context.Background(), map[string]any{"code": "total := 0\nfor _, item := range items {\n\ttotal += item\n}\nreturn total + Sum(nil)"})
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+
+	// The hand-written self call is not reported; the region's is, on its line of the region
+	issues := result.(*CheckCodeResult).Issues
+	if len(issues) != 1 || issues[0].Code != "self_call" || issues[0].Line != 5 {
+		t.Errorf("Expected one self_call issue on line 5, got %+v", issues)
+	}
+}
+
+func TestCheckCodeTool_AbortsWhenCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+func Answer() int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Answer",
+		FilePath: testFile,
+		Returns:  []parser.Return{{Type: "int"}},
+	}
+
+	tool := NewCheckCodeTool(tmpDir)
+	tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := tool.Execute(ctx, map[string]any{"code": "return 42"}); err == nil {
+		t.Errorf("Expected an error when the context is already cancelled")
+	}
+}
+
+func TestCheckCodeTool_ReportsCodeNewerThanGoVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+import (
+	"slices"
+	"strings"
+)
+
+func Repeat(n int) string {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.24\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	// Range over int needs Go 1.22 and slices.Repeat Go 1.23
+	testCode := `
+	total := 0
+	for i := range n {
+		total += i
+	}
+	return strings.Join(slices.Repeat([]string{"x"}, total), "")
+	`
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Repeat",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "n", Type: "int"}},
+		Returns:  []parser.Return{{Type: "string"}},
+	}
+
+	for _, tt := range []struct {
+		version string
+		issues  int
+	}{
+		{"1.21", 2},
+		{"1.24", 0},
+	} {
+		tool := NewCheckCodeTool(tmpDir).WithGoVersion(tt.version)
+		tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+		result, err := tool.Execute(context.Background(), map[string]any{"code": testCode})
+		if err != nil {
+			t.Fatalf("Failed to execute tool: %v", err)
+		}
+		var issues []Issue
+		for _, issue := range result.(*CheckCodeResult).Issues {
+			if issue.Code == "go_version" {
+				issues = append(issues, issue)
+			}
+		}
+		if len(issues) != tt.issues {
+			t.Errorf("Go %s: expected %d go_version issues, got %+v", tt.version, tt.issues, issues)
+		}
+	}
+}
+
+func TestCheckCodeTool_ReportsDestinationPackageImport(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "users.go")
+	destDir := filepath.Join(tmpDir, "generated")
+
+	testFileContent := `package users
+
+import "test/generated"
+
+var _ = generated.Version
+
+func Greeting(name string) string {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(destDir, "version.go"), []byte("package generated\n\nconst Version = \"1\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write dest file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "users",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Greeting",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "name", Type: "string"}},
+		Returns:  []parser.Return{{Type: "string"}},
+	}
+
+	tool := NewCheckCodeTool(tmpDir).WithDestPackage("test/generated")
+	tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+	result, err := tool.Execute(context.Background(), map[string]any{"code": `return "hello " + name + " v" + generated.Version`})
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	checkResult := result.(*CheckCodeResult)
+	if checkResult.Valid || len(checkResult.Issues) != 1 || checkResult.Issues[0].Code != "self_import" {
+		t.Fatalf("Expected one self_import issue, got %+v", checkResult.Issues)
+	}
+	if !strings.Contains(checkResult.Issues[0].Message, "without the generated. qualifier") {
+		t.Errorf("Expected the issue to tell how to fix it, got %q", checkResult.Issues[0].Message)
+	}
+}
+
+func TestCheckCodeTool_ReportsLockingViolations(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+import "sync"
+
+type Cache struct {
+	mu    sync.Mutex
+	items map[string]string
+}
+
+func (c *Cache) Set(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = value
+}
+
+func (c *Cache) Delete(key string) {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Delete",
+		Receiver: &parser.Receiver{Name: "c", Type: "*Cache"},
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "key", Type: "string"}},
+	}
+
+	tests := []struct {
+		name  string
+		code  string
+		codes []string
+	}{
+		{"locked", "c.mu.Lock()\ndefer c.mu.Unlock()\ndelete(c.items, key)", nil},
+		{"never unlocked", "c.mu.Lock()\ndelete(c.items, key)", []string{"lock_imbalance"}},
+		{"never locked", "defer c.mu.Unlock()\ndelete(c.items, key)", []string{"lock_imbalance", "unguarded_field"}},
+		{"unguarded", "delete(c.items, key)", []string{"unguarded_field"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewCheckCodeTool(tmpDir).WithConventions(&conventions.Conventions{Concurrency: conventions.ConcurrencyConventions{BalancedLocks: true, GuardedFields: true}})
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+			result, err := tool.Execute(context.Background(), map[string]any{"code": tt.code})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+			var got []string
+			for _, issue := range result.(*CheckCodeResult).Issues {
+				got = append(got, issue.Code)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.codes, ",") {
+				t.Errorf("Expected issues %v, got %+v", tt.codes, result.(*CheckCodeResult).Issues)
+			}
+		})
+	}
+}
+
+func TestCheckCodeTool_ReportsPanicsWhenForbidden(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+func First(items []int) int {
+	panic("not implemented")
+}
+
+func Last(items []int) int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	code := "if len(items) == 0 {\n\tpanic(\"empty\")\n}\nreturn items[0]"
+
+	for _, tt := range []struct {
+		name    string
+		noPanic bool
+		target  *parser.Target
+		want    int
+	}{
+		{"allowed", false, &parser.Target{Name: "First"}, 0},
+		{"configured", true, &parser.Target{Name: "First"}, 1},
+		{"directive", false, &parser.Target{Name: "First", NoPanic: true}, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.FilePath = testFile
+			tt.target.Params = []parser.Param{{Name: "items", Type: "[]int"}}
+			tt.target.Returns = []parser.Return{{Type: "int"}}
+
+			tool := NewCheckCodeTool(tmpDir).WithNoPanic(tt.noPanic)
+			tool.SetContext(tools.NewContext(fileInfo, tt.target, tmpDir))
+			result, err := tool.Execute(context.Background(), map[string]any{"code": code})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+
+			// The stub of Last is not part of the generated body
+			var got []Issue
+			for _, issue := range result.(*CheckCodeResult).Issues {
+				if issue.Code == "no_panic" {
+					got = append(got, issue)
+				}
+			}
+			if len(got) != tt.want {
+				t.Errorf("Expected %d no_panic issues, got %+v", tt.want, result.(*CheckCodeResult).Issues)
+			}
+		})
+	}
+}
+
+func TestCheckCodeTool_StopsAtMaxIssues(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+import "fmt"
+
+func Greet(name string) string {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+		Imports:       []parser.Import{{Path: "fmt"}},
+	}
+	target := &parser.Target{
+		Name:     "Greet",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "name", Type: "string"}},
+		Returns:  []parser.Return{{Type: "string"}},
+	}
+	// S1002, S1025 and SA4000
+	code := "ok := name != \"\"\nif ok == true {\n\tname = fmt.Sprintf(\"%s\", name)\n}\nif name == name {\n\tname += \"!\"\n}\nreturn \"Hello, \" + name"
+
+	for _, tt := range []struct {
+		name      string
+		maxIssues int
+		check     func(n int) bool
+	}{
+		{"no limit", 0, func(n int) bool { return n == 3 }},
+		{"limit", 1, func(n int) bool { return n == 1 }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewCheckCodeTool(tmpDir).WithMaxIssues(tt.maxIssues)
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+			result, err := tool.Execute(context.Background(), map[string]any{"code": code})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+
+			checkResult := result.(*CheckCodeResult)
+			if checkResult.Valid || !tt.check(len(checkResult.Issues)) {
+				t.Errorf("Unexpected issues with max %d: %+v", tt.maxIssues, checkResult.Issues)
+			}
+		})
+	}
+}
+
+func TestCheckCodeTool_ReportsUnformattedCode(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+func Sum(items []int) int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Sum",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "items", Type: "[]int"}},
+		Returns:  []parser.Return{{Type: "int"}},
+	}
+
+	formatted := "total := 0\nfor _, item := range items {\n\ttotal += item\n}\n_ = `raw\n  kept`\nreturn total"
+	for _, tt := range []struct {
+		name string
+		code string
+		line int // Line of the gofmt issue (0: none)
+	}{
+		{"formatted", formatted, 0},
+		{"spacing", "total := 0\nfor _, item := range items {\n\ttotal+=item\n}\n_ = `raw\n  kept`\nreturn total", 3},
+		{"indentation", "total := 0\nfor _, item := range items {\n    total += item\n}\n_ = `raw\n  kept`\nreturn total", 3},
+		{"indented body", "\ttotal := 0\n\tfor _, item := range items {\n\t\ttotal += item\n\t}\n\t_ = `raw\n  kept`\n\treturn total", 2}, // The first line is trimmed
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewCheckCodeTool(tmpDir)
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+			result, err := tool.Execute(context.Background(), map[string]any{"code": tt.code})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+
+			checkResult := result.(*CheckCodeResult)
+			var got []Issue
+			for _, issue := range checkResult.Issues {
+				if issue.Code == "gofmt" {
+					got = append(got, issue)
+				}
+			}
+			if tt.line == 0 {
+				if len(got) != 0 || !checkResult.Valid {
+					t.Errorf("Expected formatted code to be valid, got %+v", checkResult.Issues)
+				}
+				return
+			}
+			if len(got) != 1 || got[0].Line != tt.line || checkResult.Valid {
+				t.Fatalf("Expected a gofmt issue on line %d, got %+v", tt.line, checkResult.Issues)
+			}
+			if !strings.HasSuffix(got[0].Message, "\n"+formatted) {
+				t.Errorf("Expected the formatted body in the message, got %q", got[0].Message)
+			}
+		})
+	}
+
+	if body := FormatBody("x := 1\nif x>0 {\nx++\n}"); body != "x := 1\nif x > 0 {\n\tx++\n}" {
+		t.Errorf("Unexpected formatted body %q", body)
+	}
+}
+
+func TestCheckCodeTool_ReportsComplexityOverBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+func Classify(n int) string {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Classify",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "n", Type: "int"}},
+		Returns:  []parser.Return{{Type: "string"}},
+	}
+
+	// 17 lines; cyclomatic complexity 6 for the body (three cases, &&, if) and 3 for the
+	// function literal, which is not part of the body's
+	code := `switch {
+case n < 0:
+	return "negative"
+case n == 0:
+	return "zero"
+case n < 10 && n%2 == 0:
+	return "small even"
+}
+large := func() bool {
+	if n > 100 || n < -100 {
+		return true
+	}
+	return false
+}
+if large() {
+	return "large"
+}
+return "other"`
+
+	for _, tt := range []struct {
+		name   string
+		budget conventions.ComplexityConventions
+		want   []string // Issue codes and lines
+	}{
+		{"no budgets", conventions.ComplexityConventions{}, nil},
+		{"within budgets", conventions.ComplexityConventions{MaxCyclomatic: 6, MaxLines: 18}, nil},
+		{"body too complex", conventions.ComplexityConventions{MaxCyclomatic: 5}, []string{"complexity:0"}},
+		{"literal too complex", conventions.ComplexityConventions{MaxCyclomatic: 2}, []string{"complexity:0", "complexity:9"}},
+		{"too long", conventions.ComplexityConventions{MaxLines: 17}, []string{"function_length:0"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := conventions.Default()
+			conv.Complexity = tt.budget
+			tool := NewCheckCodeTool(tmpDir).WithConventions(conv)
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+			result, err := tool.Execute(context.Background(), map[string]any{"code": code})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+
+			checkResult := result.(*CheckCodeResult)
+			var got []string
+			for _, issue := range checkResult.Issues {
+				got = append(got, fmt.Sprintf("%s:%d", issue.Code, issue.Line))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected issues %v, got %+v", tt.want, checkResult.Issues)
+			}
+		})
+	}
+}
+
+func TestCheckCodeTool_Helpers(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.go")
+
+	testFileContent := `package test
+
+func CountVowels(s string) int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "CountVowels",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "s", Type: "string"}},
+		Returns:  []parser.Return{{Type: "int"}},
+	}
+
+	code := `n := 0
+for _, r := range s {
+	if isVowel(r) {
+		n++
+	}
+}
+return n`
+	helper := map[string]any{
+		"name":      "isVowel",
+		"signature": "(r rune) bool",
+		"body":      "switch r {\ncase 'a', 'e', 'i', 'o', 'u':\n\treturn true\n}\nreturn false",
+	}
+
+	execute := func(tool *CheckCodeTool, toolContext *tools.Context, params map[string]any) *CheckCodeResult {
+		t.Helper()
+		tool.SetContext(toolContext)
+		result, err := tool.Execute(context.Background(), params)
+		if err != nil {
+			t.Fatalf("Failed to execute tool: %v", err)
+		}
+		return result.(*CheckCodeResult)
+	}
+
+	if result := execute(NewCheckCodeTool(tmpDir), tools.NewContext(fileInfo, target, tmpDir), map[string]any{"code": code}); result.Valid {
+		t.Error("Expected a body calling an undeclared helper to be invalid")
+	}
+
+	params := map[string]any{"code": code, "helpers": []any{helper}}
+	if result := execute(NewCheckCodeTool(tmpDir).WithHelpers(true), tools.NewContext(fileInfo, target, tmpDir), params); !result.Valid {
+		t.Errorf("Expected the body with its helper to be valid, got %+v", result.Issues)
+	}
+
+	// Helpers of an accepted implementation are declared through the context
+	toolContext := tools.NewContext(fileInfo, target, tmpDir)
+	toolContext.Helpers = []parser.Helper{{Name: "isVowel", Signature: "(r rune) bool", Body: helper["body"].(string)}}
+	if result := execute(NewCheckCodeTool(tmpDir), toolContext, map[string]any{"code": code}); !result.Valid {
+		t.Errorf("Expected the body with the context's helper to be valid, got %+v", result.Issues)
+	}
+
+	// Names taken in the destination package are renamed, as they are when written
+	destDir := filepath.Join(tmpDir, "generated")
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("Failed to create dest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(destDir, "other.go"), []byte("package generated\n\nfunc isVowel() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write dest file: %v", err)
+	}
+	result := execute(NewCheckCodeTool(tmpDir).WithHelpers(true).WithDestDir(destDir), tools.NewContext(fileInfo, target, tmpDir), params)
+	if !result.Valid || result.Renamed["isVowel"] != "isVowel2" {
+		t.Errorf("Expected isVowel to be renamed isVowel2, got %v (%+v)", result.Renamed, result.Issues)
+	}
+
+	exported := map[string]any{"name": "IsVowel", "signature": "(r rune) bool", "body": "return false"}
+	tool := NewCheckCodeTool(tmpDir).WithHelpers(true)
+	tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+	if _, err := tool.Execute(context.Background(), map[string]any{"code": code, "helpers": []any{exported}}); err == nil {
+		t.Error("Expected an exported helper to be rejected")
+	}
+}
@@ -0,0 +1,234 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/tools"
+)
+
+// testFuncFileName is the test file a generated test function is type-checked in, next to
+// the target's source
+const testFuncFileName = "mantra_generated_test.go"
+
+// CheckTestFuncTool validates a generated test function of the target, a benchmark or a fuzz
+// test: it must compile as a test of the target's package, with the accepted implementation
+// in place, and use testing.B or testing.F as go test expects
+type CheckTestFuncTool struct {
+	kind           codegen.TestKind
+	projectRoot    string
+	implementation string            // Accepted body of the target
+	context        *tools.Context    // Stored context from SetContext
+	overlay        map[string][]byte // In-memory file contents by absolute path (optional)
+	goVersion      string            // Go release the function must compile with (optional)
+	importNames    map[string]string // Package names of the source file's imports, loaded on first use
+}
+
+// NewCheckTestFuncTool creates a tool checking test functions of kind for the target's
+// accepted implementation
+func NewCheckTestFuncTool(kind codegen.TestKind, projectRoot, implementation string) *CheckTestFuncTool {
+	return &CheckTestFuncTool{
+		kind:           kind,
+		projectRoot:    projectRoot,
+		implementation: implementation,
+	}
+}
+
+// WithOverlay type-checks against in-memory file contents, such as stubs of the generated
+// package, in place of those on disk
+func (t *CheckTestFuncTool) WithOverlay(overlay map[string][]byte) *CheckTestFuncTool {
+	t.overlay = overlay
+	return t
+}
+
+// WithGoVersion rejects functions that need a newer Go release than version, such as b.Loop
+// before Go 1.24
+func (t *CheckTestFuncTool) WithGoVersion(version string) *CheckTestFuncTool {
+	t.goVersion = version
+	return t
+}
+
+// Name returns the tool name, check_bench or check_fuzz
+func (t *CheckTestFuncTool) Name() string {
+	return "check_" + t.kind.Directive
+}
+
+// Description returns what this tool does
+func (t *CheckTestFuncTool) Description() string {
+	if t.kind.Directive == codegen.FuzzTests.Directive {
+		return "Compile the fuzz test body against the implementation and check its seed corpus and fuzz function"
+	}
+	return "Compile the benchmark body against the implementation and check its use of b.Loop() or b.N"
+}
+
+// ParametersSchema returns the JSON Schema for parameters
+func (t *CheckTestFuncTool) ParametersSchema() json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{
+		"type": "object",
+		"properties": {
+			"code": {
+				"type": "string",
+				"description": "The body of the %[1]s, the statements inside func %[2]sX(%[3]s)"
+			}
+		},
+		"required": ["code"],
+		"additionalProperties": false
+	}`, t.kind.Name, t.kind.Prefix, t.kind.Param))
+}
+
+// SetContext implements ContextAwareTool interface
+func (t *CheckTestFuncTool) SetContext(toolCtx *tools.Context) {
+	t.context = toolCtx
+	t.importNames = nil
+	if toolCtx != nil && toolCtx.ProjectRoot != "" {
+		t.projectRoot = toolCtx.ProjectRoot
+	}
+}
+
+// IsTerminal returns false as the tool doesn't end the phase
+func (t *CheckTestFuncTool) IsTerminal() bool {
+	return false
+}
+
+// Execute type-checks the test function and checks its use of the testing package
+func (t *CheckTestFuncTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	code, ok := params["code"].(string)
+	if !ok {
+		return nil, tools.NewInvalidParamsError("Parameter 'code' is required and must be a string", "Pass the "+t.kind.Name+" body as 'code'")
+	}
+	code = strings.TrimSpace(code)
+
+	if t.context == nil || t.context.FileInfo == nil || t.context.Target == nil {
+		return nil, tools.NewInternalError("Tool context not set - this tool requires FileInfo and Target from context", nil)
+	}
+	fileInfo := t.context.FileInfo
+	target := t.context.Target
+
+	modified, err := (&CheckCodeTool{}).replaceViaAST(fileInfo.SourceContent, target, t.implementation, t.context.Helpers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace function body: %w", err)
+	}
+	name := t.kind.FuncName(target)
+	if t.importNames == nil {
+		t.importNames = codegen.ImportNames(filepath.Dir(fileInfo.FilePath))
+	}
+	testFile, err := codegen.RenderTestFuncs(fileInfo.PackageName, fileInfo.Imports, t.importNames, t.kind, []codegen.TestFunc{{Name: name, Body: code}})
+	if err != nil {
+		return nil, &tools.ToolError{
+			Code:       tools.CodeInvalidParams,
+			Message:    "Code is not a valid function body",
+			Details:    err.Error(),
+			Suggestion: "Send only the statements inside the " + t.kind.Name + " function, without the signature or markdown fences",
+		}
+	}
+
+	testPath := filepath.Join(filepath.Dir(fileInfo.FilePath), testFuncFileName)
+	overlay := make(map[string][]byte, len(t.overlay)+2)
+	for path, content := range t.overlay {
+		overlay[path] = content
+	}
+	overlay[fileInfo.FilePath] = modified.Content
+	overlay[testPath] = testFile
+
+	cfg := &packages.Config{
+		Mode: packages.NeedTypes |
+			packages.NeedSyntax |
+			packages.NeedTypesInfo |
+			packages.NeedName |
+			packages.NeedImports |
+			packages.NeedFiles |
+			packages.NeedCompiledGoFiles,
+		Context: ctx,
+		Dir:     t.projectRoot,
+		Overlay: overlay,
+		Tests:   true,
+	}
+	pkgs, err := packages.Load(buildenv.Apply(cfg), filepath.Dir(fileInfo.FilePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	return t.check(pkgs, testPath, name), nil
+}
+
+// check reports the compile errors of the test package holding the function and its
+// misuses of the testing package
+func (t *CheckTestFuncTool) check(pkgs []*packages.Package, testPath, name string) *CheckCodeResult {
+	// The test variant of the package is the one compiling the test file
+	var pkg *packages.Package
+	for _, p := range pkgs {
+		for _, file := range p.CompiledGoFiles {
+			if file == testPath {
+				pkg = p
+			}
+		}
+	}
+	if pkg == nil {
+		return &CheckCodeResult{Valid: false, Issues: []Issue{{Code: "package_error", Message: "the package of the " + t.kind.Name + " could not be loaded"}}}
+	}
+
+	// Building the test variant makes go list report type errors again, unpositioned
+	typeErrors := false
+	for _, err := range pkg.Errors {
+		typeErrors = typeErrors || err.Kind == packages.TypeError
+	}
+
+	mapper := testFuncMapper(pkg, testPath, name)
+	var issues []Issue
+	for _, err := range pkg.Errors {
+		if typeErrors && err.Kind == packages.ListError {
+			continue
+		}
+		issue := Issue{Code: "package_error", Message: err.Msg}
+		if mapper != nil {
+			issue.Line, issue.Column = mapper.ParseErrorPosition(err.Pos, testPath)
+		}
+		issues = append(issues, issue)
+	}
+	if mapper == nil {
+		return &CheckCodeResult{Valid: len(issues) == 0, Issues: issues}
+	}
+
+	switch t.kind.Directive {
+	case codegen.Benchmarks.Directive:
+		issues = append(issues, checkBenchLoop(mapper)...)
+	case codegen.FuzzTests.Directive:
+		issues = append(issues, checkFuzzTarget(pkg, mapper)...)
+	}
+	if t.goVersion != "" {
+		issues = append(issues, checkGoVersion(pkg, mapper, t.goVersion)...)
+	}
+	return &CheckCodeResult{Valid: len(issues) == 0, Issues: issues}
+}
+
+// testFuncMapper maps positions of the test function's body (nil if it is not in the package)
+func testFuncMapper(pkg *packages.Package, testPath, name string) *PositionMapper {
+	for _, file := range pkg.Syntax {
+		if pkg.Fset.Position(file.Pos()).Filename != testPath {
+			continue
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Name.Name != name || fn.Body == nil {
+				continue
+			}
+			bodyStart := fn.Body.Lbrace + 1
+			return &PositionMapper{
+				funcDecl:      fn,
+				bodyStart:     bodyStart,
+				bodyEnd:       fn.Body.Rbrace,
+				fileSet:       pkg.Fset,
+				startPosition: pkg.Fset.Position(bodyStart),
+			}
+		}
+	}
+	return nil
+}
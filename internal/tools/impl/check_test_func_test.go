@@ -0,0 +1,157 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rail44/mantra/internal/codegen"
+	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/tools"
+)
+
+func TestCheckBenchTool(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "sum.go")
+
+	testFileContent := `package sum
+
+// mantra: Add up the numbers
+func Sum(numbers []int) int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module example.com/sum\n\ngo 1.24\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "sum",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Sum",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "numbers", Type: "[]int"}},
+		Returns:  []parser.Return{{Type: "int"}},
+	}
+	implementation := "total := 0\nfor _, n := range numbers {\n\ttotal += n\n}\nreturn total"
+	setup := "numbers := make([]int, 1024)\nfor i := range numbers {\n\tnumbers[i] = i\n}\n"
+
+	for _, tt := range []struct {
+		name      string
+		code      string
+		goVersion string
+		want      []string // Issue codes
+	}{
+		{"loop", setup + "for b.Loop() {\n\tSum(numbers)\n}", "", nil},
+		{"range b.N", setup + "var total int\nfor range b.N {\n\ttotal = Sum(numbers)\n}\n_ = total", "", nil},
+		{"counted b.N", setup + "for i := 0; i < b.N; i++ {\n\tSum(numbers)\n}\nb.ReportMetric(float64(len(numbers)), \"numbers/op\")", "", nil},
+		{"no loop", setup + "Sum(numbers)", "", []string{"bench_loop"}},
+		{"b.N outside a loop", setup + "Sum(numbers[:b.N%len(numbers)])", "", []string{"bench_loop"}},
+		{"both", setup + "for b.Loop() {\n\tSum(numbers)\n}\nb.Log(b.N)", "", []string{"bench_loop"}},
+		{"b.Loop in the body", setup + "for {\n\tif !b.Loop() {\n\t\tbreak\n\t}\n\tSum(numbers)\n}", "", []string{"bench_loop"}},
+		{"compile error", "for b.Loop() {\n\tProduct(nil)\n}", "", []string{"package_error"}},
+		{"b.Loop before Go 1.24", setup + "for b.Loop() {\n\tSum(numbers)\n}", "1.23", []string{"go_version"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewCheckTestFuncTool(codegen.Benchmarks, tmpDir, implementation).WithGoVersion(tt.goVersion)
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+			result, err := tool.Execute(context.Background(), map[string]any{"code": tt.code})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+
+			checkResult := result.(*CheckCodeResult)
+			var got []string
+			for _, issue := range checkResult.Issues {
+				got = append(got, issue.Code)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected issues %v, got %+v", tt.want, checkResult.Issues)
+			}
+			if checkResult.Valid != (len(tt.want) == 0) {
+				t.Errorf("Expected valid=%v, got %v", len(tt.want) == 0, checkResult.Valid)
+			}
+		})
+	}
+}
+
+func TestCheckFuzzTool(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "count.go")
+
+	testFileContent := `package count
+
+// mantra: Count the vowels of s
+func Vowels(s string) int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module example.com/count\n\ngo 1.24\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "count",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Vowels",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "s", Type: "string"}},
+		Returns:  []parser.Return{{Type: "int"}},
+	}
+	implementation := "n := 0\nfor _, r := range s {\n\tif r == 'a' || r == 'e' || r == 'i' || r == 'o' || r == 'u' {\n\t\tn++\n\t}\n}\nreturn n"
+	property := "if n := Vowels(s); n < 0 || n > len(s) {\n\tt.Errorf(\"Vowels(%q) = %d\", s, n)\n}"
+
+	for _, tt := range []struct {
+		name string
+		code string
+		want []string // Issue codes
+	}{
+		{"valid", "f.Add(\"hello\")\nf.Add(\"\")\nf.Fuzz(func(t *testing.T, s string) {\n" + property + "\n})", nil},
+		{"converted seeds", "f.Add(\"abc\", int64(2))\nf.Fuzz(func(t *testing.T, s string, n int64) {\n\t_ = Vowels(s[:min(int(max(n, 0)), len(s))])\n})", nil},
+		{"no seeds", "f.Fuzz(func(t *testing.T, s string) {\n" + property + "\n})", []string{"fuzz_target"}},
+		{"no f.Fuzz", "f.Add(\"hello\")\n_ = Vowels(\"hello\")", []string{"fuzz_target"}},
+		{"seed of the wrong type", "f.Add(\"abc\", 2)\nf.Fuzz(func(t *testing.T, s string, n int64) {\n\t_ = Vowels(s)\n\t_ = n\n})", []string{"fuzz_target"}},
+		{"seed of the wrong count", "f.Add(\"abc\", \"d\")\nf.Fuzz(func(t *testing.T, s string) {\n" + property + "\n})", []string{"fuzz_target"}},
+		{"unsupported argument", "f.Add(\"abc\")\nf.Fuzz(func(t *testing.T, s []string) {\n\t_ = s\n})", []string{"fuzz_target"}},
+		{"missing *testing.T", "f.Add(\"abc\")\nf.Fuzz(func(s string) {\n\t_ = Vowels(s)\n})", []string{"fuzz_target"}},
+		{"f inside the fuzz function", "f.Add(\"abc\")\nf.Fuzz(func(t *testing.T, s string) {\n\tf.Log(Vowels(s))\n\tf.Log(s)\n})", []string{"fuzz_target"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewCheckTestFuncTool(codegen.FuzzTests, tmpDir, implementation)
+			tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+			if tool.Name() != "check_fuzz" {
+				t.Errorf("Expected tool name check_fuzz, got %s", tool.Name())
+			}
+			result, err := tool.Execute(context.Background(), map[string]any{"code": tt.code})
+			if err != nil {
+				t.Fatalf("Failed to execute tool: %v", err)
+			}
+
+			checkResult := result.(*CheckCodeResult)
+			var got []string
+			for _, issue := range checkResult.Issues {
+				got = append(got, issue.Code)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected issues %v, got %+v", tt.want, checkResult.Issues)
+			}
+			if checkResult.Valid != (len(tt.want) == 0) {
+				t.Errorf("Expected valid=%v, got %v", len(tt.want) == 0, checkResult.Valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package impl
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/rail44/mantra/internal/conventions"
+)
+
+// checkComplexity reports generated bodies over the project's complexity budgets. Function
+// literals count as functions of their own, so moving logic into helper closures keeps the
+// body within the cyclomatic budget
+func checkComplexity(mapper *PositionMapper, conv conventions.ComplexityConventions) []Issue {
+	body := mapper.funcDecl.Body
+	if body == nil {
+		return nil
+	}
+
+	var issues []Issue
+	if conv.MaxLines > 0 {
+		lines := mapper.fileSet.Position(body.Rbrace).Line - mapper.fileSet.Position(body.Lbrace).Line - 1
+		if lines > conv.MaxLines {
+			issues = append(issues, Issue{
+				Code:    "function_length",
+				Message: fmt.Sprintf("the body is %d lines long, over the budget of %d; shorten it, e.g. by removing repetition or moving steps into helper closures", lines, conv.MaxLines),
+			})
+		}
+	}
+
+	if conv.MaxCyclomatic > 0 {
+		check := func(node ast.Node, name string) {
+			if complexity := cyclomatic(node); complexity > conv.MaxCyclomatic {
+				line, column := mapper.ToRelativePosition(node.Pos())
+				if node == body {
+					line, column = 0, 0
+				}
+				issues = append(issues, Issue{
+					Code: "complexity",
+					Message: fmt.Sprintf("%s has cyclomatic complexity %d, over the budget of %d; simplify it with early returns or lookup tables, or move branches into helper closures",
+						name, complexity, conv.MaxCyclomatic),
+					Line:   line,
+					Column: column,
+				})
+			}
+		}
+		check(body, "the body")
+		ast.Inspect(body, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.FuncLit); ok {
+				check(lit.Body, "the function literal")
+			}
+			return true
+		})
+	}
+
+	return issues
+}
+
+// cyclomatic returns the cyclomatic complexity of a function body: one plus the number of
+// branches, loops, non-default cases and && or || operators, leaving out function literals
+func cyclomatic(body ast.Node) int {
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
@@ -0,0 +1,238 @@
+package impl
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/rail44/mantra/internal/conventions"
+)
+
+// checkErrorConventions reports violations of the project's error conventions in the generated body
+func checkErrorConventions(pkg *packages.Package, mapper *PositionMapper, conv conventions.ErrorConventions) []Issue {
+	if pkg.TypesInfo == nil || mapper.funcDecl.Body == nil {
+		return nil
+	}
+
+	var sentinels map[string]string
+	if conv.ReuseSentinels {
+		sentinels = collectSentinelErrors(pkg)
+	}
+
+	var issues []Issue
+	report := func(node ast.Node, code, message string) {
+		line, column := mapper.ToRelativePosition(node.Pos())
+		issues = append(issues, Issue{
+			Code:    code,
+			Message: message,
+			Line:    line,
+			Column:  column,
+		})
+	}
+
+	ast.Inspect(mapper.funcDecl.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+
+		isErrorf := isPackageFunc(pkg.TypesInfo, call, "fmt", "Errorf")
+		isNew := isPackageFunc(pkg.TypesInfo, call, "errors", "New")
+		if !isErrorf && !isNew {
+			return true
+		}
+
+		message, ok := stringConstant(pkg.TypesInfo, call.Args[0])
+		if !ok {
+			return true
+		}
+
+		if conv.LowercaseMessages && startsWithCapital(message) {
+			report(call, "error_message_case", fmt.Sprintf("error strings should not be capitalized: %q", message))
+		}
+
+		if isErrorf && conv.RequireWrap && !strings.Contains(message, "%w") && hasErrorArg(pkg.TypesInfo, call.Args[1:]) {
+			report(call, "error_wrap", "fmt.Errorf is given an error but does not wrap it; use %w so callers can inspect it with errors.Is/As")
+		}
+
+		if name, exists := sentinels[message]; exists {
+			report(call, "error_sentinel", fmt.Sprintf("error message %q duplicates sentinel %s; return %s (or wrap it with %%w) instead", message, name, name))
+		}
+
+		return true
+	})
+
+	if conv.Style != "" {
+		issues = append(issues, checkErrorStyle(pkg, mapper, conv.Style)...)
+	}
+	return issues
+}
+
+// checkErrorStyle reports error results of the generated body that do not follow the
+// project's error style. Only functions whose last result is an error are checked, and
+// function literals in the body are left alone
+func checkErrorStyle(pkg *packages.Package, mapper *PositionMapper, style string) []Issue {
+	results := mapper.funcDecl.Type.Results
+	if results == nil || len(results.List) == 0 {
+		return nil
+	}
+	if t := pkg.TypesInfo.TypeOf(results.List[len(results.List)-1].Type); t == nil || !isErrorType(t) {
+		return nil
+	}
+	count := results.NumFields()
+
+	var issues []Issue
+	report := func(node ast.Node, message string) {
+		line, column := mapper.ToRelativePosition(node.Pos())
+		issues = append(issues, Issue{Code: "error_style", Message: message, Line: line, Column: column})
+	}
+
+	ast.Inspect(mapper.funcDecl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			// Several errors folded into one message lose all but one for errors.Is
+			if style == conventions.ErrorStyleJoin && isPackageFunc(pkg.TypesInfo, n, "fmt", "Errorf") && errorArgs(pkg.TypesInfo, n.Args[1:]) > 1 {
+				report(n, "fmt.Errorf combines several errors; use errors.Join so callers can match each with errors.Is")
+			}
+		case *ast.ReturnStmt:
+			if len(n.Results) != count {
+				return true
+			}
+			result := ast.Unparen(n.Results[count-1])
+			if ident, ok := result.(*ast.Ident); ok && ident.Name == "nil" {
+				return true
+			}
+			switch style {
+			case conventions.ErrorStyleSentinel:
+				if call, ok := result.(*ast.CallExpr); ok && createsError(pkg.TypesInfo, call) {
+					report(call, "errors are package-level sentinels here; declare var ErrXxx = errors.New(...) and return it, or wrap it with %w, instead of creating an error inline")
+				}
+			case conventions.ErrorStyleWrap:
+				if ident, ok := result.(*ast.Ident); ok && isLocalVar(pkg, ident) {
+					report(ident, fmt.Sprintf("errors are wrapped with context here; return fmt.Errorf(\"...: %%w\", %s) instead of %s as is", ident.Name, ident.Name))
+				}
+			}
+		}
+		return true
+	})
+
+	return issues
+}
+
+// createsError reports whether call builds a new error: errors.New, or fmt.Errorf without %w
+func createsError(info *types.Info, call *ast.CallExpr) bool {
+	if isPackageFunc(info, call, "errors", "New") {
+		return true
+	}
+	if !isPackageFunc(info, call, "fmt", "Errorf") || len(call.Args) == 0 {
+		return false
+	}
+	format, ok := stringConstant(info, call.Args[0])
+	return ok && !strings.Contains(format, "%w")
+}
+
+// isLocalVar reports whether ident refers to a variable declared in a function, including
+// parameters and named results, rather than at package level
+func isLocalVar(pkg *packages.Package, ident *ast.Ident) bool {
+	v, ok := pkg.TypesInfo.Uses[ident].(*types.Var)
+	return ok && v.Parent() != nil && v.Parent() != pkg.Types.Scope()
+}
+
+// isErrorType reports whether t is the error interface
+func isErrorType(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// errorArgs counts the arguments that implement the error interface
+func errorArgs(info *types.Info, args []ast.Expr) int {
+	n := 0
+	for _, arg := range args {
+		if hasErrorArg(info, []ast.Expr{arg}) {
+			n++
+		}
+	}
+	return n
+}
+
+// collectSentinelErrors maps messages of package-level errors.New sentinels to their variable names
+func collectSentinelErrors(pkg *packages.Package) map[string]string {
+	sentinels := make(map[string]string)
+
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok || len(vs.Names) != len(vs.Values) {
+					continue
+				}
+				for i, value := range vs.Values {
+					call, ok := value.(*ast.CallExpr)
+					if !ok || len(call.Args) != 1 || !isPackageFunc(pkg.TypesInfo, call, "errors", "New") {
+						continue
+					}
+					if message, ok := stringConstant(pkg.TypesInfo, call.Args[0]); ok {
+						sentinels[message] = vs.Names[i].Name
+					}
+				}
+			}
+		}
+	}
+
+	return sentinels
+}
+
+// isPackageFunc reports whether call invokes pkgPath.name
+func isPackageFunc(info *types.Info, call *ast.CallExpr, pkgPath, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	pkgName, ok := info.Uses[ident].(*types.PkgName)
+	return ok && pkgName.Imported().Path() == pkgPath
+}
+
+// stringConstant returns the value of a constant string expression
+func stringConstant(info *types.Info, expr ast.Expr) (string, bool) {
+	tv, ok := info.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// hasErrorArg reports whether any argument implements the error interface
+func hasErrorArg(info *types.Info, args []ast.Expr) bool {
+	errorType := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	for _, arg := range args {
+		if t := info.TypeOf(arg); t != nil && types.Implements(t, errorType) {
+			return true
+		}
+	}
+	return false
+}
+
+// startsWithCapital reports whether s starts with a capital letter that is not part of an acronym
+func startsWithCapital(s string) bool {
+	first, size := utf8.DecodeRuneInString(s)
+	if !unicode.IsUpper(first) {
+		return false
+	}
+	second, _ := utf8.DecodeRuneInString(s[size:])
+	return !unicode.IsUpper(second)
+}
@@ -0,0 +1,145 @@
+package impl
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// fuzzArgTypes are the parameter types go test can generate values of for a fuzz function
+var fuzzArgTypes = []types.Type{
+	types.Typ[types.String], types.NewSlice(types.Typ[types.Byte]), types.Typ[types.Bool],
+	types.Typ[types.Int], types.Typ[types.Int8], types.Typ[types.Int16], types.Typ[types.Int32], types.Typ[types.Int64],
+	types.Typ[types.Uint], types.Typ[types.Uint8], types.Typ[types.Uint16], types.Typ[types.Uint32], types.Typ[types.Uint64],
+	types.Typ[types.Float32], types.Typ[types.Float64],
+}
+
+// checkFuzzTarget reports fuzz tests that go test would reject when run: the body must call
+// f.Fuzz once with a function of a *testing.T and supported argument types, seed the corpus
+// with f.Add calls matching those types, and leave f alone inside the fuzz function
+func checkFuzzTarget(pkg *packages.Package, mapper *PositionMapper) []Issue {
+	fn := mapper.funcDecl
+	if pkg.TypesInfo == nil || len(fn.Type.Params.List) == 0 || len(fn.Type.Params.List[0].Names) == 0 {
+		return nil
+	}
+	info := pkg.TypesInfo
+	fIdent := fn.Type.Params.List[0].Names[0]
+	f := info.Defs[fIdent]
+	if f == nil {
+		return nil
+	}
+
+	var issues []Issue
+	report := func(pos token.Pos, message string) {
+		line, column := mapper.ToRelativePosition(pos)
+		issues = append(issues, Issue{Code: "fuzz_target", Message: message, Line: line, Column: column})
+	}
+
+	// fMethod returns the method of f a call invokes ("" if it is not a call on f)
+	fMethod := func(call *ast.CallExpr) string {
+		sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok {
+			return ""
+		}
+		if ident, ok := sel.X.(*ast.Ident); ok && info.Uses[ident] == f {
+			return sel.Sel.Name
+		}
+		return ""
+	}
+
+	var fuzzCalls, addCalls []*ast.CallExpr
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		switch fMethod(call) {
+		case "Fuzz":
+			fuzzCalls = append(fuzzCalls, call)
+		case "Add":
+			addCalls = append(addCalls, call)
+		}
+		return true
+	})
+
+	name := fIdent.Name
+	if len(fuzzCalls) != 1 {
+		pos := fn.Body.Lbrace
+		if len(fuzzCalls) > 1 {
+			pos = fuzzCalls[1].Pos()
+		}
+		report(pos, fmt.Sprintf("call %s.Fuzz exactly once, with the fuzz function: %[1]s.Fuzz(func(t *testing.T, ...) { ... })", name))
+		return issues
+	}
+	if len(addCalls) == 0 {
+		report(fuzzCalls[0].Pos(), fmt.Sprintf("seed the corpus with %s.Add(...) calls before %[1]s.Fuzz, with inputs taken from the instruction and edge cases", name))
+	}
+
+	call := fuzzCalls[0]
+	if len(call.Args) != 1 {
+		return issues
+	}
+	sig, ok := info.TypeOf(call.Args[0]).(*types.Signature)
+	if !ok {
+		return issues
+	}
+	params := sig.Params()
+	if params.Len() < 2 || types.TypeString(params.At(0).Type(), nil) != "*testing.T" {
+		report(call.Args[0].Pos(), fmt.Sprintf("the fuzz function must take a *testing.T followed by the fuzzed arguments: %s.Fuzz(func(t *testing.T, input string) { ... })", name))
+		return issues
+	}
+
+	var argTypes []types.Type
+	for i := 1; i < params.Len(); i++ {
+		argType := params.At(i).Type()
+		if !isFuzzArgType(argType) {
+			report(call.Args[0].Pos(), fmt.Sprintf("fuzz arguments cannot be of type %s; use string, []byte, bool, or a sized or unsized integer or float type, and convert inside the fuzz function",
+				types.TypeString(argType, types.RelativeTo(pkg.Types))))
+			return issues
+		}
+		argTypes = append(argTypes, argType)
+	}
+
+	for _, add := range addCalls {
+		if len(add.Args) != len(argTypes) {
+			report(add.Pos(), fmt.Sprintf("%s.Add takes %d value(s), one for each argument of the fuzz function after t", name, len(argTypes)))
+			continue
+		}
+		for i, arg := range add.Args {
+			argType := types.Default(info.TypeOf(arg))
+			if argType != nil && !types.Identical(argType, argTypes[i]) {
+				report(arg.Pos(), fmt.Sprintf("seed value %d of %s.Add is a %s but the fuzz function takes a %s; convert it, e.g. %s(...)",
+					i+1, name, argType, argTypes[i], argTypes[i]))
+			}
+		}
+	}
+
+	// Only the *testing.T may be used once fuzzing started
+	if lit, ok := call.Args[0].(*ast.FuncLit); ok {
+		reported := false
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && info.Uses[ident] == f && !reported {
+				report(ident.Pos(), fmt.Sprintf("%s must not be used inside the fuzz function; use its *testing.T instead", name))
+				reported = true
+			}
+			return !reported
+		})
+	}
+
+	return issues
+}
+
+// isFuzzArgType reports whether go test can generate values of t for a fuzz function. byte
+// and rune are identical to uint8 and int32; named types are not accepted, even of a
+// supported underlying type
+func isFuzzArgType(t types.Type) bool {
+	for _, allowed := range fuzzArgTypes {
+		if types.Identical(t, allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package impl
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/analysis/passes/stdversion"
+	"golang.org/x/tools/go/packages"
+)
+
+// WithGoVersion rejects generated code that needs a newer Go release than version
+// (e.g. "1.21"): language features such as generics or range over integers, and
+// standard library symbols added later
+func (t *CheckCodeTool) WithGoVersion(version string) *CheckCodeTool {
+	t.goVersion = version
+	return t
+}
+
+// checkGoVersion type-checks the package again as if it targeted version and reports the
+// language features and standard library symbols of the generated body that need a newer
+// release. Standard library symbols are only checked for Go 1.21 and later
+func checkGoVersion(pkg *packages.Package, mapper *PositionMapper, version string) []Issue {
+	if pkg.Types == nil || len(pkg.Syntax) == 0 {
+		return nil
+	}
+
+	var issues []Issue
+	add := func(err error) {
+		var typeErr types.Error
+		if !errors.As(err, &typeErr) || !mapper.IsInGeneratedCode(typeErr.Pos) {
+			return
+		}
+		line, column := mapper.ToRelativePosition(typeErr.Pos)
+		issues = append(issues, Issue{
+			Code:    "go_version",
+			Message: fmt.Sprintf("%s, but the project targets Go %s", typeErr.Msg, strings.TrimPrefix(version, "go")),
+			Line:    line,
+			Column:  column,
+		})
+	}
+
+	goVersion := "go" + strings.TrimPrefix(version, "go")
+	info := &types.Info{
+		Types:        make(map[ast.Expr]types.TypeAndValue),
+		Defs:         make(map[*ast.Ident]types.Object),
+		Uses:         make(map[*ast.Ident]types.Object),
+		Implicits:    make(map[ast.Node]types.Object),
+		Selections:   make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:       make(map[ast.Node]*types.Scope),
+		FileVersions: make(map[*ast.File]string),
+	}
+	conf := &types.Config{
+		GoVersion: goVersion,
+		Importer:  packageImporter(pkg),
+		Error: func(err error) {
+			// Only version errors are new; everything else was reported by the first check
+			if strings.Contains(err.Error(), "requires go1.") {
+				add(err)
+			}
+		},
+	}
+	checked, _ := conf.Check(pkg.PkgPath, pkg.Fset, pkg.Syntax, info)
+
+	rechecked := &packages.Package{
+		PkgPath:   pkg.PkgPath,
+		Fset:      pkg.Fset,
+		Syntax:    pkg.Syntax,
+		Types:     checked,
+		TypesInfo: info,
+	}
+	results := make(map[*analysis.Analyzer]any)
+	if result, err := runAnalyzer(inspect.Analyzer, rechecked, results, nil); err == nil {
+		results[inspect.Analyzer] = result
+	}
+	runAnalyzerSafe(stdversion.Analyzer, rechecked, results, func(diag analysis.Diagnostic) {
+		// The analyzer names the version as the module's; it is the configured one
+		msg, _, _ := strings.Cut(diag.Message, " (module is")
+		add(types.Error{Fset: pkg.Fset, Pos: diag.Pos, Msg: msg})
+	})
+	return issues
+}
+
+// packageImporter resolves imports to the packages already loaded with pkg
+func packageImporter(pkg *packages.Package) types.Importer {
+	return importerFunc(func(path string) (*types.Package, error) {
+		if path == "unsafe" {
+			return types.Unsafe, nil
+		}
+		if imported, ok := pkg.Imports[path]; ok && imported.Types != nil {
+			return imported.Types, nil
+		}
+		return nil, fmt.Errorf("package %s was not loaded", path)
+	})
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
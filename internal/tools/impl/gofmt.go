@@ -0,0 +1,113 @@
+package impl
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// gofmtPrefix wraps a function body into a file gofmt accepts; the body starts on line 4
+const gofmtPrefix = "package p\n\nfunc _() {\n"
+
+// FormatBody returns code as gofmt formats it inside a function, without the indentation of
+// the function body. Code that does not parse is returned unchanged
+func FormatBody(code string) string {
+	formatted, ok := formatBody(code)
+	if !ok {
+		return code
+	}
+	return formatted
+}
+
+// formatBody returns the formatted body of code and whether it parses
+func formatBody(code string) (string, bool) {
+	src := indentBody(code)
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		return "", false
+	}
+
+	// Drop the wrapping and the indentation gofmt gives the body
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	raw := rawStringLines(string(out))
+	start := strings.Count(gofmtPrefix, "\n")
+	body := lines[start : len(lines)-1]
+	for i, line := range body {
+		if !raw[start+i+1] {
+			body[i] = strings.TrimPrefix(line, "\t")
+		}
+	}
+	return strings.Join(body, "\n"), true
+}
+
+// indentBody wraps code into a file, indenting its lines as gofmt indents a function body.
+// Lines inside raw string literals are kept as they are, as gofmt keeps them
+func indentBody(code string) string {
+	raw := rawStringLines(gofmtPrefix + code + "\n}\n")
+	start := strings.Count(gofmtPrefix, "\n")
+
+	var b strings.Builder
+	b.WriteString(gofmtPrefix)
+	for i, line := range strings.Split(code, "\n") {
+		if line != "" && !raw[start+i+1] {
+			b.WriteString("\t")
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// rawStringLines returns the lines of src that continue a multi-line raw string literal
+func rawStringLines(src string) map[int]bool {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	lines := make(map[int]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING || !strings.HasPrefix(lit.Value, "`") {
+			return true
+		}
+		first := fset.Position(lit.Pos()).Line
+		last := fset.Position(lit.End()).Line
+		for line := first + 1; line <= last; line++ {
+			lines[line] = true
+		}
+		return true
+	})
+	return lines
+}
+
+// checkFormatting reports a body gofmt would change, with the formatted body to submit
+// instead. Code that does not parse is left to the compiler errors
+func checkFormatting(code string) []Issue {
+	formatted, ok := formatBody(code)
+	if !ok || formatted == code {
+		return nil
+	}
+
+	// First line that differs
+	got := strings.Split(code, "\n")
+	want := strings.Split(formatted, "\n")
+	line := min(len(got), len(want)) + 1
+	for i := range min(len(got), len(want)) {
+		if got[i] != want[i] {
+			line = i + 1
+			break
+		}
+	}
+
+	return []Issue{{
+		Code:    "gofmt",
+		Message: fmt.Sprintf("the body is not formatted with gofmt; submit it formatted, without the indentation of the function body:\n%s", formatted),
+		Line:    line,
+	}}
+}
@@ -0,0 +1,160 @@
+package impl
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/rail44/mantra/internal/conventions"
+)
+
+// clockFuncs are the time functions that read the wall clock
+var clockFuncs = map[string]bool{"Now": true, "Since": true, "Until": true}
+
+// randPackages are the packages whose top-level functions use a global random source
+var randPackages = map[string]bool{"math/rand": true, "math/rand/v2": true}
+
+// randConstructors build a source or generator rather than drawing from the global one
+var randConstructors = map[string]bool{
+	"New": true, "NewSource": true, "NewPCG": true, "NewChaCha8": true, "NewZipf": true,
+}
+
+// randMethods are methods of random generators; a type with one of them is taken as one
+var randMethods = []string{"Intn", "IntN", "Int63", "Int64", "Float64", "Uint64", "Perm", "Shuffle"}
+
+// checkInjection reports reads of the wall clock and the global random source in the generated
+// body when the receiver or the package provides an injectable clock or random source, which
+// tests replace to make results reproducible
+func checkInjection(pkg *packages.Package, mapper *PositionMapper, conv conventions.InjectionConventions) []Issue {
+	if pkg.TypesInfo == nil || mapper.funcDecl.Body == nil || (!conv.Clock && !conv.Random) {
+		return nil
+	}
+
+	var clock, random string
+	if conv.Clock {
+		clock = injectedClock(pkg, mapper.funcDecl)
+	}
+	if conv.Random {
+		random = injectedRandom(pkg, mapper.funcDecl)
+	}
+	if clock == "" && random == "" {
+		return nil
+	}
+
+	var issues []Issue
+	ast.Inspect(mapper.funcDecl.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		pkgName, ok := pkg.TypesInfo.Uses[ident].(*types.PkgName)
+		if !ok {
+			return true
+		}
+
+		var code, message string
+		path, name := pkgName.Imported().Path(), sel.Sel.Name
+		switch {
+		case clock != "" && path == "time" && clockFuncs[name]:
+			code = "clock_injection"
+			message = fmt.Sprintf("time.%s reads the wall clock, which tests cannot control; use %s instead", name, clock)
+		case random != "" && randPackages[path] && !randConstructors[name]:
+			code = "rand_injection"
+			message = fmt.Sprintf("%s.%s draws from the global random source, which tests cannot seed; use %s instead", ident.Name, name, random)
+		default:
+			return true
+		}
+		line, column := mapper.ToRelativePosition(call.Pos())
+		issues = append(issues, Issue{Code: code, Message: message, Line: line, Column: column})
+		return true
+	})
+
+	return issues
+}
+
+// injectedClock returns how the body should read the time, e.g. "s.clock.Now()" or "now()":
+// a receiver field, or else a package-level variable, that is a func() time.Time or has a
+// Now() time.Time method. It returns "" when there is none
+func injectedClock(pkg *packages.Package, fn *ast.FuncDecl) string {
+	return injected(pkg, fn, func(t types.Type) (string, bool) {
+		if isTimeFunc(t) {
+			return "()", true
+		}
+		if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "Now"); obj != nil {
+			if method, ok := obj.(*types.Func); ok && isTimeFunc(method.Type()) {
+				return ".Now()", true
+			}
+		}
+		return "", false
+	})
+}
+
+// injectedRandom returns the random generator the body should draw from, e.g. "s.rng": a
+// receiver field, or else a package-level variable, that is a *rand.Rand or has the methods
+// of one. It returns "" when there is none
+func injectedRandom(pkg *packages.Package, fn *ast.FuncDecl) string {
+	return injected(pkg, fn, func(t types.Type) (string, bool) {
+		for _, name := range randMethods {
+			if obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name); obj != nil {
+				if _, ok := obj.(*types.Func); ok {
+					return "", true
+				}
+			}
+		}
+		return "", false
+	})
+}
+
+// injected finds the first receiver field, or else the first package-level variable by name,
+// whose type match accepts, and returns its expression followed by the suffix match returns
+func injected(pkg *packages.Package, fn *ast.FuncDecl, match func(types.Type) (string, bool)) string {
+	if fn.Recv != nil && len(fn.Recv.List) > 0 && len(fn.Recv.List[0].Names) > 0 {
+		recvName := fn.Recv.List[0].Names[0].Name
+		if recv := pkg.TypesInfo.TypeOf(fn.Recv.List[0].Type); recv != nil && recvName != "_" {
+			if ptr, ok := recv.(*types.Pointer); ok {
+				recv = ptr.Elem()
+			}
+			if st, ok := recv.Underlying().(*types.Struct); ok {
+				for i := range st.NumFields() {
+					field := st.Field(i)
+					if suffix, ok := match(field.Type()); ok && !field.Embedded() {
+						return recvName + "." + field.Name() + suffix
+					}
+				}
+			}
+		}
+	}
+
+	scope := pkg.Types.Scope()
+	names := scope.Names()
+	sort.Strings(names)
+	for _, name := range names {
+		if v, ok := scope.Lookup(name).(*types.Var); ok {
+			if suffix, ok := match(v.Type()); ok {
+				return name + suffix
+			}
+		}
+	}
+	return ""
+}
+
+// isTimeFunc reports whether t is a function without parameters returning only a time.Time
+func isTimeFunc(t types.Type) bool {
+	sig, ok := t.Underlying().(*types.Signature)
+	if !ok || sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	named, ok := sig.Results().At(0).Type().(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "time" && named.Obj().Name() == "Time"
+}
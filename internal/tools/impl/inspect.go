@@ -25,6 +25,13 @@ func NewInspectTool(packagePath string) *InspectTool {
 	}
 }
 
+// WithOverlay makes inspect see in-memory file contents, such as stubs of the generated
+// package, in place of those on disk
+func (t *InspectTool) WithOverlay(overlay map[string][]byte) *InspectTool {
+	t.loader.WithOverlay(overlay)
+	return t
+}
+
 // Name returns the tool name
 func (t *InspectTool) Name() string {
 	return "inspect"
@@ -32,7 +39,7 @@ func (t *InspectTool) Name() string {
 
 // Description returns what this tool does
 func (t *InspectTool) Description() string {
-	return "Get detailed information about Go declarations from current package or imported packages (e.g., 'SimpleCache', 'time.Time')"
+	return "Get detailed information about Go declarations from the current package, imported packages or other packages of the project (e.g., 'SimpleCache', 'time.Time', 'legacy.parseRow'). Unexported project declarations come with a warning that they cannot be referenced directly. For an interface method (e.g., 'UserRepository.GetByEmail') it returns the concrete implementations in the project and the body of the most likely one"
 }
 
 // ParametersSchema returns the JSON Schema for parameters
@@ -50,26 +57,41 @@ func (t *InspectTool) ParametersSchema() json.RawMessage {
 	}`)
 }
 
+// notFoundSuggestion tells the model how to recover from an unknown symbol
+const notFoundSuggestion = "Use search with a wildcard pattern to find the exact name before inspecting again"
+
 // Execute runs the inspect tool
 func (t *InspectTool) Execute(ctx context.Context, params map[string]any) (any, error) {
 	// Extract parameters
 	name, ok := params["name"].(string)
 	if !ok {
-		return nil, &tools.ToolError{
-			Code:    "invalid_params",
-			Message: "Parameter 'name' is required and must be a string",
-		}
+		return nil, tools.NewInvalidParamsError("Parameter 'name' is required and must be a string", "Pass a symbol name such as 'User' or 'pkg.Func'")
+	}
+
+	// Load the package up front so a timeout or cancellation aborts it
+	if err := t.loader.LoadContext(ctx); err != nil {
+		return nil, tools.NewInternalError("Failed to load package", err)
 	}
 
 	// Try to get the declaration using the loader
-	decl, err := t.loader.GetDeclaration(name)
+	decl, err := t.loader.GetDeclarationContext(ctx, name)
 	if err != nil {
 		// Return JSON-serializable map for not found
 		return map[string]any{
-			"found": false,
-			"name":  name,
-			"kind":  "not_found",
-			"error": fmt.Sprintf("Declaration '%s' not found", name),
+			"found":      false,
+			"name":       name,
+			"kind":       "not_found",
+			"error":      fmt.Sprintf("Declaration '%s' not found", name),
+			"suggestion": notFoundSuggestion,
+		}, nil
+	}
+	if nf, ok := decl.(*pkgcontext.NotFoundDeclaration); ok && nf.Error != "" {
+		return map[string]any{
+			"found":      false,
+			"name":       name,
+			"kind":       "not_found",
+			"error":      nf.Error,
+			"suggestion": notFoundSuggestion,
 		}, nil
 	}
 
@@ -90,6 +112,9 @@ func convertDeclarationToMap(decl pkgcontext.Declaration) map[string]any {
 		"kind":    decl.GetKind(),
 		"package": decl.GetPackage(),
 	}
+	if warning := decl.GetWarning(); warning != "" {
+		result["warning"] = warning
+	}
 
 	// Add type-specific fields based on the concrete type
 	switch d := decl.(type) {
@@ -126,9 +151,20 @@ func convertDeclarationToMap(decl pkgcontext.Declaration) map[string]any {
 			result["doc"] = d.Doc
 		}
 
+	case *pkgcontext.MethodImplementationDeclaration:
+		result["interface"] = d.Interface
+		result["signature"] = d.Signature
+		result["implementations"] = d.Implementations
+		if len(d.Implementations) == 0 {
+			result["note"] = "No concrete implementation of this interface method was found in the project"
+		}
+
 	case *pkgcontext.ConstantDeclaration:
 		result["type"] = d.Type
 		result["value"] = d.Value
+		if d.Group != "" {
+			result["group"] = d.Group
+		}
 		if d.Doc != "" {
 			result["doc"] = d.Doc
 		}
@@ -145,6 +181,9 @@ func convertDeclarationToMap(decl pkgcontext.Declaration) map[string]any {
 	case *pkgcontext.TypeAliasDeclaration:
 		result["definition"] = d.Definition
 		result["type"] = d.Type
+		if d.Resolved != "" {
+			result["resolved_type"] = d.Resolved
+		}
 		if len(d.Methods) > 0 {
 			result["methods"] = d.Methods
 		}
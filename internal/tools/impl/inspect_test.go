@@ -0,0 +1,189 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgcontext "github.com/rail44/mantra/internal/context"
+)
+
+func TestInspectTool_InterfaceMethodImplementations(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"repo.go": `package app
+
+// UserRepository loads users
+type UserRepository interface {
+	GetByEmail(email string) (string, error)
+}
+
+type mockUserRepository struct{}
+
+func (m *mockUserRepository) GetByEmail(email string) (string, error) { return "mock", nil }
+`,
+		"postgres/repo.go": `package postgres
+
+// PostgresUserRepository reads users from Postgres
+type PostgresUserRepository struct{}
+
+// GetByEmail queries the users table
+func (r *PostgresUserRepository) GetByEmail(email string) (string, error) {
+	return "SELECT name FROM users WHERE email = $1", nil
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tool := NewInspectTool(root)
+	result, err := tool.Execute(context.Background(), map[string]any{"name": "UserRepository.GetByEmail"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	m := result.(map[string]any)
+	if m["kind"] != "interface_method" {
+		t.Fatalf("Expected interface_method, got %v", m)
+	}
+
+	impls := m["implementations"].([]pkgcontext.Implementation)
+	if len(impls) != 2 {
+		t.Fatalf("Expected 2 implementations, got %+v", impls)
+	}
+	if impls[0].TypeName != "PostgresUserRepository" {
+		t.Errorf("Expected the real implementation first, got %s", impls[0].TypeName)
+	}
+	if !strings.Contains(impls[0].Source, "SELECT name FROM users") {
+		t.Errorf("Expected implementation body, got %q", impls[0].Source)
+	}
+	if impls[1].TypeName != "mockUserRepository" || impls[1].Source != "" {
+		t.Errorf("Expected mock as a bodiless alternative, got %+v", impls[1])
+	}
+}
+
+func TestInspectTool_UnexportedProjectSymbols(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"app.go": `package app
+
+import "strings"
+
+func Title(s string) string { return strings.ToUpper(s) }
+`,
+		"legacy/rows.go": `package legacy
+
+// parseRow splits a CSV row
+func parseRow(row string) []string { return nil }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tool := NewInspectTool(root)
+
+	result, err := tool.Execute(context.Background(), map[string]any{"name": "legacy.parseRow"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	m := result.(map[string]any)
+	if m["found"] != true {
+		t.Fatalf("Expected unexported project function to be found, got %v", m)
+	}
+	if warning, _ := m["warning"].(string); !strings.Contains(warning, "cannot be referenced") {
+		t.Errorf("Expected an unexported warning, got %q", warning)
+	}
+
+	// Unexported names of dependencies stay hidden
+	result, err = tool.Execute(context.Background(), map[string]any{"name": "strings.explode"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if m := result.(map[string]any); m["found"] != false {
+		t.Errorf("Expected unexported dependency function to be hidden, got %v", m)
+	}
+}
+
+func TestInspectTool_OverlayOnlyPackage(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	// The generated package has not been written yet; its stubs only exist in memory
+	dest := filepath.Join(root, "generated")
+	overlay := map[string][]byte{
+		filepath.Join(dest, "shop.go"): []byte(`package generated
+
+// Discount applies a percentage discount
+func Discount(price, percent int) int {
+	panic("not implemented")
+}
+`),
+	}
+
+	tool := NewInspectTool(dest).WithOverlay(overlay)
+	result, err := tool.Execute(context.Background(), map[string]any{"name": "Discount"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	m := result.(map[string]any)
+	if m["found"] != true || m["package"] != "generated" {
+		t.Errorf("Expected Discount to be found in the overlay package, got %v", m)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written to dest, got %v", err)
+	}
+}
+
+func TestInspectTool_FunctionSignature(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"app.go": `package app
+
+import "time"
+
+type Event struct{}
+
+// Schedule queues events after a delay
+func Schedule(delay time.Duration, events ...*Event) (queued int, err error) {
+	return len(events), nil
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tool := NewInspectTool(root)
+	result, err := tool.Execute(context.Background(), map[string]any{"name": "Schedule"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := "Schedule(delay time.Duration, events ...*Event) (queued int, err error)"
+	if got := result.(map[string]any)["signature"]; got != want {
+		t.Errorf("Expected signature %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,429 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rail44/mantra/internal/analysis"
+	"github.com/rail44/mantra/internal/ignore"
+	"github.com/rail44/mantra/internal/tools"
+)
+
+// maxErrorTypeDefinition caps the definition shown for an error type
+const maxErrorTypeDefinition = 400
+
+// ListErrorsTool lists the sentinel errors, error types and error helpers a package or
+// module declares, so generated code reuses them instead of inventing new error strings
+type ListErrorsTool struct {
+	packagePath string
+	overlay     map[string][]byte
+}
+
+// NewListErrorsTool creates a new list_errors tool for the package in packagePath
+func NewListErrorsTool(packagePath string) *ListErrorsTool {
+	return &ListErrorsTool{
+		packagePath: packagePath,
+	}
+}
+
+// WithOverlay makes list_errors see in-memory file contents, such as stubs of the generated
+// files, in place of the files on disk
+func (t *ListErrorsTool) WithOverlay(overlay map[string][]byte) *ListErrorsTool {
+	t.overlay = overlay
+	return t
+}
+
+// Name returns the tool name
+func (t *ListErrorsTool) Name() string {
+	return "list_errors"
+}
+
+// Description returns what this tool does
+func (t *ListErrorsTool) Description() string {
+	return "List the sentinel errors (var ErrX = errors.New(...)), custom error types and error helpers declared in the current package or module, to reuse instead of new error strings"
+}
+
+// ParametersSchema returns the JSON Schema for parameters
+func (t *ListErrorsTool) ParametersSchema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"scope": {
+				"type": "string",
+				"enum": ["package", "module"],
+				"default": "package",
+				"description": "Scan the current package, or the exported errors of every package in the module as well"
+			},
+			"limit": {
+				"type": "integer",
+				"default": 30,
+				"description": "Maximum number of entries per kind"
+			}
+		},
+		"additionalProperties": false
+	}`)
+}
+
+// Execute runs the list_errors tool
+func (t *ListErrorsTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	scope := "package"
+	if s, ok := params["scope"].(string); ok {
+		scope = s
+	}
+
+	limit := 30
+	if l, ok := params["limit"].(float64); ok {
+		limit = int(l)
+	}
+	if limit <= 0 {
+		return nil, tools.NewInvalidParamsError("Parameter 'limit' must be positive", "Omit limit or pass a number such as 30")
+	}
+
+	root := findModuleRoot(t.packagePath)
+	dirs := []string{t.packagePath}
+	if scope == "module" {
+		var err error
+		if dirs, err = moduleDirs(ctx, root); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ListErrorsResult{Scope: scope}
+	for _, dir := range dirs {
+		// Other packages can only use what they export
+		exportedOnly := filepath.Clean(dir) != filepath.Clean(t.packagePath)
+		found, err := t.scanPackage(root, dir, exportedOnly)
+		if err != nil {
+			return nil, err
+		}
+		result.Sentinels = append(result.Sentinels, found.Sentinels...)
+		result.Types = append(result.Types, found.Types...)
+		result.Helpers = append(result.Helpers, found.Helpers...)
+	}
+
+	if len(result.Sentinels) > limit || len(result.Types) > limit || len(result.Helpers) > limit {
+		result.Truncated = true
+		result.Sentinels = result.Sentinels[:min(len(result.Sentinels), limit)]
+		result.Types = result.Types[:min(len(result.Types), limit)]
+		result.Helpers = result.Helpers[:min(len(result.Helpers), limit)]
+	}
+	return result, nil
+}
+
+// IsTerminal returns false as list_errors doesn't end the phase
+func (t *ListErrorsTool) IsTerminal() bool {
+	return false
+}
+
+// ListErrorsResult represents the errors found
+type ListErrorsResult struct {
+	Scope     string        `json:"scope"`
+	Sentinels []ErrorValue  `json:"sentinels"`
+	Types     []ErrorType   `json:"types"`
+	Helpers   []ErrorHelper `json:"helpers"`
+	Truncated bool          `json:"truncated,omitempty"` // Some kind had more entries than the limit
+}
+
+// ErrorValue is a package-level error variable, such as var ErrNotFound = errors.New("not found")
+type ErrorValue struct {
+	Name     string `json:"name"`
+	Package  string `json:"package"`
+	Message  string `json:"message,omitempty"` // Text or format passed to errors.New or fmt.Errorf
+	Location string `json:"location"`
+	Doc      string `json:"doc,omitempty"`
+}
+
+// ErrorType is a type with an Error() string method
+type ErrorType struct {
+	Name       string `json:"name"`
+	Package    string `json:"package"`
+	Pointer    bool   `json:"pointer,omitempty"` // Error is declared on the pointer receiver
+	Definition string `json:"definition,omitempty"`
+	Location   string `json:"location"`
+	Doc        string `json:"doc,omitempty"`
+}
+
+// ErrorHelper is a function taking an error, such as a wrapper or a check like IsNotFound,
+// or returning one of the error types
+type ErrorHelper struct {
+	Name      string `json:"name"`
+	Package   string `json:"package"`
+	Signature string `json:"signature"`
+	Location  string `json:"location"`
+	Doc       string `json:"doc,omitempty"`
+}
+
+// scanPackage collects the errors declared by the non-test Go files of dir
+func (t *ListErrorsTool) scanPackage(root, dir string, exportedOnly bool) (*ListErrorsResult, error) {
+	files, err := t.packageFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, path := range files {
+		var src any
+		if content, ok := t.overlay[path]; ok {
+			src = content
+		}
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			continue // Files with parse errors declare nothing
+		}
+		parsed = append(parsed, file)
+	}
+
+	// Error methods can be declared in any file of the package
+	errorTypes := make(map[string]bool) // Type name to whether Error has a pointer receiver
+	for _, file := range parsed {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && isErrorMethod(fn) {
+				_, pointer := fn.Recv.List[0].Type.(*ast.StarExpr)
+				errorTypes[analysis.ReceiverTypeName(fn.Recv.List[0].Type)] = pointer
+			}
+		}
+	}
+
+	found := &ListErrorsResult{}
+	location := func(pos token.Pos) string {
+		position := fset.Position(pos)
+		rel, err := filepath.Rel(root, position.Filename)
+		if err != nil {
+			rel = position.Filename
+		}
+		return fmt.Sprintf("%s:%d", filepath.ToSlash(rel), position.Line)
+	}
+	visible := func(name string) bool {
+		return name != "_" && (!exportedOnly || ast.IsExported(name))
+	}
+
+	for _, file := range parsed {
+		pkg := file.Name.Name
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					// A lone spec carries its doc comment on the declaration
+					doc := d.Doc
+					switch s := spec.(type) {
+					case *ast.ValueSpec:
+						if d.Tok != token.VAR {
+							continue
+						}
+						if s.Doc != nil {
+							doc = s.Doc
+						}
+						for j, name := range s.Names {
+							var value ast.Expr
+							if j < len(s.Values) {
+								value = s.Values[j]
+							}
+							message, isError := errorValue(s.Type, value)
+							if !isError || !visible(name.Name) {
+								continue
+							}
+							found.Sentinels = append(found.Sentinels, ErrorValue{
+								Name:     name.Name,
+								Package:  pkg,
+								Message:  message,
+								Location: location(name.Pos()),
+								Doc:      strings.TrimSpace(doc.Text()),
+							})
+						}
+
+					case *ast.TypeSpec:
+						pointer, isError := errorTypes[s.Name.Name]
+						if !isError || !visible(s.Name.Name) {
+							continue
+						}
+						if s.Doc != nil {
+							doc = s.Doc
+						}
+						found.Types = append(found.Types, ErrorType{
+							Name:       s.Name.Name,
+							Package:    pkg,
+							Pointer:    pointer,
+							Definition: typeDefinition(fset, s),
+							Location:   location(s.Pos()),
+							Doc:        strings.TrimSpace(doc.Text()),
+						})
+					}
+				}
+
+			case *ast.FuncDecl:
+				if d.Recv != nil || !visible(d.Name.Name) || !isErrorHelper(d, errorTypes) {
+					continue
+				}
+				found.Helpers = append(found.Helpers, ErrorHelper{
+					Name:      d.Name.Name,
+					Package:   pkg,
+					Signature: analysis.BuildFunctionSignatureFromDecl(d),
+					Location:  location(d.Pos()),
+					Doc:       strings.TrimSpace(d.Doc.Text()),
+				})
+			}
+		}
+	}
+	return found, nil
+}
+
+// packageFiles returns the non-test Go files of dir in name order, including files that
+// only exist in the overlay
+func (t *ListErrorsTool) packageFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	for path := range t.overlay {
+		if filepath.Dir(path) == filepath.Clean(dir) && !slices.Contains(files, path) {
+			files = append(files, path)
+		}
+	}
+	files = slices.DeleteFunc(files, func(path string) bool {
+		return !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go")
+	})
+	slices.Sort(files)
+	return files, nil
+}
+
+// moduleDirs returns the directories under root holding Go files, skipping vendor, testdata,
+// hidden and ignored directories
+func moduleDirs(ctx context.Context, root string) ([]string, error) {
+	var dirs []string
+	ignored := ignore.New(root)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || ignored.Match(relPath, true)) {
+				return filepath.SkipDir
+			}
+			if err := ignored.LoadDir(relPath); err != nil {
+				return fmt.Errorf("failed to read ignore files in %s: %w", relPath, err)
+			}
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		if strings.HasSuffix(path, ".go") && !ignored.Match(relPath, false) && (len(dirs) == 0 || dirs[len(dirs)-1] != dir) {
+			dirs = append(dirs, dir)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return dirs, nil
+}
+
+// findModuleRoot returns the nearest directory from dir upwards holding a go.mod, or dir
+func findModuleRoot(dir string) string {
+	current := dir
+	for {
+		if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+// errorValue reports whether a variable of the given type and value is an error, and the
+// message it is created with
+func errorValue(typ, value ast.Expr) (string, bool) {
+	if call, ok := value.(*ast.CallExpr); ok {
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok && ((pkg.Name == "errors" && sel.Sel.Name == "New") || (pkg.Name == "fmt" && sel.Sel.Name == "Errorf")) {
+				message := ""
+				if len(call.Args) > 0 {
+					if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						message, _ = strconv.Unquote(lit.Value)
+					}
+				}
+				return message, true
+			}
+		}
+	}
+	ident, ok := typ.(*ast.Ident)
+	return "", ok && ident.Name == "error"
+}
+
+// isErrorMethod reports whether fn is an Error() string method
+func isErrorMethod(fn *ast.FuncDecl) bool {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 || fn.Name.Name != "Error" {
+		return false
+	}
+	if fn.Type.Params.NumFields() != 0 || fn.Type.Results.NumFields() != 1 {
+		return false
+	}
+	result, ok := fn.Type.Results.List[0].Type.(*ast.Ident)
+	return ok && result.Name == "string"
+}
+
+// isErrorHelper reports whether fn takes an error parameter or returns one of the error types
+func isErrorHelper(fn *ast.FuncDecl, errorTypes map[string]bool) bool {
+	for _, param := range fn.Type.Params.List {
+		if ident, ok := param.Type.(*ast.Ident); ok && ident.Name == "error" {
+			return true
+		}
+	}
+	if fn.Type.Results == nil {
+		return false
+	}
+	for _, result := range fn.Type.Results.List {
+		typ := result.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		if ident, ok := typ.(*ast.Ident); ok {
+			if _, isError := errorTypes[ident.Name]; isError {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeDefinition prints the type of an error type, shortened when long
+func typeDefinition(fset *token.FileSet, spec *ast.TypeSpec) string {
+	var b strings.Builder
+	if err := printer.Fprint(&b, fset, spec.Type); err != nil {
+		return ""
+	}
+	definition := b.String()
+	if len(definition) > maxErrorTypeDefinition {
+		definition = definition[:maxErrorTypeDefinition] + "\n..."
+	}
+	return definition
+}
@@ -0,0 +1,130 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListErrorsTool(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"store/errors.go": `package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned when no record matches
+var ErrNotFound = errors.New("record not found")
+
+var (
+	errClosed   = fmt.Errorf("store closed")
+	ErrConflict error
+	maxRetries  = 3
+)
+
+// IsNotFound reports whether err is ErrNotFound
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+func Lookup(id string) (string, error) {
+	return "", ErrNotFound
+}
+`,
+		"store/validation.go": `package store
+
+// ValidationError reports an invalid field
+type ValidationError struct {
+	Field string
+}
+
+func newValidationError(field string) *ValidationError {
+	return &ValidationError{Field: field}
+}
+`,
+		"store/methods.go": `package store
+
+func (e *ValidationError) Error() string { return "invalid " + e.Field }
+`,
+		"store/store_test.go": `package store
+
+import "errors"
+
+var ErrTest = errors.New("test only")
+`,
+		"auth/auth.go": `package auth
+
+import "errors"
+
+var ErrDenied = errors.New("access denied")
+
+var errInternal = errors.New("internal")
+`,
+		"vendor/lib/lib.go": `package lib
+
+import "errors"
+
+var ErrVendored = errors.New("vendored")
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tool := NewListErrorsTool(filepath.Join(root, "store"))
+	result, err := tool.Execute(context.Background(), map[string]any{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	found := result.(*ListErrorsResult)
+
+	var sentinels []string
+	for _, s := range found.Sentinels {
+		sentinels = append(sentinels, s.Name+"="+s.Message)
+	}
+	if len(sentinels) != 3 || sentinels[0] != "ErrNotFound=record not found" || sentinels[1] != "errClosed=store closed" || sentinels[2] != "ErrConflict=" {
+		t.Errorf("Expected ErrNotFound, errClosed and ErrConflict, got %v", sentinels)
+	}
+	if found.Sentinels[0].Location != "store/errors.go:9" || found.Sentinels[0].Doc != "ErrNotFound is returned when no record matches" {
+		t.Errorf("Expected location and doc of ErrNotFound, got %+v", found.Sentinels[0])
+	}
+	if len(found.Types) != 1 || found.Types[0].Name != "ValidationError" || !found.Types[0].Pointer {
+		t.Errorf("Expected ValidationError with a pointer receiver, got %+v", found.Types)
+	}
+	if len(found.Helpers) != 2 || found.Helpers[0].Name != "IsNotFound" || found.Helpers[1].Name != "newValidationError" {
+		t.Errorf("Expected helpers IsNotFound and newValidationError, got %+v", found.Helpers)
+	}
+
+	// The module scope adds what other packages export
+	result, err = tool.Execute(context.Background(), map[string]any{"scope": "module"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	found = result.(*ListErrorsResult)
+	var names []string
+	for _, s := range found.Sentinels {
+		names = append(names, s.Package+"."+s.Name)
+	}
+	if len(names) != 4 || names[0] != "auth.ErrDenied" {
+		t.Errorf("Expected auth.ErrDenied and the sentinels of store, got %v", names)
+	}
+
+	result, err = tool.Execute(context.Background(), map[string]any{"limit": float64(1)})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if found := result.(*ListErrorsResult); !found.Truncated || len(found.Sentinels) != 1 || len(found.Helpers) != 1 {
+		t.Errorf("Expected results truncated to 1 per kind, got %+v", found)
+	}
+}
@@ -0,0 +1,140 @@
+package impl
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+
+	astanalysis "github.com/rail44/mantra/internal/analysis"
+	pkgcontext "github.com/rail44/mantra/internal/context"
+	"github.com/rail44/mantra/internal/conventions"
+)
+
+// unlockOf pairs the sync.Mutex and sync.RWMutex lock methods with their unlock methods
+var unlockOf = map[string]string{"Lock": "Unlock", "RLock": "RUnlock"}
+
+// checkLocking reports violations of the project's locking conventions in the generated body
+func checkLocking(pkg *packages.Package, mapper *PositionMapper, conv conventions.ConcurrencyConventions) []Issue {
+	if pkg.TypesInfo == nil || mapper.funcDecl.Body == nil {
+		return nil
+	}
+
+	var issues []Issue
+	report := func(pos token.Pos, code, message string) {
+		line, column := mapper.ToRelativePosition(pos)
+		issues = append(issues, Issue{
+			Code:    code,
+			Message: message,
+			Line:    line,
+			Column:  column,
+		})
+	}
+
+	if conv.BalancedLocks {
+		for _, call := range unbalancedLocks(pkg.TypesInfo, mapper.funcDecl.Body) {
+			if unlock, ok := unlockOf[call.method]; ok {
+				report(call.pos, "lock_imbalance", fmt.Sprintf("%s.%s() is never matched by %s.%s(); release the lock on every path, e.g. with defer %s.%s() right after locking",
+					call.mutex, call.method, call.mutex, unlock, call.mutex, unlock))
+			} else {
+				report(call.pos, "lock_imbalance", fmt.Sprintf("%s.%s() releases a lock this body never takes; unlocking a mutex that is not locked is a fatal error",
+					call.mutex, call.method))
+			}
+		}
+	}
+
+	fn := mapper.funcDecl
+	if conv.GuardedFields && fn.Recv != nil && len(fn.Recv.List) > 0 && len(fn.Recv.List[0].Names) > 0 {
+		recvName := fn.Recv.List[0].Names[0].Name
+		typeName := astanalysis.ReceiverTypeName(fn.Recv.List[0].Type)
+		if state := pkgcontext.NewReceiverState(pkg, typeName, fn.Name.Name); state != nil {
+			unguarded := state.Unguarded(pkg.TypesInfo, fn)
+			for _, field := range state.Fields {
+				if pos, ok := unguarded[field.Name]; ok {
+					report(pos, "unguarded_field", fmt.Sprintf("%s.%s is accessed without holding %s.%s, which the other methods of %s hold while accessing it",
+						recvName, field.Name, recvName, field.GuardedBy, typeName))
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// lockCall is a call of a sync.Mutex or sync.RWMutex method
+type lockCall struct {
+	mutex  string // Expression of the mutex, e.g. "c.mu"
+	method string // Lock, RLock, Unlock or RUnlock
+	pos    token.Pos
+}
+
+// unbalancedLocks returns the first lock of each mutex the body never unlocks and the first
+// unlock of each mutex it never locks, in source order. Locks and unlocks are matched by the
+// spelling of the mutex expression, so a deferred unlock matches as well
+func unbalancedLocks(info *types.Info, body *ast.BlockStmt) []lockCall {
+	first := make(map[string]lockCall) // By mutex and method
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !isSyncMutexMethod(info, sel) {
+			return true
+		}
+		mutex := types.ExprString(sel.X)
+		key := mutex + "." + sel.Sel.Name
+		if _, seen := first[key]; !seen {
+			first[key] = lockCall{mutex: mutex, method: sel.Sel.Name, pos: call.Pos()}
+		}
+		return true
+	})
+
+	var unbalanced []lockCall
+	for _, call := range first {
+		if unlock, ok := unlockOf[call.method]; ok {
+			if _, unlocked := first[call.mutex+"."+unlock]; !unlocked {
+				unbalanced = append(unbalanced, call)
+			}
+			continue
+		}
+		locked := false
+		for lock, unlock := range unlockOf {
+			if unlock == call.method {
+				_, locked = first[call.mutex+"."+lock]
+			}
+		}
+		if !locked {
+			unbalanced = append(unbalanced, call)
+		}
+	}
+	sort.Slice(unbalanced, func(i, j int) bool { return unbalanced[i].pos < unbalanced[j].pos })
+	return unbalanced
+}
+
+// isSyncMutexMethod reports whether sel selects Lock, RLock, Unlock or RUnlock of a
+// sync.Mutex or sync.RWMutex, directly or through an embedded field
+func isSyncMutexMethod(info *types.Info, sel *ast.SelectorExpr) bool {
+	switch sel.Sel.Name {
+	case "Lock", "RLock", "Unlock", "RUnlock":
+	default:
+		return false
+	}
+	fn, ok := info.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "sync" {
+		return false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && (named.Obj().Name() == "Mutex" || named.Obj().Name() == "RWMutex")
+}
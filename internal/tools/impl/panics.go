@@ -0,0 +1,47 @@
+package impl
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// WithNoPanic rejects generated code that calls panic. Targets marked with
+// // mantra:no_panic are checked regardless
+func (t *CheckCodeTool) WithNoPanic(noPanic bool) *CheckCodeTool {
+	t.noPanic = noPanic
+	return t
+}
+
+// checkPanics reports calls of the panic builtin in the generated body. Only the body is
+// inspected, so the panic stubs of other targets are not reported
+func checkPanics(pkg *packages.Package, mapper *PositionMapper) []Issue {
+	if pkg.TypesInfo == nil || mapper.funcDecl.Body == nil {
+		return nil
+	}
+
+	var issues []Issue
+	ast.Inspect(mapper.funcDecl.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := ast.Unparen(call.Fun).(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if builtin, ok := pkg.TypesInfo.Uses[ident].(*types.Builtin); !ok || builtin.Name() != "panic" {
+			return true
+		}
+		line, column := mapper.ToRelativePosition(call.Pos())
+		issues = append(issues, Issue{
+			Code:    "no_panic",
+			Message: "panic is not allowed in this function; return an error (or a zero value) instead",
+			Line:    line,
+			Column:  column,
+		})
+		return true
+	})
+	return issues
+}
@@ -0,0 +1,56 @@
+package impl
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// recursionPattern matches instructions that ask for a recursive implementation
+var recursionPattern = regexp.MustCompile(`(?i)\brecurs(e|es|ion|ive|ively)\b`)
+
+// checkSelfCalls reports calls of the target from its own generated body, which models make
+// in place of the logic they were asked to write. Instructions that mention recursion allow them
+func checkSelfCalls(pkg *packages.Package, mapper *PositionMapper, instruction string) []Issue {
+	if pkg.TypesInfo == nil || mapper.funcDecl.Body == nil || recursionPattern.MatchString(instruction) {
+		return nil
+	}
+	self, ok := pkg.TypesInfo.Defs[mapper.funcDecl.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+
+	var issues []Issue
+	ast.Inspect(mapper.funcDecl.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var ident *ast.Ident
+		switch fun := ast.Unparen(call.Fun).(type) {
+		case *ast.Ident:
+			ident = fun
+		case *ast.SelectorExpr:
+			ident = fun.Sel
+		case *ast.IndexExpr: // Instantiated generic function
+			ident, _ = ast.Unparen(fun.X).(*ast.Ident)
+		}
+		if ident == nil {
+			return true
+		}
+		if fn, ok := pkg.TypesInfo.Uses[ident].(*types.Func); !ok || fn.Origin() != self {
+			return true
+		}
+		line, column := mapper.ToRelativePosition(call.Pos())
+		issues = append(issues, Issue{
+			Code:    "self_call",
+			Message: self.Name() + " calls itself; implement the logic in the body instead of delegating to the function being written",
+			Line:    line,
+			Column:  column,
+		})
+		return true
+	})
+	return issues
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 
+	"github.com/rail44/mantra/internal/tools"
 	"github.com/rail44/mantra/internal/tools/schemas"
 )
 
@@ -13,6 +17,9 @@ type ResultTool struct {
 	phaseName string
 	schema    schemas.ResultSchema
 	onResult  func(any) error
+
+	mu     sync.Mutex
+	chunks map[int]string // Code chunks submitted with result_append, by part index
 }
 
 // NewResultTool creates a new result tool for a specific phase
@@ -41,9 +48,17 @@ func (t *ResultTool) ParametersSchema() json.RawMessage {
 
 // Execute runs the result tool
 func (t *ResultTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	// 0. Prepend code delivered in chunks; the chunks are kept until a result is accepted
+	params = t.withPendingCode(params)
+
 	// 1. Validate the parameters against the schema
 	if err := t.schema.Validate(params); err != nil {
-		return nil, fmt.Errorf("validation failed: %w", err)
+		return nil, &tools.ToolError{
+			Code:       tools.CodeInvalidParams,
+			Message:    "Result does not match the phase schema",
+			Details:    err.Error(),
+			Suggestion: "Fix the result fields and call result again",
+		}
 	}
 
 	// 2. Transform the data if needed
@@ -57,6 +72,8 @@ func (t *ResultTool) Execute(ctx context.Context, params map[string]any) (any, e
 		return nil, fmt.Errorf("failed to store result: %w", err)
 	}
 
+	t.Reset()
+
 	// 4. Return success message
 	return map[string]any{
 		"status":  "success",
@@ -68,3 +85,117 @@ func (t *ResultTool) Execute(ctx context.Context, params map[string]any) (any, e
 func (t *ResultTool) IsTerminal() bool {
 	return true
 }
+
+// Reset discards code chunks that were not followed by an accepted result
+func (t *ResultTool) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.chunks = nil
+}
+
+// withPendingCode returns params with buffered chunks prepended to "code".
+// Failures ignore the buffer so an abandoned implementation does not leak into the error
+func (t *ResultTool) withPendingCode(params map[string]any) map[string]any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.chunks) == 0 {
+		return params
+	}
+	if success, _ := params["success"].(bool); !success {
+		return params
+	}
+
+	merged := make(map[string]any, len(params)+1)
+	for k, v := range params {
+		merged[k] = v
+	}
+	code, _ := params["code"].(string)
+	merged["code"] = t.pendingCode() + code
+	return merged
+}
+
+// pendingCode joins the buffered chunks in part order; callers hold t.mu
+func (t *ResultTool) pendingCode() string {
+	parts := make([]int, 0, len(t.chunks))
+	for part := range t.chunks {
+		parts = append(parts, part)
+	}
+	slices.Sort(parts)
+
+	var code strings.Builder
+	for _, part := range parts {
+		code.WriteString(t.chunks[part])
+	}
+	return code.String()
+}
+
+// AppendTool returns a tool that buffers part of a long "code" value for this result tool
+func (t *ResultTool) AppendTool() *ResultAppendTool {
+	return &ResultAppendTool{result: t}
+}
+
+// ResultAppendTool lets the model deliver a long function body across several calls
+// when a single result call would exceed the model's output limit
+type ResultAppendTool struct {
+	result *ResultTool
+}
+
+// Name returns the tool name
+func (t *ResultAppendTool) Name() string {
+	return "result_append"
+}
+
+// Description returns what this tool does
+func (t *ResultAppendTool) Description() string {
+	return "Send one part of a long function body. Parts are joined by index and prepended to the \"code\" of the following result call"
+}
+
+// ParametersSchema returns the JSON Schema for parameters
+func (t *ResultAppendTool) ParametersSchema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"index": {
+				"type": "integer",
+				"minimum": 0,
+				"description": "Position of this part, starting at 0; resending an index replaces that part"
+			},
+			"chunk": {
+				"type": "string",
+				"description": "The part of the function body, continuing exactly where the previous part ended"
+			}
+		},
+		"required": ["index", "chunk"],
+		"additionalProperties": false
+	}`)
+}
+
+// Execute buffers the chunk. Parts are indexed because tool calls of one response run in parallel
+func (t *ResultAppendTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	index, ok := params["index"].(float64)
+	if !ok || index < 0 || index != float64(int(index)) {
+		return nil, tools.NewInvalidParamsError("index parameter is required and must be a non-negative integer", "Number the parts 0, 1, 2, ... in order")
+	}
+	chunk, ok := params["chunk"].(string)
+	if !ok || chunk == "" {
+		return nil, tools.NewInvalidParamsError("chunk parameter is required and must be a non-empty string", "Pass the part of the function body as \"chunk\"")
+	}
+
+	t.result.mu.Lock()
+	defer t.result.mu.Unlock()
+	if t.result.chunks == nil {
+		t.result.chunks = make(map[int]string)
+	}
+	t.result.chunks[int(index)] = chunk
+
+	return map[string]any{
+		"status":  "buffered",
+		"parts":   len(t.result.chunks),
+		"message": "Send the next part with result_append, or call result with the remaining code",
+	}, nil
+}
+
+// IsTerminal returns false; the phase ends with the following result call
+func (t *ResultAppendTool) IsTerminal() bool {
+	return false
+}
@@ -0,0 +1,86 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// codeSchema accepts any object whose "code" is a string when success is true
+type codeSchema struct{}
+
+func (codeSchema) Schema() json.RawMessage { return json.RawMessage(`{"type": "object"}`) }
+
+func (codeSchema) Validate(data any) error {
+	params := data.(map[string]any)
+	if success, _ := params["success"].(bool); success {
+		if _, ok := params["code"].(string); !ok {
+			return fmt.Errorf("missing required field: code when success is true")
+		}
+	}
+	return nil
+}
+
+func (codeSchema) Transform(data any) (any, error) { return data, nil }
+
+func TestResultTool_JoinsAppendedChunks(t *testing.T) {
+	var stored map[string]any
+	result := NewResultTool("implementation", codeSchema{}, func(v any) error {
+		stored = v.(map[string]any)
+		return nil
+	})
+	appendTool := result.AppendTool()
+	ctx := context.Background()
+
+	// Parts may arrive out of order when sent in one response
+	for _, part := range []struct {
+		index float64
+		chunk string
+	}{
+		{1, "b := 2\n"},
+		{0, "a := 1\n"},
+	} {
+		if _, err := appendTool.Execute(ctx, map[string]any{"index": part.index, "chunk": part.chunk}); err != nil {
+			t.Fatalf("result_append failed: %v", err)
+		}
+	}
+
+	if _, err := result.Execute(ctx, map[string]any{"success": true, "code": "return a + b"}); err != nil {
+		t.Fatalf("result failed: %v", err)
+	}
+	if want := "a := 1\nb := 2\nreturn a + b"; stored["code"] != want {
+		t.Errorf("Expected code %q, got %q", want, stored["code"])
+	}
+
+	// The buffer is cleared once a result is accepted
+	if _, err := result.Execute(ctx, map[string]any{"success": true, "code": "return 0"}); err != nil {
+		t.Fatalf("result failed: %v", err)
+	}
+	if stored["code"] != "return 0" {
+		t.Errorf("Expected buffer to be cleared, got %q", stored["code"])
+	}
+}
+
+func TestResultTool_ChunksOnlyWithoutCode(t *testing.T) {
+	var stored map[string]any
+	result := NewResultTool("implementation", codeSchema{}, func(v any) error {
+		stored = v.(map[string]any)
+		return nil
+	})
+	ctx := context.Background()
+
+	if _, err := result.AppendTool().Execute(ctx, map[string]any{"index": float64(0), "chunk": "return 1"}); err != nil {
+		t.Fatalf("result_append failed: %v", err)
+	}
+	if _, err := result.Execute(ctx, map[string]any{"success": true}); err != nil {
+		t.Fatalf("result failed: %v", err)
+	}
+	if stored["code"] != "return 1" {
+		t.Errorf("Expected code from chunks, got %q", stored["code"])
+	}
+
+	if _, err := result.AppendTool().Execute(ctx, map[string]any{"index": float64(-1), "chunk": "x"}); err == nil {
+		t.Error("Expected error for negative index")
+	}
+}
@@ -0,0 +1,428 @@
+package impl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rail44/mantra/internal/buildenv"
+	"github.com/rail44/mantra/internal/tools"
+)
+
+const (
+	// defaultSnippetTimeout bounds compilation and execution of a snippet harness
+	defaultSnippetTimeout = 20 * time.Second
+
+	// maxSnippetOutput limits how much raw output is returned to the model
+	maxSnippetOutput = 4000
+
+	snippetTestName     = "TestMantraRunSnippet"
+	snippetResultPrefix = "MANTRA_SNIPPET_RESULT"
+)
+
+// RunSnippetTool compiles the generated body together with a small test harness
+// and runs it with example calls, reporting returned values and panics.
+// The package is built in a throwaway copy of its module, without its own test files,
+// so nothing is written to the source tree, and the test binary runs in a temporary
+// directory without network access
+type RunSnippetTool struct {
+	timeout time.Duration
+	context *tools.Context // Stored context from SetContext
+}
+
+// NewRunSnippetTool creates a new snippet execution tool
+func NewRunSnippetTool(timeout time.Duration) *RunSnippetTool {
+	if timeout <= 0 {
+		timeout = defaultSnippetTimeout
+	}
+	return &RunSnippetTool{
+		timeout: timeout,
+	}
+}
+
+// Name returns the tool name
+func (t *RunSnippetTool) Name() string {
+	return "run_snippet"
+}
+
+// Description returns what this tool does
+func (t *RunSnippetTool) Description() string {
+	return "Compile the function body and run it with example calls to catch runtime panics. Only the package's non-test sources are built with it, so their init functions run too. The code runs without network access"
+}
+
+// ParametersSchema returns the JSON Schema for parameters
+func (t *RunSnippetTool) ParametersSchema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"code": {
+				"type": "string",
+				"description": "The generated function body to run"
+			},
+			"calls": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Go call expressions invoking the target with example inputs, e.g. [\"Add(1, 2)\"]"
+			},
+			"setup": {
+				"type": "string",
+				"description": "Optional Go statements executed before each call, e.g. to construct a receiver"
+			}
+		},
+		"required": ["code", "calls"],
+		"additionalProperties": false
+	}`)
+}
+
+// SetContext implements ContextAwareTool interface
+func (t *RunSnippetTool) SetContext(toolCtx *tools.Context) {
+	t.context = toolCtx
+}
+
+// IsTerminal returns false as run_snippet tool doesn't end the phase
+func (t *RunSnippetTool) IsTerminal() bool {
+	return false
+}
+
+// RunSnippetResult represents the outcome of running a snippet
+type RunSnippetResult struct {
+	Compiled bool                `json:"compiled"`
+	TimedOut bool                `json:"timed_out,omitempty"`
+	Results  []SnippetCallResult `json:"results,omitempty"`
+	Output   string              `json:"output,omitempty"` // Compiler errors or unexpected output
+}
+
+// SnippetCallResult represents the outcome of a single example call
+type SnippetCallResult struct {
+	Call     string `json:"call"`
+	Panicked bool   `json:"panicked"`
+	Value    string `json:"value"` // Returned values, or the panic value when Panicked
+}
+
+// Execute builds and runs the snippet harness
+func (t *RunSnippetTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	code, ok := params["code"].(string)
+	if !ok {
+		return nil, tools.NewInvalidParamsError("Parameter 'code' is required and must be a string", "Pass the function body as 'code'")
+	}
+
+	rawCalls, ok := params["calls"].([]any)
+	if !ok || len(rawCalls) == 0 {
+		return nil, tools.NewInvalidParamsError("Parameter 'calls' is required and must be a non-empty array of strings", "Pass at least one call expression, e.g. [\"Add(1, 2)\"]")
+	}
+	calls := make([]string, 0, len(rawCalls))
+	for _, c := range rawCalls {
+		call, ok := c.(string)
+		if !ok || strings.TrimSpace(call) == "" {
+			return nil, tools.NewInvalidParamsError("Each entry in 'calls' must be a non-empty string", "Remove empty entries from 'calls'")
+		}
+		calls = append(calls, strings.TrimSpace(call))
+	}
+
+	setup, _ := params["setup"].(string)
+
+	if t.context == nil || t.context.FileInfo == nil || t.context.Target == nil {
+		return nil, tools.NewInternalError("Tool context not set - this tool requires FileInfo and Target from context", nil)
+	}
+	fileInfo := t.context.FileInfo
+	target := t.context.Target
+
+	if err := SnippetIsolation(); err != nil {
+		return nil, tools.NewInternalError("run_snippet cannot run without network isolation", err)
+	}
+
+	modified, err := (&CheckCodeTool{}).replaceViaAST(fileInfo.SourceContent, target, strings.TrimSpace(code), t.context.Helpers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace function body: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "mantra-snippet-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	packageDir, err := snippetModule(workDir, filepath.Dir(fileInfo.FilePath))
+	if err != nil {
+		return nil, err
+	}
+	harness := buildSnippetHarness(fileInfo.PackageName, setup, calls, len(target.Returns))
+	files := map[string][]byte{
+		filepath.Base(fileInfo.FilePath): modified.Content,
+		"mantra_snippet_test.go":         []byte(harness),
+	}
+	for name, content := range files {
+		path := filepath.Join(packageDir, name)
+		os.Remove(path) // A link to the original file must not be written through
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write snippet file: %w", err)
+		}
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	binary := filepath.Join(workDir, "snippet.test")
+	args := append([]string{"test"}, buildenv.Flags()...)
+	args = append(args, "-c", "-o", binary, ".")
+	build := exec.CommandContext(runCtx, "go", args...)
+	build.Dir = packageDir
+	build.Env = sandboxEnv(workDir, packageDir)
+
+	var out bytes.Buffer
+	build.Stdout = &out
+	build.Stderr = &out
+	runErr := build.Run()
+	if runErr == nil {
+		// The binary runs in a network namespace of its own; relative paths used by the
+		// snippet resolve in the temporary directory
+		args := append(isolationArgs(), binary,
+			"-test.run", "^"+snippetTestName+"$",
+			"-test.count=1",
+			"-test.v",
+			"-test.timeout", t.timeout.String(),
+		)
+		cmd := exec.CommandContext(runCtx, args[0], args[1:]...)
+		cmd.Dir = workDir
+		cmd.Env = sandboxEnv(workDir, packageDir)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		runErr = cmd.Run()
+	}
+
+	result := parseSnippetOutput(out.String(), calls)
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		result.TimedOut = true
+	}
+	if runErr != nil && len(result.Results) < len(calls) {
+		result.Output = truncateOutput(out.String())
+	}
+	return result, nil
+}
+
+// buildSnippetHarness generates a test file that invokes each call under recover
+func buildSnippetHarness(packageName, setup string, calls []string, returnCount int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "package %s\n\n", packageName)
+	sb.WriteString("import (\n\t\"fmt\"\n\t\"testing\"\n)\n\n")
+	fmt.Fprintf(&sb, "func %s(t *testing.T) {\n", snippetTestName)
+	sb.WriteString("\trun := func(i int, f func() []any) {\n")
+	sb.WriteString("\t\tdefer func() {\n")
+	sb.WriteString("\t\t\tif r := recover(); r != nil {\n")
+	fmt.Fprintf(&sb, "\t\t\t\tfmt.Printf(\"%s %%d panic %%q\\n\", i, fmt.Sprint(r))\n", snippetResultPrefix)
+	sb.WriteString("\t\t\t}\n\t\t}()\n")
+	sb.WriteString("\t\tvalues := f()\n")
+	fmt.Fprintf(&sb, "\t\tfmt.Printf(\"%s %%d ok %%q\\n\", i, fmt.Sprintf(\"%%#v\", values))\n", snippetResultPrefix)
+	sb.WriteString("\t}\n\n")
+
+	for i, call := range calls {
+		fmt.Fprintf(&sb, "\trun(%d, func() []any {\n", i)
+		if setup != "" {
+			sb.WriteString(setup)
+			sb.WriteString("\n")
+		}
+		switch returnCount {
+		case 0:
+			fmt.Fprintf(&sb, "\t\t%s\n\t\treturn nil\n", call)
+		default:
+			names := make([]string, returnCount)
+			for j := range names {
+				names[j] = "r" + strconv.Itoa(j)
+			}
+			fmt.Fprintf(&sb, "\t\t%s := %s\n", strings.Join(names, ", "), call)
+			fmt.Fprintf(&sb, "\t\treturn []any{%s}\n", strings.Join(names, ", "))
+		}
+		sb.WriteString("\t})\n")
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// snippetModule lays out a throwaway copy of the module containing packageDir under dir,
+// so the snippet builds as part of the same module without touching it: the package's
+// non-test Go files are copied, and every other entry of the module is linked. Returns the
+// directory of the copied package
+func snippetModule(dir, packageDir string) (string, error) {
+	root := findModuleRoot(packageDir)
+	rel, err := filepath.Rel(root, packageDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate package in module: %w", err)
+	}
+
+	// Directories on the way to the package are recreated, with their other entries linked
+	src, dst := root, filepath.Join(dir, "module")
+	if rel != "." {
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			if err := linkEntries(src, dst, part); err != nil {
+				return "", err
+			}
+			src, dst = filepath.Join(src, part), filepath.Join(dst, part)
+		}
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return "", fmt.Errorf("failed to create snippet module: %w", err)
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read package directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasSuffix(name, "_test.go"):
+			// The package's tests, including any TestMain, stay out of the harness binary
+		case entry.Type().IsRegular() && strings.HasSuffix(name, ".go"):
+			content, err := os.ReadFile(filepath.Join(src, name))
+			if err != nil {
+				return "", fmt.Errorf("failed to copy %s: %w", name, err)
+			}
+			if err := os.WriteFile(filepath.Join(dst, name), content, 0644); err != nil {
+				return "", fmt.Errorf("failed to copy %s: %w", name, err)
+			}
+		default:
+			if err := os.Symlink(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+				return "", fmt.Errorf("failed to link %s: %w", name, err)
+			}
+		}
+	}
+	return dst, nil
+}
+
+// linkEntries creates dst with links to the entries of src other than skip and .git
+func linkEntries(src, dst, skip string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return fmt.Errorf("failed to create snippet module: %w", err)
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("failed to read module directory: %w", err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != skip && name != ".git" {
+			if err := os.Symlink(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+				return fmt.Errorf("failed to link %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isolation caches the result of probing for network namespaces
+var isolation struct {
+	once sync.Once
+	err  error
+}
+
+// isolationArgs returns the command prefix running a program in new user and network
+// namespaces, where only an unconfigured loopback interface exists
+func isolationArgs() []string {
+	return []string{"unshare", "--user", "--map-root-user", "--net"}
+}
+
+// SnippetIsolation reports why snippets cannot run without network access, or nil if they
+// can. Isolation needs Linux, unshare(1) and unprivileged user namespaces
+func SnippetIsolation() error {
+	isolation.once.Do(func() {
+		if runtime.GOOS != "linux" {
+			isolation.err = fmt.Errorf("network isolation needs Linux namespaces, not available on %s", runtime.GOOS)
+			return
+		}
+		args := append(isolationArgs(), "true")
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			isolation.err = fmt.Errorf("failed to create a network namespace with unshare: %v %s", err, strings.TrimSpace(string(out)))
+		}
+	})
+	return isolation.err
+}
+
+// sandboxEnv returns an environment that disables module downloads and keeps temporary
+// files in tmpDir. The configured build environment is kept, and vendored modules build
+// from vendor/
+func sandboxEnv(tmpDir, packageDir string) []string {
+	base := buildenv.Environ(os.Environ())
+	goflags := "-mod=readonly"
+	if buildenv.Vendored(packageDir) {
+		goflags = "-mod=vendor"
+	}
+	if configured := buildenv.Current(); configured != nil && configured.Env["GOFLAGS"] != "" {
+		if strings.Contains(configured.Env["GOFLAGS"], "-mod=") {
+			goflags = configured.Env["GOFLAGS"]
+		} else {
+			goflags += " " + configured.Env["GOFLAGS"]
+		}
+	}
+
+	env := make([]string, 0, len(base)+3)
+	for _, kv := range base {
+		key := strings.ToUpper(strings.SplitN(kv, "=", 2)[0])
+		switch key {
+		case "GOPROXY", "GOFLAGS", "TMPDIR":
+			continue
+		}
+		env = append(env, kv)
+	}
+	return append(env,
+		"GOPROXY=off",
+		"GOFLAGS="+goflags,
+		"TMPDIR="+tmpDir,
+	)
+}
+
+// parseSnippetOutput extracts per-call results from the harness output
+func parseSnippetOutput(output string, calls []string) *RunSnippetResult {
+	result := &RunSnippetResult{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "=== RUN   "+snippetTestName) {
+			result.Compiled = true
+		}
+		if !strings.HasPrefix(line, snippetResultPrefix+" ") {
+			continue
+		}
+
+		fields := strings.SplitN(strings.TrimPrefix(line, snippetResultPrefix+" "), " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil || index < 0 || index >= len(calls) {
+			continue
+		}
+		value, err := strconv.Unquote(fields[2])
+		if err != nil {
+			value = fields[2]
+		}
+		result.Results = append(result.Results, SnippetCallResult{
+			Call:     calls[index],
+			Panicked: fields[1] == "panic",
+			Value:    value,
+		})
+	}
+
+	return result
+}
+
+// truncateOutput limits raw command output returned to the model
+func truncateOutput(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxSnippetOutput {
+		return s[:maxSnippetOutput] + "\n... (truncated)"
+	}
+	return s
+}
@@ -0,0 +1,214 @@
+package impl
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rail44/mantra/internal/parser"
+	"github.com/rail44/mantra/internal/tools"
+)
+
+func TestRunSnippetTool_ReportsPanics(t *testing.T) {
+	// Create a temporary module with a single target function
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "div.go")
+
+	testFileContent := `package test
+
+func Div(a, b int) int {
+	panic("not implemented")
+}
+`
+	if err := os.WriteFile(testFile, []byte(testFileContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod file: %v", err)
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Div",
+		FilePath: testFile,
+		Params: []parser.Param{
+			{Name: "a", Type: "int"},
+			{Name: "b", Type: "int"},
+		},
+		Returns: []parser.Return{
+			{Type: "int"},
+		},
+	}
+
+	tool := NewRunSnippetTool(0)
+	tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+	result, err := tool.Execute(context.Background(), map[string]any{
+		"code":  "return a / b",
+		"calls": []any{"Div(6, 3)", "Div(1, 0)"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+
+	snippetResult, ok := result.(*RunSnippetResult)
+	if !ok {
+		t.Fatalf("Result is not *RunSnippetResult")
+	}
+	if !snippetResult.Compiled {
+		t.Fatalf("Expected snippet to compile, output: %s", snippetResult.Output)
+	}
+	if len(snippetResult.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", snippetResult.Results)
+	}
+
+	if snippetResult.Results[0].Panicked || snippetResult.Results[0].Value != "[]interface {}{2}" {
+		t.Errorf("Unexpected result for Div(6, 3): %+v", snippetResult.Results[0])
+	}
+	if !snippetResult.Results[1].Panicked {
+		t.Errorf("Expected Div(1, 0) to panic, got %+v", snippetResult.Results[1])
+	}
+
+	// The source tree must not be modified
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to read temp dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only div.go and go.mod in package dir, got %d entries", len(entries))
+	}
+}
+
+func TestRunSnippetTool_SkipsPackageTests(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "greet.go")
+	testFileContent := `package test
+
+import "os"
+
+var _ = os.Args
+
+func Greet(name string) string {
+	panic("not implemented")
+}
+`
+	files := map[string]string{
+		"greet.go": testFileContent,
+		"go.mod":   "module test\n\ngo 1.21\n",
+		// A TestMain that exits early would keep the harness from running
+		"main_test.go": `package test
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(3)
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	fileInfo := &parser.FileInfo{
+		FilePath:      testFile,
+		PackageName:   "test",
+		SourceContent: testFileContent,
+	}
+	target := &parser.Target{
+		Name:     "Greet",
+		FilePath: testFile,
+		Params:   []parser.Param{{Name: "name", Type: "string"}},
+		Returns:  []parser.Return{{Type: "string"}},
+	}
+
+	tool := NewRunSnippetTool(0)
+	tool.SetContext(tools.NewContext(fileInfo, target, tmpDir))
+
+	// Relative writes land in the temporary directory, not the package
+	result, err := tool.Execute(context.Background(), map[string]any{
+		"code":  `os.WriteFile("out.txt", nil, 0644); return "hi " + name`,
+		"calls": []any{`Greet("a")`},
+	})
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	snippetResult := result.(*RunSnippetResult)
+	if len(snippetResult.Results) != 1 || snippetResult.Results[0].Value != `[]interface {}{"hi a"}` {
+		t.Fatalf("Expected the harness to run despite TestMain, got %+v, output: %s", snippetResult.Results, snippetResult.Output)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "out.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected out.txt not to be written to the package dir, got %v", err)
+	}
+}
+
+func TestRunSnippetTool_IsolatesNetwork(t *testing.T) {
+	if err := SnippetIsolation(); err != nil {
+		t.Skipf("Network isolation unavailable: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	root := t.TempDir()
+	source := `package app
+
+import (
+	"net"
+
+	"example.com/app/internal/addr"
+)
+
+var _ = net.Dial
+
+func Reach() bool {
+	panic("not implemented")
+}
+`
+	files := map[string]string{
+		"go.mod":                 "module example.com/app\n\ngo 1.21\n",
+		"internal/addr/addr.go":  "package addr\n\nconst Target = \"" + listener.Addr().String() + "\"\n",
+		"app/app.go":             source,
+		"app/testdata/seed.json": "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	path := filepath.Join(root, "app", "app.go")
+	fileInfo := &parser.FileInfo{FilePath: path, PackageName: "app", SourceContent: source}
+	target := &parser.Target{Name: "Reach", FilePath: path, Returns: []parser.Return{{Type: "bool"}}}
+	tool := NewRunSnippetTool(0)
+	tool.SetContext(tools.NewContext(fileInfo, target, root))
+
+	// Packages of the module resolve in the copy, but the listener cannot be reached
+	result, err := tool.Execute(context.Background(), map[string]any{
+		"code":  "conn, err := net.Dial(\"tcp\", addr.Target)\nif err != nil {\n\treturn false\n}\nconn.Close()\nreturn true",
+		"calls": []any{"Reach()"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to execute tool: %v", err)
+	}
+	snippetResult := result.(*RunSnippetResult)
+	if len(snippetResult.Results) != 1 || snippetResult.Results[0].Value != "[]interface {}{false}" {
+		t.Fatalf("Expected the dial to fail, got %+v, output: %s", snippetResult.Results, snippetResult.Output)
+	}
+}
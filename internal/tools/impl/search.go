@@ -4,32 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"go/ast"
-	"go/parser"
-	"go/token"
-	"io/fs"
-	"path/filepath"
-	"strings"
-	"sync"
 
-	"github.com/rail44/mantra/internal/analysis"
 	"github.com/rail44/mantra/internal/tools"
 )
 
 // SearchTool searches for declarations using pattern matching
 type SearchTool struct {
-	projectRoot string
-	fileCache   map[string]*ast.File
-	fset        *token.FileSet
-	mu          sync.RWMutex
+	index *SymbolIndex
 }
 
-// NewSearchTool creates a new search tool
+// NewSearchTool creates a new search tool backed by the project's shared symbol index
 func NewSearchTool(projectRoot string) *SearchTool {
 	return &SearchTool{
-		projectRoot: projectRoot,
-		fileCache:   make(map[string]*ast.File),
-		fset:        token.NewFileSet(),
+		index: SharedSymbolIndex(projectRoot),
 	}
 }
 
@@ -74,10 +61,7 @@ func (t *SearchTool) Execute(ctx context.Context, params map[string]any) (any, e
 	// Extract parameters
 	pattern, ok := params["pattern"].(string)
 	if !ok {
-		return nil, &tools.ToolError{
-			Code:    "invalid_params",
-			Message: "Parameter 'pattern' is required and must be a string",
-		}
+		return nil, tools.NewInvalidParamsError("Parameter 'pattern' is required and must be a string", "Pass a name or wildcard pattern such as '*Repository'")
 	}
 
 	kind := "all"
@@ -121,176 +105,30 @@ type SearchResults struct {
 type SearchResult struct {
 	Name      string `json:"name"`
 	Kind      string `json:"kind"`
+	Receiver  string `json:"receiver,omitempty"` // For methods
 	Package   string `json:"package"`
 	Location  string `json:"location"`
 	Signature string `json:"signature,omitempty"` // For functions/methods
+	Doc       string `json:"doc,omitempty"`
 }
 
 func (t *SearchTool) search(ctx context.Context, pattern, kind string, limit int) ([]SearchResult, error) {
-	var results []SearchResult
-
-	// Walk through Go files in the project
-	err := filepath.WalkDir(t.projectRoot, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip non-Go files
-		if d.IsDir() || !strings.HasSuffix(path, ".go") {
-			return nil
-		}
-
-		// Skip vendor and hidden directories
-		if strings.Contains(path, "/vendor/") || strings.Contains(path, "/.") {
-			return nil
-		}
-
-		// Skip test files (optional)
-		if strings.HasSuffix(path, "_test.go") {
-			return nil
-		}
-
-		// Parse file
-		file, err := t.parseFile(path)
-		if err != nil {
-			// Skip files with parse errors
-			return nil
-		}
-
-		// Search in file
-		fileResults := t.searchInFile(file, path, pattern, kind)
-		results = append(results, fileResults...)
-
-		// Check limit
-		if len(results) >= limit {
-			results = results[:limit]
-			return filepath.SkipAll
-		}
-
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		return nil
-	})
-
-	return results, err
-}
-
-func (t *SearchTool) parseFile(path string) (*ast.File, error) {
-	// Check cache with read lock
-	t.mu.RLock()
-	if file, ok := t.fileCache[path]; ok {
-		t.mu.RUnlock()
-		return file, nil
-	}
-	t.mu.RUnlock()
-
-	// Parse file
-	file, err := parser.ParseFile(t.fset, path, nil, parser.ParseComments)
+	symbols, err := t.index.Search(ctx, pattern, kind, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	// Cache result with write lock
-	t.mu.Lock()
-	t.fileCache[path] = file
-	t.mu.Unlock()
-
-	return file, nil
-}
-
-func (t *SearchTool) searchInFile(file *ast.File, path, pattern, kind string) []SearchResult {
-	var results []SearchResult
-	relPath, _ := filepath.Rel(t.projectRoot, path)
-
-	// Search in declarations
-	for _, decl := range file.Decls {
-		switch d := decl.(type) {
-		case *ast.GenDecl:
-			// Handle type, const, var declarations
-			for _, spec := range d.Specs {
-				result := t.checkGenSpec(spec, d.Tok, file.Name.Name, relPath, pattern, kind)
-				if result != nil {
-					results = append(results, *result)
-				}
-			}
-
-		case *ast.FuncDecl:
-			// Handle function/method declarations
-			if kind == "all" || kind == "func" || (kind == "method" && d.Recv != nil) {
-				if matchesPattern(d.Name.Name, pattern) {
-					result := SearchResult{
-						Name:      d.Name.Name,
-						Kind:      "func",
-						Package:   file.Name.Name,
-						Location:  fmt.Sprintf("%s:%d", relPath, t.fset.Position(d.Pos()).Line),
-						Signature: analysis.BuildFunctionSignatureFromDecl(d),
-					}
-					if d.Recv != nil {
-						result.Kind = "method"
-					}
-					results = append(results, result)
-				}
-			}
-		}
-	}
-
-	return results
-}
-
-func (t *SearchTool) checkGenSpec(spec ast.Spec, tok token.Token, pkg, path, pattern, kind string) *SearchResult {
-	switch s := spec.(type) {
-	case *ast.TypeSpec:
-		// Type declaration
-		if kind == "all" || kind == "type" || kind == "struct" || kind == "interface" {
-			if matchesPattern(s.Name.Name, pattern) {
-				result := &SearchResult{
-					Name:     s.Name.Name,
-					Kind:     "type",
-					Package:  pkg,
-					Location: fmt.Sprintf("%s:%d", path, t.fset.Position(s.Pos()).Line),
-				}
-
-				// Determine specific type kind
-				switch s.Type.(type) {
-				case *ast.StructType:
-					result.Kind = "struct"
-				case *ast.InterfaceType:
-					result.Kind = "interface"
-				}
-
-				// Check if kind matches
-				// "type" matches any type (struct, interface, or type alias)
-				if kind == "all" || kind == "type" || kind == result.Kind {
-					return result
-				}
-			}
-		}
-
-	case *ast.ValueSpec:
-		// Const or var declaration
-		declKind := "var"
-		if tok == token.CONST {
-			declKind = "const"
-		}
-
-		if kind == "all" || kind == declKind {
-			for _, name := range s.Names {
-				if matchesPattern(name.Name, pattern) {
-					return &SearchResult{
-						Name:     name.Name,
-						Kind:     declKind,
-						Package:  pkg,
-						Location: fmt.Sprintf("%s:%d", path, t.fset.Position(name.Pos()).Line),
-					}
-				}
-			}
-		}
+	results := make([]SearchResult, 0, len(symbols))
+	for _, sym := range symbols {
+		results = append(results, SearchResult{
+			Name:      sym.Name,
+			Kind:      sym.Kind,
+			Receiver:  sym.Receiver,
+			Package:   sym.Package,
+			Location:  fmt.Sprintf("%s:%d", sym.File, sym.Line),
+			Signature: sym.Signature,
+			Doc:       sym.Doc,
+		})
 	}
-
-	return nil
+	return results, nil
 }
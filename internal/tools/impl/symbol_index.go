@@ -0,0 +1,445 @@
+package impl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rail44/mantra/internal/analysis"
+	"github.com/rail44/mantra/internal/ignore"
+)
+
+// symbolIndexVersion is bumped whenever the cache file format changes
+const symbolIndexVersion = 1
+
+// symbolIndexRefreshInterval is how long an index is trusted before file mtimes are checked again
+const symbolIndexRefreshInterval = 5 * time.Second
+
+// Symbol is a top-level declaration recorded in the symbol index
+type Symbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"` // struct, interface, type, func, method, const or var
+	Receiver  string `json:"receiver,omitempty"`
+	Package   string `json:"package"`
+	File      string `json:"file"` // Relative to the project root
+	Line      int    `json:"line"`
+	Doc       string `json:"doc,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// indexedFile holds the symbols of one source file and the stat data they were built from
+type indexedFile struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Symbols []Symbol  `json:"symbols"`
+}
+
+// symbolIndexFile is the on-disk representation of a SymbolIndex
+type symbolIndexFile struct {
+	Version int                     `json:"version"`
+	Root    string                  `json:"root"`
+	Files   map[string]*indexedFile `json:"files"`
+}
+
+// SymbolIndex is a persistent index of the declarations in a project.
+// Files are re-parsed only when their mtime or size changes, and the index
+// is saved to the user cache directory so later runs start warm.
+type SymbolIndex struct {
+	root      string
+	cachePath string // Empty when no cache directory is available
+
+	mu        sync.Mutex
+	files     map[string]*indexedFile
+	symbols   []Symbol         // Sorted by file and line
+	byName    map[string][]int // Indices into symbols
+	refreshed time.Time
+	loaded    bool
+}
+
+var (
+	symbolIndexesMu sync.Mutex
+	symbolIndexes   = make(map[string]*SymbolIndex)
+)
+
+// SharedSymbolIndex returns the index for projectRoot, shared by every caller in the process
+func SharedSymbolIndex(projectRoot string) *SymbolIndex {
+	root, err := filepath.Abs(projectRoot)
+	if err != nil {
+		root = projectRoot
+	}
+
+	symbolIndexesMu.Lock()
+	defer symbolIndexesMu.Unlock()
+
+	if idx, ok := symbolIndexes[root]; ok {
+		return idx
+	}
+	idx := NewSymbolIndex(root, defaultSymbolIndexPath(root))
+	symbolIndexes[root] = idx
+	return idx
+}
+
+// NewSymbolIndex creates an index for root persisted at cachePath (memory only if empty)
+func NewSymbolIndex(root, cachePath string) *SymbolIndex {
+	return &SymbolIndex{
+		root:      root,
+		cachePath: cachePath,
+		files:     make(map[string]*indexedFile),
+		byName:    make(map[string][]int),
+	}
+}
+
+// defaultSymbolIndexPath returns the cache file for root inside the user cache directory
+func defaultSymbolIndexPath(root string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, "mantra", "symbols", hex.EncodeToString(sum[:8])+".json")
+}
+
+// Search returns up to limit symbols whose name matches pattern and whose kind matches kind
+func (idx *SymbolIndex) Search(ctx context.Context, pattern, kind string, limit int) ([]Symbol, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var results []Symbol
+	add := func(sym Symbol) bool {
+		if matchesKind(sym.Kind, kind) {
+			results = append(results, sym)
+		}
+		return len(results) < limit
+	}
+
+	// Exact names are answered from the name table without scanning
+	if !strings.Contains(pattern, "*") {
+		for _, i := range idx.byName[pattern] {
+			if !add(idx.symbols[i]) {
+				break
+			}
+		}
+		return results, nil
+	}
+
+	for _, sym := range idx.symbols {
+		if matchesPattern(sym.Name, pattern) && !add(sym) {
+			break
+		}
+	}
+	return results, nil
+}
+
+// Refresh re-indexes files that changed since they were last indexed
+func (idx *SymbolIndex) Refresh(ctx context.Context) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.refreshed = time.Time{}
+	return idx.refresh(ctx)
+}
+
+// refresh brings the index up to date; the caller must hold idx.mu
+func (idx *SymbolIndex) refresh(ctx context.Context) error {
+	if time.Since(idx.refreshed) < symbolIndexRefreshInterval {
+		return nil
+	}
+
+	if !idx.loaded {
+		idx.load()
+		idx.loaded = true
+	}
+
+	changed := false
+	seen := make(map[string]bool, len(idx.files))
+	ignored := ignore.New(idx.root)
+
+	err := filepath.WalkDir(idx.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(idx.root, path)
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			// Skip vendor, hidden and ignored directories
+			name := d.Name()
+			if path != idx.root && (name == "vendor" || strings.HasPrefix(name, ".") || ignored.Match(relPath, true)) {
+				return filepath.SkipDir
+			}
+			// Rules of .gitignore and .mantraignore apply below the directory they are in
+			if err := ignored.LoadDir(relPath); err != nil {
+				return fmt.Errorf("failed to read ignore files in %s: %w", relPath, err)
+			}
+			return nil
+		}
+
+		// Skip non-Go, test and ignored files
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") || ignored.Match(relPath, false) {
+			return nil
+		}
+		seen[relPath] = true
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if entry, ok := idx.files[relPath]; ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
+			return nil
+		}
+
+		idx.files[relPath] = &indexedFile{
+			ModTime: info.ModTime(),
+			Size:    info.Size(),
+			Symbols: indexFile(path, relPath), // Files with parse errors are indexed as empty
+		}
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", idx.root, err)
+	}
+
+	for relPath := range idx.files {
+		if !seen[relPath] {
+			delete(idx.files, relPath)
+			changed = true
+		}
+	}
+
+	if changed || idx.symbols == nil {
+		idx.rebuild()
+	}
+	if changed {
+		idx.save()
+	}
+
+	idx.refreshed = time.Now()
+	return nil
+}
+
+// rebuild recomputes the sorted symbol list and the name table from idx.files
+func (idx *SymbolIndex) rebuild() {
+	paths := make([]string, 0, len(idx.files))
+	for relPath := range idx.files {
+		paths = append(paths, relPath)
+	}
+	sort.Strings(paths)
+
+	idx.symbols = make([]Symbol, 0, len(paths))
+	idx.byName = make(map[string][]int)
+	for _, relPath := range paths {
+		for _, sym := range idx.files[relPath].Symbols {
+			idx.byName[sym.Name] = append(idx.byName[sym.Name], len(idx.symbols))
+			idx.symbols = append(idx.symbols, sym)
+		}
+	}
+}
+
+// load reads the cache file, ignoring it if it is missing, unreadable or from another version
+func (idx *SymbolIndex) load() {
+	if idx.cachePath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(idx.cachePath)
+	if err != nil {
+		return
+	}
+
+	var cached symbolIndexFile
+	if err := json.Unmarshal(data, &cached); err != nil {
+		slog.Debug("Ignoring unreadable symbol index", slog.String("path", idx.cachePath), slog.String("error", err.Error()))
+		return
+	}
+	if cached.Version != symbolIndexVersion || cached.Root != idx.root || cached.Files == nil {
+		return
+	}
+
+	idx.files = cached.Files
+}
+
+// save writes the index to the cache file atomically; failures only cost a cold start next run
+func (idx *SymbolIndex) save() {
+	if idx.cachePath == "" {
+		return
+	}
+
+	if err := idx.writeCache(); err != nil {
+		slog.Debug("Failed to save symbol index", slog.String("path", idx.cachePath), slog.String("error", err.Error()))
+	}
+}
+
+func (idx *SymbolIndex) writeCache() error {
+	data, err := json.Marshal(symbolIndexFile{
+		Version: symbolIndexVersion,
+		Root:    idx.root,
+		Files:   idx.files,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode symbol index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(idx.cachePath), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(idx.cachePath), ".symbols-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write symbol index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write symbol index: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), idx.cachePath); err != nil {
+		return fmt.Errorf("failed to replace symbol index: %w", err)
+	}
+	return nil
+}
+
+// indexFile parses path and returns its top-level declarations
+func indexFile(path, relPath string) []Symbol {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	pkg := file.Name.Name
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				// A lone spec carries its doc comment on the declaration
+				doc := d.Doc
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Doc != nil {
+						doc = s.Doc
+					}
+					kind := "type"
+					switch s.Type.(type) {
+					case *ast.StructType:
+						kind = "struct"
+					case *ast.InterfaceType:
+						kind = "interface"
+					}
+					symbols = append(symbols, Symbol{
+						Name:    s.Name.Name,
+						Kind:    kind,
+						Package: pkg,
+						File:    relPath,
+						Line:    fset.Position(s.Pos()).Line,
+						Doc:     strings.TrimSpace(doc.Text()),
+					})
+
+				case *ast.ValueSpec:
+					if s.Doc != nil {
+						doc = s.Doc
+					}
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						if name.Name == "_" {
+							continue
+						}
+						symbols = append(symbols, Symbol{
+							Name:    name.Name,
+							Kind:    kind,
+							Package: pkg,
+							File:    relPath,
+							Line:    fset.Position(name.Pos()).Line,
+							Doc:     strings.TrimSpace(doc.Text()),
+						})
+					}
+				}
+			}
+
+		case *ast.FuncDecl:
+			sym := Symbol{
+				Name:      d.Name.Name,
+				Kind:      "func",
+				Package:   pkg,
+				File:      relPath,
+				Line:      fset.Position(d.Pos()).Line,
+				Doc:       strings.TrimSpace(d.Doc.Text()),
+				Signature: analysis.BuildFunctionSignatureFromDecl(d),
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				sym.Kind = "method"
+				sym.Receiver = receiverTypeName(d.Recv.List[0].Type)
+			}
+			symbols = append(symbols, sym)
+		}
+	}
+
+	return symbols
+}
+
+// receiverTypeName returns the base type name of a method receiver, e.g. "Cache" for *Cache[K, V]
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.ParenExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// matchesKind reports whether a symbol kind satisfies the requested search kind
+func matchesKind(symbolKind, kind string) bool {
+	switch kind {
+	case "", "all":
+		return true
+	case "func":
+		return symbolKind == "func" || symbolKind == "method"
+	case "type":
+		return symbolKind == "type" || symbolKind == "struct" || symbolKind == "interface"
+	default:
+		return symbolKind == kind
+	}
+}
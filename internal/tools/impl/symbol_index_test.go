@@ -0,0 +1,116 @@
+package impl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSymbolIndex_SearchAndRefresh(t *testing.T) {
+	root := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "symbols.json")
+	source := filepath.Join(root, "store.go")
+
+	if err := os.WriteFile(source, []byte(`package store
+
+// Store keeps items in memory
+type Store struct{}
+
+// Get returns an item
+func (s *Store) Get(key string) string { return "" }
+
+func NewStore() *Store { return &Store{} }
+`), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	ctx := context.Background()
+	idx := NewSymbolIndex(root, cachePath)
+
+	results, err := idx.Search(ctx, "Get", "method", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	got := results[0]
+	if got.Receiver != "Store" || got.File != "store.go" || got.Line != 7 || got.Doc != "Get returns an item" {
+		t.Errorf("Unexpected symbol: %+v", got)
+	}
+
+	results, err = idx.Search(ctx, "*Store", "all", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected Store and NewStore, got %+v", results)
+	}
+
+	// A fresh index starts from the saved cache
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("Expected cache file to be written: %v", err)
+	}
+	reloaded := NewSymbolIndex(root, cachePath)
+	reloaded.load()
+	if len(reloaded.files["store.go"].Symbols) != 3 {
+		t.Errorf("Expected 3 cached symbols, got %+v", reloaded.files["store.go"])
+	}
+
+	// Changed files are re-indexed on refresh
+	if err := os.WriteFile(source, []byte("package store\n\nfunc Open() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite source: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(source, later, later); err != nil {
+		t.Fatalf("Failed to touch source: %v", err)
+	}
+	if err := idx.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	results, _ = idx.Search(ctx, "Get", "all", 10)
+	if len(results) != 0 {
+		t.Errorf("Expected stale symbol to be dropped, got %+v", results)
+	}
+	results, _ = idx.Search(ctx, "Open", "func", 10)
+	if len(results) != 1 {
+		t.Errorf("Expected new symbol to be indexed, got %+v", results)
+	}
+}
+
+func TestSymbolIndex_HonorsIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":               "build/\n",
+		"pkg/.mantraignore":        "*_gen.go\n",
+		"store.go":                 "package store\n\nfunc Open() {}\n",
+		"build/store.go":           "package store\n\nfunc OpenBuild() {}\n",
+		"pkg/store_gen.go":         "package pkg\n\nfunc OpenGenerated() {}\n",
+		"pkg/store_handwritten.go": "package pkg\n\nfunc OpenPkg() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	results, err := NewSymbolIndex(root, "").Search(context.Background(), "Open*", "func", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	var names []string
+	for _, sym := range results {
+		names = append(names, sym.Name)
+	}
+	if got := strings.Join(names, ","); got != "OpenPkg,Open" {
+		t.Errorf("Expected ignored files to be skipped, got %s", got)
+	}
+}
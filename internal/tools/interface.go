@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"slices"
 )
 
 // Tool represents a tool that can be called by the AI
@@ -32,3 +33,22 @@ type ContextAwareTool interface {
 	// This is called before Execute if context is available
 	SetContext(toolCtx *Context)
 }
+
+// Selectable lists the tools a // mantra:tools directive can offer. Tools of a phase not
+// listed here, such as result, are always offered
+var Selectable = []string{"inspect", "search", "list_errors", "call_examples", "check_code", "run_snippet"}
+
+// Allowed returns the tools offered to the model when only the named tools are allowed.
+// A nil names allows every tool
+func Allowed(all []Tool, names []string) []Tool {
+	if names == nil {
+		return all
+	}
+	var allowed []Tool
+	for _, tool := range all {
+		if !slices.Contains(Selectable, tool.Name()) || slices.Contains(names, tool.Name()) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// compileParamsSchema compiles a tool's parameter schema for validation
+func compileParamsSchema(tool Tool) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(tool.ParametersSchema()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters schema: %w", err)
+	}
+
+	url := tool.Name() + ".json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, fmt.Errorf("invalid parameters schema: %w", err)
+	}
+	return compiler.Compile(url)
+}
+
+// validateParams checks params against the compiled schema and describes every violation
+// so the model can correct its call
+func validateParams(toolName string, schema *jsonschema.Schema, params map[string]any) error {
+	// Validate a map[string]any the same way as the decoded JSON object
+	var instance any = map[string]any{}
+	if params != nil {
+		instance = params
+	}
+
+	err := schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return NewInternalError(fmt.Sprintf("Parameters for %s could not be validated", toolName), err)
+	}
+
+	var violations []string
+	collectViolations(validationErr.BasicOutput(), &violations)
+	return &ToolError{
+		Code:       CodeInvalidParams,
+		Message:    fmt.Sprintf("Parameters for %s do not match its schema", toolName),
+		Details:    strings.Join(violations, "; "),
+		Suggestion: "Fix the listed parameters and call the tool again",
+	}
+}
+
+// collectViolations flattens schema output units into "location: problem" lines
+func collectViolations(unit *jsonschema.OutputUnit, violations *[]string) {
+	if unit.Error != nil && len(unit.Errors) == 0 {
+		location := unit.InstanceLocation
+		if location == "" {
+			location = "(root)"
+		}
+		*violations = append(*violations, fmt.Sprintf("%s: %s", location, unit.Error.String()))
+	}
+	for i := range unit.Errors {
+		collectViolations(&unit.Errors[i], violations)
+	}
+}
@@ -0,0 +1,192 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// fileGroup holds the targets of one source file, in the order they were added
+type fileGroup struct {
+	file    string
+	targets []*TargetView
+}
+
+// groupRow is a line of the grouped view: a file, or a target of an expanded file
+type groupRow struct {
+	text  string
+	group int // Index of the file the row belongs to
+}
+
+// groupByFile groups the targets by source file, in order of first appearance
+func (m *Model) groupByFile() []fileGroup {
+	var groups []fileGroup
+	index := make(map[string]int)
+	for _, target := range m.targets {
+		i, ok := index[target.File]
+		if !ok {
+			i = len(groups)
+			index[target.File] = i
+			groups = append(groups, fileGroup{file: target.File})
+		}
+		groups[i].targets = append(groups[i].targets, target)
+	}
+	return groups
+}
+
+// pageRows returns the rows of the grouped view that fit the terminal below the header
+func (m *Model) pageRows() int {
+	return max(m.height-6, 1)
+}
+
+// groupRows returns the rows of the grouped view
+func (m *Model) groupRows(groups []fileGroup) []groupRow {
+	var rows []groupRow
+	for i, group := range groups {
+		cursor := " "
+		if i == m.cursor {
+			cursor = ">"
+		}
+		fold := "[+]"
+		if m.expanded[group.file] {
+			fold = "[-]"
+		}
+		icon, summary := m.groupStatus(group)
+		rows = append(rows, groupRow{text: fmt.Sprintf("%s %s %s %s (%s)", cursor, fold, icon, group.file, summary), group: i})
+
+		if !m.expanded[group.file] {
+			continue
+		}
+		for _, target := range group.targets {
+			rows = append(rows, groupRow{text: "      " + m.groupTargetLine(target), group: i})
+		}
+	}
+	return rows
+}
+
+// groupStatus returns the aggregate status of a file's targets: an icon and the counts
+func (m *Model) groupStatus(group fileGroup) (string, string) {
+	var completed, failed, running int
+	for _, target := range group.targets {
+		switch target.Status {
+		case "completed":
+			completed++
+		case "failed":
+			failed++
+		case "running":
+			running++
+		}
+	}
+
+	summary := fmt.Sprintf("%d/%d done", completed+failed, len(group.targets))
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d failed", failed)
+	}
+	if running > 0 {
+		summary += fmt.Sprintf(", %d running", running)
+	}
+
+	switch {
+	case running > 0:
+		return m.getSpinner("running"), summary
+	case failed > 0:
+		return m.getCompletionIcon("failed"), summary
+	case completed == len(group.targets):
+		return m.getCompletionIcon("completed"), summary
+	}
+	return m.getSpinner("pending"), summary
+}
+
+// groupTargetLine renders a target of an expanded file on a single line
+func (m *Model) groupTargetLine(target *TargetView) string {
+	switch target.Status {
+	case "completed", "failed":
+		duration := target.EndTime.Sub(target.StartTime).Round(time.Millisecond)
+		return fmt.Sprintf("%s %s (%s)", m.getCompletionIcon(target.Status), target.Name, duration)
+	case "running":
+		if target.Phase != "" && target.Phase != "Initializing" {
+			return fmt.Sprintf("%s %s [%s]", m.getSpinner(target.Status), target.Name, target.Phase)
+		}
+	}
+	return fmt.Sprintf("%s %s", m.getSpinner(target.Status), target.Name)
+}
+
+// appendGroupedView appends the page of the grouped view holding the selected file
+func (m *Model) appendGroupedView(sb *strings.Builder) {
+	groups := m.groupByFile()
+	m.cursor = min(m.cursor, len(groups)-1)
+	rows := m.groupRows(groups)
+
+	perPage := m.pageRows()
+	pages := (len(rows) + perPage - 1) / perPage
+	page := 0
+	for i, row := range rows {
+		if row.group == m.cursor {
+			page = i / perPage
+			break
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\nFiles (page %d/%d; j/k select, enter expand, n/p page, a expand all):\n", page+1, pages))
+	for _, row := range rows[page*perPage : min((page+1)*perPage, len(rows))] {
+		line := row.text
+		// Truncate lines if they exceed terminal width
+		if m.width > 0 && len(line) > m.width {
+			line = line[:m.width-3] + "..."
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+}
+
+// handleGroupKey moves the selection of the grouped view and expands or collapses files
+func (m *Model) handleGroupKey(key string) {
+	groups := m.groupByFile()
+	if len(groups) == 0 {
+		return
+	}
+
+	switch key {
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(groups)-1)
+	case "enter", " ":
+		file := groups[m.cursor].file
+		m.expanded[file] = !m.expanded[file]
+	case "a":
+		// Expand all files, or collapse them all when every one is expanded
+		all := true
+		for _, group := range groups {
+			all = all && m.expanded[group.file]
+		}
+		for _, group := range groups {
+			m.expanded[group.file] = !all
+		}
+	case "n", "pgdown", "right":
+		m.cursor = m.pageStart(groups, 1)
+	case "p", "pgup", "left":
+		m.cursor = m.pageStart(groups, -1)
+	}
+}
+
+// pageStart returns the first file on the page delta pages away from the selected file's
+func (m *Model) pageStart(groups []fileGroup, delta int) int {
+	rows := m.groupRows(groups)
+	perPage := m.pageRows()
+	current := 0
+	for i, row := range rows {
+		if row.group == m.cursor {
+			current = i / perPage
+			break
+		}
+	}
+
+	target := min(max(current+delta, 0), (len(rows)-1)/perPage)
+	group := rows[target*perPage].group
+	// A page starting inside an expanded file selects the next file, if any
+	if target != current && target*perPage > 0 && rows[target*perPage-1].group == group && group+1 < len(groups) && delta > 0 {
+		group++
+	}
+	return group
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"strings"
 	"time"
@@ -13,6 +14,7 @@ import (
 // TargetView represents the view state for a single target
 type TargetView struct {
 	Name      string
+	File      string // Source file, relative to the project root
 	Index     int
 	Total     int
 	Status    string
@@ -20,6 +22,8 @@ type TargetView struct {
 	Logs      []slog.Record
 	StartTime time.Time
 	EndTime   time.Time
+	Rounds    int // Chat completion requests made (set when finished)
+	ToolCalls int // Tool calls made (set when finished)
 }
 
 // GetAllLogs returns a copy of all logs for the target
@@ -36,13 +40,23 @@ type Model struct {
 	width      int
 	height     int
 	tuiEnabled bool
+	static     bool      // Print a line per target update instead of rendering the TUI
+	out        io.Writer // Destination of static lines
+
+	// Runs that do not fit the terminal as a flat list are shown grouped by file
+	grouped  bool
+	cursor   int             // Selected file of the grouped view
+	expanded map[string]bool // Files whose targets the grouped view lists
 }
 
-// newModel creates a new TUI model
-func newModel(tuiEnabled bool) *Model {
+// newModel creates a new TUI model. A static model writes its lines to out
+func newModel(tuiEnabled, static bool, out io.Writer) *Model {
 	return &Model{
 		targets:    make([]*TargetView, 0),
 		tuiEnabled: tuiEnabled,
+		static:     static,
+		out:        out,
+		expanded:   make(map[string]bool),
 	}
 }
 
@@ -50,10 +64,17 @@ func (m *Model) IsTUIEnabled() bool {
 	return m.tuiEnabled
 }
 
+// IsStatic reports whether progress is printed as static lines. Like the TUI, the static
+// renderer keeps logs to itself
+func (m *Model) IsStatic() bool {
+	return m.static
+}
+
 // addTarget adds a new target to track
-func (m *Model) addTarget(name string, index, total int) {
+func (m *Model) addTarget(name, file string, index, total int) {
 	target := &TargetView{
 		Name:      name,
+		File:      file,
 		Index:     index,
 		Total:     total,
 		Status:    "pending",
@@ -81,6 +102,9 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "q", "ctrl+c":
 			return m, tea.Quit
 		}
+		if m.grouped {
+			m.handleGroupKey(msg.String())
+		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -100,9 +124,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Update target status
 		m.updateStatus(msg)
 
+	case statsMsg:
+		// Record request statistics for a finished target
+		m.updateStats(msg)
+
 	case addTargetMsg:
 		// Add new target
-		m.addTarget(msg.Name, msg.Index, msg.Total)
+		m.addTarget(msg.Name, msg.File, msg.Index, msg.Total)
 	}
 
 	return m, nil
@@ -122,6 +150,15 @@ func (m *Model) View() string {
 	sb.WriteString(header)
 	sb.WriteString("\n")
 
+	// Once the flat list outgrows the terminal, targets are grouped by file for good
+	if !m.grouped && m.height > 0 && len(m.targets)*2+strings.Count(header, "\n")+6 > m.height {
+		m.grouped = true
+	}
+	if m.grouped {
+		m.appendGroupedView(&sb)
+		return sb.String()
+	}
+
 	activeTargets, completedTargets := m.categorizeTargets()
 
 	// Show active targets first
@@ -172,6 +209,14 @@ type targetStats struct {
 	pending       int
 	total         int
 	totalDuration time.Duration
+	finished      time.Duration // Sum of the durations of completed and failed targets
+	phases        []phaseCount  // Running targets per phase, in order of first appearance
+}
+
+// phaseCount is the number of running targets in a phase
+type phaseCount struct {
+	phase string
+	count int
 }
 
 // calculateStatistics computes aggregate statistics for all targets
@@ -182,6 +227,13 @@ func (m *Model) calculateStatistics() targetStats {
 	var latestEnd time.Time
 
 	for _, target := range m.targets {
+		if target.Status == "completed" || target.Status == "failed" {
+			stats.finished += target.EndTime.Sub(target.StartTime)
+		}
+		if target.Status == "running" {
+			stats.addPhase(target.Phase)
+		}
+
 		switch target.Status {
 		case "completed":
 			stats.completed++
@@ -221,6 +273,41 @@ func (m *Model) calculateStatistics() targetStats {
 	return stats
 }
 
+// addPhase counts a running target in phase
+func (s *targetStats) addPhase(phase string) {
+	if phase == "" || phase == "Initializing" {
+		phase = "Starting"
+	}
+	for i := range s.phases {
+		if s.phases[i].phase == phase {
+			s.phases[i].count++
+			return
+		}
+	}
+	s.phases = append(s.phases, phaseCount{phase: phase, count: 1})
+}
+
+// averageDuration returns the mean duration of finished targets, or 0 before any finished
+func (s targetStats) averageDuration() time.Duration {
+	done := s.completed + s.failed
+	if done == 0 {
+		return 0
+	}
+	return s.finished / time.Duration(done)
+}
+
+// eta estimates the time until all targets finish from the average duration of finished
+// targets, with as many targets in flight as are running now. It returns 0 when there is
+// nothing to estimate from
+func (s targetStats) eta() time.Duration {
+	remaining := s.total - s.completed - s.failed
+	average := s.averageDuration()
+	if remaining <= 0 || average == 0 {
+		return 0
+	}
+	return average * time.Duration(remaining) / time.Duration(max(s.running, 1))
+}
+
 // buildHeader creates the header with progress bar
 func (m *Model) buildHeader(stats targetStats) string {
 	// Build progress bar
@@ -250,12 +337,30 @@ func (m *Model) buildHeader(stats targetStats) string {
 	if stats.totalDuration > 0 {
 		header += fmt.Sprintf(" | %s", stats.totalDuration.Round(time.Millisecond))
 	}
+	if average := stats.averageDuration(); average > 0 {
+		header += fmt.Sprintf(" | avg %s/target", average.Round(100*time.Millisecond))
+	}
+	if eta := stats.eta(); eta > 0 {
+		header += fmt.Sprintf(" | ETA %s", eta.Round(time.Second))
+	}
 
 	// Add status counts
 	if stats.failed > 0 {
 		header += fmt.Sprintf(" | FAILED: %d", stats.failed)
 	}
 
+	// Add the phases of running targets
+	if len(stats.phases) > 0 {
+		phases := make([]string, len(stats.phases))
+		for i, p := range stats.phases {
+			phases[i] = fmt.Sprintf("%s %d", p.phase, p.count)
+		}
+		header += fmt.Sprintf("\nRunning: %s", strings.Join(phases, ", "))
+		if stats.pending > 0 {
+			header += fmt.Sprintf(" | Waiting: %d", stats.pending)
+		}
+	}
+
 	return header
 }
 
@@ -314,6 +419,9 @@ func (m *Model) categorizeTargets() (activeTargets, completedTargets []string) {
 			icon := m.getCompletionIcon(target.Status)
 			duration := target.EndTime.Sub(target.StartTime).Round(time.Millisecond)
 			targetLine = fmt.Sprintf("%s %s (%s)", icon, target.Name, duration)
+			if target.Rounds > 0 {
+				targetLine = fmt.Sprintf("%s %s (%s, %d rounds, %d tool calls)", icon, target.Name, duration, target.Rounds, target.ToolCalls)
+			}
 
 			// Add final result message as a separate indented line (same as active targets)
 			logFound := false
@@ -391,11 +499,14 @@ func (m *Model) addLog(msg logMsg) {
 	})
 
 	// Update phase if present
-	if phase != "" {
+	if phase != "" && phase != target.Phase {
 		target.Phase = phase
+		if m.static {
+			m.printStatic(target, phase)
+		}
 	}
 
-	if !m.tuiEnabled {
+	if !m.tuiEnabled && !m.static {
 		m.PlainLog(msg.Record)
 	}
 }
@@ -411,9 +522,57 @@ func (m *Model) updateStatus(msg statusMsg) {
 
 	target := m.targets[msg.TargetIndex-1]
 	target.Status = msg.Status
+	if msg.Status == "running" {
+		target.StartTime = time.Now() // Targets are added before they start
+	}
 	if msg.Status == "completed" || msg.Status == "failed" {
 		target.EndTime = time.Now()
 	}
+
+	if m.static {
+		m.printStatic(target, m.staticStatus(target))
+	}
+}
+
+// staticStatus describes a status change of target for the static renderer
+func (m *Model) staticStatus(target *TargetView) string {
+	if target.Status != "completed" && target.Status != "failed" {
+		return target.Status
+	}
+
+	duration := target.EndTime.Sub(target.StartTime).Round(time.Millisecond)
+	text := fmt.Sprintf("%s (%s)", m.getCompletionIcon(target.Status), duration)
+	if target.Rounds > 0 {
+		text = fmt.Sprintf("%s (%s, %d rounds, %d tool calls)", m.getCompletionIcon(target.Status), duration, target.Rounds, target.ToolCalls)
+	}
+	if target.Status == "failed" && len(target.Logs) > 0 {
+		text += " - " + m.formatLogMessage(target.Logs[len(target.Logs)-1])
+	}
+
+	stats := m.calculateStatistics()
+	text += fmt.Sprintf(" | %d/%d done", stats.completed+stats.failed, stats.total)
+	if stats.failed > 0 {
+		text += fmt.Sprintf(", %d failed", stats.failed)
+	}
+	if eta := stats.eta(); eta > 0 {
+		text += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+	}
+	return text
+}
+
+// printStatic writes a line about target for the static renderer
+func (m *Model) printStatic(target *TargetView, text string) {
+	fmt.Fprintf(m.out, "[%d/%d] %s: %s\n", target.Index, target.Total, target.Name, text)
+}
+
+func (m *Model) updateStats(msg statsMsg) {
+	if !m.validateTargetIndex(msg.TargetIndex) {
+		return
+	}
+
+	target := m.targets[msg.TargetIndex-1]
+	target.Rounds = msg.Rounds
+	target.ToolCalls = msg.ToolCalls
 }
 
 // Message types
@@ -429,8 +588,15 @@ type statusMsg struct {
 	Status      string
 }
 
+type statsMsg struct {
+	TargetIndex int
+	Rounds      int
+	ToolCalls   int
+}
+
 type addTargetMsg struct {
 	Name  string
+	File  string
 	Index int
 	Total int
 }
@@ -6,16 +6,24 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"golang.org/x/term"
+
+	"github.com/rail44/mantra/internal/progress"
+	"github.com/rail44/mantra/internal/status"
 )
 
 // ProgramOptions contains options for creating a Program
 type ProgramOptions struct {
-	Plain bool // Use plain text output instead of TUI
+	Plain    bool             // Use plain text output instead of TUI
+	Static   bool             // Print a line per target update instead of the animated TUI
+	Status   *status.Tracker  // Also report progress to the status endpoint (optional)
+	Progress *progress.Stream // Also write progress events as JSON lines (optional)
 }
 
 // Program manages the TUI program and provides logger creation
 type Program struct {
 	teaProgram *tea.Program
+	status     *status.Tracker
+	progress   *progress.Stream
 }
 
 // NewProgram creates a new TUI program with default options
@@ -27,9 +35,11 @@ func NewProgram() *Program {
 func NewProgramWithOptions(opts ProgramOptions) *Program {
 	isTerminal := term.IsTerminal(int(os.Stdout.Fd()))
 
-	// Determine if TUI should be enabled
-	tuiEnabled := isTerminal && !opts.Plain
-	model := newModel(tuiEnabled)
+	// Determine if TUI should be enabled. Terminals that ask for no color or cannot redraw
+	// get static lines instead
+	static := !opts.Plain && (opts.Static || (isTerminal && noAnimation()))
+	tuiEnabled := isTerminal && !opts.Plain && !static
+	model := newModel(tuiEnabled, static, os.Stdout)
 
 	var teaProgram *tea.Program
 	if tuiEnabled {
@@ -37,18 +47,26 @@ func NewProgramWithOptions(opts ProgramOptions) *Program {
 		// We don't use alt screen to keep previous logs visible
 		teaProgram = tea.NewProgram(model)
 	} else {
-		// Plain mode or non-terminal mode - disable TUI rendering
+		// Plain, static or non-terminal mode - disable TUI rendering
 		// Still use tea.Program for event handling and model updates
 		teaProgram = tea.NewProgram(model, tea.WithInput(nil), tea.WithoutRenderer())
 	}
 
 	program := &Program{
 		teaProgram: teaProgram,
+		status:     opts.Status,
+		progress:   opts.Progress,
 	}
 
 	return program
 }
 
+// noAnimation reports whether the environment asks for output without color or animation:
+// NO_COLOR is set (https://no-color.org) or TERM is dumb
+func noAnimation() bool {
+	return os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+}
+
 // Start starts the TUI program (blocks until Quit is called)
 // Returns the final model state after the program ends
 func (p *Program) Start() (*Model, error) {
@@ -66,23 +84,34 @@ func (p *Program) Start() (*Model, error) {
 	return nil, nil
 }
 
-// AddTarget registers a new target for UI tracking
-func (p *Program) AddTarget(name string, index, total int) {
+// AddTarget registers a new target for UI tracking. file is the target's source file,
+// by which large runs are grouped
+func (p *Program) AddTarget(name, file string, index, total int) {
 	// Send message to add target
 	p.teaProgram.Send(addTargetMsg{
 		Name:  name,
+		File:  file,
 		Index: index,
 		Total: total,
 	})
+	p.status.AddTarget(name, index)
+	p.progress.TargetAdded(name, index, total)
 }
 
 // SendLog sends a log record to the TUI or outputs via plain handler
 func (p *Program) SendLog(record slog.Record) {
 	var targetIndex int
+	var phase, tool, toolErr string
 	record.Attrs(func(a slog.Attr) bool {
-		if a.Key == "targetIndex" {
+		switch a.Key {
+		case "targetIndex":
 			targetIndex = int(a.Value.Int64())
-			return false
+		case "phase":
+			phase = a.Value.String()
+		case "tool":
+			tool = a.Value.String()
+		case "error":
+			toolErr = a.Value.String()
 		}
 		return true
 	})
@@ -90,6 +119,18 @@ func (p *Program) SendLog(record slog.Record) {
 		TargetIndex: targetIndex,
 		Record:      record,
 	})
+	p.status.SetStep(targetIndex, phase, record.Message)
+	p.progress.Phase(targetIndex, phase)
+	if toolCallMessages[record.Message] {
+		p.progress.ToolCall(targetIndex, tool, toolErr)
+	}
+}
+
+// toolCallMessages are the messages logged once per finished tool call
+var toolCallMessages = map[string]bool{
+	"Tool completed":               true,
+	"Tool error":                   true,
+	"Phase failed via result tool": true,
 }
 
 // MarkAsRunning marks a target as running
@@ -98,6 +139,8 @@ func (p *Program) MarkAsRunning(targetIndex int) {
 		TargetIndex: targetIndex,
 		Status:      "running",
 	})
+	p.status.SetStatus(targetIndex, "running")
+	p.progress.TargetRunning(targetIndex)
 	// Plain mode output is handled by Handler
 }
 
@@ -107,6 +150,8 @@ func (p *Program) Complete(targetIndex int) {
 		TargetIndex: targetIndex,
 		Status:      "completed",
 	})
+	p.status.SetStatus(targetIndex, "completed")
+	p.progress.TargetCompleted(targetIndex)
 	// Plain mode output is handled by Handler
 }
 
@@ -116,9 +161,22 @@ func (p *Program) Fail(targetIndex int) {
 		TargetIndex: targetIndex,
 		Status:      "failed",
 	})
+	p.status.SetStatus(targetIndex, "failed")
+	p.progress.TargetFailed(targetIndex)
 	// Plain mode output is handled by Handler
 }
 
+// SetStats records request statistics shown next to a finished target
+func (p *Program) SetStats(targetIndex, rounds, toolCalls int) {
+	p.teaProgram.Send(statsMsg{
+		TargetIndex: targetIndex,
+		Rounds:      rounds,
+		ToolCalls:   toolCalls,
+	})
+	p.status.SetStats(targetIndex, rounds, toolCalls)
+	p.progress.Stats(targetIndex, rounds, toolCalls)
+}
+
 // Quit stops the TUI program
 func (p *Program) Quit() {
 	p.teaProgram.Quit()